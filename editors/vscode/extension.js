@@ -0,0 +1,82 @@
+// Companion extension for reporter. It runs where the terminal's shell runs
+// (the workspace side, so on the remote host under Remote-SSH or inside a dev
+// container), listens on a private Unix socket, and points reporter at it
+// through REPORTER_AGENT in every new integrated terminal. reporter then sends
+// its desktop notifications here, speaking the host agent protocol: one line
+// of the reporter/v1 JSON payload, answered by "ok" or "error: <reason>".
+'use strict';
+
+const fs = require('fs');
+const net = require('net');
+const os = require('os');
+const path = require('path');
+const vscode = require('vscode');
+
+const maxPayload = 1 << 20;
+const timeoutMs = 5000;
+
+function activate(context) {
+  // mkdtemp creates the directory with mode 0700, so only this user can
+  // reach the socket.
+  const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'reporter-vscode-'));
+  const socket = path.join(dir, 'agent.sock');
+
+  const server = net.createServer((conn) => {
+    let buf = '';
+    let done = false;
+    conn.setEncoding('utf8');
+    conn.setTimeout(timeoutMs, () => conn.destroy());
+    conn.on('error', () => {});
+    conn.on('data', (chunk) => {
+      if (done) {
+        return;
+      }
+      buf += chunk;
+      const nl = buf.indexOf('\n');
+      if (nl < 0 && buf.length <= maxPayload) {
+        return;
+      }
+      done = true;
+      conn.end(nl < 0 ? `error: payload larger than ${maxPayload} bytes\n` : show(buf.slice(0, nl)));
+    });
+  });
+  server.listen(socket);
+
+  context.environmentVariableCollection.replace('REPORTER_AGENT', socket);
+  context.subscriptions.push({
+    dispose() {
+      server.close();
+      fs.rmSync(dir, { recursive: true, force: true });
+    },
+  });
+}
+
+// show raises the notification in payload line and returns the reply.
+function show(line) {
+  let p;
+  try {
+    p = JSON.parse(line);
+  } catch (err) {
+    return `error: invalid payload: ${err.message}\n`;
+  }
+  if (p.schema !== 'reporter/v1') {
+    return `error: unsupported payload schema ${JSON.stringify(p.schema)}\n`;
+  }
+
+  const text = [p.title, p.body].filter(Boolean).join(': ') + (p.command ? ` — ${p.command}` : '');
+  const actions = p.output ? ['Show Output'] : [];
+  const raise = p.failed ? vscode.window.showErrorMessage : vscode.window.showInformationMessage;
+  // Not awaited: the message stays up until dismissed, long after reporter
+  // has its answer.
+  raise(text, ...actions).then(async (choice) => {
+    if (choice) {
+      const doc = await vscode.workspace.openTextDocument({ content: p.output });
+      await vscode.window.showTextDocument(doc);
+    }
+  });
+  return 'ok\n';
+}
+
+function deactivate() {}
+
+module.exports = { activate, deactivate };
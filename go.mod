@@ -1,3 +1,3 @@
-module reporter
+module github.com/itsrainingmani/reporter
 
 go 1.22
@@ -0,0 +1,246 @@
+package reporter
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The host agent shows desktop notifications on behalf of reporters that
+// cannot, typically ones inside a devcontainer or another container on the
+// same machine. `reporter agent` listens on a Unix socket (or a loopback
+// port) on the host; the container gets the socket through a bind mount, or
+// the port through a forward, and sends each notification there instead of to
+// its own, missing, desktop.
+//
+// The protocol is one line per connection: the reporter/v1 JSON payload
+// (see payload.go) from the client, then "ok" or "error: <reason>" from the
+// agent. It needs no HTTP stack, so it also works in -tags nopush builds.
+// IDE plugins, which can more easily serve HTTP, may instead give an http://
+// URL as the address; the same payload is then POSTed there, exactly as the
+// webhook provider sends it.
+
+// agentMountPath is where containers look for the agent socket when
+// REPORTER_AGENT is unset; mount the host's agent directory at /run/reporter.
+const agentMountPath = "/run/reporter/agent.sock"
+
+// agentTimeout bounds a whole exchange with the agent, including the
+// agent's own delivery.
+const agentTimeout = 5 * time.Second
+
+// agentMaxPayload caps the line the agent reads.
+const agentMaxPayload = 1 << 20
+
+// agentSocketPath is where `reporter agent` listens by default: under
+// $XDG_RUNTIME_DIR, which is private to the user, else the state directory.
+func agentSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "reporter", "agent.sock")
+	}
+	if dir := stateDir(); dir != "" {
+		return filepath.Join(dir, "agent.sock")
+	}
+	return ""
+}
+
+// hostAgent returns the address of the host agent to route desktop
+// notifications through, or "" to use the local notifier. REPORTER_AGENT
+// names it explicitly, then the relay of a `reporter ssh` session (see
+// ssh.go), then config agent, unless the system config locks it; otherwise
+// a container uses the agent socket if one is mounted at agentMountPath.
+func hostAgent(cfg *config) string {
+	if addr := cfg.envString("agent", "", "REPORTER_AGENT", sshRelayEnv); addr != "" {
+		return addr
+	}
+	if currentEnvironment().Container != "" {
+		if _, err := os.Stat(agentMountPath); err == nil {
+			return agentMountPath
+		}
+	}
+	return ""
+}
+
+// agentNetwork returns the network for an agent address: an http:// or
+// https:// URL is an HTTP endpoint, a path is a Unix socket, and anything
+// else a host:port.
+func agentNetwork(addr string) string {
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return "http"
+	}
+	if strings.Contains(addr, "/") {
+		return "unix"
+	}
+	return "tcp"
+}
+
+// showDesktop raises n on the desktop, through the host agent if there is
+// one.
+func showDesktop(agent, terminal string, n notification) error {
+	switch {
+	case agent != "":
+		return notifyAgent(agent, n)
+	case terminal != "":
+		return notifyTerminal(terminal, n)
+	}
+	return notifyDesktop(n)
+}
+
+// notifyAgent sends n to the host agent at addr and waits for its answer.
+func notifyAgent(addr string, n notification) error {
+	if agentNetwork(addr) == "http" {
+		// A desktop notification is only worth showing now, so there
+		// are no retries.
+		if err := pushOnce(pushTarget{URL: addr, Provider: pushProviderWebhook, PayloadVersion: 1}, n); err != nil {
+			return fmt.Errorf("host agent: %w", err)
+		}
+		return nil
+	}
+	payload, err := encodePayload(1, n)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout(agentNetwork(addr), addr, agentTimeout)
+	if err != nil {
+		return fmt.Errorf("host agent: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(agentTimeout))
+	if _, err := conn.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("host agent: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("host agent at %s: %w", addr, err)
+	}
+	if reply = strings.TrimSpace(reply); reply != "ok" {
+		return fmt.Errorf("host agent at %s: %s", addr, strings.TrimPrefix(reply, "error: "))
+	}
+	return nil
+}
+
+// runAgent implements `reporter agent`.
+func runAgent(args []string) int {
+	fset := flag.NewFlagSet("agent", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter agent [-listen path|127.0.0.1:port]")
+		fset.PrintDefaults()
+	}
+	listen := fset.String("listen", agentSocketPath(), "Unix socket `path`, or loopback host:port, to accept notifications on")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 || *listen == "" {
+		fset.Usage()
+		return 2
+	}
+
+	ln, err := listenAgent(*listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "agent: %v\n", err)
+		return 1
+	}
+	defer ln.Close()
+	fmt.Fprintf(os.Stderr, "agent: listening on %s\n", *listen)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "agent: %v\n", err)
+			return 1
+		}
+		go serveAgentConn(conn, notifyDesktop)
+	}
+}
+
+// listenAgent opens the agent's listener. A Unix socket is private to the
+// user; a stale one left by an agent that died is replaced. TCP is limited to
+// loopback, where forwarded ports arrive, since anyone who can connect can
+// raise notifications.
+func listenAgent(addr string) (net.Listener, error) {
+	switch agentNetwork(addr) {
+	case "http":
+		return nil, errors.New("reporter agent serves the socket protocol; give a path or host:port, not a URL")
+	case "tcp":
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("refusing to listen on %s: use a loopback address or a Unix socket", addr)
+		}
+		return net.Listen("tcp", addr)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(addr), 0o700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", addr, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another agent is already listening on %s", addr)
+	}
+	if fi, err := os.Lstat(addr); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", addr)
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(addr, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// serveAgentConn handles one notification on conn, raising it with show.
+func serveAgentConn(conn net.Conn, show func(notification) error) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(agentTimeout))
+	r := bufio.NewReaderSize(conn, 64<<10)
+	line, err := readLimited(r, agentMaxPayload)
+	if err != nil {
+		fmt.Fprintf(conn, "error: %v\n", err)
+		return
+	}
+	n, err := decodePayload(line)
+	if err == nil {
+		err = show(n)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "agent: %v\n", err)
+		fmt.Fprintf(conn, "error: %s\n", oneLine(err.Error()))
+		return
+	}
+	fmt.Fprintln(conn, "ok")
+}
+
+// readLimited reads one newline-terminated line of at most limit bytes.
+func readLimited(r *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > limit {
+			return nil, fmt.Errorf("payload larger than %d bytes", limit)
+		}
+		switch {
+		case err == nil:
+			return line, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		default:
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package reporter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// agentSocket returns a socket path short enough for sun_path, which
+// t.TempDir can exceed.
+func agentSocket(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "ragent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "reporter", "agent.sock")
+}
+
+func TestAgentRoundTrip(t *testing.T) {
+	path := agentSocket(t)
+	ln, err := listenAgent(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("socket mode = %v, %v; want 0600", fi.Mode(), err)
+	}
+
+	shown := make(chan notification, 1)
+	showErrs := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			serveAgentConn(conn, func(n notification) error {
+				shown <- n
+				return <-showErrs
+			})
+		}
+	}()
+
+	n := notification{
+		Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make test", Failed: true,
+		Args: []string{"make", "test"}, Finished: time.Date(2026, 3, 4, 15, 4, 5, 0, time.UTC),
+		Duration: 3 * time.Minute, ExitCode: 2, Output: "FAIL", DedupKey: "ci-7",
+		Labels: map[string]string{"env": "dev"},
+	}
+	showErrs <- nil
+	if err := notifyAgent(path, n); err != nil {
+		t.Fatalf("notifyAgent() returned error: %v", err)
+	}
+	if got := <-shown; !reflect.DeepEqual(got, n) || !got.Finished.Equal(n.Finished) {
+		t.Errorf("agent showed %+v, want %+v", got, n)
+	}
+
+	showErrs <- errors.New("osascript not found in PATH")
+	err = notifyAgent(path, n)
+	<-shown
+	if err == nil || !strings.HasSuffix(err.Error(), ": osascript not found in PATH") {
+		t.Errorf("notifyAgent() error = %v, want the agent's delivery error", err)
+	}
+
+	if _, err := listenAgent(path); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("second listenAgent() error = %v, want already listening", err)
+	}
+}
+
+func TestListenAgent(t *testing.T) {
+	// A socket left behind by an agent that died is replaced.
+	path := agentSocket(t)
+	ln, err := listenAgent(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	if ln, err = listenAgent(path); err != nil {
+		t.Errorf("listenAgent() over a stale socket: %v", err)
+	} else {
+		ln.Close()
+	}
+
+	os.WriteFile(path+".keep", nil, 0o600)
+	if _, err := listenAgent(path + ".keep"); err == nil {
+		t.Error("listenAgent() replaced a regular file")
+	}
+	if _, err := listenAgent("http://127.0.0.1:8765/"); err == nil {
+		t.Error("listenAgent() accepted an HTTP URL")
+	}
+	if _, err := listenAgent("0.0.0.0:0"); err == nil {
+		t.Error("listenAgent() accepted a non-loopback address")
+	}
+	ln, err = listenAgent("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listenAgent() on loopback: %v", err)
+	}
+	ln.Close()
+}
+
+func TestNotifyAgentUnreachable(t *testing.T) {
+	if err := notifyAgent(agentSocket(t), notification{Title: "x"}); err == nil || !strings.HasPrefix(err.Error(), "host agent: ") {
+		t.Errorf("notifyAgent() error = %v, want a host agent error", err)
+	}
+}
@@ -0,0 +1,134 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reporter attach <pid> waits for a process that is already running, one
+// started without reporter, and reports it when it exits, as if reporter
+// had run it. It takes the same flags as wrapping a command. A process that
+// is not reporter's child gives up its exit status only on some systems
+// (see waitProcessExit); elsewhere the run is reported with the status
+// unknown.
+
+// attachedProcess describes the process reporter attach waits for.
+type attachedProcess struct {
+	Command string
+	// Started is when the process started, or the zero time if unknown.
+	Started time.Time
+}
+
+// attachMode waits for the process pid to exit and reports it, showing
+// display as the command unless it is empty.
+func attachMode(pid int, display string, opts options) int {
+	if pid <= 0 || pid == os.Getpid() {
+		fmt.Fprintf(os.Stderr, "invalid pid %d\n", pid)
+		return 2
+	}
+	proc, err := inspectProcess(pid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "attach: %v\n", err)
+		return 1
+	}
+	if display == "" {
+		display = proc.Command
+	}
+	display = opts.redact.command(display)
+	if display == "" {
+		display = fmt.Sprintf("pid %d", pid)
+	}
+	start := proc.Started
+	if start.IsZero() {
+		start = time.Now()
+		if !opts.quiet {
+			fmt.Fprintf(os.Stderr, "[attach] cannot tell when pid %d started; timing it from now\n", pid)
+		}
+	}
+	if !opts.quiet {
+		fmt.Fprintf(os.Stderr, "[attach] waiting for pid %d (%s), running for %s\n", pid, oneLine(display), formatDuration(time.Since(start).Round(time.Second)))
+	}
+
+	key := opts.dedupKey
+	if key == "" {
+		dir, _ := os.Getwd()
+		key = fingerprint(runResult{Command: display}, "", dir)
+	}
+	stopProgress := startProgress(display, key, start, opts)
+	stopHeartbeat := startHeartbeat(display, start, opts)
+	exitCode, known, err := waitProcessExit(pid)
+	stopProgress()
+	stopHeartbeat()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "attach: %v\n", err)
+		return 1
+	}
+	if opts.progress > 0 {
+		opts.progressKey = key
+	}
+
+	report(runResult{
+		Command:     display,
+		Duration:    time.Since(start),
+		ExitCode:    exitCode,
+		ExitUnknown: !known,
+		Labels:      opts.labels,
+	}, opts)
+	return exitCode
+}
+
+// parsePID parses the argument of reporter attach.
+func parsePID(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("usage: reporter attach [flags] <pid>")
+	}
+	pid, err := strconv.Atoi(args[0])
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid %q", args[0])
+	}
+	return pid, nil
+}
+
+// inspectProcessPS describes pid with ps(1), which every Unix has.
+func inspectProcessPS(pid int) (attachedProcess, error) {
+	out, err := exec.Command("ps", "-o", "etime=", "-o", "command=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return attachedProcess{}, fmt.Errorf("no process with pid %d", pid)
+	}
+	etime, command, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	p := attachedProcess{Command: strings.TrimSpace(command)}
+	if elapsed, err := parseElapsed(etime); err == nil {
+		p.Started = time.Now().Add(-elapsed)
+	}
+	return p, nil
+}
+
+// parseElapsed parses the elapsed time ps prints as [[dd-]hh:]mm:ss.
+func parseElapsed(s string) (time.Duration, error) {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed time %q", s)
+		}
+		days, s = n, rest
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid elapsed time %q", s)
+	}
+	var secs int
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed time %q", s)
+		}
+		secs = secs*60 + n
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(secs)*time.Second, nil
+}
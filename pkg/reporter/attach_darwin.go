@@ -0,0 +1,38 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+func inspectProcess(pid int) (attachedProcess, error) {
+	return inspectProcessPS(pid)
+}
+
+// waitProcessExit waits for pid to exit with kqueue. macOS tells only the
+// parent a process's exit status, so it is never known.
+func waitProcessExit(pid int) (exitCode int, known bool, err error) {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return 0, false, err
+	}
+	defer syscall.Close(kq)
+	var ev syscall.Kevent_t
+	syscall.SetKevent(&ev, pid, syscall.EVFILT_PROC, syscall.EV_ADD|syscall.EV_ONESHOT)
+	ev.Fflags = syscall.NOTE_EXIT
+	events := make([]syscall.Kevent_t, 1)
+	for {
+		_, err := syscall.Kevent(kq, []syscall.Kevent_t{ev}, events, nil)
+		switch {
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.ESRCH):
+			// It exited between inspectProcess and now.
+			return 0, false, nil
+		case err != nil:
+			return 0, false, fmt.Errorf("waiting for pid %d: %w", pid, err)
+		}
+		return 0, false, nil
+	}
+}
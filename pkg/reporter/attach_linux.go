@@ -0,0 +1,134 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sysPidfdOpen is pidfd_open(2), added in Linux 5.3.
+const sysPidfdOpen = 434
+
+// clockTicks is USER_HZ, the unit of the start time in /proc/<pid>/stat;
+// it is 100 on every architecture Go supports.
+const clockTicks = 100
+
+// inspectProcess describes pid from /proc.
+func inspectProcess(pid int) (attachedProcess, error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	fields, err := procStat(pid)
+	if err != nil {
+		return attachedProcess{}, fmt.Errorf("no process with pid %d", pid)
+	}
+	var p attachedProcess
+	if cmdline, err := os.ReadFile(dir + "/cmdline"); err == nil {
+		p.Command = strings.Join(strings.FieldsFunc(string(cmdline), func(r rune) bool { return r == 0 }), " ")
+	}
+	if p.Command == "" {
+		if comm, err := os.ReadFile(dir + "/comm"); err == nil {
+			p.Command = strings.TrimSpace(string(comm))
+		}
+	}
+	// starttime, field 22, is in clock ticks since boot.
+	if ticks, err := strconv.ParseInt(fields[22-3], 10, 64); err == nil {
+		if boot, err := bootTime(); err == nil {
+			p.Started = boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+		}
+	}
+	return p, nil
+}
+
+// procStat returns the fields of /proc/<pid>/stat after the command name,
+// so that field n of proc(5) is at index n-3.
+func procStat(pid int) ([]string, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return nil, err
+	}
+	// The command name is in parentheses and may contain spaces and ")".
+	i := strings.LastIndexByte(string(b), ')')
+	if i < 0 {
+		return nil, errors.New("malformed stat")
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 22-3+1 {
+		return nil, errors.New("malformed stat")
+	}
+	return fields, nil
+}
+
+// bootTime reads when the system booted from /proc/stat.
+func bootTime() (time.Time, error) {
+	b, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if rest, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("no btime in /proc/stat")
+}
+
+// waitProcessExit waits for pid to exit, with a pidfd where the kernel has
+// them and by polling otherwise. Only the parent of a process can wait for
+// its status, so it is known only if the process is caught as a zombie,
+// before its parent reaps it.
+func waitProcessExit(pid int) (exitCode int, known bool, err error) {
+	fd, _, errno := syscall.Syscall(sysPidfdOpen, uintptr(pid), 0, 0)
+	if errno == syscall.ESRCH {
+		return 0, false, fmt.Errorf("no process with pid %d", pid)
+	}
+	if errno == 0 {
+		defer syscall.Close(int(fd))
+		if err := waitReadable(int(fd)); err != nil {
+			return 0, false, err
+		}
+	} else {
+		pollProcessExit(pid, time.Second)
+	}
+	exitCode, known = zombieExitCode(pid)
+	return exitCode, known, nil
+}
+
+// zombieExitCode returns the exit status of pid if it has exited and not
+// yet been reaped, from field 52 of its stat (Linux 3.5 and later).
+func zombieExitCode(pid int) (int, bool) {
+	fields, err := procStat(pid)
+	if err != nil || fields[0] != "Z" || len(fields) < 52-3+1 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(fields[52-3])
+	if err != nil {
+		return 0, false
+	}
+	return waitExitCode(syscall.WaitStatus(n)), true
+}
+
+// waitReadable blocks until fd is readable, as a pidfd is once its process
+// exits.
+func waitReadable(fd int) error {
+	ep, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(ep)
+	if err := syscall.EpollCtl(ep, syscall.EPOLL_CTL_ADD, fd, &syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}); err != nil {
+		return err
+	}
+	events := make([]syscall.EpollEvent, 1)
+	for {
+		if _, err := syscall.EpollWait(ep, events, -1); err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package reporter
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachLinux(t *testing.T) {
+	// The test is the parent and waits only at the end, so the exited
+	// command stays a zombie whose status can be read.
+	cmd := exec.Command("sh", "-c", "sleep 0.2; exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	p, err := inspectProcess(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Command != "sh -c sleep 0.2; exit 3" {
+		t.Errorf("Command = %q", p.Command)
+	}
+	if age := time.Since(p.Started); age < -time.Second || age > 5*time.Second {
+		t.Errorf("Started = %v, %s ago", p.Started, age)
+	}
+	code, known, err := waitProcessExit(cmd.Process.Pid)
+	if err != nil || !known || code != 3 {
+		t.Errorf("waitProcessExit() = %d, %t, %v; want 3, true", code, known, err)
+	}
+
+	cmd = exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+	if _, err := inspectProcess(cmd.Process.Pid); err == nil || !strings.Contains(err.Error(), "no process") {
+		t.Errorf("inspectProcess() of a reaped process = %v", err)
+	}
+}
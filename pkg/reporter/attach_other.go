@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !windows
+
+package reporter
+
+import (
+	"fmt"
+	"time"
+)
+
+func inspectProcess(pid int) (attachedProcess, error) {
+	return inspectProcessPS(pid)
+}
+
+// waitProcessExit polls for pid to exit. Only the parent of a process can
+// learn its exit status, so it is never known.
+func waitProcessExit(pid int) (exitCode int, known bool, err error) {
+	if !processExists(pid) {
+		return 0, false, fmt.Errorf("no process with pid %d", pid)
+	}
+	pollProcessExit(pid, time.Second)
+	return 0, false, nil
+}
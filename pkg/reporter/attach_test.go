@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseElapsed(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "00:07", want: 7 * time.Second},
+		{in: "12:34", want: 12*time.Minute + 34*time.Second},
+		{in: "01:02:03", want: time.Hour + 2*time.Minute + 3*time.Second},
+		{in: "2-03:04:05", want: 51*time.Hour + 4*time.Minute + 5*time.Second},
+		{in: "7", wantErr: true},
+		{in: "x-01:02", wantErr: true},
+		{in: "", wantErr: true},
+	} {
+		got, err := parseElapsed(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseElapsed(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParsePID(t *testing.T) {
+	if pid, err := parsePID([]string{"4242"}); err != nil || pid != 4242 {
+		t.Errorf("parsePID(4242) = %d, %v", pid, err)
+	}
+	for _, args := range [][]string{nil, {"0"}, {"-3"}, {"make"}, {"1", "2"}} {
+		if _, err := parsePID(args); err == nil {
+			t.Errorf("parsePID(%q) succeeded", args)
+		}
+	}
+}
+
+func TestResultStatus(t *testing.T) {
+	for _, tt := range []struct {
+		res  runResult
+		want string
+	}{
+		{runResult{}, "succeeded"},
+		{runResult{ExitCode: 2}, "failed (exit 2)"},
+		{runResult{ExitCode: exitTimedOut, TimedOut: true}, "timed out"},
+		{runResult{ExitUnknown: true}, "finished (exit status unknown)"},
+		{runResult{ExitCode: 130, Signal: syscall.SIGINT}, "interrupted (SIGINT)"},
+		{runResult{ExitCode: 137, Signal: syscall.SIGKILL}, "killed by SIGKILL (OOM?)"},
+		{runResult{ExitCode: 143, Signal: syscall.SIGTERM}, "killed by SIGTERM"},
+		{runResult{ExitCode: 190, Signal: 62}, "killed by signal 62"},
+		{runResult{ExitCode: exitTimedOut, TimedOut: true, Signal: syscall.SIGKILL}, "timed out"},
+	} {
+		if got := resultStatus(tt.res); got != tt.want {
+			t.Errorf("resultStatus(%+v) = %q, want %q", tt.res, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// processQueryLimitedInformation is PROCESS_QUERY_LIMITED_INFORMATION.
+const processQueryLimitedInformation = 0x1000
+
+// inspectProcess finds when pid started. Windows keeps a process's command
+// line in its own memory, so it is not shown; pass -cmd to name it.
+func inspectProcess(pid int) (attachedProcess, error) {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return attachedProcess{}, fmt.Errorf("no process with pid %d", pid)
+	}
+	defer syscall.CloseHandle(h)
+	var created, exited, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &created, &exited, &kernel, &user); err != nil {
+		return attachedProcess{}, nil
+	}
+	return attachedProcess{Started: time.Unix(0, created.Nanoseconds())}, nil
+}
+
+// waitProcessExit waits for pid to exit. Windows lets any process that can
+// open another wait for it and read its exit code.
+func waitProcessExit(pid int) (exitCode int, known bool, err error) {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, false, fmt.Errorf("no process with pid %d", pid)
+	}
+	state, err := p.Wait()
+	if err != nil {
+		return 0, false, fmt.Errorf("waiting for pid %d: %w", pid, err)
+	}
+	return state.ExitCode(), true, nil
+}
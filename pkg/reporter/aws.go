@@ -0,0 +1,285 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWS providers find credentials the way the AWS CLI and SDKs do, in this
+// order: the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment
+// variables, the AWS_PROFILE (or default) profile in ~/.aws/credentials, the
+// ECS container credentials endpoint, and the EC2 instance's role through
+// IMDSv2. SSO, assumed roles, and credential_process are not supported.
+
+// awsCredentials are the keys requests are signed with.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // set for temporary credentials
+}
+
+// awsMetadataEndpoint is the EC2 instance metadata service;
+// AWS_EC2_METADATA_SERVICE_ENDPOINT overrides it.
+const awsMetadataEndpoint = "http://169.254.169.254"
+
+// awsContainerHost serves AWS_CONTAINER_CREDENTIALS_RELATIVE_URI in ECS.
+const awsContainerHost = "http://169.254.170.2"
+
+// awsMetadataTimeout bounds each request to the metadata services, which
+// do not answer at all off AWS.
+const awsMetadataTimeout = time.Second
+
+// awsCredentialChain returns the first credentials found (see above).
+func awsCredentialChain(ctx context.Context, getenv func(string) string) (awsCredentials, error) {
+	if id, secret := getenv("AWS_ACCESS_KEY_ID"), getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCredentials{id, secret, getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	profile := awsProfile(getenv)
+	if section, err := readAWSFile(awsFile(getenv, "AWS_SHARED_CREDENTIALS_FILE", "credentials"), profile); err != nil {
+		return awsCredentials{}, err
+	} else if section["aws_access_key_id"] != "" {
+		return awsCredentials{section["aws_access_key_id"], section["aws_secret_access_key"], section["aws_session_token"]}, nil
+	}
+	if uri := awsContainerCredentialsURI(getenv); uri != "" {
+		header := http.Header{}
+		if token := getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+			header.Set("Authorization", token)
+		}
+		return awsFetchCredentials(ctx, uri, header)
+	}
+	if strings.EqualFold(getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return awsCredentials{}, errors.New("no AWS credentials found")
+	}
+	imds := awsInstanceMetadata{endpoint: strings.TrimSuffix(getenvOr(getenv, "AWS_EC2_METADATA_SERVICE_ENDPOINT", awsMetadataEndpoint), "/")}
+	role, err := imds.get(ctx, "/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials found (not on EC2, or no instance role: %v)", err)
+	}
+	role, _, _ = strings.Cut(strings.TrimSpace(role), "\n")
+	return awsFetchCredentials(ctx, imds.endpoint+"/latest/meta-data/iam/security-credentials/"+role, http.Header{"X-Aws-Ec2-Metadata-Token": {imds.token}})
+}
+
+// awsRegion returns the region from AWS_REGION, AWS_DEFAULT_REGION, the
+// profile in ~/.aws/config, or the EC2 instance's placement, in that order,
+// or "" if none is set.
+func awsRegion(ctx context.Context, getenv func(string) string) string {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := getenv(name); region != "" {
+			return region
+		}
+	}
+	profile := awsProfile(getenv)
+	if profile != "default" {
+		profile = "profile " + profile
+	}
+	if section, _ := readAWSFile(awsFile(getenv, "AWS_CONFIG_FILE", "config"), profile); section["region"] != "" {
+		return section["region"]
+	}
+	if strings.EqualFold(getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return ""
+	}
+	imds := awsInstanceMetadata{endpoint: strings.TrimSuffix(getenvOr(getenv, "AWS_EC2_METADATA_SERVICE_ENDPOINT", awsMetadataEndpoint), "/")}
+	region, _ := imds.get(ctx, "/latest/meta-data/placement/region")
+	return strings.TrimSpace(region)
+}
+
+func awsProfile(getenv func(string) string) string {
+	return getenvOr(getenv, "AWS_PROFILE", "default")
+}
+
+// awsFile returns the path in the environment variable env, or ~/.aws/name.
+func awsFile(getenv func(string) string, env, name string) string {
+	if path := getenv(env); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readAWSFile returns the keys in the [section] of the INI file at path,
+// or nil if the file or section does not exist.
+func readAWSFile(path, section string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var keys map[string]string
+	in := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[':
+			in = strings.TrimSpace(strings.Trim(line, "[]")) == section
+			if in && keys == nil {
+				keys = map[string]string{}
+			}
+		case in:
+			if k, v, ok := strings.Cut(line, "="); ok {
+				keys[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// awsContainerCredentialsURI returns the ECS credentials endpoint, or "".
+func awsContainerCredentialsURI(getenv func(string) string) string {
+	if rel := getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		return awsContainerHost + rel
+	}
+	return getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+}
+
+// awsFetchCredentials reads temporary credentials in the format the ECS and
+// EC2 metadata services share.
+func awsFetchCredentials(ctx context.Context, uri string, header http.Header) (awsCredentials, error) {
+	body, err := awsMetadataRequest(ctx, http.MethodGet, uri, header)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("fetching AWS credentials: %w", err)
+	}
+	var creds struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+	}
+	if err := json.Unmarshal([]byte(body), &creds); err != nil || creds.AccessKeyID == "" {
+		return awsCredentials{}, fmt.Errorf("fetching AWS credentials: unexpected response from %s", uri)
+	}
+	return awsCredentials{creds.AccessKeyID, creds.SecretAccessKey, creds.Token}, nil
+}
+
+// awsInstanceMetadata reads from IMDSv2, which takes a session token.
+type awsInstanceMetadata struct {
+	endpoint string
+	token    string
+}
+
+func (m *awsInstanceMetadata) get(ctx context.Context, path string) (string, error) {
+	if m.token == "" {
+		token, err := awsMetadataRequest(ctx, http.MethodPut, m.endpoint+"/latest/api/token",
+			http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"}})
+		if err != nil {
+			return "", err
+		}
+		m.token = token
+	}
+	return awsMetadataRequest(ctx, http.MethodGet, m.endpoint+path, http.Header{"X-Aws-Ec2-Metadata-Token": {m.token}})
+}
+
+func awsMetadataRequest(ctx context.Context, method, uri string, header http.Header) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, awsMetadataTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", uri, resp.Status)
+	}
+	return string(body), nil
+}
+
+// signAWS signs req, whose body is body, for service in region with AWS
+// Signature Version 4, as of now.
+func signAWS(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	stamp, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", stamp)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "host" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.Query().Encode(), canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{day, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// getenvOr returns getenv(name), or def if it is empty.
+func getenvOr(getenv func(string) string, name, def string) string {
+	if v := getenv(name); v != "" {
+		return v
+	}
+	return def
+}
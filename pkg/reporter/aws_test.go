@@ -0,0 +1,137 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignAWS checks the get-vanilla case of the AWS Signature Version 4
+// test suite.
+func TestSignAWS(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWS(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+
+	creds.SessionToken = "FQoG"
+	signAWS(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "FQoG" {
+		t.Errorf("X-Amz-Security-Token = %q, want FQoG", got)
+	}
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("session token is not signed: %s", got)
+	}
+}
+
+func TestAWSCredentialChain(t *testing.T) {
+	dir := t.TempDir()
+	credentials := filepath.Join(dir, "credentials")
+	writeFile(t, credentials, "[default]\naws_access_key_id = AKIDFILE\naws_secret_access_key = filesecret\n\n"+
+		"[ci]\n# deploy keys\naws_access_key_id=AKIDCI\naws_secret_access_key=cisecret\naws_session_token=citoken\n")
+
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			w.Write([]byte("imds-token"))
+		case r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token":
+			http.Error(w, "no token", http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("batch-role"))
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/batch-role":
+			w.Write([]byte(`{"Code":"Success","AccessKeyId":"ASIAEC2","SecretAccessKey":"ec2secret","Token":"ec2token"}`))
+		case r.URL.Path == "/latest/meta-data/placement/region":
+			w.Write([]byte("eu-west-1"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer imds.Close()
+	container := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ecs-auth" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"AccessKeyId":"ASIAECS","SecretAccessKey":"ecssecret","Token":"ecstoken"}`))
+	}))
+	defer container.Close()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want awsCredentials
+	}{
+		{
+			name: "environment",
+			env:  map[string]string{"AWS_ACCESS_KEY_ID": "AKIDENV", "AWS_SECRET_ACCESS_KEY": "envsecret", "AWS_SHARED_CREDENTIALS_FILE": credentials},
+			want: awsCredentials{"AKIDENV", "envsecret", ""},
+		},
+		{
+			name: "default profile",
+			env:  map[string]string{"AWS_SHARED_CREDENTIALS_FILE": credentials},
+			want: awsCredentials{"AKIDFILE", "filesecret", ""},
+		},
+		{
+			name: "named profile",
+			env:  map[string]string{"AWS_SHARED_CREDENTIALS_FILE": credentials, "AWS_PROFILE": "ci"},
+			want: awsCredentials{"AKIDCI", "cisecret", "citoken"},
+		},
+		{
+			name: "container",
+			env: map[string]string{"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "none"),
+				"AWS_CONTAINER_CREDENTIALS_FULL_URI": container.URL, "AWS_CONTAINER_AUTHORIZATION_TOKEN": "ecs-auth"},
+			want: awsCredentials{"ASIAECS", "ecssecret", "ecstoken"},
+		},
+		{
+			name: "instance role",
+			env:  map[string]string{"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "none"), "AWS_EC2_METADATA_SERVICE_ENDPOINT": imds.URL},
+			want: awsCredentials{"ASIAEC2", "ec2secret", "ec2token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := awsCredentialChain(context.Background(), func(k string) string { return tt.env[k] })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("awsCredentialChain() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	env := map[string]string{"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "none"), "AWS_EC2_METADATA_DISABLED": "true"}
+	if _, err := awsCredentialChain(context.Background(), func(k string) string { return env[k] }); err == nil {
+		t.Error("awsCredentialChain() without credentials succeeded")
+	}
+
+	t.Run("region", func(t *testing.T) {
+		config := filepath.Join(dir, "config")
+		writeFile(t, config, "[default]\nregion = us-east-2\n[profile ci]\nregion = ap-south-1\n")
+		for _, tt := range []struct {
+			env  map[string]string
+			want string
+		}{
+			{map[string]string{"AWS_REGION": "us-west-2", "AWS_DEFAULT_REGION": "us-east-1"}, "us-west-2"},
+			{map[string]string{"AWS_DEFAULT_REGION": "us-east-1", "AWS_CONFIG_FILE": config}, "us-east-1"},
+			{map[string]string{"AWS_CONFIG_FILE": config}, "us-east-2"},
+			{map[string]string{"AWS_CONFIG_FILE": config, "AWS_PROFILE": "ci"}, "ap-south-1"},
+			{map[string]string{"AWS_CONFIG_FILE": filepath.Join(dir, "none"), "AWS_EC2_METADATA_SERVICE_ENDPOINT": imds.URL}, "eu-west-1"},
+			{map[string]string{"AWS_CONFIG_FILE": filepath.Join(dir, "none"), "AWS_EC2_METADATA_DISABLED": "true"}, ""},
+		} {
+			if got := awsRegion(context.Background(), func(k string) string { return tt.env[k] }); got != tt.want {
+				t.Errorf("awsRegion(%v) = %q, want %q", tt.env, got, tt.want)
+			}
+		}
+	})
+}
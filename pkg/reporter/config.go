@@ -0,0 +1,618 @@
+package reporter
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// projectConfigName is the file reporter looks for in the working directory
+// and its parents, so repositories can commit their own defaults.
+const projectConfigName = ".reporter.toml"
+
+// systemConfigPath holds fleet-wide defaults managed by administrators. It is a
+// variable so tests can point it elsewhere.
+var systemConfigPath = "/etc/reporter/config.toml"
+
+type configKind int
+
+const (
+	kindString configKind = iota
+	kindBool
+	kindDuration
+	kindStringList
+	kindInt
+	kindThreshold // a duration string or "auto"
+	kindTableList // an array of tables, [[name]]
+)
+
+func (k configKind) String() string {
+	switch k {
+	case kindBool:
+		return "boolean"
+	case kindDuration:
+		return "duration string"
+	case kindStringList:
+		return "list of strings"
+	case kindInt:
+		return "non-negative integer"
+	case kindThreshold:
+		return `duration string or "auto"`
+	case kindTableList:
+		return "array of tables"
+	default:
+		return "string"
+	}
+}
+
+// configKeys lists the settings recognised in config files and the kind of
+// value each expects.
+var configKeys = map[string]configKind{
+	"threshold":         kindThreshold,
+	"always":            kindBool,
+	"title":             kindString,
+	"title_prefix":      kindString,
+	"title_template":    kindString,
+	"body_template":     kindString,
+	"no_bell":           kindBool,
+	"desktop":           kindString,
+	"even_if_focused":   kindBool,
+	"terminal_notify":   kindString,
+	"multiplexer":       kindString,
+	"quiet":             kindBool,
+	"push_url":          kindString,
+	pushURLsKey:         kindStringList,
+	"push_provider":     kindString,
+	"push_click":        kindString,
+	"push_token":        kindString,
+	"push_secret":       kindString,
+	"push_format":       kindString,
+	"push_when_idle":    kindDuration,
+	"agent":             kindString,
+	"payload_version":   kindInt,
+	"energy":            kindBool,
+	"resources":         kindBool,
+	"context":           kindBool,
+	"telemetry":         kindBool,
+	"delivery_summary":  kindBool,
+	"notify_on":         kindString,
+	"only_failures":     kindBool,
+	"ignore_interrupts": kindBool,
+	"exit_codes":        kindString,
+	"success_every":     kindDuration,
+	"progress":          kindDuration,
+	"heartbeat":         kindDuration,
+	"stall":             kindDuration,
+	"max_duration":      kindDuration,
+	"kill_after":        kindDuration,
+	"retries":           kindInt,
+	"retry_delay":       kindDuration,
+	"pty":               kindBool,
+	"wait_descendants":  kindBool,
+	"pipefail":          kindBool,
+	"capture_output":    kindInt,
+	"block":             kindStringList,
+	"notify_allow":      kindStringList,
+	"notify_deny":       kindStringList,
+	"redact":            kindStringList,
+	"no_redact":         kindBool,
+	tierTable:           kindTableList,
+	"quiet_hours":       kindStringList,
+	"quiet_hours_push":  kindString,
+	"block_action":      kindString,
+	"journal":           kindString,
+	"journal_format":    kindString,
+	"no_history":        kindBool,
+	"notification_log":  kindBool,
+	"history_store":     kindString,
+
+	// Run metrics; see statsd.go and prometheus.go.
+	"statsd":                 kindString,
+	"statsd_prefix":          kindString,
+	"statsd_tags":            kindBool,
+	"prometheus_pushgateway": kindString,
+	"prometheus_job":         kindString,
+	"prometheus_textfile":    kindString,
+
+	// Per-command thresholds; see threshold.go.
+	commandThresholdTable: kindTableList,
+
+	// Anonymous timing baselines; see export.go.
+	"export_salt": kindString,
+
+	// Sentry Cron Monitor check-ins; see sentry.go.
+	"sentry_dsn":         kindString,
+	"sentry_environment": kindString,
+
+	// healthchecks.io pings; see healthcheck.go.
+	"healthcheck_url": kindString,
+
+	// locked is only honoured in the system config; it names keys that
+	// user config, project config, flags, and environment cannot override.
+	"locked": kindStringList,
+}
+
+// labelsTable prefixes keys from the [labels] table, whose entries are
+// arbitrary labels applied to every run.
+const labelsTable = "labels."
+
+// projectConfigKeys are the keys a project config may set: when a run is
+// worth a notification and how it reads. A .reporter.toml comes with
+// whatever repository was cloned, so it may not choose where notifications
+// go, what reporter writes or runs, or what is kept out of them; such keys
+// are ignored there with a warning. Labels are allowed too.
+var projectConfigKeys = map[string]bool{
+	"threshold":           true,
+	"always":              true,
+	"title":               true,
+	"title_prefix":        true,
+	"title_template":      true,
+	"body_template":       true,
+	"no_bell":             true,
+	"energy":              true,
+	"resources":           true,
+	"context":             true,
+	"notify_on":           true,
+	"only_failures":       true,
+	"ignore_interrupts":   true,
+	"exit_codes":          true,
+	"success_every":       true,
+	"progress":            true,
+	"heartbeat":           true,
+	"stall":               true,
+	commandThresholdTable: true,
+}
+
+// config holds settings loaded from config files.
+type config struct {
+	values   map[string]any
+	sources  map[string]string // key -> file that set it
+	locked   map[string]bool
+	warnings []string
+}
+
+// loadConfig merges the system config, the user config, and the project config
+// found by walking up from dir, in that order, so later files override earlier
+// ones key by key. Missing files are skipped.
+func loadConfig(dir string) (*config, error) {
+	cfg := &config{values: map[string]any{}, sources: map[string]string{}, locked: map[string]bool{}}
+	for _, f := range configFiles(dir) {
+		if f.path == "" {
+			continue
+		}
+		if err := cfg.loadFile(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// userConfigPath returns $XDG_CONFIG_HOME/reporter/config.toml, falling back
+// to ~/.config when XDG_CONFIG_HOME is unset.
+func userConfigPath() string {
+	dir := xdgDir("XDG_CONFIG_HOME", ".config")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.toml")
+}
+
+// findProjectConfig walks up from dir looking for a .reporter.toml file and
+// returns its path, or "" if none exists.
+func findProjectConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (c *config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for key, val := range values {
+		if err := checkConfigValue(key, val); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if _, ok := values["locked"]; ok && path != systemConfigPath {
+		return fmt.Errorf("%s: locked may only be set in %s", path, systemConfigPath)
+	}
+	project := filepath.Base(path) == projectConfigName
+	for key, val := range values {
+		if project && !projectConfigKeys[key] && !strings.HasPrefix(key, labelsTable) {
+			c.warnings = append(c.warnings, fmt.Sprintf("%s may not be set in a project config; ignoring value from %s", key, path))
+			continue
+		}
+		if c.locked[key] {
+			c.warnings = append(c.warnings, fmt.Sprintf("%s is locked by %s; ignoring value from %s", key, c.sources[key], path))
+			continue
+		}
+		c.values[key] = val
+		c.sources[key] = path
+	}
+	for _, key := range c.strings("locked") {
+		c.locked[key] = true
+	}
+	return nil
+}
+
+// enforceLocks resets flags whose config key is locked to the configured value,
+// overriding both command-line values and environment-derived defaults.
+// Flag names map to keys by replacing "-" with "_".
+func (c *config) enforceLocks(fset *flag.FlagSet) {
+	explicit := map[string]bool{}
+	fset.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	fset.VisitAll(func(f *flag.Flag) {
+		key := strings.ReplaceAll(f.Name, "-", "_")
+		val, ok := c.values[key]
+		if !c.locked[key] || !ok {
+			return
+		}
+		if explicit[f.Name] {
+			c.warnings = append(c.warnings, fmt.Sprintf("-%s is locked by %s; ignoring command-line value", f.Name, c.sources[key]))
+		}
+		// Repeatable flags start over so the locked value replaces,
+		// rather than joins, what was given.
+		if r, ok := f.Value.(interface{ reset() }); ok {
+			r.reset()
+		}
+		_ = fset.Set(f.Name, fmt.Sprint(val))
+	})
+}
+
+func checkConfigValue(key string, val any) error {
+	if name, ok := strings.CutPrefix(key, labelsTable); ok {
+		if err := checkLabelKey(name); err != nil {
+			return err
+		}
+		if err := checkConfigKind(kindString, val); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	}
+	if _, known := configKeys[key]; !known && strings.HasPrefix(key, pushTable) {
+		return checkPushOption(key, val)
+	}
+	kind, ok := configKeys[key]
+	if !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+	if err := checkConfigKind(kind, val); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	switch key {
+	case "locked":
+		for _, item := range val.([]any) {
+			if _, known := configKeys[item.(string)]; !known {
+				return fmt.Errorf("locked: unknown key %q", item)
+			}
+		}
+	case tierTable:
+		if err := checkTiers(val.([]map[string]any)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case commandThresholdTable:
+		if err := checkCommandThresholds(val.([]map[string]any)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case "quiet_hours":
+		var entries []string
+		for _, item := range val.([]any) {
+			entries = append(entries, item.(string))
+		}
+		if _, err := parseQuietHours(entries); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case "quiet_hours_push":
+		if s := val.(string); s != quietHoursDowngrade && s != quietHoursSuppress {
+			return fmt.Errorf("%s: must be %q or %q", key, quietHoursDowngrade, quietHoursSuppress)
+		}
+	case "terminal_notify":
+		if err := checkTerminalNotify(val.(string)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case "multiplexer":
+		if err := checkMultiplexerMode(val.(string)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case "block", "notify_allow", "notify_deny":
+		for _, item := range val.([]any) {
+			if _, err := regexp.Compile(item.(string)); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %v", key, item, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConfigKind reports whether val, as parsed from TOML, is of kind.
+func checkConfigKind(kind configKind, val any) error {
+	mismatch := fmt.Errorf("expected %s", kind)
+	switch kind {
+	case kindStringList:
+		list, ok := val.([]any)
+		if !ok {
+			return mismatch
+		}
+		for _, item := range list {
+			if _, ok := item.(string); !ok {
+				return mismatch
+			}
+		}
+	case kindInt:
+		if n, ok := val.(int64); !ok || n < 0 {
+			return mismatch
+		}
+	case kindTableList:
+		if _, ok := val.([]map[string]any); !ok {
+			return mismatch
+		}
+	case kindBool:
+		if _, ok := val.(bool); !ok {
+			return mismatch
+		}
+	case kindDuration, kindThreshold:
+		s, ok := val.(string)
+		if !ok {
+			return mismatch
+		}
+		if kind == kindThreshold && s == thresholdAuto {
+			break
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return err
+		}
+	default:
+		if _, ok := val.(string); !ok {
+			return mismatch
+		}
+	}
+	return nil
+}
+
+func (c *config) string(key, def string) string {
+	if s, ok := c.values[key].(string); ok {
+		return s
+	}
+	return def
+}
+
+// envString returns the first of the environment variables envs that is
+// set, else key's value. enforceLocks only covers keys with a flag, so a
+// value locked in the system config is returned here whatever envs say.
+func (c *config) envString(key, def string, envs ...string) string {
+	if c.locked[key] {
+		return c.string(key, def)
+	}
+	for _, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return c.string(key, def)
+}
+
+func (c *config) strings(key string) []string {
+	list, _ := c.values[key].([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// labels returns the entries of the [labels] table.
+func (c *config) labels() map[string]string {
+	out := map[string]string{}
+	for key, val := range c.values {
+		if name, ok := strings.CutPrefix(key, labelsTable); ok {
+			out[name], _ = val.(string)
+		}
+	}
+	return out
+}
+
+// table returns the values whose keys start with prefix, keyed by the rest of
+// the key.
+func (c *config) table(prefix string) map[string]any {
+	out := map[string]any{}
+	for key, val := range c.values {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			out[name] = val
+		}
+	}
+	return out
+}
+
+func (c *config) int(key string, def int) int {
+	if n, ok := c.values[key].(int64); ok {
+		return int(n)
+	}
+	return def
+}
+
+func (c *config) bool(key string, def bool) bool {
+	if b, ok := c.values[key].(bool); ok {
+		return b
+	}
+	return def
+}
+
+// runConfig implements `reporter config`, which shows what the config files
+// set and where they are.
+func runConfig(args []string) int {
+	fset := flag.NewFlagSet("config", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter config [show|paths|keys]")
+		fmt.Fprintln(fset.Output(), "Show the settings from the config files and which file set each (show, the default),")
+		fmt.Fprintln(fset.Output(), "the files read in this directory (paths), or the keys they may set (keys).")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	action := "show"
+	switch fset.NArg() {
+	case 0:
+	case 1:
+		action = fset.Arg(0)
+	default:
+		fset.Usage()
+		return 2
+	}
+	cwd, _ := os.Getwd()
+	switch action {
+	case "show":
+		cfg, err := loadConfig(cwd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			return 1
+		}
+		for _, w := range cfg.warnings {
+			fmt.Fprintf(os.Stderr, "[policy] %s\n", w)
+		}
+		printConfig(os.Stdout, cfg)
+	case "paths":
+		printConfigPaths(os.Stdout, cwd)
+	case "keys":
+		printConfigKeys(os.Stdout)
+	default:
+		fset.Usage()
+		return 2
+	}
+	return 0
+}
+
+// printConfig lists every key set in cfg with its value and the file that
+// set it.
+func printConfig(w io.Writer, cfg *config) {
+	if len(cfg.values) == 0 {
+		fmt.Fprintln(w, "No settings in the config files; see reporter config paths.")
+		return
+	}
+	keys := make([]string, 0, len(cfg.values))
+	for key := range cfg.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, key := range keys {
+		source := shortenHome(cfg.sources[key])
+		if cfg.locked[key] {
+			source += " (locked)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, formatConfigValue(cfg.values[key]), source)
+	}
+	tw.Flush()
+}
+
+// formatConfigValue renders a value parsed from TOML back in TOML syntax,
+// with arrays of tables as inline tables.
+func formatConfigValue(val any) string {
+	switch v := val.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatConfigValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case []map[string]any:
+		parts := make([]string, len(v))
+		for i, table := range v {
+			parts[i] = formatConfigValue(table)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			keys[i] = key + " = " + formatConfigValue(v[key])
+		}
+		return "{" + strings.Join(keys, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// configFile is one of the config files loadConfig reads.
+type configFile struct {
+	name string // system, user, or project
+	path string // "" if there is no project config
+}
+
+// configFiles returns the config files read in dir, in the order they apply.
+func configFiles(dir string) []configFile {
+	return []configFile{
+		{"system", systemConfigPath},
+		{"user", userConfigPath()},
+		{"project", findProjectConfig(dir)},
+	}
+}
+
+// printConfigPaths lists the config files read in dir, in the order they
+// apply, and whether each exists.
+func printConfigPaths(w io.Writer, dir string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range configFiles(dir) {
+		status := ""
+		switch _, err := os.Stat(f.path); {
+		case f.path == "":
+			f.path, status = "none", "no "+projectConfigName+" here or in a parent directory"
+		case errors.Is(err, fs.ErrNotExist):
+			status = "not found"
+		case err != nil:
+			status = err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.name, shortenHome(f.path), status)
+	}
+	tw.Flush()
+}
+
+// printConfigKeys lists the keys config files may set and the kind of value
+// each takes.
+func printConfigKeys(w io.Writer) {
+	keys := make([]string, 0, len(configKeys))
+	for key := range configKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", key, configKeys[key])
+	}
+	fmt.Fprintf(tw, "%s<name>\t%s\n", labelsTable, kindString)
+	fmt.Fprintf(tw, "%s<provider>.<option>\t%s\n", pushTable, "depends on the option")
+	tw.Flush()
+}
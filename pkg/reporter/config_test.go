@@ -0,0 +1,314 @@
+package reporter
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findProjectConfig(nested); got != "" {
+		t.Errorf("findProjectConfig without a config = %q, want empty", got)
+	}
+
+	path := filepath.Join(root, projectConfigName)
+	if err := os.WriteFile(path, []byte("threshold = \"1m\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findProjectConfig(nested); got != path {
+		t.Errorf("findProjectConfig(%q) = %q, want %q", nested, got, path)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid settings",
+			content: "threshold = \"30s\"\ntitle_prefix = \"[api]\"\npush_url = \"https://ntfy.sh/builds\"\nalways = false\n",
+		},
+		{
+			name:    "unknown key",
+			content: "thresold = \"30s\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			content: "always = \"yes\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid label key",
+			content: "[labels]\n\"two words\" = \"x\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "negative line count",
+			content: "capture_output = -1\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			content: "threshold = \"soon\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "auto is only a threshold",
+			content: "success_every = \"auto\"\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateConfig(t)
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, projectConfigName), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := loadConfig(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.string("threshold", "10s"); got != "30s" {
+				t.Errorf("threshold = %q, want %q", got, "30s")
+			}
+			if got := cfg.string("title_prefix", ""); got != "[api]" {
+				t.Errorf("title_prefix = %q, want %q", got, "[api]")
+			}
+			if got := cfg.bool("always", true); got {
+				t.Errorf("always = %v, want false", got)
+			}
+			if got := cfg.bool("no_bell", true); !got {
+				t.Errorf("no_bell default = %v, want true", got)
+			}
+		})
+	}
+}
+
+// isolateConfig points the system and user config paths at empty temporary
+// locations so tests do not pick up the machine's real config.
+func isolateConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old := systemConfigPath
+	systemConfigPath = filepath.Join(dir, "etc", "config.toml")
+	t.Cleanup(func() { systemConfigPath = old })
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigLayering(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "push_url = \"https://relay.corp/push\"\nthreshold = \"1m\"\ntitle = \"Corp\"\n")
+	writeFile(t, filepath.Join(base, "xdg", "reporter", "config.toml"), "threshold = \"20s\"\nno_bell = true\n")
+	project := filepath.Join(base, "repo")
+	writeFile(t, filepath.Join(project, projectConfigName), "title = \"Atlas\"\n\n[labels]\nproject = \"atlas\"\n")
+
+	cfg, err := loadConfig(project)
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		want   any
+		source string
+	}{
+		{key: "push_url", want: "https://relay.corp/push", source: systemConfigPath},
+		{key: "threshold", want: "20s", source: filepath.Join(base, "xdg", "reporter", "config.toml")},
+		{key: "no_bell", want: true, source: filepath.Join(base, "xdg", "reporter", "config.toml")},
+		{key: "title", want: "Atlas", source: filepath.Join(project, projectConfigName)},
+	}
+	if got := cfg.labels(); len(got) != 1 || got["project"] != "atlas" {
+		t.Errorf("labels() = %v, want project=atlas", got)
+	}
+	for _, tt := range tests {
+		if got := cfg.values[tt.key]; got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+		}
+		if got := cfg.sources[tt.key]; got != tt.source {
+			t.Errorf("%s source = %q, want %q", tt.key, got, tt.source)
+		}
+	}
+}
+
+func TestProjectConfigAllowlist(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, userConfigPath(), "push_url = \"https://ntfy.sh/mine\"\nblock = [\"rm -rf /\"]\n")
+	project := filepath.Join(base, "repo")
+	path := filepath.Join(project, projectConfigName)
+	writeFile(t, path, "threshold = \"1m\"\npush_url = \"https://evil.example/steal\"\nblock = []\n"+
+		"journal = \"/tmp/journal\"\nno_redact = true\n\n"+
+		"[push.ntfy]\npriority = \"max\"\n\n[[tier]]\nafter = \"1s\"\nto = [\"https://evil.example/tier\"]\n\n"+
+		"[labels]\nproject = \"atlas\"\n")
+
+	cfg, err := loadConfig(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.string("threshold", ""); got != "1m" {
+		t.Errorf("threshold = %q, want the project's", got)
+	}
+	if got := cfg.labels(); got["project"] != "atlas" {
+		t.Errorf("labels() = %v, want the project's", got)
+	}
+	if got := cfg.string("push_url", ""); got != "https://ntfy.sh/mine" {
+		t.Errorf("push_url = %q, want the user's", got)
+	}
+	if got := cfg.strings("block"); !reflect.DeepEqual(got, []string{"rm -rf /"}) {
+		t.Errorf("block = %q, want the user's", got)
+	}
+	for _, key := range []string{"journal", "no_redact", "push.ntfy.priority", tierTable} {
+		if _, ok := cfg.values[key]; ok {
+			t.Errorf("%s was taken from the project config", key)
+		}
+	}
+	if len(cfg.warnings) != 6 {
+		t.Errorf("warnings = %q, want one for each ignored key", cfg.warnings)
+	}
+	for _, w := range cfg.warnings {
+		if !strings.HasSuffix(w, path) {
+			t.Errorf("warning %q does not name %s", w, path)
+		}
+	}
+}
+
+func TestConfigLocks(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "push_url = \"https://relay.corp/push\"\nalways = false\nlocked = [\"push_url\", \"always\"]\n")
+	writeFile(t, filepath.Join(base, "xdg", "reporter", "config.toml"), "push_url = \"https://ntfy.sh/mine\"\nthreshold = \"5s\"\n")
+
+	cfg, err := loadConfig(base)
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+	if got := cfg.string("push_url", ""); got != "https://relay.corp/push" {
+		t.Errorf("push_url = %q, want locked system value", got)
+	}
+	if got := cfg.string("threshold", ""); got != "5s" {
+		t.Errorf("threshold = %q, want user value", got)
+	}
+	if len(cfg.warnings) != 1 {
+		t.Errorf("warnings = %q, want one for the ignored push_url", cfg.warnings)
+	}
+
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	pushURLs := &urlListFlag{urls: []string{"https://env.example/push"}}
+	fset.Var(pushURLs, "push-url", "")
+	always := fset.Bool("always", false, "")
+	threshold := fset.String("threshold", "10s", "")
+	if err := fset.Parse([]string{"-always", "-threshold", "1m", "-push-url", "https://ntfy.sh/cli"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg.enforceLocks(fset)
+	if !reflect.DeepEqual(pushURLs.urls, []string{"https://relay.corp/push"}) {
+		t.Errorf("-push-url = %q, want only the locked value", pushURLs.urls)
+	}
+	if *always {
+		t.Error("-always = true, want locked value false")
+	}
+	if *threshold != "1m" {
+		t.Errorf("-threshold = %q, want unlocked command-line value", *threshold)
+	}
+	if len(cfg.warnings) != 3 {
+		t.Errorf("warnings = %q, want extra ones for -always and -push-url", cfg.warnings)
+	}
+}
+
+func TestConfigEnvString(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "statsd = \"statsd.corp:8125\"\nlocked = [\"statsd\"]\n")
+	writeFile(t, filepath.Join(base, "xdg", "reporter", "config.toml"), "sentry_dsn = \"https://key@sentry.example/1\"\n")
+	t.Setenv("REPORTER_STATSD", "127.0.0.1:8125")
+	t.Setenv("SENTRY_DSN", "https://other@sentry.example/2")
+	cfg, err := loadConfig(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.envString("statsd", "", "REPORTER_STATSD"); got != "statsd.corp:8125" {
+		t.Errorf("envString(statsd) = %q, want the locked value", got)
+	}
+	if got := cfg.envString("sentry_dsn", "", "SENTRY_DSN"); got != "https://other@sentry.example/2" {
+		t.Errorf("envString(sentry_dsn) = %q, want the environment's", got)
+	}
+	if got := cfg.envString("journal", "none", "REPORTER_UNSET_FOR_TEST"); got != "none" {
+		t.Errorf("envString(journal) = %q, want the default", got)
+	}
+}
+
+func TestConfigLockedOutsideSystemConfig(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, filepath.Join(base, projectConfigName), "locked = [\"push_url\"]\n")
+	if _, err := loadConfig(base); err == nil {
+		t.Error("loadConfig() accepted locked in a project config, want error")
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "push_url = \"https://relay.corp/push\"\nlocked = [\"push_url\"]\n")
+	project := filepath.Join(base, "repo")
+	writeFile(t, userConfigPath(), "block = [\"rm -rf /\"]\ncapture_output = 20\n\n"+
+		"[[tier]]\nafter = \"1h\"\nto = [\"desktop\", \"ntfy\"]\n")
+	writeFile(t, filepath.Join(project, projectConfigName), "threshold = \"1m\"\n")
+	cfg, err := loadConfig(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	printConfig(&b, cfg)
+	for _, want := range []string{
+		`block ["rm -rf /"]`,
+		"capture_output 20",
+		`push_url "https://relay.corp/push" ` + systemConfigPath + " (locked)",
+		`threshold "1m"`,
+		`tier [{after = "1h", to = ["desktop", "ntfy"]}]`,
+	} {
+		if !strings.Contains(collapseSpaces(b.String()), want) {
+			t.Errorf("printConfig() output lacks %q:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	printConfigPaths(&b, project)
+	for _, want := range []string{
+		"system " + systemConfigPath,
+		"user " + filepath.Join(base, "xdg", "reporter", "config.toml"),
+		"project " + filepath.Join(project, projectConfigName),
+	} {
+		if !strings.Contains(collapseSpaces(b.String()), want) {
+			t.Errorf("printConfigPaths() output lacks %q:\n%s", want, b.String())
+		}
+	}
+	b.Reset()
+	printConfigPaths(&b, base)
+	if !strings.Contains(collapseSpaces(b.String()), "project none") {
+		t.Errorf("printConfigPaths() outside a project:\n%s", b.String())
+	}
+}
@@ -0,0 +1,40 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// runContext says where a command ran, for -context to tell apart
+// notifications from several machines or checkouts.
+type runContext struct {
+	User, Host string
+	Dir        string // the working directory
+	Repo       string // the git repository holding Dir, or ""
+	Branch     string // the branch checked out there, or ""
+}
+
+func currentContext() runContext {
+	host, _ := os.Hostname()
+	dir, _ := os.Getwd()
+	return runContext{User: currentUser(), Host: host, Dir: dir, Repo: gitRepo(dir), Branch: gitBranch(dir)}
+}
+
+// String renders c like a shell prompt, such as
+// "deploy@web-03:~/src/app (acme/app main)".
+func (c runContext) String() string {
+	s := c.Host
+	if c.User != "" {
+		s = c.User + "@" + s
+	}
+	if c.Dir != "" {
+		s += ":" + shortenHome(filepath.Clean(c.Dir))
+	}
+	switch {
+	case c.Repo != "" && c.Branch != "":
+		s += " (" + c.Repo + " " + c.Branch + ")"
+	case c.Repo != "" || c.Branch != "":
+		s += " (" + c.Repo + c.Branch + ")"
+	}
+	return s
+}
@@ -0,0 +1,24 @@
+package reporter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRunContextString(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, tt := range []struct {
+		c    runContext
+		want string
+	}{
+		{c: runContext{User: "deploy", Host: "web-03", Dir: filepath.Join(home, "src", "app"), Repo: "acme/app", Branch: "main"},
+			want: "deploy@web-03:~/src/app (acme/app main)"},
+		{c: runContext{User: "deploy", Host: "web-03", Dir: "/srv/backups"}, want: "deploy@web-03:/srv/backups"},
+		{c: runContext{Host: "web-03", Dir: "/srv/app", Branch: "4b825dc"}, want: "web-03:/srv/app (4b825dc)"},
+	} {
+		if got := tt.c.String(); got != filepath.FromSlash(tt.want) {
+			t.Errorf("%+v.String() = %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package reporter
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// `reporter daemon` takes the shell hook's reports over a Unix socket, so a
+// prompt costs one small write instead of starting reporter, and, for a
+// notification, osascript or notify-send. The zsh hook writes to the socket
+// with zsh/net/socket, without forking; bash and fish cannot open a Unix
+// socket themselves, so their hooks keep running reporter notify.
+//
+// The daemon handles one report at a time, moving into the directory the
+// command ran in, so the project config, the history, templates and the
+// payload all see the shell's directory, and a report is judged exactly as
+// reporter notify would judge it with no flags beyond the hook's own.
+// Notifications that pass are held for -batch, and those arriving together,
+// as when a script's commands finish in quick succession, go out as one;
+// -rate caps how many go out a minute, holding the rest for the next batch.
+//
+// The protocol is one report per connection, with no reply: a header line,
+// then one line each for the duration in milliseconds, possibly fractional,
+// the exit status, the threshold ("" for the config's), "1" for -always, the
+// directory, and the labels as space-separated key=value pairs, and then the
+// command line, which runs to the end and may span lines.
+
+// hookReportHeader starts every report, so a stray connection is not taken
+// for one.
+const hookReportHeader = "reporter-hook/1"
+
+const (
+	// daemonMaxReport caps the report the daemon reads.
+	daemonMaxReport = 64 << 10
+	// daemonReadTimeout bounds reading a report; the hook writes it at
+	// once.
+	daemonReadTimeout = time.Second
+	// daemonDedupWindow is how long an identical report counts as the same
+	// run reported twice, as when the hook is sourced from two places.
+	daemonDedupWindow = 2 * time.Second
+)
+
+// hookReport is what the shell hook tells the daemon about one command.
+type hookReport struct {
+	command   string
+	dir       string
+	duration  time.Duration
+	exitCode  int
+	threshold string
+	always    bool
+	labels    map[string]string
+}
+
+// parseHookReport parses a report in the daemon's protocol.
+func parseHookReport(data []byte) (hookReport, error) {
+	fields := strings.SplitN(string(data), "\n", 8)
+	if len(fields) < 8 || fields[0] != hookReportHeader {
+		return hookReport{}, errors.New("not a reporter hook report")
+	}
+	r := hookReport{threshold: fields[3], always: fields[4] == "1", dir: fields[5], command: strings.TrimSpace(fields[7])}
+	// Older hooks sent zsh's fractional milliseconds.
+	var err error
+	if r.duration, err = parseDecimal(fields[1], time.Millisecond); err != nil {
+		return hookReport{}, fmt.Errorf("invalid duration %q", fields[1])
+	}
+	if r.exitCode, err = strconv.Atoi(fields[2]); err != nil {
+		return hookReport{}, fmt.Errorf("invalid exit status %q", fields[2])
+	}
+	if !strings.HasPrefix(r.dir, "/") {
+		return hookReport{}, fmt.Errorf("invalid directory %q", r.dir)
+	}
+	labels := labelFlag{}
+	for _, kv := range strings.Fields(fields[6]) {
+		if err := labels.Set(kv); err != nil {
+			return hookReport{}, err
+		}
+	}
+	if len(labels) > 0 {
+		r.labels = labels
+	}
+	return r, nil
+}
+
+// daemonSocketPath is where `reporter daemon` listens by default, next to
+// the agent's socket.
+func daemonSocketPath() string {
+	path := agentSocketPath()
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path, "agent.sock") + "daemon.sock"
+}
+
+// heldNotification is a notification the daemon holds for the next batch.
+type heldNotification struct {
+	res  runResult
+	opts options
+	dir  string
+}
+
+// daemon batches and rate-limits the notifications about hook reports. Its
+// fields are only used from the goroutine running run.
+type daemon struct {
+	batch time.Duration
+	rate  int // notifications a minute; 0 for no limit
+
+	// handle judges a report, adding to pending anything to notify
+	// about; send sends a batch. Tests replace them.
+	handle func(r hookReport)
+	send   func(batch []heldNotification)
+
+	seen    map[string]time.Time // recent reports, for dedup
+	pending []heldNotification
+	sent    []time.Time // notifications in the last minute
+}
+
+func newDaemon(batch time.Duration, rate int) *daemon {
+	d := &daemon{batch: batch, rate: rate, seen: map[string]time.Time{}}
+	d.handle, d.send = d.report, sendBatch
+	return d
+}
+
+// run handles reports until the channel is closed, then sends whatever is
+// still held.
+func (d *daemon) run(reports <-chan hookReport) {
+	var flush <-chan time.Time
+	for {
+		select {
+		case r, ok := <-reports:
+			if !ok {
+				if len(d.pending) > 0 {
+					d.flush(time.Now())
+				}
+				return
+			}
+			if d.duplicate(r, time.Now()) {
+				continue
+			}
+			d.handle(r)
+			if len(d.pending) > 0 && flush == nil {
+				flush = time.After(d.batch)
+			}
+		case now := <-flush:
+			flush = nil
+			if wait := d.rateWait(now); wait > 0 {
+				flush = time.After(wait)
+				continue
+			}
+			d.flush(now)
+		}
+	}
+}
+
+// duplicate reports whether r repeats a report seen within
+// daemonDedupWindow, and remembers it.
+func (d *daemon) duplicate(r hookReport, now time.Time) bool {
+	for k, t := range d.seen {
+		if now.Sub(t) >= daemonDedupWindow {
+			delete(d.seen, k)
+		}
+	}
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%d", r.dir, r.command, r.exitCode, r.duration.Milliseconds())
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+// rateWait returns how long to wait before the next notification to stay
+// within the rate, or 0 to send it now.
+func (d *daemon) rateWait(now time.Time) time.Duration {
+	for len(d.sent) > 0 && now.Sub(d.sent[0]) >= time.Minute {
+		d.sent = d.sent[1:]
+	}
+	if d.rate <= 0 || len(d.sent) < d.rate {
+		return 0
+	}
+	return d.sent[0].Add(time.Minute).Sub(now)
+}
+
+// flush sends the pending notifications as one batch.
+func (d *daemon) flush(now time.Time) {
+	batch := d.pending
+	d.pending = nil
+	d.sent = append(d.sent, now)
+	d.send(batch)
+}
+
+// report judges r as reporter notify would, holding the notification about
+// it, if any, for the next batch.
+func (d *daemon) report(r hookReport) {
+	if err := os.Chdir(r.dir); err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+		return
+	}
+	cfg, err := loadConfig(r.dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: invalid config for %s: %v\n", r.dir, err)
+		return
+	}
+	opts, err := hookOptions(cfg, r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: invalid config for %s: %v\n", r.dir, err)
+		return
+	}
+	opts.hold = func(res runResult, opts options) {
+		d.pending = append(d.pending, heldNotification{res: res, opts: opts, dir: r.dir})
+	}
+	res := runResult{
+		Command:  opts.redact.command(r.command),
+		Duration: r.duration,
+		ExitCode: r.exitCode,
+		Labels:   opts.labels,
+		Signal:   shellSignal(r.exitCode),
+	}
+	report(res, opts)
+}
+
+// hookOptions returns the options reporter notify would run with, given the
+// hook's flags in r and no others. The daemon has no terminal of its own, so
+// there is no bell, stderr fallback or terminal escape sequence, and no way
+// to tell whether the shell's window is focused.
+func hookOptions(cfg *config, r hookReport) (options, error) {
+	opts := options{
+		always:            r.always || cfg.bool("always", false),
+		notifyOn:          cfg.string("notify_on", notifyOnAlways),
+		title:             cfg.string("title", "Task finished"),
+		quiet:             true,
+		context:           cfg.bool("context", false),
+		telemetry:         cfg.bool("telemetry", false),
+		summary:           cfg.bool("delivery_summary", false),
+		ignoreInterrupts:  cfg.bool("ignore_interrupts", false),
+		tiers:             configTiers(cfg),
+		evenIfFocused:     true,
+		commandThresholds: configCommandThresholds(cfg),
+	}
+	thresholdStr := r.threshold
+	if thresholdStr == "" {
+		thresholdStr = cfg.string("threshold", "10s")
+	}
+	autoThreshold := thresholdStr == thresholdAuto
+	opts.threshold = autoThresholdFloor
+	var err error
+	if !autoThreshold {
+		if opts.threshold, err = time.ParseDuration(thresholdStr); err != nil {
+			return options{}, fmt.Errorf("invalid threshold: %w", err)
+		}
+	}
+	if cfg.bool("only_failures", false) {
+		opts.notifyOn = notifyOnFailure
+	}
+	if prefix := cfg.string("title_prefix", ""); prefix != "" {
+		opts.title = prefix + " " + opts.title
+	}
+	labels := labelFlag(cfg.labels())
+	for k, v := range r.labels {
+		labels[k] = v
+	}
+	opts.labels = labels
+	if opts.successEvery, err = time.ParseDuration(cfg.string("success_every", "0")); err != nil {
+		return options{}, err
+	}
+	if opts.pushWhenIdle, err = time.ParseDuration(cfg.string("push_when_idle", "0")); err != nil {
+		return options{}, err
+	}
+	if opts.exitCodes, err = parseExitCodes(cfg.string("exit_codes", "")); err != nil {
+		return options{}, err
+	}
+	if opts.journal, err = newJournal(cfg.string("journal", ""), cfg.string("journal_format", "")); err != nil {
+		return options{}, err
+	}
+	if opts.templates, err = newMessageTemplates(cfg.string("title_template", ""), cfg.string("body_template", "")); err != nil {
+		return options{}, err
+	}
+	if opts.statsd, err = newStatsdSink(cfg.string("statsd", ""), cfg.string("statsd_prefix", "reporter"), cfg.bool("statsd_tags", true)); err != nil {
+		return options{}, err
+	}
+	if opts.prometheus, err = newPromSink(cfg.string("prometheus_pushgateway", ""), cfg.string("prometheus_job", "reporter"), expandHome(cfg.string("prometheus_textfile", ""))); err != nil {
+		return options{}, err
+	}
+	opts.quietHours = configQuietHours(cfg)
+	if !cfg.bool("no_redact", false) {
+		opts.redact = newRedactor(cfg.strings("redact"))
+	}
+	if cfg.bool("notification_log", true) {
+		opts.notificationLog = notificationLogPath()
+	}
+	opts.snoozed, _ = mutedUntil(mutePath(), time.Now())
+	if opts.muted, err = hookMuted(r.command, cfg.strings("notify_allow"), notifyDenyPatterns(cfg)); err != nil {
+		return options{}, err
+	}
+
+	if opts.push, opts.desktop, err = daemonDelivery(cfg); err != nil {
+		return options{}, err
+	}
+	opts.agent = hostAgent(cfg)
+
+	if !cfg.bool("no_history", false) || autoThreshold {
+		store, err := openHistory(cfg)
+		if err != nil {
+			return options{}, err
+		}
+		if !cfg.bool("no_history", false) {
+			opts.history = store
+		}
+		if autoThreshold {
+			opts.pastRuns = store
+		}
+	}
+	return opts, nil
+}
+
+// daemonDelivery resolves where the daemon sends notifications: the push
+// settings as their flags would, left unset, and the desktop. The daemon is
+// there to notify this desktop and usually runs from a systemd unit or
+// launchd agent, without the terminal desktop = "auto" looks for, so there
+// auto means always.
+func daemonDelivery(cfg *config) (push []pushTarget, desktop bool, err error) {
+	fset := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	resolvePush := registerPushFlags(fset, cfg, "")
+	resolveDesktop := registerDesktopFlag(fset, cfg)
+	if err := fset.Parse(nil); err != nil {
+		return nil, false, err
+	}
+	if cfg.string("desktop", desktopAuto) == desktopAuto {
+		_ = fset.Set("desktop", desktopAlways)
+	}
+	cfg.enforceLocks(fset)
+	if push, err = resolvePush(); err != nil {
+		return nil, false, err
+	}
+	if desktop, err = resolveDesktop(); err != nil {
+		return nil, false, err
+	}
+	return push, desktop, nil
+}
+
+// sendBatch sends a batch of held notifications: one on its own, exactly
+// as reporter notify would, and several as one summary.
+func sendBatch(batch []heldNotification) {
+	if len(batch) == 0 {
+		return
+	}
+	// A batch goes where its first failure, or else its last run, would
+	// have gone, as tiers and quiet hours had it.
+	lead := batch[len(batch)-1]
+	for _, h := range batch {
+		if h.res.ExitCode != 0 {
+			lead = h
+			break
+		}
+	}
+	if err := os.Chdir(lead.dir); err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+	}
+	if len(batch) == 1 {
+		notify(lead.res, lead.opts)
+		return
+	}
+	deliver(batchNotification(batch, lead.opts.title, time.Now()), lead.opts)
+}
+
+// batchNotification summarizes several runs in one notification, a line
+// for each.
+func batchNotification(batch []heldNotification, title string, now time.Time) notification {
+	n := notification{
+		Title:    title,
+		Subtitle: plural(len(batch), "command") + " finished",
+		Finished: now,
+	}
+	lines := make([]string, 0, len(batch))
+	failed := 0
+	for _, h := range batch {
+		mark := "✓"
+		if h.res.ExitCode != 0 {
+			mark = "✗"
+			if failed == 0 {
+				n.ExitCode = h.res.ExitCode
+			}
+			failed++
+		}
+		lines = append(lines, fmt.Sprintf("%s %s — %s in %s", mark, oneLine(h.res.Command), resultStatus(h.res), formatDuration(h.res.Duration)))
+		n.Duration = max(n.Duration, h.res.Duration)
+	}
+	if failed > 0 {
+		n.Failed = true
+		n.Subtitle += fmt.Sprintf(", %d failed", failed)
+	}
+	n.Body = strings.Join(lines, "\n")
+	return n
+}
+
+// serveDaemonConn reads one report from conn and queues it.
+func serveDaemonConn(conn net.Conn, reports chan<- hookReport) {
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(daemonReadTimeout))
+	data, err := io.ReadAll(io.LimitReader(conn, daemonMaxReport+1))
+	if err == nil && len(data) > daemonMaxReport {
+		err = fmt.Errorf("report larger than %d bytes", daemonMaxReport)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+		return
+	}
+	r, err := parseHookReport(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+		return
+	}
+	select {
+	case reports <- r:
+	default:
+		fmt.Fprintf(os.Stderr, "daemon: too many reports waiting; dropped %q\n", oneLine(r.command))
+	}
+}
+
+// runDaemon implements `reporter daemon`.
+func runDaemon(args []string) int {
+	fset := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter daemon [flags]")
+		fset.PrintDefaults()
+	}
+	listen := fset.String("listen", daemonSocketPath(), "Unix socket `path` to take shell hook reports on")
+	batch := fset.Duration("batch", 2*time.Second, "send notifications arriving within this `long` of the first as one")
+	rate := fset.Int("rate", 6, "send at most this many notifications a minute, holding the rest for the next batch; 0 for no limit")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 || *listen == "" || *batch < 0 || *rate < 0 {
+		fset.Usage()
+		return 2
+	}
+	if agentNetwork(*listen) != "unix" {
+		fmt.Fprintln(os.Stderr, "daemon: -listen takes a Unix socket path")
+		return 2
+	}
+
+	ln, err := listenAgent(*listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "daemon: %v\n", strings.Replace(err.Error(), "another agent", "another daemon", 1))
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "daemon: listening on %s\n", *listen)
+	var push []pushTarget
+	if wd, err := os.Getwd(); err == nil {
+		if cfg, err := loadConfig(wd); err == nil {
+			var desktop bool
+			if push, desktop, err = daemonDelivery(cfg); err == nil && !desktop && len(push) == 0 {
+				fmt.Fprintln(os.Stderr, "daemon: desktop = \"never\" and no push_url is set, so notifications go nowhere")
+			}
+		}
+	}
+	// pushDialer keeps the DNS answers for every later push, and
+	// pushClient keeps each push's connection open for the next, so one
+	// prewarm is enough; the daemon does not redial while idle.
+	for _, t := range push {
+		prewarmPush(t.URL)
+	}
+
+	reports := make(chan hookReport, 256)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		newDaemon(*batch, *rate).run(reports)
+	}()
+
+	// On SIGINT or SIGTERM, stop taking reports and send what is held.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		ln.Close()
+	}()
+
+	var conns sync.WaitGroup
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "daemon: %v\n", err)
+			}
+			break
+		}
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			serveDaemonConn(conn, reports)
+		}()
+	}
+	conns.Wait()
+	close(reports)
+	wg.Wait()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return 1
+	}
+	return 0
+}
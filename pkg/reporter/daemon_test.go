@@ -0,0 +1,240 @@
+package reporter
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHookReport(t *testing.T) {
+	report := func(fields ...string) []byte { return []byte(strings.Join(fields, "\n")) }
+	tests := []struct {
+		name    string
+		data    []byte
+		want    hookReport
+		wantErr bool
+	}{
+		{
+			name: "report",
+			data: report(hookReportHeader, "12500", "2", "30s", "", "/home/me/src", "atuin_session=abc ", "make test"),
+			want: hookReport{command: "make test", dir: "/home/me/src", duration: 12500 * time.Millisecond, exitCode: 2, threshold: "30s", labels: map[string]string{"atuin_session": "abc"}},
+		},
+		{
+			name: "multi-line command",
+			data: report(hookReportHeader, "0", "0", "", "1", "/tmp", "", "for f in *; do\n  gzip $f\ndone"),
+			want: hookReport{command: "for f in *; do\n  gzip $f\ndone", dir: "/tmp", always: true},
+		},
+		{
+			name: "fractional milliseconds",
+			data: report(hookReportHeader, "20000.5", "0", "", "", "/tmp", "", "sleep 20"),
+			want: hookReport{command: "sleep 20", dir: "/tmp", duration: 20000500 * time.Microsecond},
+		},
+		{name: "wrong header", data: report("reporter-hook/9", "0", "0", "", "", "/tmp", "", "ls"), wantErr: true},
+		{name: "too short", data: report(hookReportHeader, "0", "0"), wantErr: true},
+		{name: "bad duration", data: report(hookReportHeader, "1.5s", "0", "", "", "/tmp", "", "ls"), wantErr: true},
+		{name: "bad exit status", data: report(hookReportHeader, "0", "x", "", "", "/tmp", "", "ls"), wantErr: true},
+		{name: "relative directory", data: report(hookReportHeader, "0", "0", "", "", "src", "", "ls"), wantErr: true},
+		{name: "bad label", data: report(hookReportHeader, "0", "0", "", "", "/tmp", "nolabel", "ls"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHookReport(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseHookReport() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.command != tt.want.command || got.dir != tt.want.dir || got.duration != tt.want.duration ||
+				got.exitCode != tt.want.exitCode || got.threshold != tt.want.threshold || got.always != tt.want.always ||
+				formatLabels(got.labels) != formatLabels(tt.want.labels) {
+				t.Errorf("parseHookReport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonDuplicate(t *testing.T) {
+	d := newDaemon(0, 0)
+	now := time.Now()
+	r := hookReport{command: "make", dir: "/src", duration: 20 * time.Second}
+	if d.duplicate(r, now) {
+		t.Error("first report counted as a duplicate")
+	}
+	if !d.duplicate(r, now.Add(time.Second)) {
+		t.Error("the same report a second later was not counted as a duplicate")
+	}
+	other := r
+	other.exitCode = 1
+	if d.duplicate(other, now.Add(time.Second)) {
+		t.Error("a report with another exit status counted as a duplicate")
+	}
+	if d.duplicate(r, now.Add(daemonDedupWindow+time.Second)) {
+		t.Error("the same report after the window counted as a duplicate")
+	}
+}
+
+func TestDaemonRateWait(t *testing.T) {
+	d := newDaemon(0, 2)
+	now := time.Now()
+	if wait := d.rateWait(now); wait != 0 {
+		t.Errorf("rateWait() with nothing sent = %v", wait)
+	}
+	d.sent = []time.Time{now.Add(-50 * time.Second), now.Add(-10 * time.Second)}
+	if wait := d.rateWait(now); wait != 10*time.Second {
+		t.Errorf("rateWait() at the limit = %v, want until the oldest is a minute old", wait)
+	}
+	if wait := d.rateWait(now.Add(15 * time.Second)); wait != 0 || len(d.sent) != 1 {
+		t.Errorf("rateWait() once the oldest has aged out = %v with %d sent", wait, len(d.sent))
+	}
+	d.rate = 0
+	d.sent = []time.Time{now, now, now}
+	if wait := d.rateWait(now); wait != 0 {
+		t.Errorf("rateWait() with no limit = %v", wait)
+	}
+}
+
+func TestDaemonRun(t *testing.T) {
+	d := newDaemon(30*time.Millisecond, 0)
+	d.handle = func(r hookReport) {
+		d.pending = append(d.pending, heldNotification{res: runResult{Command: r.command}})
+	}
+	batches := make(chan []heldNotification, 10)
+	d.send = func(batch []heldNotification) { batches <- batch }
+
+	reports := make(chan hookReport)
+	done := make(chan struct{})
+	go func() {
+		d.run(reports)
+		close(done)
+	}()
+	reports <- hookReport{command: "make build", dir: "/src"}
+	reports <- hookReport{command: "make build", dir: "/src"} // a duplicate
+	reports <- hookReport{command: "make test", dir: "/src"}
+	batch := <-batches
+	if len(batch) != 2 || batch[0].res.Command != "make build" || batch[1].res.Command != "make test" {
+		t.Errorf("first batch = %+v, want make build and make test", batch)
+	}
+
+	// What is held when the daemon stops is sent at once.
+	reports <- hookReport{command: "make deploy", dir: "/src"}
+	close(reports)
+	<-done
+	select {
+	case batch := <-batches:
+		if len(batch) != 1 || batch[0].res.Command != "make deploy" {
+			t.Errorf("last batch = %+v, want make deploy", batch)
+		}
+	default:
+		t.Error("the held notification was not sent on stopping")
+	}
+}
+
+func TestBatchNotification(t *testing.T) {
+	batch := []heldNotification{
+		{res: runResult{Command: "make build", Duration: 2 * time.Minute}},
+		{res: runResult{Command: "make test", Duration: 3 * time.Minute, ExitCode: 2}},
+	}
+	n := batchNotification(batch, "Task finished", time.Now())
+	if n.Title != "Task finished" || n.Subtitle != "2 commands finished, 1 failed" || !n.Failed || n.ExitCode != 2 {
+		t.Errorf("batchNotification() = %+v", n)
+	}
+	want := "✓ make build — succeeded in 2m00s\n✗ make test — failed (exit 2) in 3m00s"
+	if n.Body != want {
+		t.Errorf("Body = %q, want %q", n.Body, want)
+	}
+	if n.Duration != 3*time.Minute {
+		t.Errorf("Duration = %v, want the longest run's", n.Duration)
+	}
+}
+
+func TestServeDaemonConn(t *testing.T) {
+	reports := make(chan hookReport, 1)
+	client, server := net.Pipe()
+	go serveDaemonConn(server, reports)
+	client.Write([]byte(strings.Join([]string{hookReportHeader, "61000", "0", "", "", "/src", "", "cargo build"}, "\n")))
+	client.Close()
+	r := <-reports
+	if r.command != "cargo build" || r.duration != 61*time.Second {
+		t.Errorf("queued %+v", r)
+	}
+}
+
+func TestHookOptions(t *testing.T) {
+	dir := isolateConfig(t)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("REPORTER_PUSH_URL", "")
+	writeFile(t, filepath.Join(dir, "xdg", "reporter", "config.toml"), `
+threshold = "1m"
+title_prefix = "[laptop]"
+only_failures = true
+push_url = "https://ntfy.sh/builds"
+notify_deny = ["^vim\\b"]
+
+[labels]
+team = "infra"
+`)
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := hookOptions(cfg, hookReport{command: "make", labels: map[string]string{"atuin_session": "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.threshold != time.Minute || opts.title != "[laptop] Task finished" || opts.notifyOn != notifyOnFailure {
+		t.Errorf("hookOptions() threshold %v, title %q, notify_on %q", opts.threshold, opts.title, opts.notifyOn)
+	}
+	if opts.labels["team"] != "infra" || opts.labels["atuin_session"] != "abc" {
+		t.Errorf("hookOptions() labels = %v, want the config's and the hook's", opts.labels)
+	}
+	if len(opts.push) != 1 || opts.push[0].URL != "https://ntfy.sh/builds" {
+		t.Errorf("hookOptions() push = %+v", opts.push)
+	}
+	if opts.bell || !opts.quiet || opts.muted || opts.history == nil {
+		t.Errorf("hookOptions() = %+v", opts)
+	}
+
+	// The hook's threshold wins, and notify_deny mutes.
+	opts, err = hookOptions(cfg, hookReport{command: "vim notes.md", threshold: "10s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.threshold != 10*time.Second || !opts.muted {
+		t.Errorf("hookOptions() threshold %v, muted %v", opts.threshold, opts.muted)
+	}
+}
+
+func TestDaemonDelivery(t *testing.T) {
+	dir := isolateConfig(t)
+	t.Setenv("REPORTER_PUSH_URL", "https://example.com/mine")
+	writeFile(t, systemConfigPath, "push_url = \"https://relay.corp/push\"\nlocked = [\"push_url\"]\n")
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a terminal, auto still shows the desktop, and the locked
+	// push_url beats the environment.
+	push, desktop, err := daemonDelivery(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !desktop || len(push) != 1 || push[0].URL != "https://relay.corp/push" {
+		t.Errorf("daemonDelivery() = %+v, %v; want the locked push_url and the desktop", push, desktop)
+	}
+
+	writeFile(t, filepath.Join(dir, "xdg", "reporter", "config.toml"), "desktop = \"never\"\n")
+	if cfg, err = loadConfig(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, desktop, err = daemonDelivery(cfg); err != nil || desktop {
+		t.Errorf("daemonDelivery() with desktop = \"never\" = %v, %v; want false", desktop, err)
+	}
+}
@@ -0,0 +1,463 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file implements just enough of the D-Bus wire protocol to call
+// org.freedesktop.Notifications on the session bus without shelling out to
+// notify-send or pulling in a D-Bus library. Outgoing messages are always
+// little-endian; replies in either byte order are accepted.
+
+const (
+	dbusMethodCall   = 1
+	dbusMethodReturn = 2
+	dbusError        = 3
+
+	dbusFieldPath        = 1
+	dbusFieldInterface   = 2
+	dbusFieldMember      = 3
+	dbusFieldErrorName   = 4
+	dbusFieldReplySerial = 5
+	dbusFieldDestination = 6
+	dbusFieldSignature   = 8
+
+	// dbusMaxMessage is the protocol's upper bound on message size.
+	dbusMaxMessage = 1 << 27
+)
+
+// Urgency levels defined by the Desktop Notifications spec.
+const (
+	urgencyLow      byte = 0
+	urgencyNormal   byte = 1
+	urgencyCritical byte = 2
+)
+
+// desktopNotification is a request to the org.freedesktop.Notifications
+// service.
+type desktopNotification struct {
+	Summary string
+	Body    string
+	Urgency byte
+	// ReplacesID updates an earlier notification in place instead of
+	// showing a new one. Zero shows a new notification.
+	ReplacesID uint32
+	// Actions alternates action keys and their labels.
+	Actions []string
+	// Timeout in milliseconds; -1 lets the server decide.
+	Timeout int32
+}
+
+type dbusObjectPath string
+type dbusSignature string
+
+type dbusHeaderField struct {
+	code  byte
+	value any
+}
+
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) byte(b byte) { e.buf = append(e.buf, b) }
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *dbusEncoder) int32(v int32) { e.uint32(uint32(v)) }
+
+func (e *dbusEncoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+func (e *dbusEncoder) signature(s string) {
+	e.byte(byte(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+// array writes an array whose elements have the given alignment; fn writes
+// the elements. The length prefix excludes the padding before the first one.
+func (e *dbusEncoder) array(elemAlign int, fn func()) {
+	e.align(4)
+	lenPos := len(e.buf)
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	e.align(elemAlign)
+	start := len(e.buf)
+	fn()
+	binary.LittleEndian.PutUint32(e.buf[lenPos:], uint32(len(e.buf)-start))
+}
+
+func (e *dbusEncoder) variant(v any) {
+	switch v := v.(type) {
+	case byte:
+		e.signature("y")
+		e.byte(v)
+	case bool:
+		e.signature("b")
+		var n uint32
+		if v {
+			n = 1
+		}
+		e.uint32(n)
+	case int32:
+		e.signature("i")
+		e.int32(v)
+	case uint32:
+		e.signature("u")
+		e.uint32(v)
+	case string:
+		e.signature("s")
+		e.string(v)
+	case dbusObjectPath:
+		e.signature("o")
+		e.string(string(v))
+	case dbusSignature:
+		e.signature("g")
+		e.signature(string(v))
+	default:
+		panic(fmt.Sprintf("dbus: unsupported variant type %T", v))
+	}
+}
+
+func encodeDBusMessage(typ byte, serial uint32, fields []dbusHeaderField, body []byte) []byte {
+	e := &dbusEncoder{}
+	e.byte('l')
+	e.byte(typ)
+	e.byte(0) // flags
+	e.byte(1) // protocol version
+	e.uint32(uint32(len(body)))
+	e.uint32(serial)
+	e.array(8, func() {
+		for _, f := range fields {
+			e.align(8)
+			e.byte(f.code)
+			e.variant(f.value)
+		}
+	})
+	e.align(8)
+	return append(e.buf, body...)
+}
+
+type dbusMessage struct {
+	typ         byte
+	order       binary.ByteOrder
+	serial      uint32
+	replySerial uint32
+	errorName   string
+	signature   string
+	body        []byte
+}
+
+type dbusDecoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *dbusDecoder) align(n int) {
+	d.pos = (d.pos + n - 1) / n * n
+}
+
+func (d *dbusDecoder) need(n int) error {
+	if d.pos+n > len(d.buf) {
+		return errors.New("dbus: truncated message")
+	}
+	return nil
+}
+
+func (d *dbusDecoder) byte() (byte, error) {
+	if err := d.need(1); err != nil {
+		return 0, err
+	}
+	d.pos++
+	return d.buf[d.pos-1], nil
+}
+
+func (d *dbusDecoder) uint32() (uint32, error) {
+	d.align(4)
+	if err := d.need(4); err != nil {
+		return 0, err
+	}
+	d.pos += 4
+	return d.order.Uint32(d.buf[d.pos-4:]), nil
+}
+
+func (d *dbusDecoder) string() (string, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return "", err
+	}
+	if err := d.need(int(n) + 1); err != nil {
+		return "", err
+	}
+	s := string(d.buf[d.pos : d.pos+int(n)])
+	d.pos += int(n) + 1
+	return s, nil
+}
+
+func (d *dbusDecoder) signature() (string, error) {
+	n, err := d.byte()
+	if err != nil {
+		return "", err
+	}
+	if err := d.need(int(n) + 1); err != nil {
+		return "", err
+	}
+	s := string(d.buf[d.pos : d.pos+int(n)])
+	d.pos += int(n) + 1
+	return s, nil
+}
+
+func readDBusMessage(r io.Reader) (*dbusMessage, error) {
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	m := &dbusMessage{typ: head[1]}
+	switch head[0] {
+	case 'l':
+		m.order = binary.LittleEndian
+	case 'B':
+		m.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("dbus: invalid endianness %q", head[0])
+	}
+	bodyLen := m.order.Uint32(head[4:])
+	m.serial = m.order.Uint32(head[8:])
+	fieldsLen := m.order.Uint32(head[12:])
+	if uint64(bodyLen)+uint64(fieldsLen) > dbusMaxMessage {
+		return nil, errors.New("dbus: message too large")
+	}
+	headerEnd := 16 + int(fieldsLen)
+	pad := (8 - headerEnd%8) % 8
+
+	full := make([]byte, headerEnd+pad+int(bodyLen))
+	copy(full, head)
+	if _, err := io.ReadFull(r, full[16:]); err != nil {
+		return nil, err
+	}
+
+	d := &dbusDecoder{buf: full[:headerEnd], pos: 16, order: m.order}
+	for d.pos < headerEnd {
+		d.align(8)
+		code, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		sig, err := d.signature()
+		if err != nil {
+			return nil, err
+		}
+		var s string
+		var u uint32
+		switch sig {
+		case "s", "o":
+			s, err = d.string()
+		case "g":
+			s, err = d.signature()
+		case "u":
+			u, err = d.uint32()
+		default:
+			return nil, fmt.Errorf("dbus: unexpected header field type %q", sig)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch code {
+		case dbusFieldErrorName:
+			m.errorName = s
+		case dbusFieldReplySerial:
+			m.replySerial = u
+		case dbusFieldSignature:
+			m.signature = s
+		}
+	}
+	m.body = full[headerEnd+pad:]
+	return m, nil
+}
+
+// parseBusAddress returns the socket path for the first unix transport in a
+// D-Bus server address such as "unix:path=/run/user/1000/bus,guid=...".
+// Abstract sockets are returned with Go's "@" prefix.
+func parseBusAddress(addr string) (string, error) {
+	for _, entry := range strings.Split(addr, ";") {
+		rest, ok := strings.CutPrefix(entry, "unix:")
+		if !ok {
+			continue
+		}
+		for _, kv := range strings.Split(rest, ",") {
+			key, val, _ := strings.Cut(kv, "=")
+			val, err := url.PathUnescape(val)
+			if err != nil {
+				return "", fmt.Errorf("invalid bus address %q: %w", addr, err)
+			}
+			switch key {
+			case "path":
+				return val, nil
+			case "abstract":
+				return "@" + val, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no supported transport in bus address %q", addr)
+}
+
+func sessionBusPath() (string, error) {
+	if addr := os.Getenv("DBUS_SESSION_BUS_ADDRESS"); addr != "" {
+		return parseBusAddress(addr)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "bus"), nil
+	}
+	return "", errors.New("no session bus: DBUS_SESSION_BUS_ADDRESS and XDG_RUNTIME_DIR are unset")
+}
+
+type dbusConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	serial uint32
+}
+
+func dialSessionBus(timeout time.Duration) (*dbusConn, error) {
+	path, err := sessionBusPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	c, err := newDBusConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newDBusConn authenticates on conn and registers with the bus.
+func newDBusConn(conn net.Conn) (*dbusConn, error) {
+	c := &dbusConn{conn: conn, r: bufio.NewReader(conn)}
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := fmt.Fprintf(conn, "\x00AUTH EXTERNAL %s\r\n", uid); err != nil {
+		return nil, err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return nil, fmt.Errorf("dbus: authentication rejected: %s", strings.TrimSpace(line))
+	}
+	if _, err := io.WriteString(conn, "BEGIN\r\n"); err != nil {
+		return nil, err
+	}
+	if _, err := c.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello", "", nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *dbusConn) Close() error { return c.conn.Close() }
+
+func (c *dbusConn) call(dest, path, iface, member, sig string, body []byte) (*dbusMessage, error) {
+	c.serial++
+	fields := []dbusHeaderField{
+		{dbusFieldPath, dbusObjectPath(path)},
+		{dbusFieldInterface, iface},
+		{dbusFieldMember, member},
+		{dbusFieldDestination, dest},
+	}
+	if sig != "" {
+		fields = append(fields, dbusHeaderField{dbusFieldSignature, dbusSignature(sig)})
+	}
+	if _, err := c.conn.Write(encodeDBusMessage(dbusMethodCall, c.serial, fields, body)); err != nil {
+		return nil, err
+	}
+	for {
+		m, err := readDBusMessage(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if m.replySerial != c.serial {
+			continue // signals such as NameAcquired
+		}
+		if m.typ == dbusError {
+			msg := m.errorName
+			if strings.HasPrefix(m.signature, "s") {
+				d := &dbusDecoder{buf: m.body, order: m.order}
+				if text, err := d.string(); err == nil {
+					msg += ": " + text
+				}
+			}
+			return nil, fmt.Errorf("dbus: %s", msg)
+		}
+		return m, nil
+	}
+}
+
+// notify sends n and returns the id the server assigned to it.
+func (c *dbusConn) notify(n desktopNotification) (uint32, error) {
+	e := &dbusEncoder{}
+	e.string("reporter")
+	e.uint32(n.ReplacesID)
+	e.string("") // app_icon
+	e.string(n.Summary)
+	e.string(n.Body)
+	e.array(4, func() {
+		for _, a := range n.Actions {
+			e.string(a)
+		}
+	})
+	e.array(8, func() {
+		e.align(8)
+		e.string("urgency")
+		e.variant(n.Urgency)
+	})
+	e.int32(n.Timeout)
+
+	reply, err := c.call("org.freedesktop.Notifications", "/org/freedesktop/Notifications",
+		"org.freedesktop.Notifications", "Notify", "susssasa{sv}i", e.buf)
+	if err != nil {
+		return 0, err
+	}
+	if reply.signature != "u" || len(reply.body) < 4 {
+		return 0, fmt.Errorf("dbus: unexpected Notify reply signature %q", reply.signature)
+	}
+	return reply.order.Uint32(reply.body), nil
+}
+
+// notifyDBus shows n through the session bus notification service.
+func notifyDBus(n desktopNotification) (uint32, error) {
+	c, err := dialSessionBus(2 * time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	return c.notify(n)
+}
@@ -0,0 +1,133 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseBusAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "path with guid",
+			addr: "unix:path=/run/user/1000/bus,guid=0123456789abcdef",
+			want: "/run/user/1000/bus",
+		},
+		{
+			name: "abstract socket",
+			addr: "unix:abstract=/tmp/dbus-XYZ,guid=abc",
+			want: "@/tmp/dbus-XYZ",
+		},
+		{
+			name: "escaped path after unsupported transport",
+			addr: "tcp:host=localhost,port=1234;unix:path=/tmp/my%20bus",
+			want: "/tmp/my bus",
+		},
+		{
+			name:    "no unix transport",
+			addr:    "tcp:host=localhost,port=1234",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBusAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBusAddress(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseBusAddress(%q) = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeBus plays the bus side of a connection: it accepts authentication,
+// answers Hello, and replies to Notify with id.
+func fakeBus(t *testing.T, conn net.Conn, id uint32, got chan<- *dbusMessage) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		t.Errorf("fake bus: unexpected auth line %q (%v)", line, err)
+		return
+	}
+	conn.Write([]byte("OK 0123456789abcdef\r\n"))
+	if line, _ := r.ReadString('\n'); line != "BEGIN\r\n" {
+		t.Errorf("fake bus: expected BEGIN, got %q", line)
+		return
+	}
+
+	reply := func(serial uint32, sig string, body []byte) {
+		fields := []dbusHeaderField{{dbusFieldReplySerial, serial}, {dbusFieldSignature, dbusSignature(sig)}}
+		conn.Write(encodeDBusMessage(dbusMethodReturn, 100+serial, fields, body))
+	}
+
+	hello, err := readDBusMessage(r)
+	if err != nil {
+		t.Errorf("fake bus: reading Hello: %v", err)
+		return
+	}
+	e := &dbusEncoder{}
+	e.string(":1.42")
+	reply(hello.serial, "s", e.buf)
+
+	call, err := readDBusMessage(r)
+	if err != nil {
+		t.Errorf("fake bus: reading Notify: %v", err)
+		return
+	}
+	got <- call
+	reply(call.serial, "u", binary.LittleEndian.AppendUint32(nil, id))
+}
+
+func TestDBusNotify(t *testing.T) {
+	client, server := net.Pipe()
+	got := make(chan *dbusMessage, 1)
+	go fakeBus(t, server, 7, got)
+
+	c, err := newDBusConn(client)
+	if err != nil {
+		t.Fatalf("newDBusConn() returned error: %v", err)
+	}
+	defer c.Close()
+
+	id, err := c.notify(desktopNotification{
+		Summary:    "Task finished",
+		Body:       "make test — succeeded in 12s",
+		Urgency:    urgencyCritical,
+		ReplacesID: 3,
+		Timeout:    -1,
+	})
+	if err != nil {
+		t.Fatalf("notify() returned error: %v", err)
+	}
+	if id != 7 {
+		t.Errorf("notify() id = %d, want 7", id)
+	}
+
+	call := <-got
+	if call.signature != "susssasa{sv}i" {
+		t.Errorf("Notify signature = %q", call.signature)
+	}
+	d := &dbusDecoder{buf: call.body, order: call.order}
+	appName, _ := d.string()
+	replaces, _ := d.uint32()
+	_, _ = d.string() // icon
+	summary, _ := d.string()
+	body, _ := d.string()
+	if appName != "reporter" || replaces != 3 || summary != "Task finished" || body != "make test — succeeded in 12s" {
+		t.Errorf("Notify args = %q, %d, %q, %q", appName, replaces, summary, body)
+	}
+	if !strings.Contains(string(call.body), "urgency") || call.body[len(call.body)-5] != urgencyCritical {
+		t.Errorf("Notify hints do not carry critical urgency: % x", call.body)
+	}
+}
@@ -0,0 +1,96 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A dedup key names a logical job, such as one CI pipeline wrapped on several
+// runners. Notifications that share a key replace each other instead of
+// stacking up: push receivers get the key in a header, and desktop
+// notifications raised on this machine reuse the ID of the previous one.
+
+const (
+	dedupFileName = "dedup.json"
+	// dedupTTL bounds how long a key remembers its notification; a run
+	// reported days later deserves a fresh notification.
+	dedupTTL = 24 * time.Hour
+)
+
+type dedupRecord struct {
+	ID   uint32    `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+func dedupPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, dedupFileName)
+}
+
+func loadDedup(path string) (map[string]dedupRecord, error) {
+	records := map[string]dedupRecord{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		// A corrupt file only costs one duplicate notification.
+		return map[string]dedupRecord{}, nil
+	}
+	return records, nil
+}
+
+// lookupDedup returns the desktop notification ID last shown for key, or 0.
+func lookupDedup(path, key string, now time.Time) uint32 {
+	if path == "" || key == "" {
+		return 0
+	}
+	records, err := loadDedup(path)
+	if err != nil {
+		return 0
+	}
+	if r, ok := records[key]; ok && now.Sub(r.Time) < dedupTTL {
+		return r.ID
+	}
+	return 0
+}
+
+// storeDedup remembers id as the notification shown for key, dropping
+// expired keys.
+func storeDedup(path, key string, id uint32, now time.Time) error {
+	if path == "" || key == "" {
+		return nil
+	}
+	records, err := loadDedup(path)
+	if err != nil {
+		return err
+	}
+	for k, r := range records {
+		if now.Sub(r.Time) >= dedupTTL {
+			delete(records, k)
+		}
+	}
+	records[key] = dedupRecord{ID: id, Time: now}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,51 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDedupStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reporter", dedupFileName)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if id := lookupDedup(path, "pipeline-42", now); id != 0 {
+		t.Errorf("lookupDedup() on a missing file = %d, want 0", id)
+	}
+	if err := storeDedup(path, "old", 3, now.Add(-2*dedupTTL)); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeDedup(path, "pipeline-42", 17, now); err != nil {
+		t.Fatalf("storeDedup() returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		at   time.Time
+		want uint32
+	}{
+		{name: "same key", key: "pipeline-42", at: now.Add(time.Minute), want: 17},
+		{name: "other key", key: "pipeline-43", at: now, want: 0},
+		{name: "expired", key: "pipeline-42", at: now.Add(dedupTTL), want: 0},
+		{name: "pruned", key: "old", at: now.Add(-2 * dedupTTL), want: 0},
+		{name: "empty key", key: "", at: now, want: 0},
+	}
+	for _, tt := range tests {
+		if got := lookupDedup(path, tt.key, tt.at); got != tt.want {
+			t.Errorf("%s: lookupDedup(%q) = %d, want %d", tt.name, tt.key, got, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeDedup(path, "pipeline-42", 18, now); err != nil {
+		t.Errorf("storeDedup() over a corrupt file returned error: %v", err)
+	}
+	if got := lookupDedup(path, "pipeline-42", now); got != 18 {
+		t.Errorf("lookupDedup() after recovery = %d, want 18", got)
+	}
+}
@@ -0,0 +1,156 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// delivery is the outcome of handing a notification to one backend.
+type delivery struct {
+	Backend string `json:"backend"` // "desktop" or the push provider, e.g. "ntfy"
+	// Destination is the host a push went to. It leaves out the URL's
+	// path and query, which often hold webhook secrets or ntfy topics.
+	Destination string `json:"destination,omitempty"`
+	OK          bool   `json:"ok"`
+	Error       string `json:"error,omitempty"`
+	// Queued marks a failed push kept in the spool to be sent again
+	// later (see spool.go).
+	Queued bool `json:"queued,omitempty"`
+	err    error
+}
+
+func newDelivery(backend string, err error) delivery {
+	d := delivery{Backend: backend, OK: err == nil, err: err}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d
+}
+
+// pushAll sends n to every target at once, each with its own timeout (see
+// pushToPhone), so a slow destination does not hold up the others. Pushes
+// that still fail for a transient reason are spooled in spool, unless it is
+// empty. Results are in target order.
+func pushAll(targets []pushTarget, n notification, spool string) ([]delivery, []latencySample) {
+	deliveries := make([]delivery, len(targets))
+	samples := make([]latencySample, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sample, err := timeBackend("push", func() error { return pushToPhone(t, n) })
+			d := newDelivery(t.Provider, err)
+			d.Destination = pushDestination(t.URL)
+			if err != nil && spool != "" && pushRetryable(err) {
+				d.Queued = spoolPush(spool, t, n, time.Now()) == nil
+			}
+			deliveries[i], samples[i] = d, sample
+		}()
+	}
+	wg.Wait()
+	return deliveries, samples
+}
+
+// pushDestination returns the host of a push URL, or for schemes such as
+// telegram://<chat_id>, what stands in its place.
+func pushDestination(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// summarizeDeliveries renders deliveries as one line, such as
+// "desktop ✓, ntfy ✗ timeout". Backends used more than once are told apart by
+// destination, as in "ntfy (ntfy.sh) ✓, ntfy (ntfy.example.com) ✓".
+func summarizeDeliveries(ds []delivery) string {
+	count := map[string]int{}
+	for _, d := range ds {
+		count[d.Backend]++
+	}
+	parts := make([]string, len(ds))
+	for i, d := range ds {
+		name := d.Backend
+		if count[name] > 1 && d.Destination != "" {
+			name += " (" + d.Destination + ")"
+		}
+		switch {
+		case d.OK:
+			parts[i] = name + " ✓"
+		case d.Queued:
+			parts[i] = name + " ✗ " + briefError(d.err) + ", queued"
+		default:
+			parts[i] = name + " ✗ " + briefError(d.err)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// anyFailed reports whether any delivery failed.
+func anyFailed(ds []delivery) bool {
+	for _, d := range ds {
+		if !d.OK {
+			return true
+		}
+	}
+	return false
+}
+
+// briefError shortens err for the one-line summary: timeouts read "timeout",
+// and wrapped errors keep only their innermost message.
+func briefError(err error) string {
+	var timeout interface{ Timeout() bool }
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeout) && timeout.Timeout()) {
+		return "timeout"
+	}
+	msg := err.Error()
+	if i := strings.LastIndex(msg, ": "); i >= 0 {
+		msg = msg[i+2:]
+	}
+	return msg
+}
+
+// runReport is written by -report-json once a run has been reported.
+type runReport struct {
+	Command    string            `json:"command"`
+	ExitCode   int               `json:"exit_code"`
+	DurationMS int64             `json:"duration_ms"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Notified is false when the threshold, -notify-on, -exit-codes,
+	// -ignore-interrupts, or -success-every suppressed the notification;
+	// Deliveries is then empty.
+	Notified   bool       `json:"notified"`
+	Deliveries []delivery `json:"deliveries"`
+}
+
+// writeReport writes r as JSON to path, or to stderr when path is "-".
+func writeReport(path string, r runReport) error {
+	if r.Deliveries == nil {
+		r.Deliveries = []delivery{}
+	}
+	var w io.Writer = os.Stderr
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
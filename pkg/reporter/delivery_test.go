@@ -0,0 +1,115 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestSummarizeDeliveries(t *testing.T) {
+	tests := []struct {
+		name string
+		ds   []delivery
+		want string
+	}{
+		{
+			name: "all delivered",
+			ds:   []delivery{newDelivery("desktop", nil), newDelivery("slack", nil)},
+			want: "desktop ✓, slack ✓",
+		},
+		{
+			name: "timeout",
+			ds: []delivery{
+				newDelivery("desktop", nil),
+				newDelivery("ntfy", fmt.Errorf("posting to https://ntfy.sh/x: %w", context.DeadlineExceeded)),
+				newDelivery("slack", nil),
+			},
+			want: "desktop ✓, ntfy ✗ timeout, slack ✓",
+		},
+		{
+			name: "net timeout",
+			ds:   []delivery{newDelivery("webhook", fmt.Errorf("posting to https://example.com: dial tcp: %w", timeoutError{}))},
+			want: "webhook ✗ timeout",
+		},
+		{
+			name: "wrapped error",
+			ds: []delivery{
+				newDelivery("desktop", errors.New("notify-send: exit status 1")),
+				newDelivery("plain", errors.New("push to https://example.com returned 503 Service Unavailable")),
+			},
+			want: "desktop ✗ exit status 1, plain ✗ push to https://example.com returned 503 Service Unavailable",
+		},
+		{
+			name: "same provider twice",
+			ds: []delivery{
+				{Backend: "ntfy", Destination: "ntfy.sh", OK: true},
+				{Backend: "ntfy", Destination: "ntfy.example.com", err: errors.New("401 Unauthorized")},
+				{Backend: "slack", Destination: "hooks.slack.com", OK: true},
+			},
+			want: "ntfy (ntfy.sh) ✓, ntfy (ntfy.example.com) ✗ 401 Unauthorized, slack ✓",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeDeliveries(tt.ds); got != tt.want {
+				t.Errorf("summarizeDeliveries() = %q, want %q", got, tt.want)
+			}
+			if got, want := anyFailed(tt.ds), tt.name != "all delivered"; got != want {
+				t.Errorf("anyFailed() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	r := runReport{
+		Command:    "make",
+		ExitCode:   2,
+		DurationMS: 1500,
+		Notified:   true,
+		Deliveries: []delivery{newDelivery("desktop", nil), newDelivery("ntfy", errors.New("posting: timeout"))},
+	}
+	if err := writeReport(path, r); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not JSON: %v\n%s", err, data)
+	}
+	want := map[string]any{
+		"command":     "make",
+		"exit_code":   float64(2),
+		"duration_ms": float64(1500),
+		"notified":    true,
+		"deliveries": []any{
+			map[string]any{"backend": "desktop", "ok": true},
+			map[string]any{"backend": "ntfy", "ok": false, "error": "posting: timeout"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %v, want %v", got, want)
+	}
+
+	if err := writeReport(path, runReport{Command: "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if err := json.Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got["deliveries"], []any{}) {
+		t.Errorf("suppressed run report = %s, want an empty deliveries list", data)
+	}
+}
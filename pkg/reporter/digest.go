@@ -0,0 +1,119 @@
+package reporter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// digestTop is how many of the slowest runs a digest lists.
+const digestTop = 5
+
+// digestPeriods maps -period values to the window they cover. Windows are
+// rolling so a cron job firing just after midnight still reports a full day.
+var digestPeriods = map[string]struct {
+	window time.Duration
+	label  string
+}{
+	"day":  {24 * time.Hour, "Daily digest"},
+	"week": {7 * 24 * time.Hour, "Weekly digest"},
+}
+
+// buildDigest renders stats as a single notification: totals in the body and
+// the slowest runs as extra lines.
+func buildDigest(title string, s historyStats) notification {
+	n := notification{Title: title, Subtitle: "since " + s.Since.Local().Format("Mon Jan 2 15:04")}
+	if s.Runs == 0 {
+		n.Body = "no runs recorded"
+		return n
+	}
+	n.Body = fmt.Sprintf("%d runs, %s waiting, %d failed", s.Runs, formatDuration(ms(s.TotalMS)), s.Failures)
+	lines := make([]string, len(s.Slowest))
+	for i, e := range s.Slowest {
+		lines[i] = fmt.Sprintf("%d. %s %s", i+1, formatDuration(e.duration()), cleanLine(oneLine(e.Command)))
+		if e.ExitCode != 0 {
+			lines[i] += fmt.Sprintf(" (exit %d)", e.ExitCode)
+		}
+	}
+	n.Output = strings.Join(lines, "\n")
+	return n
+}
+
+// runDigest implements `reporter digest`, meant to be run from cron or
+// launchd.
+func runDigest(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+
+	fset := flag.NewFlagSet("digest", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter digest [flags]")
+		fset.PrintDefaults()
+	}
+	period := fset.String("period", "day", "summarize the past day or week")
+	resolvePush := registerPushFlags(fset, cfg, "HTTP endpoint to also push the digest to")
+	resolveDesktop := registerDesktopFlag(fset, cfg)
+	printOnly := fset.Bool("print", false, "print the digest instead of sending a notification")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	cfg.enforceLocks(fset)
+	p, ok := digestPeriods[*period]
+	if fset.NArg() != 0 || !ok {
+		fset.Usage()
+		return 2
+	}
+	targets, err := resolvePush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	desktop, err := resolveDesktop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	entries, ok := readHistory()
+	if !ok {
+		return 1
+	}
+	filter := historyFilter{since: time.Now().Add(-p.window)}
+	var matched []historyEntry
+	for _, e := range entries {
+		if filter.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	n := buildDigest(p.label, computeStats(matched, filter.since, digestTop))
+
+	// From cron without a push target, printing is the only delivery left;
+	// cron mails the output.
+	if *printOnly || (!desktop && len(targets) == 0) {
+		fmt.Println(n.Title)
+		fmt.Println(notificationText(n))
+		return 0
+	}
+	status := 0
+	if desktop {
+		agent := hostAgent(cfg)
+		if err := showDesktop(agent, terminalNotifier(cfg, currentEnvironment(), agent), n); err != nil {
+			fmt.Fprintf(os.Stderr, "[notify] %v\n", err)
+			status = 1
+		}
+	}
+	pushed, _ := pushAll(targets, n, spoolDir())
+	for _, d := range pushed {
+		if d.err != nil {
+			fmt.Fprintf(os.Stderr, "[push] %v\n", d.err)
+			status = 1
+		}
+	}
+	return status
+}
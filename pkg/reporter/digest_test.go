@@ -0,0 +1,34 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildDigest(t *testing.T) {
+	since := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	empty := buildDigest("Daily digest", historyStats{Since: since})
+	if empty.Body != "no runs recorded" || empty.Output != "" {
+		t.Errorf("empty digest = %+v", empty)
+	}
+
+	s := computeStats([]historyEntry{
+		{Command: "make test", DurationMS: 30000},
+		{Command: "cargo build\n  --release", DurationMS: 754000, ExitCode: 101},
+		{Command: "ls", DurationMS: 5},
+	}, since, digestTop)
+	n := buildDigest("Weekly digest", s)
+
+	if n.Title != "Weekly digest" || n.Failed {
+		t.Errorf("digest title/failed = %q/%v", n.Title, n.Failed)
+	}
+	if want := "3 runs, 13m04s waiting, 1 failed"; n.Body != want {
+		t.Errorf("digest body = %q, want %q", n.Body, want)
+	}
+	lines := strings.Split(n.Output, "\n")
+	if len(lines) != 3 || lines[0] != "1. 12m34s cargo build ⏎ --release (exit 101)" || lines[1] != "2. 30s make test" {
+		t.Errorf("digest lines = %q", lines)
+	}
+}
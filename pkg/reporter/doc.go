@@ -0,0 +1,19 @@
+// Package reporter runs commands and reports how they went. It is the
+// reporter command, whose main only calls Main, and it serves Go programs
+// that want the same notifications without shelling out to the binary:
+//
+//	r := reporter.Runner{
+//		Threshold: 30 * time.Second,
+//		PushURLs:  []string{"ntfy://ntfy.sh/builds"},
+//		Notifiers: []reporter.Notifier{
+//			reporter.Webhook{URL: "https://hooks.example.com/builds", Token: token},
+//		},
+//	}
+//	res, err := r.Run(ctx, exec.CommandContext(ctx, "make", "release"))
+//
+// A Runner runs commands and filters and delivers their notifications as
+// the command does. Notifications are Notification values; anything with a
+// Notify method can receive them. Webhook sends them as the reporter/v1
+// JSON payload the command's webhook provider sends, described by Payload,
+// so a receiver handles both alike.
+package reporter
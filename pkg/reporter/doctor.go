@@ -0,0 +1,352 @@
+package reporter
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// runDoctor implements `reporter doctor`, printing diagnostics that help
+// explain missing or slow notifications.
+func runDoctor(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, cfgErr := loadConfig(cwd)
+	if cfgErr != nil {
+		// The config check below reports the error; carry on with the
+		// defaults so the rest of the diagnosis still helps.
+		cfg = &config{values: map[string]any{}, sources: map[string]string{}, locked: map[string]bool{}}
+	}
+
+	fset := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter doctor [flags]")
+		fset.PrintDefaults()
+	}
+	send := fset.Bool("send", false, "also send a test notification to the desktop, the terminal multiplexer, and every push destination")
+	resolvePush := registerPushFlags(fset, cfg, "HTTP endpoint to check instead of the configured ones")
+	resolveDesktop := registerDesktopFlag(fset, cfg)
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	targets, err := resolvePush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	desktop, err := resolveDesktop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	healthy := cfgErr == nil
+
+	agent := hostAgent(cfg)
+	terminal := terminalNotifier(cfg, currentEnvironment(), agent)
+	printEnvironment(os.Stdout, currentEnvironment(), interactive(), agent, terminal)
+	fmt.Println()
+	printConfigCheck(os.Stdout, configFiles(cwd))
+	fmt.Println()
+	checks := desktopNotifierChecks(runtime.GOOS, runtime.GOOS == "linux" && isWSL(), exec.LookPath, probeSessionBus)
+	printNotifierChecks(os.Stdout, checks)
+	fmt.Println()
+	mux, popup := "", false
+	if mode := cfg.string("multiplexer", multiplexerAuto); mode != multiplexerNever {
+		mux, popup = detectMultiplexer(os.Getenv), mode == multiplexerPopup
+	}
+	printBackends(os.Stdout, backendChoice{desktop: desktop, agent: agent, terminal: terminal, notifiers: checks, multiplexer: mux, push: targets})
+	fmt.Println()
+	if muted, until := mutedUntil(mutePath(), time.Now()); muted {
+		fmt.Printf("Notifications are %s; turn them back on with reporter unmute\n\n", describeMute(until, time.Now()))
+	}
+	if n := countSpool(spoolDir()); n > 0 {
+		fmt.Printf("%s waiting in %s; send with reporter flush\n\n", plural(n, "queued push notification"), spoolDir())
+	}
+	if *send {
+		n := notification{
+			Title:    "reporter doctor",
+			Body:     "test notification; if you can read this, notifications reach you",
+			Subtitle: "reporter doctor",
+			Finished: time.Now(),
+		}
+		deliveries := sendTestNotification(n, desktop, agent, terminal, mux, popup, targets)
+		printTestDeliveries(os.Stdout, deliveries)
+		fmt.Println()
+		healthy = healthy && !anyFailed(deliveries)
+	}
+
+	path := latencyPath()
+	samples, err := loadLatency(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading %s: %v\n", path, err)
+		return 1
+	}
+	printLatency(os.Stdout, path, samples)
+	if !healthy {
+		return 1
+	}
+	return 0
+}
+
+// printConfigCheck parses each config file on its own and reports whether it
+// exists and is valid, so a typo in one file is pinned to that file.
+func printConfigCheck(w io.Writer, files []configFile) {
+	fmt.Fprintln(w, "Config files")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range files {
+		if f.path == "" {
+			fmt.Fprintf(tw, "  %s\tnone\tno %s here or in a parent directory\n", f.name, projectConfigName)
+			continue
+		}
+		status := "ok"
+		cfg := &config{values: map[string]any{}, sources: map[string]string{}, locked: map[string]bool{}}
+		switch err := cfg.loadFile(f.path); {
+		case errors.Is(err, fs.ErrNotExist):
+			status = "not found"
+		case err != nil:
+			status = "✗ " + strings.TrimPrefix(err.Error(), f.path+": ")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.name, shortenHome(f.path), status)
+	}
+	tw.Flush()
+}
+
+// notifierCheck is whether one of the local desktop notifiers can be used.
+type notifierCheck struct {
+	name   string // such as "D-Bus" or "notify-send"
+	ok     bool
+	detail string
+}
+
+// desktopNotifierChecks checks the local notifiers notifyDesktop tries on
+// goos, in the order it tries them. lookPath finds a program, and sessionBus
+// connects to the D-Bus session bus, returning its address.
+func desktopNotifierChecks(goos string, wsl bool, lookPath func(string) (string, error), sessionBus func() (string, error)) []notifierCheck {
+	program := func(name string, alternatives ...string) notifierCheck {
+		for _, p := range append([]string{name}, alternatives...) {
+			if path, err := lookPath(p); err == nil {
+				return notifierCheck{name: name, ok: true, detail: path}
+			}
+		}
+		return notifierCheck{name: name, detail: "not found in PATH"}
+	}
+	switch {
+	case goos == "darwin":
+		return []notifierCheck{program("osascript")}
+	case goos == "linux" && wsl:
+		return []notifierCheck{program("wsl-notify-send", "wsl-notify-send.exe"), program("powershell.exe", powershellFallback)}
+	case goos == "linux":
+		bus := notifierCheck{name: "D-Bus"}
+		if addr, err := sessionBus(); err != nil {
+			bus.detail = err.Error()
+		} else {
+			bus.ok, bus.detail = true, "session bus at "+addr
+		}
+		return []notifierCheck{bus, program("notify-send")}
+	}
+	return []notifierCheck{{name: goos, detail: "no desktop notifier for this platform"}}
+}
+
+// probeSessionBus connects to the D-Bus session bus and authenticates, which
+// is as far as a notification gets before the notification server answers.
+func probeSessionBus() (string, error) {
+	path, err := sessionBusPath()
+	if err != nil {
+		return "", err
+	}
+	c, err := dialSessionBus(time.Second)
+	if err != nil {
+		return "", err
+	}
+	c.Close()
+	return path, nil
+}
+
+func printNotifierChecks(w io.Writer, checks []notifierCheck) {
+	fmt.Fprintln(w, "Desktop notifiers")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range checks {
+		mark := "✗"
+		if c.ok {
+			mark = "✓"
+		}
+		fmt.Fprintf(tw, "  %s\t%s %s\n", c.name, mark, c.detail)
+	}
+	tw.Flush()
+}
+
+// backendChoice is what a run here would notify through.
+type backendChoice struct {
+	desktop         bool
+	agent, terminal string
+	notifiers       []notifierCheck
+	multiplexer     string
+	push            []pushTarget
+}
+
+// printBackends explains where a notification sent now would go.
+func printBackends(w io.Writer, b backendChoice) {
+	fmt.Fprintln(w, "Backends")
+	switch {
+	case !b.desktop:
+		fmt.Fprintln(w, "  desktop:      off (see Environment; -desktop always turns it on)")
+	case b.agent != "":
+		fmt.Fprintf(w, "  desktop:      through the host agent at %s\n", b.agent)
+	case b.terminal != "":
+		fmt.Fprintf(w, "  desktop:      raised by the terminal with %s escape sequences\n", strings.ToUpper(b.terminal[:3])+" "+b.terminal[3:])
+	default:
+		notifier := ""
+		for _, c := range b.notifiers {
+			if c.ok {
+				notifier = c.name
+				break
+			}
+		}
+		if notifier == "" {
+			fmt.Fprintln(w, "  desktop:      ✗ no notifier works here; notifications are printed on stderr instead")
+		} else {
+			fmt.Fprintf(w, "  desktop:      %s\n", notifier)
+		}
+	}
+	if b.multiplexer != "" {
+		fmt.Fprintf(w, "  multiplexer:  %s\n", b.multiplexer)
+	}
+	switch {
+	case len(b.push) == 0:
+		fmt.Fprintln(w, "  push:         none configured (set push_url or REPORTER_PUSH_URL)")
+	case !pushCompiled:
+		fmt.Fprintln(w, "  push:         ✗ not compiled into this build (built with -tags nopush)")
+	default:
+		for _, t := range b.push {
+			fmt.Fprintf(w, "  push:         %s to %s\n", t.Provider, pushDestination(t.URL))
+		}
+	}
+}
+
+// sendTestNotification sends n to every backend a run would use. Pushes are
+// sent once each, without retries or the offline queue, so the result is
+// known straight away.
+func sendTestNotification(n notification, desktop bool, agent, terminal, mux string, popup bool, targets []pushTarget) []delivery {
+	var deliveries []delivery
+	if desktop {
+		deliveries = append(deliveries, newDelivery("desktop", showDesktop(agent, terminal, n)))
+	}
+	if mux != "" {
+		deliveries = append(deliveries, newDelivery(mux, notifyMultiplexer(mux, popup, n)))
+	}
+	pushed := make([]delivery, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pushed[i] = newDelivery(t.Provider, pushOnce(t, n))
+			pushed[i].Destination = pushDestination(t.URL)
+		}()
+	}
+	wg.Wait()
+	return append(deliveries, pushed...)
+}
+
+func printTestDeliveries(w io.Writer, deliveries []delivery) {
+	fmt.Fprintln(w, "Test notification")
+	if len(deliveries) == 0 {
+		fmt.Fprintln(w, "  nowhere to send it: desktop notifications are off and no push destination is configured")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, d := range deliveries {
+		name := d.Backend
+		if d.Destination != "" {
+			name += " (" + d.Destination + ")"
+		}
+		status := "✓ sent"
+		if !d.OK {
+			status = "✗ " + d.Error
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, status)
+	}
+	tw.Flush()
+}
+
+// printEnvironment explains what was auto-detected about the environment and
+// the defaults that follow from it. agent is the host agent, if any, and
+// terminal the escape sequence protocol desktop notifications use.
+func printEnvironment(w io.Writer, env environment, tty bool, agent, terminal string) {
+	orNone := func(s string) string {
+		if s == "" {
+			return "none detected"
+		}
+		return s
+	}
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	fmt.Fprintln(w, "Environment")
+	fmt.Fprintf(w, "  container: %s\n", orNone(env.Container))
+	fmt.Fprintf(w, "  CI:        %s\n", orNone(env.CI))
+	switch {
+	case tty && env.Terminal != "":
+		fmt.Fprintf(w, "  terminal:  %s\n", env.Terminal)
+	case tty:
+		fmt.Fprintln(w, "  terminal:  attached")
+	default:
+		fmt.Fprintln(w, "  terminal:  none (stderr is not a terminal and there is no controlling terminal)")
+	}
+	for _, e := range env.Evidence {
+		fmt.Fprintf(w, "  detected:  %s\n", e)
+	}
+	if agent != "" {
+		fmt.Fprintf(w, "  agent:     %s (desktop notifications are shown by the agent)\n", agent)
+	}
+	if terminal != "" {
+		fmt.Fprintf(w, "  desktop:   shown by the terminal with %s escape sequences (terminal_notify)\n", strings.ToUpper(terminal[:3])+" "+terminal[3:])
+	}
+	fmt.Fprintf(w, "  defaults:  desktop %s, bell %s, quiet %s\n",
+		onOff(agent != "" || (tty && !env.headless())), onOff(!env.headless()), onOff(env.headless()))
+	switch {
+	case agent != "":
+	case env.remoteVSCode():
+		fmt.Fprintln(w, "  VS Code runs this shell remotely; install the companion extension (editors/vscode) to see notifications in the VS Code window")
+	case env.headless():
+		fmt.Fprintln(w, "  only push notifications are delivered here; set push_url, or override with -desktop, no_bell, and quiet")
+	}
+}
+
+func printLatency(w io.Writer, path string, samples []latencySample) {
+	fmt.Fprintln(w, "Notification latency")
+	if len(samples) == 0 {
+		fmt.Fprintln(w, "  no samples recorded; enable with -telemetry or telemetry = true in config")
+		return
+	}
+	fmt.Fprintf(w, "  %d samples in %s\n\n", len(samples), path)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  BACKEND\tSENDS\tFAILED\tP50\tP95\tMAX")
+	for _, s := range summarizeLatency(samples) {
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\t%s\t%s\n", s.Backend, s.Count, s.Failures,
+			roundLatency(s.P50), roundLatency(s.P95), roundLatency(s.Max))
+	}
+	tw.Flush()
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	if d < 10*time.Millisecond {
+		return d.Round(100 * time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
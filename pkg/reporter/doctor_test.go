@@ -0,0 +1,155 @@
+package reporter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDesktopNotifierChecks(t *testing.T) {
+	lookPath := func(found ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, f := range found {
+				if f == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+	bus := func() (string, error) { return "/run/user/1000/bus", nil }
+	noBus := func() (string, error) { return "", errors.New("no session bus") }
+
+	tests := []struct {
+		name       string
+		goos       string
+		wsl        bool
+		lookPath   func(string) (string, error)
+		sessionBus func() (string, error)
+		want       []notifierCheck
+	}{
+		{
+			name: "macOS", goos: "darwin", lookPath: lookPath("osascript"),
+			want: []notifierCheck{{name: "osascript", ok: true, detail: "/usr/bin/osascript"}},
+		},
+		{
+			name: "Linux with a session bus", goos: "linux", lookPath: lookPath(), sessionBus: bus,
+			want: []notifierCheck{
+				{name: "D-Bus", ok: true, detail: "session bus at /run/user/1000/bus"},
+				{name: "notify-send", detail: "not found in PATH"},
+			},
+		},
+		{
+			name: "Linux without one", goos: "linux", lookPath: lookPath("notify-send"), sessionBus: noBus,
+			want: []notifierCheck{
+				{name: "D-Bus", detail: "no session bus"},
+				{name: "notify-send", ok: true, detail: "/usr/bin/notify-send"},
+			},
+		},
+		{
+			name: "WSL", goos: "linux", wsl: true, lookPath: lookPath("wsl-notify-send.exe"),
+			want: []notifierCheck{
+				{name: "wsl-notify-send", ok: true, detail: "/usr/bin/wsl-notify-send.exe"},
+				{name: "powershell.exe", detail: "not found in PATH"},
+			},
+		},
+		{
+			name: "other", goos: "plan9", lookPath: lookPath(),
+			want: []notifierCheck{{name: "plan9", detail: "no desktop notifier for this platform"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := desktopNotifierChecks(tt.goos, tt.wsl, tt.lookPath, tt.sessionBus)
+			if len(got) != len(tt.want) {
+				t.Fatalf("desktopNotifierChecks() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("check %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPrintConfigCheck(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "config.toml")
+	bad := filepath.Join(dir, projectConfigName)
+	if err := os.WriteFile(good, []byte("threshold = \"30s\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("thresold = \"30s\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	printConfigCheck(&b, []configFile{
+		{"system", filepath.Join(dir, "missing.toml")},
+		{"user", good},
+		{"project", bad},
+	})
+	out := collapseSpaces(b.String())
+	for _, want := range []string{"system " + filepath.Join(dir, "missing.toml") + " not found", "user " + good + " ok", "project " + bad + " ✗ "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printConfigCheck() output lacks %q:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(out, bad+": ") {
+		t.Errorf("printConfigCheck() repeats the path in the error:\n%s", b.String())
+	}
+}
+
+func TestPrintBackends(t *testing.T) {
+	checks := []notifierCheck{{name: "D-Bus"}, {name: "notify-send", ok: true}}
+	tests := []struct {
+		name   string
+		choice backendChoice
+		want   []string
+	}{
+		{name: "off", choice: backendChoice{}, want: []string{"desktop: off", "push: none configured"}},
+		{name: "first working notifier", choice: backendChoice{desktop: true, notifiers: checks}, want: []string{"desktop: notify-send"}},
+		{name: "no notifier", choice: backendChoice{desktop: true, notifiers: checks[:1]}, want: []string{"no notifier works here"}},
+		{name: "agent", choice: backendChoice{desktop: true, agent: agentMountPath}, want: []string{"through the host agent at " + agentMountPath}},
+		{name: "terminal", choice: backendChoice{desktop: true, terminal: terminalNotifyOSC777}, want: []string{"OSC 777 escape sequences"}},
+		{name: "multiplexer", choice: backendChoice{multiplexer: multiplexerTmux}, want: []string{"multiplexer: tmux"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			printBackends(&b, tt.choice)
+			out := collapseSpaces(b.String())
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("printBackends() output lacks %q:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPrintTestDeliveries(t *testing.T) {
+	var b strings.Builder
+	d := newDelivery("ntfy", errors.New("401 Unauthorized"))
+	d.Destination = "ntfy.sh"
+	printTestDeliveries(&b, []delivery{newDelivery("desktop", nil), d})
+	out := collapseSpaces(b.String())
+	for _, want := range []string{"desktop ✓ sent", "ntfy (ntfy.sh) ✗ 401 Unauthorized"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printTestDeliveries() output lacks %q:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	printTestDeliveries(&b, nil)
+	if !strings.Contains(b.String(), "nowhere to send it") {
+		t.Errorf("printTestDeliveries() with no backends:\n%s", b.String())
+	}
+}
+
+// collapseSpaces joins the words of s with single spaces, so tests do not
+// depend on how tabwriter pads columns.
+func collapseSpaces(s string) string { return strings.Join(strings.Fields(s), " ") }
@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A drop directory holds notifications the file push provider wrote, one
+// JSON payload per file, on a machine with no network of its own. Once
+// something has carried the files to a machine that has one, `reporter spool
+// flush <dir>` sends each to that machine's push destinations, oldest first,
+// and deletes it.
+
+// flushDropDir sends every notification dropped in dir to targets, oldest
+// first. It stops at the first that could not be delivered or queued,
+// leaving it and the rest in place, and returns how many it sent.
+func flushDropDir(dir string, targets []pushTarget, now time.Time) (int, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, name := range spoolNames(dir, now) {
+		path := filepath.Join(dir, name)
+		claimed := path + claimedSuffix
+		if os.Rename(path, claimed) != nil {
+			continue // another reporter is sending it
+		}
+		_ = os.Chtimes(claimed, now, now)
+		data, err := os.ReadFile(claimed)
+		if err != nil {
+			os.Rename(claimed, path)
+			return sent, err
+		}
+		n, err := decodePayload(data)
+		if err != nil {
+			os.Rename(claimed, path)
+			return sent, fmt.Errorf("%s: %w", name, err)
+		}
+		// A push that failed for a transient reason is in the offline
+		// queue, which resends it; only other failures keep the file.
+		pushed, _ := pushAll(targets, delayed(n, now), spoolDir())
+		for _, d := range pushed {
+			if d.err != nil && !d.Queued {
+				os.Rename(claimed, path)
+				return sent, fmt.Errorf("%s: %s: %w", name, d.Backend, d.err)
+			}
+		}
+		os.Remove(claimed)
+		sent++
+	}
+	return sent, nil
+}
+
+// runSpool implements `reporter spool flush <dir>`.
+func runSpool(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+
+	fset := flag.NewFlagSet("spool flush", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter spool flush [flags] <dir>")
+		fmt.Fprintln(fset.Output(), "Send the notifications dropped in dir by a file:// push destination to the push destinations configured here.")
+		fset.PrintDefaults()
+	}
+	resolvePush := registerPushFlags(fset, cfg, "HTTP endpoint to send the dropped notifications to")
+	if len(args) == 0 || args[0] != "flush" {
+		fset.Usage()
+		return 2
+	}
+	if err := fset.Parse(args[1:]); err != nil {
+		return 2
+	}
+	cfg.enforceLocks(fset)
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return 2
+	}
+	if !pushCompiled {
+		fmt.Fprintln(os.Stderr, "spool flush: push support is not compiled into this build (built with -tags nopush)")
+		return 1
+	}
+	targets, err := resolvePush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "spool flush: no push destinations configured; set push_url or use -push-url")
+		return 2
+	}
+	sent, err := flushDropDir(fset.Arg(0), targets, time.Now())
+	fmt.Printf("sent %s\n", plural(sent, "notification"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "spool flush: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFlushDropDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	var commands []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var p Payload
+		json.Unmarshal(b, &p)
+		if status == http.StatusOK {
+			commands = append(commands, p.Command)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	drop := pushTarget{URL: "file://" + filepath.ToSlash(dir), Provider: pushProviderFile, PayloadVersion: 1}
+	for _, command := range []string{"make", "./deploy", "./backup"} {
+		if err := pushToPhone(drop, notification{Title: "Task finished", Body: "succeeded in 1h", Subtitle: command, Duration: time.Hour}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := pushTarget{URL: srv.URL, Provider: pushProviderWebhook, PayloadVersion: 1}
+
+	// A rejected notification stays, with those after it.
+	status = http.StatusForbidden
+	if sent, err := flushDropDir(dir, []pushTarget{target}, time.Now()); sent != 0 || err == nil {
+		t.Errorf("flushDropDir() to a forbidding server = %d, %v", sent, err)
+	}
+	if names := spoolNames(dir, time.Now()); len(names) != 3 {
+		t.Errorf("after a rejection %d files remain, want 3", len(names))
+	}
+
+	status = http.StatusOK
+	if sent, err := flushDropDir(dir, []pushTarget{target}, time.Now()); sent != 3 || err != nil {
+		t.Errorf("flushDropDir() = %d, %v; want 3 sent", sent, err)
+	}
+	if len(commands) != 3 || commands[0] != "make" || commands[2] != "./backup" {
+		t.Errorf("sent %q, want the notifications in the order dropped", commands)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d files left after flushing", len(entries))
+	}
+	if _, err := flushDropDir(filepath.Join(dir, "missing"), []pushTarget{target}, time.Now()); err == nil {
+		t.Error("flushDropDir() of a missing directory succeeded")
+	}
+}
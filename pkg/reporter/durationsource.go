@@ -0,0 +1,127 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shells report how long a command took in their own terms: zsh and bash 5
+// keep the clock in $EPOCHREALTIME, seconds with microseconds, and fish has
+// $CMD_DURATION in milliseconds. reporter notify takes them as they are,
+// with -duration-from source:value, rather than have hooks turn them into a
+// Go duration first; -duration also takes plain, possibly fractional,
+// seconds.
+
+// durationSource turns a value reported by a shell into the command's
+// duration, given when it was reported.
+type durationSource func(value string, now time.Time) (time.Duration, error)
+
+// durationSources are the sources -duration-from accepts, by name.
+var durationSources = map[string]durationSource{
+	// The command's start, as a Unix time.
+	"epoch":   startedAt(time.Second),
+	"epochms": startedAt(time.Millisecond),
+	"epochns": startedAt(time.Nanosecond),
+	"zsh":     startedAt(time.Second), // $EPOCHREALTIME when it started
+	"bash":    startedAt(time.Second), // $EPOCHREALTIME, in bash 5 and later
+	// The duration itself.
+	"s":    lasted(time.Second),
+	"ms":   lasted(time.Millisecond),
+	"fish": lasted(time.Millisecond), // $CMD_DURATION
+}
+
+// startedAt is a source giving the command's start as a Unix time in unit.
+// A start slightly ahead of now, as another CPU's clock can be, counts as
+// no time at all.
+func startedAt(unit time.Duration) durationSource {
+	return func(value string, now time.Time) (time.Duration, error) {
+		since, err := parseDecimal(value, unit)
+		if err != nil {
+			return 0, err
+		}
+		return max(now.Sub(time.Unix(0, 0).Add(since)), 0), nil
+	}
+}
+
+// lasted is a source giving the duration itself, in unit.
+func lasted(unit time.Duration) durationSource {
+	return func(value string, _ time.Time) (time.Duration, error) {
+		return parseDecimal(value, unit)
+	}
+}
+
+// parseDurationFrom parses a -duration-from value, source:value, as of now.
+func parseDurationFrom(s string, now time.Time) (time.Duration, error) {
+	name, value, ok := strings.Cut(s, ":")
+	source := durationSources[name]
+	if !ok || source == nil {
+		names := make([]string, 0, len(durationSources))
+		for name := range durationSources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return 0, fmt.Errorf("invalid -duration-from %q: want source:value, with source one of %s", s, strings.Join(names, ", "))
+	}
+	d, err := source(value, now)
+	if err != nil {
+		return 0, fmt.Errorf("invalid -duration-from %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// parseReportedDuration parses -duration: a Go duration such as 1m30s, or
+// seconds, such as 90 or 1.25.
+func parseReportedDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return 0, fmt.Errorf("negative duration %q", s)
+		}
+		return d, nil
+	}
+	d, err := parseDecimal(s, time.Second)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: want one such as 1m30s, or seconds such as 90.5", s)
+	}
+	return d, nil
+}
+
+// parseDecimal parses a non-negative decimal number of units, keeping every
+// digit down to the nanosecond, which float64 would not for a Unix time. A
+// comma may stand for the decimal point, as bash writes $EPOCHREALTIME in
+// some locales.
+func parseDecimal(s string, unit time.Duration) (time.Duration, error) {
+	whole, frac, _ := strings.Cut(strings.Replace(strings.TrimSpace(s), ",", ".", 1), ".")
+	if whole == "" && frac == "" {
+		return 0, errors.New("no number")
+	}
+	n := int64(0)
+	if whole != "" {
+		var err error
+		if n, err = strconv.ParseInt(whole, 10, 64); err != nil || whole[0] == '-' || whole[0] == '+' {
+			return 0, fmt.Errorf("%q is not a non-negative number", s)
+		}
+	}
+	if n > int64(1<<63-1)/int64(unit) {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	d := time.Duration(n) * unit
+	if len(frac) > 9 {
+		frac = frac[:9] // below a nanosecond in every unit
+	}
+	if frac != "" {
+		f, err := strconv.ParseUint(frac, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a non-negative number", s)
+		}
+		scale := int64(1)
+		for range frac {
+			scale *= 10
+		}
+		d += time.Duration(int64(f) * int64(unit) / scale)
+	}
+	return d, nil
+}
@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDurationFrom(t *testing.T) {
+	now := time.Unix(1718000000, 500_000_000)
+	tests := []struct {
+		from string
+		want time.Duration
+		err  bool
+	}{
+		{from: "zsh:1718000000.123456", want: 376544 * time.Microsecond},
+		{from: "bash:1717999990,5", want: 10 * time.Second}, // a locale's decimal comma
+		{from: "epoch:1717999940", want: 60*time.Second + 500*time.Millisecond},
+		{from: "epochms:1717999999500", want: time.Second},
+		{from: "epochns:1718000000499999999", want: time.Nanosecond},
+		{from: "zsh:1718000001.0", want: 0}, // a clock slightly ahead
+		{from: "fish:1234", want: 1234 * time.Millisecond},
+		{from: "ms:12.5", want: 12500 * time.Microsecond},
+		{from: "s:.25", want: 250 * time.Millisecond},
+		{from: "s:90", want: 90 * time.Second},
+		{from: "zsh:", err: true},
+		{from: "zsh:-5", err: true},
+		{from: "zsh:1e9", err: true},
+		{from: "tcsh:5", err: true},
+		{from: "1718000000", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDurationFrom(tt.from, now)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseDurationFrom(%q) = %v, %v; want %v (error %t)", tt.from, got, err, tt.want, tt.err)
+		}
+	}
+	if _, err := parseDurationFrom("tcsh:5", now); err == nil || !strings.Contains(err.Error(), "epoch, epochms, epochns, fish, ms, s, zsh") {
+		t.Errorf("error for an unknown source = %v, want the known ones listed", err)
+	}
+}
+
+func TestParseReportedDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{in: "1m30s", want: 90 * time.Second},
+		{in: "12345ms", want: 12345 * time.Millisecond},
+		{in: "0", want: 0},
+		{in: "90", want: 90 * time.Second},
+		{in: "1.25", want: 1250 * time.Millisecond},
+		{in: "0.000001", want: time.Microsecond},
+		{in: "-1s", err: true},
+		{in: "-1.5", err: true},
+		{in: "soon", err: true},
+		{in: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseReportedDuration(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseReportedDuration(%q) = %v, %v; want %v (error %t)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package reporter
+
+import "fmt"
+
+// energyMeter estimates the energy consumed between its creation and Stop.
+// Readings are system-wide, so other load on the machine is included.
+type energyMeter interface {
+	Stop() (joules float64, err error)
+}
+
+func formatEnergy(joules float64) string {
+	switch {
+	case joules >= 1e6:
+		return fmt.Sprintf("%.1f MJ", joules/1e6)
+	case joules >= 1e3:
+		return fmt.Sprintf("%.1f kJ", joules/1e3)
+	default:
+		return fmt.Sprintf("%.0f J", joules)
+	}
+}
@@ -0,0 +1,77 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const powermetricsInterval = time.Second
+
+// powermetricsMeter samples CPU package power with powermetrics(1) and
+// integrates it over the run. powermetrics only works as root.
+type powermetricsMeter struct {
+	cmd *exec.Cmd
+	out bytes.Buffer
+}
+
+func startEnergyMeter() (energyMeter, error) {
+	if os.Geteuid() != 0 {
+		return nil, errors.New("powermetrics requires root; run reporter with sudo to measure energy")
+	}
+	path, err := exec.LookPath("powermetrics")
+	if err != nil {
+		return nil, fmt.Errorf("powermetrics not found in PATH")
+	}
+	m := &powermetricsMeter{}
+	m.cmd = exec.Command(path, "--samplers", "cpu_power", "-i", strconv.Itoa(int(powermetricsInterval.Milliseconds())))
+	m.cmd.Stdout = &m.out
+	if err := m.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting powermetrics: %w", err)
+	}
+	return m, nil
+}
+
+func (m *powermetricsMeter) Stop() (float64, error) {
+	_ = m.cmd.Process.Signal(os.Interrupt)
+	_ = m.cmd.Wait()
+
+	// Each sample reports average power over one interval, e.g.
+	// "Combined Power (CPU + GPU + ANE): 1234 mW" on Apple silicon or
+	// "Intel energy model derived package power (CPUs+GT+SA): 4.56W".
+	var joules float64
+	samples := 0
+	scanner := bufio.NewScanner(&m.out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var watts float64
+		switch {
+		case strings.HasPrefix(line, "Combined Power"):
+			mw, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(line[strings.LastIndex(line, ":")+1:]), " mW"), 64)
+			if err != nil {
+				continue
+			}
+			watts = mw / 1000
+		case strings.Contains(line, "package power"):
+			w, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(line[strings.LastIndex(line, ":")+1:]), "W"), 64)
+			if err != nil {
+				continue
+			}
+			watts = w
+		default:
+			continue
+		}
+		joules += watts * powermetricsInterval.Seconds()
+		samples++
+	}
+	if samples == 0 {
+		return 0, errors.New("powermetrics produced no power samples")
+	}
+	return joules, nil
+}
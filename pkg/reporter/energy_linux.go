@@ -0,0 +1,130 @@
+package reporter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const raplRoot = "/sys/class/powercap"
+
+// raplSampleInterval is how often the counters are read. A package counter
+// wraps at max_energy_range_uj, as often as every 40 minutes or so under
+// load, so a multi-hour build read only at its start and end would lose
+// every wrap but one.
+const raplSampleInterval = 30 * time.Second
+
+type raplZone struct {
+	path     string
+	maxRange uint64
+	last     uint64 // the latest reading
+	total    uint64 // microjoules counted since the start
+}
+
+// advance counts the energy used up to a reading of now. A reading below
+// the last means the counter wrapped, once: samples are taken far more often
+// than it can wrap twice.
+func (z *raplZone) advance(now uint64) {
+	if now >= z.last {
+		z.total += now - z.last
+	} else {
+		z.total += z.maxRange - z.last + now
+	}
+	z.last = now
+}
+
+// raplMeter reads the Intel RAPL package counters exposed by the powercap
+// driver every raplSampleInterval, adding up what each zone counted in
+// between.
+type raplMeter struct {
+	zones []raplZone
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func startEnergyMeter() (energyMeter, error) {
+	return startRaplMeter(raplRoot, raplSampleInterval)
+}
+
+func startRaplMeter(root string, interval time.Duration) (*raplMeter, error) {
+	dirs, _ := filepath.Glob(filepath.Join(root, "intel-rapl:*"))
+	m := &raplMeter{stop: make(chan struct{}), done: make(chan struct{})}
+	for _, dir := range dirs {
+		// Top-level package domains only; subzones (intel-rapl:0:0) are
+		// already counted by their parent.
+		if strings.Count(filepath.Base(dir), ":") != 1 {
+			continue
+		}
+		path := filepath.Join(dir, "energy_uj")
+		start, err := readUintFile(path)
+		if err != nil {
+			continue
+		}
+		// Without its range, a wrap cannot be told from a huge jump.
+		maxRange, err := readUintFile(filepath.Join(dir, "max_energy_range_uj"))
+		if err != nil || maxRange == 0 {
+			continue
+		}
+		m.zones = append(m.zones, raplZone{path: path, maxRange: maxRange, last: start})
+	}
+	if len(m.zones) == 0 {
+		return nil, errors.New("no readable RAPL domains in " + root + " (energy_uj is often root-only)")
+	}
+	go m.sampleEvery(interval)
+	return m, nil
+}
+
+func (m *raplMeter) sampleEvery(interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			_ = m.sample() // a zone unreadable now is caught up next time
+		}
+	}
+}
+
+// sample advances every zone to its current reading, returning the first
+// error reading one.
+func (m *raplMeter) sample() error {
+	var first error
+	for i := range m.zones {
+		now, err := readUintFile(m.zones[i].path)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		m.zones[i].advance(now)
+	}
+	return first
+}
+
+func (m *raplMeter) Stop() (float64, error) {
+	close(m.stop)
+	<-m.done
+	if err := m.sample(); err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, z := range m.zones {
+		total += z.total
+	}
+	return float64(total) / 1e6, nil
+}
+
+func readUintFile(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
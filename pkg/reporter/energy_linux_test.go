@@ -0,0 +1,46 @@
+package reporter
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRaplMeter(t *testing.T) {
+	root := t.TempDir()
+	set := func(zone string, uj uint64) {
+		writeFile(t, filepath.Join(root, zone, "energy_uj"), strconv.FormatUint(uj, 10)+"\n")
+	}
+	set("intel-rapl:0", 900_000_000)
+	writeFile(t, filepath.Join(root, "intel-rapl:0", "max_energy_range_uj"), "1000000000\n")
+	set("intel-rapl:0:0", 5) // a subzone, counted by its package
+	set("intel-rapl:1", 7)   // no range, so a wrap could not be told apart
+
+	m, err := startRaplMeter(root, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.zones) != 1 {
+		t.Fatalf("zones = %+v, want only intel-rapl:0", m.zones)
+	}
+	// The counter wraps twice over the run, with a sample in between.
+	set("intel-rapl:0", 50_000_000)
+	if err := m.sample(); err != nil {
+		t.Fatal(err)
+	}
+	set("intel-rapl:0", 0)
+	if err := m.sample(); err != nil {
+		t.Fatal(err)
+	}
+	set("intel-rapl:0", 25_000_000)
+	joules, err := m.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100 J up to the first wrap, 50 J after it, 950 J up to the second,
+	// and 25 J after that.
+	if joules != 1125 {
+		t.Errorf("Stop() = %v J, want 1125", joules)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package reporter
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func startEnergyMeter() (energyMeter, error) {
+	return nil, fmt.Errorf("energy measurement is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,23 @@
+package reporter
+
+import "testing"
+
+func TestFormatEnergy(t *testing.T) {
+	tests := []struct {
+		name   string
+		joules float64
+		want   string
+	}{
+		{name: "joules", joules: 850, want: "850 J"},
+		{name: "kilojoules", joules: 12_345, want: "12.3 kJ"},
+		{name: "megajoules", joules: 3_600_000, want: "3.6 MJ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatEnergy(tt.joules); got != tt.want {
+				t.Errorf("formatEnergy(%v) = %q, want %q", tt.joules, got, tt.want)
+			}
+		})
+	}
+}
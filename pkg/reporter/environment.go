@@ -0,0 +1,130 @@
+package reporter
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// environment describes where reporter is running, as far as that changes its
+// defaults. Containers and CI runners have no desktop and nobody at the
+// terminal, so desktop notifications, the bell, and the stderr status line
+// are off by default there and pushes are the only delivery.
+type environment struct {
+	Container string // e.g. "docker", "kubernetes"; "" if none detected
+	CI        string // e.g. "GitHub Actions"; "" if none detected
+	// Terminal is the terminal emulator named by TERM_PROGRAM, e.g.
+	// "VS Code", and SSH is set when the session came in over SSH. Neither
+	// changes the defaults; they explain them in reporter doctor.
+	Terminal string
+	SSH      bool
+	// Evidence explains each detection, for reporter doctor.
+	Evidence []string
+}
+
+// headless reports whether the environment has no desktop to notify.
+func (e environment) headless() bool { return e.Container != "" || e.CI != "" }
+
+// ciSystems maps an environment variable each CI system sets to its name.
+// Generic CI=true is checked last.
+var ciSystems = []struct{ env, name string }{
+	{"GITHUB_ACTIONS", "GitHub Actions"},
+	{"GITLAB_CI", "GitLab CI"},
+	{"BUILDKITE", "Buildkite"},
+	{"CIRCLECI", "CircleCI"},
+	{"JENKINS_URL", "Jenkins"},
+	{"TF_BUILD", "Azure Pipelines"},
+	{"TEAMCITY_VERSION", "TeamCity"},
+	{"BITBUCKET_BUILD_NUMBER", "Bitbucket Pipelines"},
+	{"DRONE", "Drone"},
+	{"TRAVIS", "Travis CI"},
+	{"CI", "CI"},
+}
+
+// terminalNames gives display names for common TERM_PROGRAM values; others
+// are shown as they are.
+var terminalNames = map[string]string{
+	"vscode":         terminalVSCode,
+	"iTerm.app":      "iTerm2",
+	"Apple_Terminal": "Terminal.app",
+}
+
+const (
+	terminalVSCode    = "VS Code"
+	terminalJetBrains = "JetBrains"
+)
+
+// cgroupRuntimes maps a substring of /proc/self/cgroup to the container
+// runtime that puts it there.
+var cgroupRuntimes = []struct{ marker, name string }{
+	{"kubepods", "kubernetes"},
+	{"docker", "docker"},
+	{"libpod", "podman"},
+	{"containerd", "containerd"},
+	{"lxc", "lxc"},
+}
+
+// currentEnvironment is detected once per process.
+var currentEnvironment = sync.OnceValue(func() environment {
+	return detectEnvironment(os.Getenv, os.ReadFile, func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+})
+
+// detectEnvironment looks for a container runtime and a CI system using the
+// given lookups, so tests can fake them.
+func detectEnvironment(getenv func(string) string, readFile func(string) ([]byte, error), exists func(string) bool) environment {
+	var e environment
+	switch {
+	case getenv("KUBERNETES_SERVICE_HOST") != "":
+		e.Container = "kubernetes"
+		e.Evidence = append(e.Evidence, "KUBERNETES_SERVICE_HOST is set")
+	case exists("/.dockerenv"):
+		e.Container = "docker"
+		e.Evidence = append(e.Evidence, "/.dockerenv exists")
+	case exists("/run/.containerenv"):
+		e.Container = "podman"
+		e.Evidence = append(e.Evidence, "/run/.containerenv exists")
+	case getenv("container") != "":
+		// Set by systemd-nspawn, LXC, and Podman, among others.
+		e.Container = getenv("container")
+		e.Evidence = append(e.Evidence, "container="+e.Container)
+	default:
+		if data, err := readFile("/proc/self/cgroup"); err == nil {
+			for _, rt := range cgroupRuntimes {
+				if strings.Contains(string(data), rt.marker) {
+					e.Container = rt.name
+					e.Evidence = append(e.Evidence, "/proc/self/cgroup mentions "+rt.marker)
+					break
+				}
+			}
+		}
+	}
+
+	if e.Terminal = getenv("TERM_PROGRAM"); terminalNames[e.Terminal] != "" {
+		e.Terminal = terminalNames[e.Terminal]
+	}
+	if e.Terminal == "" && strings.HasPrefix(getenv("TERMINAL_EMULATOR"), "JetBrains") {
+		// JetBrains IDEs' terminal sets TERMINAL_EMULATOR=JetBrains-JediTerm
+		// instead of TERM_PROGRAM.
+		e.Terminal = terminalJetBrains
+	}
+	e.SSH = getenv("SSH_CONNECTION") != "" || getenv("SSH_TTY") != ""
+
+	for _, ci := range ciSystems {
+		if v := getenv(ci.env); v != "" && v != "false" && v != "0" {
+			e.CI = ci.name
+			e.Evidence = append(e.Evidence, ci.env+"="+v)
+			break
+		}
+	}
+	return e
+}
+
+// remoteVSCode reports whether this is a VS Code terminal whose shell runs
+// away from the VS Code window, over Remote-SSH or in a dev container, so
+// desktop notifiers here would not reach the user.
+func (e environment) remoteVSCode() bool {
+	return e.Terminal == terminalVSCode && (e.SSH || e.Container != "")
+}
@@ -0,0 +1,124 @@
+package reporter
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectEnvironment(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		files  map[string]string
+		want   environment
+		isHead bool
+	}{
+		{name: "laptop", files: map[string]string{"/proc/self/cgroup": "0::/user.slice/user-1000.slice/session-2.scope\n"}},
+		{
+			name:   "docker",
+			files:  map[string]string{"/.dockerenv": ""},
+			want:   environment{Container: "docker", Evidence: []string{"/.dockerenv exists"}},
+			isHead: true,
+		},
+		{
+			name:   "kubernetes with cgroup v1",
+			env:    map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			files:  map[string]string{"/proc/self/cgroup": "12:pids:/kubepods/besteffort/pod1\n"},
+			want:   environment{Container: "kubernetes", Evidence: []string{"KUBERNETES_SERVICE_HOST is set"}},
+			isHead: true,
+		},
+		{
+			name:   "cgroup only",
+			files:  map[string]string{"/proc/self/cgroup": "1:name=systemd:/docker/0123abcd\n"},
+			want:   environment{Container: "docker", Evidence: []string{"/proc/self/cgroup mentions docker"}},
+			isHead: true,
+		},
+		{
+			name:   "nspawn",
+			env:    map[string]string{"container": "systemd-nspawn"},
+			want:   environment{Container: "systemd-nspawn", Evidence: []string{"container=systemd-nspawn"}},
+			isHead: true,
+		},
+		{
+			name:   "github actions in podman",
+			env:    map[string]string{"GITHUB_ACTIONS": "true", "CI": "true"},
+			files:  map[string]string{"/run/.containerenv": ""},
+			want:   environment{Container: "podman", CI: "GitHub Actions", Evidence: []string{"/run/.containerenv exists", "GITHUB_ACTIONS=true"}},
+			isHead: true,
+		},
+		{
+			name:   "generic CI",
+			env:    map[string]string{"CI": "1"},
+			want:   environment{CI: "CI", Evidence: []string{"CI=1"}},
+			isHead: true,
+		},
+		{name: "CI disabled", env: map[string]string{"CI": "false"}},
+		{
+			name: "VS Code Remote-SSH",
+			env:  map[string]string{"TERM_PROGRAM": "vscode", "SSH_CONNECTION": "10.0.0.2 50000 10.0.0.3 22"},
+			want: environment{Terminal: "VS Code", SSH: true},
+		},
+		{name: "other terminal", env: map[string]string{"TERM_PROGRAM": "WezTerm"}, want: environment{Terminal: "WezTerm"}},
+		{name: "JetBrains", env: map[string]string{"TERMINAL_EMULATOR": "JetBrains-JediTerm"}, want: environment{Terminal: "JetBrains"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectEnvironment(
+				func(k string) string { return tt.env[k] },
+				func(path string) ([]byte, error) {
+					if data, ok := tt.files[path]; ok {
+						return []byte(data), nil
+					}
+					return nil, errors.New("not found")
+				},
+				func(path string) bool { _, ok := tt.files[path]; return ok },
+			)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectEnvironment() = %+v, want %+v", got, tt.want)
+			}
+			if got.remoteVSCode() != (tt.name == "VS Code Remote-SSH") {
+				t.Errorf("remoteVSCode() = %v", got.remoteVSCode())
+			}
+			if got.headless() != tt.isHead {
+				t.Errorf("headless() = %v, want %v", got.headless(), tt.isHead)
+			}
+		})
+	}
+}
+
+func TestPrintEnvironment(t *testing.T) {
+	var buf bytes.Buffer
+	printEnvironment(&buf, environment{Container: "docker", Evidence: []string{"/.dockerenv exists"}}, true, "", "")
+	for _, want := range []string{"container: docker", "detected:  /.dockerenv exists", "desktop off, bell off, quiet on", "only push notifications"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	printEnvironment(&buf, environment{}, true, "", "")
+	if out := buf.String(); !strings.Contains(out, "container: none detected") || !strings.Contains(out, "desktop on, bell on, quiet off") {
+		t.Errorf("output for a desktop session:\n%s", out)
+	}
+
+	buf.Reset()
+	printEnvironment(&buf, environment{Container: "docker"}, true, agentMountPath, "")
+	if out := buf.String(); !strings.Contains(out, "agent:     "+agentMountPath) || !strings.Contains(out, "desktop on, bell off") || strings.Contains(out, "only push") {
+		t.Errorf("output for a devcontainer with a host agent:\n%s", out)
+	}
+
+	buf.Reset()
+	printEnvironment(&buf, environment{Terminal: terminalVSCode, SSH: true}, true, "", "")
+	if out := buf.String(); !strings.Contains(out, "terminal:  VS Code") || !strings.Contains(out, "companion extension") {
+		t.Errorf("output for VS Code over SSH:\n%s", out)
+	}
+
+	buf.Reset()
+	printEnvironment(&buf, environment{SSH: true}, true, "", terminalNotifyOSC777)
+	if out := buf.String(); !strings.Contains(out, "shown by the terminal with OSC 777 escape sequences") {
+		t.Errorf("output over SSH with terminal notifications:\n%s", out)
+	}
+}
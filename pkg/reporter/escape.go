@@ -0,0 +1,128 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Every string that crosses into another language — an AppleScript literal, a
+// shell word, notification markup, a JSON document — goes through one of the
+// functions in this file. Command lines are user- and filename-controlled, so
+// each escaper must be total: any input yields a literal that decodes back to
+// the (control-character-scrubbed) input. escape_test.go fuzzes that property.
+
+// scrubControl replaces ASCII control characters, including NUL, with spaces.
+// They cannot be passed through exec arguments (NUL) or would break
+// single-line notification layouts.
+func scrubControl(s string) string {
+	if strings.IndexFunc(s, isControl) < 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if isControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// escapeForAppleScript returns s ready to be placed between double quotes in
+// an AppleScript string literal.
+func escapeForAppleScript(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+	)
+	return replacer.Replace(scrubControl(s))
+}
+
+// escapeMarkup escapes s for the XML-like body markup understood by
+// freedesktop notification servers, where a stray "&" or "<" can make the whole
+// body fail to render.
+func escapeMarkup(s string) string {
+	replacer := strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	)
+	return replacer.Replace(s)
+}
+
+// escapeSlack escapes the three characters Slack reserves for mrkdwn control
+// sequences such as <@user> and <url|label>: the same three, escaped the
+// same way, as freedesktop markup.
+func escapeSlack(s string) string { return escapeMarkup(s) }
+
+// escapeMarkdownV2 escapes s for Telegram's MarkdownV2, which rejects the whole
+// message if any of its reserved characters appears unescaped in plain text.
+func escapeMarkdownV2(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(markdownV2Reserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// markdownV2Reserved lists the characters MarkdownV2 requires to be escaped
+// outside code.
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+
+// escapeMarkdownV2Code escapes s for a MarkdownV2 pre or code entity, inside
+// which only ` and \ are special.
+func escapeMarkdownV2Code(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		"`", "\\`",
+	)
+	return replacer.Replace(s)
+}
+
+// jsonString returns s as a JSON string literal. Unlike json.Marshal it leaves
+// <, >, and & readable. Invalid UTF-8 is replaced with U+FFFD.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string cannot fail
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// sqlQuote returns s as a SQL string literal. NUL cannot appear in SQLite text
+// passed through the shell, so it is dropped.
+func sqlQuote(s string) string {
+	s = strings.ReplaceAll(s, "\x00", "")
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// shellQuote quotes s for safe use as a single word in POSIX shells and fish.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/._-+=:@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powershellQuote returns s as a single-quoted PowerShell string literal.
+// PowerShell also treats typographic single quotes as delimiters, so all of
+// them are doubled.
+func powershellQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,390 @@
+package reporter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEscapeForAppleScript(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "no special characters",
+			input: "hello world",
+			want:  "hello world",
+		},
+		{
+			name:  "double quotes",
+			input: `say "hello"`,
+			want:  `say \"hello\"`,
+		},
+		{
+			name:  "backslash",
+			input: `path\to\file`,
+			want:  `path\\to\\file`,
+		},
+		{
+			name:  "newline",
+			input: "line1\nline2",
+			want:  "line1 line2",
+		},
+		{
+			name:  "carriage return",
+			input: "line1\rline2",
+			want:  "line1 line2",
+		},
+		{
+			name:  "tab",
+			input: "col1\tcol2",
+			want:  "col1 col2",
+		},
+		{
+			name:  "mixed special characters",
+			input: "say \"hello\"\nwith\\path\tand\rtabs",
+			want:  "say \\\"hello\\\" with\\\\path and tabs",
+		},
+		{
+			name:  "empty string",
+			input: "",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := escapeForAppleScript(tt.input)
+			if got != tt.want {
+				t.Errorf("escapeForAppleScript(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "/usr/local/bin/reporter", want: "/usr/local/bin/reporter"},
+		{input: "/Users/me/My Tools/reporter", want: "'/Users/me/My Tools/reporter'"},
+		{input: "it's", want: `'it'\''s'`},
+		{input: "$(rm -rf ~)", want: "'$(rm -rf ~)'"},
+		{input: "", want: "''"},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.input); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPowershellQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "make build", want: "'make build'"},
+		{input: "it's", want: "'it''s'"},
+		{input: "smart ’quote", want: "'smart ’’quote'"},
+		{input: "$(Remove-Item C:\\)", want: "'$(Remove-Item C:\\)'"},
+	}
+
+	for _, tt := range tests {
+		if got := powershellQuote(tt.input); got != tt.want {
+			t.Errorf("powershellQuote(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeMarkup(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "make test", want: "make test"},
+		{input: "a && b", want: "a &amp;&amp; b"},
+		{input: "cat <in >out", want: "cat &lt;in &gt;out"},
+		{input: "echo <b>bold</b>", want: "echo &lt;b&gt;bold&lt;/b&gt;"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeMarkup(tt.input); got != tt.want {
+			t.Errorf("escapeMarkup(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		code  string
+	}{
+		{input: "make test", want: "make test", code: "make test"},
+		{input: "v1.2 (rc-1)!", want: `v1\.2 \(rc\-1\)\!`, code: "v1.2 (rc-1)!"},
+		{input: "*bold* _it_ [x](y)", want: `\*bold\* \_it\_ \[x\]\(y\)`, code: "*bold* _it_ [x](y)"},
+		{input: "`code` \\", want: "\\`code\\` \\\\", code: "\\`code\\` \\\\"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeMarkdownV2(tt.input); got != tt.want {
+			t.Errorf("escapeMarkdownV2(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if got := escapeMarkdownV2Code(tt.input); got != tt.code {
+			t.Errorf("escapeMarkdownV2Code(%q) = %q, want %q", tt.input, got, tt.code)
+		}
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "make test", want: `"make test"`},
+		{input: `say "hi" && <exit>`, want: `"say \"hi\" && <exit>"`},
+		{input: "tab\there", want: `"tab\there"`},
+		{input: "bad \xff byte", want: `"bad ` + "�" + ` byte"`},
+	}
+
+	for _, tt := range tests {
+		if got := jsonString(tt.input); got != tt.want {
+			t.Errorf("jsonString(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+// unquoteAppleScript decodes the body of an AppleScript string literal and
+// reports whether it contained an unescaped quote that would end the literal.
+func unquoteAppleScript(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return "", false
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", false
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
+
+// unquoteShell decodes a word produced by shellQuote using POSIX rules.
+func unquoteShell(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case strings.IndexByte(" \t\n;&|<>()$`\"*?[]#~", c) >= 0:
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// unquotePowershell decodes a single-quoted PowerShell literal.
+func unquotePowershell(s string) (string, bool) {
+	isQuote := func(r rune) bool {
+		return r == '\'' || r == '‘' || r == '’' || r == '‚' || r == '‛'
+	}
+	runes := []rune(s)
+	if len(runes) < 2 || !isQuote(runes[0]) || !isQuote(runes[len(runes)-1]) {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 1; i < len(runes)-1; i++ {
+		if isQuote(runes[i]) {
+			if i+1 >= len(runes)-1 || runes[i+1] != runes[i] {
+				return "", false
+			}
+			i++
+		}
+		b.WriteRune(runes[i])
+	}
+	return b.String(), true
+}
+
+var fuzzSeeds = []string{
+	"",
+	"make test",
+	`say "hello" \ there`,
+	"line1\nline2\r\x00\x7f",
+	"it's a ’smart’ quote",
+	"$(rm -rf ~); `id` && <b>&amp;</b>",
+	"bad \xff utf8",
+}
+
+func FuzzEscapeForAppleScript(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, ok := unquoteAppleScript(escapeForAppleScript(s))
+		if !ok {
+			t.Fatalf("escapeForAppleScript(%q) lets the literal terminate early", s)
+		}
+		if want := scrubControl(s); got != want {
+			t.Fatalf("escapeForAppleScript(%q) decodes to %q, want %q", s, got, want)
+		}
+		if strings.IndexFunc(got, isControl) >= 0 {
+			t.Fatalf("escapeForAppleScript(%q) keeps control characters", s)
+		}
+	})
+}
+
+func FuzzEscapeMarkup(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	unescape := strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+	f.Fuzz(func(t *testing.T, s string) {
+		got := escapeMarkup(s)
+		if strings.ContainsAny(got, "<>") {
+			t.Fatalf("escapeMarkup(%q) = %q contains markup delimiters", s, got)
+		}
+		if back := unescape.Replace(got); back != s {
+			t.Fatalf("escapeMarkup(%q) decodes to %q", s, back)
+		}
+	})
+}
+
+func FuzzJSONString(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var back string
+		if err := json.Unmarshal([]byte(jsonString(s)), &back); err != nil {
+			t.Fatalf("jsonString(%q) is not valid JSON: %v", s, err)
+		}
+		if want := strings.ToValidUTF8(s, "�"); utf8.ValidString(s) && back != want {
+			t.Fatalf("jsonString(%q) decodes to %q", s, back)
+		}
+	})
+}
+
+func TestSQLQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "make test", want: "'make test'"},
+		{input: "it's", want: "'it''s'"},
+		{input: "'); DROP TABLE runs; --", want: "'''); DROP TABLE runs; --'"},
+		{input: "nul\x00byte", want: "'nulbyte'"},
+	}
+
+	for _, tt := range tests {
+		if got := sqlQuote(tt.input); got != tt.want {
+			t.Errorf("sqlQuote(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func FuzzSQLQuote(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		q := sqlQuote(s)
+		if len(q) < 2 || q[0] != '\'' || q[len(q)-1] != '\'' {
+			t.Fatalf("sqlQuote(%q) = %q is not a single literal", s, q)
+		}
+		body := q[1 : len(q)-1]
+		if strings.Contains(strings.ReplaceAll(body, "''", ""), "'") {
+			t.Fatalf("sqlQuote(%q) = %q contains an unescaped quote", s, q)
+		}
+		if got := strings.ReplaceAll(body, "''", "'"); got != strings.ReplaceAll(s, "\x00", "") {
+			t.Fatalf("sqlQuote(%q) decodes to %q", s, got)
+		}
+	})
+}
+
+func FuzzShellQuote(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, ok := unquoteShell(shellQuote(s))
+		if !ok || got != s {
+			t.Fatalf("shellQuote(%q) = %q does not decode to a single word with the input", s, shellQuote(s))
+		}
+	})
+}
+
+func FuzzPowershellQuote(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			return
+		}
+		got, ok := unquotePowershell(powershellQuote(s))
+		if !ok || got != s {
+			t.Fatalf("powershellQuote(%q) = %q does not decode to the input", s, powershellQuote(s))
+		}
+	})
+}
+
+// unescapeBackslashes drops the backslash before each escaped character. It
+// reports false if s ends in an unpaired backslash.
+func unescapeBackslashes(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) {
+				return "", false
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
+
+func FuzzEscapeMarkdownV2(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			return
+		}
+		got := escapeMarkdownV2(s)
+		for i := 0; i < len(got); i++ {
+			if got[i] == '\\' {
+				i++
+			} else if strings.IndexByte(markdownV2Reserved, got[i]) >= 0 {
+				t.Fatalf("escapeMarkdownV2(%q) = %q leaves %q unescaped", s, got, got[i])
+			}
+		}
+		if back, ok := unescapeBackslashes(got); !ok || back != s {
+			t.Fatalf("escapeMarkdownV2(%q) decodes to %q", s, back)
+		}
+		if back, ok := unescapeBackslashes(escapeMarkdownV2Code(s)); !ok || back != s {
+			t.Fatalf("escapeMarkdownV2Code(%q) decodes to %q", s, back)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exitCodeSet is the set of exit statuses that -exit-codes lets notify,
+// written as a comma-separated list of codes and ranges such as
+// "1,2,100-125". A nil set allows every status.
+type exitCodeSet []exitCodeRange
+
+type exitCodeRange struct{ lo, hi int }
+
+// maxExitCode is the largest status a process can exit with.
+const maxExitCode = 255
+
+func parseExitCodes(s string) (exitCodeSet, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var set exitCodeSet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		r, err := exitCodeRangeOf(lo, hi, isRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit code %q: want a code or range from 0 to %d, such as 1 or 100-125", part, maxExitCode)
+		}
+		set = append(set, r)
+	}
+	return set, nil
+}
+
+func exitCodeRangeOf(lo, hi string, isRange bool) (exitCodeRange, error) {
+	l, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return exitCodeRange{}, err
+	}
+	h := l
+	if isRange {
+		if h, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+			return exitCodeRange{}, err
+		}
+	}
+	if l < 0 || h > maxExitCode || l > h {
+		return exitCodeRange{}, errors.New("out of range")
+	}
+	return exitCodeRange{l, h}, nil
+}
+
+// allows reports whether a run that exited with code may notify.
+func (s exitCodeSet) allows(code int) bool {
+	if s == nil {
+		return true
+	}
+	for _, r := range s {
+		if code >= r.lo && code <= r.hi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package reporter
+
+import "testing"
+
+func TestParseExitCodes(t *testing.T) {
+	tests := []struct {
+		in      string
+		allowed []int
+		denied  []int
+		wantErr bool
+	}{
+		{in: "", allowed: []int{0, 1, 255}},
+		{in: "1", allowed: []int{1}, denied: []int{0, 2}},
+		{in: "1,2,100-125", allowed: []int{1, 2, 100, 110, 125}, denied: []int{0, 3, 99, 126}},
+		{in: " 0 , 130 - 131 ", allowed: []int{0, 130, 131}, denied: []int{1, 132}},
+		{in: "1,,2", wantErr: true},
+		{in: "x", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "5-3", wantErr: true},
+		{in: "256", wantErr: true},
+		{in: "1-2-3", wantErr: true},
+	}
+	for _, tt := range tests {
+		set, err := parseExitCodes(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseExitCodes(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		for _, code := range tt.allowed {
+			if !set.allows(code) {
+				t.Errorf("parseExitCodes(%q) does not allow %d", tt.in, code)
+			}
+		}
+		for _, code := range tt.denied {
+			if set.allows(code) {
+				t.Errorf("parseExitCodes(%q) allows %d", tt.in, code)
+			}
+		}
+	}
+}
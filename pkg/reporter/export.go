@@ -0,0 +1,212 @@
+package reporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// `reporter export` writes timing baselines that can be pooled across a team
+// without sharing anything about the machine they came from: commands are cut
+// down to their program and subcommand words, then hashed with a salt the team
+// shares, so the same build hashes alike on every laptop, while paths,
+// arguments, labels and host names never leave it.
+
+// exportSchema identifies the export format, for tools pooling the files.
+const exportSchema = "reporter-export/v1"
+
+// exportDoc is what `reporter export` prints.
+type exportDoc struct {
+	Schema   string           `json:"schema"`
+	Since    time.Time        `json:"since"`
+	Until    time.Time        `json:"until"`
+	Machine  string           `json:"machine"` // the salted hash of the host name
+	OS       string           `json:"os"`
+	Arch     string           `json:"arch"`
+	CPUs     int              `json:"cpus"`
+	Commands []exportedTiming `json:"commands"`
+}
+
+// exportedTiming is the baseline for one command shape. Durations cover the
+// successful runs only, so a build that fails in its first second does not
+// drag the baseline down.
+type exportedTiming struct {
+	Command  string `json:"command"` // the salted hash of the command shape
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+	P50MS    int64  `json:"p50_ms"`
+	P90MS    int64  `json:"p90_ms"`
+	MinMS    int64  `json:"min_ms"`
+	MaxMS    int64  `json:"max_ms"`
+}
+
+// subcommandWord matches the plain words that name a subcommand, such as
+// "build" in "cargo build", as opposed to flags, paths and values.
+var subcommandWord = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// commandShape reduces a command line to its program's base name and up to
+// two subcommand words after it: "VERBOSE=1 ./bin/cargo build --release -p
+// api" becomes "cargo build". Leading environment assignments are skipped.
+func commandShape(command string) string {
+	first, _, _ := strings.Cut(strings.TrimSpace(command), "\n")
+	fields := strings.Fields(first)
+	for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "=") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	shape := []string{filepath.Base(fields[0])}
+	for _, f := range fields[1:] {
+		if len(shape) == 3 || !subcommandWord.MatchString(f) {
+			break
+		}
+		shape = append(shape, f)
+	}
+	return strings.Join(shape, " ")
+}
+
+// exportHash is the HMAC-SHA256 of s keyed with salt, cut to 16 hex digits.
+func exportHash(salt, s string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// computeExport aggregates entries by command shape, leaving out shapes seen
+// fewer than minRuns times, whose timings would say most about one run.
+func computeExport(entries []historyEntry, salt string, minRuns int) []exportedTiming {
+	type shapeRuns struct {
+		runs, failures int
+		durations      []time.Duration
+	}
+	byShape := map[string]*shapeRuns{}
+	for _, e := range entries {
+		shape := commandShape(e.Command)
+		if shape == "" {
+			continue
+		}
+		s := byShape[shape]
+		if s == nil {
+			s = &shapeRuns{}
+			byShape[shape] = s
+		}
+		s.runs++
+		if e.ExitCode != 0 {
+			s.failures++
+			continue
+		}
+		s.durations = append(s.durations, e.duration())
+	}
+
+	timings := []exportedTiming{}
+	for shape, s := range byShape {
+		if s.runs < minRuns {
+			continue
+		}
+		t := exportedTiming{Command: exportHash(salt, shape), Runs: s.runs, Failures: s.failures}
+		if d := s.durations; len(d) > 0 {
+			t.P50MS = percentile(d, 50).Milliseconds()
+			t.P90MS = percentile(d, 90).Milliseconds()
+			t.MinMS = percentile(d, 0).Milliseconds()
+			t.MaxMS = percentile(d, 100).Milliseconds()
+		}
+		timings = append(timings, t)
+	}
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Command < timings[j].Command })
+	return timings
+}
+
+// runExport implements `reporter export`.
+func runExport(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+
+	fset := flag.NewFlagSet("export", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter export [flags]")
+		fmt.Fprintln(fset.Output(), "       reporter export -hash COMMAND")
+		fset.PrintDefaults()
+	}
+	salt := fset.String("salt", cfg.string("export_salt", ""), "secret shared by the team, so commands hash alike on every machine but cannot be guessed from outside")
+	since := fset.String("since", "720h", "only count runs started at or after this time (e.g. 24h, 2006-01-02, or RFC 3339)")
+	command := fset.String("command", "", "only count runs whose command contains this text (case-insensitive)")
+	labels := labelFlag{}
+	fset.Var(labels, "label", "only count runs with this `key=value` label (repeatable)")
+	where := fset.String("where", "", "only count runs matching this expression")
+	minRuns := fset.Int("min-runs", 3, "leave out commands run fewer than this many times")
+	hash := fset.String("hash", "", "print the hash `COMMAND` exports as, to tell which baseline is which")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	cfg.enforceLocks(fset)
+	if fset.NArg() != 0 || *minRuns < 1 {
+		fset.Usage()
+		return 2
+	}
+	if *salt == "" {
+		fmt.Fprintln(os.Stderr, "reporter export needs a -salt (or export_salt in config) shared by the team; without one, command hashes are easy to reverse")
+		return 2
+	}
+	if *hash != "" {
+		fmt.Printf("%s\t%s\n", exportHash(*salt, commandShape(*hash)), commandShape(*hash))
+		return 0
+	}
+
+	now := time.Now()
+	filter := historyFilter{command: *command, labels: labels}
+	if filter.since, err = parseTimeBound(*since, now); err != nil {
+		fmt.Fprintf(os.Stderr, "-since: %v\n", err)
+		return 2
+	}
+	if *where != "" {
+		expr, err := compileWhere(*where, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-where: %v\n", err)
+			return 2
+		}
+		filter.where = expr
+	}
+
+	entries, ok := readHistory()
+	if !ok {
+		return 1
+	}
+	var matched []historyEntry
+	for _, e := range entries {
+		if filter.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	host, _ := os.Hostname()
+	doc := exportDoc{
+		Schema:   exportSchema,
+		Since:    filter.since.UTC().Truncate(time.Hour),
+		Until:    now.UTC().Truncate(time.Hour),
+		Machine:  exportHash(*salt, host),
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CPUs:     runtime.NumCPU(),
+		Commands: computeExport(matched, *salt, *minRuns),
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandShape(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{command: "go test ./...", want: "go test"},
+		{command: "VERBOSE=1 ./bin/cargo build --release -p api", want: "cargo build"},
+		{command: "npm run build -- --watch", want: "npm run build"},
+		{command: "kubectl get pods web-7f9", want: "kubectl get pods"},
+		{command: "make -C /home/me/src deploy", want: "make"},
+		{command: "/home/me/scripts/backfill.sh 2026-03-01", want: "backfill.sh"},
+		{command: "docker build \\\n  -t app .", want: "docker build"},
+		{command: "FOO=bar", want: ""},
+		{command: "  ", want: ""},
+	}
+	for _, tt := range tests {
+		if got := commandShape(tt.command); got != tt.want {
+			t.Errorf("commandShape(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestExportHash(t *testing.T) {
+	a, b := exportHash("team", "cargo build"), exportHash("other", "cargo build")
+	if len(a) != 16 || a == b {
+		t.Errorf("exportHash() = %q with one salt and %q with another", a, b)
+	}
+	if exportHash("team", "cargo build") != a {
+		t.Error("exportHash() is not stable")
+	}
+}
+
+func TestComputeExport(t *testing.T) {
+	var entries []historyEntry
+	for i, d := range []int64{300, 100, 200, 500, 400} {
+		e := historyEntry{Command: "cargo build --release", Dir: "/home/me/src/api", DurationMS: d}
+		if i == 4 {
+			e.ExitCode = 101
+		}
+		entries = append(entries, e)
+	}
+	entries = append(entries,
+		historyEntry{Command: "./secret-migration.sh --db prod", DurationMS: 9000},
+		historyEntry{Command: "", DurationMS: 10},
+	)
+
+	got := computeExport(entries, "team", 2)
+	want := []exportedTiming{{
+		Command:  exportHash("team", "cargo build"),
+		Runs:     5,
+		Failures: 1,
+		P50MS:    200,
+		P90MS:    500,
+		MinMS:    100,
+		MaxMS:    500,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computeExport() = %+v, want %+v", got, want)
+	}
+
+	if got := computeExport(entries, "team", 1); len(got) != 2 {
+		t.Errorf("computeExport() with -min-runs 1 = %+v, want both commands", got)
+	}
+}
@@ -0,0 +1,54 @@
+package reporter
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Focus detection skips the desktop notification when the terminal that ran
+// the command is the focused window, where a popup would only cover what the
+// user is already looking at; the bell still rings. Each platform asks its
+// window system which window is focused (see focus_darwin.go and
+// focus_linux.go). When that cannot be told, as over SSH, the terminal counts
+// as unfocused and the notification is shown.
+
+// focusTimeout bounds the queries that find the focused window.
+const focusTimeout = time.Second
+
+// terminalFocused reports whether the terminal showing this process is the
+// focused window. Inside tmux, the pane must also be the one on screen, and
+// the terminal is the one running the tmux client.
+func terminalFocused(env environment) bool {
+	if env.SSH || env.headless() {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), focusTimeout)
+	defer cancel()
+	pid := os.Getpid()
+	if os.Getenv("TMUX") != "" {
+		out, err := exec.CommandContext(ctx, "tmux", "display-message", "-p", "-t", os.Getenv("TMUX_PANE"),
+			"#{window_active}#{pane_active} #{client_pid}").Output()
+		client, visible := tmuxPaneVisible(string(out))
+		if err != nil || !visible {
+			return false
+		}
+		pid = client
+	}
+	return windowFocused(ctx, pid)
+}
+
+// tmuxPaneVisible parses the output of tmux display-message with the format
+// "#{window_active}#{pane_active} #{client_pid}", reporting whether the pane is
+// on screen in an attached client and that client's process.
+func tmuxPaneVisible(out string) (client int, visible bool) {
+	active, pid, ok := strings.Cut(strings.TrimSpace(out), " ")
+	client, err := strconv.Atoi(pid)
+	if !ok || err != nil || active != "11" {
+		return 0, false
+	}
+	return client, true
+}
@@ -0,0 +1,22 @@
+package reporter
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// windowFocused reports whether the terminal app is frontmost. macOS
+// terminals export their bundle ID to the processes they start, as
+// __CFBundleIdentifier, which is compared with the frontmost app's; asking
+// for the path to the frontmost app needs no Automation permission. Any window
+// of the terminal app counts, and pid is not needed.
+func windowFocused(ctx context.Context, pid int) bool {
+	bundle := os.Getenv("__CFBundleIdentifier")
+	if bundle == "" {
+		return false
+	}
+	out, err := exec.CommandContext(ctx, "osascript", "-e", "id of app (path to frontmost application as text)").Output()
+	return err == nil && strings.TrimSpace(string(out)) == bundle
+}
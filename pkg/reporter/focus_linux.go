@@ -0,0 +1,152 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// windowFocused reports whether the focused window belongs to pid or one of
+// its ancestors, which is where the terminal emulator is. The focused
+// window's process comes from sway or Hyprland on those Wayland compositors,
+// and from the window manager's _NET_ACTIVE_WINDOW under X11; other Wayland
+// compositors do not tell.
+func windowFocused(ctx context.Context, pid int) bool {
+	var focused int
+	var err error
+	switch {
+	case os.Getenv("SWAYSOCK") != "":
+		focused, err = swayFocusedPID(ctx)
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		focused, err = hyprlandFocusedPID(ctx)
+	case os.Getenv("WAYLAND_DISPLAY") == "" && os.Getenv("DISPLAY") != "":
+		focused, err = x11FocusedPID(ctx)
+	default:
+		return false
+	}
+	return err == nil && isAncestor(focused, pid)
+}
+
+func swayFocusedPID(ctx context.Context) (int, error) {
+	out, err := exec.CommandContext(ctx, "swaymsg", "-t", "get_tree").Output()
+	if err != nil {
+		return 0, err
+	}
+	return parseSwayTree(out)
+}
+
+// swayNode is the part of a node in swaymsg's tree that matters here.
+type swayNode struct {
+	Focused       bool       `json:"focused"`
+	PID           int        `json:"pid"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+// parseSwayTree returns the process of the focused window in the output of
+// swaymsg -t get_tree.
+func parseSwayTree(data []byte) (int, error) {
+	var root swayNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return 0, err
+	}
+	var find func(n swayNode) int
+	find = func(n swayNode) int {
+		if n.Focused && n.PID > 0 {
+			return n.PID
+		}
+		for _, children := range [][]swayNode{n.Nodes, n.FloatingNodes} {
+			for _, c := range children {
+				if pid := find(c); pid > 0 {
+					return pid
+				}
+			}
+		}
+		return 0
+	}
+	if pid := find(root); pid > 0 {
+		return pid, nil
+	}
+	return 0, fmt.Errorf("sway: no focused window")
+}
+
+func hyprlandFocusedPID(ctx context.Context) (int, error) {
+	out, err := exec.CommandContext(ctx, "hyprctl", "-j", "activewindow").Output()
+	if err != nil {
+		return 0, err
+	}
+	var w struct {
+		PID int `json:"pid"`
+	}
+	if err := json.Unmarshal(out, &w); err != nil || w.PID <= 0 {
+		return 0, fmt.Errorf("hyprland: no focused window")
+	}
+	return w.PID, nil
+}
+
+func x11FocusedPID(ctx context.Context) (int, error) {
+	out, err := exec.CommandContext(ctx, "xprop", "-root", "_NET_ACTIVE_WINDOW").Output()
+	if err != nil {
+		return 0, err
+	}
+	window, ok := xpropValue(string(out))
+	if !ok || window == "0x0" {
+		return 0, fmt.Errorf("x11: no focused window")
+	}
+	if out, err = exec.CommandContext(ctx, "xprop", "-id", window, "_NET_WM_PID").Output(); err != nil {
+		return 0, err
+	}
+	value, _ := xpropValue(string(out))
+	pid, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("x11: focused window %s has no _NET_WM_PID", window)
+	}
+	return pid, nil
+}
+
+// xpropValue returns the last word of a line of xprop output such as
+// "_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3a00007" or
+// "_NET_WM_PID(CARDINAL) = 4242".
+func xpropValue(out string) (string, bool) {
+	fields := strings.Fields(out)
+	if len(fields) < 3 || strings.Contains(out, "not found") {
+		return "", false
+	}
+	return strings.TrimSuffix(fields[len(fields)-1], ","), true
+}
+
+// isAncestor reports whether ancestor is pid or one of its ancestors.
+func isAncestor(ancestor, pid int) bool {
+	for i := 0; pid > 1 && i < 64; i++ {
+		if pid == ancestor {
+			return true
+		}
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return false
+		}
+		if pid, err = parentPID(string(data)); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// parentPID returns the parent process ID from the contents of
+// /proc/<pid>/stat, whose second field, the command name, may hold spaces and
+// parentheses.
+func parentPID(stat string) (int, error) {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("malformed stat %q", stat)
+	}
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("malformed stat %q", stat)
+	}
+	return strconv.Atoi(fields[1])
+}
@@ -0,0 +1,62 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestParseSwayTree(t *testing.T) {
+	tree := `{"id":1,"focused":false,"nodes":[{"id":2,"nodes":[{"id":3,"focused":false,"pid":100},
+		{"id":4,"nodes":[],"floating_nodes":[{"id":5,"focused":true,"pid":4242}]}]}]}`
+	pid, err := parseSwayTree([]byte(tree))
+	if err != nil || pid != 4242 {
+		t.Errorf("parseSwayTree() = %d, %v; want 4242", pid, err)
+	}
+	if _, err := parseSwayTree([]byte(`{"id":1,"nodes":[]}`)); err == nil {
+		t.Error("parseSwayTree() found a focused window in an empty tree")
+	}
+}
+
+func TestXpropValue(t *testing.T) {
+	tests := []struct {
+		out, want string
+		ok        bool
+	}{
+		{out: "_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3a00007\n", want: "0x3a00007", ok: true},
+		{out: "_NET_WM_PID(CARDINAL) = 4242\n", want: "4242", ok: true},
+		{out: "_NET_WM_PID:  not found.\n", ok: false},
+		{out: "", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := xpropValue(tt.out)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("xpropValue(%q) = %q, %v; want %q, %v", tt.out, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParentPID(t *testing.T) {
+	ppid, err := parentPID("4242 (tmux: server (1)) S 77 4242 4242 0 -1")
+	if err != nil || ppid != 77 {
+		t.Errorf("parentPID() = %d, %v; want 77", ppid, err)
+	}
+	if _, err := parentPID("garbage"); err == nil {
+		t.Error("parentPID() accepted malformed stat")
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	if _, err := os.Stat(fmt.Sprintf("/proc/%d/stat", os.Getpid())); err != nil {
+		t.Skip("no /proc")
+	}
+	if !isAncestor(os.Getppid(), os.Getpid()) {
+		t.Error("parent is not an ancestor")
+	}
+	if !isAncestor(os.Getpid(), os.Getpid()) {
+		t.Error("a process is not its own ancestor")
+	}
+	if isAncestor(os.Getpid(), os.Getppid()) {
+		t.Error("child counted as an ancestor of its parent")
+	}
+}
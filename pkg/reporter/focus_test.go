@@ -0,0 +1,32 @@
+package reporter
+
+import "testing"
+
+func TestTmuxPaneVisible(t *testing.T) {
+	tests := []struct {
+		out     string
+		client  int
+		visible bool
+	}{
+		{out: "11 4242\n", client: 4242, visible: true},
+		{out: "10 4242\n", visible: false}, // another pane is active
+		{out: "01 4242\n", visible: false}, // another window is active
+		{out: "11 \n", visible: false},     // no client attached
+		{out: "", visible: false},
+	}
+	for _, tt := range tests {
+		client, visible := tmuxPaneVisible(tt.out)
+		if client != tt.client || visible != tt.visible {
+			t.Errorf("tmuxPaneVisible(%q) = %d, %v; want %d, %v", tt.out, client, visible, tt.client, tt.visible)
+		}
+	}
+}
+
+func TestTerminalFocusedRemote(t *testing.T) {
+	if terminalFocused(environment{SSH: true}) {
+		t.Error("terminal over SSH counted as focused")
+	}
+	if terminalFocused(environment{CI: "GitHub Actions"}) {
+		t.Error("CI runner counted as focused")
+	}
+}
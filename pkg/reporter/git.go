@@ -0,0 +1,122 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitBranch returns the branch checked out in the git repository holding
+// dir, or the abbreviated commit when HEAD is detached, or "" outside a
+// repository. It reads .git directly rather than running git, which may be
+// slow or missing.
+func gitBranch(dir string) string {
+	gitDir, _ := findGitDir(dir)
+	if gitDir == "" {
+		return ""
+	}
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref := strings.TrimSpace(string(head))
+	if branch, ok := strings.CutPrefix(ref, "ref: refs/heads/"); ok {
+		return branch
+	}
+	if len(ref) >= 7 && !strings.HasPrefix(ref, "ref: ") {
+		return ref[:7]
+	}
+	return ""
+}
+
+// gitRepo names the git repository holding dir after its origin remote,
+// such as acme/app for git@github.com:acme/app.git, or after its top-level
+// directory if it has none. It returns "" outside a repository.
+func gitRepo(dir string) string {
+	gitDir, top := findGitDir(dir)
+	if gitDir == "" {
+		return ""
+	}
+	// A worktree's git directory points at the repository's shared one,
+	// which holds the config.
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		path := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(gitDir, path)
+		}
+		gitDir = path
+	}
+	if url := originURL(filepath.Join(gitDir, "config")); url != "" {
+		return repoName(url)
+	}
+	return filepath.Base(top)
+}
+
+// originURL returns the URL of the origin remote in the git config file at
+// path, or "".
+func originURL(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	inOrigin := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inOrigin = line == `[remote "origin"]`
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if inOrigin && ok && strings.TrimSpace(key) == "url" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// repoName shortens a remote URL to its last two path elements, such as
+// acme/app for https://github.com/acme/app.git or git@github.com:acme/app.
+func repoName(url string) string {
+	path := strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if _, rest, ok := strings.Cut(path, "://"); ok {
+		_, path, _ = strings.Cut(rest, "/")
+	} else if _, rest, ok := strings.Cut(path, ":"); ok {
+		path = rest // scp-like git@host:owner/name
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, "/")
+}
+
+// findGitDir returns the git directory of the repository holding dir and
+// the repository's top-level directory, or "" for both. Worktrees and
+// submodules have a .git file naming their git directory instead.
+func findGitDir(dir string) (gitDir, top string) {
+	for {
+		path := filepath.Join(dir, ".git")
+		if info, err := os.Stat(path); err == nil {
+			if info.IsDir() {
+				return path, dir
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", ""
+			}
+			gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+			if !ok {
+				return "", ""
+			}
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return gitDir, dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
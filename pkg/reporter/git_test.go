@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitBranch(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := filepath.Join(root, "repo")
+	write(filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/feature/login\n")
+	detached := filepath.Join(root, "detached")
+	write(filepath.Join(detached, ".git", "HEAD"), "4b825dc642cb6eb9a060e54bf8d69288fbee4904\n")
+	// A worktree's .git is a file naming its git directory.
+	worktree := filepath.Join(root, "worktree")
+	write(filepath.Join(repo, ".git", "worktrees", "hotfix", "HEAD"), "ref: refs/heads/hotfix\n")
+	write(filepath.Join(worktree, ".git"), "gitdir: ../repo/.git/worktrees/hotfix\n")
+
+	for _, tt := range []struct {
+		dir  string
+		want string
+	}{
+		{dir: repo, want: "feature/login"},
+		{dir: filepath.Join(repo, "src", "app"), want: "feature/login"},
+		{dir: detached, want: "4b825dc"},
+		{dir: worktree, want: "hotfix"},
+		{dir: root, want: ""},
+	} {
+		if got := gitBranch(tt.dir); got != tt.want {
+			t.Errorf("gitBranch(%s) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestGitRepo(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := filepath.Join(root, "checkout")
+	write(filepath.Join(repo, ".git", "config"), "[core]\n\tbare = false\n[remote \"upstream\"]\n\turl = https://github.com/other/fork.git\n"+
+		"[remote \"origin\"]\n\turl = git@github.com:acme/app.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n")
+	// A worktree's config is in the repository's shared git directory.
+	worktree := filepath.Join(root, "hotfix")
+	write(filepath.Join(repo, ".git", "worktrees", "hotfix", "commondir"), "../..\n")
+	write(filepath.Join(worktree, ".git"), "gitdir: ../checkout/.git/worktrees/hotfix\n")
+	local := filepath.Join(root, "scratch")
+	write(filepath.Join(local, ".git", "config"), "[core]\n\tbare = false\n")
+
+	for _, tt := range []struct {
+		dir  string
+		want string
+	}{
+		{dir: repo, want: "acme/app"},
+		{dir: filepath.Join(repo, "cmd"), want: "acme/app"},
+		{dir: worktree, want: "acme/app"},
+		{dir: local, want: "scratch"},
+		{dir: root, want: ""},
+	} {
+		if got := gitRepo(tt.dir); got != tt.want {
+			t.Errorf("gitRepo(%s) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	for _, tt := range []struct {
+		url  string
+		want string
+	}{
+		{url: "git@github.com:acme/app.git", want: "acme/app"},
+		{url: "https://github.com/acme/app.git", want: "acme/app"},
+		{url: "https://gitlab.example.com/group/sub/app/", want: "sub/app"},
+		{url: "ssh://git@git.example.com:2222/acme/app", want: "acme/app"},
+		{url: "/srv/git/app.git", want: "git/app"},
+	} {
+		if got := repoName(tt.url); got != tt.want {
+			t.Errorf("repoName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
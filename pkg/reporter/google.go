@@ -0,0 +1,242 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Google Cloud providers find credentials the way the gcloud CLI and
+// client libraries do (Application Default Credentials): the JSON file
+// named by GOOGLE_APPLICATION_CREDENTIALS, a service account key or an
+// authorized user, then the one `gcloud auth application-default login`
+// writes, then the service account of the VM through its metadata server.
+
+// googleMetadataHost is the GCE metadata server; GCE_METADATA_HOST
+// overrides it.
+const googleMetadataHost = "metadata.google.internal"
+
+// googleMetadataTimeout bounds each request to the metadata server, which
+// does not answer at all off Google Cloud.
+const googleMetadataTimeout = time.Second
+
+// googleCredentialsFile is the part of an ADC file reporter uses.
+type googleCredentialsFile struct {
+	Type     string `json:"type"` // "service_account" or "authorized_user"
+	TokenURI string `json:"token_uri"`
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	ProjectID    string `json:"project_id"`
+	// authorized_user
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	RefreshToken   string `json:"refresh_token"`
+	QuotaProjectID string `json:"quota_project_id"`
+}
+
+// googleADCPath returns the ADC file to use, or "" if there is none.
+func googleADCPath(getenv func(string) string) string {
+	if path := getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+	dir := getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(getenv("APPDATA"), "gcloud")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+	path := filepath.Join(dir, "application_default_credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// readGoogleADC reads the ADC file, if there is one.
+func readGoogleADC(getenv func(string) string) (*googleCredentialsFile, error) {
+	path := googleADCPath(getenv)
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f googleCredentialsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// googleAccessToken returns an OAuth access token for scope from the
+// Application Default Credentials (see above).
+func googleAccessToken(ctx context.Context, scope string, getenv func(string) string) (string, error) {
+	f, err := readGoogleADC(getenv)
+	if err != nil {
+		return "", err
+	}
+	if f == nil {
+		token, err := googleMetadata(ctx, getenv, "instance/service-accounts/default/token?scopes="+url.QueryEscape(scope))
+		if err != nil {
+			return "", fmt.Errorf("no Google credentials found (not on Google Cloud, and no GOOGLE_APPLICATION_CREDENTIALS: %v)", err)
+		}
+		var tok struct {
+			AccessToken string `json:"access_token"`
+		}
+		if json.Unmarshal([]byte(token), &tok) != nil || tok.AccessToken == "" {
+			return "", errors.New("the metadata server returned no access token")
+		}
+		return tok.AccessToken, nil
+	}
+	tokenURL := f.TokenURI
+	if tokenURL == "" {
+		tokenURL = googleTokenURL
+	}
+	switch f.Type {
+	case "service_account":
+		assertion, err := googleJWT(f, scope, tokenURL, time.Now())
+		if err != nil {
+			return "", err
+		}
+		return googleTokenExchange(ctx, tokenURL, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	case "authorized_user":
+		return googleTokenExchange(ctx, tokenURL, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {f.RefreshToken},
+			"client_id":     {f.ClientID},
+			"client_secret": {f.ClientSecret},
+		})
+	}
+	return "", fmt.Errorf("unsupported Google credentials type %q", f.Type)
+}
+
+// googleJWT returns the signed assertion a service account exchanges for an
+// access token.
+func googleJWT(f *googleCredentialsFile, scope, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(f.PrivateKey))
+	if block == nil {
+		return "", errors.New("the service account key has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("parsing the service account key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("the service account key is not an RSA key")
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": f.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   f.ClientEmail,
+		"scope": scope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// googleTokenExchange posts form to Google's token endpoint and returns the
+// access token in the response.
+func googleTokenExchange(ctx context.Context, endpoint string, form url.Values) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("creating request for %s: %w", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("getting a Google access token: %w", err)
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	_ = json.Unmarshal(data, &tok)
+	if resp.StatusCode >= 300 || tok.AccessToken == "" {
+		reason := resp.Status
+		if tok.Error != "" {
+			reason = strings.TrimSuffix(tok.Error+": "+tok.Description, ": ")
+		}
+		return "", &statusError{resp.StatusCode, fmt.Errorf("getting a Google access token: %s", reason)}
+	}
+	return tok.AccessToken, nil
+}
+
+// googleProject returns the Google Cloud project from GOOGLE_CLOUD_PROJECT,
+// the ADC file, or the metadata server, or "" if none is known.
+func googleProject(ctx context.Context, getenv func(string) string) string {
+	if project := getenv("GOOGLE_CLOUD_PROJECT"); project != "" {
+		return project
+	}
+	if f, _ := readGoogleADC(getenv); f != nil {
+		if f.ProjectID != "" {
+			return f.ProjectID
+		}
+		return f.QuotaProjectID
+	}
+	project, _ := googleMetadata(ctx, getenv, "project/project-id")
+	return strings.TrimSpace(project)
+}
+
+// googleMetadata reads path from the metadata server.
+func googleMetadata(ctx context.Context, getenv func(string) string, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, googleMetadataTimeout)
+	defer cancel()
+	host := getenvOr(getenv, "GCE_METADATA_HOST", googleMetadataHost)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the metadata server returned %s", resp.Status)
+	}
+	return string(body), nil
+}
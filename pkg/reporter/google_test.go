@@ -0,0 +1,114 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoogleAccessToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	var gotForm map[string]string
+	token := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		gotForm = map[string]string{}
+		for k := range r.PostForm {
+			gotForm[k] = r.PostForm.Get(k)
+		}
+		w.Write([]byte(`{"access_token":"ya29.fresh","expires_in":3599}`))
+	}))
+	defer token.Close()
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			w.Write([]byte(`{"access_token":"ya29.vm","expires_in":3599,"token_type":"Bearer"}`))
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte("vm-project"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metadata.Close()
+
+	dir := t.TempDir()
+	serviceAccount := filepath.Join(dir, "sa.json")
+	sa, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "etl-prod",
+		"private_key_id": "k1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "reporter@etl-prod.iam.gserviceaccount.com",
+		"token_uri":      token.URL,
+	})
+	writeFile(t, serviceAccount, string(sa))
+	user := filepath.Join(dir, "user.json")
+	writeFile(t, user, `{"type":"authorized_user","client_id":"cid","client_secret":"cs","refresh_token":"1//r","quota_project_id":"dev-project","token_uri":"`+token.URL+`"}`)
+
+	env := map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": serviceAccount}
+	getenv := func(k string) string { return env[k] }
+	got, err := googleAccessToken(context.Background(), pubSubScope, getenv)
+	if err != nil || got != "ya29.fresh" {
+		t.Fatalf("googleAccessToken() with a service account = %q, %v", got, err)
+	}
+	if gotForm["grant_type"] != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		t.Errorf("grant_type = %q", gotForm["grant_type"])
+	}
+	parts := strings.Split(gotForm["assertion"], ".")
+	if len(parts) != 3 {
+		t.Fatalf("assertion = %q, want a JWT", gotForm["assertion"])
+	}
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var c struct{ Iss, Scope, Aud string }
+	json.Unmarshal(claims, &c)
+	if c.Iss != "reporter@etl-prod.iam.gserviceaccount.com" || c.Scope != pubSubScope || c.Aud != token.URL {
+		t.Errorf("claims = %s", claims)
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("assertion signature: %v", err)
+	}
+	if p := googleProject(context.Background(), getenv); p != "etl-prod" {
+		t.Errorf("googleProject() = %q, want etl-prod", p)
+	}
+
+	env = map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": user}
+	if got, err := googleAccessToken(context.Background(), pubSubScope, getenv); err != nil || got != "ya29.fresh" {
+		t.Errorf("googleAccessToken() with an authorized user = %q, %v", got, err)
+	}
+	if gotForm["grant_type"] != "refresh_token" || gotForm["refresh_token"] != "1//r" || gotForm["client_id"] != "cid" {
+		t.Errorf("form = %v", gotForm)
+	}
+	if p := googleProject(context.Background(), getenv); p != "dev-project" {
+		t.Errorf("googleProject() = %q, want dev-project", p)
+	}
+
+	env = map[string]string{"CLOUDSDK_CONFIG": dir, "GCE_METADATA_HOST": strings.TrimPrefix(metadata.URL, "http://")}
+	if got, err := googleAccessToken(context.Background(), pubSubScope, getenv); err != nil || got != "ya29.vm" {
+		t.Errorf("googleAccessToken() on a VM = %q, %v", got, err)
+	}
+	if p := googleProject(context.Background(), getenv); p != "vm-project" {
+		t.Errorf("googleProject() = %q, want vm-project", p)
+	}
+}
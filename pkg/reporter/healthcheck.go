@@ -0,0 +1,117 @@
+package reporter
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// -healthcheck-url pings a healthchecks.io check, or any service speaking
+// its ping API, such as a self-hosted Healthchecks or Uptime Kuma's push
+// monitors, for a wrapped command: /start when it starts, the bare URL when
+// it succeeds, and /fail when it fails. The check's own schedule then alerts
+// when a cron job does not run at all, which no notification about a run
+// can. Like Sentry check-ins, pings go out whatever the threshold and even
+// while notifications are muted.
+
+// Ping kinds, appended to the check's URL; "" reports success.
+const (
+	healthcheckStart   = "start"
+	healthcheckSuccess = ""
+	healthcheckFail    = "fail"
+)
+
+// healthcheckMaxBody caps the log sent with a ping; healthchecks.io keeps
+// the first 100 kB.
+const healthcheckMaxBody = 100_000
+
+// healthcheck is a check to ping for one run.
+type healthcheck struct {
+	url *url.URL
+	// rid, a random UUID, pairs the result with its start ping, so the
+	// service times the run and overlapping runs are told apart.
+	rid string
+}
+
+// newHealthcheck returns the check at rawURL, or nil if rawURL is "".
+func newHealthcheck(rawURL string) (*healthcheck, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: want the check's ping URL, such as https://hc-ping.com/<uuid>", redactPingURL(rawURL))
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80 // version 4, RFC 4122 variant
+	return &healthcheck{url: u, rid: fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])}, nil
+}
+
+// redactPingURL hides the path of a ping URL, whose UUID or ping key is all
+// it takes to ping the check, for error messages.
+func redactPingURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host + "/***"
+	}
+	return "***"
+}
+
+// pingURL returns the URL that pings kind.
+func (h *healthcheck) pingURL(kind string) string {
+	u := *h.url
+	if kind != healthcheckSuccess {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + kind
+		u.RawPath = ""
+	}
+	q := u.Query()
+	q.Set("rid", h.rid)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// healthcheckBody is the log sent with the result: how the run went, the
+// command, and, with -capture-output, the tail of its output.
+func healthcheckBody(res runResult) string {
+	body := fmt.Sprintf("%s in %s\n%s\n", resultStatus(res), formatDuration(res.Duration), res.Command)
+	if len(res.Output) > 0 {
+		body += "\n" + strings.Join(res.Output, "\n") + "\n"
+	}
+	if len(body) > healthcheckMaxBody {
+		body = body[:healthcheckMaxBody]
+	}
+	return body
+}
+
+// start pings the start of the run, without holding up the command, and
+// returns the function that pings how it went. Failures are reported on
+// stderr unless quiet. A nil check does nothing.
+func (h *healthcheck) start(quiet bool) (finish func(res runResult)) {
+	if h == nil {
+		return func(runResult) {}
+	}
+	warn := func(err error) {
+		if err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "[healthcheck] %v\n", err)
+		}
+	}
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		warn(h.ping(healthcheckStart, ""))
+	}()
+	return func(res runResult) {
+		// A result arriving before its start would leave the check
+		// marked as started.
+		<-started
+		kind := healthcheckSuccess
+		if res.ExitCode != 0 {
+			kind = healthcheckFail
+		}
+		warn(h.ping(kind, healthcheckBody(res)))
+	}
+}
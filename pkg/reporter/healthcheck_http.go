@@ -0,0 +1,41 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ping sends one ping of kind, with body as its log.
+func (h *healthcheck) ping(kind, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.pingURL(kind), strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating ping request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	name := kind
+	if name == healthcheckSuccess {
+		name = "success"
+	}
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		// A *url.Error names the URL, which is the check's secret.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("pinging %s to %s: %w", name, req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pinging %s: %s returned %s", name, req.URL.Host, resp.Status)
+	}
+	return nil
+}
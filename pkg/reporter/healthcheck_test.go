@@ -0,0 +1,77 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewHealthcheck(t *testing.T) {
+	for _, tt := range []struct {
+		url  string
+		want string // the fail ping URL, without rid; "" for an error
+	}{
+		{"https://hc-ping.com/0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0", "https://hc-ping.com/0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0/fail"},
+		{"https://hc.example.com/ping/pk123/nightly-etl/", "https://hc.example.com/ping/pk123/nightly-etl/fail"},
+		{"hc-ping.com/0b1c2d3e", ""},
+		{"ftp://hc-ping.com/0b1c2d3e", ""},
+	} {
+		h, err := newHealthcheck(tt.url)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("newHealthcheck(%q) accepted an invalid URL", tt.url)
+		case tt.want != "" && err != nil:
+			t.Errorf("newHealthcheck(%q) returned error: %v", tt.url, err)
+		case tt.want != "" && h.pingURL(healthcheckFail) != tt.want+"?rid="+h.rid:
+			t.Errorf("newHealthcheck(%q) fails at %q, want %q", tt.url, h.pingURL(healthcheckFail), tt.want)
+		}
+		if err != nil && strings.Contains(err.Error(), "0b1c2d3e") {
+			t.Errorf("error %q reveals the check's UUID", err)
+		}
+	}
+	if h, err := newHealthcheck(""); h != nil || err != nil {
+		t.Errorf("newHealthcheck(\"\") = %v, %v; want nil", h, err)
+	}
+	h, _ := newHealthcheck("https://hc-ping.com/x")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(h.rid) {
+		t.Errorf("rid = %q, want a UUID", h.rid)
+	}
+}
+
+func TestHealthcheckPings(t *testing.T) {
+	var mu sync.Mutex
+	var paths, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths, bodies = append(paths, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("rid")), append(bodies, string(b))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	h, err := newHealthcheck(srv.URL + "/ping/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.start(false)(runResult{Command: "./etl", Duration: 95 * time.Second, ExitCode: 2, Output: []string{"loading", "error: disk full"}})
+	want := []string{"POST /ping/abc/start " + h.rid, "POST /ping/abc/fail " + h.rid}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Errorf("pinged %q, want %q", paths, want)
+	}
+	if len(bodies) == 2 && bodies[1] != "failed (exit 2) in 1m35s\n./etl\n\nloading\nerror: disk full\n" {
+		t.Errorf("fail ping body = %q", bodies[1])
+	}
+
+	paths = nil
+	h.start(false)(runResult{Command: "./etl", Duration: time.Second})
+	if want := "POST /ping/abc " + h.rid; len(paths) != 2 || paths[1] != want {
+		t.Errorf("pinged %q, want success as %q", paths, want)
+	}
+}
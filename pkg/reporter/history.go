@@ -0,0 +1,413 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// The history is a journal of every wrapped or reported run. By default it is
+// one JSON object per line in the data directory, appended with a single write
+// so concurrent shells do not interleave lines; history_store = "sqlite"
+// switches to a SQLite database (see history_sqlite.go).
+
+const historyFileName = "history.jsonl"
+
+// Values for the history_store config key.
+const (
+	historyStoreJSONL  = "jsonl"
+	historyStoreSQLite = "sqlite"
+)
+
+// historyStore persists run history.
+type historyStore interface {
+	Record(entries ...historyEntry) error
+	// Load returns every entry, oldest first. A store that does not exist
+	// yet yields no entries.
+	Load() ([]historyEntry, error)
+	// Path is where the store lives, for messages.
+	Path() string
+}
+
+// openHistory returns the store selected by history_store.
+func openHistory(cfg *config) (historyStore, error) {
+	switch kind := cfg.string("history_store", historyStoreJSONL); kind {
+	case historyStoreJSONL:
+		return jsonlHistory{path: historyPath()}, nil
+	case historyStoreSQLite:
+		return sqliteHistory{path: sqliteHistoryPath()}, nil
+	default:
+		return nil, fmt.Errorf("history_store must be %q or %q, not %q", historyStoreJSONL, historyStoreSQLite, kind)
+	}
+}
+
+// readHistory loads the configured history for the reporting subcommands,
+// printing any error. ok is false if the caller should exit.
+func readHistory() (entries []historyEntry, ok bool) {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return nil, false
+	}
+	store, err := openHistory(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return nil, false
+	}
+	entries, err = store.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading %s: %v\n", store.Path(), err)
+		return nil, false
+	}
+	// Entries are stored when runs finish; order them by start time.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, true
+}
+
+type jsonlHistory struct{ path string }
+
+func (h jsonlHistory) Record(entries ...historyEntry) error { return recordHistory(h.path, entries...) }
+func (h jsonlHistory) Load() ([]historyEntry, error)        { return loadHistory(h.path) }
+func (h jsonlHistory) Path() string                         { return h.path }
+
+type historyEntry struct {
+	Time       time.Time         `json:"time"` // when the command started
+	Command    string            `json:"command"`
+	Dir        string            `json:"cwd,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	DurationMS int64             `json:"duration_ms"`
+	ExitCode   int               `json:"exit"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Args is the argv of a wrapped command, with secrets scrubbed; a
+	// command the shell hook reports has none, and neither does the
+	// SQLite store.
+	Args []string `json:"args,omitempty"`
+}
+
+func (e historyEntry) duration() time.Duration {
+	return ms(e.DurationMS)
+}
+
+func historyPath() string {
+	dir := dataDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, historyFileName)
+}
+
+// newHistoryEntry describes res as run in the current directory on this host.
+func newHistoryEntry(res runResult, end time.Time) historyEntry {
+	dir, _ := os.Getwd()
+	host, _ := os.Hostname()
+	return historyEntry{
+		Time:       end.Add(-res.Duration).Truncate(time.Millisecond),
+		Command:    res.Command,
+		Dir:        dir,
+		Host:       host,
+		DurationMS: res.Duration.Milliseconds(),
+		ExitCode:   res.ExitCode,
+		Labels:     res.Labels,
+		Args:       res.Args,
+	}
+}
+
+func recordHistory(path string, entries ...historyEntry) error {
+	if path == "" || len(entries) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Commands can contain secrets passed as arguments; keep the journal
+	// private.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadHistory reads recorded entries, oldest first, skipping malformed lines.
+// A missing file yields no entries.
+func loadHistory(path string) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e historyEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// historyFilter selects entries for `reporter history`. Zero values match
+// everything.
+type historyFilter struct {
+	command string // case-insensitive substring
+	status  string // notifyOnAlways, notifyOnFailure, or notifyOnSuccess
+	since   time.Time
+	until   time.Time
+	labels  map[string]string
+	where   whereExpr
+}
+
+func (f historyFilter) match(e historyEntry) bool {
+	if f.command != "" && !strings.Contains(strings.ToLower(e.Command), strings.ToLower(f.command)) {
+		return false
+	}
+	if f.status != "" && !outcomeWanted(f.status, e.ExitCode) {
+		return false
+	}
+	if !hasLabels(e.Labels, f.labels) {
+		return false
+	}
+	if f.where != nil && !f.where(e) {
+		return false
+	}
+	if !f.since.IsZero() && e.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !e.Time.Before(f.until) {
+		return false
+	}
+	return true
+}
+
+// parseTimeBound accepts a duration meaning "that long ago" (e.g. 36h), a
+// date (2006-01-02, local time), or an RFC 3339 timestamp.
+func parseTimeBound(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want a duration such as 24h, a date such as 2006-01-02, or an RFC 3339 timestamp", s)
+}
+
+// runHistory implements `reporter history`.
+func runHistory(args []string) int {
+	if len(args) > 0 && args[0] == "import" {
+		return runHistoryImport(args[1:])
+	}
+	if len(args) > 0 && args[0] == "tui" {
+		return runHistoryTUI(args[1:])
+	}
+	fset := flag.NewFlagSet("history", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter history [flags]")
+		fmt.Fprintln(fset.Output(), "       reporter history tui [flags]")
+		fmt.Fprintln(fset.Output(), "       reporter history import -from atuin|zsh-histdb [-db path]")
+		fset.PrintDefaults()
+	}
+	command := fset.String("command", "", "only show runs whose command contains this text (case-insensitive)")
+	status := fset.String("status", notifyOnAlways, "only show runs with this outcome: always, failure, or success")
+	since := fset.String("since", "", "only show runs started at or after this time (e.g. 24h, 2006-01-02, or RFC 3339)")
+	until := fset.String("until", "", "only show runs started before this time")
+	limit := fset.Int("n", 20, "show at most this many of the most recent matching runs (0 for all)")
+	labels := labelFlag{}
+	fset.Var(labels, "label", "only show runs with this `key=value` label (repeatable)")
+	where := fset.String("where", "", "only show runs matching this expression, e.g. 'exit != 0 && duration > 10m'")
+	asJSON := fset.Bool("json", false, "print matching entries as JSON lines")
+	format := fset.String("format", "", "print each matching entry with this Go `template`, e.g. '{{.Duration}}\\t{{.ExitCode}}\\t{{.Command}}'")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	if *asJSON && *format != "" {
+		fmt.Fprintln(os.Stderr, "-json and -format cannot be used together")
+		return 2
+	}
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		if tmpl, err = template.New("format").Option("missingkey=error").Parse(unescapeFormat(*format)); err != nil {
+			fmt.Fprintf(os.Stderr, "-format: %v\n", err)
+			return 2
+		}
+	}
+
+	filter := historyFilter{command: *command, status: *status, labels: labels}
+	switch *status {
+	case notifyOnAlways, notifyOnFailure, notifyOnSuccess:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -status %q: want always, failure, or success\n", *status)
+		return 2
+	}
+	now := time.Now()
+	if *where != "" {
+		expr, err := compileWhere(*where, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-where: %v\n", err)
+			return 2
+		}
+		filter.where = expr
+	}
+	for _, b := range []struct {
+		flag  string
+		value string
+		dst   *time.Time
+	}{{"since", *since, &filter.since}, {"until", *until, &filter.until}} {
+		if b.value == "" {
+			continue
+		}
+		t, err := parseTimeBound(b.value, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-%s: %v\n", b.flag, err)
+			return 2
+		}
+		*b.dst = t
+	}
+
+	entries, ok := readHistory()
+	if !ok {
+		return 1
+	}
+	var matched []historyEntry
+	for _, e := range entries {
+		if filter.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if *limit > 0 && len(matched) > *limit {
+		matched = matched[len(matched)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range matched {
+			if err := enc.Encode(e); err != nil {
+				return 1
+			}
+		}
+		return 0
+	}
+	if tmpl != nil {
+		if err := formatHistory(os.Stdout, tmpl, matched); err != nil {
+			fmt.Fprintf(os.Stderr, "-format: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printHistory(os.Stdout, matched)
+	return 0
+}
+
+// historyFields is what history -format templates see about a run.
+type historyFields struct {
+	Time       time.Time // when the run started, in local time
+	Command    string    // on one line
+	Dir        string    // the full path, so scripts can cd into it
+	Host       string
+	Duration   string // as notifications show it, such as 12m03s
+	DurationMS int64
+	ExitCode   int
+	Failed     bool
+	Labels     map[string]string
+}
+
+// formatHistory prints each entry through tmpl, one per line, so scripts and
+// pickers such as fzf can be built on the history without parsing the table.
+func formatHistory(w io.Writer, tmpl *template.Template, entries []historyEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		labels := e.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if err := tmpl.Execute(bw, historyFields{
+			Time:       e.Time.Local(),
+			Command:    oneLine(e.Command),
+			Dir:        e.Dir,
+			Host:       e.Host,
+			Duration:   formatDuration(e.duration()),
+			DurationMS: e.DurationMS,
+			ExitCode:   e.ExitCode,
+			Failed:     e.ExitCode != 0,
+			Labels:     labels,
+		}); err != nil {
+			return err
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// unescapeFormat turns the \t, \n and \\ a shell passes through single quotes
+// into the characters they name.
+func unescapeFormat(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n").Replace(s)
+}
+
+func printHistory(w io.Writer, entries []historyEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tDURATION\tEXIT\tDIR\tCOMMAND")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"),
+			formatDuration(e.duration()), e.ExitCode, shortenHome(e.Dir), oneLine(e.Command))
+	}
+	tw.Flush()
+}
+
+// oneLine flattens a multi-line command so it fits a table row.
+func oneLine(s string) string {
+	var b strings.Builder
+	for i, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if i > 0 {
+			b.WriteString(" ⏎ ")
+		}
+		b.WriteString(strings.TrimSpace(line))
+	}
+	return b.String()
+}
+
+// clipRunes shortens s to at most limit characters, ending in an ellipsis if
+// it was cut.
+func clipRunes(s string, limit int) string {
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,210 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Shell history tools that already time commands can backfill reporter's
+// history, so stats are useful from the first day. Both keep SQLite
+// databases, read through the sqlite3 shell like the SQLite history store.
+
+// importSource describes a tool whose history can be imported.
+type importSource struct {
+	// defaultDB returns the tool's default database location.
+	defaultDB func() string
+	// query selects started_ms, duration_ms, exit, command, cwd, host, and
+	// session for finished commands.
+	query string
+	// sessionLabel is the label holding the tool's session ID, matching
+	// the one the shell hooks attach to live runs.
+	sessionLabel string
+	// host cleans up the tool's host column.
+	host func(string) string
+}
+
+var importSources = map[string]importSource{
+	"atuin": {
+		defaultDB: func() string {
+			if p := os.Getenv("ATUIN_DB_PATH"); p != "" {
+				return p
+			}
+			dir := xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+			return filepath.Join(filepath.Dir(dir), "atuin", "history.db")
+		},
+		// Times are nanoseconds; a duration of -1 marks a command that
+		// never finished.
+		query: `SELECT timestamp / 1000000 AS started_ms, duration / 1000000 AS duration_ms, exit, command, cwd, hostname AS host, session
+FROM history WHERE deleted_at IS NULL AND duration >= 0 ORDER BY timestamp;`,
+		sessionLabel: "atuin_session",
+		// Atuin stores "host:user".
+		host: func(s string) string { host, _, _ := strings.Cut(s, ":"); return host },
+	},
+	"zsh-histdb": {
+		defaultDB: func() string {
+			if p := os.Getenv("HISTDB_FILE"); p != "" {
+				return p
+			}
+			home, _ := os.UserHomeDir()
+			return filepath.Join(home, ".histdb", "zsh-history.db")
+		},
+		// Times are seconds; unfinished commands have no duration.
+		query: `SELECT h.start_time * 1000 AS started_ms, h.duration * 1000 AS duration_ms, h.exit_status AS exit, c.argv AS command, p.dir AS cwd, p.host AS host, CAST(h.session AS TEXT) AS session
+FROM history h JOIN commands c ON c.id = h.command_id JOIN places p ON p.id = h.place_id
+WHERE h.duration IS NOT NULL AND h.exit_status IS NOT NULL ORDER BY h.start_time;`,
+		sessionLabel: "histdb_session",
+		host:         func(s string) string { return s },
+	},
+}
+
+type importRow struct {
+	StartedMS  int64  `json:"started_ms"`
+	DurationMS int64  `json:"duration_ms"`
+	Exit       int    `json:"exit"`
+	Command    string `json:"command"`
+	Cwd        string `json:"cwd"`
+	Host       string `json:"host"`
+	Session    string `json:"session"`
+}
+
+// importHistory reads finished commands from the named tool's database. Each
+// entry is labelled imported=<tool> and with the tool's session ID.
+func importHistory(tool, db string) ([]historyEntry, error) {
+	src := importSources[tool]
+	if _, err := os.Stat(db); err != nil {
+		return nil, err
+	}
+	out, err := runSQLite(db, true, src.query)
+	if err != nil {
+		return nil, err
+	}
+	var rows []importRow
+	if len(strings.TrimSpace(string(out))) > 0 {
+		if err := json.Unmarshal(out, &rows); err != nil {
+			return nil, fmt.Errorf("decoding sqlite3 output: %w", err)
+		}
+	}
+	entries := make([]historyEntry, 0, len(rows))
+	for _, r := range rows {
+		labels := map[string]string{"imported": tool}
+		if r.Session != "" {
+			labels[src.sessionLabel] = r.Session
+		}
+		entries = append(entries, historyEntry{
+			Time:       time.UnixMilli(r.StartedMS).UTC(),
+			Command:    strings.TrimRight(r.Command, "\n"),
+			Dir:        r.Cwd,
+			Host:       src.host(r.Host),
+			DurationMS: r.DurationMS,
+			ExitCode:   r.Exit,
+			Labels:     labels,
+		})
+	}
+	return entries, nil
+}
+
+// liveRunSlack is how far apart the start times of the same run may be when
+// recorded live by the shell hook and by the history tool. The hook derives
+// the start from when reporter ran, which is slightly after the command ended.
+const liveRunSlack = 2 * time.Second
+
+// newImports returns the entries of imported that are not already in
+// existing: either imported before (same start and command) or recorded live
+// by the shell hook in the same shell session.
+func newImports(existing, imported []historyEntry) []historyEntry {
+	exact := make(map[string]bool, len(existing))
+	live := map[string][]time.Time{} // session label, value, and command -> start times
+	for _, e := range existing {
+		exact[fmt.Sprintf("%d\x00%s", e.Time.UnixMilli(), e.Command)] = true
+		if e.Labels["imported"] != "" {
+			continue
+		}
+		for _, src := range importSources {
+			if session := e.Labels[src.sessionLabel]; session != "" {
+				k := src.sessionLabel + "\x00" + session + "\x00" + e.Command
+				live[k] = append(live[k], e.Time)
+			}
+		}
+	}
+
+	var fresh []historyEntry
+next:
+	for _, e := range imported {
+		key := fmt.Sprintf("%d\x00%s", e.Time.UnixMilli(), e.Command)
+		if exact[key] {
+			continue
+		}
+		for label, session := range e.Labels {
+			for _, t := range live[label+"\x00"+session+"\x00"+e.Command] {
+				if d := t.Sub(e.Time); d > -liveRunSlack && d < liveRunSlack {
+					continue next
+				}
+			}
+		}
+		exact[key] = true
+		fresh = append(fresh, e)
+	}
+	return fresh
+}
+
+// runHistoryImport implements `reporter history import`.
+func runHistoryImport(args []string) int {
+	fset := flag.NewFlagSet("history import", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter history import -from atuin|zsh-histdb [-db path]")
+		fset.PrintDefaults()
+	}
+	from := fset.String("from", "", "tool to import from: atuin or zsh-histdb")
+	db := fset.String("db", "", "path to the tool's database (default: the tool's standard location)")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	src, ok := importSources[*from]
+	if !ok || fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	if *db == "" {
+		*db = src.defaultDB()
+	}
+
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+	store, err := openHistory(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+
+	imported, err := importHistory(*from, *db)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "no %s database at %s; pass -db\n", *from, *db)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading %s: %v\n", *db, err)
+		return 1
+	}
+	existing, err := store.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading %s: %v\n", store.Path(), err)
+		return 1
+	}
+	fresh := newImports(existing, imported)
+	if err := store.Record(fresh...); err != nil {
+		fmt.Fprintf(os.Stderr, "writing %s: %v\n", store.Path(), err)
+		return 1
+	}
+	fmt.Printf("imported %d of %d runs from %s into %s\n", len(fresh), len(imported), *db, store.Path())
+	return 0
+}
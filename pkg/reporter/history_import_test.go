@@ -0,0 +1,104 @@
+package reporter
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImportHistory(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 250e6, time.UTC)
+	tests := []struct {
+		tool    string
+		schema  string
+		session map[string]string
+		want    []historyEntry
+	}{
+		{
+			tool: "atuin",
+			schema: `CREATE TABLE history (id TEXT PRIMARY KEY, timestamp INTEGER NOT NULL, duration INTEGER NOT NULL, exit INTEGER NOT NULL,
+	command TEXT NOT NULL, cwd TEXT NOT NULL, session TEXT NOT NULL, hostname TEXT NOT NULL, deleted_at INTEGER);
+INSERT INTO history VALUES ('a', 1772366400250000000, 90500000000, 0, 'make test', '/src/atlas', 's', 'dev:ann', NULL);
+INSERT INTO history VALUES ('b', 1772366460000000000, 12000000, 2, 'grep -r ''it''s''', '/tmp', 's', 'dev:ann', NULL);
+INSERT INTO history VALUES ('c', 1772366520000000000, -1, 0, 'vim', '/tmp', 's', 'dev:ann', NULL);
+INSERT INTO history VALUES ('d', 1772366580000000000, 1000000, 0, 'secret', '/tmp', 's', 'dev:ann', 1772366600000000000);`,
+			session: map[string]string{"atuin_session": "s"},
+			want: []historyEntry{
+				{Time: start, Command: "make test", Dir: "/src/atlas", Host: "dev", DurationMS: 90500, ExitCode: 0},
+				{Time: start.Add(time.Minute - 250*time.Millisecond), Command: "grep -r 'it's'", Dir: "/tmp", Host: "dev", DurationMS: 12, ExitCode: 2},
+			},
+		},
+		{
+			tool: "zsh-histdb",
+			schema: `CREATE TABLE commands (id INTEGER PRIMARY KEY, argv TEXT);
+CREATE TABLE places (id INTEGER PRIMARY KEY, host TEXT, dir TEXT);
+CREATE TABLE history (id INTEGER PRIMARY KEY, session INT, command_id INT, place_id INT, exit_status INT, start_time INT, duration INT);
+INSERT INTO commands VALUES (1, 'make test'), (2, 'sleep 100');
+INSERT INTO places VALUES (1, 'dev', '/src/atlas');
+INSERT INTO history VALUES (1, 1, 1, 1, 1, 1772366400, 91);
+INSERT INTO history VALUES (2, 1, 2, 1, NULL, 1772366500, NULL);`,
+			session: map[string]string{"histdb_session": "1"},
+			want: []historyEntry{
+				{Time: start.Truncate(time.Second), Command: "make test", Dir: "/src/atlas", Host: "dev", DurationMS: 91000, ExitCode: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			db := filepath.Join(t.TempDir(), "history.db")
+			if out, err := exec.Command("sqlite3", db, tt.schema).CombinedOutput(); err != nil {
+				t.Fatalf("creating fixture: %v\n%s", err, out)
+			}
+			got, err := importHistory(tt.tool, db)
+			if err != nil {
+				t.Fatalf("importHistory() returned error: %v", err)
+			}
+			for i := range tt.want {
+				tt.want[i].Labels = map[string]string{"imported": tt.tool}
+				for k, v := range tt.session {
+					tt.want[i].Labels[k] = v
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("importHistory() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportHistoryMissingDB(t *testing.T) {
+	if _, err := importHistory("atuin", filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("importHistory() on a missing database returned no error")
+	}
+}
+
+func TestNewImports(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	imported := func(at time.Time, command string) historyEntry {
+		return historyEntry{Time: at, Command: command, Labels: map[string]string{"imported": "atuin", "atuin_session": "s1"}}
+	}
+	existing := []historyEntry{
+		// Recorded live by the hook: start derived from the end, so a bit late.
+		{Time: start.Add(400 * time.Millisecond), Command: "make", Labels: map[string]string{"atuin_session": "s1"}},
+		// Imported earlier.
+		imported(start.Add(time.Hour), "make test"),
+		// Same command live in another session.
+		{Time: start.Add(2*time.Hour + 300*time.Millisecond), Command: "make", Labels: map[string]string{"atuin_session": "s2"}},
+	}
+	in := []historyEntry{
+		imported(start, "make"),
+		imported(start.Add(time.Hour), "make test"),
+		imported(start.Add(2*time.Hour), "make"),
+		imported(start.Add(3*time.Hour), "make"),
+		imported(start.Add(3*time.Hour), "make"),
+	}
+	want := []historyEntry{in[2], in[3]}
+	if got := newImports(existing, in); !reflect.DeepEqual(got, want) {
+		t.Errorf("newImports() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The SQLite history store keeps runs in a database that dashboards and ad hoc
+// SQL can read directly. reporter drives the sqlite3 command-line shell rather
+// than linking a driver, so the binary stays dependency-free.
+//
+// The schema below is stable: columns are only ever added, and user_version
+// is bumped when that happens. README.md documents it for readers.
+
+const (
+	sqliteHistoryFileName = "history.sqlite3"
+	sqliteSchemaVersion   = 1
+	// sqliteBusyTimeout lets concurrent shells wait for each other's writes
+	// instead of failing with "database is locked".
+	sqliteBusyTimeout = 2 * time.Second
+)
+
+var sqliteSchema = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS runs (
+  id          INTEGER PRIMARY KEY,
+  started_at  TEXT    NOT NULL, -- UTC, RFC 3339 with milliseconds
+  command     TEXT    NOT NULL,
+  cwd         TEXT    NOT NULL DEFAULT '',
+  host        TEXT    NOT NULL DEFAULT '',
+  duration_ms INTEGER NOT NULL,
+  exit_code   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS runs_started_at ON runs (started_at);
+CREATE TABLE IF NOT EXISTS run_labels (
+  run_id INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+  key    TEXT    NOT NULL,
+  value  TEXT    NOT NULL,
+  PRIMARY KEY (run_id, key)
+);
+PRAGMA user_version = %d;
+`, sqliteSchemaVersion)
+
+// sqliteTimeFormat sorts lexically in time order, so started_at comparisons
+// work in plain SQL.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000Z"
+
+func sqliteHistoryPath() string {
+	dir := dataDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, sqliteHistoryFileName)
+}
+
+type sqliteHistory struct{ path string }
+
+func (h sqliteHistory) Path() string { return h.path }
+
+func (h sqliteHistory) Record(entries ...historyEntry) error {
+	if h.path == "" || len(entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(h.path); errors.Is(err, fs.ErrNotExist) {
+		// Commands can contain secrets passed as arguments; keep the
+		// database private.
+		f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+	var script strings.Builder
+	script.WriteString(sqliteSchema)
+	// BEGIN IMMEDIATE takes the write lock up front, which insertRunSQL
+	// relies on.
+	script.WriteString("BEGIN IMMEDIATE;\n")
+	for _, e := range entries {
+		script.WriteString(insertRunSQL(e))
+	}
+	script.WriteString("COMMIT;\n")
+	_, err := runSQLite(h.path, false, script.String())
+	return err
+}
+
+// insertRunSQL returns statements inserting e and its labels. They must run
+// inside a BEGIN IMMEDIATE transaction.
+func insertRunSQL(e historyEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO runs (started_at, command, cwd, host, duration_ms, exit_code) VALUES (%s, %s, %s, %s, %d, %d);\n",
+		sqlQuote(e.Time.UTC().Format(sqliteTimeFormat)), sqlQuote(e.Command), sqlQuote(e.Dir), sqlQuote(e.Host), e.DurationMS, e.ExitCode)
+	keys := make([]string, 0, len(e.Labels))
+	for k := range e.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// last_insert_rowid() would point at the previous label row;
+		// the write lock guarantees the newest run is ours.
+		fmt.Fprintf(&b, "INSERT INTO run_labels (run_id, key, value) VALUES ((SELECT max(id) FROM runs), %s, %s);\n", sqlQuote(k), sqlQuote(e.Labels[k]))
+	}
+	return b.String()
+}
+
+const sqliteSelectRuns = `SELECT started_at, command, cwd, host, duration_ms, exit_code,
+  (SELECT json_group_object(key, value) FROM run_labels WHERE run_id = runs.id) AS labels
+FROM runs ORDER BY id;
+`
+
+type sqliteRunRow struct {
+	StartedAt  string `json:"started_at"`
+	Command    string `json:"command"`
+	Cwd        string `json:"cwd"`
+	Host       string `json:"host"`
+	DurationMS int64  `json:"duration_ms"`
+	ExitCode   int    `json:"exit_code"`
+	Labels     string `json:"labels"`
+}
+
+func (h sqliteHistory) Load() ([]historyEntry, error) {
+	if _, err := os.Stat(h.path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	out, err := runSQLite(h.path, true, sqliteSelectRuns)
+	if err != nil {
+		return nil, err
+	}
+	return parseSQLiteRuns(out)
+}
+
+// parseSQLiteRuns decodes the output of sqlite3 -json for sqliteSelectRuns.
+func parseSQLiteRuns(out []byte) ([]historyEntry, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil // sqlite3 prints nothing for an empty result
+	}
+	var rows []sqliteRunRow
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("decoding sqlite3 output: %w", err)
+	}
+	entries := make([]historyEntry, 0, len(rows))
+	for _, r := range rows {
+		started, err := time.Parse(time.RFC3339, r.StartedAt)
+		if err != nil {
+			continue
+		}
+		e := historyEntry{
+			Time:       started,
+			Command:    r.Command,
+			Dir:        r.Cwd,
+			Host:       r.Host,
+			DurationMS: r.DurationMS,
+			ExitCode:   r.ExitCode,
+		}
+		if r.Labels != "" && r.Labels != "{}" {
+			_ = json.Unmarshal([]byte(r.Labels), &e.Labels)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// runSQLite feeds script to the sqlite3 shell for the database at path and
+// returns its output. Queries run read-only and print JSON.
+func runSQLite(path string, query bool, script string) ([]byte, error) {
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return nil, errors.New("the sqlite3 command-line shell is not in PATH")
+	}
+	args := []string{"-bail", "-batch"}
+	if query {
+		args = append(args, "-readonly", "-json")
+	}
+	cmd := exec.Command(bin, append(args, path)...)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(".timeout %d\n", sqliteBusyTimeout.Milliseconds()) + script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sqlite3: %s", msg)
+		}
+		return nil, fmt.Errorf("sqlite3: %w", err)
+	}
+	return out, nil
+}
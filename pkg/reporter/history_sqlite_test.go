@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLiteHistory(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	store := sqliteHistory{path: filepath.Join(t.TempDir(), "reporter", sqliteHistoryFileName)}
+
+	entries, err := store.Load()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Load() on a missing database = %v, %v; want no entries", entries, err)
+	}
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 123e6, time.UTC)
+	want := []historyEntry{
+		{Time: start, Command: "make test", Dir: "/src/atlas", Host: "dev", DurationMS: 90000, ExitCode: 0},
+		{
+			Time:       start.Add(time.Minute),
+			Command:    "echo 'quoted' \"twice\"\n; DROP TABLE runs; -- ✓",
+			Dir:        "/tmp/it's here",
+			DurationMS: 5,
+			ExitCode:   2,
+			Labels:     map[string]string{"project": "atlas", "note": "a=b,c"},
+		},
+	}
+	for _, e := range want {
+		if err := store.Record(e); err != nil {
+			t.Fatalf("Record() returned error: %v", err)
+		}
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Load() returned %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) {
+			t.Errorf("entry %d time = %v, want %v", i, got[i].Time, want[i].Time)
+		}
+		got[i].Time = want[i].Time
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if fi, err := os.Stat(store.path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("database mode = %v (%v), want 0600", fi.Mode().Perm(), err)
+	}
+
+	// The documented schema is what dashboards query directly.
+	out, err := exec.Command("sqlite3", store.path, "PRAGMA user_version; SELECT count(*) FROM runs JOIN run_labels ON run_id = runs.id WHERE exit_code != 0;").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "1\n2\n" {
+		t.Errorf("schema query output = %q, want version 1 and 2 labels on failed runs", out)
+	}
+}
+
+func TestOpenHistory(t *testing.T) {
+	base := isolateConfig(t)
+	tests := []struct {
+		content string
+		want    historyStore
+		wantErr bool
+	}{
+		{content: "", want: jsonlHistory{path: historyPath()}},
+		{content: "history_store = \"sqlite\"\n", want: sqliteHistory{path: sqliteHistoryPath()}},
+		{content: "history_store = \"postgres\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		writeFile(t, systemConfigPath, tt.content)
+		cfg, err := loadConfig(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := openHistory(cfg)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("openHistory(%q) error = %v, wantErr %v", tt.content, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("openHistory(%q) = %#v, want %#v", tt.content, got, tt.want)
+		}
+	}
+}
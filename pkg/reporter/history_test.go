@@ -0,0 +1,132 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestRecordAndLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reporter", historyFileName)
+
+	entries, err := loadHistory(path)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("loadHistory() on a missing file = %v, %v; want no entries", entries, err)
+	}
+
+	end := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := newHistoryEntry(runResult{Command: "make test", Args: []string{"make", "test"}, Duration: 90 * time.Second, ExitCode: 2, Labels: map[string]string{"env": "ci"}}, end)
+	if !e.Time.Equal(end.Add(-90*time.Second)) || e.DurationMS != 90000 || e.Dir == "" {
+		t.Errorf("newHistoryEntry() = %+v", e)
+	}
+	for i := 0; i < 2; i++ {
+		if err := recordHistory(path, e); err != nil {
+			t.Fatalf("recordHistory() returned error: %v", err)
+		}
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("not json\n")
+	f.Close()
+
+	entries, err = loadHistory(path)
+	if err != nil {
+		t.Fatalf("loadHistory() returned error: %v", err)
+	}
+	if len(entries) != 2 || !reflect.DeepEqual(entries[0], e) {
+		t.Errorf("loadHistory() = %+v, want two copies of %+v", entries, e)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("history file mode = %v (%v), want 0600", fi.Mode().Perm(), err)
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entry := historyEntry{Time: base, Command: "go test ./...", ExitCode: 1, Labels: map[string]string{"project": "atlas"}}
+
+	tests := []struct {
+		name   string
+		filter historyFilter
+		want   bool
+	}{
+		{name: "empty filter", filter: historyFilter{}, want: true},
+		{name: "command substring", filter: historyFilter{command: "GO TEST"}, want: true},
+		{name: "command mismatch", filter: historyFilter{command: "make"}, want: false},
+		{name: "failures", filter: historyFilter{status: notifyOnFailure}, want: true},
+		{name: "successes", filter: historyFilter{status: notifyOnSuccess}, want: false},
+		{name: "label", filter: historyFilter{labels: map[string]string{"project": "atlas"}}, want: true},
+		{name: "label mismatch", filter: historyFilter{labels: map[string]string{"project": "zeus"}}, want: false},
+		{name: "since before", filter: historyFilter{since: base.Add(-time.Hour)}, want: true},
+		{name: "since after", filter: historyFilter{since: base.Add(time.Minute)}, want: false},
+		{name: "until is exclusive", filter: historyFilter{until: base}, want: false},
+		{name: "within range", filter: historyFilter{since: base, until: base.Add(time.Second)}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.match(entry); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "36h", want: now.Add(-36 * time.Hour)},
+		{input: "2026-02-14", want: time.Date(2026, 2, 14, 0, 0, 0, 0, time.Local)},
+		{input: "2026-02-14T09:30:00Z", want: time.Date(2026, 2, 14, 9, 30, 0, 0, time.UTC)},
+		{input: "last week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.input, now)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseTimeBound(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatHistory(t *testing.T) {
+	entries := []historyEntry{
+		{Time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Command: "make test", Dir: "/src/app", DurationMS: 90000},
+		{Time: time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC), Command: "./deploy.sh \\\n  --prod", DurationMS: 1500, ExitCode: 2, Labels: map[string]string{"project": "atlas"}},
+	}
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{name: "escaped tabs", format: `{{.Duration}}\t{{.ExitCode}}\t{{.Command}}`, want: "1m30s\t0\tmake test\n2s\t2\t./deploy.sh \\ ⏎ --prod\n"},
+		{name: "fields", format: `{{.Dir}} {{.DurationMS}} {{if .Failed}}FAIL{{end}}`, want: "/src/app 90000 \n 1500 FAIL\n"},
+		{name: "labels", format: `{{index .Labels "project"}}`, want: "\natlas\n"},
+		{name: "escaped backslash", format: `{{.ExitCode}}\\t`, want: "0\\t\n2\\t\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("format").Option("missingkey=error").Parse(unescapeFormat(tt.format))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := formatHistory(&b, tmpl, entries); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("formatHistory() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// `reporter history tui` browses the history full screen, much like fzf:
+// typing narrows the runs by fuzzy matching their commands, newest first, and
+// keys act on the selected run. It needs a Unix terminal, which it switches
+// to raw mode with the pseudo-terminal code (see pty.go).
+
+// tuiKey is a key read from the terminal: a named key such as "up" or
+// "ctrl-r", or a typed rune.
+type tuiKey struct {
+	name string
+	r    rune
+}
+
+// tuiAction is what the browser asks runHistoryTUI to do after a key.
+type tuiAction int
+
+const (
+	tuiNone tuiAction = iota
+	tuiQuit
+	tuiRerun  // run the selected command again, leaving the browser
+	tuiOutput // show the output captured when the selected run failed
+	tuiCopy   // copy the selected command to the clipboard
+	tuiResend // send the selected run's notification again
+)
+
+const tuiHelp = "enter re-run · ^O output · ^Y copy · ^R resend · esc quit"
+
+// readKey reads one key press, decoding the escape sequences terminals send
+// for arrow and paging keys. An escape not followed by more input at once is
+// the Esc key itself.
+func readKey(r *bufio.Reader) (tuiKey, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return tuiKey{}, err
+	}
+	switch {
+	case b == 0x1b:
+		if r.Buffered() == 0 {
+			return tuiKey{name: "esc"}, nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return tuiKey{name: "esc"}, nil
+		}
+		var seq []byte
+		for r.Buffered() > 0 {
+			c, _ := r.ReadByte()
+			seq = append(seq, c)
+			if c >= 0x40 && c <= 0x7e {
+				break
+			}
+		}
+		switch string(seq) {
+		case "A":
+			return tuiKey{name: "up"}, nil
+		case "B":
+			return tuiKey{name: "down"}, nil
+		case "5~":
+			return tuiKey{name: "pgup"}, nil
+		case "6~":
+			return tuiKey{name: "pgdn"}, nil
+		}
+		return tuiKey{}, nil
+	case b == '\r' || b == '\n':
+		return tuiKey{name: "enter"}, nil
+	case b == 0x7f || b == 0x08:
+		return tuiKey{name: "backspace"}, nil
+	case b < 0x20:
+		return tuiKey{name: "ctrl-" + string(rune('a'+b-1))}, nil
+	}
+	if err := r.UnreadByte(); err != nil {
+		return tuiKey{}, err
+	}
+	c, _, err := r.ReadRune()
+	return tuiKey{r: c}, err
+}
+
+// fuzzyScore reports whether every space-separated term of query appears in
+// text with its characters in order, ignoring case, and scores the match:
+// runs of adjacent characters and characters starting a word count more,
+// gaps count against it.
+func fuzzyScore(query, text string) (int, bool) {
+	lower := []rune(strings.ToLower(text))
+	total := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		score, ok := fuzzyTerm([]rune(term), lower)
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// fuzzyTerm matches term greedily from each place its first character
+// occurs in text and keeps the best score.
+func fuzzyTerm(term, text []rune) (best int, ok bool) {
+	for start := range text {
+		if text[start] != term[0] {
+			continue
+		}
+		score, j, prev := 0, 0, -1
+		for i := start; i < len(text) && j < len(term); i++ {
+			if text[i] != term[j] {
+				continue
+			}
+			score++
+			if prev >= 0 && prev == i-1 {
+				score += 4
+			} else if prev >= 0 {
+				score -= min(i-prev-1, 3)
+			}
+			if i == 0 || strings.ContainsRune(" /-_.=:", text[i-1]) {
+				score += 3
+			}
+			prev = i
+			j++
+		}
+		if j == len(term) && (!ok || score > best) {
+			best, ok = score, true
+		}
+	}
+	return best, ok
+}
+
+// historyBrowser is the state of `reporter history tui`, apart from the
+// terminal.
+type historyBrowser struct {
+	entries []historyEntry // newest first
+	query   []rune
+	matches []int // indices into entries, best match first
+	cursor  int   // index into matches of the selected run
+	top     int   // first match on screen
+	status  string
+
+	// output holds the lines being viewed with ^O, or nil for the list.
+	output    []string
+	outputTop int
+}
+
+// newHistoryBrowser browses entries, given oldest first as loaded.
+func newHistoryBrowser(entries []historyEntry) *historyBrowser {
+	b := &historyBrowser{entries: make([]historyEntry, len(entries))}
+	for i, e := range entries {
+		b.entries[len(entries)-1-i] = e
+	}
+	b.filter()
+	return b
+}
+
+// filter matches the entries against the query and selects the best match.
+func (b *historyBrowser) filter() {
+	type match struct{ index, score int }
+	var ms []match
+	for i, e := range b.entries {
+		if score, ok := fuzzyScore(string(b.query), e.Command); ok {
+			ms = append(ms, match{i, score})
+		}
+	}
+	// Stable, so equal matches stay newest first.
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].score > ms[j].score })
+	b.matches = b.matches[:0]
+	for _, m := range ms {
+		b.matches = append(b.matches, m.index)
+	}
+	b.cursor, b.top = 0, 0
+}
+
+// selected returns the run under the cursor; ok is false if nothing matches.
+func (b *historyBrowser) selected() (e historyEntry, ok bool) {
+	if len(b.matches) == 0 {
+		return historyEntry{}, false
+	}
+	return b.entries[b.matches[b.cursor]], true
+}
+
+// move moves the cursor by delta, scrolling to keep it among the rows shown.
+func (b *historyBrowser) move(delta, rows int) {
+	b.cursor = max(min(b.cursor+delta, len(b.matches)-1), 0)
+	if b.cursor < b.top {
+		b.top = b.cursor
+	}
+	if rows > 0 && b.cursor >= b.top+rows {
+		b.top = b.cursor - rows + 1
+	}
+}
+
+// handle applies k with rows lines of list on screen and returns what the
+// caller should do about it.
+func (b *historyBrowser) handle(k tuiKey, rows int) tuiAction {
+	if b.output != nil {
+		switch k.name {
+		case "ctrl-c":
+			return tuiQuit
+		case "esc", "enter", "ctrl-o":
+			b.output = nil
+		case "up", "ctrl-p":
+			b.outputTop = max(b.outputTop-1, 0)
+		case "down", "ctrl-n":
+			b.outputTop = max(min(b.outputTop+1, len(b.output)-rows), 0)
+		case "pgup":
+			b.outputTop = max(b.outputTop-rows, 0)
+		case "pgdn":
+			b.outputTop = max(min(b.outputTop+rows, len(b.output)-rows), 0)
+		default:
+			if k.r == 'q' {
+				b.output = nil
+			}
+		}
+		return tuiNone
+	}
+
+	b.status = ""
+	switch k.name {
+	case "esc", "ctrl-c", "ctrl-g", "ctrl-q":
+		return tuiQuit
+	case "up", "ctrl-p", "ctrl-k":
+		b.move(-1, rows)
+	case "down", "ctrl-n":
+		b.move(1, rows)
+	case "pgup":
+		b.move(-rows, rows)
+	case "pgdn":
+		b.move(rows, rows)
+	case "backspace":
+		if len(b.query) > 0 {
+			b.query = b.query[:len(b.query)-1]
+			b.filter()
+		}
+	case "ctrl-u":
+		b.query = b.query[:0]
+		b.filter()
+	case "ctrl-w":
+		q := strings.TrimRight(string(b.query), " ")
+		b.query = []rune(q[:strings.LastIndex(q, " ")+1])
+		b.filter()
+	case "enter", "ctrl-o", "ctrl-y", "ctrl-r":
+		if _, ok := b.selected(); !ok {
+			return tuiNone
+		}
+		return map[string]tuiAction{"enter": tuiRerun, "ctrl-o": tuiOutput, "ctrl-y": tuiCopy, "ctrl-r": tuiResend}[k.name]
+	case "":
+		if k.r >= ' ' {
+			b.query = append(b.query, k.r)
+			b.filter()
+		}
+	}
+	return tuiNone
+}
+
+// listRows is how many runs fit on a screen height lines tall, under the
+// prompt and count lines and above the help line.
+func listRows(height int) int {
+	return max(height-3, 1)
+}
+
+// render draws the browser on a width by height screen.
+func (b *historyBrowser) render(w io.Writer, width, height int) {
+	width = max(width, 20)
+	rows := listRows(height)
+	var lines []string
+	clip := func(s string) string { return clipRunes(s, width) }
+
+	if b.output != nil {
+		e, _ := b.selected()
+		lines = append(lines, "\x1b[1m"+clip(oneLine(e.Command))+"\x1b[0m", "")
+		end := min(b.outputTop+rows, len(b.output))
+		for _, line := range b.output[b.outputTop:end] {
+			lines = append(lines, clip(line))
+		}
+		for len(lines) < rows+2 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "\x1b[2m"+clip("↑↓ scroll · esc back")+"\x1b[0m")
+		fmt.Fprint(w, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+		return
+	}
+
+	lines = append(lines, "> "+string(b.query))
+	count := fmt.Sprintf("  %d/%d", len(b.matches), len(b.entries))
+	if b.status != "" {
+		count += "  " + b.status
+	}
+	lines = append(lines, "\x1b[2m"+clip(count)+"\x1b[0m")
+	end := min(b.top+rows, len(b.matches))
+	for i := b.top; i < end; i++ {
+		e := b.entries[b.matches[i]]
+		row := fmt.Sprintf("%s %8s %4d  %s", e.Time.Local().Format("01-02 15:04"), formatDuration(e.duration()), e.ExitCode, oneLine(e.Command))
+		row = clipRunes(row, width-2)
+		switch {
+		case i == b.cursor:
+			row = "\x1b[7m> " + row + "\x1b[0m"
+		case e.ExitCode != 0:
+			row = "  \x1b[31m" + row + "\x1b[0m"
+		default:
+			row = "  " + row
+		}
+		lines = append(lines, row)
+	}
+	for len(lines) < rows+2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "\x1b[2m"+clip(tuiHelp)+"\x1b[0m")
+	// Leave the cursor after the query.
+	fmt.Fprintf(w, "\x1b[H\x1b[2J%s\x1b[1;%dH", strings.Join(lines, "\r\n"), len(b.query)+3)
+}
+
+// sentFor finds the notification sent about e in the log, if any: the
+// newest one about the same command logged within a minute of the run
+// finishing.
+func sentFor(sent []sentNotification, e historyEntry) (sentNotification, bool) {
+	end := e.Time.Add(e.duration())
+	for i := len(sent) - 1; i >= 0; i-- {
+		s := sent[i]
+		if s.Command == e.Command && !s.Time.Before(e.Time) && s.Time.Sub(end) < time.Minute {
+			return s, true
+		}
+	}
+	return sentNotification{}, false
+}
+
+// resendNotification rebuilds the notification about e, from the log when
+// it was sent, or as reporter would have sent it otherwise.
+func resendNotification(sent []sentNotification, e historyEntry, title string) notification {
+	n := notification{
+		Title:    title,
+		Subtitle: e.Command,
+		Failed:   e.ExitCode != 0,
+		Finished: e.Time.Add(e.duration()),
+		Duration: e.duration(),
+		ExitCode: e.ExitCode,
+		Labels:   e.Labels,
+	}
+	if s, ok := sentFor(sent, e); ok {
+		n.Title, n.Body, n.Output = s.Title, s.Body, s.Output
+		return n
+	}
+	n.Body = fmt.Sprintf("%s in %s", resultStatus(runResult{ExitCode: e.ExitCode, Signal: shellSignal(e.ExitCode)}), formatDuration(e.duration()))
+	return n
+}
+
+// clipboardSequence is the OSC 52 escape sequence that has the terminal
+// copy text to the clipboard, wrapped for tmux to pass it on.
+func clipboardSequence(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// runHistoryTUI implements `reporter history tui`.
+func runHistoryTUI(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+	fset := flag.NewFlagSet("history tui", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter history tui [flags]")
+		fmt.Fprintln(fset.Output(), "Browse past runs with fuzzy search; re-run one, view its output, copy it, or resend its notification.")
+		fset.PrintDefaults()
+	}
+	resolvePush := registerPushFlags(fset, cfg, "HTTP endpoint to also resend notifications to")
+	resolveDesktop := registerDesktopFlag(fset, cfg)
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	cfg.enforceLocks(fset)
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	targets, err := resolvePush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	desktop, err := resolveDesktop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, "history tui: needs a terminal; use reporter history to list runs")
+		return 2
+	}
+
+	entries, ok := readHistory()
+	if !ok {
+		return 1
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "history tui: no runs recorded yet")
+		return 1
+	}
+	sent, err := loadNotifications(notificationLogPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[notifications] %v\n", err)
+	}
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history tui: %v\n", err)
+		return 1
+	}
+	screen := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(screen, "\x1b[?1049h") // the alternate screen, as full-screen tools use
+	leave := func() {
+		fmt.Fprint(screen, "\x1b[?1049l")
+		screen.Flush()
+		restore()
+	}
+
+	b := newHistoryBrowser(entries)
+	in := bufio.NewReader(os.Stdin)
+	for {
+		width, height := terminalSize(os.Stdout)
+		b.render(screen, width, height)
+		screen.Flush()
+		k, err := readKey(in)
+		if err != nil {
+			leave()
+			return 1
+		}
+		e, _ := b.selected()
+		switch b.handle(k, listRows(height)) {
+		case tuiQuit:
+			leave()
+			return 0
+		case tuiRerun:
+			if strings.Contains(e.Command, redacted) {
+				b.status = "the command was recorded with secrets scrubbed; copy it with ^Y and fill them in"
+				continue
+			}
+			leave()
+			return rerun(e)
+		case tuiOutput:
+			s, ok := sentFor(sent, e)
+			switch {
+			case !ok:
+				b.status = "no notification was sent about this run"
+			case s.Output == "":
+				b.status = "no output was captured for this run (see -capture-output)"
+			default:
+				b.output, b.outputTop = strings.Split(s.Output, "\n"), 0
+			}
+		case tuiCopy:
+			fmt.Fprint(screen, clipboardSequence(e.Command, os.Getenv("TMUX") != ""))
+			b.status = "copied the command to the clipboard"
+		case tuiResend:
+			b.status = resend(cfg, desktop, targets, resendNotification(sent, e, cfg.string("title", "Task finished")))
+		}
+	}
+}
+
+// resend sends n to the desktop and push targets and says how it went.
+func resend(cfg *config, desktop bool, targets []pushTarget, n notification) string {
+	if !desktop && len(targets) == 0 {
+		return "nowhere to resend to: use -desktop always or -push-url"
+	}
+	var deliveries []delivery
+	if desktop {
+		agent := hostAgent(cfg)
+		err := showDesktop(agent, terminalNotifier(cfg, currentEnvironment(), agent), n)
+		deliveries = append(deliveries, newDelivery("desktop", err))
+	}
+	pushed, _ := pushAll(targets, n, spoolDir())
+	deliveries = append(deliveries, pushed...)
+	return "resent: " + summarizeDeliveries(deliveries)
+}
+
+// rerun runs e's command again under reporter, in the directory it ran in,
+// and returns its exit status. A command recorded without its argv is a
+// shell command line.
+func rerun(e historyEntry) int {
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history tui: %v\n", err)
+		return 1
+	}
+	args := []string{}
+	for k, v := range e.Labels {
+		args = append(args, "-label", k+"="+v)
+	}
+	if len(e.Args) > 0 {
+		args = append(append(args, "--"), e.Args...)
+	} else {
+		args = append(args, "-c", e.Command)
+	}
+	cmd := exec.Command(self, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if fi, err := os.Stat(e.Dir); err == nil && fi.IsDir() {
+		cmd.Dir = e.Dir
+	} else {
+		cmd.Dir, _ = os.Getwd()
+	}
+	fmt.Fprintf(os.Stderr, "%s$ %s\n", shortenHome(cmd.Dir), e.Command)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history tui: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadKey(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("m\x1b[A\x1b[6~\r\x7f\x12é"))
+	var got []tuiKey
+	for {
+		k, err := readKey(in)
+		if err != nil {
+			break
+		}
+		got = append(got, k)
+	}
+	want := []tuiKey{{r: 'm'}, {name: "up"}, {name: "pgdn"}, {name: "enter"}, {name: "backspace"}, {name: "ctrl-r"}, {r: 'é'}}
+	if len(got) != len(want) {
+		t.Fatalf("readKey() read %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if k, _ := readKey(bufio.NewReader(strings.NewReader("\x1b"))); k.name != "esc" {
+		t.Errorf("a lone escape reads as %+v, want esc", k)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	for _, tt := range []struct {
+		query, text string
+		ok          bool
+	}{
+		{query: "", text: "make", ok: true},
+		{query: "mkt", text: "make test", ok: true},
+		{query: "MAKE", text: "make test", ok: true},
+		{query: "tm", text: "make test", ok: false},
+		{query: "make deploy", text: "make -C infra deploy", ok: true},
+		{query: "make deploy", text: "make test", ok: false},
+	} {
+		if _, ok := fuzzyScore(tt.query, tt.text); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.text, ok, tt.ok)
+		}
+	}
+
+	// Adjacent characters and word starts rank higher than scattered ones.
+	for _, tt := range []struct{ query, better, worse string }{
+		{query: "test", better: "go test ./...", worse: "terraform state list"},
+		{query: "bk", better: "./scripts/backfill --kind=daily", worse: "docker build ."},
+	} {
+		b, _ := fuzzyScore(tt.query, tt.better)
+		w, _ := fuzzyScore(tt.query, tt.worse)
+		if b <= w {
+			t.Errorf("fuzzyScore(%q) ranks %q (%d) no higher than %q (%d)", tt.query, tt.better, b, tt.worse, w)
+		}
+	}
+}
+
+func TestHistoryBrowser(t *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	b := newHistoryBrowser([]historyEntry{
+		{Time: start, Command: "make test", DurationMS: 60000},
+		{Time: start.Add(time.Hour), Command: "terraform apply", DurationMS: 300000, ExitCode: 1},
+		{Time: start.Add(2 * time.Hour), Command: "go test ./...", DurationMS: 90000},
+	})
+	if e, _ := b.selected(); e.Command != "go test ./..." {
+		t.Fatalf("selected %q first, want the newest run", e.Command)
+	}
+
+	for _, r := range "test" {
+		b.handle(tuiKey{r: r}, 10)
+	}
+	if len(b.matches) != 2 {
+		t.Fatalf("query %q matches %d runs, want 2", string(b.query), len(b.matches))
+	}
+	b.handle(tuiKey{name: "down"}, 10)
+	if e, _ := b.selected(); e.Command != "make test" {
+		t.Errorf("down selected %q, want make test", e.Command)
+	}
+	b.handle(tuiKey{name: "down"}, 10)
+	if b.cursor != 1 {
+		t.Errorf("down past the last match moved the cursor to %d", b.cursor)
+	}
+	for key, want := range map[string]tuiAction{"enter": tuiRerun, "ctrl-o": tuiOutput, "ctrl-y": tuiCopy, "ctrl-r": tuiResend, "esc": tuiQuit} {
+		if got := b.handle(tuiKey{name: key}, 10); got != want {
+			t.Errorf("%s = action %d, want %d", key, got, want)
+		}
+	}
+
+	b.handle(tuiKey{name: "ctrl-u"}, 10)
+	for _, r := range "zzz" {
+		b.handle(tuiKey{r: r}, 10)
+	}
+	if b.handle(tuiKey{name: "enter"}, 10) != tuiNone {
+		t.Error("enter with no matching run asked for an action")
+	}
+	b.handle(tuiKey{name: "ctrl-w"}, 10)
+	if len(b.query) != 0 || len(b.matches) != 3 {
+		t.Errorf("ctrl-w left query %q matching %d runs", string(b.query), len(b.matches))
+	}
+
+	var screen bytes.Buffer
+	b.render(&screen, 80, 10)
+	for _, want := range []string{"3/3", "go test ./...", "terraform apply", tuiHelp} {
+		if !strings.Contains(screen.String(), want) {
+			t.Errorf("screen does not show %q:\n%s", want, screen.String())
+		}
+	}
+
+	b.output = []string{"line 1", "line 2", "line 3"}
+	b.handle(tuiKey{name: "down"}, 2)
+	if b.outputTop != 1 {
+		t.Errorf("scrolling output moved to line %d, want 1", b.outputTop)
+	}
+	b.handle(tuiKey{name: "esc"}, 2)
+	if b.output != nil {
+		t.Error("esc did not leave the output view")
+	}
+}
+
+func TestResendNotification(t *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	e := historyEntry{Time: start, Command: "./nightly-etl", DurationMS: 180000, ExitCode: 2}
+	sent := []sentNotification{
+		{Time: start.Add(-time.Hour), Title: "Task finished", Body: "failed (exit 2) in 2m00s", Command: "./nightly-etl", Failed: true},
+		{Time: start.Add(3*time.Minute + time.Second), Title: "ETL", Body: "failed (exit 2) in 3m00s", Command: "./nightly-etl", Failed: true, Output: "error: disk full"},
+		{Time: start.Add(3 * time.Minute), Title: "Task finished", Body: "succeeded in 1s", Command: "make"},
+	}
+	n := resendNotification(sent, e, "Task finished")
+	if n.Title != "ETL" || n.Output != "error: disk full" || !n.Failed {
+		t.Errorf("resendNotification() = %+v, want the logged notification", n)
+	}
+
+	n = resendNotification(nil, e, "Task finished")
+	if n.Title != "Task finished" || n.Body != "failed (exit 2) in 3m00s" || n.Subtitle != "./nightly-etl" {
+		t.Errorf("resendNotification() without a log = %+v", n)
+	}
+}
+
+func TestClipboardSequence(t *testing.T) {
+	if got, want := clipboardSequence("make", false), "\x1b]52;c;bWFrZQ==\a"; got != want {
+		t.Errorf("clipboardSequence() = %q, want %q", got, want)
+	}
+	if got, want := clipboardSequence("make", true), "\x1bPtmux;\x1b\x1b]52;c;bWFrZQ==\a\x1b\\"; got != want {
+		t.Errorf("clipboardSequence() in tmux = %q, want %q", got, want)
+	}
+}
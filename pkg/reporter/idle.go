@@ -0,0 +1,73 @@
+package reporter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -push-when-idle holds back pushes while the user is at the desktop, where
+// the desktop notification already reaches them, and sends them only once
+// the keyboard and mouse have been idle for a while. Each platform asks how
+// long the user has been idle (see idle_darwin.go and idle_linux.go). When
+// that cannot be told, as over SSH, pushes are sent.
+
+// idleTimeout bounds the queries that find how long the user has been idle.
+const idleTimeout = time.Second
+
+// userIdle returns how long the desktop's user has been idle, and false if
+// it cannot be told.
+func userIdle(env environment) (time.Duration, bool) {
+	if env.SSH || env.headless() {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), idleTimeout)
+	defer cancel()
+	return idleTime(ctx, time.Now())
+}
+
+// userAway reports whether pushes should go out under -push-when-idle: the
+// user has been idle for at least after, or it cannot be told.
+func userAway(env environment, after time.Duration) bool {
+	idle, ok := userIdle(env)
+	return !ok || idle >= after
+}
+
+// parseHIDIdleTime finds HIDIdleTime, in nanoseconds, in the output of
+// ioreg -c IOHIDSystem.
+func parseHIDIdleTime(out string) (time.Duration, bool) {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != `"HIDIdleTime"` {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		return time.Duration(ns), err == nil
+	}
+	return 0, false
+}
+
+// parseIdleHint reads the output of loginctl show-session -p IdleHint -p
+// IdleSinceHint: not idle, or idle since a time in microseconds since the
+// epoch.
+func parseIdleHint(out string, now time.Time) (time.Duration, bool) {
+	var hint string
+	var since int64
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "IdleHint":
+			hint = value
+		case "IdleSinceHint":
+			since, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	switch {
+	case hint == "no":
+		return 0, true
+	case hint == "yes" && since > 0:
+		return max(now.Sub(time.UnixMicro(since)), 0), true
+	}
+	return 0, false
+}
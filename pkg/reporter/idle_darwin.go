@@ -0,0 +1,17 @@
+package reporter
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// idleTime returns the time since the last keyboard or mouse input, which
+// the HID system reports as HIDIdleTime.
+func idleTime(ctx context.Context, now time.Time) (time.Duration, bool) {
+	out, err := exec.CommandContext(ctx, "ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+	if err != nil {
+		return 0, false
+	}
+	return parseHIDIdleTime(string(out))
+}
@@ -0,0 +1,33 @@
+package reporter
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idleTime returns how long the user has been idle: from xprintidle under
+// X11, otherwise from the IdleHint logind keeps for the session. Desktops set
+// IdleHint only after their own idle delay, such as GNOME's five minutes, so
+// on Wayland a shorter -push-when-idle acts like that delay.
+func idleTime(ctx context.Context, now time.Time) (time.Duration, bool) {
+	if os.Getenv("WAYLAND_DISPLAY") == "" && os.Getenv("DISPLAY") != "" {
+		if out, err := exec.CommandContext(ctx, "xprintidle").Output(); err == nil {
+			if ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64); err == nil {
+				return time.Duration(ms) * time.Millisecond, true
+			}
+		}
+	}
+	session := os.Getenv("XDG_SESSION_ID")
+	if session == "" {
+		return 0, false
+	}
+	out, err := exec.CommandContext(ctx, "loginctl", "show-session", session, "-p", "IdleHint", "-p", "IdleSinceHint").Output()
+	if err != nil {
+		return 0, false
+	}
+	return parseIdleHint(string(out), now)
+}
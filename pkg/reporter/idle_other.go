@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package reporter
+
+import (
+	"context"
+	"time"
+)
+
+// idleTime reports false: there is no way to tell here, so pushes are sent.
+func idleTime(ctx context.Context, now time.Time) (time.Duration, bool) { return 0, false }
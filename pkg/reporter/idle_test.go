@@ -0,0 +1,50 @@
+package reporter
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseHIDIdleTime(t *testing.T) {
+	out := `+-o IOHIDSystem  <class IOHIDSystem, id 0x100000456, registered, matched, active, busy 0 (0 ms), retain 25>
+    {
+      "HIDIdleTimeDelta" = 10000
+      "HIDIdleTime" = 754212458
+      "HIDParameters" = {"HIDClickTime"=500000000}
+    }
+`
+	if got, ok := parseHIDIdleTime(out); !ok || got != 754212458 {
+		t.Errorf("parseHIDIdleTime() = %v, %v; want 754.212458ms", got, ok)
+	}
+	if _, ok := parseHIDIdleTime("+-o IOHIDSystem\n"); ok {
+		t.Error("parseHIDIdleTime() without HIDIdleTime reported ok")
+	}
+}
+
+func TestParseIdleHint(t *testing.T) {
+	now := time.Date(2026, 3, 6, 14, 0, 0, 0, time.UTC)
+	since := now.Add(-12 * time.Minute).UnixMicro()
+	tests := []struct {
+		out  string
+		want time.Duration
+		ok   bool
+	}{
+		{"IdleHint=no\nIdleSinceHint=0\n", 0, true},
+		{"IdleHint=yes\nIdleSinceHint=" + strconv.FormatInt(since, 10) + "\n", 12 * time.Minute, true},
+		{"IdleHint=yes\nIdleSinceHint=0\n", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseIdleHint(tt.out, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseIdleHint(%q) = %v, %v; want %v, %v", tt.out, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUserAwayOverSSH(t *testing.T) {
+	if !userAway(environment{SSH: true}, time.Hour) {
+		t.Error("userAway() over SSH = false, want pushes sent when idleness cannot be told")
+	}
+}
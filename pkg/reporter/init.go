@@ -0,0 +1,61 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/itsrainingmani/reporter/shell"
+)
+
+// runInit implements `reporter init <shell>`, printing shell integration that
+// calls back into this binary with `reporter notify`.
+func runInit(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: reporter init zsh|bash|fish")
+		return 2
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		bin = "reporter"
+	}
+	if err := writeShellInit(os.Stdout, args[0], bin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func writeShellInit(w io.Writer, shellName, bin string) error {
+	switch shellName {
+	case "zsh", "bash":
+		// The hook script returns early when already loaded; wrapping it in
+		// a function keeps those returns valid under eval.
+		fmt.Fprintf(w, "# reporter %s shell integration for %s.\n", Version, shellName)
+		fmt.Fprintf(w, "# Load with: eval \"$(reporter init %s)\"\n", shellName)
+		fmt.Fprintln(w, "_reporter_init() {")
+		fmt.Fprintf(w, ": \"${REPORTER_BIN:=%s}\"\n", shellQuote(bin))
+		fmt.Fprint(w, stripShebang(shell.Posix))
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w, "_reporter_init")
+		fmt.Fprintln(w, "unset -f _reporter_init")
+	case "fish":
+		fmt.Fprintf(w, "# reporter %s shell integration for fish.\n", Version)
+		fmt.Fprintln(w, "# Load with: reporter init fish | source")
+		fmt.Fprintf(w, "set -q REPORTER_BIN; or set -g REPORTER_BIN %s\n", shellQuote(bin))
+		fmt.Fprint(w, shell.Fish)
+	default:
+		return fmt.Errorf("unsupported shell %q (want zsh, bash, or fish)", shellName)
+	}
+	return nil
+}
+
+func stripShebang(script string) string {
+	if strings.HasPrefix(script, "#!") {
+		if i := strings.IndexByte(script, '\n'); i >= 0 {
+			return script[i+1:]
+		}
+	}
+	return script
+}
@@ -0,0 +1,103 @@
+package reporter
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteShellInit(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{
+			shell: "zsh",
+			want:  []string{"_reporter_init() {", ": \"${REPORTER_BIN:=/opt/bin/reporter}\"", "add-zsh-hook preexec _reporter_start", "notify -duration-from \"ms:$dur_ms\"", "histdb_session=$HISTDB_SESSION", hookReportHeader, "zsocket \"$REPORTER_DAEMON_SOCKET\""},
+		},
+		{
+			shell: "bash",
+			want:  []string{"_reporter_init() {", "trap '_reporter_start \"$BASH_COMMAND\"' DEBUG", "unset -f _reporter_init"},
+		},
+		{
+			shell: "fish",
+			want:  []string{"set -q REPORTER_BIN; or set -g REPORTER_BIN /opt/bin/reporter", "--on-event fish_postexec", "notify -duration-from fish:$CMD_DURATION", "atuin_session=$ATUIN_SESSION"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeShellInit(&buf, tt.shell, "/opt/bin/reporter"); err != nil {
+				t.Fatalf("writeShellInit(%q) returned error: %v", tt.shell, err)
+			}
+			out := buf.String()
+			if strings.Contains(out, "#!/usr/bin/env") {
+				t.Errorf("writeShellInit(%q) output contains a shebang", tt.shell)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("writeShellInit(%q) output missing %q", tt.shell, w)
+				}
+			}
+		})
+	}
+
+	if err := writeShellInit(&bytes.Buffer{}, "tcsh", "reporter"); err == nil {
+		t.Error("writeShellInit(\"tcsh\") succeeded, want error")
+	}
+}
+
+// TestZshInitDaemonReport runs `reporter init zsh` in zsh and checks the
+// report its hook hands the daemon, which must survive the hook being
+// wrapped in a function.
+func TestZshInitDaemonReport(t *testing.T) {
+	zsh, err := exec.LookPath("zsh")
+	if err != nil {
+		t.Skip("zsh not installed")
+	}
+	var init bytes.Buffer
+	if err := writeShellInit(&init, "zsh", "true"); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "daemon.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("no Unix sockets: %v", err)
+	}
+	defer ln.Close()
+	reports := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		reports <- data
+	}()
+
+	cmd := exec.Command(zsh, "-f", "-c", init.String()+"\n_reporter_start 'sleep 1'; _reporter_finish")
+	cmd.Env = append(os.Environ(), "REPORTER_DAEMON_SOCKET="+socket)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("zsh: %v\n%s", err, out)
+	}
+	select {
+	case data := <-reports:
+		if dur := strings.Split(string(data), "\n")[1]; strings.Contains(dur, ".") {
+			t.Errorf("hook sent duration %q, want whole milliseconds", dur)
+		}
+		if _, err := parseHookReport(data); err != nil {
+			t.Errorf("parseHookReport() of the hook's report returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hook sent no report")
+	}
+}
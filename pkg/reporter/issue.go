@@ -0,0 +1,24 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -issue names a ticket, such as ABC-123, that the run belongs to. The jira
+// and linear push providers post the run's summary to it as a comment, so a
+// long migration or backfill reports back where the team tracks it. Other
+// providers ignore it.
+
+// checkIssueKey reports whether key looks like a Jira or Linear issue key:
+// a project key of capital letters, digits, and underscores that starts with
+// a letter, a hyphen, and a number.
+func checkIssueKey(key string) error {
+	project, number, ok := strings.Cut(key, "-")
+	if !ok || project == "" || number == "" || project[0] < 'A' || project[0] > 'Z' ||
+		strings.Trim(project, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") != "" ||
+		strings.Trim(number, "0123456789") != "" {
+		return fmt.Errorf("invalid issue %q: want a key such as ABC-123", key)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package reporter
+
+import "testing"
+
+func TestCheckIssueKey(t *testing.T) {
+	tests := []struct {
+		key string
+		ok  bool
+	}{
+		{"ABC-123", true},
+		{"DATA_2-7", true},
+		{"abc-123", false},
+		{"2FA-1", false},
+		{"ABC", false},
+		{"ABC-", false},
+		{"-123", false},
+		{"ABC-12a", false},
+		{"ABC-1-2", false},
+	}
+	for _, tt := range tests {
+		if err := checkIssueKey(tt.key); (err == nil) != tt.ok {
+			t.Errorf("checkIssueKey(%q) = %v, want ok %v", tt.key, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package reporter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// -journal appends a line about each run that passes the threshold to a notes
+// file, such as an Obsidian daily note or an Org-mode journal, so the journal
+// records what long jobs ran and how they went. The path and the line are Go
+// templates over a journalEntry, so runs can land in the note for the day:
+//
+//	journal = "~/notes/daily/{{.Time.Format \"2006-01-02\"}}.md"
+//	journal_format = "- {{.Time.Format \"15:04\"}} {{.Command}}: {{.Status}}"
+
+// Default journal_format lines, by the journal's file extension.
+const (
+	journalMarkdown = "- {{.Time.Format \"15:04\"}} `{{.Command}}` {{.Status}} in {{.Duration}} ({{.Dir}})"
+	journalOrg      = "- {{.Time.Format \"15:04\"}} ={{.Command}}= {{.Status}} in {{.Duration}} ({{.Dir}})"
+)
+
+// journalEntry is what journal templates see about a run.
+type journalEntry struct {
+	Time     time.Time // when the run finished, in local time
+	Command  string    // on one line
+	Dir      string    // with the home directory shortened to ~
+	Host     string
+	Duration string // as notifications show it, such as 12m03s
+	ExitCode int
+	Failed   bool
+	Status   string // "succeeded" or "failed (exit 2)"
+	Labels   map[string]string
+}
+
+type journal struct {
+	path, line *template.Template
+}
+
+// newJournal parses the journal path and line templates; format "" picks a
+// line to suit the file type. It returns nil if path is "".
+func newJournal(path, format string) (*journal, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if format == "" {
+		format = journalMarkdown
+		if strings.HasSuffix(path, ".org") {
+			format = journalOrg
+		}
+	}
+	p, err := template.New("journal").Option("missingkey=error").Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("journal path: %w", err)
+	}
+	l, err := template.New("journal_format").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("journal_format: %w", err)
+	}
+	return &journal{path: p, line: l}, nil
+}
+
+func newJournalEntry(res runResult, dir string, now time.Time) journalEntry {
+	host, _ := os.Hostname()
+	return journalEntry{
+		Time:     now.Local(),
+		Command:  oneLine(res.Command),
+		Dir:      shortenHome(dir),
+		Host:     host,
+		Duration: formatDuration(res.Duration),
+		ExitCode: res.ExitCode,
+		Failed:   res.ExitCode != 0,
+		Status:   resultStatus(res),
+		Labels:   res.Labels,
+	}
+}
+
+// record appends the line for res, which ran in dir and finished at now.
+func (j *journal) record(res runResult, dir string, now time.Time) error {
+	e := newJournalEntry(res, dir, now)
+	var path, line bytes.Buffer
+	if err := j.path.Execute(&path, e); err != nil {
+		return err
+	}
+	if err := j.line.Execute(&line, e); err != nil {
+		return err
+	}
+	return appendJournal(expandHome(path.String()), strings.TrimRight(line.String(), "\n"))
+}
+
+// appendJournal appends line to the notes file at path, starting a new line
+// first if the file does not end with one.
+func appendJournal(path, line string) error {
+	if path == "" {
+		return errors.New("journal path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = "\n" + line
+		}
+	}
+	_, err = f.WriteString(line + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalRecord(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	finished := time.Date(2026, 3, 6, 14, 5, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		path   string
+		format string
+		res    runResult
+		file   string
+		want   string
+	}{
+		{
+			name: "markdown daily note",
+			path: `~/notes/{{.Time.Format "2006-01-02"}}.md`,
+			res:  runResult{Command: "make test", Duration: 12*time.Minute + 3*time.Second},
+			file: "notes/2026-03-06.md",
+			want: "- 14:05 `make test` succeeded in 12m03s (~/src/app)\n",
+		},
+		{
+			name: "org journal",
+			path: `~/org/journal.org`,
+			res:  runResult{Command: "cargo build\n--release", Duration: time.Minute, ExitCode: 101},
+			file: "org/journal.org",
+			want: "- 14:05 =cargo build ⏎ --release= failed (exit 101) in 1m00s (~/src/app)\n",
+		},
+		{
+			name:   "custom format",
+			path:   home + "/log.txt",
+			format: `{{.Time.Format "15:04"}} {{if .Failed}}FAIL{{else}}ok{{end}} {{.Command}} {{.Labels.env}}`,
+			res:    runResult{Command: "deploy", ExitCode: 1, Labels: map[string]string{"env": "prod"}},
+			file:   "log.txt",
+			want:   "14:05 FAIL deploy prod\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := newJournal(tt.path, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := j.record(tt.res, filepath.Join(home, "src", "app"), finished); err != nil {
+				t.Fatalf("record() returned error: %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(home, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("journal = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "today.md")
+	writeFile(t, path, "# Friday\n\nNotes without a final newline")
+	for _, line := range []string{"- first", "- second"} {
+		if err := appendJournal(path, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, _ := os.ReadFile(path)
+	if want := "# Friday\n\nNotes without a final newline\n- first\n- second\n"; string(got) != want {
+		t.Errorf("journal = %q, want %q", got, want)
+	}
+}
+
+func TestNewJournalErrors(t *testing.T) {
+	if j, err := newJournal("", "{{"); j != nil || err != nil {
+		t.Errorf("newJournal(\"\") = %v, %v; want no journal", j, err)
+	}
+	for _, tt := range []struct{ path, format string }{
+		{path: "~/notes/{{.Time.Format}.md"},
+		{path: "~/notes.md", format: "{{if}}"},
+	} {
+		if _, err := newJournal(tt.path, tt.format); err == nil {
+			t.Errorf("newJournal(%q, %q) accepted an invalid template", tt.path, tt.format)
+		}
+	}
+	j, err := newJournal("~/notes.md", "{{.Nope}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	if err := j.record(runResult{Command: "make"}, "/", time.Now()); err == nil {
+		t.Error("record() with an unknown field returned no error")
+	}
+}
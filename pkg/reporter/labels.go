@@ -0,0 +1,69 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels are arbitrary key/value pairs attached to a run, such as
+// project=atlas or env=prod. They are recorded in the history and sent with
+// pushes so runs can be filtered downstream.
+
+// labelFlag collects repeated -label k=v flags. Later values for a key win.
+type labelFlag map[string]string
+
+func (l labelFlag) String() string { return formatLabels(l) }
+
+func (l labelFlag) Set(s string) error {
+	k, v, err := parseLabel(s)
+	if err != nil {
+		return err
+	}
+	l[k] = v
+	return nil
+}
+
+// parseLabel splits "key=value". Keys may contain letters, digits, and
+// . _ - so they survive headers, query strings, and shell hooks unquoted.
+func parseLabel(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("label %q: want key=value", s)
+	}
+	if err := checkLabelKey(key); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func checkLabelKey(key string) error {
+	if key == "" || strings.Trim(key, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._-") != "" {
+		return fmt.Errorf("invalid label key %q: use letters, digits, '.', '_', or '-'", key)
+	}
+	return nil
+}
+
+// formatLabels renders labels as "k=v,k2=v2" sorted by key.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// hasLabels reports whether labels contains every pair in want.
+func hasLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
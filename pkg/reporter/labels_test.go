@@ -0,0 +1,63 @@
+package reporter
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestLabelFlag(t *testing.T) {
+	labels := labelFlag{}
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Var(labels, "label", "")
+	if err := fset.Parse([]string{"-label", "project=atlas", "-label", "env=prod", "-label", "note=a=b", "-label", "env=staging"}); err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if got, want := labels.String(), "env=staging,note=a=b,project=atlas"; got != want {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+}
+
+func TestParseLabel(t *testing.T) {
+	tests := []struct {
+		input   string
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{input: "project=atlas", key: "project", value: "atlas"},
+		{input: "team.name=core-infra", key: "team.name", value: "core-infra"},
+		{input: "empty=", key: "empty", value: ""},
+		{input: "novalue", wantErr: true},
+		{input: "=x", wantErr: true},
+		{input: "bad key=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		key, value, err := parseLabel(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseLabel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if key != tt.key || value != tt.value {
+			t.Errorf("parseLabel(%q) = %q, %q; want %q, %q", tt.input, key, value, tt.key, tt.value)
+		}
+	}
+}
+
+func TestHasLabels(t *testing.T) {
+	labels := map[string]string{"project": "atlas", "env": "prod"}
+	tests := []struct {
+		want  map[string]string
+		match bool
+	}{
+		{want: nil, match: true},
+		{want: map[string]string{"project": "atlas"}, match: true},
+		{want: map[string]string{"project": "atlas", "env": "prod"}, match: true},
+		{want: map[string]string{"project": "zeus"}, match: false},
+		{want: map[string]string{"owner": ""}, match: false},
+	}
+	for _, tt := range tests {
+		if got := hasLabels(labels, tt.want); got != tt.match {
+			t.Errorf("hasLabels(%v) = %v, want %v", tt.want, got, tt.match)
+		}
+	}
+}
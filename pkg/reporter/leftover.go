@@ -0,0 +1,73 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// A command that forks and exits at once, as a service that daemonizes does,
+// looks like it "succeeded in 80ms" while its work has only begun. When the
+// command exits within daemonizeWindow, reporter looks for processes it left
+// running and says so, on stderr and in the notification, rather than time
+// the launch as if it were the job. With -wait-descendants, reporter instead
+// waits for everything the command started, whenever it exits, and times
+// the run until the last of them is gone.
+
+// daemonizeWindow is how soon after starting a command must exit for the
+// processes it leaves behind to suggest it daemonized. Longer jobs that
+// leave a build server running, as Gradle does, are not worth a warning.
+const daemonizeWindow = time.Second
+
+// descendantPoll is how often -wait-descendants looks for processes the
+// command left running.
+const descendantPoll = 250 * time.Millisecond
+
+// waitDescendants waits until the processes the command left running in g
+// have exited, or reporter was interrupted, as interrupted reports.
+func waitDescendants(g *processGroup, interrupted *atomic.Bool) {
+	for !interrupted.Load() && len(g.leftovers()) > 0 {
+		time.Sleep(descendantPoll)
+	}
+}
+
+// leftoverProcess is a process the command started that outlived it. pid
+// is 0 if only name, a description, is known.
+type leftoverProcess struct {
+	pid  int
+	name string
+}
+
+func (p leftoverProcess) String() string {
+	switch {
+	case p.pid == 0:
+		return p.name
+	case p.name == "":
+		return fmt.Sprint(p.pid)
+	}
+	return fmt.Sprintf("%d %s", p.pid, p.name)
+}
+
+// processCount returns "1 process" or "n processes".
+func processCount(n int) string {
+	if n == 1 {
+		return "1 process"
+	}
+	return fmt.Sprintf("%d processes", n)
+}
+
+// leftoverWarning describes the processes command left running after
+// exiting in duration, naming the first few.
+func leftoverWarning(command string, duration time.Duration, left []leftoverProcess) string {
+	names := make([]string, 0, 3)
+	for i, p := range left {
+		if i == 3 {
+			names = append(names, "...")
+			break
+		}
+		names = append(names, p.String())
+	}
+	return fmt.Sprintf("%s exited after %s but left %s running (%s); the notification times the command, not what it started in the background (use -wait-descendants to wait for it)",
+		oneLine(command), formatDuration(duration), processCount(len(left)), strings.Join(names, ", "))
+}
@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// prSetChildSubreaper is prctl's PR_SET_CHILD_SUBREAPER.
+const prSetChildSubreaper = 36
+
+// becomeSubreaper has the command's orphaned descendants reparented to
+// reporter instead of init, so one that daemonized, even into a session of
+// its own, can still be found, until undo is called.
+func becomeSubreaper() (undo func()) {
+	_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0)
+	return func() { _, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 0, 0) }
+}
+
+// leftovers returns the processes still running in the command's group, or
+// orphaned and so reparented to reporter, after the command exited.
+// Reporter's own children, such as notify-send, stay in reporter's group
+// and are not counted. Adopted orphans that have exited are reaped, as
+// nobody else will.
+func (g *processGroup) leftovers() []leftoverProcess {
+	self, own := os.Getpid(), syscall.Getpgrp()
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var left []leftoverProcess
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", e.Name(), "stat"))
+		if err != nil {
+			continue // it exited meanwhile
+		}
+		name, state, ppid, pgrp, ok := parseProcStat(data)
+		if !ok || pgrp != g.pid && (ppid != self || pgrp == own) {
+			continue
+		}
+		if state == 'Z' {
+			if ppid == self && pid != g.pid {
+				var ws syscall.WaitStatus
+				_, _ = syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+			}
+			continue
+		}
+		left = append(left, leftoverProcess{pid: pid, name: name})
+	}
+	return left
+}
+
+// parseProcStat parses the name, state, parent and process group from the
+// contents of /proc/<pid>/stat. The name is in parentheses and may hold
+// spaces and parentheses itself, so the fields after it are found from the
+// last ')'.
+func parseProcStat(data []byte) (name string, state byte, ppid, pgrp int, ok bool) {
+	open, end := bytes.IndexByte(data, '('), bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return "", 0, 0, 0, false
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 3 || len(fields[0]) != 1 {
+		return "", 0, 0, 0, false
+	}
+	ppid, err1 := strconv.Atoi(string(fields[1]))
+	pgrp, err2 := strconv.Atoi(string(fields[2]))
+	if err1 != nil || err2 != nil {
+		return "", 0, 0, 0, false
+	}
+	return string(data[open+1 : end]), fields[0][0], ppid, pgrp, true
+}
@@ -0,0 +1,104 @@
+package reporter
+
+import (
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		stat      string
+		name      string
+		state     byte
+		ppid, grp int
+		ok        bool
+	}{
+		{stat: "4242 (nginx) S 1 4242 4242 0 -1", name: "nginx", state: 'S', ppid: 1, grp: 4242, ok: true},
+		{stat: "77 (my (odd) name) Z 10 20 20 0", name: "my (odd) name", state: 'Z', ppid: 10, grp: 20, ok: true},
+		{stat: "77 (truncated", ok: false},
+		{stat: "77 (x) S one 2", ok: false},
+	}
+	for _, tt := range tests {
+		name, state, ppid, grp, ok := parseProcStat([]byte(tt.stat))
+		if ok != tt.ok || ok && (name != tt.name || state != tt.state || ppid != tt.ppid || grp != tt.grp) {
+			t.Errorf("parseProcStat(%q) = %q, %c, %d, %d, %v", tt.stat, name, state, ppid, grp, ok)
+		}
+	}
+}
+
+func TestLeftovers(t *testing.T) {
+	defer becomeSubreaper()()
+	cmd := exec.Command("sh", "-c", "sleep 5 & exit 0")
+	group, err := startInProcessGroup(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := group.wait(cmd); err != nil {
+		t.Fatal(err)
+	}
+	left := group.leftovers()
+	// The sleep was reparented to this process; kill and reap it.
+	for _, p := range left {
+		_ = syscall.Kill(p.pid, syscall.SIGKILL)
+		var ws syscall.WaitStatus
+		_, _ = syscall.Wait4(p.pid, &ws, 0, nil)
+	}
+	// It may not have exec'd sleep yet, and so still be named sh.
+	if len(left) != 1 || left[0].name != "sleep" && left[0].name != "sh" {
+		t.Fatalf("leftovers() = %v, want the sleep started in the background", left)
+	}
+
+	cmd = exec.Command("true")
+	if group, err = startInProcessGroup(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := group.wait(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if left := group.leftovers(); len(left) != 0 {
+		t.Errorf("leftovers() after true = %v, want none", left)
+	}
+}
+
+func TestWaitDescendants(t *testing.T) {
+	defer becomeSubreaper()()
+	// One sleep stays in the command's group; the other daemonizes into a
+	// session of its own, where only adopting it finds it.
+	cmd := exec.Command("sh", "-c", "sleep 0.4 & setsid sleep 0.6 & exit 0")
+	group, err := startInProcessGroup(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, _, err := group.wait(cmd); err != nil {
+		t.Fatal(err)
+	}
+	var interrupted atomic.Bool
+	waitDescendants(group, &interrupted)
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("waitDescendants() returned after %v, before the daemonized sleep exited", elapsed)
+	}
+	if left := group.leftovers(); len(left) != 0 {
+		t.Errorf("leftovers() after waitDescendants() = %v, want none", left)
+	}
+
+	cmd = exec.Command("sh", "-c", "sleep 5 & exit 0")
+	if group, err = startInProcessGroup(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := group.wait(cmd); err != nil {
+		t.Fatal(err)
+	}
+	interrupted.Store(true)
+	start = time.Now()
+	waitDescendants(group, &interrupted)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waitDescendants() after an interrupt took %v", elapsed)
+	}
+	interrupted.Store(false)
+	_ = syscall.Kill(-group.pid, syscall.SIGKILL)
+	waitDescendants(group, &interrupted) // reaps the sleep
+}
@@ -0,0 +1,36 @@
+//go:build !linux && !windows
+
+package reporter
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// becomeSubreaper does nothing: only Linux lets reporter adopt the
+// command's orphans, so elsewhere a command that daemonized into a session
+// of its own goes unnoticed.
+func becomeSubreaper() (undo func()) { return func() {} }
+
+// leftovers returns the processes still running in the command's group
+// after the command exited.
+func (g *processGroup) leftovers() []leftoverProcess {
+	if syscall.Kill(-g.pid, 0) != nil {
+		return nil
+	}
+	out, err := exec.Command("pgrep", "-l", "-g", strconv.Itoa(g.pid)).Output()
+	if err != nil {
+		// Something is there, even if pgrep cannot say what.
+		return []leftoverProcess{{name: "in process group " + strconv.Itoa(g.pid)}}
+	}
+	var left []leftoverProcess
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		pid, name, _ := strings.Cut(line, " ")
+		if n, err := strconv.Atoi(pid); err == nil {
+			left = append(left, leftoverProcess{pid: n, name: name})
+		}
+	}
+	return left
+}
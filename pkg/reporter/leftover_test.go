@@ -0,0 +1,46 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeftoverWarning(t *testing.T) {
+	tests := []struct {
+		name string
+		left []leftoverProcess
+		want string
+	}{
+		{
+			name: "one",
+			left: []leftoverProcess{{pid: 4242, name: "nginx"}},
+			want: "nginx -g daemon on; exited after 80ms but left 1 process running (4242 nginx);",
+		},
+		{
+			name: "many",
+			left: []leftoverProcess{{pid: 1, name: "a"}, {pid: 2}, {pid: 3, name: "c"}, {pid: 4, name: "d"}},
+			want: "left 4 processes running (1 a, 2, 3 c, ...);",
+		},
+		{
+			name: "unknown",
+			left: []leftoverProcess{{name: "in process group 77"}},
+			want: "left 1 process running (in process group 77);",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := leftoverWarning("nginx -g daemon on;", 80*time.Millisecond, tt.left)
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("leftoverWarning() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationLeftover(t *testing.T) {
+	n := newNotification(runResult{Command: "nginx", Duration: 80 * time.Millisecond, Leftover: 2}, options{})
+	if want := "succeeded in 80ms, left 2 processes running"; n.Body != want {
+		t.Errorf("Body = %q, want %q", n.Body, want)
+	}
+}
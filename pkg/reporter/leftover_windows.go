@@ -0,0 +1,8 @@
+package reporter
+
+// becomeSubreaper does nothing on Windows.
+func becomeSubreaper() (undo func()) { return func() {} }
+
+// leftovers returns nil: Windows does not track what a process started
+// once it exits.
+func (g *processGroup) leftovers() []leftoverProcess { return nil }
@@ -0,0 +1,1436 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Version is the command's version, set at build time with -ldflags
+// "-X github.com/itsrainingmani/reporter/pkg/reporter.Version=...".
+var Version = "dev"
+
+// Cached notifier availability (computed once).
+var (
+	notifierOnce   sync.Once
+	notifierPath   string
+	notifierExists bool
+)
+
+// Modes sharing the flags for wrapping a command; a bare invocation, with no
+// subcommand, is run mode that also accepts the flags of notify mode.
+const (
+	modeRun    = "run"
+	modeNotify = "notify"
+	modeAttach = "attach"
+	modeWait   = "wait"
+)
+
+// Main runs the reporter command on os.Args, as the reporter binary does,
+// and exits with its status.
+func Main() {
+	mode := ""
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "init":
+			os.Exit(runInit(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "stats":
+			os.Exit(runStats(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "suggest-thresholds":
+			os.Exit(runSuggestThresholds(os.Args[2:]))
+		case "digest":
+			os.Exit(runDigest(os.Args[2:]))
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
+		case "daemon":
+			os.Exit(runDaemon(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "ssh":
+			os.Exit(runSSH(os.Args[2:]))
+		case "flush":
+			os.Exit(runFlush(os.Args[2:]))
+		case "spool":
+			os.Exit(runSpool(os.Args[2:]))
+		case "notifications":
+			os.Exit(runNotifications(os.Args[2:]))
+		case "mute":
+			os.Exit(runMute(os.Args[2:]))
+		case "unmute":
+			os.Exit(runUnmute(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case modeRun, modeNotify, modeAttach, modeWait:
+			// These take the same flags as wrapping a command, plus
+			// their own.
+			mode = os.Args[1]
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+		}
+	}
+
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(2)
+	}
+
+	thresholdStr := flag.String("threshold", cfg.string("threshold", "10s"), "minimum duration before a notification is sent (e.g. 5s, 1m30s), or auto to learn it from each command's history")
+	always := flag.Bool("always", cfg.bool("always", false), "send a notification even if the command completes before the threshold")
+	title := flag.String("title", cfg.string("title", "Task finished"), "title to display in notifications")
+	titleTemplate := flag.String("title-template", cfg.string("title_template", ""), "Go `template` for the notification title, over fields such as {{.Command}}, {{.Host}}, and {{.Branch}}")
+	bodyTemplate := flag.String("body-template", cfg.string("body_template", ""), "Go `template` for the notification body, over fields such as {{.Status}}, {{.Duration}}, {{.ExitCode}}, and {{.Cwd}}")
+	headless := currentEnvironment().headless()
+	silentBell := flag.Bool("no-bell", cfg.bool("no_bell", headless), "do not emit a terminal bell alongside the notification (default true in containers and CI)")
+	quiet := flag.Bool("quiet", cfg.bool("quiet", headless), "do not fall back to printing the notification on stderr (default true in containers and CI)")
+	var shellCmd, commandStr, durationStr, durationFrom string
+	var exitFlag int
+	notifyOnly := mode == modeNotify
+	if mode == "" || mode == modeRun {
+		flag.StringVar(&shellCmd, "c", "", "run this command string through $SHELL -c (e.g. \"make build && make test\")")
+	}
+	if mode == "" || mode == modeNotify || mode == modeAttach {
+		flag.StringVar(&commandStr, "cmd", "", "command string to display in notifications")
+	}
+	if mode == "" || mode == modeNotify {
+		flag.StringVar(&durationStr, "duration", "", "duration of the already-finished command, such as 1m30s, or seconds, such as 90.5")
+		flag.StringVar(&durationFrom, "duration-from", "", "take the duration as a shell reports it, as `source:value`, e.g. zsh:$start for $EPOCHREALTIME saved when the command started, or fish:$CMD_DURATION")
+		flag.IntVar(&exitFlag, "exit", 0, "exit code of the already-finished command")
+	}
+	if mode == "" {
+		flag.BoolVar(&notifyOnly, "notify-only", false, "skip running a command and just send a notification (deprecated: use reporter notify)")
+	}
+	resolvePush := registerPushFlags(flag.CommandLine, cfg, "HTTP endpoint for phone push notifications (e.g. ntfy topic URL)")
+	resolveDesktop := registerDesktopFlag(flag.CommandLine, cfg)
+	pushWhenIdleStr := flag.String("push-when-idle", cfg.string("push_when_idle", "0"), "only push when the desktop's keyboard and mouse have been idle this `long` (e.g. 5m); at the desk, the desktop notification suffices")
+	evenIfFocused := flag.Bool("even-if-focused", cfg.bool("even_if_focused", false), "show the desktop notification even when the terminal that ran the command is the focused window")
+	energy := flag.Bool("energy", cfg.bool("energy", false), "estimate energy consumed during the run (Intel RAPL on Linux, powermetrics on macOS)")
+	withContext := flag.Bool("context", cfg.bool("context", false), "add the user, host, working directory, and git repository and branch to notifications")
+	resources := flag.Bool("resources", cfg.bool("resources", false), "include the command's CPU time and peak memory (max RSS) in the report")
+	notifyOn := flag.String("notify-on", cfg.string("notify_on", notifyOnAlways), "which outcomes trigger a notification: always, failure, or success")
+	onlyFailures := flag.Bool("only-failures", cfg.bool("only_failures", false), "only notify about failed runs; shorthand for -notify-on failure")
+	ignoreInterrupts := flag.Bool("ignore-interrupts", cfg.bool("ignore_interrupts", false), "do not notify about commands stopped with Ctrl-C (killed by SIGINT or exiting 130)")
+	exitCodesStr := flag.String("exit-codes", cfg.string("exit_codes", ""), "only notify for these exit `codes`, a list of codes and ranges such as \"1,2,100-125\"")
+	successEveryStr := flag.String("success-every", cfg.string("success_every", "0"), "notify about successes of the same job at most once per `period` (e.g. 24h); failures always notify")
+	progressStr := flag.String("progress", cfg.string("progress", "0"), "while the command runs, update one \"running\" notification every `interval` (e.g. 5m) on backends that can update it in place")
+	heartbeatStr := flag.String("heartbeat", cfg.string("heartbeat", "0"), "while the command runs, send a \"still running\" notification every `interval` (e.g. 30m) to every backend")
+	stallStr := cfg.string("stall", "0")
+	if mode == "" || mode == modeRun {
+		flag.StringVar(&stallStr, "stall", stallStr, "with -heartbeat, only send heartbeats once the command has printed nothing for this `long` (e.g. 10m)")
+	}
+	maxDurationStr := flag.String("max-duration", cfg.string("max_duration", "0"), "notify when the command is still running after this `long` (e.g. 2h); with -kill-after, stop it instead")
+	killAfterStr := flag.String("kill-after", cfg.string("kill_after", "0"), "with -max-duration, send the command SIGTERM at the limit and SIGKILL this `long` later (e.g. 30s), and report it as timed out")
+	retries := flag.Int("retries", cfg.int("retries", 0), "re-run a failed command up to `N` times, notifying about each failure")
+	retryDelayStr := flag.String("retry-delay", cfg.string("retry_delay", defaultRetryDelay.String()), "with -retries, wait this `long` before the first retry, doubling it before each one after")
+	waitDescendants, pipefail := cfg.bool("wait_descendants", false), cfg.bool("pipefail", false)
+	if mode == "" || mode == modeRun {
+		flag.BoolVar(&pipefail, "pipefail", pipefail, "with -c, run the command string with set -o pipefail, so a failed stage of a pipeline fails it, and name that stage in notifications")
+		flag.BoolVar(&waitDescendants, "wait-descendants", waitDescendants, "after the command exits, wait for the processes it started, even ones that daemonized, and time the run until they exit too")
+	}
+	usePTY := flag.Bool("pty", cfg.bool("pty", false), "run the command on a pseudo-terminal so it keeps colors and progress output even when reporter's output is piped")
+	captureOutput := flag.Int("capture-output", cfg.int("capture_output", 0), "include the last N lines of output (stderr first) in notifications for failed runs")
+	telemetry := flag.Bool("telemetry", cfg.bool("telemetry", false), "record how long each notification backend takes, locally, for reporter doctor")
+	deliverySummary := flag.Bool("delivery-summary", cfg.bool("delivery_summary", false), "when a notification backend fails, print one line summarizing every backend instead of separate errors")
+	reportJSON := flag.String("report-json", "", "write a JSON report of the run and each notification delivery to this `file` (- for stderr)")
+	dedupKey := flag.String("dedup-key", getenvDefault("REPORTER_DEDUP_KEY", ""), "name of the logical job; notifications with the same key replace each other instead of stacking up")
+	issue := flag.String("issue", getenvDefault("REPORTER_ISSUE", ""), "ticket `key` such as ABC-123 for the jira and linear push providers to comment on")
+	healthcheckURL := flag.String("healthcheck-url", getenvDefault("REPORTER_HEALTHCHECK_URL", cfg.string("healthcheck_url", "")), "ping this healthchecks.io check `URL` when the command starts, succeeds, or fails")
+	sentrySlug := flag.String("sentry-monitor", getenvDefault("REPORTER_SENTRY_MONITOR", ""), "check in to this Sentry Cron Monitor `slug` when the command starts and exits, using SENTRY_DSN")
+	labels := labelFlag(cfg.labels())
+	flag.Var(labels, "label", "attach a `key=value` label to the run (repeatable); recorded in history and sent with pushes")
+	journalPath := flag.String("journal", cfg.string("journal", ""), "append a line about each run that passes the threshold to this notes `file`, a template such as ~/notes/{{.Time.Format \"2006-01-02\"}}.md")
+	noRedact := flag.Bool("no-redact", cfg.bool("no_redact", false), "show the command line as is, without scrubbing passwords and tokens from it")
+	noHistory := flag.Bool("no-history", cfg.bool("no_history", false), "do not record this run in the history journal")
+	forcePush := flag.Bool("force-push", false, "send pushes normally even during quiet_hours")
+	force := flag.Bool("force", false, "run the command even if it matches a blocked pattern (unless block_action is \"refuse\")")
+	showVersion := flag.Bool("version", false, "print version and exit")
+	var resolveWait func() ([]waitCondition, time.Duration, time.Duration, error)
+	if mode == modeWait {
+		resolveWait = registerWaitFlags(flag.CommandLine)
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [run] [flags] -- <command> [args...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [run] [flags] -c \"<shell command>\"\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s notify [flags] -duration <duration> -exit <code> -cmd \"<command>\"\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s attach [flags] <pid>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s wait [flags] -tcp host:port | -file path | -http url\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s init zsh|bash|fish\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s daemon [-listen path]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s serve [-listen host:port] [-token token]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s ssh [ssh options] <host> [command]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s config [show|paths|keys]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s doctor [-send]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s test [-push]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s history [flags]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s stats [flags]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s export [flags]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s suggest-thresholds [-write]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s digest -period day|week\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s flush\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s spool flush [flags] <dir>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s notifications [flags]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s mute [duration] | unmute\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+	cfg.enforceLocks(flag.CommandLine)
+	for _, w := range cfg.warnings {
+		fmt.Fprintf(os.Stderr, "[policy] %s\n", w)
+	}
+
+	if *showVersion {
+		fmt.Printf("reporter %s\n", Version)
+		os.Exit(0)
+	}
+
+	threshold, autoThreshold := autoThresholdFloor, *thresholdStr == thresholdAuto
+	if !autoThreshold {
+		if threshold, err = time.ParseDuration(*thresholdStr); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid threshold: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	switch cfg.string("block_action", blockActionForce) {
+	case blockActionForce, blockActionRefuse:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid config: block_action must be %q or %q\n", blockActionForce, blockActionRefuse)
+		os.Exit(2)
+	}
+
+	successEvery, err := time.ParseDuration(*successEveryStr)
+	if err != nil || successEvery < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -success-every %q: want a duration such as 24h\n", *successEveryStr)
+		os.Exit(2)
+	}
+
+	pushWhenIdle, err := time.ParseDuration(*pushWhenIdleStr)
+	if err != nil || pushWhenIdle < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -push-when-idle %q: want a duration such as 5m\n", *pushWhenIdleStr)
+		os.Exit(2)
+	}
+
+	progress, err := time.ParseDuration(*progressStr)
+	if err != nil || progress < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -progress %q: want a duration such as 5m\n", *progressStr)
+		os.Exit(2)
+	}
+
+	heartbeat, err := time.ParseDuration(*heartbeatStr)
+	if err != nil || heartbeat < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -heartbeat %q: want a duration such as 30m\n", *heartbeatStr)
+		os.Exit(2)
+	}
+	stall, err := time.ParseDuration(stallStr)
+	if err != nil || stall < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -stall %q: want a duration such as 10m\n", stallStr)
+		os.Exit(2)
+	}
+
+	maxDuration, err := time.ParseDuration(*maxDurationStr)
+	if err != nil || maxDuration < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -max-duration %q: want a duration such as 2h\n", *maxDurationStr)
+		os.Exit(2)
+	}
+	killAfter, err := time.ParseDuration(*killAfterStr)
+	if err != nil || killAfter < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -kill-after %q: want a duration such as 30s\n", *killAfterStr)
+		os.Exit(2)
+	}
+	if killAfter > 0 && maxDuration == 0 {
+		fmt.Fprintln(os.Stderr, "-kill-after needs -max-duration")
+		os.Exit(2)
+	}
+	if *retries < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -retries %d: want 0 or more\n", *retries)
+		os.Exit(2)
+	}
+	retryDelay, err := time.ParseDuration(*retryDelayStr)
+	if err != nil || retryDelay < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -retry-delay %q: want a duration such as 30s\n", *retryDelayStr)
+		os.Exit(2)
+	}
+
+	if *onlyFailures {
+		if *notifyOn == notifyOnSuccess {
+			fmt.Fprintln(os.Stderr, "-only-failures cannot be combined with -notify-on success")
+			os.Exit(2)
+		}
+		*notifyOn = notifyOnFailure
+	}
+	notes, err := newJournal(*journalPath, cfg.string("journal_format", ""))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -journal: %v\n", err)
+		os.Exit(2)
+	}
+	templates, err := newMessageTemplates(*titleTemplate, *bodyTemplate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid %v\n", err)
+		os.Exit(2)
+	}
+	if *issue != "" {
+		if err := checkIssueKey(*issue); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -issue: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	sentry, err := newSentryMonitor(cfg.envString("sentry_dsn", "", "SENTRY_DSN"), *sentrySlug,
+		cfg.envString("sentry_environment", "", "SENTRY_ENVIRONMENT"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -sentry-monitor: %v\n", err)
+		os.Exit(2)
+	}
+	healthcheck, err := newHealthcheck(*healthcheckURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -healthcheck-url: %v\n", err)
+		os.Exit(2)
+	}
+	statsd, err := newStatsdSink(cfg.envString("statsd", "", "REPORTER_STATSD"), cfg.string("statsd_prefix", "reporter"), cfg.bool("statsd_tags", true))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	prometheus, err := newPromSink(cfg.envString("prometheus_pushgateway", "", "REPORTER_PUSHGATEWAY"), cfg.string("prometheus_job", "reporter"),
+		expandHome(cfg.envString("prometheus_textfile", "", "REPORTER_PROMETHEUS_TEXTFILE")))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	exitCodes, err := parseExitCodes(*exitCodesStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -exit-codes: %v\n", err)
+		os.Exit(2)
+	}
+
+	switch *notifyOn {
+	case notifyOnAlways, notifyOnFailure, notifyOnSuccess:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -notify-on %q: want always, failure, or success\n", *notifyOn)
+		os.Exit(2)
+	}
+
+	if prefix := cfg.string("title_prefix", ""); prefix != "" {
+		*title = prefix + " " + *title
+	}
+
+	opts := options{
+		threshold:        threshold,
+		always:           *always,
+		notifyOn:         *notifyOn,
+		title:            *title,
+		bell:             !*silentBell,
+		quiet:            *quiet,
+		energy:           *energy,
+		resources:        *resources,
+		context:          *withContext,
+		telemetry:        *telemetry,
+		summary:          *deliverySummary,
+		reportJSON:       *reportJSON,
+		pty:              *usePTY,
+		waitDescendants:  waitDescendants,
+		captureOutput:    *captureOutput,
+		dedupKey:         *dedupKey,
+		issue:            *issue,
+		labels:           labels,
+		successEvery:     successEvery,
+		exitCodes:        exitCodes,
+		ignoreInterrupts: *ignoreInterrupts,
+		tiers:            configTiers(cfg),
+		progress:         progress,
+		heartbeat:        heartbeat,
+		stall:            stall,
+		maxDuration:      maxDuration,
+		killAfter:        killAfter,
+		retries:          *retries,
+		retryDelay:       retryDelay,
+		journal:          notes,
+		templates:        templates,
+		evenIfFocused:    *evenIfFocused,
+		pushWhenIdle:     pushWhenIdle,
+		sentry:           sentry,
+		healthcheck:      healthcheck,
+		statsd:           statsd,
+		prometheus:       prometheus,
+	}
+	if !*forcePush {
+		opts.quietHours = configQuietHours(cfg)
+	}
+	if !*noRedact {
+		opts.redact = newRedactor(cfg.strings("redact"))
+	}
+	if cfg.bool("notification_log", true) {
+		opts.notificationLog = notificationLogPath()
+	}
+	opts.snoozed, _ = mutedUntil(mutePath(), time.Now())
+
+	if opts.push, err = resolvePush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if opts.desktop, err = resolveDesktop(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	opts.agent = hostAgent(cfg)
+	opts.terminalNotify = terminalNotifier(cfg, currentEnvironment(), opts.agent)
+	if mode := cfg.string("multiplexer", multiplexerAuto); mode != multiplexerNever {
+		opts.multiplexer = detectMultiplexer(os.Getenv)
+		opts.multiplexerPopup = mode == multiplexerPopup
+	}
+	opts.commandThresholds = configCommandThresholds(cfg)
+
+	if !*noHistory || autoThreshold {
+		store, err := openHistory(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			os.Exit(2)
+		}
+		if !*noHistory {
+			opts.history = store
+		}
+		if autoThreshold {
+			opts.pastRuns = store
+		}
+	}
+
+	if mode == modeAttach {
+		pid, err := parsePID(flag.Args())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(attachMode(pid, commandStr, opts))
+	}
+
+	if mode == modeWait {
+		conds, timeout, interval, err := resolveWait()
+		if err == nil && flag.NArg() > 0 {
+			err = fmt.Errorf("unexpected arguments: %s", strings.Join(flag.Args(), " "))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(waitMode(conds, timeout, interval, opts))
+	}
+
+	if notifyOnly {
+		var duration time.Duration
+		switch {
+		case durationStr != "" && durationFrom != "":
+			err = errors.New("-duration and -duration-from cannot be combined")
+		case durationFrom != "":
+			duration, err = parseDurationFrom(durationFrom, time.Now())
+		case durationStr != "":
+			duration, err = parseReportedDuration(durationStr)
+		default:
+			err = errors.New("-duration or -duration-from is required to notify about a finished command")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		cmdText := commandStr
+		if cmdText == "" {
+			cmdText = strings.Join(flag.Args(), " ")
+		}
+		if opts.muted, err = hookMuted(cmdText, cfg.strings("notify_allow"), notifyDenyPatterns(cfg)); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			os.Exit(2)
+		}
+		exitCode := notifyOnlyMode(opts.redact.command(cmdText), duration, exitFlag, opts)
+		os.Exit(exitCode)
+	}
+
+	args, display, shown := flag.Args(), strings.Join(flag.Args(), " "), strings.Join(opts.redact.args(flag.Args()), " ")
+	if shellCmd != "" {
+		if flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "-c cannot be combined with a command after --")
+			os.Exit(2)
+		}
+		args, display, shown = shellArgs(shellCmd), shellCmd, opts.redact.command(shellCmd)
+		if pipefail {
+			var ok bool
+			if args, ok = pipefailArgs(args); !ok {
+				if !opts.quiet {
+					fmt.Fprintf(os.Stderr, "[pipefail] %s has no pipefail; running the command as it is\n", args[0])
+				}
+			} else if f, err := os.CreateTemp("", "reporter-pipestatus"); err == nil {
+				f.Close()
+				os.Setenv(pipeStatusEnv, f.Name())
+				opts.pipeStatus, opts.pipeline = f.Name(), pipelineStages(shown)
+			}
+		}
+	}
+
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pattern, err := matchingPattern(display, blockPatterns(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(2)
+	}
+	if pattern != "" {
+		refuse := cfg.string("block_action", blockActionForce) == blockActionRefuse
+		if refuse || !*force {
+			hint := "; rerun with -force to run it anyway"
+			if refuse {
+				hint = " and block_action is \"refuse\""
+			}
+			fmt.Fprintf(os.Stderr, "[policy] refusing to run %q: matches blocked pattern `%s`%s\n", display, pattern, hint)
+			os.Exit(126)
+		}
+		fmt.Fprintf(os.Stderr, "[policy] %q matches blocked pattern `%s`; running because of -force\n", display, pattern)
+	}
+
+	exitCode := runWithNotification(args, shown, opts)
+	if opts.pipeStatus != "" {
+		os.Remove(opts.pipeStatus)
+	}
+	os.Exit(exitCode)
+}
+
+// options holds the resolved settings shared by run and notify-only modes.
+type options struct {
+	threshold time.Duration
+	always    bool
+	notifyOn  string
+	title     string
+	bell      bool
+	desktop   bool   // attempt desktop notifications (see -desktop)
+	agent     string // host agent to show them through (see agent.go)
+	quiet     bool   // no stderr fallback or delivery errors on stderr
+	push      []pushTarget
+	energy    bool
+	resources bool // report CPU time and peak memory (see resources.go)
+	// context adds where the command ran to notifications (see context.go).
+	context   bool
+	telemetry bool
+	// summary replaces per-backend error lines with one line covering
+	// every backend when any of them fails.
+	summary    bool
+	reportJSON string // -report-json destination, "" for none
+	pty        bool
+	// waitDescendants times the run until the processes the command left
+	// running exit too (see leftover.go).
+	waitDescendants bool
+	// pipeStatus, with -pipefail in bash, is the file the shell writes its
+	// last pipeline's statuses to, and pipeline the stages of the command
+	// string, if it is one pipeline (see pipefail.go).
+	pipeStatus string
+	pipeline   []string
+	// captureOutput is how many trailing output lines to attach to failure
+	// notifications; zero disables capturing.
+	captureOutput int
+	history       historyStore // nil when history is off
+	dedupKey      string
+	issue         string // -issue ticket key; see issue.go
+	labels        map[string]string
+	// successEvery limits success notifications to one per job and period;
+	// zero notifies about every success.
+	successEvery time.Duration
+	exitCodes    exitCodeSet // nil allows every exit code
+	// ignoreInterrupts suppresses notifications for runs stopped with
+	// Ctrl-C (see interruptedByUser).
+	ignoreInterrupts bool
+	// pastRuns is read for -threshold auto, which then replaces
+	// threshold for each run; nil otherwise.
+	pastRuns historyStore
+	// commandThresholds replace threshold for the commands they match
+	// (see threshold.go).
+	commandThresholds []commandThreshold
+	// muted silences notifications for a run the shell hook reported that
+	// matches notify_deny (see hookMuted); it is still recorded.
+	muted bool
+	tiers []tier // route runs by duration; see tiers.go
+	// progress is how often to update the running notification; zero
+	// sends none (see progress.go).
+	progress time.Duration
+	// progressKey is the dedup key of the running notification, which the
+	// notification for the finished run replaces; "" without -progress.
+	progressKey string
+	// heartbeat is how often to send a "still running" notification; zero
+	// sends none (see progress.go).
+	heartbeat time.Duration
+	// stall, if set, holds heartbeats back until the command has printed
+	// nothing for that long, as tracked by activity, which is set for
+	// wrapped commands only.
+	stall    time.Duration
+	activity *outputActivity
+	// hold, if set, is handed the notification about a run instead of it
+	// being sent, as the daemon does to batch them (see daemon.go).
+	hold func(res runResult, opts options)
+	// noState keeps no failure streaks and spools no pushes, for a Runner
+	// that has not asked for them (see Runner.KeepState).
+	noState bool
+	// maxDuration is the command's time limit, and killAfter how long
+	// after SIGTERM at the limit to send SIGKILL; zero killAfter only
+	// notifies (see watchdog.go).
+	maxDuration time.Duration
+	killAfter   time.Duration
+	// retries is how many times to re-run a failed command, waiting
+	// retryDelay before the first retry and twice as long before each
+	// one after (see retry.go).
+	retries    int
+	retryDelay time.Duration
+	// quietHours quiets pushes at night and on weekends; nil when there
+	// are none or with -force-push (see quiethours.go).
+	quietHours *quietHours
+	// pushQuietly asks push providers to deliver without sound, as
+	// quietPush sets during quiet hours.
+	pushQuietly bool
+	journal     *journal // nil without -journal; see journal.go
+	// templates replace the title and body of notifications; nil without
+	// -title-template or -body-template (see templates.go).
+	templates *messageTemplates
+	// redact scrubs secrets from the command line shown; nil with
+	// -no-redact (see redact.go).
+	redact *redactor
+	// notificationLog is where sent notifications are logged, "" for
+	// nowhere (see notifications.go).
+	notificationLog string
+	// snoozed silences every notification while `reporter mute` is in
+	// effect; runs are still recorded (see mute.go).
+	snoozed bool
+	// evenIfFocused shows desktop notifications while the terminal is
+	// focused; otherwise focused is set and only the bell rings.
+	evenIfFocused bool
+	focused       bool
+	// pushWhenIdle holds back pushes unless the user has been idle this
+	// long while the desktop notification reaches them; zero always pushes
+	// (see idle.go).
+	pushWhenIdle time.Duration
+	// terminalNotify is the escape sequence protocol that shows desktop
+	// notifications through the terminal, as over SSH; "" uses the
+	// desktop notifier (see osc.go).
+	terminalNotify string
+	sentry         *sentryMonitor // -sentry-monitor; nil without it (see sentry.go)
+	healthcheck    *healthcheck   // -healthcheck-url; nil without it (see healthcheck.go)
+	// multiplexer is the tmux or screen to also show notifications in, ""
+	// for none, and multiplexerPopup shows them as a popup (see
+	// multiplexer.go).
+	multiplexer      string
+	multiplexerPopup bool
+	statsd           *statsdSink // nil without statsd; see statsd.go
+	prometheus       *promSink   // nil without a Pushgateway or textfile; see prometheus.go
+	// notifiers are handed every notification too, with notifyContext,
+	// when a Runner reports (see runner.go).
+	notifiers     []Notifier
+	notifyContext context.Context
+}
+
+// runResult describes a finished command.
+type runResult struct {
+	Command string
+	// Args is the argv that was run, or nil when reporting a command run
+	// elsewhere (notify-only mode).
+	Args     []string
+	Duration time.Duration
+	ExitCode int
+	// Energy is the estimated energy consumed during the run in joules, or
+	// zero if it was not measured.
+	Energy float64
+	// Output holds the last lines the command printed, if captured.
+	Output []string
+	Labels map[string]string
+	// Recovered is how many failed runs in a row this successful one
+	// follows (see streak.go), or 0.
+	Recovered int
+	// TimedOut marks a command stopped at its -max-duration limit.
+	TimedOut bool
+	// ExitUnknown marks a run whose exit status could not be learned, as
+	// with reporter attach; ExitCode is then 0.
+	ExitUnknown bool
+	// Attempts is how many times the command has run, with -retries;
+	// otherwise 0. Duration then spans every attempt and the delays
+	// between them.
+	Attempts int
+	// RetryIn, for a failed attempt that will be retried, is how long
+	// until the next one. Such an attempt is notified but not recorded.
+	RetryIn time.Duration
+	// Signal is the signal that killed the command, if one did; ExitCode
+	// is then 128 plus its number, as a shell shows it.
+	Signal syscall.Signal
+	// FailedStage says which stage of a pipeline failed, with -pipefail
+	// (see pipefail.go).
+	FailedStage string
+	// Leftover is how many processes the command left running when it
+	// exited within daemonizeWindow, as one that daemonizes does (see
+	// leftover.go).
+	Leftover int
+	// Resources is what the command used, summed over every attempt;
+	// zero when reporter did not run it.
+	Resources resourceUsage
+}
+
+// shellArgs returns the argv that runs script through the user's shell.
+func shellArgs(script string) []string {
+	return []string{getenvDefault("SHELL", "/bin/sh"), "-c", script}
+}
+
+// runWithNotification runs args and reports the outcome, showing display as
+// the command in notifications. With -retries, a failed command is run
+// again (see retry.go).
+func runWithNotification(args []string, display string, opts options) int {
+	var meter energyMeter
+	if opts.energy {
+		var err error
+		if meter, err = startEnergyMeter(); err != nil {
+			fmt.Fprintf(os.Stderr, "[energy] %v\n", err)
+		}
+	}
+
+	for _, t := range opts.push {
+		prewarmPush(t.URL)
+	}
+
+	defer becomeSubreaper()()
+	finishCheckIn := opts.sentry.start(opts.quiet)
+	finishHealthcheck := opts.healthcheck.start(opts.quiet)
+	start := time.Now()
+
+	// Without a dedup key, the running notification is keyed by the job.
+	key := opts.dedupKey
+	if key == "" {
+		dir, _ := os.Getwd()
+		key = fingerprint(runResult{Command: display}, "", dir)
+	}
+	if opts.stall > 0 && opts.heartbeat > 0 {
+		opts.activity = newOutputActivity(start)
+	}
+	stopProgress := startProgress(display, key, start, opts)
+	stopHeartbeat := startHeartbeat(display, start, opts)
+
+	var res runResult
+	var used resourceUsage
+	delay := opts.retryDelay
+	for attempt := 1; ; attempt++ {
+		var interrupted, ok bool
+		res, interrupted, ok = runAttempt(args, display, opts)
+		used = used.add(res.Resources)
+		res.Resources = used
+		if !ok {
+			stopProgress()
+			stopHeartbeat()
+			finishCheckIn(1)
+			finishHealthcheck(runResult{Command: display, Duration: time.Since(start), ExitCode: 1})
+			return 1
+		}
+		if opts.retries > 0 {
+			res.Attempts = attempt
+		}
+		if res.ExitCode == 0 || interrupted || attempt > opts.retries {
+			break
+		}
+		retry := res
+		retry.RetryIn = delay
+		if !report(retry, opts) && !opts.quiet {
+			fmt.Fprintf(os.Stderr, "[retry] %s; retrying in %s (attempt %d of %d)\n", resultStatus(res), formatDuration(delay), attempt+1, opts.retries+1)
+		}
+		if !sleepUnlessSignaled(delay) {
+			break
+		}
+		delay *= 2
+	}
+	stopProgress()
+	stopHeartbeat()
+	if opts.progress > 0 {
+		opts.progressKey = key
+	}
+	if opts.retries > 0 {
+		res.Duration = time.Since(start)
+	}
+
+	if meter != nil {
+		var err error
+		if res.Energy, err = meter.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "[energy] %v\n", err)
+		}
+	}
+	finishCheckIn(res.ExitCode)
+	finishHealthcheck(res)
+	report(res, opts)
+
+	return res.ExitCode
+}
+
+// runAttempt runs args once and returns how it went, and whether reporter
+// was sent a signal while it ran, as when the user presses Ctrl-C. It
+// returns false if the command could not be run, having said why.
+func runAttempt(args []string, display string, opts options) (res runResult, interrupted, ok bool) {
+	if opts.pipeStatus != "" {
+		_ = os.Truncate(opts.pipeStatus, 0) // left by an earlier attempt
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	// Capturing or watching for -stall puts pipes between the command and
+	// the terminal; with -pty the combined terminal output is copied
+	// instead.
+	var stdoutTail, stderrTail *lineTail
+	var ptyOut io.Writer = os.Stdout
+	if opts.captureOutput > 0 {
+		stdoutTail, stderrTail = newLineTail(opts.captureOutput), newLineTail(opts.captureOutput)
+		cmd.Stdout = io.MultiWriter(os.Stdout, stdoutTail)
+		cmd.Stderr = io.MultiWriter(os.Stderr, stderrTail)
+		ptyOut = io.MultiWriter(os.Stdout, stdoutTail)
+	}
+	if opts.activity != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, opts.activity)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, opts.activity)
+		ptyOut = io.MultiWriter(ptyOut, opts.activity)
+	}
+
+	res, interrupted, err := execute(cmd, display, ptyOut, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return runResult{}, false, false
+	}
+	res.Args = opts.redact.args(args)
+	if opts.pipeStatus != "" {
+		res.FailedStage = readFailedStage(opts.pipeStatus, res.ExitCode, opts.pipeline)
+	}
+	if opts.captureOutput > 0 {
+		res.Output = tailOutput(stdoutTail.Lines(), stderrTail.Lines(), opts.captureOutput)
+	}
+	return res, interrupted, true
+}
+
+// execute starts cmd in a process group of its own, or with opts.pty on a
+// pseudo-terminal whose output it copies to ptyOut, and waits for it, and
+// with opts.waitDescendants for what it leaves running. Meanwhile the
+// signals reporter gets go to the command's group. It returns how the run
+// went, with the command shown as display, and whether reporter was sent
+// a signal while it ran; the error says why the command could not be run.
+func execute(cmd *exec.Cmd, display string, ptyOut io.Writer, opts options) (res runResult, interrupted bool, err error) {
+	start := time.Now()
+
+	// Signals sent to reporter are passed on to the command's whole
+	// process group (see processgroup_unix.go).
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, forwardedSignals...)
+	defer signal.Stop(sigChan)
+
+	var pty *ptySession
+	var group *processGroup
+	if opts.pty {
+		if pty, err = startPTY(cmd, os.Stdin, ptyOut); err != nil {
+			return runResult{}, false, fmt.Errorf("failed to start command on a pseudo-terminal: %w", err)
+		}
+		group = ptyProcessGroup(cmd, pty)
+	} else if group, err = startInProcessGroup(cmd); err != nil {
+		return runResult{}, false, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	var signaled atomic.Bool
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for sig := range sigChan {
+			switch sig {
+			case syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP:
+				signaled.Store(true)
+			}
+			group.signal(sig)
+		}
+	}()
+
+	watchdog := startWatchdog(cmd.Process, display, start, opts)
+	exitCode, killedBy, err := group.wait(cmd)
+	timedOut := watchdog.stop()
+	if err == nil && opts.waitDescendants {
+		waitDescendants(group, &signaled)
+	}
+	duration := time.Since(start)
+	signal.Stop(sigChan)
+	close(sigChan)
+	<-forwarded
+	if pty != nil {
+		pty.finish()
+	}
+	if err != nil {
+		return runResult{}, false, fmt.Errorf("failed to run command: %w", err)
+	}
+	var left []leftoverProcess
+	if duration < daemonizeWindow && !opts.waitDescendants {
+		left = group.leftovers()
+	}
+	if len(left) > 0 && !opts.quiet {
+		fmt.Fprintf(os.Stderr, "[daemonized] %s\n", leftoverWarning(display, duration, left))
+	}
+	// In the terminal's foreground, Ctrl-C goes to the command and not to
+	// reporter, so it shows only in how the command ended.
+	switch {
+	case killedBy == syscall.SIGINT || killedBy == syscall.SIGTERM || killedBy == syscall.SIGHUP:
+		signaled.Store(true)
+	case exitCode == exitInterrupted:
+		signaled.Store(true)
+	}
+	if timedOut {
+		exitCode = exitTimedOut
+	}
+
+	res = runResult{
+		Command:   display,
+		Duration:  duration,
+		ExitCode:  exitCode,
+		Labels:    opts.labels,
+		TimedOut:  timedOut,
+		Signal:    killedBy,
+		Resources: group.usage,
+		Leftover:  len(left),
+	}
+	return res, signaled.Load(), nil
+}
+
+// exitStatus returns the exit code in err, as returned by cmd.Wait, the
+// status a shell would show: 128 plus the signal for a command killed by
+// one, which is returned too. It returns err itself if the command could
+// not be waited for.
+func exitStatus(err error) (exitCode int, sig syscall.Signal, _ error) {
+	if err == nil {
+		return 0, 0, nil
+	}
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return 0, 0, err
+	}
+	if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal()), ws.Signal(), nil
+	}
+	return ee.ExitCode(), 0, nil
+}
+
+func notifyOnlyMode(command string, duration time.Duration, exitCode int, opts options) int {
+	report(runResult{Command: command, Duration: duration, ExitCode: exitCode, Labels: opts.labels, Signal: shellSignal(exitCode)}, opts)
+	return exitCode
+}
+
+// shellSignal returns the signal a shell's exit status of 128 plus its
+// number stands for, or 0 for other statuses.
+func shellSignal(exitCode int) syscall.Signal {
+	if _, ok := signalNames[syscall.Signal(exitCode-128)]; ok && exitCode > 128 {
+		return syscall.Signal(exitCode - 128)
+	}
+	return 0
+}
+
+// report records a finished command in the history, then rings the bell and
+// sends notifications if it passes the configured filters, which it returns
+// whether it did.
+func report(res runResult, opts options) bool {
+	dir, _ := os.Getwd()
+	threshold := opts.threshold
+	if opts.pastRuns != nil {
+		// Read before this run is recorded, so it is judged against the
+		// ones before it.
+		entries, err := opts.pastRuns.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[history] %v\n", err)
+		}
+		threshold = autoThreshold(entries, res.Command, dir)
+	}
+	if t, ok := matchCommandThreshold(opts.commandThresholds, res.Command); ok {
+		threshold = t
+	}
+	// Without an exit status the run is neither a success nor a failure
+	// to the records kept of each, and a failed attempt that will be
+	// retried is not the run's outcome yet.
+	record := !res.ExitUnknown && res.RetryIn == 0
+	if opts.history != nil && record {
+		if err := opts.history.Record(newHistoryEntry(res, time.Now())); err != nil {
+			fmt.Fprintf(os.Stderr, "[history] %v\n", err)
+		}
+	}
+	if opts.statsd != nil && record {
+		if err := opts.statsd.record(res); err != nil {
+			fmt.Fprintf(os.Stderr, "[statsd] %v\n", err)
+		}
+	}
+	if opts.prometheus != nil && record {
+		if err := opts.prometheus.record(res); err != nil {
+			fmt.Fprintf(os.Stderr, "[prometheus] %v\n", err)
+		}
+	}
+	if opts.journal != nil && res.RetryIn == 0 && !opts.muted && shouldNotify(res.Duration, threshold, opts.always) {
+		if err := opts.journal.record(res, dir, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "[journal] %v\n", err)
+		}
+	}
+	fp := fingerprint(res, opts.dedupKey, dir)
+	if record && !opts.noState {
+		recovered, err := updateStreak(streakPath(), fp, res.ExitCode, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[streak] %v\n", err)
+		}
+		res.Recovered = recovered
+	}
+	var deliveries []delivery
+	// A recovery is news even to those who only asked about failures, and
+	// a run that may have failed passes the outcome filters.
+	notified := !opts.muted && !opts.snoozed && shouldNotify(res.Duration, threshold, opts.always) &&
+		((outcomeWanted(opts.notifyOn, res.ExitCode) && opts.exitCodes.allows(res.ExitCode)) || res.Recovered > 0 || res.ExitUnknown) &&
+		!(opts.ignoreInterrupts && interruptedByUser(res))
+	if notified && res.ExitCode == 0 && !res.ExitUnknown {
+		silenced, err := successSilenced(silencePath(), fp, opts.successEvery, res.Recovered > 0, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[success-every] %v\n", err)
+		}
+		notified = !silenced
+	}
+	if len(opts.push) > 0 && !opts.noState && !opts.quietHours.active(time.Now()) {
+		// Pushes queued while offline go out before this one, in order;
+		// during quiet hours they wait for the morning.
+		flushQueued(opts.quiet)
+	}
+	if notified {
+		opts := quietPush(routeTier(opts, res.Duration), time.Now())
+		if opts.desktop && !opts.evenIfFocused && terminalFocused(currentEnvironment()) {
+			opts.desktop, opts.focused = false, true
+		}
+		if len(opts.push) > 0 && opts.pushWhenIdle > 0 && (opts.desktop || opts.focused) &&
+			!userAway(currentEnvironment(), opts.pushWhenIdle) {
+			opts.push = nil
+		}
+		if opts.bell {
+			ringBell()
+		}
+		if opts.hold != nil {
+			opts.hold(res, opts)
+		} else {
+			deliveries = notify(res, opts)
+		}
+	}
+	if opts.reportJSON != "" && res.RetryIn == 0 {
+		r := runReport{
+			Command:    res.Command,
+			ExitCode:   res.ExitCode,
+			DurationMS: res.Duration.Milliseconds(),
+			Labels:     res.Labels,
+			Notified:   notified,
+			Deliveries: deliveries,
+		}
+		if err := writeReport(opts.reportJSON, r); err != nil {
+			fmt.Fprintf(os.Stderr, "[report] %v\n", err)
+		}
+	}
+	return notified
+}
+
+// resultStatus describes how res ended, as Status does, for runs that also
+// time out or end without an exit status.
+func resultStatus(res runResult) string {
+	switch {
+	case res.TimedOut:
+		return "timed out"
+	case res.ExitUnknown:
+		return "finished (exit status unknown)"
+	case res.Signal != 0:
+		return Status(res.ExitCode, signalName(res.Signal))
+	}
+	return Status(res.ExitCode, "")
+}
+
+// signalName returns the name of sig, such as SIGTERM.
+func signalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
+
+// exitInterrupted is the exit status of a command stopped with Ctrl-C.
+const exitInterrupted = 130
+
+// interruptedByUser reports whether res was stopped with Ctrl-C: killed by
+// SIGINT, or exiting with the status a shell gives that.
+func interruptedByUser(res runResult) bool {
+	return res.Signal == syscall.SIGINT || res.ExitCode == exitInterrupted && res.Signal == 0 && !res.TimedOut
+}
+
+func shouldNotify(duration, threshold time.Duration, always bool) bool {
+	if always {
+		return true
+	}
+	return duration >= threshold
+}
+
+// Values for -notify-on.
+const (
+	notifyOnAlways  = "always"
+	notifyOnFailure = "failure"
+	notifyOnSuccess = "success"
+)
+
+func outcomeWanted(notifyOn string, exitCode int) bool {
+	switch notifyOn {
+	case notifyOnFailure:
+		return exitCode != 0
+	case notifyOnSuccess:
+		return exitCode == 0
+	default:
+		return true
+	}
+}
+
+// notification is the rendered message handed to every backend.
+type notification struct {
+	Title    string
+	Body     string
+	Subtitle string
+	// Failed marks notifications for commands that exited non-zero so
+	// backends can style them more urgently.
+	Failed bool
+	// Recovered, for a success, is how many failed runs in a row it
+	// follows, so backends can mark recoveries distinctly.
+	Recovered int
+	// Running marks an update about a run still in progress (see
+	// -progress), which backends show quietly.
+	Running bool
+	// Quiet asks push providers to deliver without sound, during quiet
+	// hours.
+	Quiet bool
+	// Args, Finished, Duration, and ExitCode describe the run for backends
+	// that show them as separate fields. Digests, which cover many runs,
+	// leave them zero.
+	Args     []string
+	Finished time.Time
+	Duration time.Duration
+	ExitCode int
+	// Output is the tail of the command's output, one line per line, shown
+	// for failed runs when capturing is enabled.
+	Output string
+	// DedupKey, if set, names the logical job so backends can collapse
+	// notifications for it.
+	DedupKey string
+	Labels   map[string]string
+	// Issue is the ticket the run belongs to, for issue tracker providers.
+	Issue string
+	// TimedOut marks a run stopped at its -max-duration limit.
+	TimedOut bool
+	// ExitUnknown marks a run whose exit status is unknown.
+	ExitUnknown bool
+	// Attempts is how many times the command ran, with -retries, and
+	// Retrying marks a failed attempt that will be retried.
+	Attempts int
+	Retrying bool
+	// Signal names the signal that killed the command, such as SIGKILL.
+	Signal string
+	// Resources is what the command used, with -resources.
+	Resources resourceUsage
+	// GitRepo and GitBranch name the checkout the command ran in, with
+	// -context.
+	GitRepo, GitBranch string
+	// Dir is where the command ran, when not in the current directory, as
+	// for a Runner's command with its own Dir.
+	Dir string
+}
+
+// dir returns where n's command ran.
+func (n notification) dir() string {
+	if n.Dir != "" {
+		return n.Dir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// notify sends the notification for res to every configured backend and
+// returns how each delivery went.
+func notify(res runResult, opts options) []delivery {
+	return deliver(newNotification(res, opts), opts)
+}
+
+// deliver sends n to every configured backend and returns how each delivery
+// went.
+func deliver(n notification, opts options) []delivery {
+	var samples []latencySample
+	var deliveries []delivery
+	if opts.desktop {
+		sample, err := timeBackend("desktop", func() error { return showDesktop(opts.agent, opts.terminalNotify, n) })
+		samples = append(samples, sample)
+		deliveries = append(deliveries, newDelivery("desktop", err))
+		if err != nil && !opts.quiet {
+			// Graceful fallback to stderr if the platform notifier is unavailable.
+			notifyStderr(n)
+		}
+	} else if len(opts.push) == 0 && opts.multiplexer == "" && !opts.quiet && !opts.focused {
+		// Nobody is at a screen and there is nowhere else to send it; leave
+		// the message where cron mail or CI logs will show it.
+		notifyStderr(n)
+	}
+
+	if opts.multiplexer != "" && !opts.focused {
+		sample, err := timeBackend(opts.multiplexer, func() error { return notifyMultiplexer(opts.multiplexer, opts.multiplexerPopup, n) })
+		samples = append(samples, sample)
+		deliveries = append(deliveries, newDelivery(opts.multiplexer, err))
+		if err != nil && !opts.quiet && !opts.summary {
+			fmt.Fprintf(os.Stderr, "[%s] %v\n", opts.multiplexer, err)
+		}
+	}
+
+	spool := spoolDir()
+	if opts.noState {
+		spool = ""
+	}
+	pushed, pushSamples := pushAll(opts.push, n, spool)
+	samples = append(samples, pushSamples...)
+	deliveries = append(deliveries, pushed...)
+	for _, d := range pushed {
+		switch {
+		case d.err == nil || opts.quiet || opts.summary:
+		case d.Queued:
+			fmt.Fprintf(os.Stderr, "[push] %v; queued to resend later\n", d.err)
+		default:
+			fmt.Fprintf(os.Stderr, "[push] %v\n", d.err)
+		}
+	}
+
+	for _, notifier := range opts.notifiers {
+		sample, err := timeBackend("notifier", func() error { return notifier.Notify(opts.notifyContext, n.public()) })
+		samples = append(samples, sample)
+		deliveries = append(deliveries, newDelivery("notifier", err))
+		if err != nil && !opts.quiet && !opts.summary {
+			fmt.Fprintf(os.Stderr, "[notifier] %v\n", err)
+		}
+	}
+
+	if opts.summary && anyFailed(deliveries) {
+		fmt.Fprintf(os.Stderr, "[notify] delivered: %s\n", summarizeDeliveries(deliveries))
+	}
+
+	if opts.telemetry {
+		if err := recordLatency(latencyPath(), samples); err != nil {
+			fmt.Fprintf(os.Stderr, "[telemetry] %v\n", err)
+		}
+	}
+	if err := recordNotification(opts.notificationLog, n, deliveries, time.Now()); err != nil && !opts.quiet {
+		fmt.Fprintf(os.Stderr, "[notifications] %v\n", err)
+	}
+	return deliveries
+}
+
+// newNotification renders the notification about res: its body, the
+// templates, and the context and captured output asked for.
+func newNotification(res runResult, opts options) notification {
+	body := fmt.Sprintf("%s in %s%s", resultStatus(res), formatDuration(res.Duration), attemptNote(res, opts.retries))
+	if res.FailedStage != "" {
+		body += ", " + res.FailedStage
+	}
+	if res.Leftover > 0 {
+		body += fmt.Sprintf(", left %s running", processCount(res.Leftover))
+	}
+	if res.Recovered > 0 {
+		body = fmt.Sprintf("recovered: %s after %s", body, plural(res.Recovered, "failed run"))
+	}
+	if note := resourceNote(res.Resources); opts.resources && note != "" {
+		body += ", " + note
+	}
+	if res.Energy > 0 {
+		body += fmt.Sprintf(", ~%s", formatEnergy(res.Energy))
+	}
+	n := notification{
+		Title:       opts.title,
+		Body:        body,
+		Subtitle:    res.Command,
+		Failed:      res.ExitCode != 0,
+		Recovered:   res.Recovered,
+		Args:        res.Args,
+		Finished:    time.Now(),
+		Duration:    res.Duration,
+		ExitCode:    res.ExitCode,
+		DedupKey:    opts.dedupKey,
+		Labels:      res.Labels,
+		Quiet:       opts.pushQuietly,
+		Issue:       opts.issue,
+		TimedOut:    res.TimedOut,
+		ExitUnknown: res.ExitUnknown,
+		Attempts:    res.Attempts,
+		Retrying:    res.RetryIn > 0,
+	}
+	if res.Signal != 0 {
+		n.Signal = signalName(res.Signal)
+	}
+	if opts.resources {
+		n.Resources = res.Resources
+	}
+	if err := opts.templates.apply(res, &n); err != nil && !opts.quiet {
+		fmt.Fprintf(os.Stderr, "[template] %v\n", err)
+	}
+	if opts.context {
+		c := currentContext()
+		n.Body += "\n" + c.String()
+		n.GitRepo, n.GitBranch = c.Repo, c.Branch
+	}
+	if n.Failed {
+		n.Output = strings.Join(res.Output, "\n")
+	}
+	if n.DedupKey == "" {
+		// Replace the running notification from -progress.
+		n.DedupKey = opts.progressKey
+	}
+	return n
+}
+
+// notifyStderr prints n as [notify] lines on stderr.
+func notifyStderr(n notification) {
+	fmt.Fprintf(os.Stderr, "[notify] %s — %s\n", n.Subtitle, n.Body)
+	if n.Output != "" {
+		fmt.Fprintf(os.Stderr, "[notify] %s\n", strings.ReplaceAll(n.Output, "\n", "\n[notify] "))
+	}
+}
+
+func notifyDesktop(n notification) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return notifyMac(n)
+	case "linux":
+		if isWSL() {
+			return notifyWSL(n)
+		}
+		return notifyLinux(n)
+	default:
+		return fmt.Errorf("no notifier available for %s", runtime.GOOS)
+	}
+}
+
+func initNotifier() {
+	notifierOnce.Do(func() {
+		switch runtime.GOOS {
+		case "darwin":
+			notifierPath, _ = exec.LookPath("osascript")
+		case "linux":
+			notifierPath, _ = exec.LookPath("notify-send")
+		}
+		notifierExists = notifierPath != ""
+	})
+}
+
+func notifyMac(n notification) error {
+	initNotifier()
+	if !notifierExists {
+		return fmt.Errorf("osascript not found in PATH")
+	}
+	title := n.Title
+	sound := ""
+	switch {
+	case n.Failed:
+		title = "✗ " + title
+		sound = ` sound name "Basso"`
+	case n.Recovered > 0:
+		title = "✓ " + title
+		sound = ` sound name "Glass"`
+	}
+	body := n.Body
+	if n.Failed && n.Output != "" {
+		// Banners show a single line; the last captured line is usually
+		// the error.
+		body += ": " + n.Output[strings.LastIndexByte(n.Output, '\n')+1:]
+	}
+	script := fmt.Sprintf(`display notification "%s" with title "%s" subtitle "%s"%s`,
+		escapeForAppleScript(body), escapeForAppleScript(title), escapeForAppleScript(n.Subtitle), sound)
+	return exec.Command(notifierPath, "-e", script).Run()
+}
+
+func notifyLinux(n notification) error {
+	// Notification servers render the body as markup, so a command such as
+	// "cat <in >out" must be escaped or it is dropped as a malformed tag.
+	message := escapeMarkup(notificationText(n))
+	urgency := urgencyNormal
+	switch {
+	case n.Failed:
+		urgency = urgencyCritical
+	case n.Running:
+		urgency = urgencyLow
+	}
+	id, err := notifyDBus(desktopNotification{
+		Summary:    n.Title,
+		Body:       message,
+		Urgency:    urgency,
+		ReplacesID: lookupDedup(dedupPath(), n.DedupKey, time.Now()),
+		Timeout:    -1,
+	})
+	if err == nil {
+		if err := storeDedup(dedupPath(), n.DedupKey, id, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "[dedup] %v\n", err)
+		}
+		return nil
+	}
+
+	// Fall back to notify-send, e.g. when the bus address is not exported
+	// into this environment but notify-send knows how to find it.
+	initNotifier()
+	if !notifierExists {
+		return fmt.Errorf("%v; notify-send not found in PATH", err)
+	}
+	level := "normal"
+	if n.Failed {
+		level = "critical"
+	}
+	return exec.Command(notifierPath, "-u", level, n.Title, message).Run()
+}
+
+// notificationText renders the command, status, and any captured output as the
+// plain-text body used by multi-line backends.
+func notificationText(n notification) string {
+	text := fmt.Sprintf("%s — %s", n.Subtitle, n.Body)
+	if n.Output != "" {
+		text += "\n" + n.Output
+	}
+	return text
+}
+
+func formatDuration(d time.Duration) string {
+	return FormatDuration(d)
+}
+
+// clockTime formats t as a local time of day, adding the weekday unless t is
+// on the same day as now.
+func clockTime(t, now time.Time) string {
+	t, now = t.Local(), now.Local()
+	if t.YearDay() != now.YearDay() || t.Year() != now.Year() {
+		return t.Format("Mon 15:04")
+	}
+	return t.Format("15:04")
+}
+
+func getenvDefault(key, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
+
+// plural formats n with noun, adding an "s" unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -1,6 +1,8 @@
-package main
+package reporter
 
 import (
+	"reflect"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -61,6 +63,27 @@ func TestShouldNotify(t *testing.T) {
 	}
 }
 
+func TestOutcomeWanted(t *testing.T) {
+	tests := []struct {
+		notifyOn string
+		exitCode int
+		want     bool
+	}{
+		{notifyOn: notifyOnAlways, exitCode: 0, want: true},
+		{notifyOn: notifyOnAlways, exitCode: 1, want: true},
+		{notifyOn: notifyOnFailure, exitCode: 0, want: false},
+		{notifyOn: notifyOnFailure, exitCode: 2, want: true},
+		{notifyOn: notifyOnSuccess, exitCode: 0, want: true},
+		{notifyOn: notifyOnSuccess, exitCode: 1, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := outcomeWanted(tt.notifyOn, tt.exitCode); got != tt.want {
+			t.Errorf("outcomeWanted(%q, %d) = %v, want %v", tt.notifyOn, tt.exitCode, got, tt.want)
+		}
+	}
+}
+
 func TestFormatDuration(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -114,61 +137,17 @@ func TestFormatDuration(t *testing.T) {
 	}
 }
 
-func TestEscapeForAppleScript(t *testing.T) {
-	tests := []struct {
-		name  string
-		input string
-		want  string
-	}{
-		{
-			name:  "no special characters",
-			input: "hello world",
-			want:  "hello world",
-		},
-		{
-			name:  "double quotes",
-			input: `say "hello"`,
-			want:  `say \"hello\"`,
-		},
-		{
-			name:  "backslash",
-			input: `path\to\file`,
-			want:  `path\\to\\file`,
-		},
-		{
-			name:  "newline",
-			input: "line1\nline2",
-			want:  "line1 line2",
-		},
-		{
-			name:  "carriage return",
-			input: "line1\rline2",
-			want:  "line1 line2",
-		},
-		{
-			name:  "tab",
-			input: "col1\tcol2",
-			want:  "col1 col2",
-		},
-		{
-			name:  "mixed special characters",
-			input: "say \"hello\"\nwith\\path\tand\rtabs",
-			want:  "say \\\"hello\\\" with\\\\path and tabs",
-		},
-		{
-			name:  "empty string",
-			input: "",
-			want:  "",
-		},
+func TestShellArgs(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+	got := shellArgs("make build && make test")
+	want := []string{"/bin/zsh", "-c", "make build && make test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shellArgs() = %q, want %q", got, want)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := escapeForAppleScript(tt.input)
-			if got != tt.want {
-				t.Errorf("escapeForAppleScript(%q) = %q, want %q", tt.input, got, tt.want)
-			}
-		})
+	t.Setenv("SHELL", "")
+	if got := shellArgs("true")[0]; got != "/bin/sh" {
+		t.Errorf("shellArgs() without $SHELL uses %q, want /bin/sh", got)
 	}
 }
 
@@ -219,3 +198,39 @@ func TestGetenvDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestShellSignal(t *testing.T) {
+	for _, tt := range []struct {
+		exitCode int
+		want     syscall.Signal
+	}{
+		{0, 0},
+		{1, 0},
+		{128, 0},
+		{130, syscall.SIGINT},
+		{137, syscall.SIGKILL},
+		{143, syscall.SIGTERM},
+		{255, 0},
+	} {
+		if got := shellSignal(tt.exitCode); got != tt.want {
+			t.Errorf("shellSignal(%d) = %v, want %v", tt.exitCode, got, tt.want)
+		}
+	}
+}
+
+func TestInterruptedByUser(t *testing.T) {
+	for _, tt := range []struct {
+		res  runResult
+		want bool
+	}{
+		{runResult{ExitCode: 130, Signal: syscall.SIGINT}, true},
+		{runResult{ExitCode: 130}, true},
+		{runResult{ExitCode: 143, Signal: syscall.SIGTERM}, false},
+		{runResult{ExitCode: 1}, false},
+		{runResult{}, false},
+	} {
+		if got := interruptedByUser(tt.res); got != tt.want {
+			t.Errorf("interruptedByUser(%+v) = %t, want %t", tt.res, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Inside tmux or GNU screen, notifications are also shown by the multiplexer,
+// in the status line of every client attached to the session. That reaches
+// the user where desktop notifiers cannot, such as in a session on a remote
+// machine or one nested inside another, and in whichever window they are
+// looking at. The bell, on by default, also flags the command's window in
+// the status line. The multiplexer config key picks how:
+//
+//	auto   a status line message (default)
+//	popup  a tmux popup, closed with Enter; screen shows a message
+//	never  nothing
+//
+// Nothing is shown while the command's pane is the one on screen in a
+// focused terminal, where the user already sees the result.
+
+// Multiplexers, as named in deliveries.
+const (
+	multiplexerTmux   = "tmux"
+	multiplexerScreen = "screen"
+)
+
+// Values of the multiplexer config key.
+const (
+	multiplexerAuto  = "auto"
+	multiplexerPopup = "popup"
+	multiplexerNever = "never"
+)
+
+// multiplexerTimeout bounds the commands that show a notification.
+const multiplexerTimeout = 2 * time.Second
+
+// checkMultiplexerMode validates a multiplexer config value.
+func checkMultiplexerMode(s string) error {
+	switch s {
+	case multiplexerAuto, multiplexerPopup, multiplexerNever:
+		return nil
+	}
+	return fmt.Errorf("must be %q, %q, or %q", multiplexerAuto, multiplexerPopup, multiplexerNever)
+}
+
+// detectMultiplexer returns the multiplexer running this process, innermost
+// first, or "".
+func detectMultiplexer(getenv func(string) string) string {
+	switch {
+	case getenv("TMUX") != "":
+		return multiplexerTmux
+	case getenv("STY") != "":
+		return multiplexerScreen
+	}
+	return ""
+}
+
+// multiplexerText renders n as one line for a status line or popup.
+func multiplexerText(n notification) string {
+	return scrubControl(strings.TrimSpace(n.Title + ": " + oneLine(n.Subtitle) + " " + n.Body))
+}
+
+// notifyMultiplexer shows n in mux, as a popup if popup is set and mux can
+// show one.
+func notifyMultiplexer(mux string, popup bool, n notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), multiplexerTimeout)
+	defer cancel()
+	text := multiplexerText(n)
+	if mux == multiplexerScreen {
+		return exec.CommandContext(ctx, "screen", "-S", os.Getenv("STY"), "-X", "echo", text).Run()
+	}
+
+	out, err := exec.CommandContext(ctx, "tmux", "list-clients", "-t", os.Getenv("TMUX_PANE"), "-F", "#{client_name}").Output()
+	if err != nil {
+		return fmt.Errorf("listing tmux clients: %w", err)
+	}
+	clients := strings.Fields(string(out))
+	if len(clients) == 0 {
+		return errors.New("no client is attached to the tmux session")
+	}
+	for _, client := range clients {
+		if err := exec.CommandContext(ctx, "tmux", tmuxArgs(client, text, popup)...).Run(); err != nil {
+			return fmt.Errorf("tmux: %w", err)
+		}
+	}
+	return nil
+}
+
+// tmuxArgs returns the tmux command showing text on client. Messages are
+// expanded as formats, where ## is a literal #.
+func tmuxArgs(client, text string, popup bool) []string {
+	if !popup {
+		return []string{"display-message", "-c", client, "--", strings.ReplaceAll(text, "#", "##")}
+	}
+	width := min(len([]rune(text))+4, 120)
+	return []string{"display-popup", "-c", client, "-T", " reporter ", "-w", fmt.Sprint(width), "-h", "5", "-E",
+		"printf '%s\\n' " + shellQuote(text) + "; read -r _"}
+}
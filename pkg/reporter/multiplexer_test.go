@@ -0,0 +1,55 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectMultiplexer(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"TMUX": "/tmp/tmux-1000/default,1234,0"}, multiplexerTmux},
+		{map[string]string{"STY": "4321.pts-0.dev"}, multiplexerScreen},
+		{map[string]string{"TMUX": "/tmp/tmux-1000/default,1234,0", "STY": "4321.pts-0.dev"}, multiplexerTmux},
+		{map[string]string{}, ""},
+	}
+	for _, tt := range tests {
+		if got := detectMultiplexer(func(k string) string { return tt.env[k] }); got != tt.want {
+			t.Errorf("detectMultiplexer(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestTmuxArgs(t *testing.T) {
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m", Subtitle: "make #{pane_id}\ntest"}
+	text := multiplexerText(n)
+	if want := "Task finished: make #{pane_id} ⏎ test failed (exit 2) in 3m"; text != want {
+		t.Fatalf("multiplexerText() = %q, want %q", text, want)
+	}
+	got := tmuxArgs("/dev/pts/3", text, false)
+	want := []string{"display-message", "-c", "/dev/pts/3", "--", "Task finished: make ##{pane_id} ⏎ test failed (exit 2) in 3m"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tmuxArgs() = %q, want %q", got, want)
+	}
+
+	got = tmuxArgs("/dev/pts/3", "it's done", true)
+	want = []string{"display-popup", "-c", "/dev/pts/3", "-T", " reporter ", "-w", "13", "-h", "5", "-E",
+		`printf '%s\n' 'it'\''s done'; read -r _`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tmuxArgs(popup) = %q, want %q", got, want)
+	}
+}
+
+func TestMultiplexerConfig(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "multiplexer = \"popup\"\n")
+	if _, err := loadConfig(base); err != nil {
+		t.Errorf("loadConfig() returned error: %v", err)
+	}
+	writeFile(t, systemConfigPath, "multiplexer = \"zellij\"\n")
+	if _, err := loadConfig(base); err == nil {
+		t.Error("loadConfig() accepted multiplexer = \"zellij\", want error")
+	}
+}
@@ -0,0 +1,135 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// `reporter mute` silences notifications from every reporter invocation,
+// shell hooks included, for a while or until `reporter unmute`, such as
+// during a demo or a meeting. Muted runs are still recorded in the history
+// and the journal.
+
+const muteFileName = "mute.json"
+
+type muteRecord struct {
+	// Until is when notifications resume; zero means at `reporter unmute`.
+	Until time.Time `json:"until,omitempty"`
+}
+
+func mutePath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, muteFileName)
+}
+
+// mutedUntil reports whether notifications are muted at now and, if so, until
+// when; a zero time means until `reporter unmute`. A missing or unreadable
+// file means they are not muted.
+func mutedUntil(path string, now time.Time) (muted bool, until time.Time) {
+	if path == "" {
+		return false, time.Time{}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, time.Time{}
+	}
+	var r muteRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return false, time.Time{}
+	}
+	if !r.Until.IsZero() && !now.Before(r.Until) {
+		return false, time.Time{}
+	}
+	return true, r.Until
+}
+
+// storeMute mutes notifications until the given time, or until unmuted if it
+// is zero.
+func storeMute(path string, until time.Time) error {
+	if path == "" {
+		return errors.New("no state directory to keep it in")
+	}
+	data, err := json.Marshal(muteRecord{Until: until})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// describeMute says until when notifications are muted.
+func describeMute(until, now time.Time) string {
+	if until.IsZero() {
+		return "muted until reporter unmute"
+	}
+	return "muted until " + clockTime(until, now)
+}
+
+// runMute implements `reporter mute [duration]`.
+func runMute(args []string) int {
+	fset := flag.NewFlagSet("mute", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter mute [duration]")
+		fmt.Fprintln(fset.Output(), "Silence notifications from every reporter, shell hooks included, for duration (e.g. 1h) or until reporter unmute.")
+	}
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() > 1 {
+		fset.Usage()
+		return 2
+	}
+	now := time.Now()
+	var until time.Time
+	if fset.NArg() == 1 {
+		d, err := time.ParseDuration(fset.Arg(0))
+		if err != nil || d <= 0 {
+			fmt.Fprintf(os.Stderr, "mute: invalid duration %q: want one such as 30m or 2h\n", fset.Arg(0))
+			return 2
+		}
+		until = now.Add(d)
+	}
+	if err := storeMute(mutePath(), until); err != nil {
+		fmt.Fprintf(os.Stderr, "mute: %v\n", err)
+		return 1
+	}
+	fmt.Printf("notifications %s\n", describeMute(until, now))
+	return 0
+}
+
+// runUnmute implements `reporter unmute`.
+func runUnmute(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: reporter unmute")
+		return 2
+	}
+	path := mutePath()
+	muted, _ := mutedUntil(path, time.Now())
+	if path != "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "unmute: %v\n", err)
+			return 1
+		}
+	}
+	if !muted {
+		fmt.Println("notifications were not muted")
+		return 0
+	}
+	fmt.Println("notifications unmuted")
+	return 0
+}
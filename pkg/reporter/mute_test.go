@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMutedUntil(t *testing.T) {
+	path := filepath.Join(t.TempDir(), muteFileName)
+	now := time.Date(2026, 3, 6, 14, 0, 0, 0, time.UTC)
+
+	if muted, _ := mutedUntil(path, now); muted {
+		t.Error("muted without a mute file")
+	}
+
+	if err := storeMute(path, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if muted, until := mutedUntil(path, now.Add(59*time.Minute)); !muted || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("mutedUntil() = %v, %v within the hour, want muted until 15:00", muted, until)
+	}
+	if muted, _ := mutedUntil(path, now.Add(time.Hour)); muted {
+		t.Error("still muted once the time is up")
+	}
+
+	if err := storeMute(path, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if muted, until := mutedUntil(path, now.AddDate(1, 0, 0)); !muted || !until.IsZero() {
+		t.Errorf("mutedUntil() = %v, %v a year later, want muted until unmuted", muted, until)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if muted, _ := mutedUntil(path, now); muted {
+		t.Error("muted by a corrupt file")
+	}
+}
+
+func TestRunMute(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if code := runMute([]string{"soon"}); code != 2 {
+		t.Errorf("runMute(soon) = %d, want 2", code)
+	}
+	if code := runMute([]string{"-5m"}); code != 2 {
+		t.Errorf("runMute(-5m) = %d, want 2", code)
+	}
+	if code := runMute([]string{"1h"}); code != 0 {
+		t.Fatalf("runMute(1h) = %d, want 0", code)
+	}
+	if muted, until := mutedUntil(mutePath(), time.Now()); !muted || time.Until(until) < 59*time.Minute {
+		t.Errorf("after reporter mute 1h, mutedUntil() = %v, %v", muted, until)
+	}
+	if code := runUnmute(nil); code != 0 {
+		t.Fatalf("runUnmute() = %d, want 0", code)
+	}
+	if muted, _ := mutedUntil(mutePath(), time.Now()); muted {
+		t.Error("still muted after reporter unmute")
+	}
+	if code := runUnmute(nil); code != 0 {
+		t.Errorf("runUnmute() when not muted = %d, want 0", code)
+	}
+}
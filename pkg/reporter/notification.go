@@ -0,0 +1,119 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Notification is what a Notifier is told about a run.
+type Notification struct {
+	Title    string // such as "Task finished"
+	Body     string // such as "failed (exit 2) in 3m00s"
+	Command  string // the command line, for display
+	Args     []string
+	Dir      string
+	Host     string
+	User     string
+	Failed   bool
+	ExitCode int
+	Signal   string // the signal that killed the command, such as SIGKILL
+	Duration time.Duration
+	Finished time.Time
+	Output   string // trailing output, usually only for failures
+	Labels   map[string]string
+	// DedupKey lets receivers that support it replace an earlier
+	// notification with the same key.
+	DedupKey string
+}
+
+// public returns n as a Notification, for Notifiers.
+func (n notification) public() Notification {
+	host, _ := os.Hostname()
+	return Notification{
+		Title:    n.Title,
+		Body:     n.Body,
+		Command:  n.Subtitle,
+		Args:     n.Args,
+		Dir:      n.dir(),
+		Host:     host,
+		User:     currentUser(),
+		Failed:   n.Failed,
+		ExitCode: n.ExitCode,
+		Signal:   n.Signal,
+		Duration: n.Duration,
+		Finished: n.Finished,
+		Output:   n.Output,
+		Labels:   n.Labels,
+		DedupKey: n.DedupKey,
+	}
+}
+
+// Payload returns n as the reporter/v1 JSON payload.
+func (n Notification) Payload() Payload {
+	p := Payload{
+		Schema:     PayloadSchema,
+		Title:      n.Title,
+		Body:       n.Body,
+		Command:    n.Command,
+		Host:       n.Host,
+		Failed:     n.Failed,
+		ExitCode:   n.ExitCode,
+		DurationMS: n.Duration.Milliseconds(),
+		Output:     n.Output,
+		DedupKey:   n.DedupKey,
+		Labels:     n.Labels,
+		Args:       n.Args,
+		Dir:        n.Dir,
+		User:       n.User,
+		Signal:     n.Signal,
+	}
+	if !n.Finished.IsZero() {
+		p.StartedAt = n.Finished.Add(-n.Duration).Format(time.RFC3339Nano)
+		p.FinishedAt = n.Finished.Format(time.RFC3339Nano)
+	}
+	return p
+}
+
+// Notifier delivers notifications, to a phone, a chat, a desktop, or
+// anywhere else.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error { return f(ctx, n) }
+
+// Writer is a Notifier printing each notification as a line, the way the
+// reporter command falls back to stderr:
+//
+//	[notify] make release — failed (exit 2) in 3m00s
+type Writer struct {
+	W io.Writer
+}
+
+func (w Writer) Notify(ctx context.Context, n Notification) error {
+	what := n.Command
+	if what == "" {
+		what = n.Title
+	}
+	_, err := fmt.Fprintf(w.W, "[notify] %s — %s\n", what, n.Body)
+	return err
+}
+
+// Notify sends n to every notifier, even when some fail, and returns their
+// errors joined.
+func Notify(ctx context.Context, n Notification, notifiers ...Notifier) error {
+	var errs []error
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
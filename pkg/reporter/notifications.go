@@ -0,0 +1,223 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+// Each notification about a run is appended to a JSONL file in the state
+// directory along with how every backend took it, so `reporter
+// notifications` can bring back one dismissed too quickly. The log is
+// separate from the run history, which may be turned off, and keeps only the
+// newest notifications.
+
+const (
+	notificationLogFileName = "notifications.jsonl"
+	// notificationLogMaxBytes bounds the log; once it grows past that,
+	// older notifications are dropped to keep at most notificationLogKeep
+	// in half the space.
+	notificationLogKeep     = 500
+	notificationLogMaxBytes = 512 << 10
+)
+
+// sentNotification is a line of the notification log.
+type sentNotification struct {
+	Time       time.Time  `json:"time"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Command    string     `json:"command,omitempty"`
+	Failed     bool       `json:"failed,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	Deliveries []delivery `json:"deliveries"`
+}
+
+func notificationLogPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, notificationLogFileName)
+}
+
+// recordNotification appends n, sent at now, and how it was delivered to
+// the log at path. Nothing is recorded if path is "" or no backend took it.
+func recordNotification(path string, n notification, deliveries []delivery, now time.Time) error {
+	if path == "" || len(deliveries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Only readable by the user, like the history: bodies name commands.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(sentNotification{
+		Time:       now,
+		Title:      n.Title,
+		Body:       n.Body,
+		Command:    n.Subtitle,
+		Failed:     n.Failed,
+		Output:     n.Output,
+		Deliveries: deliveries,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Size() > notificationLogMaxBytes {
+		return trimNotifications(path)
+	}
+	return nil
+}
+
+// trimNotifications rewrites the log keeping only the newest notifications.
+func trimNotifications(path string) error {
+	sent, err := loadNotifications(path)
+	if err != nil {
+		return err
+	}
+	var lines [][]byte
+	size := 0
+	for i := len(sent) - 1; i >= 0 && len(lines) < notificationLogKeep; i-- {
+		line, err := json.Marshal(sent[i])
+		if err != nil {
+			return err
+		}
+		if size += len(line) + 1; size > notificationLogMaxBytes/2 {
+			break
+		}
+		lines = append(lines, line)
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if _, err := f.Write(append(lines[i], '\n')); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadNotifications reads the log, oldest first, skipping malformed lines.
+// A missing file yields no notifications.
+func loadNotifications(path string) ([]sentNotification, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sent []sentNotification
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var s sentNotification
+		if json.Unmarshal(scanner.Bytes(), &s) != nil {
+			continue
+		}
+		for i, d := range s.Deliveries {
+			if !d.OK {
+				s.Deliveries[i].err = errors.New(d.Error)
+			}
+		}
+		sent = append(sent, s)
+	}
+	return sent, scanner.Err()
+}
+
+// runNotifications implements `reporter notifications`.
+func runNotifications(args []string) int {
+	fset := flag.NewFlagSet("notifications", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter notifications [flags]")
+		fmt.Fprintln(fset.Output(), "List the notifications reporter sent recently and how each backend took them.")
+		fset.PrintDefaults()
+	}
+	limit := fset.Int("n", 20, "show at most this many of the most recent notifications (0 for all)")
+	failed := fset.Bool("failed", false, "only show notifications about failed runs")
+	asJSON := fset.Bool("json", false, "print the notifications as JSON lines")
+	clearLog := fset.Bool("clear", false, "delete the log of sent notifications")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	path := notificationLogPath()
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "notifications: cannot determine the state directory")
+		return 1
+	}
+	if *clearLog {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "notifications: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	all, err := loadNotifications(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notifications: %v\n", err)
+		return 1
+	}
+	var sent []sentNotification
+	for _, s := range all {
+		if !*failed || s.Failed {
+			sent = append(sent, s)
+		}
+	}
+	if *limit > 0 && len(sent) > *limit {
+		sent = sent[len(sent)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, s := range sent {
+			if err := enc.Encode(s); err != nil {
+				return 1
+			}
+		}
+		return 0
+	}
+	printNotifications(os.Stdout, sent)
+	return 0
+}
+
+func printNotifications(w io.Writer, sent []sentNotification) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SENT\tCOMMAND\tMESSAGE\tDELIVERED")
+	for _, s := range sent {
+		what := s.Command
+		if what == "" {
+			what = s.Title
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Time.Local().Format("2006-01-02 15:04:05"),
+			oneLine(what), oneLine(s.Body), summarizeDeliveries(s.Deliveries))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,67 @@
+package reporter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordNotification(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", notificationLogFileName)
+	now := time.Date(2026, 5, 1, 9, 30, 0, 0, time.Local)
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m00s", Subtitle: "make\ntest", Failed: true, Output: "FAIL: TestLogin"}
+	deliveries := []delivery{
+		newDelivery("desktop", nil),
+		{Backend: "ntfy", Destination: "ntfy.sh", Error: "push to https://ntfy.sh returned 503", Queued: true, err: errors.New("push to https://ntfy.sh returned 503")},
+	}
+	if err := recordNotification(path, n, deliveries, now); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing was delivered, so there is nothing to bring back.
+	if err := recordNotification(path, notification{Body: "quiet"}, nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("log is %v (%v), want readable by the user only", info.Mode(), err)
+	}
+
+	sent, err := loadNotifications(path)
+	if err != nil || len(sent) != 1 {
+		t.Fatalf("loadNotifications() = %d notifications (%v), want 1", len(sent), err)
+	}
+	if s := sent[0]; !s.Time.Equal(now) || s.Body != n.Body || s.Command != n.Subtitle || !s.Failed || s.Output != n.Output || len(s.Deliveries) != 2 {
+		t.Errorf("loaded %+v", s)
+	}
+
+	var out bytes.Buffer
+	printNotifications(&out, sent)
+	want := "2026-05-01 09:30:00  make ⏎ test  failed (exit 2) in 3m00s  desktop ✓, ntfy ✗ push to https://ntfy.sh returned 503, queued"
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || lines[1] != want {
+		t.Errorf("printNotifications() =\n%s\nwant a header and\n%s", out.String(), want)
+	}
+}
+
+func TestTrimNotifications(t *testing.T) {
+	path := filepath.Join(t.TempDir(), notificationLogFileName)
+	body := strings.Repeat("x", 2000)
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := range notificationLogMaxBytes/2000 + 10 {
+		if err := recordNotification(path, notification{Body: body}, []delivery{newDelivery("desktop", nil)}, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent, err := loadNotifications(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() > notificationLogMaxBytes || len(sent) > notificationLogMaxBytes/2000 {
+		t.Errorf("log holds %d notifications in %d bytes after trimming (%v)", len(sent), info.Size(), err)
+	}
+	if last := sent[len(sent)-1]; !last.Time.Equal(start.Add(time.Duration(notificationLogMaxBytes/2000+9) * time.Minute)) {
+		t.Errorf("newest notification is from %v; trimming dropped it", last.Time)
+	}
+}
@@ -0,0 +1,111 @@
+package reporter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Over SSH, the machine running the command has no way to reach the user's
+// desktop, but the terminal emulator on the other end of the connection does.
+// Many terminals raise a desktop notification for an escape sequence written
+// to them, so reporter writes one to the controlling terminal instead of
+// calling a notifier that would show it on the remote machine, if anywhere.
+// The terminal_notify config key chooses the sequence:
+//
+//	auto    over SSH, the sequence the terminal is known to support (default)
+//	osc9    ESC ] 9 ; text BEL, from iTerm2, also in WezTerm and Ghostty
+//	osc777  ESC ] 777 ; notify ; title ; body BEL, from rxvt, also in foot,
+//	        WezTerm, and Ghostty
+//	osc99   ESC ] 99 ; ... ESC \, kitty's own protocol
+//	never   always use the desktop notifier
+//
+// Naming a sequence uses it everywhere, not just over SSH.
+
+// Values of terminal_notify, where the sequences double as protocol names.
+const (
+	terminalNotifyAuto   = "auto"
+	terminalNotifyNever  = "never"
+	terminalNotifyOSC9   = "osc9"
+	terminalNotifyOSC777 = "osc777"
+	terminalNotifyOSC99  = "osc99"
+)
+
+// checkTerminalNotify validates a terminal_notify value.
+func checkTerminalNotify(s string) error {
+	switch s {
+	case terminalNotifyAuto, terminalNotifyNever, terminalNotifyOSC9, terminalNotifyOSC777, terminalNotifyOSC99:
+		return nil
+	}
+	return fmt.Errorf("must be %s, %s, %s, %s, or %s", terminalNotifyAuto, terminalNotifyOSC9, terminalNotifyOSC777, terminalNotifyOSC99, terminalNotifyNever)
+}
+
+// terminalNotifier returns the escape sequence protocol to raise desktop
+// notifications with, or "" to use the desktop notifier. A host agent always
+// wins, since it reaches the desktop directly.
+func terminalNotifier(cfg *config, env environment, agent string) string {
+	mode := cfg.string("terminal_notify", terminalNotifyAuto)
+	switch {
+	case agent != "" || mode == terminalNotifyNever:
+		return ""
+	case mode != terminalNotifyAuto:
+		return mode
+	case env.SSH && !env.headless():
+		return detectTerminalProtocol(os.Getenv)
+	}
+	return ""
+}
+
+// detectTerminalProtocol picks the sequence for the terminal on the other end
+// of the connection. Over SSH only TERM and LC_* variables are usually
+// forwarded: kitty, foot, and Ghostty set TERM to their own entries, and
+// iTerm2 sets LC_TERMINAL. Any other terminal gets OSC 9, which is the most
+// widely supported and is ignored where it is not.
+func detectTerminalProtocol(getenv func(string) string) string {
+	term := getenv("TERM")
+	switch {
+	case term == "xterm-kitty":
+		return terminalNotifyOSC99
+	case strings.HasPrefix(term, "foot"), term == "xterm-ghostty", term == "wezterm", getenv("TERM_PROGRAM") == "WezTerm":
+		return terminalNotifyOSC777
+	}
+	return terminalNotifyOSC9
+}
+
+// terminalSequence renders n as the escape sequence for protocol. Inside tmux
+// it is wrapped for passthrough to the outer terminal, which tmux only
+// forwards with allow-passthrough on.
+func terminalSequence(protocol string, n notification, tmux bool) string {
+	title := scrubControl(n.Title)
+	body := scrubControl(strings.TrimSpace(oneLine(n.Subtitle) + " " + n.Body))
+	var seq string
+	switch protocol {
+	case terminalNotifyOSC777:
+		// The title ends at the next semicolon.
+		seq = "\x1b]777;notify;" + strings.ReplaceAll(title, ";", ",") + ";" + body + "\a"
+	case terminalNotifyOSC99:
+		// Base64 (e=1) keeps the text clear of the protocol's delimiters;
+		// d=0 holds the title until the body completes the notification.
+		enc := base64.StdEncoding.EncodeToString
+		seq = "\x1b]99;i=reporter:d=0:e=1;" + enc([]byte(title)) + "\x1b\\" +
+			"\x1b]99;i=reporter:d=1:e=1:p=body;" + enc([]byte(body)) + "\x1b\\"
+	default:
+		seq = "\x1b]9;" + title + ": " + body + "\a"
+	}
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// notifyTerminal writes the sequence for n to the controlling terminal.
+func notifyTerminal(protocol string, n notification) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("no terminal to show the notification: %w", err)
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(terminalSequence(protocol, n, os.Getenv("TMUX") != ""))
+	return err
+}
@@ -0,0 +1,90 @@
+package reporter
+
+import (
+	"testing"
+)
+
+func TestDetectTerminalProtocol(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, terminalNotifyOSC99},
+		{map[string]string{"TERM": "foot-extra"}, terminalNotifyOSC777},
+		{map[string]string{"TERM": "xterm-ghostty"}, terminalNotifyOSC777},
+		{map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, terminalNotifyOSC777},
+		{map[string]string{"TERM": "xterm-256color", "LC_TERMINAL": "iTerm2"}, terminalNotifyOSC9},
+		{map[string]string{"TERM": "xterm-256color"}, terminalNotifyOSC9},
+	}
+	for _, tt := range tests {
+		if got := detectTerminalProtocol(func(k string) string { return tt.env[k] }); got != tt.want {
+			t.Errorf("detectTerminalProtocol(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestTerminalNotifier(t *testing.T) {
+	t.Setenv("TERM", "xterm-kitty")
+	cfg := func(mode string) *config {
+		c := &config{values: map[string]any{}}
+		if mode != "" {
+			c.values["terminal_notify"] = mode
+		}
+		return c
+	}
+	tests := []struct {
+		name  string
+		mode  string
+		env   environment
+		agent string
+		want  string
+	}{
+		{name: "ssh", env: environment{SSH: true}, want: terminalNotifyOSC99},
+		{name: "local", env: environment{}, want: ""},
+		{name: "ssh into a container", env: environment{SSH: true, Container: "docker"}, want: ""},
+		{name: "agent", env: environment{SSH: true}, agent: "/run/reporter.sock", want: ""},
+		{name: "never", mode: terminalNotifyNever, env: environment{SSH: true}, want: ""},
+		{name: "named", mode: terminalNotifyOSC777, env: environment{}, want: terminalNotifyOSC777},
+	}
+	for _, tt := range tests {
+		if got := terminalNotifier(cfg(tt.mode), tt.env, tt.agent); got != tt.want {
+			t.Errorf("%s: terminalNotifier() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTerminalSequence(t *testing.T) {
+	n := notification{Title: "Task; finished", Body: "failed (exit 2) in 3m", Subtitle: "make\x1b]0;pwned\a test"}
+	tests := []struct {
+		protocol string
+		tmux     bool
+		want     string
+	}{
+		{terminalNotifyOSC9, false, "\x1b]9;Task; finished: make ]0;pwned  test failed (exit 2) in 3m\a"},
+		{terminalNotifyOSC777, false, "\x1b]777;notify;Task, finished;make ]0;pwned  test failed (exit 2) in 3m\a"},
+		{terminalNotifyOSC99, false, "\x1b]99;i=reporter:d=0:e=1;VGFzazsgZmluaXNoZWQ=\x1b\\" +
+			"\x1b]99;i=reporter:d=1:e=1:p=body;bWFrZSBdMDtwd25lZCAgdGVzdCBmYWlsZWQgKGV4aXQgMikgaW4gM20=\x1b\\"},
+		{terminalNotifyOSC9, true, "\x1bPtmux;\x1b\x1b]9;Task; finished: make ]0;pwned  test failed (exit 2) in 3m\a\x1b\\"},
+	}
+	for _, tt := range tests {
+		if got := terminalSequence(tt.protocol, n, tt.tmux); got != tt.want {
+			t.Errorf("terminalSequence(%s, tmux %v) = %q, want %q", tt.protocol, tt.tmux, got, tt.want)
+		}
+	}
+}
+
+func TestTerminalNotifyConfig(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "terminal_notify = \"osc777\"\n")
+	cfg, err := loadConfig(base)
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+	if got := terminalNotifier(cfg, environment{}, ""); got != terminalNotifyOSC777 {
+		t.Errorf("terminalNotifier() = %q, want the configured protocol", got)
+	}
+	writeFile(t, systemConfigPath, "terminal_notify = \"osc1337\"\n")
+	if _, err := loadConfig(base); err == nil {
+		t.Error("loadConfig() accepted terminal_notify = \"osc1337\", want error")
+	}
+}
@@ -0,0 +1,107 @@
+package reporter
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxCapturedLine caps the length of a captured line so one minified blob or
+// progress line cannot crowd out everything else in a notification.
+const maxCapturedLine = 200
+
+// ansiEscape matches CSI sequences (colors, cursor movement) and OSC sequences
+// (titles, hyperlinks) so captured output reads as plain text.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// lineTail is an io.Writer that remembers the last n non-blank lines written
+// to it. It is safe for concurrent use.
+type lineTail struct {
+	mu      sync.Mutex
+	n       int
+	lines   []string
+	partial []byte
+}
+
+func newLineTail(n int) *lineTail {
+	return &lineTail{n: n}
+}
+
+func (t *lineTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.partial = append(t.partial, data[:i]...)
+		t.push(string(t.partial))
+		t.partial = t.partial[:0]
+		data = data[i+1:]
+	}
+	t.partial = append(t.partial, data...)
+	// A progress bar that never ends its line must not grow without bound.
+	if len(t.partial) > 64<<10 {
+		t.partial = append(t.partial[:0], t.partial[len(t.partial)-maxCapturedLine*4:]...)
+	}
+	return len(p), nil
+}
+
+func (t *lineTail) push(line string) {
+	line = cleanLine(line)
+	if line == "" {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.n {
+		t.lines = t.lines[len(t.lines)-t.n:]
+	}
+}
+
+// Lines returns the remembered lines, oldest first, including an unterminated
+// final line.
+func (t *lineTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string(nil), t.lines...)
+	if last := cleanLine(string(t.partial)); last != "" {
+		lines = append(lines, last)
+	}
+	if len(lines) > t.n {
+		lines = lines[len(lines)-t.n:]
+	}
+	return lines
+}
+
+// cleanLine strips terminal escapes, keeps only what a carriage return would
+// leave visible, and truncates long lines.
+func cleanLine(line string) string {
+	line = ansiEscape.ReplaceAllString(line, "")
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	line = strings.TrimRight(strings.ToValidUTF8(line, "�"), " \t")
+	if utf8.RuneCountInString(line) > maxCapturedLine {
+		line = string([]rune(line)[:maxCapturedLine-1]) + "…"
+	}
+	return line
+}
+
+// tailOutput picks up to n lines to show for a failed run. Errors usually land
+// on stderr, so its lines come first; remaining room is filled with the end of
+// stdout, which is shown before them.
+func tailOutput(stdout, stderr []string, n int) []string {
+	if len(stderr) > n {
+		stderr = stderr[len(stderr)-n:]
+	}
+	room := n - len(stderr)
+	if len(stdout) > room {
+		stdout = stdout[len(stdout)-room:]
+	}
+	return append(append([]string(nil), stdout...), stderr...)
+}
@@ -0,0 +1,118 @@
+package reporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      int
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "keeps last lines",
+			n:      2,
+			writes: []string{"one\ntwo\nthree\n"},
+			want:   []string{"two", "three"},
+		},
+		{
+			name:   "lines split across writes",
+			n:      3,
+			writes: []string{"compil", "ing\nerr", "or: boom\n"},
+			want:   []string{"compiling", "error: boom"},
+		},
+		{
+			name:   "unterminated final line",
+			n:      2,
+			writes: []string{"a\nb\nlast"},
+			want:   []string{"b", "last"},
+		},
+		{
+			name:   "skips blank lines",
+			n:      2,
+			writes: []string{"x\n\n   \n"},
+			want:   []string{"x"},
+		},
+		{
+			name:   "strips colors and progress redraws",
+			n:      2,
+			writes: []string{"\x1b[31mFAIL\x1b[0m pkg\r\n10%\r50%\r100%\n"},
+			want:   []string{"FAIL pkg", "100%"},
+		},
+		{
+			name:   "strips hyperlinks",
+			n:      1,
+			writes: []string{"see \x1b]8;;https://example.com\x07docs\x1b]8;;\x07\n"},
+			want:   []string{"see docs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tail := newLineTail(tt.n)
+			for _, w := range tt.writes {
+				tail.Write([]byte(w))
+			}
+			if got := tail.Lines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineTailTruncatesLongLines(t *testing.T) {
+	tail := newLineTail(1)
+	tail.Write([]byte(strings.Repeat("x", 1000) + "\n"))
+	got := tail.Lines()[0]
+	if n := len([]rune(got)); n != maxCapturedLine || !strings.HasSuffix(got, "…") {
+		t.Errorf("long line kept %d runes (%q...), want %d ending in an ellipsis", n, got[:10], maxCapturedLine)
+	}
+}
+
+func TestTailOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout []string
+		stderr []string
+		n      int
+		want   []string
+	}{
+		{
+			name:   "stderr fills the budget",
+			stdout: []string{"building"},
+			stderr: []string{"e1", "e2", "e3"},
+			n:      2,
+			want:   []string{"e2", "e3"},
+		},
+		{
+			name:   "stdout fills remaining room",
+			stdout: []string{"o1", "o2", "o3"},
+			stderr: []string{"e1"},
+			n:      3,
+			want:   []string{"o2", "o3", "e1"},
+		},
+		{
+			name:   "stdout only",
+			stdout: []string{"o1", "o2"},
+			n:      5,
+			want:   []string{"o1", "o2"},
+		},
+		{
+			name: "nothing captured",
+			n:    5,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tailOutput(tt.stdout, tt.stderr, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tailOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
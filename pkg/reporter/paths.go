@@ -0,0 +1,52 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// xdgDir returns the reporter directory under the XDG base directory named by
+// env, or under ~/fallback when env is unset. It returns "" if neither can be
+// determined.
+func xdgDir(env, fallback string) string {
+	base := os.Getenv(env)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, fallback)
+	}
+	return filepath.Join(base, "reporter")
+}
+
+// stateDir holds data reporter accumulates about itself, such as latency
+// telemetry.
+func stateDir() string { return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")) }
+
+// dataDir holds user data reporter keeps on their behalf, such as the run
+// history.
+func dataDir() string { return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")) }
+
+// shortenHome replaces the home directory at the start of dir with ~.
+func shortenHome(dir string) string {
+	home, _ := os.UserHomeDir()
+	if home != "" && (dir == home || strings.HasPrefix(dir, home+string(filepath.Separator))) {
+		return "~" + dir[len(home):]
+	}
+	return dir
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + rest
+}
@@ -0,0 +1,283 @@
+package reporter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// JSON payloads for webhook receivers are versioned. Within a version fields
+// are only ever added; renaming, retyping, or removing one means a new
+// version, and receivers pin the version they understand with
+// -payload-version. Every payload names its version in "schema".
+
+// payloadVersions maps each supported version to its encoder.
+var payloadVersions = map[int]func(n notification) any{
+	1: newPayloadV1,
+}
+
+// defaultPayloadVersion stays at 1 so upgrading reporter never changes what
+// an existing receiver gets; newer versions are opt-in.
+const defaultPayloadVersion = 1
+
+// payloadSchema returns the "schema" value for version.
+func payloadSchema(version int) string { return fmt.Sprintf("reporter/v%d", version) }
+
+// checkPayloadVersion reports whether version is supported.
+func checkPayloadVersion(version int) error {
+	if _, ok := payloadVersions[version]; !ok {
+		return fmt.Errorf("unsupported payload version %d (supported: 1 to %d)", version, len(payloadVersions))
+	}
+	return nil
+}
+
+// encodePayload renders n as JSON in the given payload version. Like
+// jsonString, it leaves <, >, and & readable.
+func encodePayload(version int, n notification) ([]byte, error) {
+	if err := checkPayloadVersion(version); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(payloadVersions[version](n)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// PayloadSchema is the "schema" of a Payload.
+const PayloadSchema = "reporter/v1"
+
+// Payload is the reporter/v1 JSON payload sent to webhook receivers. Fields
+// are only ever added to it; renaming, retyping, or removing one means a new
+// schema version.
+type Payload struct {
+	Schema     string            `json:"schema"` // always "reporter/v1"
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Command    string            `json:"command"`
+	Host       string            `json:"host"`
+	Failed     bool              `json:"failed"`
+	ExitCode   int               `json:"exit_code"`
+	DurationMS int64             `json:"duration_ms"`
+	Output     string            `json:"output,omitempty"`
+	DedupKey   string            `json:"dedup_key,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Args       []string          `json:"args,omitempty"` // argv as run; absent for shell-hook reports
+	Dir        string            `json:"cwd,omitempty"`
+	User       string            `json:"user,omitempty"`
+	StartedAt  string            `json:"started_at,omitempty"` // RFC 3339; absent for digests
+	FinishedAt string            `json:"finished_at,omitempty"`
+	// RecoveredAfter counts the failed runs a success follows, on
+	// recoveries.
+	RecoveredAfter int `json:"recovered_after,omitempty"`
+	// Running marks a progress update sent while the command runs; its
+	// DurationMS is the time elapsed so far.
+	Running bool `json:"running,omitempty"`
+	// Quiet marks a notification sent during quiet hours.
+	Quiet bool `json:"quiet,omitempty"`
+	// Issue is the ticket the run belongs to.
+	Issue string `json:"issue,omitempty"`
+	// TimedOut marks a run stopped at its time limit.
+	TimedOut bool `json:"timed_out,omitempty"`
+	// ExitUnknown marks a run whose exit status could not be learned, such
+	// as an attached process.
+	ExitUnknown bool `json:"exit_unknown,omitempty"`
+	// Attempts is how many times the command ran, when it was retried, and
+	// Retrying marks a failed attempt that will be retried.
+	Attempts int  `json:"attempts,omitempty"`
+	Retrying bool `json:"retrying,omitempty"`
+	// Signal names the signal that killed the command, such as "SIGKILL";
+	// exit_code is then 128 plus its number.
+	Signal string `json:"signal,omitempty"`
+	// CPUUserMS and CPUSystemMS are the CPU time the command used in user
+	// and system mode, and MaxRSSBytes its peak resident memory, when
+	// resource usage is reported.
+	CPUUserMS   int64 `json:"cpu_user_ms,omitempty"`
+	CPUSystemMS int64 `json:"cpu_system_ms,omitempty"`
+	MaxRSSBytes int64 `json:"max_rss_bytes,omitempty"`
+	// GitRepo and GitBranch name the git checkout the command ran in, when
+	// context is reported.
+	GitRepo   string `json:"git_repo,omitempty"`
+	GitBranch string `json:"git_branch,omitempty"`
+}
+
+func newPayloadV1(n notification) any {
+	host, _ := os.Hostname()
+	p := Payload{
+		Schema:         payloadSchema(1),
+		Title:          n.Title,
+		Body:           n.Body,
+		Command:        n.Subtitle,
+		Host:           host,
+		Failed:         n.Failed,
+		ExitCode:       n.ExitCode,
+		DurationMS:     n.Duration.Milliseconds(),
+		Output:         n.Output,
+		DedupKey:       n.DedupKey,
+		Labels:         n.Labels,
+		Args:           n.Args,
+		Dir:            n.dir(),
+		User:           currentUser(),
+		RecoveredAfter: n.Recovered,
+		Running:        n.Running,
+		Quiet:          n.Quiet,
+		Issue:          n.Issue,
+		TimedOut:       n.TimedOut,
+		ExitUnknown:    n.ExitUnknown,
+		Attempts:       n.Attempts,
+		Retrying:       n.Retrying,
+		Signal:         n.Signal,
+		CPUUserMS:      n.Resources.UserCPU.Milliseconds(),
+		CPUSystemMS:    n.Resources.SystemCPU.Milliseconds(),
+		MaxRSSBytes:    n.Resources.MaxRSS,
+		GitRepo:        n.GitRepo,
+		GitBranch:      n.GitBranch,
+	}
+	if !n.Finished.IsZero() {
+		p.StartedAt = n.Finished.Add(-n.Duration).Format(time.RFC3339Nano)
+		p.FinishedAt = n.Finished.Format(time.RFC3339Nano)
+	}
+	return p
+}
+
+// currentUser returns the login name running reporter, or "" if unknown.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// decodePayload parses a payload sent by another reporter, the inverse of
+// encodePayload, for receivers such as the host agent.
+func decodePayload(data []byte) (notification, error) {
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return notification{}, fmt.Errorf("invalid payload: %w", err)
+	}
+	if p.Schema != payloadSchema(1) {
+		return notification{}, fmt.Errorf("unsupported payload schema %q", p.Schema)
+	}
+	n := notification{
+		Title:       p.Title,
+		Body:        p.Body,
+		Subtitle:    p.Command,
+		Failed:      p.Failed,
+		Args:        p.Args,
+		Duration:    ms(p.DurationMS),
+		ExitCode:    p.ExitCode,
+		Output:      p.Output,
+		DedupKey:    p.DedupKey,
+		Labels:      p.Labels,
+		Recovered:   p.RecoveredAfter,
+		Running:     p.Running,
+		Quiet:       p.Quiet,
+		Issue:       p.Issue,
+		TimedOut:    p.TimedOut,
+		ExitUnknown: p.ExitUnknown,
+		Attempts:    p.Attempts,
+		Retrying:    p.Retrying,
+		Signal:      p.Signal,
+		Resources:   resourceUsage{UserCPU: ms(p.CPUUserMS), SystemCPU: ms(p.CPUSystemMS), MaxRSS: p.MaxRSSBytes},
+		GitRepo:     p.GitRepo,
+		GitBranch:   p.GitBranch,
+	}
+	if p.FinishedAt != "" {
+		n.Finished, _ = time.Parse(time.RFC3339Nano, p.FinishedAt)
+	}
+	return n, nil
+}
+
+// CloudEvents wrap the payload for event routers, such as Knative brokers
+// and EventBridge API destinations, that route on the envelope: type tells
+// apart how a run went, and data is the reporter/vN payload, unchanged.
+
+// cloudEventTypePrefix namespaces the event types by the project's home.
+const cloudEventTypePrefix = "com.github.itsrainingmani.reporter."
+
+// cloudEvent is a CloudEvents 1.0 event in the structured JSON format.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// Outcomes of a notification, which event-bus providers route on.
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeRecovered = "recovered"
+	outcomeRunning   = "running" // a progress update or heartbeat
+	outcomeDigest    = "digest"
+)
+
+// notificationOutcome returns which of the outcomes n reports.
+func notificationOutcome(n notification) string {
+	switch {
+	case n.Running:
+		return outcomeRunning
+	case n.Finished.IsZero():
+		return outcomeDigest
+	case n.Failed:
+		return outcomeFailed
+	case n.Recovered > 0:
+		return outcomeRecovered
+	}
+	return outcomeSucceeded
+}
+
+// cloudEventType returns the event type for n: run.succeeded, run.failed,
+// run.recovered, run.running, or digest.
+func cloudEventType(n notification) string {
+	outcome := notificationOutcome(n)
+	if outcome == outcomeDigest {
+		return cloudEventTypePrefix + outcome
+	}
+	return cloudEventTypePrefix + "run." + outcome
+}
+
+// notificationID returns an ID derived from n, so a push that is retried or
+// resent from the spool carries the same one and receivers can drop the
+// duplicate.
+func notificationID(n notification) string {
+	host, _ := os.Hostname()
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%d\x00%d\x00%t",
+		host, n.Title, n.Subtitle, n.Finished.UnixNano(), n.Duration, n.ExitCode, n.Running)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// newCloudEvent wraps the payload for n in a CloudEvent, with
+// notificationID as its ID.
+func newCloudEvent(version int, n notification, now time.Time) (cloudEvent, error) {
+	data, err := encodePayload(version, n)
+	if err != nil {
+		return cloudEvent{}, err
+	}
+	host, _ := os.Hostname()
+	at := n.Finished
+	if at.IsZero() {
+		at = now
+	}
+	return cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              notificationID(n),
+		Source:          "reporter://" + host,
+		Type:            cloudEventType(n),
+		Subject:         oneLine(n.Subtitle),
+		Time:            at.Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            data,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package reporter
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestEncodePayloadV1 pins the reporter/v1 format. Receivers depend on it:
+// fields may be added, but never renamed, retyped, or removed.
+func TestEncodePayloadV1(t *testing.T) {
+	host, _ := os.Hostname()
+	dir, _ := os.Getwd()
+	// Fields every payload carries about where reporter runs.
+	local := `,"cwd":` + jsonString(dir) + `,"user":` + jsonString(currentUser())
+	finished := time.Date(2026, 3, 4, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		n    notification
+		want string
+	}{
+		{
+			name: "minimal",
+			n:    notification{Title: "Task finished", Body: "succeeded in 12s", Subtitle: "make", Duration: 12 * time.Second},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"succeeded in 12s","command":"make","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":12000` + local + `}`,
+		},
+		{
+			name: "everything",
+			n: notification{
+				Title: "Deploy", Body: "failed (exit 3) in 1m", Subtitle: "./deploy <prod>", Failed: true,
+				Duration: time.Minute, ExitCode: 3, Output: "timeout", DedupKey: "deploy-prod",
+				Labels: map[string]string{"env": "prod", "app": "atlas"},
+				Args:   []string{"./deploy", "<prod>"}, Finished: finished,
+			},
+			want: `{"schema":"reporter/v1","title":"Deploy","body":"failed (exit 3) in 1m","command":"./deploy <prod>","host":` + jsonString(host) +
+				`,"failed":true,"exit_code":3,"duration_ms":60000,"output":"timeout","dedup_key":"deploy-prod","labels":{"app":"atlas","env":"prod"}` +
+				`,"args":["./deploy","<prod>"]` + local + `,"started_at":"2026-03-04T15:03:05Z","finished_at":"2026-03-04T15:04:05Z"}`,
+		},
+		{
+			name: "recovered",
+			n:    notification{Title: "Build", Body: "recovered: succeeded in 1s after 3 failed runs", Subtitle: "make", Duration: time.Second, Recovered: 3},
+			want: `{"schema":"reporter/v1","title":"Build","body":"recovered: succeeded in 1s after 3 failed runs","command":"make","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":1000` + local + `,"recovered_after":3}`,
+		},
+		{
+			name: "running",
+			n:    progressNotification("make", "ci-7", 12*time.Minute, nil),
+			want: `{"schema":"reporter/v1","title":"Running","body":"12m00s elapsed","command":"make","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":720000,"dedup_key":"ci-7"` + local + `,"running":true}`,
+		},
+		{
+			name: "issue",
+			n:    notification{Title: "Task finished", Body: "succeeded in 3h", Subtitle: "./backfill", Duration: 3 * time.Hour, Issue: "DATA-42"},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"succeeded in 3h","command":"./backfill","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":10800000` + local + `,"issue":"DATA-42"}`,
+		},
+		{
+			name: "timed out",
+			n:    notification{Title: "Task finished", Body: "timed out in 2h", Subtitle: "./backfill", Failed: true, ExitCode: 124, Duration: 2 * time.Hour, TimedOut: true},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"timed out in 2h","command":"./backfill","host":` + jsonString(host) +
+				`,"failed":true,"exit_code":124,"duration_ms":7200000` + local + `,"timed_out":true}`,
+		},
+		{
+			name: "exit unknown",
+			n:    notification{Title: "Task finished", Body: "finished (exit status unknown) in 2h", Subtitle: "./backfill", Duration: 2 * time.Hour, ExitUnknown: true},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"finished (exit status unknown) in 2h","command":"./backfill","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":7200000` + local + `,"exit_unknown":true}`,
+		},
+		{
+			name: "killed",
+			n:    notification{Title: "Task finished", Body: "killed by SIGKILL (OOM?) in 2h", Subtitle: "./backfill", Failed: true, ExitCode: 137, Duration: 2 * time.Hour, Signal: "SIGKILL"},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"killed by SIGKILL (OOM?) in 2h","command":"./backfill","host":` + jsonString(host) +
+				`,"failed":true,"exit_code":137,"duration_ms":7200000` + local + `,"signal":"SIGKILL"}`,
+		},
+		{
+			name: "resources",
+			n: notification{Title: "Task finished", Body: "succeeded in 42m, used 31m CPU, 12.0 GB peak RSS", Subtitle: "make", Duration: 42 * time.Minute,
+				Resources: resourceUsage{UserCPU: 29 * time.Minute, SystemCPU: 2 * time.Minute, MaxRSS: 12e9}},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"succeeded in 42m, used 31m CPU, 12.0 GB peak RSS","command":"make","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":2520000` + local + `,"cpu_user_ms":1740000,"cpu_system_ms":120000,"max_rss_bytes":12000000000}`,
+		},
+		{
+			name: "context",
+			n:    notification{Title: "Task finished", Body: "succeeded in 5m\ndeploy@web-03:~/src/app (acme/app main)", Subtitle: "make", Duration: 5 * time.Minute, GitRepo: "acme/app", GitBranch: "main"},
+			want: `{"schema":"reporter/v1","title":"Task finished","body":"succeeded in 5m\ndeploy@web-03:~/src/app (acme/app main)","command":"make","host":` + jsonString(host) +
+				`,"failed":false,"exit_code":0,"duration_ms":300000` + local + `,"git_repo":"acme/app","git_branch":"main"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodePayload(1, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("encodePayload(1) =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPayloadVersion(t *testing.T) {
+	if err := checkPayloadVersion(defaultPayloadVersion); err != nil {
+		t.Errorf("default version rejected: %v", err)
+	}
+	for _, v := range []int{0, -1, len(payloadVersions) + 1} {
+		if err := checkPayloadVersion(v); err == nil {
+			t.Errorf("checkPayloadVersion(%d) succeeded, want error", v)
+		}
+	}
+}
+
+func TestCloudEventType(t *testing.T) {
+	finished := time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		n    notification
+		want string
+	}{
+		{notification{Finished: finished}, "run.succeeded"},
+		{notification{Finished: finished, Failed: true}, "run.failed"},
+		{notification{Finished: finished, Recovered: 3}, "run.recovered"},
+		{notification{Running: true}, "run.running"},
+		{notification{Title: "3 commands finished"}, "digest"},
+	} {
+		if got := cloudEventType(tt.n); got != cloudEventTypePrefix+tt.want {
+			t.Errorf("cloudEventType(%+v) = %q, want %q", tt.n, got, cloudEventTypePrefix+tt.want)
+		}
+	}
+}
+
+func TestNewCloudEventID(t *testing.T) {
+	n := notification{Title: "Build", Subtitle: "make", Finished: time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC), Duration: time.Minute}
+	a, err := newCloudEvent(1, n, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newCloudEvent(1, n, time.Now().Add(time.Hour))
+	if a.ID != b.ID {
+		t.Errorf("IDs for a resend differ: %q, %q", a.ID, b.ID)
+	}
+	n.ExitCode, n.Failed = 1, true
+	if c, _ := newCloudEvent(1, n, time.Now()); c.ID == a.ID {
+		t.Errorf("different runs share ID %q", a.ID)
+	}
+}
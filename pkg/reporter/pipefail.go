@@ -0,0 +1,141 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// -pipefail runs a -c command string with set -o pipefail, so that in
+// `curl ... | tar x` a failed download is not masked by tar succeeding: the
+// pipeline fails with the status of its last stage to fail. bash also
+// reports what each stage of the last pipeline returned, through an EXIT
+// trap, and the notification then says which stage failed. sh, dash, and
+// fish have no pipefail, and the command runs as it is.
+
+// pipeStatusEnv names the file bash's EXIT trap writes the last pipeline's
+// statuses to.
+const pipeStatusEnv = "REPORTER_PIPESTATUS"
+
+// pipefailPreludes are what sets pipefail in each shell that has it, by
+// name; it goes on the script's first line so line numbers in errors stay
+// right.
+var pipefailPreludes = map[string]string{
+	"bash": `set -o pipefail; trap 'printf "%s\n" "${PIPESTATUS[*]}" >"${` + pipeStatusEnv + `:-/dev/null}"' EXIT; `,
+	"zsh":  "set -o pipefail; ",
+	"ksh":  "set -o pipefail; ",
+	"mksh": "set -o pipefail; ",
+}
+
+// pipefailArgs returns args, as shellArgs returns them, with pipefail set
+// in the script, and whether the shell could do so.
+func pipefailArgs(args []string) ([]string, bool) {
+	prelude, ok := pipefailPreludes[filepath.Base(args[0])]
+	if !ok || len(args) != 3 || args[1] != "-c" {
+		return args, false
+	}
+	return []string{args[0], "-c", prelude + args[2]}, true
+}
+
+// pipelineStages splits script into the commands of its pipeline, or
+// returns nil unless script is one pipeline of at least two stages. Quotes,
+// escapes, and brackets are respected, so `a "x|y" | $(b | c)` has two
+// stages.
+func pipelineStages(script string) []string {
+	var stages []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '`':
+			end := strings.IndexByte(script[i+1:], '`')
+			if end < 0 {
+				return nil
+			}
+			i += 1 + end
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		case depth > 0:
+		case c == '&' && (i > 0 && (script[i-1] == '>' || script[i-1] == '<') || i+1 < len(script) && script[i+1] == '>'):
+			// 2>&1 or &>file, a redirection
+		case c == ';' || c == '&' || c == '\n':
+			return nil
+		case c == '|':
+			if i+1 < len(script) && script[i+1] == '|' {
+				return nil // ||, a list rather than a pipeline
+			}
+			stages = append(stages, strings.TrimSpace(script[start:i]))
+			if i+1 < len(script) && script[i+1] == '&' {
+				i++ // |&, which pipes stderr too
+			}
+			start = i + 1
+		}
+	}
+	stages = append(stages, strings.TrimSpace(script[start:]))
+	if len(stages) < 2 || quote != 0 || depth != 0 {
+		return nil
+	}
+	for _, s := range stages {
+		if s == "" {
+			return nil
+		}
+	}
+	return stages
+}
+
+// failedStage describes the stage of the last pipeline that made a run
+// exit with exitCode, such as "stage 1 of 2 failed (curl -fsS $URL)", given
+// the statuses bash recorded and the script's stages, if known. It returns
+// "" when the statuses do not account for exitCode, as when the script
+// exited on its own or its last pipeline was a single command.
+func failedStage(statuses string, exitCode int, stages []string) string {
+	fields := strings.Fields(statuses)
+	if len(fields) < 2 || exitCode == 0 {
+		return ""
+	}
+	failed := -1
+	for i, f := range fields {
+		code, err := strconv.Atoi(f)
+		if err != nil {
+			return ""
+		}
+		if code != 0 {
+			failed = i
+		}
+	}
+	if failed < 0 || fields[failed] != strconv.Itoa(exitCode) {
+		return ""
+	}
+	desc := fmt.Sprintf("stage %d of %d failed", failed+1, len(fields))
+	if len(stages) == len(fields) {
+		desc += " (" + stages[failed] + ")"
+	}
+	return desc
+}
+
+// readFailedStage reads the statuses bash left in path after a run that
+// exited with exitCode, for failedStage.
+func readFailedStage(path string, exitCode int, stages []string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return failedStage(string(data), exitCode, stages)
+}
@@ -0,0 +1,87 @@
+package reporter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipelineStages(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{script: "curl -fsS $URL | tar xz", want: []string{"curl -fsS $URL", "tar xz"}},
+		{script: `grep "a|b" log |& sort | uniq -c 2>&1`, want: []string{`grep "a|b" log`, "sort", "uniq -c 2>&1"}},
+		{script: "echo $(ls | wc -l) 'x|y' | cat", want: []string{"echo $(ls | wc -l) 'x|y'", "cat"}},
+		{script: "echo `ls | wc -l` | cat", want: []string{"echo `ls | wc -l`", "cat"}},
+		{script: "make", want: nil},
+		{script: "make || true", want: nil},
+		{script: "a | b; c | d", want: nil},
+		{script: "a | b && c", want: nil},
+		{script: "a | b &", want: nil},
+		{script: "a |", want: nil},
+		{script: "echo 'a | b", want: nil},
+	}
+	for _, tt := range tests {
+		if got := pipelineStages(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pipelineStages(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestFailedStage(t *testing.T) {
+	stages := []string{"curl -fsS $URL", "tar xz"}
+	tests := []struct {
+		statuses string
+		exitCode int
+		stages   []string
+		want     string
+	}{
+		{statuses: "22 0\n", exitCode: 22, stages: stages, want: "stage 1 of 2 failed (curl -fsS $URL)"},
+		{statuses: "22 2\n", exitCode: 2, stages: stages, want: "stage 2 of 2 failed (tar xz)"},
+		{statuses: "0 1 0\n", exitCode: 1, want: "stage 2 of 3 failed"},
+		{statuses: "1 0\n", exitCode: 5, stages: stages}, // the script exited on its own
+		{statuses: "1\n", exitCode: 1},                   // not a pipeline
+		{statuses: "0 0\n", exitCode: 0, stages: stages}, // succeeded
+		{statuses: "", exitCode: 1, stages: stages},      // an EXIT trap of the script's own
+		{statuses: "x 1\n", exitCode: 1, stages: stages}, // garbled
+	}
+	for _, tt := range tests {
+		if got := failedStage(tt.statuses, tt.exitCode, tt.stages); got != tt.want {
+			t.Errorf("failedStage(%q, %d) = %q, want %q", tt.statuses, tt.exitCode, got, tt.want)
+		}
+	}
+}
+
+func TestPipefailArgs(t *testing.T) {
+	if args, ok := pipefailArgs([]string{"/bin/dash", "-c", "false | true"}); ok || !reflect.DeepEqual(args, []string{"/bin/dash", "-c", "false | true"}) {
+		t.Errorf("pipefailArgs() for dash = %q, %v; want it unchanged", args, ok)
+	}
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	args, ok := pipefailArgs([]string{bash, "-c", "echo hi | (exit 3) | cat >/dev/null"})
+	if !ok {
+		t.Fatalf("pipefailArgs() for bash = %q, not ok", args)
+	}
+	status := filepath.Join(t.TempDir(), "pipestatus")
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), pipeStatusEnv+"="+status)
+	exitCode, _, err := exitStatus(cmd.Run())
+	if err != nil || exitCode != 3 {
+		t.Errorf("exit status = %d, %v; want 3 from the failed stage", exitCode, err)
+	}
+	if got := readFailedStage(status, exitCode, pipelineStages("echo hi | (exit 3) | cat >/dev/null")); got != "stage 2 of 3 failed ((exit 3))" {
+		t.Errorf("readFailedStage() = %q", got)
+	}
+	// Without the file, the trap writes nowhere, quietly.
+	out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if strings.TrimSpace(string(out)) != "" {
+		t.Errorf("output without %s = %q, %v", pipeStatusEnv, out, err)
+	}
+}
@@ -0,0 +1,86 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Values for the block_action config key.
+const (
+	blockActionForce  = "force"  // blocked commands run only with -force
+	blockActionRefuse = "refuse" // blocked commands never run
+)
+
+// defaultBlockPatterns apply when no config file sets block. They catch the
+// classic copy-paste disasters; set block = [] to disable them.
+var defaultBlockPatterns = []string{
+	// rm of the filesystem root, whatever the flags.
+	`\brm\s+(-\S+\s+)*(/|/\*)(\s|;|&|\||$)`,
+	// Fork bombs such as :(){ :|:& };:
+	`([\w:]+)\s*\(\)\s*\{\s*[\w:]+\s*\|\s*[\w:]+\s*&\s*;?\s*\}`,
+	// Formatting or overwriting a raw disk.
+	`\bmkfs(\.\w+)?\s+.*/dev/`,
+	`\bdd\s+.*\bof=/dev/(sd|hd|vd|xvd|nvme|disk|mmcblk)`,
+}
+
+// blockPatterns returns the configured block patterns, or the defaults if no
+// config file sets any.
+func blockPatterns(cfg *config) []string {
+	if _, ok := cfg.values["block"]; ok {
+		return cfg.strings("block")
+	}
+	return defaultBlockPatterns
+}
+
+// matchingPattern returns the first of patterns that matches command, or ""
+// if none does. Runs of whitespace are collapsed first so spacing cannot
+// dodge a pattern.
+func matchingPattern(command string, patterns []string) (string, error) {
+	normalized := strings.Join(strings.Fields(command), " ")
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+		if re.MatchString(normalized) {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// defaultNotifyDeny applies when no config file sets notify_deny. It mutes
+// the shell hook for interactive programs, which run "long" by definition;
+// set notify_deny = [] to hear about them too.
+var defaultNotifyDeny = []string{
+	// Editors and pagers.
+	`^(sudo )?(vim?|nvim|nano|emacs|micro|hx|less|more|man)\b`,
+	// Remote shells and terminal multiplexers.
+	`^(ssh|mosh|et|tmux|screen|zellij)\b`,
+	// Monitors that run until quit.
+	`^(sudo )?(top|htop|btop|watch)\b`,
+	`^(tail|journalctl|kubectl logs) .*(-f|-F|--follow)\b`,
+}
+
+// notifyDenyPatterns returns the configured notify_deny patterns, or the
+// defaults if no config file sets any.
+func notifyDenyPatterns(cfg *config) []string {
+	if _, ok := cfg.values["notify_deny"]; ok {
+		return cfg.strings("notify_deny")
+	}
+	return defaultNotifyDeny
+}
+
+// hookMuted reports whether the shell hook should stay quiet about command:
+// it matches a notify_deny pattern and no notify_allow pattern. Allow
+// patterns override deny patterns, so notify_deny = [".*"] with a list of
+// allowed commands only ever notifies about those.
+func hookMuted(command string, allow, deny []string) (bool, error) {
+	denied, err := matchingPattern(command, deny)
+	if err != nil || denied == "" {
+		return false, err
+	}
+	allowed, err := matchingPattern(command, allow)
+	return allowed == "", err
+}
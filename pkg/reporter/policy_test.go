@@ -0,0 +1,125 @@
+package reporter
+
+import "testing"
+
+func TestBlockedByDefaults(t *testing.T) {
+	tests := []struct {
+		command string
+		blocked bool
+	}{
+		{command: "rm -rf /", blocked: true},
+		{command: "sudo rm  -rf   --no-preserve-root /", blocked: true},
+		{command: "rm -fr /*", blocked: true},
+		{command: "make clean && rm -rf / ; echo done", blocked: true},
+		{command: ":(){ :|:& };:", blocked: true},
+		{command: "bomb() { bomb | bomb & }; bomb", blocked: true},
+		{command: "mkfs.ext4 /dev/sda1", blocked: true},
+		{command: "dd if=image.iso of=/dev/sdb bs=4M", blocked: true},
+		{command: "rm -rf ./build", blocked: false},
+		{command: "rm -rf /tmp/cache", blocked: false},
+		{command: "dd if=/dev/zero of=disk.img bs=1M count=10", blocked: false},
+		{command: "make test", blocked: false},
+	}
+
+	for _, tt := range tests {
+		got, err := matchingPattern(tt.command, defaultBlockPatterns)
+		if err != nil {
+			t.Fatalf("matchingPattern(%q) returned error: %v", tt.command, err)
+		}
+		if (got != "") != tt.blocked {
+			t.Errorf("matchingPattern(%q) = %q, want blocked %v", tt.command, got, tt.blocked)
+		}
+	}
+}
+
+func TestBlockPatternsFromConfig(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "block = [\"^terraform destroy\"]\nblock_action = \"refuse\"\nlocked = [\"block\", \"block_action\"]\n")
+
+	cfg, err := loadConfig(base)
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+	patterns := blockPatterns(cfg)
+	if got, _ := matchingPattern("terraform  destroy -auto-approve", patterns); got != "^terraform destroy" {
+		t.Errorf("matchingPattern() = %q, want the configured pattern", got)
+	}
+	if got, _ := matchingPattern("rm -rf /", patterns); got != "" {
+		t.Errorf("configured block list still applies default %q", got)
+	}
+
+	writeFile(t, systemConfigPath, "block = []\n")
+	if cfg, err = loadConfig(base); err != nil {
+		t.Fatal(err)
+	}
+	if got := blockPatterns(cfg); len(got) != 0 {
+		t.Errorf("block = [] gives patterns %q, want none", got)
+	}
+}
+
+func TestBlockInvalidPattern(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "block = [\"rm (\"]\n")
+	if _, err := loadConfig(base); err == nil {
+		t.Error("loadConfig() accepted an invalid block pattern, want error")
+	}
+}
+
+func TestHookMuted(t *testing.T) {
+	tests := []struct {
+		command string
+		allow   []string
+		deny    []string
+		muted   bool
+	}{
+		{command: "vim main.go", deny: defaultNotifyDeny, muted: true},
+		{command: "sudo  nvim /etc/hosts", deny: defaultNotifyDeny, muted: true},
+		{command: "ssh build01", deny: defaultNotifyDeny, muted: true},
+		{command: "tmux attach", deny: defaultNotifyDeny, muted: true},
+		{command: "tail -f /var/log/syslog", deny: defaultNotifyDeny, muted: true},
+		{command: "kubectl logs api --follow", deny: defaultNotifyDeny, muted: true},
+		{command: "less README.md", deny: defaultNotifyDeny, muted: true},
+		{command: "make test", deny: defaultNotifyDeny, muted: false},
+		{command: "tail -n 20 build.log", deny: defaultNotifyDeny, muted: false},
+		{command: "manage.py migrate", deny: defaultNotifyDeny, muted: false},
+		{command: "view.sh", deny: defaultNotifyDeny, muted: false},
+		// Allow patterns override deny patterns.
+		{command: "cargo build", deny: []string{".*"}, allow: []string{"^make\\b", "^cargo build"}, muted: false},
+		{command: "git pull", deny: []string{".*"}, allow: []string{"^make\\b", "^cargo build"}, muted: true},
+		{command: "ssh build01 make", deny: defaultNotifyDeny, allow: []string{"\\bmake\\b"}, muted: false},
+		{command: "vim main.go", muted: false},
+	}
+	for _, tt := range tests {
+		muted, err := hookMuted(tt.command, tt.allow, tt.deny)
+		if err != nil {
+			t.Fatalf("hookMuted(%q) returned error: %v", tt.command, err)
+		}
+		if muted != tt.muted {
+			t.Errorf("hookMuted(%q, allow %q, deny %q) = %v, want %v", tt.command, tt.allow, tt.deny, muted, tt.muted)
+		}
+	}
+}
+
+func TestNotifyDenyPatternsFromConfig(t *testing.T) {
+	base := isolateConfig(t)
+	cfg, err := loadConfig(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notifyDenyPatterns(cfg); len(got) != len(defaultNotifyDeny) {
+		t.Errorf("notify_deny unset gives %q, want the defaults", got)
+	}
+
+	writeFile(t, systemConfigPath, "notify_deny = []\n")
+	if cfg, err = loadConfig(base); err != nil {
+		t.Fatal(err)
+	}
+	if got := notifyDenyPatterns(cfg); len(got) != 0 {
+		t.Errorf("notify_deny = [] gives patterns %q, want none", got)
+	}
+
+	writeFile(t, systemConfigPath, "notify_allow = [\"make (\"]\n")
+	if _, err := loadConfig(base); err == nil {
+		t.Error("loadConfig() accepted an invalid notify_allow pattern, want error")
+	}
+}
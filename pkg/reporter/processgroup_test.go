@@ -0,0 +1,103 @@
+//go:build !windows
+
+package reporter
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProcessGroupSignal(t *testing.T) {
+	// The shell's background sleep is in its group, so SIGTERM must reach
+	// it even though the shell does not pass it on.
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30; wait")
+	group, err := startInProcessGroup(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pgid, err := syscall.Getpgid(cmd.Process.Pid); err != nil || pgid != cmd.Process.Pid {
+		t.Errorf("command's process group = %d (%v), want its own, %d", pgid, err, cmd.Process.Pid)
+	}
+	time.Sleep(100 * time.Millisecond) // let the shell start both sleeps
+	group.signal(syscall.SIGTERM)
+	exitCode, sig, err := group.wait(cmd)
+	if err != nil || exitCode != 128+int(syscall.SIGTERM) || sig != syscall.SIGTERM {
+		t.Errorf("wait() = %d, %v, %v, want 143, terminated", exitCode, sig, err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); syscall.Kill(-cmd.Process.Pid, 0) == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Error("process group still has members after SIGTERM")
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			break
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		script string
+		want   int
+		sig    syscall.Signal
+	}{
+		{script: "exit 0", want: 0},
+		{script: "exit 3", want: 3},
+		{script: "kill -KILL $$", want: 137, sig: syscall.SIGKILL},
+	} {
+		exitCode, sig, err := exitStatus(exec.Command("sh", "-c", tt.script).Run())
+		if err != nil || exitCode != tt.want || sig != tt.sig {
+			t.Errorf("exitStatus(%q) = %d, %v, %v, want %d, %v", tt.script, exitCode, sig, err, tt.want, tt.sig)
+		}
+	}
+	if _, _, err := exitStatus(exec.Command("/nonexistent/command").Run()); err == nil {
+		t.Error("exitStatus() of a command that did not start returned no error")
+	}
+}
+
+func TestRunReportsSignals(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	report := filepath.Join(t.TempDir(), "report.json")
+	for _, tt := range []struct {
+		signal           string
+		ignoreInterrupts bool
+		wantCode         int
+		wantNotified     bool
+	}{
+		{signal: "KILL", wantCode: 137, wantNotified: true},
+		{signal: "INT", wantCode: 130, wantNotified: true},
+		{signal: "INT", ignoreInterrupts: true, wantCode: 130},
+		{signal: "KILL", ignoreInterrupts: true, wantCode: 137, wantNotified: true},
+	} {
+		opts := options{always: true, quiet: true, reportJSON: report, ignoreInterrupts: tt.ignoreInterrupts}
+		code := runWithNotification([]string{"sh", "-c", "kill -" + tt.signal + " $$"}, "oom", opts)
+		data, err := os.ReadFile(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r runReport
+		json.Unmarshal(data, &r)
+		if code != tt.wantCode || r.Notified != tt.wantNotified {
+			t.Errorf("SIG%s with -ignore-interrupts=%t: exit %d, notified %t; want %d, %t", tt.signal, tt.ignoreInterrupts, code, r.Notified, tt.wantCode, tt.wantNotified)
+		}
+	}
+}
+
+func TestRunMeasuresResources(t *testing.T) {
+	// The shell waits for the busy loop, so its CPU time is the shell's
+	// children's and still counts.
+	cmd := exec.Command("sh", "-c", "(i=0; while [ $i -lt 200000 ]; do i=$((i+1)); done)")
+	group, err := startInProcessGroup(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := group.wait(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if u := group.usage; u.UserCPU+u.SystemCPU <= 0 || u.MaxRSS < 1e5 {
+		t.Errorf("usage = %+v, want CPU time and a peak RSS", u)
+	}
+}
@@ -0,0 +1,221 @@
+//go:build !windows
+
+package reporter
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// The wrapped command runs in a process group of its own, so the signals
+// reporter passes on reach everything it started, such as the compilers
+// under make -j or the scripts npm runs, not only the command itself. When
+// reporter runs in the foreground of a terminal, the command's group is
+// made the terminal's foreground job, as a shell does: Ctrl-C, Ctrl-\, and
+// window resizes go straight to it, and it can read the terminal. Ctrl-Z
+// stops the command and then reporter, which hands the terminal back to the
+// shell, and fg or bg resumes both.
+
+// forwardedSignals are the signals reporter passes on to the command.
+var forwardedSignals = []os.Signal{
+	syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT,
+	syscall.SIGTSTP, syscall.SIGCONT, syscall.SIGWINCH, syscall.SIGUSR1, syscall.SIGUSR2,
+}
+
+// processGroup is the process group of a running command, which the
+// command leads.
+type processGroup struct {
+	mu  sync.Mutex
+	pid int
+	// terminal is set when the command reads reporter's terminal, and tty
+	// while its group is the terminal's foreground job.
+	terminal bool
+	tty      bool
+	pty      *ptySession // with -pty, whose raw mode is undone while stopped
+	// usage is what the command used, once wait returns.
+	usage resourceUsage
+}
+
+// startInProcessGroup starts cmd in a new process group, in the
+// terminal's foreground if reporter is there and cmd reads the terminal.
+func startInProcessGroup(cmd *exec.Cmd) (*processGroup, error) {
+	g := &processGroup{terminal: cmd.Stdin == os.Stdin && isTerminal(os.Stdin)}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	if g.terminal && ownsTerminal() {
+		// The child's stdin, fd 0, is the terminal.
+		cmd.SysProcAttr.Foreground, cmd.SysProcAttr.Ctty = true, 0
+		g.tty = true
+	}
+	if err := cmd.Start(); err != nil {
+		g.reclaimTerminal()
+		return nil, err
+	}
+	g.pid = cmd.Process.Pid
+	return g, nil
+}
+
+// ptyProcessGroup returns the group of cmd, started by startPTY, which
+// leads a session and so a group of its own.
+func ptyProcessGroup(cmd *exec.Cmd, pty *ptySession) *processGroup {
+	return &processGroup{pid: cmd.Process.Pid, pty: pty}
+}
+
+// signal sends sig to every process in the group.
+func (g *processGroup) signal(sig os.Signal) {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return
+	}
+	if s == syscall.SIGWINCH && g.pty != nil {
+		return // resizing the pseudo-terminal signals the command itself
+	}
+	// While suspend is resuming the command, wait for it to hand over the
+	// terminal first.
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_ = syscall.Kill(-g.pid, s)
+}
+
+// wait waits for the command to exit, suspending reporter while the
+// command is stopped by job control (see suspend), and returns the status a
+// shell would show and the signal that killed it, if any, recording what
+// it used in g.usage. cmd.Wait is called too, to finish copying output.
+func (g *processGroup) wait(cmd *exec.Cmd) (exitCode int, sig syscall.Signal, err error) {
+	var ws syscall.WaitStatus
+	var ru syscall.Rusage
+	for {
+		_, err = syscall.Wait4(g.pid, &ws, syscall.WUNTRACED, &ru)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil || !ws.Stopped() {
+			break
+		}
+		g.suspend(ws.StopSignal())
+	}
+	g.mu.Lock()
+	g.reclaimTerminal()
+	g.mu.Unlock()
+	if err != nil {
+		// Someone else collected the status; let os/exec report it.
+		exitCode, sig, err = exitStatus(cmd.Wait())
+		if cmd.ProcessState != nil {
+			if ru, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+				g.usage = rusageResources(ru)
+			}
+		}
+		return exitCode, sig, err
+	}
+	g.usage = rusageResources(&ru)
+	// The status is already collected, so cmd.Wait only reports that there
+	// is none left once output is copied.
+	_ = cmd.Wait()
+	if ws.Signaled() {
+		sig = ws.Signal()
+	}
+	return waitExitCode(ws), sig, nil
+}
+
+// rusageResources converts the usage the kernel reports for a child.
+func rusageResources(ru *syscall.Rusage) resourceUsage {
+	// ru_maxrss is in kilobytes, except on Apple's systems.
+	rss := int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		rss *= 1024
+	}
+	return resourceUsage{
+		UserCPU:   time.Duration(ru.Utime.Nano()),
+		SystemCPU: time.Duration(ru.Stime.Nano()),
+		MaxRSS:    rss,
+	}
+}
+
+// suspend stops reporter, with the rest of its job, after the command was
+// stopped by sig, and resumes the command when reporter is continued.
+// Only stops the command's job would get from the terminal count, like
+// Ctrl-Z or reading the terminal from the background, and SIGSTOP raised
+// by a program in the foreground that handles Ctrl-Z itself.
+func (g *processGroup) suspend(sig syscall.Signal) {
+	switch {
+	case sig == syscall.SIGTSTP || sig == syscall.SIGTTIN || sig == syscall.SIGTTOU:
+	case sig == syscall.SIGSTOP && (g.tty || g.pty != nil):
+	default:
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reclaimTerminal()
+	if g.pty != nil {
+		g.pty.suspend()
+	}
+	// SIGSTOP takes effect on reporter's other threads first, so this one
+	// could run on for a moment; wait until it is really continued.
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+	defer signal.Stop(cont)
+	_ = syscall.Kill(0, syscall.SIGSTOP)
+	<-cont
+	// Continued by fg, which also makes reporter's job the foreground
+	// one, or by bg, which does not.
+	if g.pty != nil {
+		g.pty.resume()
+	}
+	if g.terminal && ownsTerminal() {
+		g.tty = setForeground(g.pid) == nil
+	}
+	_ = syscall.Kill(-g.pid, syscall.SIGCONT)
+}
+
+// reclaimTerminal makes reporter's own group the terminal's foreground
+// job again, if the command's group still is. Call it with g.mu held.
+func (g *processGroup) reclaimTerminal() {
+	if !g.tty {
+		return
+	}
+	g.tty = false
+	if pgrp, err := foregroundGroup(); err == nil && (pgrp == g.pid || g.pid == 0) {
+		_ = setForeground(syscall.Getpgrp())
+	}
+}
+
+// ownsTerminal reports whether reporter's group is the foreground job of
+// the terminal on stdin.
+func ownsTerminal() bool {
+	pgrp, err := foregroundGroup()
+	return err == nil && pgrp == syscall.Getpgrp()
+}
+
+// foregroundGroup returns the foreground process group of the terminal on
+// stdin, as tcgetpgrp(3) does.
+func foregroundGroup() (int, error) {
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return 0, errno
+	}
+	return int(pgrp), nil
+}
+
+// setForeground makes pgrp the foreground process group of the terminal on
+// stdin, as tcsetpgrp(3) does. Reporter may no longer be in the foreground
+// itself, so SIGTTOU, which would stop it, is ignored meanwhile.
+func setForeground(pgrp int) error {
+	if !signal.Ignored(syscall.SIGTTOU) {
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+	}
+	id := int32(pgrp)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&id))); errno != 0 {
+		return errno
+	}
+	return nil
+}
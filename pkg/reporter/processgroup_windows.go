@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Windows has no process groups to signal: Ctrl-C reaches every process
+// attached to the console, and the signals reporter is sent are passed on
+// to the command alone.
+
+// forwardedSignals are the signals reporter passes on to the command.
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// processGroup is a running command.
+type processGroup struct {
+	p *os.Process
+	// usage is the CPU time the command used, once wait returns; Windows
+	// does not report a process's peak memory after it exits.
+	usage resourceUsage
+}
+
+// startInProcessGroup starts cmd.
+func startInProcessGroup(cmd *exec.Cmd) (*processGroup, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &processGroup{p: cmd.Process}, nil
+}
+
+// ptyProcessGroup returns the group of cmd started by startPTY, which
+// Windows does not support.
+func ptyProcessGroup(cmd *exec.Cmd, pty *ptySession) *processGroup {
+	return &processGroup{p: cmd.Process}
+}
+
+// signal sends sig to the command.
+func (g *processGroup) signal(sig os.Signal) {
+	_ = g.p.Signal(sig)
+}
+
+// wait waits for the command to exit and returns its exit code,
+// recording what it used in g.usage.
+func (g *processGroup) wait(cmd *exec.Cmd) (exitCode int, sig syscall.Signal, err error) {
+	exitCode, sig, err = exitStatus(cmd.Wait())
+	if cmd.ProcessState != nil {
+		if ru, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+			g.usage = resourceUsage{UserCPU: filetimeDuration(ru.UserTime), SystemCPU: filetimeDuration(ru.KernelTime)}
+		}
+	}
+	return exitCode, sig, err
+}
+
+// filetimeDuration converts a FILETIME holding a span of time, in 100ns
+// units.
+func filetimeDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(int64(ft.HighDateTime)<<32|int64(ft.LowDateTime)) * 100
+}
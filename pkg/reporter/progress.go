@@ -0,0 +1,188 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// -progress keeps a single "running" notification up to date while a long
+// command runs, instead of sending a new one each time. Only backends that
+// can update a notification in place get the updates: desktop notifications
+// on Linux, in WSL, or through a host agent, and ntfy. The notification for
+// the finished run then replaces the running one. -heartbeat instead sends a
+// new notification each time, to every backend; with -stall, only while the
+// command has printed nothing for that long, so a job visibly making
+// progress stays quiet and one that has stalled is reported.
+
+// progressTitle is the title of the running notification.
+const progressTitle = "Running"
+
+// progressDesktop reports whether desktop notifications shown through agent,
+// or on this machine when agent is "", can be updated in place.
+func progressDesktop(agent string) bool {
+	return agent != "" || runtime.GOOS == "linux"
+}
+
+// progressTargets returns the push targets that can update a notification in
+// place.
+func progressTargets(targets []pushTarget) []pushTarget {
+	var updatable []pushTarget
+	for _, t := range targets {
+		if t.Provider == pushProviderNtfy {
+			updatable = append(updatable, t)
+		}
+	}
+	return updatable
+}
+
+// progressNotification renders the update for command, running for elapsed
+// so far. Notifications sharing key replace each other.
+func progressNotification(command, key string, elapsed time.Duration, labels map[string]string) notification {
+	return notification{
+		Title:    progressTitle,
+		Body:     fmt.Sprintf("%s elapsed", formatDuration(elapsed.Round(time.Second))),
+		Subtitle: command,
+		Running:  true,
+		Duration: elapsed,
+		DedupKey: key,
+		Labels:   labels,
+	}
+}
+
+// startProgress updates the running notification for command every
+// opts.progress, once the run has passed the threshold, until stop is called.
+// stop waits for an update in flight, so none lands after the notification
+// for the finished run.
+func startProgress(command, key string, start time.Time, opts options) (stop func()) {
+	// Terminal notifications cannot be updated in place.
+	desktop := opts.desktop && opts.terminalNotify == "" && progressDesktop(opts.agent)
+	if opts.progress <= 0 || opts.snoozed || (!desktop && len(progressTargets(opts.push)) == 0) {
+		return func() {}
+	}
+	warn := warnOnce("progress", opts.quiet)
+	return every(opts.progress, func(now time.Time) {
+		elapsed := now.Sub(start)
+		if elapsed < opts.threshold {
+			return
+		}
+		n := progressNotification(command, key, elapsed, opts.labels)
+		if desktop {
+			warn("desktop", showDesktop(opts.agent, "", n))
+		}
+		for _, t := range progressTargets(quietPush(opts, now).push) {
+			warn(t.Provider, pushOnce(t, n))
+		}
+	})
+}
+
+// heartbeatTitle is the title of heartbeat notifications.
+const heartbeatTitle = "Still running"
+
+// startHeartbeat sends a new "still running" notification for command to
+// every backend every opts.heartbeat, once the run has passed the threshold,
+// until stop is called. Unlike -progress, each heartbeat is a notification
+// of its own, so a job that has hung shows as heartbeats that stop coming.
+func startHeartbeat(command string, start time.Time, opts options) (stop func()) {
+	if opts.heartbeat <= 0 || opts.snoozed || (!opts.desktop && opts.multiplexer == "" && len(opts.push) == 0) {
+		return func() {}
+	}
+	warn := warnOnce("heartbeat", opts.quiet)
+	return every(opts.heartbeat, func(now time.Time) {
+		elapsed := now.Sub(start)
+		if elapsed < opts.threshold {
+			return
+		}
+		n := progressNotification(command, "", elapsed, opts.labels)
+		n.Title = heartbeatTitle
+		if opts.activity != nil {
+			quiet := opts.activity.quietFor(now)
+			if quiet < opts.stall {
+				return
+			}
+			n.Body += fmt.Sprintf(", no output for %s", formatDuration(quiet.Round(time.Second)))
+		}
+		sendRunning(n, opts, now, warn)
+	})
+}
+
+// outputActivity records when a command last printed anything, for -stall.
+// It is an io.Writer that output is copied to.
+type outputActivity struct {
+	last atomic.Int64 // Unix nanoseconds
+}
+
+// newOutputActivity returns an outputActivity that counts start as the last
+// output, so a command is not stalled before it has had time to print.
+func newOutputActivity(start time.Time) *outputActivity {
+	a := &outputActivity{}
+	a.last.Store(start.UnixNano())
+	return a
+}
+
+func (a *outputActivity) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		a.last.Store(time.Now().UnixNano())
+	}
+	return len(p), nil
+}
+
+// quietFor returns how long before now the command last printed.
+func (a *outputActivity) quietFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, a.last.Load()))
+}
+
+// sendRunning sends n, about a run still in progress, to every backend.
+// Such notifications are only worth sending now, so they are neither
+// retried nor queued.
+func sendRunning(n notification, opts options, now time.Time, warn func(backend string, err error)) {
+	if opts.desktop {
+		warn("desktop", showDesktop(opts.agent, opts.terminalNotify, n))
+	}
+	if opts.multiplexer != "" {
+		warn(opts.multiplexer, notifyMultiplexer(opts.multiplexer, false, n))
+	}
+	for _, t := range quietPush(opts, now).push {
+		warn(t.Provider, pushOnce(t, n))
+	}
+}
+
+// every calls fn on a ticker every interval, from a goroutine, until stop is
+// called. stop waits for a call in flight to return.
+func every(interval time.Duration, fn func(now time.Time)) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				fn(now)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// warnOnce returns a function that reports the first error from each backend
+// on stderr, prefixed with tag, unless quiet.
+func warnOnce(tag string, quiet bool) func(backend string, err error) {
+	warned := map[string]bool{}
+	return func(backend string, err error) {
+		if err != nil && !quiet && !warned[backend] {
+			fmt.Fprintf(os.Stderr, "[%s] %s: %v\n", tag, backend, err)
+			warned[backend] = true
+		}
+	}
+}
@@ -0,0 +1,183 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressTargets(t *testing.T) {
+	targets := []pushTarget{
+		{URL: "https://ntfy.sh/builds", Provider: pushProviderNtfy},
+		{URL: "https://hooks.slack.com/services/T/B/X", Provider: pushProviderSlack},
+		{URL: "https://ntfy.example.com/ops", Provider: pushProviderNtfy},
+	}
+	got := progressTargets(targets)
+	if len(got) != 2 || got[0].URL != targets[0].URL || got[1].URL != targets[2].URL {
+		t.Errorf("progressTargets() = %v, want the ntfy targets", got)
+	}
+}
+
+func TestStartProgress(t *testing.T) {
+	var mu sync.Mutex
+	var sequences []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sequences = append(sequences, r.Header.Get("X-Sequence-ID"))
+	}))
+	defer srv.Close()
+
+	opts := options{
+		progress: 10 * time.Millisecond,
+		push: []pushTarget{
+			{URL: srv.URL, Provider: pushProviderNtfy},
+			{URL: srv.URL, Provider: pushProviderSlack},
+		},
+	}
+	stop := startProgress("make test", "ci-7", time.Now(), opts)
+	time.Sleep(55 * time.Millisecond)
+	stop()
+	mu.Lock()
+	sent := len(sequences)
+	for _, seq := range sequences {
+		if seq != ntfySequenceID("ci-7") {
+			t.Errorf("update sent with sequence ID %q, want the one for the key", seq)
+		}
+	}
+	mu.Unlock()
+	if sent < 2 {
+		t.Errorf("sent %d updates in 55ms at 10ms intervals, want several, to ntfy only", sent)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sequences) != sent {
+		t.Errorf("%d updates sent after stop", len(sequences)-sent)
+	}
+}
+
+func TestStartProgressThreshold(t *testing.T) {
+	var mu sync.Mutex
+	sent := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent++
+	}))
+	defer srv.Close()
+
+	opts := options{
+		threshold: time.Hour,
+		progress:  5 * time.Millisecond,
+		push:      []pushTarget{{URL: srv.URL, Provider: pushProviderNtfy}},
+	}
+	stop := startProgress("make test", "ci-7", time.Now(), opts)
+	time.Sleep(30 * time.Millisecond)
+	stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if sent != 0 {
+		t.Errorf("sent %d updates before the threshold, want none", sent)
+	}
+}
+
+func TestStartHeartbeat(t *testing.T) {
+	var mu sync.Mutex
+	var titles, sequences []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, r.Header.Get("Title"))
+		sequences = append(sequences, r.Header.Get("X-Sequence-ID"))
+	}))
+	defer srv.Close()
+
+	opts := options{
+		heartbeat: 10 * time.Millisecond,
+		dedupKey:  "ci-7",
+		push:      []pushTarget{{URL: srv.URL, Provider: pushProviderNtfy}},
+	}
+	stop := startHeartbeat("make test", time.Now(), opts)
+	time.Sleep(55 * time.Millisecond)
+	stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(titles) < 2 {
+		t.Errorf("sent %d heartbeats in 55ms at 10ms intervals, want several", len(titles))
+	}
+	for i := range titles {
+		if titles[i] != heartbeatTitle || sequences[i] != "" {
+			t.Errorf("heartbeat titled %q with sequence ID %q, want a new %q notification each time", titles[i], sequences[i], heartbeatTitle)
+		}
+	}
+}
+
+func TestStartHeartbeatStall(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	activity := newOutputActivity(start)
+	opts := options{
+		heartbeat: 5 * time.Millisecond,
+		stall:     100 * time.Millisecond,
+		activity:  activity,
+		push:      []pushTarget{{URL: srv.URL, Provider: pushProviderNtfy}},
+	}
+	stop := startHeartbeat("make test", start, opts)
+
+	// Steady output holds heartbeats back.
+	for i := 0; i < 40; i++ {
+		fmt.Fprintln(activity, "ok")
+		time.Sleep(2 * time.Millisecond)
+	}
+	mu.Lock()
+	if len(bodies) != 0 {
+		t.Errorf("sent %d heartbeats while output was flowing, want none", len(bodies))
+	}
+	mu.Unlock()
+
+	// Once it stops, they come.
+	time.Sleep(250 * time.Millisecond)
+	stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) == 0 {
+		t.Fatal("sent no heartbeats after output stalled")
+	}
+	if !strings.Contains(bodies[0], "no output for") {
+		t.Errorf("heartbeat body %q does not say how long output has stalled", bodies[0])
+	}
+}
+
+func TestOutputActivity(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	a := newOutputActivity(start)
+	if got := a.quietFor(start.Add(time.Minute)); got != time.Minute {
+		t.Errorf("quietFor() before any output = %v, want the time since start", got)
+	}
+	a.Write(nil)
+	if got := a.quietFor(start.Add(time.Minute)); got != time.Minute {
+		t.Errorf("quietFor() after an empty write = %v, want it unchanged", got)
+	}
+	a.Write([]byte("building\n"))
+	if got := a.quietFor(time.Now()); got > time.Second {
+		t.Errorf("quietFor() right after output = %v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The prometheus_pushgateway and prometheus_textfile config keys export the
+// last run of each command, per host, for Prometheus to scrape: pushed to a
+// Pushgateway, or written to a file for node_exporter's textfile collector.
+//
+//	reporter_run_duration_seconds{command="make",host="build1",env="prod"} 184
+//	reporter_run_exit_code{command="make",host="build1",env="prod"} 2
+//	reporter_run_last_completion_timestamp_seconds{command="make",host="build1",env="prod"} 1760621463.5
+//
+// The command label is the program's name, as for statsd, and the run's
+// labels follow.
+
+// promMetrics are the metrics exported for a run, with their help text.
+var promMetrics = []struct{ name, help string }{
+	{"reporter_run_duration_seconds", "How long the last run of the command took."},
+	{"reporter_run_exit_code", "The exit code of the last run of the command."},
+	{"reporter_run_last_completion_timestamp_seconds", "When the last run of the command finished, as a Unix time."},
+}
+
+// promSink exports run metrics to a Pushgateway, a textfile, or both.
+type promSink struct {
+	gateway  *url.URL
+	job      string
+	textfile string
+}
+
+// newPromSink returns the sink for a Pushgateway at gateway and a textfile
+// at textfile, or nil if both are "".
+func newPromSink(gateway, job, textfile string) (*promSink, error) {
+	if gateway == "" && textfile == "" {
+		return nil, nil
+	}
+	s := &promSink{job: job, textfile: textfile}
+	if gateway != "" {
+		u, err := url.Parse(gateway)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid prometheus_pushgateway %q: want the gateway's URL, such as http://pushgateway:9091", gateway)
+		}
+		s.gateway = u
+	}
+	if textfile != "" && !strings.HasSuffix(textfile, ".prom") {
+		return nil, fmt.Errorf("invalid prometheus_textfile %q: the textfile collector only reads files ending in .prom", textfile)
+	}
+	if s.job == "" {
+		s.job = "reporter"
+	}
+	return s, nil
+}
+
+// promLabels returns the labels of res's series, command and host first,
+// then the run's own, sorted, with their names made valid.
+func promLabels(res runResult, host string) [][2]string {
+	labels := [][2]string{{"command", programName(res)}, {"host", host}}
+	keys := make([]string, 0, len(res.Labels))
+	for k := range res.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := promLabelName(k)
+		switch name {
+		case "command", "host", "job", "instance":
+			continue // reporter's own, or the gateway's
+		}
+		labels = append(labels, [2]string{name, res.Labels[k]})
+	}
+	return labels
+}
+
+// promLabelName replaces the characters a label name cannot have.
+func promLabelName(s string) string {
+	name := []byte(s)
+	for i, c := range name {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	if strings.HasPrefix(string(name), "__") {
+		return "l" + string(name) // __ is reserved
+	}
+	return string(name)
+}
+
+// promSelector renders labels as {a="b",...}.
+func promSelector(labels [][2]string) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(l[1])
+		parts[i] = l[0] + `="` + v + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// promValues returns the value of each of promMetrics for res, finished at
+// end.
+func promValues(res runResult, end time.Time) []string {
+	return []string{
+		strconv.FormatFloat(res.Duration.Seconds(), 'g', -1, 64),
+		strconv.Itoa(res.ExitCode),
+		strconv.FormatFloat(float64(end.UnixMilli())/1000, 'f', -1, 64),
+	}
+}
+
+// exposition renders series, metric name and labels to value, in the text
+// format, grouped by metric in promMetrics' order.
+func exposition(series map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, m := range promMetrics {
+		var names []string
+		for s := range series {
+			if strings.HasPrefix(s, m.name+"{") {
+				names = append(names, s)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, s := range names {
+			fmt.Fprintf(&buf, "%s %s\n", s, series[s])
+		}
+	}
+	return buf.Bytes()
+}
+
+// pushBody renders the metrics for res for the Pushgateway, without the
+// command and host labels, which the group's URL carries.
+func pushBody(res runResult, host string, end time.Time) []byte {
+	selector := promSelector(promLabels(res, host)[2:])
+	if selector == "{}" {
+		selector = ""
+	}
+	series := map[string]string{}
+	for i, v := range promValues(res, end) {
+		series[promMetrics[i].name+selector] = v
+	}
+	return exposition(series)
+}
+
+// groupURL returns the URL of the Pushgateway group holding the metrics of
+// program's runs on host: one group per command, so that each push replaces
+// that command's last run and leaves the others'.
+func (s *promSink) groupURL(program, host string) string {
+	path := strings.TrimSuffix(s.gateway.Path, "/") + "/metrics"
+	for _, l := range [][2]string{{"job", s.job}, {"host", host}, {"command", program}} {
+		if l[1] == "" || strings.Contains(l[1], "/") {
+			// A slash, even escaped, would end the value, and an empty
+			// one would be no value at all.
+			path += "/" + l[0] + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(l[1]))
+			if l[1] == "" {
+				path += "="
+			}
+			continue
+		}
+		path += "/" + l[0] + "/" + url.PathEscape(l[1])
+	}
+	u := *s.gateway
+	u.Path, u.RawPath = "", ""
+	return u.String() + path
+}
+
+// writeTextfile records res in the textfile, keeping the last run of every
+// other command and host already there.
+func (s *promSink) writeTextfile(res runResult, host string, end time.Time) error {
+	series := map[string]string{}
+	if f, err := os.Open(s.textfile); err == nil {
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := sc.Text()
+			if i := strings.LastIndexByte(line, ' '); i > 0 && !strings.HasPrefix(line, "#") {
+				series[line[:i]] = line[i+1:]
+			}
+		}
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	selector := promSelector(promLabels(res, host))
+	for i, v := range promValues(res, end) {
+		series[promMetrics[i].name+selector] = v
+	}
+	if err := os.MkdirAll(filepath.Dir(s.textfile), 0o755); err != nil {
+		return err
+	}
+	// The collector must never see a half-written file, so it is renamed
+	// into place; with concurrent runs the last rename wins, which at worst
+	// loses one run's update.
+	tmp := fmt.Sprintf("%s.%d.tmp", s.textfile, os.Getpid())
+	if err := os.WriteFile(tmp, exposition(series), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.textfile)
+}
+
+// record exports the metrics for res wherever the sink sends them.
+func (s *promSink) record(res runResult) error {
+	host, _ := os.Hostname()
+	end := time.Now()
+	var errs []error
+	if s.textfile != "" {
+		errs = append(errs, s.writeTextfile(res, host, end))
+	}
+	if s.gateway != nil {
+		errs = append(errs, s.push(s.groupURL(programName(res), host), pushBody(res, host, end)))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,30 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// push replaces the metrics of the Pushgateway group at groupURL with body.
+func (s *promSink) push(groupURL string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, groupURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushing to %s: %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
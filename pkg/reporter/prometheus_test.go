@@ -0,0 +1,109 @@
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPromTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics", "reporter.prom")
+	s, err := newPromSink("", "reporter", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := time.Unix(1760621463, 500e6)
+	make1 := runResult{Args: []string{"/usr/bin/make", "test"}, Duration: 1500 * time.Millisecond, ExitCode: 2,
+		Labels: map[string]string{"env": "prod", "bad-name": `a "b"`}}
+	if err := s.writeTextfile(make1, "build1", end); err != nil {
+		t.Fatalf("writeTextfile() returned error: %v", err)
+	}
+	if err := s.writeTextfile(runResult{Command: "go test ./...", Duration: time.Minute}, "build1", end); err != nil {
+		t.Fatalf("writeTextfile() returned error: %v", err)
+	}
+	// A later run of make replaces the first.
+	make1.ExitCode = 0
+	if err := s.writeTextfile(make1, "build1", end); err != nil {
+		t.Fatalf("writeTextfile() returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP reporter_run_duration_seconds How long the last run of the command took.
+# TYPE reporter_run_duration_seconds gauge
+reporter_run_duration_seconds{command="go",host="build1"} 60
+reporter_run_duration_seconds{command="make",host="build1",bad_name="a \"b\"",env="prod"} 1.5
+# HELP reporter_run_exit_code The exit code of the last run of the command.
+# TYPE reporter_run_exit_code gauge
+reporter_run_exit_code{command="go",host="build1"} 0
+reporter_run_exit_code{command="make",host="build1",bad_name="a \"b\"",env="prod"} 0
+# HELP reporter_run_last_completion_timestamp_seconds When the last run of the command finished, as a Unix time.
+# TYPE reporter_run_last_completion_timestamp_seconds gauge
+reporter_run_last_completion_timestamp_seconds{command="go",host="build1"} 1760621463.5
+reporter_run_last_completion_timestamp_seconds{command="make",host="build1",bad_name="a \"b\"",env="prod"} 1760621463.5
+`
+	if string(data) != want {
+		t.Errorf("textfile =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestPromGroupURL(t *testing.T) {
+	s, _ := newPromSink("http://pushgateway:9091/prefix/", "ci", "")
+	for _, tt := range []struct{ program, host, want string }{
+		{"make", "build1", "http://pushgateway:9091/prefix/metrics/job/ci/host/build1/command/make"},
+		{"my cmd", "", "http://pushgateway:9091/prefix/metrics/job/ci/host@base64/=/command/my%20cmd"},
+		{"a/b", "h", "http://pushgateway:9091/prefix/metrics/job/ci/host/h/command@base64/YS9i"},
+	} {
+		if got := s.groupURL(tt.program, tt.host); got != tt.want {
+			t.Errorf("groupURL(%q, %q) = %q, want %q", tt.program, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestNewPromSink(t *testing.T) {
+	for _, tt := range []struct {
+		gateway, textfile string
+		ok                bool
+	}{
+		{"http://pushgateway:9091", "", true},
+		{"", "/var/lib/node_exporter/reporter.prom", true},
+		{"pushgateway:9091", "", false},
+		{"", "/tmp/reporter.txt", false},
+	} {
+		if _, err := newPromSink(tt.gateway, "reporter", tt.textfile); (err == nil) != tt.ok {
+			t.Errorf("newPromSink(%q, %q) error = %v, want ok %v", tt.gateway, tt.textfile, err, tt.ok)
+		}
+	}
+	if s, err := newPromSink("", "reporter", ""); s != nil || err != nil {
+		t.Errorf("newPromSink(\"\", \"\") = %v, %v; want nil", s, err)
+	}
+}
+
+func TestPromPush(t *testing.T) {
+	if !pushCompiled {
+		t.Skip("built with -tags nopush")
+	}
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	s, _ := newPromSink(srv.URL, "reporter", "")
+	if err := s.record(runResult{Args: []string{"make"}, Duration: 2 * time.Second, ExitCode: 1, Labels: map[string]string{"env": "ci"}}); err != nil {
+		t.Fatalf("record() returned error: %v", err)
+	}
+	if method != http.MethodPut || !strings.HasSuffix(path, "/command/make") {
+		t.Errorf("request = %s %s, want PUT to the make group", method, path)
+	}
+	if !strings.Contains(body, "reporter_run_duration_seconds{env=\"ci\"} 2\n") || !strings.Contains(body, "reporter_run_exit_code{env=\"ci\"} 1\n") {
+		t.Errorf("body =\n%s\nwant the duration and exit code without command and host", body)
+	}
+}
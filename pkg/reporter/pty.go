@@ -0,0 +1,181 @@
+//go:build linux || darwin
+
+package reporter
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ptyDrainTimeout bounds how long output is drained after the command exits.
+// Background processes that inherited the terminal can otherwise keep it open
+// indefinitely.
+const ptyDrainTimeout = 250 * time.Millisecond
+
+// ptySession connects a command running on a pseudo-terminal to reporter's own
+// stdio.
+type ptySession struct {
+	master  *os.File
+	output  chan struct{} // closed when output copying stops
+	winch   chan os.Signal
+	restore func()
+	raw     *os.File // the terminal switched to raw mode, if any
+}
+
+// startPTY starts cmd with a new pseudo-terminal as its controlling terminal
+// and stdio. Output is copied to stdout. If stdin is non-nil it is forwarded to
+// the command; a terminal stdin is switched to raw mode so keystrokes, including
+// Ctrl-C, reach the command's line discipline unmodified.
+func startPTY(cmd *exec.Cmd, stdin *os.File, stdout io.Writer) (*ptySession, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer slave.Close()
+
+	s := &ptySession{master: master, output: make(chan struct{}), restore: func() {}}
+	if stdin != nil && isTerminal(stdin) {
+		copyWinsize(stdin, master)
+		if restore, err := makeRaw(stdin); err == nil {
+			s.restore, s.raw = restore, stdin
+		}
+	} else if stdin != nil {
+		// Piped input is not typed, so the terminal should not echo it
+		// back into the output.
+		disableEcho(master)
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if err := cmd.Start(); err != nil {
+		s.restore()
+		master.Close()
+		return nil, err
+	}
+
+	go func() {
+		defer close(s.output)
+		_, _ = io.Copy(stdout, master)
+	}()
+	if stdin != nil {
+		if isTerminal(stdin) {
+			s.winch = make(chan os.Signal, 1)
+			signal.Notify(s.winch, syscall.SIGWINCH)
+			go func() {
+				for range s.winch {
+					copyWinsize(stdin, master)
+				}
+			}()
+		}
+		go func() {
+			_, _ = io.Copy(master, stdin)
+			if !isTerminal(stdin) {
+				// Piped input ended: deliver end-of-file through the line
+				// discipline as a Ctrl-D would.
+				_, _ = master.Write([]byte{4})
+			}
+		}()
+	}
+	return s, nil
+}
+
+// suspend restores the terminal while reporter is stopped, as by Ctrl-Z,
+// and resume switches it back to raw mode.
+func (s *ptySession) suspend() {
+	s.restore()
+	s.restore = func() {}
+}
+
+func (s *ptySession) resume() {
+	if s.raw == nil {
+		return
+	}
+	if restore, err := makeRaw(s.raw); err == nil {
+		s.restore = restore
+	}
+	copyWinsize(s.raw, s.master)
+}
+
+// finish drains output written before the command exited and restores the
+// terminal. Call it after cmd.Wait returns.
+func (s *ptySession) finish() {
+	if s.winch != nil {
+		signal.Stop(s.winch)
+		close(s.winch)
+	}
+	if err := s.master.SetReadDeadline(time.Now().Add(ptyDrainTimeout)); err != nil {
+		s.master.Close()
+	}
+	<-s.output
+	s.master.Close()
+	s.restore()
+}
+
+func isTerminal(f *os.File) bool {
+	var t syscall.Termios
+	return ioctl(f.Fd(), ioctlGetTermios, unsafe.Pointer(&t)) == nil
+}
+
+// makeRaw puts the terminal into raw mode, as cfmakeraw(3) does, and returns a
+// function that restores the previous settings.
+func makeRaw(f *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := ioctl(f.Fd(), ioctlGetTermios, unsafe.Pointer(&old)); err != nil {
+		return nil, err
+	}
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(f.Fd(), ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return func() { _ = ioctl(f.Fd(), ioctlSetTermios, unsafe.Pointer(&old)) }, nil
+}
+
+func disableEcho(f *os.File) {
+	var t syscall.Termios
+	if ioctl(f.Fd(), ioctlGetTermios, unsafe.Pointer(&t)) == nil {
+		t.Lflag &^= syscall.ECHO
+		_ = ioctl(f.Fd(), ioctlSetTermios, unsafe.Pointer(&t))
+	}
+}
+
+type winsize struct {
+	Row, Col, Xpixel, Ypixel uint16
+}
+
+// copyWinsize gives the pseudo-terminal the window size of the real terminal
+// so full-screen tools and progress bars lay out correctly.
+func copyWinsize(from, to *os.File) {
+	var ws winsize
+	if ioctl(from.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&ws)) == nil {
+		_ = ioctl(to.Fd(), syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+	}
+}
+
+// terminalSize returns the size of the terminal f, or 80 by 24 if it is
+// unknown.
+func terminalSize(f *os.File) (cols, rows int) {
+	var ws winsize
+	if ioctl(f.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&ws)) != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+func ioctl(fd, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
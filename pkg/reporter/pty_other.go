@@ -0,0 +1,32 @@
+//go:build !linux && !darwin
+
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+type ptySession struct{}
+
+func startPTY(cmd *exec.Cmd, stdin *os.File, stdout io.Writer) (*ptySession, error) {
+	return nil, fmt.Errorf("pseudo-terminals are not supported on %s", runtime.GOOS)
+}
+
+func (s *ptySession) finish()  {}
+func (s *ptySession) suspend() {}
+func (s *ptySession) resume()  {}
+
+func makeRaw(f *os.File) (func(), error) {
+	return nil, fmt.Errorf("raw terminal mode is not supported on %s", runtime.GOOS)
+}
+
+func terminalSize(f *os.File) (cols, rows int) { return 80, 24 }
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,65 @@
+//go:build linux || darwin
+
+package reporter
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestStartPTY(t *testing.T) {
+	if _, err := os.Stat("/dev/ptmx"); err != nil {
+		t.Skip("no /dev/ptmx")
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		stdin  string
+		want   string
+	}{
+		{name: "stdout is a terminal", script: "test -t 1 && echo tty", want: "tty"},
+		{name: "stderr is a terminal", script: "test -t 2 && echo tty >&2", want: "tty"},
+		{name: "controlling terminal", script: "exec </dev/tty && echo ok", want: "ok"},
+		{name: "piped input reaches the command", script: "read line && echo got $line", stdin: "hello\n", want: "got hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdin *os.File
+			if tt.stdin != "" {
+				r, w, err := os.Pipe()
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer r.Close()
+				go func() {
+					w.WriteString(tt.stdin)
+					w.Close()
+				}()
+				stdin = r
+			}
+
+			var out bytes.Buffer
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			s, err := startPTY(cmd, stdin, &out)
+			if err != nil {
+				t.Fatalf("startPTY() returned error: %v", err)
+			}
+			err = cmd.Wait()
+			s.finish()
+			if err != nil {
+				t.Fatalf("command failed: %v (output %q)", err, out.String())
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.want)
+			}
+			if tt.stdin != "" && strings.Contains(out.String(), tt.stdin) {
+				t.Errorf("output = %q echoes piped input", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// warmConnTTL bounds how long a pre-dialed connection is handed out. Servers
+// commonly drop idle connections that never sent a request after a minute.
+const warmConnTTL = 30 * time.Second
+
+var pushDialer = &warmDialer{}
+
+// pushClient is shared by all pushes so they benefit from prewarmPush.
+var pushClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         pushDialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// pushCompiled reports whether this build can send pushes.
+const pushCompiled = true
+
+// pushTimeout bounds each attempt at a push.
+const pushTimeout = 5 * time.Second
+
+// pushRetries is how many times a push that failed for a transient reason,
+// such as a dropped connection or a 503, is tried again. The waits between
+// attempts double from pushBackoff.
+const pushRetries = 2
+
+var pushBackoff = 500 * time.Millisecond
+
+// pushToPhone delivers n through target's provider, retrying transient
+// failures. It does nothing when no push URL is configured.
+func pushToPhone(target pushTarget, n notification) error {
+	return pushAttempts(target, n, pushRetries)
+}
+
+// pushOnce is pushToPhone without retries, for resending spooled pushes.
+func pushOnce(target pushTarget, n notification) error {
+	return pushAttempts(target, n, 0)
+}
+
+func pushAttempts(target pushTarget, n notification, retries int) error {
+	if target.URL == "" {
+		return nil
+	}
+	spec, ok := pushProviders[target.Provider]
+	if !ok {
+		return fmt.Errorf("unknown push provider %q", target.Provider)
+	}
+	p, err := spec.new(target)
+	if err != nil {
+		return fmt.Errorf("%s: %w", target.Provider, err)
+	}
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		err = p.Push(ctx, n)
+		cancel()
+		if err == nil || attempt == retries || !pushRetryable(err) {
+			return err
+		}
+		time.Sleep(pushBackoff << attempt)
+	}
+}
+
+// statusError is a push rejected with a non-2xx response.
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// pushRetryable reports whether a push that failed with err may succeed if
+// sent again: network failures, timeouts, and server-side errors. Other
+// rejections, such as a bad token, would only be rejected again.
+func pushRetryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusRequestTimeout || se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// newTextPush builds a POST of a plain-text body to rawURL with the common
+// reporter headers (see newPushRequest).
+func newTextPush(ctx context.Context, rawURL string, target pushTarget, n notification, body string) (*http.Request, error) {
+	return newPushRequest(ctx, rawURL, target, n, "text/plain", strings.NewReader(body))
+}
+
+// newPushRequest builds a POST of body to rawURL carrying the headers every
+// generic provider sends: authorization, the dedup key, and labels.
+func newPushRequest(ctx context.Context, rawURL string, target pushTarget, n notification, contentType string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s: %w", rawURL, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if auth := target.authorization(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if n.DedupKey != "" {
+		req.Header.Set("X-Reporter-Dedup-Key", n.DedupKey)
+	}
+	if len(n.Labels) > 0 {
+		req.Header.Set("X-Reporter-Labels", formatLabels(n.Labels))
+	}
+	return req, nil
+}
+
+// sendPush performs req with the shared client, treating any non-2xx
+// response as an error.
+func sendPush(req *http.Request) error {
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &statusError{resp.StatusCode, fmt.Errorf("push to %s returned %s", req.URL, resp.Status)}
+	}
+	return nil
+}
+
+// codeFence wraps s in a ``` code block of at most limit characters. Neither
+// Slack nor Discord can escape backticks inside one, so runs of three are
+// broken up with a zero-width space.
+func codeFence(s string, limit int) string {
+	const fence = "```"
+	r := []rune(strings.ReplaceAll(s, fence, "``\u200b`"))
+	if max := limit - 2*len(fence); len(r) > max {
+		r = append(r[:max-1], '…')
+	}
+	return fence + string(r) + fence
+}
+
+// prewarmPush resolves the push endpoint and opens a connection to it in the
+// background, so the push at the end of a wrapped command skips DNS and TCP
+// setup. It is best-effort: failures only mean the push dials normally.
+func prewarmPush(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return
+	}
+	// ntfy://host/topic and gotify://host are sent over HTTPS; other
+	// provider schemes, such as telegram://<chat_id>, do not name the host
+	// to dial.
+	switch u.Scheme {
+	case "http", "https", "ntfy", "gotify":
+	default:
+		return
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	go pushDialer.warm(u.Hostname(), port)
+}
+
+// warmDialer caches DNS answers and pre-dialed connections per address.
+type warmDialer struct {
+	mu    sync.Mutex
+	addrs map[string][]string // host -> resolved IPs
+	conns map[string]warmConn // host:port -> pre-dialed connection
+}
+
+type warmConn struct {
+	conn net.Conn
+	at   time.Time
+}
+
+func (d *warmDialer) warm(host, port string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil || len(ips) == 0 {
+		return
+	}
+	d.mu.Lock()
+	if d.addrs == nil {
+		d.addrs = map[string][]string{}
+	}
+	d.addrs[host] = ips
+	d.mu.Unlock()
+
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "tcp", net.JoinHostPort(ips[0], port))
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conns == nil {
+		d.conns = map[string]warmConn{}
+	}
+	if old, ok := d.conns[net.JoinHostPort(host, port)]; ok {
+		old.conn.Close()
+	}
+	d.conns[net.JoinHostPort(host, port)] = warmConn{conn: conn, at: time.Now()}
+}
+
+// DialContext hands out a live pre-dialed connection for addr if there is one,
+// otherwise dials using cached DNS answers before falling back to a normal dial.
+func (d *warmDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	wc, ok := d.conns[addr]
+	delete(d.conns, addr)
+	host, port, _ := net.SplitHostPort(addr)
+	ips := d.addrs[host]
+	d.mu.Unlock()
+
+	if ok {
+		if time.Since(wc.at) < warmConnTTL && connAlive(wc.conn) {
+			return wc.conn, nil
+		}
+		wc.conn.Close()
+	}
+
+	nd := net.Dialer{Timeout: 5 * time.Second}
+	for _, ip := range ips {
+		if conn, err := nd.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+	if len(ips) > 0 {
+		// The host may have moved; look it up again rather than trying
+		// the old answers on every push.
+		d.mu.Lock()
+		delete(d.addrs, host)
+		d.mu.Unlock()
+	}
+	return nd.DialContext(ctx, network, addr)
+}
+
+// connAlive reports whether the peer has not closed conn. An idle connection
+// that has not sent a request should have nothing to read, so a read that
+// times out means it is still usable.
+func connAlive(conn net.Conn) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	_ = conn.SetReadDeadline(time.Time{})
+	var ne net.Error
+	return err != nil && errors.As(err, &ne) && ne.Timeout()
+}
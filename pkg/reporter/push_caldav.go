@@ -0,0 +1,180 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderCalDAV, pushProviderSpec{
+		schemes: []string{"caldav"},
+		options: map[string]configKind{
+			"min_duration": kindDuration,
+		},
+		new: newCalDAVPush,
+	})
+}
+
+const pushProviderCalDAV = "caldav"
+
+// Calendar providers record each run as an event spanning it, so a calendar
+// shows when builds and deploys occupied a machine. They skip runs shorter
+// than the min_duration option, digests, and progress updates, none of which
+// are a span of time worth blocking out.
+
+// calendarEvent is a finished run as a calendar event.
+type calendarEvent struct {
+	// UID is derived from the run, so a push that is retried or resent
+	// from the spool does not create a second event.
+	UID         string
+	Summary     string
+	Description string
+	Start, End  time.Time
+}
+
+// newCalendarEvent returns the event for n, or false if n should not get
+// one.
+func newCalendarEvent(n notification, minDuration time.Duration) (calendarEvent, bool) {
+	if n.Finished.IsZero() || n.Running || n.Duration < minDuration {
+		return calendarEvent{}, false
+	}
+	host, _ := os.Hostname()
+	start := n.Finished.Add(-n.Duration)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d", host, n.Subtitle, start.UnixNano())))
+
+	icon := "✓"
+	if n.Failed {
+		icon = "✗"
+	}
+	description := []string{n.Body}
+	if host != "" {
+		description = append(description, "Host: "+host)
+	}
+	if len(n.Labels) > 0 {
+		description = append(description, "Labels: "+formatLabels(n.Labels))
+	}
+	if n.Output != "" {
+		description = append(description, "", n.Output)
+	}
+	return calendarEvent{
+		UID:         hex.EncodeToString(sum[:16]),
+		Summary:     icon + " " + oneLine(n.Subtitle),
+		Description: strings.Join(description, "\n"),
+		Start:       start,
+		End:         n.Finished,
+	}, true
+}
+
+// calendarMinDuration returns target's min_duration option.
+func calendarMinDuration(target pushTarget) (time.Duration, error) {
+	d, err := time.ParseDuration(target.option("min_duration", "0s"))
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid min_duration %q: want a duration such as 10m", target.option("min_duration", ""))
+	}
+	return d, nil
+}
+
+// calDAVPush stores each run as an iCalendar event in a CalDAV calendar
+// collection. The URL is the collection (caldav://host/path for HTTPS, or an
+// http(s) URL with -push-provider caldav), and the push token is
+// "user:password", usually an app password.
+type calDAVPush struct {
+	target      pushTarget
+	collection  string
+	minDuration time.Duration
+}
+
+func newCalDAVPush(target pushTarget) (pushProvider, error) {
+	p := calDAVPush{target: target, collection: target.URL}
+	if rest, ok := strings.CutPrefix(p.collection, "caldav://"); ok {
+		p.collection = "https://" + rest
+	}
+	p.collection = strings.TrimSuffix(p.collection, "/") + "/"
+	var err error
+	if p.minDuration, err = calendarMinDuration(target); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Push creates the event for n. If-None-Match keeps a resent push from
+// replacing the event, and the server's refusal then means it already exists.
+func (p calDAVPush) Push(ctx context.Context, n notification) error {
+	e, ok := newCalendarEvent(n, p.minDuration)
+	if !ok {
+		return nil
+	}
+	eventURL := p.collection + e.UID + ".ics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, eventURL, strings.NewReader(e.ics(time.Now())))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", eventURL, err)
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("If-None-Match", "*")
+	if auth := p.target.authorization(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	err = sendPush(req)
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusPreconditionFailed {
+		return nil
+	}
+	return err
+}
+
+// ics renders e as an iCalendar object stamped at now.
+func (e calendarEvent) ics(now time.Time) string {
+	const layout = "20060102T150405Z"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//reporter//reporter//EN",
+		"BEGIN:VEVENT",
+		"UID:" + e.UID + "@reporter",
+		"DTSTAMP:" + now.UTC().Format(layout),
+		"DTSTART:" + e.Start.UTC().Format(layout),
+		"DTEND:" + e.End.UTC().Format(layout),
+		"SUMMARY:" + escapeICalText(e.Summary),
+		"DESCRIPTION:" + escapeICalText(e.Description),
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICalLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// escapeICalText escapes s for an iCalendar TEXT value (RFC 5545 3.3.11).
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine splits line into lines of at most 75 octets, each continuation
+// starting with a space, without splitting a UTF-8 sequence.
+func foldICalLine(line string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
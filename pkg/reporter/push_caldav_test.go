@@ -0,0 +1,99 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToCalDAV(t *testing.T) {
+	var gotMethod, gotPath, gotBody, gotAuth, gotMatch string
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.Path, string(b)
+		gotAuth, gotMatch = r.Header.Get("Authorization"), r.Header.Get("If-None-Match")
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	target := pushTarget{
+		URL:      srv.URL + "/dav/calendars/me/builds",
+		Provider: pushProviderCalDAV,
+		Token:    "me:app-password",
+		Options:  map[string]any{"min_duration": "5m"},
+	}
+	finished := time.Date(2026, 3, 6, 14, 5, 0, 0, time.UTC)
+	n := notification{
+		Title: "Task finished", Body: "failed (exit 2) in 12m00s", Subtitle: "make test, lint; deploy",
+		Failed: true, ExitCode: 2, Duration: 12 * time.Minute, Finished: finished,
+	}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	e, _ := newCalendarEvent(n, 0)
+	if gotMethod != http.MethodPut || gotPath != "/dav/calendars/me/builds/"+e.UID+".ics" {
+		t.Errorf("%s %s, want PUT of the event into the collection", gotMethod, gotPath)
+	}
+	if gotAuth != "Basic bWU6YXBwLXBhc3N3b3Jk" || gotMatch != "*" {
+		t.Errorf("Authorization = %q, If-None-Match = %q", gotAuth, gotMatch)
+	}
+	for _, want := range []string{
+		"BEGIN:VEVENT\r\n",
+		"DTSTART:20260306T135300Z\r\n",
+		"DTEND:20260306T140500Z\r\n",
+		`SUMMARY:✗ make test\, lint\; deploy` + "\r\n",
+	} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("event lacks %q:\n%s", want, gotBody)
+		}
+	}
+
+	// An event that already exists is a success.
+	status = http.StatusPreconditionFailed
+	if err := pushToPhone(target, n); err != nil {
+		t.Errorf("pushToPhone() of an existing event returned error: %v", err)
+	}
+
+	// Short runs, digests, and progress updates make no event.
+	gotMethod = ""
+	for _, skipped := range []notification{
+		{Title: "Task finished", Subtitle: "make", Duration: time.Minute, Finished: finished},
+		{Title: "Daily digest", Body: "12 runs"},
+		progressNotification("make", "", 10*time.Minute, nil),
+	} {
+		if err := pushToPhone(target, skipped); err != nil || gotMethod != "" {
+			t.Errorf("pushToPhone(%+v) = %v, sent %q; want nothing sent", skipped, err, gotMethod)
+		}
+	}
+}
+
+func TestCalendarEventUID(t *testing.T) {
+	finished := time.Date(2026, 3, 6, 14, 5, 0, 0, time.UTC)
+	n := notification{Subtitle: "make", Duration: time.Minute, Finished: finished}
+	a, _ := newCalendarEvent(n, 0)
+	b, _ := newCalendarEvent(n, 0)
+	n.Finished = finished.Add(time.Second)
+	c, _ := newCalendarEvent(n, 0)
+	if a.UID != b.UID || a.UID == c.UID {
+		t.Errorf("UIDs %q, %q, %q: want the same run to get the same UID and another run a different one", a.UID, b.UID, c.UID)
+	}
+}
+
+func TestFoldICalLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 60)
+	folded := foldICalLine(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("folded line is %d octets: %q", len(part), part)
+		}
+	}
+	if got := strings.ReplaceAll(folded, "\r\n ", ""); got != line {
+		t.Errorf("unfolding gives %q, want %q", got, line)
+	}
+}
@@ -0,0 +1,75 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderCloudEvents, pushProviderSpec{
+		options: map[string]configKind{
+			"mode": kindString,
+		},
+		new: func(target pushTarget) (pushProvider, error) {
+			if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+				return nil, err
+			}
+			switch mode := target.option("mode", cloudEventsStructured); mode {
+			case cloudEventsStructured, cloudEventsBinary:
+			default:
+				return nil, fmt.Errorf("invalid mode %q: want %s or %s", mode, cloudEventsStructured, cloudEventsBinary)
+			}
+			return cloudEventsPush{target: target}, nil
+		},
+	})
+}
+
+// CloudEvents HTTP content modes: the whole event as the body, or the data
+// as the body with the attributes in ce- headers.
+const (
+	cloudEventsStructured = "structured"
+	cloudEventsBinary     = "binary"
+)
+
+// cloudEventsPush POSTs the JSON payload as a CloudEvent (see cloudEvent).
+// Like the webhook provider it is never detected from a URL; select it with
+// -push-format cloudevents or -push-provider cloudevents.
+type cloudEventsPush struct{ target pushTarget }
+
+func (p cloudEventsPush) Push(ctx context.Context, n notification) error {
+	e, err := newCloudEvent(p.target.PayloadVersion, n, time.Now())
+	if err != nil {
+		return err
+	}
+	body, contentType := []byte(e.Data), "application/json"
+	if p.target.option("mode", cloudEventsStructured) == cloudEventsStructured {
+		if body, err = json.Marshal(e); err != nil {
+			return err
+		}
+		contentType = "application/cloudevents+json"
+	}
+	req, err := newPushRequest(ctx, p.target.URL, p.target, n, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType == "application/json" {
+		req.Header.Set("Ce-Specversion", e.SpecVersion)
+		req.Header.Set("Ce-Id", e.ID)
+		req.Header.Set("Ce-Source", e.Source)
+		req.Header.Set("Ce-Type", e.Type)
+		req.Header.Set("Ce-Time", e.Time)
+		if e.Subject != "" {
+			req.Header.Set("Ce-Subject", e.Subject)
+		}
+	}
+	req.Header.Set("X-Reporter-Schema", payloadSchema(p.target.PayloadVersion))
+	if p.target.Secret != "" {
+		req.Header.Set("X-Reporter-Signature", signPayload(p.target.Secret, body))
+	}
+	return sendPush(req)
+}
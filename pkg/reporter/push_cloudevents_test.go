@@ -0,0 +1,73 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPushToCloudEvents(t *testing.T) {
+	var gotHeader http.Header
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderCloudEvents, PayloadVersion: 1}
+	n := notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true,
+		Finished: time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC), Duration: 3 * time.Minute, ExitCode: 2}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if got := gotHeader.Get("Content-Type"); got != "application/cloudevents+json" {
+		t.Errorf("Content-Type = %q, want application/cloudevents+json", got)
+	}
+	var e struct {
+		cloudEvent
+		Data Payload `json:"data"`
+	}
+	if err := json.Unmarshal(gotBody, &e); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, gotBody)
+	}
+	if e.SpecVersion != "1.0" || e.Type != cloudEventTypePrefix+"run.failed" || e.Subject != "make" ||
+		e.Time != "2026-05-01T09:30:00Z" || e.DataContentType != "application/json" || e.ID == "" {
+		t.Errorf("event = %+v", e.cloudEvent)
+	}
+	if e.Data.Schema != "reporter/v1" || e.Data.ExitCode != 2 || !e.Data.Failed {
+		t.Errorf("data = %+v", e.Data)
+	}
+	id := e.ID
+
+	target.Options = map[string]any{"mode": "binary"}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	for h, want := range map[string]string{
+		"Content-Type":      "application/json",
+		"Ce-Specversion":    "1.0",
+		"Ce-Id":             id,
+		"Ce-Type":           cloudEventTypePrefix + "run.failed",
+		"Ce-Subject":        "make",
+		"X-Reporter-Schema": "reporter/v1",
+	} {
+		if got := gotHeader.Get(h); got != want {
+			t.Errorf("%s = %q, want %q", h, got, want)
+		}
+	}
+	var data Payload
+	if err := json.Unmarshal(gotBody, &data); err != nil || data.Schema != "reporter/v1" {
+		t.Errorf("binary body = %s (%v)", gotBody, err)
+	}
+
+	target.Options = map[string]any{"mode": "batched"}
+	if err := pushToPhone(target, n); err == nil {
+		t.Error("pushToPhone() with an unknown mode succeeded")
+	}
+}
@@ -0,0 +1,133 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderDiscord, pushProviderSpec{
+		match: isDiscordWebhookURL,
+		options: map[string]configKind{
+			"username":        kindString,
+			"avatar_url":      kindString,
+			"failure_mention": kindString,
+		},
+		new: func(target pushTarget) (pushProvider, error) {
+			return discordPush{target: target}, nil
+		},
+	})
+}
+
+const pushProviderDiscord = "discord"
+
+// Embed colors: Discord's own green, red and blurple.
+const (
+	discordColorSuccess   = 0x57f287
+	discordColorFailure   = 0xed4245
+	discordColorRecovered = 0x5865f2
+)
+
+// Discord's limits on embed titles and field values.
+const (
+	discordTitleLimit = 256
+	discordFieldLimit = 1024
+)
+
+// discordPush posts an embed to a Discord channel webhook.
+type discordPush struct{ target pushTarget }
+
+type discordMessage struct {
+	Content   string         `json:"content,omitempty"`
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+func (p discordPush) message(n notification) discordMessage {
+	embed := discordEmbed{Title: n.Title, Description: n.Body, Color: discordColorSuccess}
+	if r := []rune(embed.Title); len(r) > discordTitleLimit {
+		embed.Title = string(r[:discordTitleLimit-1]) + "…"
+	}
+	switch {
+	case n.Failed:
+		embed.Color = discordColorFailure
+	case n.Recovered > 0:
+		embed.Color = discordColorRecovered
+	}
+	if n.Subtitle != "" {
+		embed.Fields = append(embed.Fields, discordField{Name: "Command", Value: codeFence(n.Subtitle, discordFieldLimit)})
+	}
+	if n.Duration > 0 {
+		embed.Fields = append(embed.Fields,
+			discordField{Name: "Duration", Value: formatDuration(n.Duration), Inline: true},
+			discordField{Name: "Exit code", Value: strconv.Itoa(n.ExitCode), Inline: true})
+	}
+	if n.Output != "" {
+		embed.Fields = append(embed.Fields, discordField{Name: "Output", Value: codeFence(n.Output, discordFieldLimit)})
+	}
+	if len(n.Labels) > 0 {
+		embed.Footer = &discordFooter{Text: formatLabels(n.Labels)}
+	}
+	msg := discordMessage{
+		Username:  p.target.option("username", ""),
+		AvatarURL: p.target.option("avatar_url", ""),
+		Embeds:    []discordEmbed{embed},
+	}
+	if n.Failed && !n.Quiet {
+		msg.Content = p.target.option("failure_mention", "")
+	}
+	return msg
+}
+
+func (p discordPush) Push(ctx context.Context, n notification) error {
+	body, err := json.Marshal(p.message(n))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.target.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.target.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return sendPush(req)
+}
+
+// isDiscordWebhookURL reports whether u is a Discord channel webhook, on
+// discord.com or the older discordapp.com, including the ptb and canary
+// clients' hosts.
+func isDiscordWebhookURL(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range []string{"discord.com", "discordapp.com"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return strings.HasPrefix(u.Path, "/api/webhooks/")
+		}
+	}
+	return false
+}
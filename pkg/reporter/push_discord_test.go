@@ -0,0 +1,75 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToDiscord(t *testing.T) {
+	var got discordMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = discordMessage{}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %v\n%s", err, b)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderDiscord, Options: map[string]any{"failure_mention": "<@&1234>"}}
+	n := notification{
+		Title:    "Migration",
+		Body:     "failed (exit 1) in 42m",
+		Subtitle: "rails db:migrate",
+		Failed:   true,
+		Duration: 42 * time.Minute,
+		ExitCode: 1,
+		Labels:   map[string]string{"env": "prod"},
+	}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	want := discordMessage{
+		Content: "<@&1234>",
+		Embeds: []discordEmbed{{
+			Title:       "Migration",
+			Description: "failed (exit 1) in 42m",
+			Color:       discordColorFailure,
+			Fields: []discordField{
+				{Name: "Command", Value: "```rails db:migrate```"},
+				{Name: "Duration", Value: formatDuration(42 * time.Minute), Inline: true},
+				{Name: "Exit code", Value: "1", Inline: true},
+			},
+			Footer: &discordFooter{Text: "env=prod"},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("message =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// A digest has no single run, so no duration or exit code fields.
+	n = notification{Title: "Daily digest", Body: "12 runs", Subtitle: "since Mon"}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if e := got.Embeds[0]; e.Color != discordColorSuccess || len(e.Fields) != 1 || got.Content != "" {
+		t.Errorf("digest message = %+v, want green with only the command field and no mention", got)
+	}
+
+	n.Title = strings.Repeat("é", 300)
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if r := []rune(got.Embeds[0].Title); len(r) != discordTitleLimit {
+		t.Errorf("long title sent as %d characters, want %d", len(r), discordTitleLimit)
+	}
+}
@@ -0,0 +1,95 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderDisplay, pushProviderSpec{
+		schemes: []string{"esphome"},
+		options: map[string]configKind{
+			"max_length": kindInt,
+		},
+		new: newDisplayPush,
+	})
+}
+
+const pushProviderDisplay = "display"
+
+// displayDefaultLength fits two short lines on a small e-ink badge.
+const displayDefaultLength = 64
+
+// displayPush shows a short status text on a small display, such as an
+// e-ink badge on the desk. esphome://<device>/<text> sets a text entity of an
+// ESPHome device through its web server's REST API, for a display lambda to
+// draw; with -push-provider display, any http(s) URL is sent the text as a
+// plain POST body instead.
+type displayPush struct {
+	target    pushTarget
+	endpoint  string
+	esphome   bool
+	maxLength int
+}
+
+func newDisplayPush(target pushTarget) (pushProvider, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", target.URL, err)
+	}
+	p := displayPush{target: target, endpoint: target.URL, maxLength: target.optionInt("max_length", displayDefaultLength)}
+	if p.maxLength < 16 {
+		return nil, fmt.Errorf("invalid max_length %d: want at least 16", p.maxLength)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "esphome":
+		id := strings.Trim(u.Path, "/")
+		if u.Host == "" || id == "" || strings.Contains(id, "/") {
+			return nil, errors.New("use esphome://<device>/<text entity> as the push URL, such as esphome://badge.local/job_status")
+		}
+		p.esphome = true
+		p.endpoint = "http://" + u.Host + "/text/" + url.PathEscape(id) + "/set"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported URL %q: use esphome://<device>/<text entity> or an http(s) URL", target.URL)
+	}
+	return p, nil
+}
+
+func (p displayPush) Push(ctx context.Context, n notification) error {
+	text := displayText(n, p.maxLength)
+	if !p.esphome {
+		req, err := newTextPush(ctx, p.endpoint, p.target, n, text)
+		if err != nil {
+			return err
+		}
+		return sendPush(req)
+	}
+	req, err := newPushRequest(ctx, p.endpoint+"?value="+url.QueryEscape(text), p.target, n, "text/plain", nil)
+	if err != nil {
+		return err
+	}
+	return sendPush(req)
+}
+
+// displayText is the status shown for n in at most limit characters: the
+// command on one line and how it went on the next, such as
+//
+//	make -j8 all
+//	failed (exit 2) in 3m00s
+//
+// The command is shortened first, so the outcome stays readable.
+func displayText(n notification, limit int) string {
+	what := n.Subtitle
+	if what == "" {
+		what = n.Title
+	}
+	status := oneLine(n.Body)
+	room := max(limit-len([]rune(status))-1, limit/4)
+	return clipRunes(clipRunes(oneLine(what), room)+"\n"+status, limit)
+}
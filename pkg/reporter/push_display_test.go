@@ -0,0 +1,88 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPushToDisplay(t *testing.T) {
+	var gotMethod, gotPath, gotValue, gotBody, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotValue, gotBody, gotAuth = r.Method, r.URL.Path, r.URL.Query().Get("value"), string(b), r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m00s", Subtitle: "make -j8 all", Failed: true, ExitCode: 2}
+	want := "make -j8 all\nfailed (exit 2) in 3m00s"
+
+	esphome := pushTarget{URL: "esphome://" + strings.TrimPrefix(srv.URL, "http://") + "/job_status", Token: "admin:badge"}
+	esphome.Provider = detectPushProvider(esphome.URL)
+	if esphome.Provider != pushProviderDisplay {
+		t.Errorf("detectPushProvider(%q) = %q", esphome.URL, esphome.Provider)
+	}
+	if err := pushToPhone(esphome, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotMethod != http.MethodPost || gotPath != "/text/job_status/set" || gotValue != want || gotAuth != "Basic YWRtaW46YmFkZ2U=" {
+		t.Errorf("ESPHome got %s %s value %q, auth %q", gotMethod, gotPath, gotValue, gotAuth)
+	}
+
+	plain := pushTarget{URL: srv.URL + "/status", Provider: pushProviderDisplay}
+	if err := pushToPhone(plain, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotPath != "/status" || gotBody != want {
+		t.Errorf("endpoint got %s %q, want /status %q", gotPath, gotBody, want)
+	}
+}
+
+func TestNewDisplayPush(t *testing.T) {
+	for _, tt := range []struct {
+		url      string
+		options  map[string]any
+		endpoint string
+		wantErr  bool
+	}{
+		{url: "esphome://badge.local/job_status", endpoint: "http://badge.local/text/job_status/set"},
+		{url: "esphome://192.168.1.40:8080/job%20status", endpoint: "http://192.168.1.40:8080/text/job%20status/set"},
+		{url: "https://badge.example/api/text", endpoint: "https://badge.example/api/text"},
+		{url: "esphome://badge.local", wantErr: true},
+		{url: "esphome://badge.local/text/job_status", wantErr: true},
+		{url: "mqtt://broker/badge", wantErr: true},
+		{url: "esphome://badge.local/job_status", options: map[string]any{"max_length": int64(8)}, wantErr: true},
+	} {
+		p, err := newDisplayPush(pushTarget{URL: tt.url, Options: tt.options})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newDisplayPush(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.(displayPush).endpoint != tt.endpoint {
+			t.Errorf("newDisplayPush(%q) sends to %s, want %s", tt.url, p.(displayPush).endpoint, tt.endpoint)
+		}
+	}
+}
+
+func TestDisplayText(t *testing.T) {
+	for _, tt := range []struct {
+		n     notification
+		limit int
+		want  string
+	}{
+		{n: notification{Subtitle: "make", Body: "succeeded in 42m00s"}, limit: 64, want: "make\nsucceeded in 42m00s"},
+		{n: notification{Subtitle: "./scripts/backfill --from 2026-01-01 --to 2026-06-30", Body: "12m00s elapsed", Running: true}, limit: 32,
+			want: "./scripts/backfi…\n12m00s elapsed"},
+		{n: notification{Title: "Overnight digest", Body: "3 runs, 1 failed"}, limit: 64, want: "Overnight digest\n3 runs, 1 failed"},
+		{n: notification{Subtitle: "make", Body: "recovered: succeeded in 42m00s after 3 failed runs"}, limit: 24,
+			want: "make\nrecovered: succeed…"},
+	} {
+		if got := displayText(tt.n, tt.limit); got != tt.want {
+			t.Errorf("displayText(%+v, %d) = %q, want %q", tt.n, tt.limit, got, tt.want)
+		}
+	}
+}
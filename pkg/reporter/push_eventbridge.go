@@ -0,0 +1,173 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderEventBridge, pushProviderSpec{
+		schemes: []string{"eventbridge"},
+		options: map[string]configKind{
+			"region":   kindString,
+			"source":   kindString,
+			"endpoint": kindString,
+		},
+		new: newEventBridgePush,
+	})
+}
+
+const pushProviderEventBridge = "eventbridge"
+
+// eventBridgeSource is the default source of the events; rules match on it.
+const eventBridgeSource = "reporter"
+
+// eventBridgePush puts each notification on an Amazon EventBridge event bus
+// with PutEvents, for rules that start follow-up work such as Lambda
+// functions or Step Functions. The URL names the bus, eventbridge://default,
+// eventbridge://<name>, or eventbridge://<arn>, and the detail is the JSON
+// payload. Credentials come from the AWS credential chain (see aws.go) when
+// each push is sent, so temporary ones are never stale.
+type eventBridgePush struct {
+	target   pushTarget
+	bus      string
+	region   string
+	source   string
+	endpoint string // "" for the region's public endpoint
+}
+
+func newEventBridgePush(target pushTarget) (pushProvider, error) {
+	rest, _ := strings.CutPrefix(target.URL, "eventbridge://")
+	bus := strings.Trim(rest, "/")
+	if bus == "" {
+		return nil, errors.New("no event bus: use eventbridge://default or eventbridge://<bus_name> as the push URL")
+	}
+	if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+		return nil, err
+	}
+	p := eventBridgePush{
+		target:   target,
+		bus:      bus,
+		region:   target.option("region", ""),
+		source:   target.option("source", eventBridgeSource),
+		endpoint: strings.TrimSuffix(target.option("endpoint", ""), "/"),
+	}
+	// A bus ARN names its region: arn:aws:events:<region>:<account>:event-bus/<name>.
+	if fields := strings.Split(bus, ":"); p.region == "" && len(fields) == 6 && fields[0] == "arn" {
+		p.region = fields[3]
+	}
+	return p, nil
+}
+
+// eventBridgeDetailType returns the detail-type of the event for n, such as
+// "Run Failed".
+func eventBridgeDetailType(n notification) string {
+	outcome := notificationOutcome(n)
+	if outcome == outcomeDigest {
+		return "Digest"
+	}
+	return "Run " + strings.ToUpper(outcome[:1]) + outcome[1:]
+}
+
+// eventBridgeEntry is an entry of a PutEvents request.
+type eventBridgeEntry struct {
+	Source       string `json:"Source"`
+	DetailType   string `json:"DetailType"`
+	Detail       string `json:"Detail"`
+	EventBusName string `json:"EventBusName"`
+	Time         int64  `json:"Time,omitempty"` // seconds since the epoch
+}
+
+func (p eventBridgePush) Push(ctx context.Context, n notification) error {
+	detail, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	entry := eventBridgeEntry{
+		Source:       p.source,
+		DetailType:   eventBridgeDetailType(n),
+		Detail:       string(detail),
+		EventBusName: p.bus,
+	}
+	if !n.Finished.IsZero() {
+		entry.Time = n.Finished.Unix()
+	}
+	body, err := json.Marshal(map[string][]eventBridgeEntry{"Entries": {entry}})
+	if err != nil {
+		return err
+	}
+
+	creds, err := awsCredentialChain(ctx, os.Getenv)
+	if err != nil {
+		return err
+	}
+	region := p.region
+	if region == "" {
+		if region = awsRegion(ctx, os.Getenv); region == "" {
+			return errors.New("no AWS region: set region in [push.eventbridge] or AWS_REGION")
+		}
+	}
+	endpoint := p.endpoint
+	if endpoint == "" {
+		endpoint = "https://events." + region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSEvents.PutEvents")
+	signAWS(req, body, creds, region, "events", time.Now())
+
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return eventBridgeError(resp.StatusCode, respBody)
+	}
+	var result struct {
+		FailedEntryCount int
+		Entries          []struct{ ErrorCode, ErrorMessage string }
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("EventBridge returned an unexpected response: %w", err)
+	}
+	if result.FailedEntryCount > 0 && len(result.Entries) > 0 {
+		e := result.Entries[0]
+		err := fmt.Errorf("EventBridge rejected the event: %s: %s", e.ErrorCode, e.ErrorMessage)
+		if e.ErrorCode == "ThrottlingException" || e.ErrorCode == "InternalFailure" {
+			return &statusError{http.StatusServiceUnavailable, err}
+		}
+		return err
+	}
+	return nil
+}
+
+// eventBridgeError describes an error response. AWS reports throttling as
+// a 400, which is retried like a 429.
+func eventBridgeError(code int, body []byte) error {
+	var e struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &e)
+	kind := e.Type[strings.LastIndex(e.Type, "#")+1:]
+	err := fmt.Errorf("EventBridge returned %d %s: %s", code, kind, e.Message)
+	if kind == "ThrottlingException" {
+		code = http.StatusTooManyRequests
+	}
+	return &statusError{code, err}
+}
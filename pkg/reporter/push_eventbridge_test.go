@@ -0,0 +1,93 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToEventBridge(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "none"))
+
+	var gotHeader http.Header
+	var got struct{ Entries []eventBridgeEntry }
+	respond := `{"FailedEntryCount":0,"Entries":[{"EventId":"11710aed-b79e-4468-a20b-bb3c0c3b4860"}]}`
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %v\n%s", err, b)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(respond))
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: "eventbridge://batch-jobs", Provider: pushProviderEventBridge, PayloadVersion: 1,
+		Options: map[string]any{"endpoint": srv.URL}}
+	finished := time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC)
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m", Subtitle: "./nightly-etl", Failed: true,
+		Finished: finished, Duration: 3 * time.Minute, ExitCode: 2}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotHeader.Get("X-Amz-Target") != "AWSEvents.PutEvents" || gotHeader.Get("Content-Type") != "application/x-amz-json-1.1" {
+		t.Errorf("headers = %v", gotHeader)
+	}
+	if auth := gotHeader.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20") ||
+		!strings.Contains(auth, "/us-west-2/events/aws4_request") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if len(got.Entries) != 1 {
+		t.Fatalf("entries = %+v", got.Entries)
+	}
+	e := got.Entries[0]
+	if e.Source != "reporter" || e.DetailType != "Run Failed" || e.EventBusName != "batch-jobs" || e.Time != finished.Unix() {
+		t.Errorf("entry = %+v", e)
+	}
+	var detail Payload
+	if err := json.Unmarshal([]byte(e.Detail), &detail); err != nil || detail.Command != "./nightly-etl" || detail.ExitCode != 2 {
+		t.Errorf("detail = %s (%v)", e.Detail, err)
+	}
+
+	respond = `{"FailedEntryCount":1,"Entries":[{"ErrorCode":"ThrottlingException","ErrorMessage":"Rate exceeded"}]}`
+	if err := pushToPhone(target, n); err == nil || !pushRetryable(err) {
+		t.Errorf("pushToPhone() with a throttled entry = %v, want a retryable error", err)
+	}
+	status, respond = http.StatusBadRequest, `{"__type":"com.amazon.coral.validate#ValidationException","message":"bad bus"}`
+	if err := pushToPhone(target, n); err == nil || pushRetryable(err) || !strings.Contains(err.Error(), "ValidationException: bad bus") {
+		t.Errorf("pushToPhone() with a rejected request = %v, want a permanent error", err)
+	}
+}
+
+func TestNewEventBridgePush(t *testing.T) {
+	for _, tt := range []struct {
+		url, region string
+		wantErr     bool
+	}{
+		{url: "eventbridge://default"},
+		{url: "eventbridge://arn:aws:events:eu-central-1:123456789012:event-bus/jobs", region: "eu-central-1"},
+		{url: "eventbridge://", wantErr: true},
+	} {
+		p, err := newEventBridgePush(pushTarget{URL: tt.url, PayloadVersion: 1})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newEventBridgePush(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.(eventBridgePush).region != tt.region {
+			t.Errorf("newEventBridgePush(%q) region = %q, want %q", tt.url, p.(eventBridgePush).region, tt.region)
+		}
+	}
+}
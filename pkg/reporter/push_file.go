@@ -0,0 +1,61 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderFile, pushProviderSpec{
+		schemes: []string{"file"},
+		new:     newFilePush,
+	})
+}
+
+const pushProviderFile = "file"
+
+// filePush drops each notification into a directory as a file holding its
+// JSON payload, for machines with no network to push over: another process
+// carries the files out, and `reporter spool flush` sends them on from
+// wherever they land (see dropdir.go). The URL is file:///<directory>.
+type filePush struct {
+	target pushTarget
+	dir    string
+}
+
+func newFilePush(target pushTarget) (pushProvider, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil || u.Path == "" || (u.Host != "" && u.Host != "localhost") {
+		return nil, errors.New("no directory: use file:///<directory> as the push URL")
+	}
+	if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+		return nil, err
+	}
+	dir := u.Path
+	if len(dir) > 2 && dir[0] == '/' && dir[2] == ':' {
+		dir = dir[1:] // file:///C:/drops on Windows
+	}
+	return filePush{target: target, dir: filepath.FromSlash(dir)}, nil
+}
+
+func (p filePush) Push(ctx context.Context, n notification) error {
+	data, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	// Group-readable, so a ferrying process need not run as this user.
+	if err := os.MkdirAll(p.dir, 0o750); err != nil {
+		return err
+	}
+	if err := writeSpoolFile(p.dir, time.Now(), data, 0o640); err != nil {
+		return fmt.Errorf("dropping the notification in %s: %w", p.dir, err)
+	}
+	return nil
+}
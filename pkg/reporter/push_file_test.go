@@ -0,0 +1,63 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "drops")
+	target := pushTarget{URL: "file://" + filepath.ToSlash(dir), Provider: pushProviderFile, PayloadVersion: 1}
+	if detectPushProvider(target.URL) != pushProviderFile {
+		t.Errorf("detectPushProvider(%q) = %q", target.URL, detectPushProvider(target.URL))
+	}
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m", Subtitle: "./nightly-etl", Failed: true,
+		Finished: time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC), Duration: 3 * time.Minute, ExitCode: 2}
+	for range 2 {
+		if err := pushToPhone(target, n); err != nil {
+			t.Fatalf("pushToPhone() returned error: %v", err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("drop directory has %d files (%v), want 2", len(entries), err)
+	}
+	for _, ent := range entries {
+		if !strings.HasSuffix(ent.Name(), spoolSuffix) {
+			t.Errorf("dropped file %s is not named *%s", ent.Name(), spoolSuffix)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, ent.Name()))
+		got, err := decodePayload(data)
+		if err != nil || got.Subtitle != n.Subtitle || got.ExitCode != 2 || !got.Finished.Equal(n.Finished) {
+			t.Errorf("dropped file holds %+v (%v)", got, err)
+		}
+	}
+}
+
+func TestNewFilePush(t *testing.T) {
+	for _, tt := range []struct {
+		url     string
+		dir     string
+		wantErr bool
+	}{
+		{url: "file:///var/spool/reporter", dir: filepath.FromSlash("/var/spool/reporter")},
+		{url: "file://localhost/var/spool/reporter", dir: filepath.FromSlash("/var/spool/reporter")},
+		{url: "file:///C:/drops", dir: filepath.FromSlash("C:/drops")},
+		{url: "file://", wantErr: true},
+		{url: "file://fileserver/share", wantErr: true},
+	} {
+		p, err := newFilePush(pushTarget{URL: tt.url, PayloadVersion: 1})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newFilePush(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.(filePush).dir != tt.dir {
+			t.Errorf("newFilePush(%q) writes to %s, want %s", tt.url, p.(filePush).dir, tt.dir)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderGoogleCalendar, pushProviderSpec{
+		schemes: []string{"gcal"},
+		options: map[string]configKind{
+			"min_duration":  kindDuration,
+			"client_id":     kindString,
+			"client_secret": kindString,
+			"refresh_token": kindString,
+			"api_url":       kindString,
+			"token_url":     kindString,
+		},
+		new: newGoogleCalendarPush,
+	})
+}
+
+const pushProviderGoogleCalendar = "gcal"
+
+// Google's Calendar API and OAuth token endpoint; api_url and token_url
+// override them.
+const (
+	googleCalendarAPI = "https://www.googleapis.com/calendar/v3"
+	googleTokenURL    = "https://oauth2.googleapis.com/token"
+)
+
+// googleCalendarPush adds each run as an event to a Google calendar (see
+// calendarEvent). The URL names the calendar, gcal://primary or
+// gcal://<calendar_id>. Access tokens expire within the hour, so rather than
+// a push token it usually has an OAuth client and refresh token in its
+// options, from which it gets a fresh access token for each push.
+type googleCalendarPush struct {
+	target      pushTarget
+	events      string // the calendar's events endpoint
+	minDuration time.Duration
+}
+
+func newGoogleCalendarPush(target pushTarget) (pushProvider, error) {
+	// Calendar IDs contain @, which url.Parse would take for userinfo.
+	rest, ok := strings.CutPrefix(target.URL, "gcal://")
+	calendar := strings.Trim(rest, "/")
+	if !ok || calendar == "" {
+		return nil, errors.New("no calendar: use gcal://primary or gcal://<calendar_id> as the push URL")
+	}
+	if target.Token == "" && target.option("refresh_token", "") == "" {
+		return nil, errors.New("no credentials: set refresh_token, client_id, and client_secret in [push.gcal], or an access token in push_token")
+	}
+	api := strings.TrimSuffix(target.option("api_url", googleCalendarAPI), "/")
+	p := googleCalendarPush{target: target, events: api + "/calendars/" + url.PathEscape(calendar) + "/events"}
+	var err error
+	if p.minDuration, err = calendarMinDuration(target); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// googleEvent is the part of a Calendar API event reporter sets.
+type googleEvent struct {
+	// ID is the event's UID; Google refuses a second event with the same
+	// one, so a resent push does not duplicate it.
+	ID          string          `json:"id"`
+	Summary     string          `json:"summary"`
+	Description string          `json:"description"`
+	Start       googleEventTime `json:"start"`
+	End         googleEventTime `json:"end"`
+}
+
+type googleEventTime struct {
+	DateTime string `json:"dateTime"`
+}
+
+func (p googleCalendarPush) Push(ctx context.Context, n notification) error {
+	e, ok := newCalendarEvent(n, p.minDuration)
+	if !ok {
+		return nil
+	}
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(googleEvent{
+		ID:          e.UID,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Start:       googleEventTime{DateTime: e.Start.Format(time.RFC3339)},
+		End:         googleEventTime{DateTime: e.End.Format(time.RFC3339)},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.events, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.events, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	err = sendGoogle(req)
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusConflict {
+		return nil // the event exists from an earlier attempt
+	}
+	return err
+}
+
+// accessToken returns the push token, or exchanges the refresh token option
+// for an access token.
+func (p googleCalendarPush) accessToken(ctx context.Context) (string, error) {
+	refresh := p.target.option("refresh_token", "")
+	if refresh == "" {
+		return p.target.Token, nil
+	}
+	return googleTokenExchange(ctx, p.target.option("token_url", googleTokenURL), url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refresh},
+		"client_id":     {p.target.option("client_id", "")},
+		"client_secret": {p.target.option("client_secret", "")},
+	})
+}
+
+// sendGoogle performs req, turning an error response into an error carrying
+// Google's message.
+func sendGoogle(req *http.Request) error {
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+		return &statusError{resp.StatusCode, fmt.Errorf("google calendar returned %s: %s", resp.Status, apiErr.Error.Message)}
+	}
+	return &statusError{resp.StatusCode, fmt.Errorf("google calendar returned %s", resp.Status)}
+}
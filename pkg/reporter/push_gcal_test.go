@@ -0,0 +1,106 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToGoogleCalendar(t *testing.T) {
+	noPushBackoff(t)
+	var got googleEvent
+	var gotPath, gotAuth string
+	status := http.StatusOK
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "1//refresh" || r.Form.Get("client_id") != "client" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid_grant","error_description":"Bad Request"}`)
+			return
+		}
+		io.WriteString(w, `{"access_token":"ya29.fresh","expires_in":3599,"token_type":"Bearer"}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = googleEvent{}
+		json.Unmarshal(b, &got)
+		gotPath, gotAuth = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		if status == http.StatusForbidden {
+			io.WriteString(w, `{"error":{"code":403,"message":"Insufficient Permission"}}`)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	target := pushTarget{
+		URL:      "gcal://team@group.calendar.google.com",
+		Provider: pushProviderGoogleCalendar,
+		Options: map[string]any{
+			"api_url":       srv.URL + "/calendar/v3",
+			"token_url":     srv.URL + "/token",
+			"client_id":     "client",
+			"client_secret": "secret",
+			"refresh_token": "1//refresh",
+		},
+	}
+	finished := time.Date(2026, 3, 6, 14, 5, 0, 0, time.UTC)
+	n := notification{Title: "Task finished", Body: "succeeded in 2h00m00s", Subtitle: "./deploy prod", Duration: 2 * time.Hour, Finished: finished}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotPath != "/calendar/v3/calendars/team@group.calendar.google.com/events" || gotAuth != "Bearer ya29.fresh" {
+		t.Errorf("posted to %s with %q, want the calendar's events with the refreshed token", gotPath, gotAuth)
+	}
+	e, _ := newCalendarEvent(n, 0)
+	want := googleEvent{
+		ID: e.UID, Summary: "✓ ./deploy prod", Description: e.Description,
+		Start: googleEventTime{DateTime: "2026-03-06T12:05:00Z"}, End: googleEventTime{DateTime: "2026-03-06T14:05:00Z"},
+	}
+	if got != want {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+
+	status = http.StatusConflict
+	if err := pushToPhone(target, n); err != nil {
+		t.Errorf("pushToPhone() of an existing event returned error: %v", err)
+	}
+	status = http.StatusForbidden
+	if err := pushToPhone(target, n); err == nil || !strings.Contains(err.Error(), "Insufficient Permission") {
+		t.Errorf("pushToPhone() error = %v, want Google's message", err)
+	}
+
+	target.Options["refresh_token"] = "revoked"
+	if err := pushToPhone(target, n); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("pushToPhone() error = %v, want the token endpoint's error", err)
+	}
+}
+
+func TestNewGoogleCalendarPush(t *testing.T) {
+	tests := []struct {
+		url     string
+		token   string
+		options map[string]any
+		wantErr bool
+	}{
+		{url: "gcal://primary", token: "ya29.token"},
+		{url: "gcal://primary", options: map[string]any{"refresh_token": "1//x"}},
+		{url: "gcal://primary", wantErr: true},
+		{url: "gcal://", token: "ya29.token", wantErr: true},
+		{url: "https://calendar.google.com/", token: "ya29.token", wantErr: true},
+		{url: "gcal://primary", token: "ya29.token", options: map[string]any{"min_duration": "soon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := newGoogleCalendarPush(pushTarget{URL: tt.url, Token: tt.token, Options: tt.options})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
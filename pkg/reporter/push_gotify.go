@@ -0,0 +1,138 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderGotify, pushProviderSpec{
+		schemes: []string{"gotify"},
+		options: map[string]configKind{
+			"priority":           kindInt,
+			"failure_priority":   kindInt,
+			"interrupt_priority": kindInt,
+			"recovered_priority": kindInt,
+		},
+		new: newGotifyPush,
+	})
+}
+
+const pushProviderGotify = "gotify"
+
+// gotifyMaxPriority is the highest priority Gotify clients distinguish; 8 and
+// above pop up on Android.
+const gotifyMaxPriority = 10
+
+// gotifyQuietPriority is the highest priority Android shows without sound,
+// used during quiet hours.
+const gotifyQuietPriority = 3
+
+// gotifyPush posts a message to a self-hosted Gotify server. The URL is the
+// server (gotify://host/path for HTTPS, or an http(s) URL with -push-provider
+// gotify) and the push token is the application token.
+type gotifyPush struct {
+	endpoint string
+	token    string
+	click    string
+	// success, failure, interrupt, and recovered are the priorities for each
+	// outcome.
+	success, failure, interrupt, recovered int
+}
+
+func newGotifyPush(target pushTarget) (pushProvider, error) {
+	p := gotifyPush{
+		token:     target.Token,
+		click:     target.Click,
+		success:   target.optionInt("priority", 5),
+		failure:   target.optionInt("failure_priority", 8),
+		interrupt: target.optionInt("interrupt_priority", 2),
+		recovered: target.optionInt("recovered_priority", 8),
+	}
+	server := target.URL
+	if rest, ok := strings.CutPrefix(server, "gotify://"); ok {
+		server = "https://" + rest
+	}
+	p.endpoint = strings.TrimSuffix(strings.TrimSuffix(server, "/"), "/message") + "/message"
+	if p.token == "" {
+		return nil, errors.New("no application token: set REPORTER_PUSH_TOKEN or push_token")
+	}
+	for _, prio := range []int{p.success, p.failure, p.interrupt, p.recovered} {
+		if prio > gotifyMaxPriority {
+			return nil, fmt.Errorf("invalid priority %d: want 0 to %d", prio, gotifyMaxPriority)
+		}
+	}
+	return p, nil
+}
+
+// gotifyMessage is the body of POST /message.
+type gotifyMessage struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+// message renders n for Gotify, with the priority following the outcome.
+func (p gotifyPush) message(n notification) gotifyMessage {
+	m := gotifyMessage{Title: n.Title, Message: n.Body, Priority: p.success}
+	switch {
+	case n.Failed && n.ExitCode == exitInterrupted:
+		m.Priority = p.interrupt
+	case n.Failed:
+		m.Priority = p.failure
+	case n.Recovered > 0:
+		m.Priority = p.recovered
+	}
+	if n.Quiet {
+		m.Priority = min(m.Priority, gotifyQuietPriority)
+	}
+	if n.Subtitle != "" {
+		m.Message += "\n" + n.Subtitle
+	}
+	if n.Output != "" {
+		m.Message += "\n\n" + n.Output
+	}
+	if p.click != "" {
+		m.Extras = map[string]any{"client::notification": map[string]any{"click": map[string]string{"url": p.click}}}
+	}
+	return m
+}
+
+func (p gotifyPush) Push(ctx context.Context, n notification) error {
+	body, err := json.Marshal(p.message(n))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", p.token)
+
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", p.endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Description string `json:"errorDescription"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Description != "" {
+			return &statusError{resp.StatusCode, fmt.Errorf("gotify returned %s: %s", resp.Status, apiErr.Description)}
+		}
+		return &statusError{resp.StatusCode, fmt.Errorf("gotify returned %s", resp.Status)}
+	}
+	return nil
+}
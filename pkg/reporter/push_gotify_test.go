@@ -0,0 +1,115 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPushToGotify(t *testing.T) {
+	var got gotifyMessage
+	var gotPath, gotKey string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = gotifyMessage{}
+		json.Unmarshal(b, &got)
+		gotPath, gotKey = r.URL.Path, r.Header.Get("X-Gotify-Key")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, `{"error":"Unauthorized","errorCode":401,"errorDescription":"you need to provide a valid access token or user credentials to access this api"}`)
+		}
+	}))
+	defer srv.Close()
+
+	target := pushTarget{
+		URL:      srv.URL + "/gotify/",
+		Provider: pushProviderGotify,
+		Token:    "AbCdEf.123",
+		Click:    "https://ci.example/jobs/7",
+		Options:  map[string]any{"failure_priority": int64(10)},
+	}
+	click := map[string]any{"client::notification": map[string]any{"click": map[string]any{"url": "https://ci.example/jobs/7"}}}
+	tests := []struct {
+		name string
+		n    notification
+		want gotifyMessage
+	}{
+		{
+			name: "success",
+			n:    notification{Title: "Build", Body: "succeeded in 3m", Subtitle: "make"},
+			want: gotifyMessage{Title: "Build", Message: "succeeded in 3m\nmake", Priority: 5, Extras: click},
+		},
+		{
+			name: "failure",
+			n:    notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true, ExitCode: 2, Output: "FAIL"},
+			want: gotifyMessage{Title: "Build", Message: "failed (exit 2) in 3m\nmake\n\nFAIL", Priority: 10, Extras: click},
+		},
+		{
+			name: "interrupted",
+			n:    notification{Title: "Build", Body: "failed (exit 130) in 3m", Failed: true, ExitCode: exitInterrupted},
+			want: gotifyMessage{Title: "Build", Message: "failed (exit 130) in 3m", Priority: 2, Extras: click},
+		},
+		{
+			name: "recovered",
+			n:    notification{Title: "Build", Body: "recovered: succeeded in 3m after 2 failed runs", Recovered: 2},
+			want: gotifyMessage{Title: "Build", Message: "recovered: succeeded in 3m after 2 failed runs", Priority: 8, Extras: click},
+		},
+		{
+			name: "quiet hours",
+			n:    notification{Title: "Build", Body: "failed (exit 2) in 3m", Failed: true, ExitCode: 2, Quiet: true},
+			want: gotifyMessage{Title: "Build", Message: "failed (exit 2) in 3m", Priority: gotifyQuietPriority, Extras: click},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pushToPhone(target, tt.n); err != nil {
+				t.Fatalf("pushToPhone() returned error: %v", err)
+			}
+			if gotPath != "/gotify/message" || gotKey != "AbCdEf.123" {
+				t.Errorf("posted to %s with key %q, want /gotify/message with the application token", gotPath, gotKey)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("message = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	status = http.StatusUnauthorized
+	err := pushToPhone(target, notification{Title: "x"})
+	if err == nil || !strings.Contains(err.Error(), "valid access token") {
+		t.Errorf("pushToPhone() error = %v, want Gotify's error description", err)
+	}
+}
+
+func TestNewGotifyPush(t *testing.T) {
+	tests := []struct {
+		url      string
+		token    string
+		options  map[string]any
+		endpoint string
+		wantErr  bool
+	}{
+		{url: "gotify://push.example.com", token: "app", endpoint: "https://push.example.com/message"},
+		{url: "gotify://example.com/gotify/", token: "app", endpoint: "https://example.com/gotify/message"},
+		{url: "http://nas.lan:8080/message", token: "app", endpoint: "http://nas.lan:8080/message"},
+		{url: "gotify://push.example.com", wantErr: true},
+		{url: "gotify://push.example.com", token: "app", options: map[string]any{"priority": int64(11)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := newGotifyPush(pushTarget{URL: tt.url, Token: tt.token, Options: tt.options})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.(gotifyPush).endpoint != tt.endpoint {
+			t.Errorf("%s: endpoint = %q, want %q", tt.url, p.(gotifyPush).endpoint, tt.endpoint)
+		}
+	}
+}
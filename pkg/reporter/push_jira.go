@@ -0,0 +1,122 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderJira, pushProviderSpec{
+		schemes: []string{"jira"},
+		match:   isJiraCloudURL,
+		new:     newJiraPush,
+	})
+}
+
+const pushProviderJira = "jira"
+
+// Issue tracker providers comment on the ticket named with -issue (see
+// issue.go) and send nothing for runs without one, so they can stay in the
+// config next to other destinations. Progress updates get no comment.
+
+// issueComment renders n as the text of a comment, using code to format a
+// block of preformatted text in the tracker's markup.
+func issueComment(n notification, bold, code func(string) string) string {
+	icon := "✅"
+	switch {
+	case n.Recovered > 0:
+		icon = "🎉"
+	case n.Failed:
+		icon = "❌"
+	}
+	lines := []string{icon + " " + bold(n.Title) + ": " + n.Body, code(n.Subtitle)}
+	var details []string
+	if host, _ := os.Hostname(); host != "" {
+		details = append(details, "Host: "+host)
+	}
+	if len(n.Labels) > 0 {
+		details = append(details, "Labels: "+formatLabels(n.Labels))
+	}
+	if len(details) > 0 {
+		lines = append(lines, strings.Join(details, " · "))
+	}
+	if n.Output != "" {
+		lines = append(lines, code(n.Output))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isJiraCloudURL reports whether u is a Jira Cloud site.
+func isJiraCloudURL(u *url.URL) bool {
+	return strings.HasSuffix(strings.ToLower(u.Hostname()), ".atlassian.net")
+}
+
+// jiraPush comments on a Jira issue through the REST API. The URL is the
+// site (jira://example.atlassian.net for HTTPS, or an http(s) URL with
+// -push-provider jira). On Jira Cloud the push token is "email:api-token";
+// on Data Center it is a personal access token.
+type jiraPush struct {
+	target pushTarget
+	site   string
+}
+
+func newJiraPush(target pushTarget) (pushProvider, error) {
+	site := target.URL
+	if rest, ok := strings.CutPrefix(site, "jira://"); ok {
+		site = "https://" + rest
+	}
+	if target.Token == "" {
+		return nil, errors.New("no credentials: set REPORTER_PUSH_TOKEN or push_token to email:api-token or a personal access token")
+	}
+	return jiraPush{target: target, site: strings.TrimSuffix(site, "/")}, nil
+}
+
+// jiraText escapes Jira wiki markup in s by wrapping it in {noformat}, whose
+// only terminator is another {noformat}.
+func jiraText(s string) string {
+	return "{noformat}" + strings.ReplaceAll(s, "{noformat}", "{ noformat}") + "{noformat}"
+}
+
+func jiraBold(s string) string { return "*" + s + "*" }
+
+func (p jiraPush) Push(ctx context.Context, n notification) error {
+	if n.Issue == "" || n.Running {
+		return nil
+	}
+	body, err := json.Marshal(map[string]string{"body": issueComment(n, jiraBold, jiraText)})
+	if err != nil {
+		return err
+	}
+	endpoint := p.site + "/rest/api/2/issue/" + url.PathEscape(n.Issue) + "/comment"
+	req, err := newPushRequest(ctx, endpoint, p.target, n, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	// Jira explains rejections, such as an issue that does not exist, in
+	// errorMessages.
+	var apiErr struct {
+		ErrorMessages []string `json:"errorMessages"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &apiErr) == nil && len(apiErr.ErrorMessages) > 0 {
+		return &statusError{resp.StatusCode, fmt.Errorf("jira returned %s for %s: %s", resp.Status, n.Issue, strings.Join(apiErr.ErrorMessages, "; "))}
+	}
+	return &statusError{resp.StatusCode, fmt.Errorf("jira returned %s for %s", resp.Status, n.Issue)}
+}
@@ -0,0 +1,83 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToJira(t *testing.T) {
+	var gotPath, gotAuth string
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got)
+		if strings.Contains(r.URL.Path, "NOPE-1") {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"errorMessages":["Issue does not exist or you do not have permission to see it."],"errors":{}}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL + "/", Provider: pushProviderJira, Token: "me@example.com:api-token"}
+	n := notification{
+		Title: "Task finished", Body: "failed (exit 1) in 3h02m", Subtitle: "./backfill --since 2024",
+		Failed: true, ExitCode: 1, Duration: 3 * time.Hour, Output: "row 1812: {noformat} bad", Issue: "DATA-42",
+	}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotPath != "/rest/api/2/issue/DATA-42/comment" {
+		t.Errorf("posted to %q, want the issue's comments", gotPath)
+	}
+	if gotAuth != "Basic bWVAZXhhbXBsZS5jb206YXBpLXRva2Vu" {
+		t.Errorf("Authorization = %q, want basic auth", gotAuth)
+	}
+	for _, want := range []string{
+		"❌ *Task finished*: failed (exit 1) in 3h02m\n",
+		"{noformat}./backfill --since 2024{noformat}",
+		"{noformat}row 1812: { noformat} bad{noformat}",
+	} {
+		if !strings.Contains(got["body"], want) {
+			t.Errorf("comment lacks %q:\n%s", want, got["body"])
+		}
+	}
+
+	n.Issue = "NOPE-1"
+	err := pushToPhone(target, n)
+	if err == nil || !strings.Contains(err.Error(), "Issue does not exist") {
+		t.Errorf("pushToPhone() to a missing issue = %v, want Jira's message", err)
+	}
+
+	// Runs without an issue and progress updates make no comment.
+	gotPath = ""
+	for _, skipped := range []notification{
+		{Title: "Task finished", Subtitle: "make"},
+		progressNotification("make", "", 10*time.Minute, nil),
+	} {
+		if err := pushToPhone(target, skipped); err != nil || gotPath != "" {
+			t.Errorf("pushToPhone(%+v) = %v, posted to %q; want nothing sent", skipped, err, gotPath)
+		}
+	}
+}
+
+func TestDetectJiraCloud(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://example.atlassian.net": pushProviderJira,
+		"jira://jira.example.com":       pushProviderJira,
+		"https://atlassian.net.example": pushProviderPlain,
+	} {
+		if got := detectPushProvider(url); got != want {
+			t.Errorf("detectPushProvider(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderKafka, pushProviderSpec{
+		schemes: []string{"kafka"},
+		options: map[string]configKind{
+			"topic":   kindString,
+			"brokers": kindStringList,
+			"sasl":    kindString,
+			"tls":     kindBool,
+		},
+		new: newKafkaPush,
+	})
+}
+
+const pushProviderKafka = "kafka"
+
+// kafkaDefaultPort is the port Kafka brokers listen on.
+const kafkaDefaultPort = "9092"
+
+// SASL mechanisms the kafka provider can authenticate with.
+const (
+	kafkaSASLPlain       = "PLAIN"
+	kafkaSASLSCRAMSHA256 = "SCRAM-SHA-256"
+	kafkaSASLSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// kafkaPush produces each notification to a Kafka topic. The producer itself
+// is only compiled in with -tags kafka (see push_kafka_client.go), which few
+// users need; without it, pushes fail with a note saying so. The URL is
+// kafka://[user:password@]broker[:port][,broker...]/<topic>; a push token is
+// used as user:password. Credentials are sent with SASL/PLAIN unless sasl
+// names a SCRAM mechanism.
+type kafkaPush struct {
+	target  pushTarget
+	brokers []string // host:port, tried in order
+	topic   string
+	user    string
+	pass    string
+	sasl    string // a mechanism, or "" without credentials
+	tls     bool
+}
+
+func newKafkaPush(target pushTarget) (pushProvider, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return nil, errors.New("invalid URL: use kafka://broker[:port][,broker...]/<topic> as the push URL")
+	}
+	p := kafkaPush{
+		target: target,
+		topic:  target.option("topic", strings.Trim(u.Path, "/")),
+		tls:    target.Options["tls"] == true,
+	}
+	for _, broker := range target.optionList("brokers", strings.Split(u.Host, ",")) {
+		if broker == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			broker = net.JoinHostPort(broker, kafkaDefaultPort)
+		}
+		p.brokers = append(p.brokers, broker)
+	}
+	if len(p.brokers) == 0 {
+		return nil, errors.New("no brokers: use kafka://broker[:port][,broker...]/<topic> as the push URL")
+	}
+	if p.topic == "" {
+		return nil, errors.New("no topic: use kafka://broker[:port]/<topic> as the push URL")
+	}
+	if strings.Trim(p.topic, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._-") != "" || len(p.topic) > 249 {
+		return nil, fmt.Errorf("invalid topic %q: want letters, digits, '.', '_', and '-'", p.topic)
+	}
+	if u.User != nil {
+		p.user = u.User.Username()
+		p.pass, _ = u.User.Password()
+	}
+	if target.Token != "" {
+		user, pass, ok := strings.Cut(target.Token, ":")
+		if !ok {
+			return nil, errors.New("invalid push token: want user:password for Kafka")
+		}
+		p.user, p.pass = user, pass
+	}
+	switch mech := strings.ToUpper(target.option("sasl", "")); {
+	case mech == "" && p.user != "":
+		p.sasl = kafkaSASLPlain
+	case mech == "":
+	case mech != kafkaSASLPlain && mech != kafkaSASLSCRAMSHA256 && mech != kafkaSASLSCRAMSHA512:
+		return nil, fmt.Errorf("invalid sasl %q: want PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512", mech)
+	case p.user == "":
+		return nil, fmt.Errorf("sasl %s needs a user and password in the URL or push token", mech)
+	default:
+		p.sasl = mech
+	}
+	if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,621 @@
+//go:build kafka && !nopush
+
+package reporter
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The producer speaks the Kafka protocol directly, with the oldest request
+// versions that carry record headers, so it works with brokers from 0.11 on:
+// Metadata v1 finds the leader of the message's partition, and Produce v3
+// sends it a single-record batch, waiting for every in-sync replica.
+
+// Kafka API keys and the versions used.
+const (
+	kafkaProduce          = 0
+	kafkaMetadata         = 3
+	kafkaSASLHandshake    = 17
+	kafkaSASLAuthenticate = 36
+
+	kafkaProduceVersion          = 3
+	kafkaMetadataVersion         = 1
+	kafkaSASLHandshakeVersion    = 1
+	kafkaSASLAuthenticateVersion = 0
+)
+
+// kafkaErrors names the error codes a producer commonly gets back; the
+// retryable ones go away once the cluster settles, e.g. after a leader
+// election.
+var kafkaErrors = map[int16]struct {
+	name      string
+	retryable bool
+}{
+	2:  {"CORRUPT_MESSAGE", true},
+	3:  {"UNKNOWN_TOPIC_OR_PARTITION", false},
+	5:  {"LEADER_NOT_AVAILABLE", true},
+	6:  {"NOT_LEADER_OR_FOLLOWER", true},
+	7:  {"REQUEST_TIMED_OUT", true},
+	10: {"MESSAGE_TOO_LARGE", false},
+	13: {"NETWORK_EXCEPTION", true},
+	19: {"NOT_ENOUGH_REPLICAS", true},
+	20: {"NOT_ENOUGH_REPLICAS_AFTER_APPEND", true},
+	29: {"TOPIC_AUTHORIZATION_FAILED", false},
+	31: {"CLUSTER_AUTHORIZATION_FAILED", false},
+	33: {"UNSUPPORTED_SASL_MECHANISM", false},
+	35: {"UNSUPPORTED_VERSION", false},
+	58: {"SASL_AUTHENTICATION_FAILED", false},
+	87: {"INVALID_RECORD", false},
+}
+
+// kafkaError returns an error for code, retryable if Kafka says it is, or nil
+// for code 0.
+func kafkaError(code int16, format string, args ...any) error {
+	if code == 0 {
+		return nil
+	}
+	name, retryable := fmt.Sprintf("error %d", code), false
+	if e, ok := kafkaErrors[code]; ok {
+		name, retryable = e.name, e.retryable
+	}
+	err := fmt.Errorf("%s: %s", fmt.Sprintf(format, args...), name)
+	if retryable {
+		return &statusError{http.StatusServiceUnavailable, err}
+	}
+	return err
+}
+
+func (p kafkaPush) Push(ctx context.Context, n notification) error {
+	data, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	conn, err := p.dialAny(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { conn.Close() }()
+
+	leader, partition, err := p.leader(conn, kafkaKey(n))
+	if err != nil {
+		return err
+	}
+	if leader != conn.addr {
+		lc, err := p.dial(ctx, leader)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		conn = lc
+	}
+	return p.produce(ctx, conn, partition, kafkaRecordBatch(n, data, p.target.PayloadVersion))
+}
+
+// kafkaKey returns the record key for n, which picks its partition: the
+// dedup key or the command, so a job's runs stay in order.
+func kafkaKey(n notification) string {
+	if n.DedupKey != "" {
+		return n.DedupKey
+	}
+	return n.Subtitle
+}
+
+// dialAny connects to the first bootstrap broker that answers.
+func (p kafkaPush) dialAny(ctx context.Context) (*kafkaConn, error) {
+	var errs []error
+	for _, broker := range p.brokers {
+		conn, err := p.dial(ctx, broker)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// dial connects to the broker at addr, over TLS and authenticated as
+// configured.
+func (p kafkaPush) dial(ctx context.Context, addr string) (*kafkaConn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+	if p.tls {
+		host, _, _ := net.SplitHostPort(addr)
+		tc := tls.Client(nc, &tls.Config{ServerName: host})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("TLS handshake with %s: %w", addr, err)
+		}
+		nc = tc
+	}
+	conn := &kafkaConn{Conn: nc, addr: addr, r: bufio.NewReader(nc)}
+	if p.sasl != "" {
+		if err := p.authenticate(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// leader returns the address of the broker leading the topic partition the
+// record key hashes to, and that partition.
+func (p kafkaPush) leader(conn *kafkaConn, key string) (string, int32, error) {
+	var req kafkaEncoder
+	req.int32(1)
+	req.string(p.topic)
+	resp, err := conn.call(kafkaMetadata, kafkaMetadataVersion, req.b)
+	if err != nil {
+		return "", 0, err
+	}
+	brokers := map[int32]string{}
+	for range resp.array() {
+		id := resp.int32()
+		host := resp.string()
+		port := resp.int32()
+		resp.nullableString() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	resp.int32() // controller
+	var partitions []int32
+	leaders := map[int32]int32{}
+	for range resp.array() {
+		code := resp.int16()
+		name := resp.string()
+		resp.int8() // internal
+		for range resp.array() {
+			resp.int16()
+			partition, leader := resp.int32(), resp.int32()
+			resp.array32() // replicas
+			resp.array32() // in-sync replicas
+			if name == p.topic {
+				partitions = append(partitions, partition)
+				leaders[partition] = leader
+			}
+		}
+		if name == p.topic {
+			if err := kafkaError(code, "topic %s", p.topic); err != nil {
+				return "", 0, err
+			}
+		}
+	}
+	if resp.err != nil {
+		return "", 0, fmt.Errorf("reading metadata from %s: %w", conn.addr, resp.err)
+	}
+	if len(partitions) == 0 {
+		return "", 0, kafkaError(3, "topic %s", p.topic)
+	}
+	// Partitions are numbered from 0; hash the key as Kafka's Java client
+	// does, so its consumers see the same partitioning.
+	partition := int32(murmur2([]byte(key))&0x7fffffff) % int32(len(partitions))
+	addr, ok := brokers[leaders[partition]]
+	if !ok {
+		return "", 0, kafkaError(5, "topic %s partition %d", p.topic, partition)
+	}
+	return addr, partition, nil
+}
+
+// produce sends batch to the partition's leader on conn.
+func (p kafkaPush) produce(ctx context.Context, conn *kafkaConn, partition int32, batch []byte) error {
+	timeout := 30 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	var req kafkaEncoder
+	req.int16(-1) // no transaction
+	req.int16(-1) // acks from all in-sync replicas
+	req.int32(int32(timeout / time.Millisecond))
+	req.int32(1)
+	req.string(p.topic)
+	req.int32(1)
+	req.int32(partition)
+	req.bytes(batch)
+	resp, err := conn.call(kafkaProduce, kafkaProduceVersion, req.b)
+	if err != nil {
+		return err
+	}
+	for range resp.array() {
+		resp.string()
+		for range resp.array() {
+			resp.int32()
+			code := resp.int16()
+			resp.int64() // base offset
+			resp.int64() // log append time
+			if resp.err == nil {
+				if err := kafkaError(code, "producing to %s partition %d", p.topic, partition); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if resp.err != nil {
+		return fmt.Errorf("reading the produce response from %s: %w", conn.addr, resp.err)
+	}
+	return nil
+}
+
+// kafkaRecordBatch returns a record batch (magic 2) holding n as one record:
+// the JSON payload keyed by kafkaKey, with headers carrying the outcome,
+// exit code, schema, dedup key, and labels, as for NATS.
+func kafkaRecordBatch(n notification, data []byte, version int) []byte {
+	var headers [][2]string
+	add := func(key, value string) {
+		if value = oneLine(value); value != "" {
+			headers = append(headers, [2]string{key, value})
+		}
+	}
+	add("Reporter-Id", notificationID(n))
+	add("Reporter-Status", notificationOutcome(n))
+	add("Reporter-Exit-Code", strconv.Itoa(n.ExitCode))
+	add("Reporter-Schema", payloadSchema(version))
+	add("Reporter-Dedup-Key", n.DedupKey)
+	if len(n.Labels) > 0 {
+		add("Reporter-Labels", formatLabels(n.Labels))
+	}
+
+	var record []byte
+	record = append(record, 0)              // attributes
+	record = binary.AppendVarint(record, 0) // timestamp delta
+	record = binary.AppendVarint(record, 0) // offset delta
+	record = appendVarBytes(record, kafkaKey(n))
+	record = appendVarBytes(record, string(data))
+	record = binary.AppendVarint(record, int64(len(headers)))
+	for _, h := range headers {
+		record = appendVarBytes(record, h[0])
+		record = appendVarBytes(record, h[1])
+	}
+
+	ts := n.Finished
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	var body kafkaEncoder // everything the CRC covers
+	body.int16(0)         // attributes: no compression
+	body.int32(0)         // last offset delta
+	body.int64(ts.UnixMilli())
+	body.int64(ts.UnixMilli())
+	body.int64(-1) // producer ID
+	body.int16(-1) // producer epoch
+	body.int32(-1) // base sequence
+	body.int32(1)
+	body.b = binary.AppendVarint(body.b, int64(len(record)))
+	body.b = append(body.b, record...)
+
+	var batch kafkaEncoder
+	batch.int64(0) // base offset
+	batch.int32(int32(4 + 1 + 4 + len(body.b)))
+	batch.int32(-1) // partition leader epoch
+	batch.int8(2)   // magic
+	batch.int32(int32(crc32.Checksum(body.b, crc32.MakeTable(crc32.Castagnoli))))
+	batch.b = append(batch.b, body.b...)
+	return batch.b
+}
+
+// appendVarBytes appends s with its varint length, as records encode keys,
+// values, and headers.
+func appendVarBytes(b []byte, s string) []byte {
+	return append(binary.AppendVarint(b, int64(len(s))), s...)
+}
+
+// murmur2 is the hash Kafka's Java client partitions record keys with.
+func murmur2(data []byte) uint32 {
+	const m, r = 0x5bd1e995, 24
+	h := uint32(0x9747b28c) ^ uint32(len(data))
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h = h*m ^ k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// authenticate logs in on conn with the configured SASL mechanism.
+func (p kafkaPush) authenticate(conn *kafkaConn) error {
+	var req kafkaEncoder
+	req.string(p.sasl)
+	resp, err := conn.call(kafkaSASLHandshake, kafkaSASLHandshakeVersion, req.b)
+	if err != nil {
+		return err
+	}
+	code := resp.int16()
+	var mechanisms []string
+	for range resp.array() {
+		mechanisms = append(mechanisms, resp.string())
+	}
+	if code == 33 {
+		return fmt.Errorf("broker %s does not accept SASL %s (it offers %s)", conn.addr, p.sasl, strings.Join(mechanisms, ", "))
+	}
+	if err := kafkaError(code, "SASL handshake with %s", conn.addr); err != nil {
+		return err
+	}
+
+	if p.sasl == kafkaSASLPlain {
+		_, err := conn.saslAuthenticate([]byte("\x00" + p.user + "\x00" + p.pass))
+		return err
+	}
+	newHash := sha256.New
+	if p.sasl == kafkaSASLSCRAMSHA512 {
+		newHash = sha512.New
+	}
+	var nonce [18]byte
+	rand.Read(nonce[:])
+	return scramAuthenticate(conn, newHash, p.user, p.pass, base64.RawStdEncoding.EncodeToString(nonce[:]))
+}
+
+// saslAuthenticate sends one SASL message and returns the broker's answer.
+func (c *kafkaConn) saslAuthenticate(msg []byte) ([]byte, error) {
+	var req kafkaEncoder
+	req.bytes(msg)
+	resp, err := c.call(kafkaSASLAuthenticate, kafkaSASLAuthenticateVersion, req.b)
+	if err != nil {
+		return nil, err
+	}
+	code := resp.int16()
+	message := resp.nullableString()
+	answer := resp.bytesField()
+	if code != 0 && message != "" {
+		return nil, fmt.Errorf("SASL authentication with %s: %s", c.addr, message)
+	}
+	if err := kafkaError(code, "SASL authentication with %s", c.addr); err != nil {
+		return nil, err
+	}
+	return answer, resp.err
+}
+
+// scramAuthenticate runs the SCRAM exchange of RFC 5802 on conn with the
+// client nonce, checking the broker's signature too.
+func scramAuthenticate(conn *kafkaConn, newHash func() hash.Hash, user, pass, nonce string) error {
+	name := strings.NewReplacer("=", "=3D", ",", "=2C").Replace(user)
+	clientFirst := "n=" + name + ",r=" + nonce
+	answer, err := conn.saslAuthenticate([]byte("n,," + clientFirst))
+	if err != nil {
+		return err
+	}
+	serverFirst := string(answer)
+	attrs := scramAttributes(serverFirst)
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	iterations, ierr := strconv.Atoi(attrs["i"])
+	if err != nil || ierr != nil || iterations < 1 || !strings.HasPrefix(attrs["r"], nonce) {
+		return fmt.Errorf("SASL authentication with %s: unexpected SCRAM challenge", conn.addr)
+	}
+
+	mac := func(key []byte, msg string) []byte {
+		h := hmac.New(newHash, key)
+		h.Write([]byte(msg))
+		return h.Sum(nil)
+	}
+	salted := pbkdf2(newHash, []byte(pass), salt, iterations)
+	clientKey := mac(salted, "Client Key")
+	h := newHash()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+	clientFinal := "c=biws,r=" + attrs["r"]
+	authMessage := clientFirst + "," + serverFirst + "," + clientFinal
+	proof := mac(storedKey, authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	answer, err = conn.saslAuthenticate([]byte(clientFinal + ",p=" + base64.StdEncoding.EncodeToString(proof)))
+	if err != nil {
+		return err
+	}
+	final := scramAttributes(string(answer))
+	if e := final["e"]; e != "" {
+		return fmt.Errorf("SASL authentication with %s: %s", conn.addr, e)
+	}
+	want := mac(mac(salted, "Server Key"), authMessage)
+	if got, err := base64.StdEncoding.DecodeString(final["v"]); err != nil || !hmac.Equal(got, want) {
+		return fmt.Errorf("SASL authentication with %s: the broker's SCRAM signature does not match", conn.addr)
+	}
+	return nil
+}
+
+// scramAttributes splits a SCRAM message such as "r=...,s=...,i=4096".
+func scramAttributes(msg string) map[string]string {
+	attrs := map[string]string{}
+	for _, field := range strings.Split(msg, ",") {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			attrs[key] = value
+		}
+	}
+	return attrs
+}
+
+// pbkdf2 derives a key as long as newHash's output from password and salt,
+// per RFC 8018; SCRAM needs only the first block.
+func pbkdf2(newHash func() hash.Hash, password, salt []byte, iterations int) []byte {
+	h := hmac.New(newHash, password)
+	h.Write(salt)
+	h.Write([]byte{0, 0, 0, 1})
+	u := h.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		h.Reset()
+		h.Write(u)
+		u = h.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+// kafkaConn is a connection to one broker.
+type kafkaConn struct {
+	net.Conn
+	addr          string
+	r             *bufio.Reader
+	correlationID int32
+}
+
+// call sends a request with body and returns the body of its response.
+func (c *kafkaConn) call(apiKey, version int16, body []byte) (*kafkaDecoder, error) {
+	c.correlationID++
+	var req kafkaEncoder
+	req.int32(0) // size, filled in below
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(c.correlationID)
+	req.string("reporter")
+	req.b = append(req.b, body...)
+	binary.BigEndian.PutUint32(req.b, uint32(len(req.b)-4))
+	if _, err := c.Write(req.b); err != nil {
+		return nil, fmt.Errorf("writing to %s: %w", c.addr, err)
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, fmt.Errorf("reading from %s: %w", c.addr, err)
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > 16<<20 {
+		return nil, fmt.Errorf("%s is not a Kafka broker", c.addr)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, fmt.Errorf("reading from %s: %w", c.addr, err)
+	}
+	if int32(binary.BigEndian.Uint32(resp)) != c.correlationID {
+		return nil, fmt.Errorf("broker %s answered a different request", c.addr)
+	}
+	return &kafkaDecoder{b: resp[4:]}, nil
+}
+
+// kafkaEncoder builds a request in the protocol's big-endian encoding.
+type kafkaEncoder struct{ b []byte }
+
+func (e *kafkaEncoder) int8(v int8)   { e.b = append(e.b, byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { e.b = binary.BigEndian.AppendUint16(e.b, uint16(v)) }
+func (e *kafkaEncoder) int32(v int32) { e.b = binary.BigEndian.AppendUint32(e.b, uint32(v)) }
+func (e *kafkaEncoder) int64(v int64) { e.b = binary.BigEndian.AppendUint64(e.b, uint64(v)) }
+
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// kafkaDecoder reads a response; after the first short read, err is set and
+// every read returns zero.
+type kafkaDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *kafkaDecoder) take(n int) []byte {
+	if d.err != nil || n < 0 || n > len(d.b) {
+		if d.err == nil {
+			d.err = errors.New("truncated response")
+		}
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) string() string { return string(d.take(int(d.int16()))) }
+
+func (d *kafkaDecoder) nullableString() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *kafkaDecoder) bytesField() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.take(int(n))
+}
+
+// array returns the length of an array, or 0 for a null one or after an
+// error, for ranging over its elements.
+func (d *kafkaDecoder) array() int {
+	n := d.int32()
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// array32 skips an array of int32s.
+func (d *kafkaDecoder) array32() {
+	for range d.array() {
+		d.int32()
+	}
+}
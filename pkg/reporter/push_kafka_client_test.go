@@ -0,0 +1,299 @@
+//go:build kafka && !nopush
+
+package reporter
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKafkaBroker answers Metadata, Produce, and SASL requests like a
+// single Kafka broker.
+type fakeKafkaBroker struct {
+	addr string
+	// leader, if set, is advertised as the leader of every partition
+	// instead of this broker.
+	leader     string
+	partitions int
+	topicError int16
+	// produceError is returned for every produce.
+	produceError int16
+	// sasl holds the answers to SASL authenticate requests, in order; an
+	// answer starting with "!" is sent as that error message.
+	sasl    []string
+	batches chan []byte // produced record batches
+	auth    chan string // SASL messages received
+}
+
+func fakeKafka(t *testing.T, b *fakeKafkaBroker) *fakeKafkaBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	b.addr = ln.Addr().String()
+	if b.partitions == 0 {
+		b.partitions = 3
+	}
+	b.batches, b.auth = make(chan []byte, 4), make(chan string, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeKafkaBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	sasl := b.sasl
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		buf := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return
+		}
+		req := &kafkaDecoder{b: buf}
+		apiKey, _, correlationID := req.int16(), req.int16(), req.int32()
+		req.nullableString() // client ID
+
+		var resp kafkaEncoder
+		resp.int32(0)
+		resp.int32(correlationID)
+		switch apiKey {
+		case kafkaSASLHandshake:
+			resp.int16(0)
+			resp.int32(2)
+			resp.string(kafkaSASLPlain)
+			resp.string(kafkaSASLSCRAMSHA256)
+		case kafkaSASLAuthenticate:
+			b.auth <- string(req.bytesField())
+			answer := sasl[0]
+			sasl = sasl[1:]
+			if msg, ok := strings.CutPrefix(answer, "!"); ok {
+				resp.int16(58)
+				resp.string(msg)
+				resp.bytes(nil)
+			} else {
+				resp.int16(0)
+				resp.int16(-1)
+				resp.bytes([]byte(answer))
+			}
+		case kafkaMetadata:
+			req.int32()
+			topic := req.string()
+			leaderID, host, port := int32(1), b.addr, 0
+			if b.leader != "" {
+				leaderID, host = 2, b.leader
+			}
+			host, portStr, _ := net.SplitHostPort(host)
+			port, _ = strconv.Atoi(portStr)
+			resp.int32(1)
+			resp.int32(leaderID)
+			resp.string(host)
+			resp.int32(int32(port))
+			resp.int16(-1)
+			resp.int32(leaderID) // controller
+			resp.int32(1)
+			resp.int16(b.topicError)
+			resp.string(topic)
+			resp.int8(0)
+			resp.int32(int32(b.partitions))
+			for i := range b.partitions {
+				resp.int16(0)
+				resp.int32(int32(i))
+				resp.int32(leaderID)
+				resp.int32(1)
+				resp.int32(leaderID)
+				resp.int32(1)
+				resp.int32(leaderID)
+			}
+		case kafkaProduce:
+			req.nullableString()
+			req.int16()
+			req.int32()
+			req.int32()
+			topic := req.string()
+			req.int32()
+			partition := req.int32()
+			b.batches <- req.bytesField()
+			resp.int32(1)
+			resp.string(topic)
+			resp.int32(1)
+			resp.int32(partition)
+			resp.int16(b.produceError)
+			resp.int64(42)
+			resp.int64(-1)
+			resp.int32(0) // throttle time
+		default:
+			return
+		}
+		binary.BigEndian.PutUint32(resp.b, uint32(len(resp.b)-4))
+		conn.Write(resp.b)
+	}
+}
+
+// kafkaRecord is the one record of a produced batch.
+type kafkaRecord struct {
+	key, value string
+	headers    map[string]string
+}
+
+// decodeKafkaBatch checks a record batch's CRC and returns its record.
+func decodeKafkaBatch(t *testing.T, batch []byte) kafkaRecord {
+	t.Helper()
+	if len(batch) < 61 || batch[16] != 2 {
+		t.Fatalf("batch %x is not a magic 2 record batch", batch)
+	}
+	if crc := binary.BigEndian.Uint32(batch[17:]); crc != crc32.Checksum(batch[21:], crc32.MakeTable(crc32.Castagnoli)) {
+		t.Fatalf("batch CRC %08x does not match", crc)
+	}
+	if count := binary.BigEndian.Uint32(batch[57:]); count != 1 {
+		t.Fatalf("batch has %d records, want 1", count)
+	}
+	b := batch[61:]
+	varint := func() int {
+		v, n := binary.Varint(b)
+		b = b[n:]
+		return int(v)
+	}
+	str := func() string {
+		n := varint()
+		s := string(b[:n])
+		b = b[n:]
+		return s
+	}
+	varint()  // length
+	b = b[1:] // attributes
+	varint()  // timestamp delta
+	varint()  // offset delta
+	rec := kafkaRecord{key: str(), value: str(), headers: map[string]string{}}
+	for range varint() {
+		k := str()
+		rec.headers[k] = str()
+	}
+	return rec
+}
+
+func TestPushToKafka(t *testing.T) {
+	// The bootstrap broker names another as the partition leader; both ask
+	// for the login.
+	leader := fakeKafka(t, &fakeKafkaBroker{sasl: []string{""}})
+	bootstrap := fakeKafka(t, &fakeKafkaBroker{leader: leader.addr, sasl: []string{""}})
+	target := pushTarget{URL: "kafka://127.0.0.1:1," + bootstrap.addr + "/jobs", Provider: pushProviderKafka, Token: "deploy:s3cret", PayloadVersion: 1}
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m", Subtitle: "./nightly-etl", Failed: true,
+		Finished: time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC), Duration: 3 * time.Minute, ExitCode: 2,
+		DedupKey: "nightly-etl", Labels: map[string]string{"project": "atlas"}}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if msg := <-bootstrap.auth; msg != "\x00deploy\x00s3cret" {
+		t.Errorf("bootstrap SASL/PLAIN message = %q", msg)
+	}
+	if msg := <-leader.auth; msg != "\x00deploy\x00s3cret" {
+		t.Errorf("leader SASL/PLAIN message = %q", msg)
+	}
+	rec := decodeKafkaBatch(t, <-leader.batches)
+	if rec.key != "nightly-etl" {
+		t.Errorf("record key = %q, want the dedup key", rec.key)
+	}
+	var data Payload
+	if err := json.Unmarshal([]byte(rec.value), &data); err != nil || data.Command != "./nightly-etl" || data.ExitCode != 2 {
+		t.Errorf("record value = %s (%v)", rec.value, err)
+	}
+	for key, want := range map[string]string{
+		"Reporter-Id":        notificationID(n),
+		"Reporter-Status":    "failed",
+		"Reporter-Exit-Code": "2",
+		"Reporter-Schema":    "reporter/v1",
+		"Reporter-Dedup-Key": "nightly-etl",
+		"Reporter-Labels":    "project=atlas",
+	} {
+		if rec.headers[key] != want {
+			t.Errorf("header %s = %q, want %q", key, rec.headers[key], want)
+		}
+	}
+}
+
+func TestPushToKafkaErrors(t *testing.T) {
+	n := notification{Title: "Task finished", Subtitle: "make"}
+	for _, tt := range []struct {
+		name      string
+		broker    fakeKafkaBroker
+		token     string
+		want      string
+		retryable bool
+	}{
+		{name: "unknown topic", broker: fakeKafkaBroker{topicError: 3}, want: "UNKNOWN_TOPIC_OR_PARTITION"},
+		{name: "leader election", broker: fakeKafkaBroker{topicError: 5}, want: "LEADER_NOT_AVAILABLE", retryable: true},
+		{name: "not enough replicas", broker: fakeKafkaBroker{produceError: 19}, want: "NOT_ENOUGH_REPLICAS", retryable: true},
+		{name: "denied", broker: fakeKafkaBroker{produceError: 29}, want: "TOPIC_AUTHORIZATION_FAILED"},
+		{name: "bad password", broker: fakeKafkaBroker{sasl: []string{"!Authentication failed: Invalid username or password"}},
+			token: "deploy:wrong", want: "Invalid username or password"},
+	} {
+		b := fakeKafka(t, &tt.broker)
+		target := pushTarget{URL: "kafka://" + b.addr + "/jobs", Provider: pushProviderKafka, Token: tt.token, PayloadVersion: 1}
+		err := pushToPhone(target, n)
+		if err == nil || pushRetryable(err) != tt.retryable || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: pushToPhone() returned %v, retryable %t", tt.name, err, pushRetryable(err))
+		}
+	}
+}
+
+func TestSCRAMAuthenticate(t *testing.T) {
+	// The SCRAM-SHA-256 exchange from RFC 7677.
+	b := fakeKafka(t, &fakeKafkaBroker{sasl: []string{
+		"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+		"v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=",
+	}})
+	nc, err := net.Dial("tcp", b.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	conn := &kafkaConn{Conn: nc, addr: b.addr, r: bufio.NewReader(nc)}
+	if err := scramAuthenticate(conn, sha256.New, "user", "pencil", "rOprNGfwEbeRWgbNEkqO"); err != nil {
+		t.Fatalf("scramAuthenticate() returned error: %v", err)
+	}
+	if msg := <-b.auth; msg != "n,,n=user,r=rOprNGfwEbeRWgbNEkqO" {
+		t.Errorf("client-first message = %q", msg)
+	}
+	if msg := <-b.auth; msg != "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=" {
+		t.Errorf("client-final message = %q", msg)
+	}
+}
+
+func TestMurmur2(t *testing.T) {
+	// Values from Kafka's own tests of its Java client.
+	for in, want := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		if got := int32(murmur2([]byte(in))); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
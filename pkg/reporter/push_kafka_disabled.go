@@ -0,0 +1,15 @@
+//go:build !kafka && !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+)
+
+// Built without -tags kafka: the Kafka producer is left out of the default
+// binary, and kafka:// URLs only report that.
+
+func (p kafkaPush) Push(ctx context.Context, n notification) error {
+	return errors.New("Kafka support is not compiled into this build (build with -tags kafka)")
+}
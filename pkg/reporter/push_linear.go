@@ -0,0 +1,138 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderLinear, pushProviderSpec{
+		schemes: []string{"linear"},
+		options: map[string]configKind{
+			"api_url": kindString,
+		},
+		new: newLinearPush,
+	})
+}
+
+const pushProviderLinear = "linear"
+
+// linearAPI is Linear's GraphQL endpoint; the api_url option overrides it.
+const linearAPI = "https://api.linear.app/graphql"
+
+// linearPush comments on a Linear issue (see issueComment). The URL is just
+// linear://, and the push token is a personal API key or an OAuth access
+// token.
+type linearPush struct {
+	token    string
+	endpoint string
+}
+
+func newLinearPush(target pushTarget) (pushProvider, error) {
+	if target.Token == "" {
+		return nil, errors.New("no API key: set REPORTER_PUSH_TOKEN or push_token")
+	}
+	return linearPush{token: target.Token, endpoint: target.option("api_url", linearAPI)}, nil
+}
+
+func markdownBold(s string) string { return "**" + s + "**" }
+
+// markdownCode fences s, lengthening the fence past any run of backticks in s.
+func markdownCode(s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + s + "\n" + fence
+}
+
+func (p linearPush) Push(ctx context.Context, n notification) error {
+	if n.Issue == "" || n.Running {
+		return nil
+	}
+	// Comments take the issue's ID, so look it up from the key first.
+	var found struct {
+		Issue struct {
+			ID string `json:"id"`
+		} `json:"issue"`
+	}
+	if err := p.query(ctx, `query($id: String!) { issue(id: $id) { id } }`, map[string]any{"id": n.Issue}, &found); err != nil {
+		return err
+	}
+	if found.Issue.ID == "" {
+		return fmt.Errorf("linear has no issue %s", n.Issue)
+	}
+	var created struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	input := map[string]any{"issueId": found.Issue.ID, "body": issueComment(n, markdownBold, markdownCode)}
+	if err := p.query(ctx, `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success } }`, map[string]any{"input": input}, &created); err != nil {
+		return err
+	}
+	if !created.CommentCreate.Success {
+		return fmt.Errorf("linear did not create the comment on %s", n.Issue)
+	}
+	return nil
+}
+
+// query runs a GraphQL query and decodes its data into out. GraphQL errors
+// come back with a 200 status and are returned as plain errors, which are
+// not retried.
+func (p linearPush) query(ctx context.Context, query string, variables map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Personal API keys are sent as they are, OAuth tokens as bearer tokens.
+	if strings.HasPrefix(p.token, "lin_api_") {
+		req.Header.Set("Authorization", p.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	_ = json.Unmarshal(data, &result)
+	if len(result.Errors) > 0 {
+		msgs := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			msgs[i] = e.Message
+		}
+		err = fmt.Errorf("linear returned %s: %s", resp.Status, strings.Join(msgs, "; "))
+		if resp.StatusCode >= 300 {
+			return &statusError{resp.StatusCode, err}
+		}
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return &statusError{resp.StatusCode, fmt.Errorf("linear returned %s", resp.Status)}
+	}
+	if len(result.Data) == 0 {
+		return errors.New("linear returned no data")
+	}
+	return json.Unmarshal(result.Data, out)
+}
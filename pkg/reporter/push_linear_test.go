@@ -0,0 +1,71 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToLinear(t *testing.T) {
+	var gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &req)
+		switch {
+		case strings.HasPrefix(req.Query, "query") && req.Variables["id"] == "ENG-7":
+			io.WriteString(w, `{"data":{"issue":{"id":"9f1c2a"}}}`)
+		case strings.HasPrefix(req.Query, "query"):
+			io.WriteString(w, `{"data":null,"errors":[{"message":"Entity not found: Issue"}]}`)
+		default:
+			input, _ := req.Variables["input"].(map[string]any)
+			if input["issueId"] != "9f1c2a" {
+				t.Errorf("commentCreate issueId = %v, want the looked-up ID", input["issueId"])
+			}
+			gotBody, _ = input["body"].(string)
+			io.WriteString(w, `{"data":{"commentCreate":{"success":true}}}`)
+		}
+	}))
+	defer srv.Close()
+
+	target := pushTarget{
+		URL: "linear://", Provider: pushProviderLinear, Token: "lin_api_abc",
+		Options: map[string]any{"api_url": srv.URL},
+	}
+	n := notification{
+		Title: "Task finished", Body: "succeeded in 2h", Subtitle: "rake data:backfill",
+		Duration: 2 * time.Hour, Labels: map[string]string{"env": "prod"}, Issue: "ENG-7",
+	}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotAuth != "lin_api_abc" {
+		t.Errorf("Authorization = %q, want the API key as is", gotAuth)
+	}
+	for _, want := range []string{"✅ **Task finished**: succeeded in 2h\n", "```\nrake data:backfill\n```", "Labels: env=prod"} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("comment lacks %q:\n%s", want, gotBody)
+		}
+	}
+
+	n.Issue = "ENG-8"
+	if err := pushToPhone(target, n); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("pushToPhone() to a missing issue = %v, want Linear's message", err)
+	}
+}
+
+func TestMarkdownCode(t *testing.T) {
+	if got, want := markdownCode("a ``` b"), "````\na ``` b\n````"; got != want {
+		t.Errorf("markdownCode() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderLPR, pushProviderSpec{
+		schemes: []string{"lpr"},
+		options: map[string]configKind{
+			"printer": kindString,
+			"raw":     kindBool,
+		},
+		new: newLPRPush,
+	})
+}
+
+const pushProviderLPR = "lpr"
+
+// lprPush prints a one-line summary of each run through lpr(1), for labs
+// that keep a line or receipt printer ticking out overnight job status. The
+// URL is lpr://<printer>, or lpr:// for the default printer. lpr is always
+// the one on PATH: config, which may come from a cloned project, does not
+// get to pick a program to run.
+type lprPush struct {
+	args []string
+}
+
+func newLPRPush(target pushTarget) (pushProvider, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return nil, errors.New("invalid URL: use lpr://<printer>, or lpr:// for the default printer")
+	}
+	printer := target.option("printer", u.Host)
+	if strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("%q is not a printer name: use lpr://<printer>", u.Host+u.Path)
+	}
+	p := lprPush{args: []string{"-J", "reporter"}}
+	if printer != "" {
+		p.args = append(p.args, "-P", printer)
+	}
+	if target.Options["raw"] == true {
+		// Send the text as is, for printers that take plain text, rather
+		// than through the spooler's filters, which page it.
+		p.args = append(p.args, "-l")
+	}
+	return p, nil
+}
+
+func (p lprPush) Push(ctx context.Context, n notification) error {
+	if n.Running {
+		return nil // not worth the paper
+	}
+	cmd := exec.CommandContext(ctx, "lpr", p.args...)
+	cmd.Stdin = strings.NewReader(lprLine(n, time.Now()) + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("lpr: %s", msg)
+		}
+		return fmt.Errorf("lpr: %w", err)
+	}
+	return nil
+}
+
+// lprLine is the line printed for n, such as
+//
+//	2026-05-01 09:30 build01 ./nightly-etl: failed (exit 2) in 3m00s
+//
+// Runs finished at now when n has no time, as with digests.
+func lprLine(n notification, now time.Time) string {
+	at := n.Finished
+	if at.IsZero() {
+		at = now
+	}
+	what := n.Subtitle
+	if what == "" {
+		what = n.Title
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s %s %s: %s", at.Local().Format("2006-01-02 15:04"), host, oneLine(what), oneLine(n.Body))
+}
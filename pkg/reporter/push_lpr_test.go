@@ -0,0 +1,97 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToLPR(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs sh")
+	}
+	// A stand-in for lpr, first on PATH, that keeps its arguments and input.
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	out := filepath.Join(dir, "printed")
+	lpr := filepath.Join(dir, "lpr")
+	if err := os.WriteFile(lpr, []byte("#!/bin/sh\necho \"$@\" >"+out+"\ncat >>"+out+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	target := pushTarget{URL: "lpr://Zebra_Receipt", Provider: pushProviderLPR, Options: map[string]any{"raw": true}}
+	if detectPushProvider(target.URL) != pushProviderLPR {
+		t.Errorf("detectPushProvider(%q) = %q", target.URL, detectPushProvider(target.URL))
+	}
+	finished := time.Date(2026, 5, 1, 9, 30, 0, 0, time.Local)
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m00s", Subtitle: "./nightly-etl", Failed: true, Finished: finished, ExitCode: 2}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	host, _ := os.Hostname()
+	want := "-J reporter -P Zebra_Receipt -l\n2026-05-01 09:30 " + host + " ./nightly-etl: failed (exit 2) in 3m00s\n"
+	if got, _ := os.ReadFile(out); string(got) != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+
+	os.Remove(out)
+	if err := pushToPhone(target, notification{Subtitle: "./nightly-etl", Running: true}); err != nil {
+		t.Fatalf("pushToPhone() returned error for a progress update: %v", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("a progress update was printed")
+	}
+
+	if err := os.WriteFile(lpr, []byte("#!/bin/sh\necho 'lpr: The printer or class does not exist.' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := pushToPhone(target, n); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("pushToPhone() with a missing printer returned %v", err)
+	}
+}
+
+func TestNewLPRPush(t *testing.T) {
+	for _, tt := range []struct {
+		url     string
+		options map[string]any
+		args    []string
+		wantErr bool
+	}{
+		{url: "lpr://", args: []string{"-J", "reporter"}},
+		{url: "lpr://lab-laser", args: []string{"-J", "reporter", "-P", "lab-laser"}},
+		{url: "lpr://", options: map[string]any{"printer": "ticker", "raw": true}, args: []string{"-J", "reporter", "-P", "ticker", "-l"}},
+		{url: "lpr://lab/laser", wantErr: true},
+	} {
+		p, err := newLPRPush(pushTarget{URL: tt.url, Options: tt.options})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newLPRPush(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !slices.Equal(p.(lprPush).args, tt.args) {
+			t.Errorf("newLPRPush(%q) runs lpr %q, want %q", tt.url, p.(lprPush).args, tt.args)
+		}
+	}
+}
+
+func TestLPRLine(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Date(2026, 5, 2, 7, 0, 0, 0, time.Local)
+	for _, tt := range []struct {
+		n    notification
+		want string
+	}{
+		{n: notification{Subtitle: "make\n  all", Body: "succeeded in 42m00s", Finished: time.Date(2026, 5, 1, 23, 5, 0, 0, time.Local)},
+			want: "2026-05-01 23:05 " + host + " make ⏎ all: succeeded in 42m00s"},
+		{n: notification{Title: "Overnight digest", Body: "3 runs, 1 failed"},
+			want: "2026-05-02 07:00 " + host + " Overnight digest: 3 runs, 1 failed"},
+	} {
+		if got := lprLine(tt.n, now); got != tt.want {
+			t.Errorf("lprLine(%+v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderNATS, pushProviderSpec{
+		schemes: []string{"nats"},
+		options: map[string]configKind{
+			"subject":   kindString,
+			"jetstream": kindBool,
+			"tls":       kindBool,
+		},
+		new: newNATSPush,
+	})
+}
+
+const pushProviderNATS = "nats"
+
+// natsDefaultPort is the port NATS clients connect to.
+const natsDefaultPort = "4222"
+
+// natsPush publishes each notification to a subject on a NATS server,
+// speaking the client protocol directly: INFO, CONNECT, then PUB (or HPUB,
+// with headers) and a PING whose PONG confirms the server took the message.
+// The URL is nats://[user:password@]host[:port]/<subject>; a push token is
+// used as the auth token, or as user:password when it has a colon. With
+// jetstream = true, the publish waits for the stream's acknowledgement, so a
+// message no stream stored is an error instead of silently dropped.
+type natsPush struct {
+	target    pushTarget
+	addr      string // host:port
+	host      string
+	subject   string
+	user      string
+	pass      string
+	token     string
+	jetstream bool
+	tls       bool
+}
+
+func newNATSPush(target pushTarget) (pushProvider, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil || u.Hostname() == "" {
+		return nil, errors.New("no server: use nats://host[:port]/<subject> as the push URL")
+	}
+	p := natsPush{
+		target:    target,
+		host:      u.Hostname(),
+		subject:   target.option("subject", strings.Trim(u.Path, "/")),
+		jetstream: target.Options["jetstream"] == true,
+		tls:       target.Options["tls"] == true,
+	}
+	port := u.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+	p.addr = net.JoinHostPort(p.host, port)
+	if p.subject == "" {
+		return nil, errors.New("no subject: use nats://host[:port]/<subject> as the push URL")
+	}
+	if strings.ContainsAny(p.subject, " \t\r\n*>") || strings.Contains(p.subject, "..") {
+		return nil, fmt.Errorf("invalid subject %q: want dot-separated tokens without wildcards", p.subject)
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			p.user, p.pass = u.User.Username(), pass
+		} else {
+			p.token = u.User.Username()
+		}
+	}
+	if user, pass, ok := strings.Cut(target.Token, ":"); ok {
+		p.user, p.pass, p.token = user, pass, ""
+	} else if target.Token != "" {
+		p.user, p.pass, p.token = "", "", target.Token
+	}
+	if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// natsInfo is the part of the server's INFO reporter uses.
+type natsInfo struct {
+	Headers     bool  `json:"headers"`
+	TLSRequired bool  `json:"tls_required"`
+	MaxPayload  int64 `json:"max_payload"`
+}
+
+// natsHeaders returns the message headers for n: Nats-Msg-Id, which
+// JetStream uses to drop a retried publish, and the outcome, exit code,
+// dedup key, and labels, so consumers can filter without decoding the body.
+func natsHeaders(n notification, version int) []byte {
+	var b bytes.Buffer
+	b.WriteString("NATS/1.0\r\n")
+	add := func(key, value string) {
+		if value = oneLine(value); value != "" {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	add("Nats-Msg-Id", notificationID(n))
+	add("Reporter-Status", notificationOutcome(n))
+	add("Reporter-Exit-Code", strconv.Itoa(n.ExitCode))
+	add("Reporter-Schema", payloadSchema(version))
+	add("Reporter-Dedup-Key", n.DedupKey)
+	if len(n.Labels) > 0 {
+		add("Reporter-Labels", formatLabels(n.Labels))
+	}
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func (p natsPush) Push(ctx context.Context, n notification) error {
+	data, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", p.addr, err)
+	}
+	defer func() { conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(conn)
+	line, err := natsReadLine(r)
+	if err != nil {
+		return fmt.Errorf("reading from %s: %w", p.addr, err)
+	}
+	var info natsInfo
+	rest, ok := strings.CutPrefix(line, "INFO ")
+	if !ok || json.Unmarshal([]byte(rest), &info) != nil {
+		return fmt.Errorf("%s is not a NATS server", p.addr)
+	}
+	if p.tls || info.TLSRequired {
+		tc := tls.Client(conn, &tls.Config{ServerName: p.host})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("TLS handshake with %s: %w", p.addr, err)
+		}
+		conn, r = tc, bufio.NewReader(tc)
+	}
+	if info.MaxPayload > 0 && int64(len(data)) > info.MaxPayload {
+		return fmt.Errorf("the payload is %d bytes, more than the server's limit of %d", len(data), info.MaxPayload)
+	}
+
+	opts := map[string]any{
+		"verbose":       false,
+		"pedantic":      false,
+		"tls_required":  p.tls || info.TLSRequired,
+		"name":          "reporter",
+		"lang":          "go",
+		"version":       Version,
+		"protocol":      1,
+		"headers":       info.Headers,
+		"no_responders": info.Headers,
+	}
+	if p.user != "" {
+		opts["user"], opts["pass"] = p.user, p.pass
+	}
+	if p.token != "" {
+		opts["auth_token"] = p.token
+	}
+	connect, _ := json.Marshal(opts)
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "CONNECT %s\r\n", connect)
+	reply := ""
+	if p.jetstream {
+		var id [8]byte
+		rand.Read(id[:])
+		reply = " _INBOX.reporter." + hex.EncodeToString(id[:])
+		fmt.Fprintf(&out, "SUB%s 1\r\n", reply)
+	}
+	if info.Headers {
+		hdr := natsHeaders(n, p.target.PayloadVersion)
+		fmt.Fprintf(&out, "HPUB %s%s %d %d\r\n%s%s\r\n", p.subject, reply, len(hdr), len(hdr)+len(data), hdr, data)
+	} else {
+		fmt.Fprintf(&out, "PUB %s%s %d\r\n%s\r\n", p.subject, reply, len(data), data)
+	}
+	out.WriteString("PING\r\n")
+	if _, err := conn.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("publishing to %s: %w", p.addr, err)
+	}
+
+	for {
+		line, err := natsReadLine(r)
+		if err != nil {
+			return fmt.Errorf("reading from %s: %w", p.addr, err)
+		}
+		verb, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "PING":
+			if _, err := io.WriteString(conn, "PONG\r\n"); err != nil {
+				return fmt.Errorf("writing to %s: %w", p.addr, err)
+			}
+		case "PONG":
+			// The server has handled the publish; a JetStream one is done
+			// only once the stream acknowledges it.
+			if !p.jetstream {
+				return nil
+			}
+		case "-ERR":
+			return fmt.Errorf("NATS server %s: %s", p.addr, strings.Trim(args, "'"))
+		case "MSG", "HMSG":
+			return p.readAck(r, strings.ToUpper(verb) == "HMSG", strings.Fields(args))
+		}
+	}
+}
+
+// readAck reads the JetStream acknowledgement whose MSG or HMSG line had
+// fields, and returns the error it reports, if any.
+func (p natsPush) readAck(r *bufio.Reader, headers bool, fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("NATS server %s sent a malformed message", p.addr)
+	}
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || total < 0 {
+		return fmt.Errorf("NATS server %s sent a malformed message", p.addr)
+	}
+	hdrLen := 0
+	if headers {
+		if hdrLen, err = strconv.Atoi(fields[len(fields)-2]); err != nil || hdrLen > total {
+			return fmt.Errorf("NATS server %s sent a malformed message", p.addr)
+		}
+	}
+	msg := make([]byte, total+2) // and the trailing CRLF
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return fmt.Errorf("reading from %s: %w", p.addr, err)
+	}
+	// A reply with no payload and a status in its headers, such as
+	// "NATS/1.0 503", means no stream listens on the subject.
+	if status, _, _ := strings.Cut(string(msg[:hdrLen]), "\r\n"); headers && strings.HasPrefix(status, "NATS/1.0 503") {
+		return fmt.Errorf("no JetStream stream listens on subject %s", p.subject)
+	}
+	var ack struct {
+		Stream string `json:"stream"`
+		Error  *struct {
+			Code        int    `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(msg[hdrLen:total], &ack); err != nil {
+		return fmt.Errorf("JetStream returned an unexpected acknowledgement: %w", err)
+	}
+	if ack.Error != nil {
+		code := ack.Error.Code
+		if code == 0 {
+			code = http.StatusBadRequest
+		}
+		return &statusError{code, fmt.Errorf("JetStream rejected the message: %s", ack.Error.Description)}
+	}
+	if ack.Stream == "" {
+		return errors.New("JetStream returned an acknowledgement without a stream")
+	}
+	return nil
+}
+
+// natsReadLine reads a protocol line without its CRLF.
+func natsReadLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,200 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// natsMessage is a message the fake NATS server received.
+type natsMessage struct {
+	connect map[string]any
+	subject string
+	reply   string
+	headers string
+	data    []byte
+}
+
+// fakeNATS serves one connection at a time like a NATS server with headers
+// support, answering each publish with ack (if a reply subject is given)
+// and reporting what it received on the returned channel. An ack of "" means
+// no responders; authErr makes it reject the CONNECT.
+func fakeNATS(t *testing.T, ack, authErr string) (string, <-chan natsMessage) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	msgs := make(chan natsMessage, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			serveNATS(conn, ack, authErr, msgs)
+		}
+	}()
+	return ln.Addr().String(), msgs
+}
+
+func serveNATS(conn net.Conn, ack, authErr string, msgs chan<- natsMessage) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, `INFO {"server_id":"test","headers":true,"max_payload":1048576}`+"\r\n")
+	r := bufio.NewReader(conn)
+	var m natsMessage
+	for {
+		line, err := natsReadLine(r)
+		if err != nil {
+			return
+		}
+		verb, args, _ := strings.Cut(line, " ")
+		f := strings.Fields(args)
+		switch verb {
+		case "CONNECT":
+			json.Unmarshal([]byte(args), &m.connect)
+			if authErr != "" {
+				io.WriteString(conn, "-ERR '"+authErr+"'\r\n")
+				return
+			}
+		case "HPUB":
+			m.subject = f[0]
+			if len(f) == 4 {
+				m.reply = f[1]
+			}
+			hdrLen, _ := strconv.Atoi(f[len(f)-2])
+			total, _ := strconv.Atoi(f[len(f)-1])
+			body := make([]byte, total+2)
+			io.ReadFull(r, body)
+			m.headers, m.data = string(body[:hdrLen]), body[hdrLen:total]
+			msgs <- m
+			switch {
+			case m.reply == "":
+			case ack == "":
+				fmt.Fprintf(conn, "HMSG %s 1 16 16\r\nNATS/1.0 503\r\n\r\n\r\n", m.reply)
+			default:
+				fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", m.reply, len(ack), ack)
+			}
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestPushToNATS(t *testing.T) {
+	addr, msgs := fakeNATS(t, "", "")
+	target := pushTarget{URL: "nats://deploy:s3cret@" + addr + "/jobs.etl", Provider: pushProviderNATS, PayloadVersion: 1}
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m", Subtitle: "./nightly-etl", Failed: true,
+		Finished: time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC), Duration: 3 * time.Minute, ExitCode: 2, DedupKey: "nightly-etl", Labels: map[string]string{"project": "atlas"}}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	m := <-msgs
+	if m.subject != "jobs.etl" || m.reply != "" {
+		t.Errorf("published to %q with reply %q", m.subject, m.reply)
+	}
+	if m.connect["user"] != "deploy" || m.connect["pass"] != "s3cret" || m.connect["headers"] != true {
+		t.Errorf("CONNECT = %v", m.connect)
+	}
+	var data Payload
+	if err := json.Unmarshal(m.data, &data); err != nil || data.Command != "./nightly-etl" || data.ExitCode != 2 {
+		t.Errorf("data = %s (%v)", m.data, err)
+	}
+	for _, want := range []string{
+		"Nats-Msg-Id: " + notificationID(n),
+		"Reporter-Status: failed",
+		"Reporter-Exit-Code: 2",
+		"Reporter-Dedup-Key: nightly-etl",
+		"Reporter-Labels: project=atlas",
+	} {
+		if !strings.Contains(m.headers, want+"\r\n") {
+			t.Errorf("headers missing %q:\n%s", want, m.headers)
+		}
+	}
+
+	// A push token replaces the credentials in the URL.
+	target.Token = "t0ken"
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() with a token returned error: %v", err)
+	}
+	if m := <-msgs; m.connect["auth_token"] != "t0ken" || m.connect["user"] != nil {
+		t.Errorf("CONNECT with a token = %v", m.connect)
+	}
+}
+
+func TestPushToNATSJetStream(t *testing.T) {
+	target := pushTarget{Provider: pushProviderNATS, PayloadVersion: 1, Options: map[string]any{"jetstream": true}}
+	n := notification{Title: "Task finished", Body: "succeeded in 3m", Subtitle: "make"}
+
+	addr, msgs := fakeNATS(t, `{"stream":"JOBS","seq":7}`, "")
+	target.URL = "nats://" + addr + "/jobs.make"
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if m := <-msgs; !strings.HasPrefix(m.reply, "_INBOX.") {
+		t.Errorf("published without a reply subject: %q", m.reply)
+	}
+
+	addr, _ = fakeNATS(t, "", "")
+	target.URL = "nats://" + addr + "/jobs.make"
+	if err := pushToPhone(target, n); err == nil || !strings.Contains(err.Error(), "no JetStream stream") {
+		t.Errorf("pushToPhone() without a stream returned %v", err)
+	}
+
+	addr, _ = fakeNATS(t, `{"error":{"code":400,"err_code":10060,"description":"expected stream does not match"}}`, "")
+	target.URL = "nats://" + addr + "/jobs.make"
+	if err := pushToPhone(target, n); err == nil || pushRetryable(err) || !strings.Contains(err.Error(), "expected stream does not match") {
+		t.Errorf("pushToPhone() with a rejected message returned %v", err)
+	}
+}
+
+func TestPushToNATSAuthorizationError(t *testing.T) {
+	addr, _ := fakeNATS(t, "", "Authorization Violation")
+	target := pushTarget{URL: "nats://" + addr + "/jobs", Provider: pushProviderNATS, Token: "wrong", PayloadVersion: 1}
+	err := pushToPhone(target, notification{Title: "Task finished"})
+	if err == nil || pushRetryable(err) || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("pushToPhone() returned %v", err)
+	}
+}
+
+func TestNewNATSPush(t *testing.T) {
+	for _, tt := range []struct {
+		url     string
+		options map[string]any
+		addr    string
+		subject string
+		token   string
+		wantErr bool
+	}{
+		{url: "nats://localhost/jobs", addr: "localhost:4222", subject: "jobs"},
+		{url: "nats://t0ken@nats.lan:4333/jobs.etl", addr: "nats.lan:4333", subject: "jobs.etl", token: "t0ken"},
+		{url: "nats://localhost", options: map[string]any{"subject": "ci.builds"}, addr: "localhost:4222", subject: "ci.builds"},
+		{url: "nats://localhost", wantErr: true},
+		{url: "nats:///jobs", wantErr: true},
+		{url: "nats://localhost/jobs.>", wantErr: true},
+		{url: "nats://localhost/jobs..etl", wantErr: true},
+	} {
+		p, err := newNATSPush(pushTarget{URL: tt.url, Options: tt.options, PayloadVersion: 1})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newNATSPush(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		np := p.(natsPush)
+		if np.addr != tt.addr || np.subject != tt.subject || np.token != tt.token {
+			t.Errorf("newNATSPush(%q) = %s %s token %q, want %s %s token %q", tt.url, np.addr, np.subject, np.token, tt.addr, tt.subject, tt.token)
+		}
+	}
+}
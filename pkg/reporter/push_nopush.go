@@ -0,0 +1,48 @@
+//go:build nopush
+
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Built with -tags nopush: the HTTP client and push code are left out to keep
+// the binary small and quick to start from shell hooks.
+
+const pushCompiled = false
+
+func pushToPhone(target pushTarget, n notification) error {
+	if target.URL == "" {
+		return nil
+	}
+	return errors.New("push support is not compiled into this build (built with -tags nopush)")
+}
+
+func pushOnce(target pushTarget, n notification) error { return pushToPhone(target, n) }
+
+func pushRetryable(error) bool { return false }
+
+func prewarmPush(string) {}
+
+func (m *sentryMonitor) checkIn(status string) error {
+	return errors.New("sentry check-ins are not compiled into this build (built with -tags nopush)")
+}
+
+func (h *healthcheck) ping(kind, body string) error {
+	return errors.New("healthcheck pings are not compiled into this build (built with -tags nopush)")
+}
+
+func httpCondition(string) (waitCondition, error) {
+	return waitCondition{}, errors.New("-http is not compiled into this build (built with -tags nopush)")
+}
+
+func runServe([]string) int {
+	fmt.Fprintln(os.Stderr, "serve: not compiled into this build (built with -tags nopush)")
+	return 1
+}
+
+func (s *promSink) push(string, []byte) error {
+	return errors.New("pushgateway support is not compiled into this build (built with -tags nopush)")
+}
@@ -0,0 +1,139 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderNtfy, pushProviderSpec{
+		schemes: []string{"ntfy"},
+		match:   isNtfyURL,
+		options: map[string]configKind{
+			"priority":           kindString,
+			"failure_priority":   kindString,
+			"tags":               kindStringList,
+			"failure_tags":       kindStringList,
+			"recovered_priority": kindString,
+			"recovered_tags":     kindStringList,
+			"icon":               kindString,
+		},
+		new: newNtfyPush,
+	})
+}
+
+// ntfyPriorities are the values ntfy accepts in the Priority header.
+var ntfyPriorities = map[string]bool{
+	"1": true, "2": true, "3": true, "4": true, "5": true,
+	"min": true, "low": true, "default": true, "high": true, "max": true, "urgent": true,
+}
+
+// ntfyQuietPriorities are the priorities at which ntfy notifies without
+// sound or vibration.
+var ntfyQuietPriorities = map[string]bool{"1": true, "2": true, "min": true, "low": true}
+
+// ntfyPush publishes to an ntfy topic, using its headers for the title,
+// priority, tags, and click action.
+type ntfyPush struct {
+	target          pushTarget
+	url             string
+	priority        string
+	failurePriority string
+	// recoveredPriority and recoveredTags mark the first success after
+	// failures.
+	recoveredPriority string
+	tags              []string
+	failureTags       []string
+	recoveredTags     []string
+}
+
+func newNtfyPush(target pushTarget) (pushProvider, error) {
+	p := ntfyPush{
+		target:            target,
+		url:               target.URL,
+		priority:          target.option("priority", "default"),
+		failurePriority:   target.option("failure_priority", "high"),
+		recoveredPriority: target.option("recovered_priority", "high"),
+		tags:              target.optionList("tags", []string{"white_check_mark"}),
+		failureTags:       target.optionList("failure_tags", []string{"warning"}),
+		recoveredTags:     target.optionList("recovered_tags", []string{"tada"}),
+	}
+	// ntfy://host/topic is shorthand for https://host/topic.
+	if rest, ok := strings.CutPrefix(p.url, "ntfy://"); ok {
+		p.url = "https://" + rest
+	}
+	for _, prio := range []string{p.priority, p.failurePriority, p.recoveredPriority} {
+		if !ntfyPriorities[prio] {
+			return nil, fmt.Errorf("invalid priority %q: want 1-5, min, low, default, high, max, or urgent", prio)
+		}
+	}
+	return p, nil
+}
+
+// Push sends n. ntfy shows the Title header as the notification title, so the
+// body leaves it out. Header values must be ASCII, so the title is sent as an
+// RFC 2047 encoded word when needed, which ntfy decodes. Notifications with a
+// dedup key share a sequence ID, so ntfy updates the earlier one in place.
+func (p ntfyPush) Push(ctx context.Context, n notification) error {
+	body := fmt.Sprintf("%s\n%s", n.Body, n.Subtitle)
+	if n.Output != "" {
+		body += "\n\n" + n.Output
+	}
+	req, err := newTextPush(ctx, p.url, p.target, n, body)
+	if err != nil {
+		return err
+	}
+	h := req.Header
+	h.Set("Title", mime.QEncoding.Encode("utf-8", n.Title))
+	priority, tags := p.priority, p.tags
+	switch {
+	case n.Failed:
+		priority, tags = p.failurePriority, p.failureTags
+	case n.Recovered > 0:
+		priority, tags = p.recoveredPriority, p.recoveredTags
+	case n.Running:
+		priority, tags = "low", []string{"hourglass_flowing_sand"}
+	}
+	if n.Quiet && !ntfyQuietPriorities[priority] {
+		priority = "low"
+	}
+	h.Set("Priority", priority)
+	if len(tags) > 0 {
+		h.Set("Tags", strings.Join(tags, ","))
+	}
+	if n.DedupKey != "" {
+		h.Set("X-Sequence-ID", ntfySequenceID(n.DedupKey))
+	}
+	if p.target.Click != "" {
+		h.Set("Click", p.target.Click)
+	}
+	if icon := p.target.option("icon", ""); icon != "" {
+		h.Set("Icon", icon)
+	}
+	return sendPush(req)
+}
+
+// isNtfyURL reports whether u points at ntfy.sh or a self-hosted ntfy server
+// whose hostname says so, such as ntfy.example.com.
+func isNtfyURL(u *url.URL) bool {
+	for _, part := range strings.Split(strings.ToLower(u.Hostname()), ".") {
+		if part == "ntfy" {
+			return true
+		}
+	}
+	return false
+}
+
+// ntfySequenceID maps a dedup key, which may be any string, to the letters,
+// digits, dashes, and underscores ntfy allows in a sequence ID.
+func ntfySequenceID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "reporter-" + hex.EncodeToString(sum[:12])
+}
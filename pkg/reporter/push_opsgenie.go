@@ -0,0 +1,144 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderOpsgenie, pushProviderSpec{
+		schemes: []string{"opsgenie"},
+		options: map[string]configKind{
+			"priority": kindString,
+			"tags":     kindStringList,
+		},
+		new: newOpsgeniePush,
+	})
+}
+
+const pushProviderOpsgenie = "opsgenie"
+
+// opsgenieAPI is the Alert API; EU accounts use api.eu.opsgenie.com.
+const opsgenieAPI = "https://api.opsgenie.com"
+
+// Opsgenie's limits on an alert's message and alias, in characters.
+const (
+	opsgenieMessageLimit = 130
+	opsgenieAliasLimit   = 512
+)
+
+// opsgeniePush creates an alert when a job fails and closes it when the job
+// next succeeds, matched by alias (see incidentKey). The URL is opsgenie://
+// or opsgenie://api.eu.opsgenie.com, and the push token is the API key of an
+// API integration.
+type opsgeniePush struct {
+	api      string
+	key      string
+	priority string
+	tags     []string
+}
+
+func newOpsgeniePush(target pushTarget) (pushProvider, error) {
+	p := opsgeniePush{
+		api:      opsgenieAPI,
+		key:      target.Token,
+		priority: target.option("priority", "P1"),
+		tags:     target.optionList("tags", nil),
+	}
+	if rest, ok := strings.CutPrefix(target.URL, "opsgenie://"); ok {
+		if host := strings.Trim(rest, "/"); host != "" {
+			p.api = "https://" + host
+		}
+	} else {
+		p.api = strings.TrimSuffix(target.URL, "/")
+	}
+	if p.key == "" {
+		return nil, errors.New("no API key: set REPORTER_PUSH_TOKEN or push_token to the integration's API key")
+	}
+	switch p.priority {
+	case "P1", "P2", "P3", "P4", "P5":
+	default:
+		return nil, fmt.Errorf("invalid priority %q: want P1 to P5", p.priority)
+	}
+	return p, nil
+}
+
+// opsgenieAlert is the body of POST /v2/alerts.
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details"`
+}
+
+func (p opsgeniePush) Push(ctx context.Context, n notification) error {
+	trigger, ok := incidentEvent(n)
+	if !ok {
+		return nil
+	}
+	host, _ := os.Hostname()
+	alias := clipRunes(incidentKey(n), opsgenieAliasLimit)
+	if !trigger {
+		body := map[string]string{"source": host, "note": n.Body}
+		endpoint := p.api + "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		return p.post(ctx, endpoint, body)
+	}
+	details := map[string]string{}
+	for k, v := range incidentDetails(n) {
+		details[k] = fmt.Sprint(v)
+	}
+	alert := opsgenieAlert{
+		Message:     clipRunes(n.Title+": "+oneLine(n.Subtitle), opsgenieMessageLimit),
+		Alias:       alias,
+		Description: strings.TrimSpace(n.Body + "\n\n" + n.Output),
+		Source:      host,
+		Priority:    p.priority,
+		Tags:        p.tags,
+		Details:     details,
+	}
+	return p.post(ctx, p.api+"/v2/alerts", alert)
+}
+
+// post sends body to endpoint. Opsgenie accepts a request before processing
+// it, so closing an alert that does not exist still succeeds.
+func (p opsgeniePush) post(ctx context.Context, endpoint string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+p.key)
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(msg, &apiErr) == nil && apiErr.Message != "" {
+		return &statusError{resp.StatusCode, fmt.Errorf("opsgenie returned %s: %s", resp.Status, apiErr.Message)}
+	}
+	return &statusError{resp.StatusCode, fmt.Errorf("opsgenie returned %s", resp.Status)}
+}
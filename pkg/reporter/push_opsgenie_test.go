@@ -0,0 +1,58 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPushToOpsgenie(t *testing.T) {
+	type request struct {
+		path, auth string
+		body       map[string]any
+	}
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(b, &body)
+		got = append(got, request{r.URL.RequestURI(), r.Header.Get("Authorization"), body})
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"result":"Request will be processed","requestId":"43a29c5c"}`)
+	}))
+	defer srv.Close()
+
+	target := pushTarget{
+		URL: srv.URL, Provider: pushProviderOpsgenie, Token: "eb243592",
+		Options: map[string]any{"priority": "P2", "tags": []any{"etl"}},
+	}
+	finished := time.Date(2026, 3, 6, 2, 0, 0, 0, time.UTC)
+	failed := notification{
+		Title: "Task finished", Body: "failed (exit 1) in 2h", Subtitle: "./nightly-etl", Failed: true, ExitCode: 1,
+		Duration: 2 * time.Hour, Finished: finished, DedupKey: "etl/prod",
+	}
+	succeeded := notification{Title: "Task finished", Body: "succeeded in 2h", Subtitle: "./nightly-etl", Finished: finished, DedupKey: "etl/prod"}
+	for _, n := range []notification{failed, succeeded} {
+		if err := pushToPhone(target, n); err != nil {
+			t.Fatalf("pushToPhone() returned error: %v", err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("sent %d requests, want a create and a close", len(got))
+	}
+	create, closed := got[0], got[1]
+	if create.path != "/v2/alerts" || create.auth != "GenieKey eb243592" {
+		t.Errorf("create went to %q with Authorization %q", create.path, create.auth)
+	}
+	if create.body["alias"] != "etl/prod" || create.body["priority"] != "P2" || create.body["message"] != "Task finished: ./nightly-etl" {
+		t.Errorf("alert = %v", create.body)
+	}
+	if closed.path != "/v2/alerts/etl%2Fprod/close?identifierType=alias" {
+		t.Errorf("close went to %q, want the alert's alias", closed.path)
+	}
+}
@@ -0,0 +1,190 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderPagerDuty, pushProviderSpec{
+		schemes: []string{"pagerduty"},
+		match:   isPagerDutyEventsURL,
+		options: map[string]configKind{
+			"severity": kindString,
+		},
+		new: newPagerDutyPush,
+	})
+}
+
+const pushProviderPagerDuty = "pagerduty"
+
+// pagerDutyEvents is the Events API v2 endpoint.
+const pagerDutyEvents = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySummaryLimit is the most characters PagerDuty keeps of a summary.
+const pagerDutySummaryLimit = 1024
+
+// Incident providers open an incident when a job fails and resolve it when
+// the job next succeeds. Both are keyed by the job, so the success finds the
+// failure's incident and repeated failures of a job add to one incident.
+// Progress updates, digests, and commands stopped with Ctrl-C send nothing.
+
+// incidentKey names the job of n: its dedup key, or else a hash of the host
+// and command, which stays the same when a push is resent from the spool.
+func incidentKey(n notification) string {
+	if n.DedupKey != "" {
+		return n.DedupKey
+	}
+	host, _ := os.Hostname()
+	sum := sha256.Sum256([]byte(host + "\x00" + n.Subtitle))
+	return "reporter-" + hex.EncodeToString(sum[:8])
+}
+
+// incidentEvent reports whether n opens or resolves an incident, and false
+// if it does neither, as for a failed attempt that will be retried.
+func incidentEvent(n notification) (trigger, ok bool) {
+	if n.Running || n.Retrying || n.Finished.IsZero() || n.ExitCode == exitInterrupted {
+		return false, false
+	}
+	return n.Failed, true
+}
+
+// incidentDetails are the facts about a failed run attached to its incident.
+func incidentDetails(n notification) map[string]any {
+	host, _ := os.Hostname()
+	details := map[string]any{
+		"command":   n.Subtitle,
+		"host":      host,
+		"exit_code": n.ExitCode,
+		"duration":  formatDuration(n.Duration),
+	}
+	if n.Output != "" {
+		details["output"] = n.Output
+	}
+	for k, v := range n.Labels {
+		details["label_"+k] = v
+	}
+	return details
+}
+
+// isPagerDutyEventsURL reports whether u is an Events API endpoint, such as
+// events.pagerduty.com or events.eu.pagerduty.com.
+func isPagerDutyEventsURL(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	return strings.HasPrefix(host, "events.") && strings.HasSuffix(host, ".pagerduty.com")
+}
+
+// pagerDutyPush sends Events API v2 events. The URL is pagerduty:// for the
+// default endpoint, pagerduty://events.eu.pagerduty.com for the EU one, or an
+// Events API URL, and the push token is the service's integration (routing)
+// key.
+type pagerDutyPush struct {
+	endpoint   string
+	routingKey string
+	severity   string
+}
+
+func newPagerDutyPush(target pushTarget) (pushProvider, error) {
+	p := pagerDutyPush{endpoint: target.URL, routingKey: target.Token, severity: target.option("severity", "critical")}
+	if rest, ok := strings.CutPrefix(p.endpoint, "pagerduty://"); ok {
+		p.endpoint = pagerDutyEvents
+		if host := strings.Trim(rest, "/"); host != "" {
+			p.endpoint = "https://" + host + "/v2/enqueue"
+		}
+	}
+	if p.routingKey == "" {
+		return nil, errors.New("no integration key: set REPORTER_PUSH_TOKEN or push_token to the service's Events API v2 key")
+	}
+	switch p.severity {
+	case "critical", "error", "warning", "info":
+	default:
+		return nil, fmt.Errorf("invalid severity %q: want critical, error, warning, or info", p.severity)
+	}
+	return p, nil
+}
+
+// pagerDutyEvent is an Events API v2 event.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // trigger or resolve
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"` // only for trigger
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     string         `json:"timestamp"`
+	CustomDetails map[string]any `json:"custom_details"`
+}
+
+// event renders n, or returns false if n sends no event.
+func (p pagerDutyPush) event(n notification) (pagerDutyEvent, bool) {
+	trigger, ok := incidentEvent(n)
+	if !ok {
+		return pagerDutyEvent{}, false
+	}
+	e := pagerDutyEvent{RoutingKey: p.routingKey, EventAction: "resolve", DedupKey: incidentKey(n)}
+	if trigger {
+		host, _ := os.Hostname()
+		e.EventAction = "trigger"
+		e.Payload = &pagerDutyPayload{
+			Summary:       clipRunes(n.Title+": "+oneLine(n.Subtitle)+" "+n.Body, pagerDutySummaryLimit),
+			Source:        host,
+			Severity:      p.severity,
+			Timestamp:     n.Finished.Format(time.RFC3339),
+			CustomDetails: incidentDetails(n),
+		}
+	}
+	return e, true
+}
+
+func (p pagerDutyPush) Push(ctx context.Context, n notification) error {
+	e, ok := p.event(n)
+	if !ok {
+		return nil
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	// PagerDuty explains rejected events, such as an unknown routing key, in
+	// message and errors.
+	var apiErr struct {
+		Message string   `json:"message"`
+		Errors  []string `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+		reason := strings.Join(append([]string{apiErr.Message}, apiErr.Errors...), ": ")
+		return &statusError{resp.StatusCode, fmt.Errorf("pagerduty returned %s: %s", resp.Status, reason)}
+	}
+	return &statusError{resp.StatusCode, fmt.Errorf("pagerduty returned %s", resp.Status)}
+}
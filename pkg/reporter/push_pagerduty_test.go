@@ -0,0 +1,107 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToPagerDuty(t *testing.T) {
+	var events []pagerDutyEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e pagerDutyEvent
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &e); err != nil {
+			t.Errorf("invalid event %s: %v", b, err)
+		}
+		if e.RoutingKey != "R0UT1NG" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"status":"invalid event","message":"Event object is invalid","errors":["Invalid routing key"]}`)
+			return
+		}
+		events = append(events, e)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderPagerDuty, Token: "R0UT1NG", Options: map[string]any{"severity": "error"}}
+	finished := time.Date(2026, 3, 6, 2, 0, 0, 0, time.UTC)
+	failed := notification{
+		Title: "Task finished", Body: "failed (exit 1) in 2h", Subtitle: "./nightly-etl", Failed: true, ExitCode: 1,
+		Duration: 2 * time.Hour, Finished: finished, Output: "connection refused",
+	}
+	succeeded := notification{Title: "Task finished", Body: "succeeded in 2h", Subtitle: "./nightly-etl", Duration: 2 * time.Hour, Finished: finished.Add(24 * time.Hour)}
+	for _, n := range []notification{
+		failed,
+		succeeded,
+		progressNotification("./nightly-etl", "", time.Hour, nil),
+		{Title: "Task finished", Subtitle: "./nightly-etl", Failed: true, ExitCode: exitInterrupted, Finished: finished},
+	} {
+		if err := pushToPhone(target, n); err != nil {
+			t.Fatalf("pushToPhone() returned error: %v", err)
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("sent %d events, want a trigger and a resolve: %+v", len(events), events)
+	}
+	trigger, resolve := events[0], events[1]
+	if trigger.EventAction != "trigger" || resolve.EventAction != "resolve" {
+		t.Errorf("event actions %q, %q; want trigger then resolve", trigger.EventAction, resolve.EventAction)
+	}
+	if trigger.DedupKey == "" || trigger.DedupKey != resolve.DedupKey {
+		t.Errorf("dedup keys %q, %q; want the same job's key on both", trigger.DedupKey, resolve.DedupKey)
+	}
+	if p := trigger.Payload; p == nil || p.Severity != "error" || p.Summary != "Task finished: ./nightly-etl failed (exit 1) in 2h" ||
+		p.CustomDetails["output"] != "connection refused" {
+		t.Errorf("trigger payload = %+v", trigger.Payload)
+	}
+	if resolve.Payload != nil {
+		t.Errorf("resolve carries a payload: %+v", resolve.Payload)
+	}
+
+	failed.DedupKey = "etl-prod"
+	if e, _ := (pagerDutyPush{}).event(failed); e.DedupKey != "etl-prod" {
+		t.Errorf("dedup key = %q, want the -dedup-key", e.DedupKey)
+	}
+
+	target.Token = "wrong"
+	if err := pushToPhone(target, failed); err == nil || !strings.Contains(err.Error(), "Invalid routing key") {
+		t.Errorf("pushToPhone() with a bad key = %v, want PagerDuty's message", err)
+	}
+}
+
+func TestNewPagerDutyPush(t *testing.T) {
+	tests := []struct {
+		url, severity string
+		endpoint      string
+		wantErr       bool
+	}{
+		{url: "pagerduty://", endpoint: pagerDutyEvents},
+		{url: "pagerduty://events.eu.pagerduty.com", endpoint: "https://events.eu.pagerduty.com/v2/enqueue"},
+		{url: "https://events.pagerduty.com/v2/enqueue", endpoint: pagerDutyEvents},
+		{url: "pagerduty://", severity: "urgent", wantErr: true},
+	}
+	for _, tt := range tests {
+		target := pushTarget{URL: tt.url, Token: "k"}
+		if tt.severity != "" {
+			target.Options = map[string]any{"severity": tt.severity}
+		}
+		p, err := newPagerDutyPush(target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newPagerDutyPush(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.(pagerDutyPush).endpoint != tt.endpoint {
+			t.Errorf("newPagerDutyPush(%q) endpoint = %q, want %q", tt.url, p.(pagerDutyPush).endpoint, tt.endpoint)
+		}
+	}
+	if got := detectPushProvider("https://events.eu.pagerduty.com/v2/enqueue"); got != pushProviderPagerDuty {
+		t.Errorf("detectPushProvider() = %q, want pagerduty", got)
+	}
+}
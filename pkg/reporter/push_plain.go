@@ -0,0 +1,32 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	registerPushProvider(pushProviderPlain, pushProviderSpec{
+		new: func(target pushTarget) (pushProvider, error) {
+			return plainPush{target: target}, nil
+		},
+	})
+}
+
+// plainPush POSTs the notification as text, which works with most webhook
+// receivers. It is used for URLs no other provider recognises.
+type plainPush struct{ target pushTarget }
+
+func (p plainPush) Push(ctx context.Context, n notification) error {
+	body := fmt.Sprintf("%s — %s\n%s", n.Title, n.Body, n.Subtitle)
+	if n.Output != "" {
+		body += "\n\n" + n.Output
+	}
+	req, err := newTextPush(ctx, p.target.URL, p.target, n, body)
+	if err != nil {
+		return err
+	}
+	return sendPush(req)
+}
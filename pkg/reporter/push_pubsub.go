@@ -0,0 +1,163 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderPubSub, pushProviderSpec{
+		schemes: []string{"pubsub"},
+		options: map[string]configKind{
+			"api_url": kindString,
+		},
+		new: newPubSubPush,
+	})
+}
+
+const pushProviderPubSub = "pubsub"
+
+// pubSubAPI is the Pub/Sub REST API; api_url overrides it, and
+// PUBSUB_EMULATOR_HOST points it at the emulator.
+const pubSubAPI = "https://pubsub.googleapis.com/v1"
+
+// pubSubScope is the OAuth scope for publishing.
+const pubSubScope = "https://www.googleapis.com/auth/pubsub"
+
+// Pub/Sub's limits on attribute keys and values, in bytes.
+const (
+	pubSubKeyLimit   = 256
+	pubSubValueLimit = 1024
+)
+
+// pubSubPush publishes each notification to a Google Cloud Pub/Sub topic,
+// for subscribers that start follow-up work. The URL is
+// pubsub://<project>/<topic>, or pubsub://<topic> for the project of the
+// credentials or VM. The message data is the JSON payload, and attributes
+// carry what subscription filters need (see pubSubAttributes).
+type pubSubPush struct {
+	target  pushTarget
+	project string
+	topic   string
+}
+
+func newPubSubPush(target pushTarget) (pushProvider, error) {
+	rest, _ := strings.CutPrefix(target.URL, "pubsub://")
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	// Accept the topic's resource name too: projects/<project>/topics/<topic>.
+	if len(parts) == 4 && parts[0] == "projects" && parts[2] == "topics" {
+		parts = []string{parts[1], parts[3]}
+	}
+	p := pubSubPush{target: target}
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		p.topic = parts[0]
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		p.project, p.topic = parts[0], parts[1]
+	default:
+		return nil, errors.New("no topic: use pubsub://<project>/<topic> as the push URL")
+	}
+	if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// pubSubAttributes returns the message attributes for n: status (see
+// notificationOutcome), exit_code, duration_ms, command, host, schema, the
+// dedup key, and each label as label_<key>.
+func pubSubAttributes(n notification, version int) map[string]string {
+	host, _ := os.Hostname()
+	attrs := map[string]string{
+		"status":      notificationOutcome(n),
+		"exit_code":   strconv.Itoa(n.ExitCode),
+		"duration_ms": strconv.FormatInt(n.Duration.Milliseconds(), 10),
+		"command":     oneLine(n.Subtitle),
+		"host":        host,
+		"schema":      payloadSchema(version),
+	}
+	if n.DedupKey != "" {
+		attrs["dedup_key"] = n.DedupKey
+	}
+	for k, v := range n.Labels {
+		attrs["label_"+k] = v
+	}
+	for k, v := range attrs {
+		if len(k) > pubSubKeyLimit {
+			delete(attrs, k)
+		} else if v == "" {
+			delete(attrs, k) // empty values are rejected
+		} else if len(v) > pubSubValueLimit {
+			attrs[k] = clipRunes(v, pubSubValueLimit/4)
+		}
+	}
+	return attrs
+}
+
+func (p pubSubPush) Push(ctx context.Context, n notification) error {
+	data, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{
+		"messages": []map[string]any{{
+			"data":       data, // []byte marshals as base64
+			"attributes": pubSubAttributes(n, p.target.PayloadVersion),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	api, token := p.target.option("api_url", pubSubAPI), p.target.Token
+	if emulator := os.Getenv("PUBSUB_EMULATOR_HOST"); emulator != "" {
+		api = "http://" + emulator + "/v1"
+	} else if token == "" {
+		if token, err = googleAccessToken(ctx, pubSubScope, os.Getenv); err != nil {
+			return err
+		}
+	}
+	project := p.project
+	if project == "" {
+		if project = googleProject(ctx, os.Getenv); project == "" {
+			return errors.New("no Google Cloud project: use pubsub://<project>/<topic> or set GOOGLE_CLOUD_PROJECT")
+		}
+	}
+	endpoint := strings.TrimSuffix(api, "/") + "/projects/" + project + "/topics/" + p.topic + ":publish"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(msg, &apiErr) == nil && apiErr.Error.Message != "" {
+		return &statusError{resp.StatusCode, fmt.Errorf("pub/sub returned %s: %s", resp.Status, apiErr.Error.Message)}
+	}
+	return &statusError{resp.StatusCode, fmt.Errorf("pub/sub returned %s", resp.Status)}
+}
@@ -0,0 +1,97 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToPubSub(t *testing.T) {
+	var gotPath, gotAuth string
+	var got struct {
+		Messages []struct {
+			Data       []byte
+			Attributes map[string]string
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %v\n%s", err, b)
+		}
+		w.Write([]byte(`{"messageIds":["4242"]}`))
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: "pubsub://etl-prod/job-results", Provider: pushProviderPubSub, Token: "ya29.token", PayloadVersion: 1,
+		Options: map[string]any{"api_url": srv.URL + "/v1"}}
+	n := notification{Title: "Task finished", Body: "failed (exit 2) in 3m", Subtitle: "./nightly-etl", Failed: true,
+		Finished: time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC), Duration: 3 * time.Minute, ExitCode: 2,
+		Labels: map[string]string{"project": "atlas"}}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotPath != "/v1/projects/etl-prod/topics/job-results:publish" || gotAuth != "Bearer ya29.token" {
+		t.Errorf("published to %s with %q", gotPath, gotAuth)
+	}
+	if len(got.Messages) != 1 {
+		t.Fatalf("messages = %+v", got.Messages)
+	}
+	m := got.Messages[0]
+	var data Payload
+	if err := json.Unmarshal(m.Data, &data); err != nil || data.Command != "./nightly-etl" || data.ExitCode != 2 {
+		t.Errorf("data = %s (%v)", m.Data, err)
+	}
+	for k, want := range map[string]string{
+		"status":        "failed",
+		"exit_code":     "2",
+		"duration_ms":   "180000",
+		"command":       "./nightly-etl",
+		"schema":        "reporter/v1",
+		"label_project": "atlas",
+	} {
+		if m.Attributes[k] != want {
+			t.Errorf("attribute %s = %q, want %q", k, m.Attributes[k], want)
+		}
+	}
+
+	// The emulator needs no credentials.
+	t.Setenv("PUBSUB_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	target.Token, target.Options = "", nil
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() to the emulator returned error: %v", err)
+	}
+	if gotAuth != "" {
+		t.Errorf("Authorization = %q for the emulator, want none", gotAuth)
+	}
+}
+
+func TestNewPubSubPush(t *testing.T) {
+	for _, tt := range []struct {
+		url, project, topic string
+	}{
+		{"pubsub://etl-prod/job-results", "etl-prod", "job-results"},
+		{"pubsub://projects/etl-prod/topics/job-results", "etl-prod", "job-results"},
+		{"pubsub://job-results", "", "job-results"},
+		{"pubsub://", "", ""},
+		{"pubsub://a/b/c", "", ""},
+	} {
+		p, err := newPubSubPush(pushTarget{URL: tt.url, PayloadVersion: 1})
+		if tt.topic == "" {
+			if err == nil {
+				t.Errorf("newPubSubPush(%q) succeeded", tt.url)
+			}
+			continue
+		}
+		if err != nil || p.(pubSubPush).project != tt.project || p.(pubSubPush).topic != tt.topic {
+			t.Errorf("newPubSubPush(%q) = %+v, %v", tt.url, p, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerPushProvider(pushProviderPushover, pushProviderSpec{
+		schemes: []string{"pushover"},
+		options: map[string]configKind{
+			"user":               kindString,
+			"device":             kindString,
+			"priority":           kindString,
+			"failure_priority":   kindString,
+			"interrupt_priority": kindString,
+			"recovered_priority": kindString,
+			"sound":              kindString,
+			"failure_sound":      kindString,
+			"retry":              kindDuration,
+			"expire":             kindDuration,
+			"api_url":            kindString,
+		},
+		new: newPushoverPush,
+	})
+}
+
+const pushProviderPushover = "pushover"
+
+const pushoverAPI = "https://api.pushover.net/1/messages.json"
+
+// Pushover's limits on titles and messages.
+const (
+	pushoverTitleLimit   = 250
+	pushoverMessageLimit = 1024
+)
+
+// pushoverQuiet is the highest priority that arrives without sound or
+// vibration, used during quiet hours.
+const pushoverQuiet = -1
+
+// pushoverEmergency is the priority that repeats until acknowledged; it
+// needs retry and expire, which Pushover bounds.
+const (
+	pushoverEmergency = 2
+	pushoverMinRetry  = 30 * time.Second
+	pushoverMaxExpire = 3 * time.Hour
+)
+
+// pushoverPush sends a form-encoded message through the Pushover API. The URL
+// names the user or group key (pushover://<user_key>) and the push token is
+// the application token.
+type pushoverPush struct {
+	endpoint string
+	token    string
+	user     string
+	device   string
+	// success, failure, interrupt, and recovered are the priorities, from
+	// -2 to 2, for each outcome.
+	success, failure, interrupt, recovered int
+	sound, failureSound                    string
+	retry, expire                          time.Duration
+}
+
+func newPushoverPush(target pushTarget) (pushProvider, error) {
+	p := pushoverPush{
+		endpoint:     target.option("api_url", pushoverAPI),
+		token:        target.Token,
+		user:         target.option("user", ""),
+		device:       target.option("device", ""),
+		sound:        target.option("sound", ""),
+		failureSound: target.option("failure_sound", ""),
+	}
+	if rest, ok := strings.CutPrefix(target.URL, "pushover://"); ok && strings.Trim(rest, "/") != "" {
+		p.user = strings.Trim(rest, "/")
+	}
+	if p.user == "" {
+		return nil, errors.New("no user key: use pushover://<user_key> as the push URL or set user in [push.pushover]")
+	}
+	if p.token == "" {
+		return nil, errors.New("no application token: set REPORTER_PUSH_TOKEN or push_token")
+	}
+
+	var err error
+	for _, prio := range []struct {
+		key string
+		def int
+		dst *int
+	}{
+		{"priority", 0, &p.success},
+		{"failure_priority", 1, &p.failure},
+		{"interrupt_priority", -1, &p.interrupt},
+		{"recovered_priority", 1, &p.recovered},
+	} {
+		if *prio.dst, err = parsePushoverPriority(target.option(prio.key, strconv.Itoa(prio.def))); err != nil {
+			return nil, fmt.Errorf("%s: %w", prio.key, err)
+		}
+	}
+	if p.retry, err = time.ParseDuration(target.option("retry", "1m")); err != nil || p.retry < pushoverMinRetry {
+		return nil, fmt.Errorf("retry must be at least %s", pushoverMinRetry)
+	}
+	if p.expire, err = time.ParseDuration(target.option("expire", "1h")); err != nil || p.expire <= 0 || p.expire > pushoverMaxExpire {
+		return nil, fmt.Errorf("expire must be positive and at most %s", pushoverMaxExpire)
+	}
+	return p, nil
+}
+
+// parsePushoverPriority accepts Pushover's numeric priorities or their names.
+func parsePushoverPriority(s string) (int, error) {
+	names := map[string]int{"lowest": -2, "low": -1, "normal": 0, "high": 1, "emergency": 2}
+	if n, ok := names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= -2 && n <= 2 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q: want -2 to 2 or lowest, low, normal, high, or emergency", s)
+}
+
+// form renders n as the fields of a Pushover message.
+func (p pushoverPush) form(n notification) url.Values {
+	priority, sound := p.success, p.sound
+	switch {
+	case n.Failed && n.ExitCode == exitInterrupted:
+		priority = p.interrupt
+	case n.Failed:
+		priority = p.failure
+		if p.failureSound != "" {
+			sound = p.failureSound
+		}
+	case n.Recovered > 0:
+		priority = p.recovered
+	}
+	if n.Quiet {
+		priority = min(priority, pushoverQuiet)
+	}
+
+	message := n.Body
+	if n.Subtitle != "" {
+		message += "\n" + n.Subtitle
+	}
+	if n.Output != "" {
+		message += "\n\n" + n.Output
+	}
+	if r := []rune(message); len(r) > pushoverMessageLimit {
+		message = string(r[:pushoverMessageLimit-1]) + "…"
+	}
+	title := n.Title
+	if r := []rune(title); len(r) > pushoverTitleLimit {
+		title = string(r[:pushoverTitleLimit-1]) + "…"
+	}
+
+	v := url.Values{
+		"token":    {p.token},
+		"user":     {p.user},
+		"title":    {title},
+		"message":  {message},
+		"priority": {strconv.Itoa(priority)},
+	}
+	if priority == pushoverEmergency {
+		v.Set("retry", strconv.Itoa(int(p.retry.Seconds())))
+		v.Set("expire", strconv.Itoa(int(p.expire.Seconds())))
+	}
+	if p.device != "" {
+		v.Set("device", p.device)
+	}
+	if sound != "" {
+		v.Set("sound", sound)
+	}
+	return v
+}
+
+func (p pushoverPush) Push(ctx context.Context, n notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, strings.NewReader(p.form(n).Encode()))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", p.endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if json.Unmarshal(data, &apiErr) == nil && len(apiErr.Errors) > 0 {
+			return &statusError{resp.StatusCode, fmt.Errorf("pushover returned %s: %s", resp.Status, strings.Join(apiErr.Errors, "; "))}
+		}
+		return &statusError{resp.StatusCode, fmt.Errorf("pushover returned %s", resp.Status)}
+	}
+	return nil
+}
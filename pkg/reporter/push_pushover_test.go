@@ -0,0 +1,128 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPushToPushover(t *testing.T) {
+	var got url.Values
+	var contentType string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		got, _ = url.ParseQuery(string(b))
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, `{"user":"invalid","errors":["user identifier is not a valid user, group, or subscribed user key"],"status":0}`)
+		}
+	}))
+	defer srv.Close()
+
+	target := pushTarget{
+		URL:      "pushover://uQiRzpo4DXghDmr9QzzfQu27cmVRsG",
+		Provider: pushProviderPushover,
+		Token:    "azGDORePK8gMaC0QOYAMyEEuzJnyUi",
+		Options: map[string]any{
+			"api_url":          srv.URL,
+			"failure_priority": "emergency",
+			"failure_sound":    "siren",
+			"retry":            "45s",
+		},
+	}
+	tests := []struct {
+		name string
+		n    notification
+		want map[string]string
+	}{
+		{
+			name: "success",
+			n:    notification{Title: "Build", Body: "succeeded in 3m", Subtitle: "make"},
+			want: map[string]string{
+				"token": "azGDORePK8gMaC0QOYAMyEEuzJnyUi", "user": "uQiRzpo4DXghDmr9QzzfQu27cmVRsG",
+				"title": "Build", "message": "succeeded in 3m\nmake", "priority": "0", "retry": "", "sound": "",
+			},
+		},
+		{
+			name: "failure is an emergency",
+			n:    notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true, ExitCode: 2, Output: "FAIL"},
+			want: map[string]string{
+				"message": "failed (exit 2) in 3m\nmake\n\nFAIL", "priority": "2", "retry": "45", "expire": "3600", "sound": "siren",
+			},
+		},
+		{
+			name: "interrupted",
+			n:    notification{Title: "Build", Body: "failed (exit 130) in 3m", Failed: true, ExitCode: exitInterrupted},
+			want: map[string]string{"priority": "-1", "retry": "", "sound": ""},
+		},
+		{
+			name: "quiet hours",
+			n:    notification{Title: "Build", Body: "failed (exit 2) in 3m", Failed: true, ExitCode: 2, Quiet: true},
+			want: map[string]string{"priority": "-1", "retry": "", "expire": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pushToPhone(target, tt.n); err != nil {
+				t.Fatalf("pushToPhone() returned error: %v", err)
+			}
+			if contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("Content-Type = %q", contentType)
+			}
+			for k, want := range tt.want {
+				if g := got.Get(k); g != want {
+					t.Errorf("%s = %q, want %q", k, g, want)
+				}
+			}
+		})
+	}
+
+	status = http.StatusBadRequest
+	err := pushToPhone(target, notification{Title: "x"})
+	if err == nil || !strings.Contains(err.Error(), "not a valid user") {
+		t.Errorf("pushToPhone() error = %v, want Pushover's error message", err)
+	}
+}
+
+func TestNewPushoverPush(t *testing.T) {
+	base := pushTarget{URL: "pushover://ukey", Token: "app"}
+	tests := []struct {
+		name    string
+		options map[string]any
+		url     string
+		token   string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "user option", url: "pushover://", options: map[string]any{"user": "gkey"}},
+		{name: "named priority", options: map[string]any{"priority": "lowest"}},
+		{name: "no user", url: "pushover://", wantErr: true},
+		{name: "no token", token: "-", wantErr: true},
+		{name: "bad priority", options: map[string]any{"failure_priority": "3"}, wantErr: true},
+		{name: "retry too short", options: map[string]any{"retry": "10s"}, wantErr: true},
+		{name: "expire too long", options: map[string]any{"expire": "4h"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := base
+			target.Options = tt.options
+			if tt.url != "" {
+				target.URL = tt.url
+			}
+			if tt.token == "-" {
+				target.Token = ""
+			}
+			_, err := newPushoverPush(target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderRedis, pushProviderSpec{
+		schemes: []string{"redis", "rediss"},
+		options: map[string]configKind{
+			"channel": kindString,
+			"stream":  kindBool,
+			"maxlen":  kindInt,
+			"db":      kindInt,
+		},
+		new: newRedisPush,
+	})
+}
+
+const pushProviderRedis = "redis"
+
+// redisDefaultPort is the port Redis clients connect to.
+const redisDefaultPort = "6379"
+
+// redisPush puts each notification on Redis, speaking RESP directly: it
+// PUBLISHes the JSON payload to a channel, or with stream = true, XADDs an
+// entry to a stream, whose consumers can read it later. The URL is
+// redis://[user:password@]host[:port]/<channel>, or rediss:// for TLS; a
+// push token is used as the password, or as user:password when it has a
+// colon.
+type redisPush struct {
+	target pushTarget
+	addr   string // host:port
+	host   string
+	key    string // the channel or stream
+	user   string
+	pass   string
+	stream bool
+	maxLen int
+	db     int
+	tls    bool
+}
+
+func newRedisPush(target pushTarget) (pushProvider, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil || u.Hostname() == "" {
+		return nil, errors.New("no server: use redis://host[:port]/<channel> as the push URL")
+	}
+	p := redisPush{
+		target: target,
+		host:   u.Hostname(),
+		key:    target.option("channel", strings.Trim(u.Path, "/")),
+		stream: target.Options["stream"] == true,
+		tls:    u.Scheme == "rediss",
+	}
+	port := u.Port()
+	if port == "" {
+		port = redisDefaultPort
+	}
+	p.addr = net.JoinHostPort(p.host, port)
+	if p.key == "" {
+		return nil, errors.New("no channel: use redis://host[:port]/<channel> as the push URL")
+	}
+	if p.maxLen = target.optionInt("maxlen", 0); p.maxLen < 0 {
+		return nil, fmt.Errorf("invalid maxlen %d: want a number of entries", p.maxLen)
+	}
+	if p.db = target.optionInt("db", 0); p.db < 0 {
+		return nil, fmt.Errorf("invalid db %d: want a database number", p.db)
+	}
+	if u.User != nil {
+		p.pass, _ = u.User.Password()
+		p.user = u.User.Username()
+		if p.pass == "" {
+			// redis://secret@host, as redis-cli -u reads it
+			p.user, p.pass = "", p.user
+		}
+	}
+	if user, pass, ok := strings.Cut(target.Token, ":"); ok {
+		p.user, p.pass = user, pass
+	} else if target.Token != "" {
+		p.user, p.pass = "", target.Token
+	}
+	if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// redisStreamFields returns the fields of n's stream entry: the JSON
+// payload, and the outcome, exit code, dedup key, and labels, so consumers
+// can filter without decoding it.
+func redisStreamFields(n notification, version int, data []byte) []string {
+	fields := []string{
+		"id", notificationID(n),
+		"status", notificationOutcome(n),
+		"exit_code", strconv.Itoa(n.ExitCode),
+		"schema", payloadSchema(version),
+	}
+	if n.DedupKey != "" {
+		fields = append(fields, "dedup_key", n.DedupKey)
+	}
+	if len(n.Labels) > 0 {
+		fields = append(fields, "labels", formatLabels(n.Labels))
+	}
+	return append(fields, "payload", string(data))
+}
+
+func (p redisPush) Push(ctx context.Context, n notification) error {
+	data, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", p.addr, err)
+	}
+	defer func() { conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if p.tls {
+		tc := tls.Client(conn, &tls.Config{ServerName: p.host})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("TLS handshake with %s: %w", p.addr, err)
+		}
+		conn = tc
+	}
+
+	// The commands are pipelined, and their replies read in order.
+	var out bytes.Buffer
+	var commands []string
+	send := func(args ...string) {
+		commands = append(commands, args[0])
+		writeRedisCommand(&out, args...)
+	}
+	switch {
+	case p.user != "":
+		send("AUTH", p.user, p.pass)
+	case p.pass != "":
+		send("AUTH", p.pass)
+	}
+	if p.db != 0 {
+		send("SELECT", strconv.Itoa(p.db))
+	}
+	if p.stream {
+		args := []string{"XADD", p.key}
+		if p.maxLen > 0 {
+			args = append(args, "MAXLEN", "~", strconv.Itoa(p.maxLen))
+		}
+		send(append(append(args, "*"), redisStreamFields(n, p.target.PayloadVersion, data)...)...)
+	} else {
+		send("PUBLISH", p.key, string(data))
+	}
+	if _, err := conn.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("writing to %s: %w", p.addr, err)
+	}
+
+	r := bufio.NewReader(conn)
+	for _, command := range commands {
+		if err := readRedisReply(r); err != nil {
+			var re redisError
+			if errors.As(err, &re) {
+				return re.statusError(p.addr, command)
+			}
+			return fmt.Errorf("reading from %s: %w", p.addr, err)
+		}
+	}
+	return nil
+}
+
+// writeRedisCommand appends args to b as a RESP array of bulk strings.
+func writeRedisCommand(b *bytes.Buffer, args ...string) {
+	fmt.Fprintf(b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// redisError is an error reply, such as "WRONGPASS invalid username-password
+// pair".
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// statusError returns e as the error of command: retryable if the server
+// is only briefly unable to serve it, such as while loading its data set.
+func (e redisError) statusError(addr, command string) error {
+	err := fmt.Errorf("Redis server %s rejected %s: %s", addr, command, e)
+	switch code, _, _ := strings.Cut(string(e), " "); code {
+	case "LOADING", "BUSY", "TRYAGAIN", "MASTERDOWN", "CLUSTERDOWN":
+		return &statusError{http.StatusServiceUnavailable, err}
+	}
+	return err
+}
+
+// readRedisReply reads one RESP reply and returns it as a redisError if it
+// is an error reply; the value of any other reply is discarded.
+func readRedisReply(r *bufio.Reader) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return errors.New("malformed reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return errors.New("malformed reply")
+		}
+		if size < 0 {
+			return nil // a null reply
+		}
+		_, err = io.CopyN(io.Discard, r, int64(size)+2)
+		return err
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return errors.New("malformed reply")
+		}
+		for range count {
+			if err := readRedisReply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected reply %q", line)
+}
@@ -0,0 +1,118 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderSlack, pushProviderSpec{
+		match: isSlackWebhookURL,
+		options: map[string]configKind{
+			"username":        kindString,
+			"icon_emoji":      kindString,
+			"failure_mention": kindString,
+		},
+		new: func(target pushTarget) (pushProvider, error) {
+			return slackPush{target: target}, nil
+		},
+	})
+}
+
+const pushProviderSlack = "slack"
+
+// Attachment colors: Slack's own green, red and blue.
+const (
+	slackColorSuccess   = "#2eb886"
+	slackColorFailure   = "#e01e5a"
+	slackColorRecovered = "#36c5f0"
+)
+
+// slackTextLimit is the most characters Slack accepts in a section's text.
+const slackTextLimit = 3000
+
+// slackPush posts to a Slack incoming webhook. The message is Block Kit
+// inside a colored attachment, so the sidebar shows green or red at a glance.
+type slackPush struct{ target pushTarget }
+
+type slackMessage struct {
+	Text        string            `json:"text"` // fallback for notifications
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(s string) *slackText { return &slackText{Type: "mrkdwn", Text: s} }
+
+func slackCode(s string) string { return codeFence(escapeSlack(s), slackTextLimit) }
+
+func (p slackPush) message(n notification) slackMessage {
+	headline := "*" + escapeSlack(n.Title) + "*\n" + escapeSlack(n.Body)
+	if mention := p.target.option("failure_mention", ""); n.Failed && !n.Quiet && mention != "" {
+		headline = mention + " " + headline
+	}
+	blocks := []slackBlock{{Type: "section", Text: mrkdwn(headline)}}
+	if n.Subtitle != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn(slackCode(n.Subtitle))})
+	}
+	if n.Output != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn(slackCode(n.Output))})
+	}
+	if len(n.Labels) > 0 {
+		blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{*mrkdwn(escapeSlack(formatLabels(n.Labels)))}})
+	}
+	color := slackColorSuccess
+	switch {
+	case n.Failed:
+		color = slackColorFailure
+	case n.Recovered > 0:
+		color = slackColorRecovered
+	}
+	return slackMessage{
+		Text:        n.Title + " — " + n.Body,
+		Username:    p.target.option("username", ""),
+		IconEmoji:   p.target.option("icon_emoji", ""),
+		Attachments: []slackAttachment{{Color: color, Blocks: blocks}},
+	}
+}
+
+func (p slackPush) Push(ctx context.Context, n notification) error {
+	body, err := json.Marshal(p.message(n))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.target.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", p.target.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return sendPush(req)
+}
+
+// isSlackWebhookURL reports whether u is a Slack incoming webhook.
+func isSlackWebhookURL(u *url.URL) bool {
+	return strings.EqualFold(u.Hostname(), "hooks.slack.com")
+}
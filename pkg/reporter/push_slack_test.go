@@ -0,0 +1,88 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPushToSlack(t *testing.T) {
+	var got slackMessage
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		got = slackMessage{}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %v\n%s", err, b)
+		}
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderSlack, Options: map[string]any{
+		"username":        "reporter",
+		"failure_mention": "<!here>",
+	}}
+	n := notification{
+		Title:    "Migration",
+		Body:     "failed (exit 1) in 42m",
+		Subtitle: "rails db:migrate <prod>",
+		Failed:   true,
+		Output:   "PG::LockNotAvailable",
+		Labels:   map[string]string{"env": "prod"},
+	}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	want := slackMessage{
+		Text:     "Migration — failed (exit 1) in 42m",
+		Username: "reporter",
+		Attachments: []slackAttachment{{
+			Color: slackColorFailure,
+			Blocks: []slackBlock{
+				{Type: "section", Text: mrkdwn("<!here> *Migration*\nfailed (exit 1) in 42m")},
+				{Type: "section", Text: mrkdwn("```rails db:migrate &lt;prod&gt;```")},
+				{Type: "section", Text: mrkdwn("```PG::LockNotAvailable```")},
+				{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "env=prod"}}},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("message =\n%+v\nwant\n%+v", got, want)
+	}
+
+	n = notification{Title: "Build", Body: "succeeded in 3m", Subtitle: "make"}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if a := got.Attachments[0]; a.Color != slackColorSuccess || strings.Contains(a.Blocks[0].Text.Text, "<!here>") {
+		t.Errorf("success attachment = %+v, want green without a mention", a)
+	}
+}
+
+func TestSlackCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "make", want: "```make```"},
+		{in: "echo ```x``` && a<b", want: "```echo ``\u200b`x``\u200b` &amp;&amp; a&lt;b```"},
+	}
+	for _, tt := range tests {
+		if got := slackCode(tt.in); got != tt.want {
+			t.Errorf("slackCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := []rune(slackCode(strings.Repeat("x", 5000))); len(got) != slackTextLimit {
+		t.Errorf("slackCode of a long command is %d characters, want %d", len(got), slackTextLimit)
+	}
+}
@@ -0,0 +1,158 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerPushProvider(pushProviderTelegram, pushProviderSpec{
+		schemes: []string{"telegram"},
+		options: map[string]configKind{
+			"chat_id": kindString,
+			"api_url": kindString,
+			"silent":  kindBool,
+		},
+		new: newTelegramPush,
+	})
+}
+
+const pushProviderTelegram = "telegram"
+
+// telegramAPI is the Bot API server; a self-hosted one can be set with the
+// api_url option.
+const telegramAPI = "https://api.telegram.org"
+
+// telegramCodeLimit caps each code block, keeping messages well under
+// Telegram's 4096-character limit.
+const telegramCodeLimit = 1500
+
+// telegramPush sends a MarkdownV2 message through a bot. The URL names the
+// chat (telegram://<chat_id>) and the push token is the bot token, so the
+// token never appears on a command line.
+type telegramPush struct {
+	endpoint string
+	chatID   string
+	silent   bool
+}
+
+func newTelegramPush(target pushTarget) (pushProvider, error) {
+	// Chat IDs may be negative or @channel names, which url.Parse would
+	// mistake for userinfo, so the URL is split by hand.
+	chatID := target.option("chat_id", "")
+	if rest, ok := strings.CutPrefix(target.URL, "telegram://"); ok && strings.Trim(rest, "/") != "" {
+		chatID = strings.Trim(rest, "/")
+	}
+	if chatID == "" {
+		return nil, errors.New("no chat ID: use telegram://<chat_id> as the push URL or set chat_id in [push.telegram]")
+	}
+	if target.Token == "" {
+		return nil, errors.New("no bot token: set REPORTER_PUSH_TOKEN or push_token")
+	}
+	api := strings.TrimSuffix(target.option("api_url", telegramAPI), "/")
+	return telegramPush{
+		endpoint: api + "/bot" + target.Token + "/sendMessage",
+		chatID:   chatID,
+		silent:   target.Options["silent"] == true,
+	}, nil
+}
+
+// telegramCode renders s as a MarkdownV2 pre block.
+func telegramCode(s string) string {
+	if r := []rune(s); len(r) > telegramCodeLimit {
+		s = string(r[:telegramCodeLimit-1]) + "…"
+	}
+	return "```\n" + escapeMarkdownV2Code(s) + "\n```"
+}
+
+func telegramText(n notification) string {
+	icon := "✅"
+	switch {
+	case n.Failed:
+		icon = "❌"
+	case n.Recovered > 0:
+		icon = "🎉"
+	}
+	lines := []string{icon + " *" + escapeMarkdownV2(n.Title) + "*", escapeMarkdownV2(n.Body)}
+	if n.Subtitle != "" {
+		lines = append(lines, telegramCode(n.Subtitle))
+	}
+	if n.Duration > 0 {
+		lines = append(lines, fmt.Sprintf("*Duration:* %s  *Exit:* %d", escapeMarkdownV2(formatDuration(n.Duration)), n.ExitCode))
+	}
+	if n.Output != "" {
+		lines = append(lines, telegramCode(n.Output))
+	}
+	if len(n.Labels) > 0 {
+		keys := make([]string, 0, len(n.Labels))
+		for k := range n.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels := make([]string, len(keys))
+		for i, k := range keys {
+			labels[i] = "#" + escapeMarkdownV2(k) + " " + escapeMarkdownV2(n.Labels[k])
+		}
+		lines = append(lines, "_"+strings.Join(labels, ", ")+"_")
+	}
+	return strings.Join(lines, "\n")
+}
+
+type telegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	DisableNotification   bool   `json:"disable_notification,omitempty"`
+}
+
+func (p telegramPush) Push(ctx context.Context, n notification) error {
+	body, err := json.Marshal(telegramMessage{
+		ChatID:                p.chatID,
+		Text:                  telegramText(n),
+		ParseMode:             "MarkdownV2",
+		DisableWebPagePreview: true,
+		DisableNotification:   p.silent && !n.Failed || n.Quiet,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("creating request for the Telegram Bot API: invalid api_url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The endpoint contains the bot token, so errors name the API instead
+	// of echoing the URL.
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("posting to the Telegram Bot API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Description string `json:"description"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Description != "" {
+			return &statusError{resp.StatusCode, fmt.Errorf("telegram returned %s: %s", resp.Status, apiErr.Description)}
+		}
+		return &statusError{resp.StatusCode, fmt.Errorf("telegram returned %s", resp.Status)}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToTelegram(t *testing.T) {
+	noPushBackoff(t)
+	var got telegramMessage
+	var gotPath string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotPath = r.URL.Path
+		got = telegramMessage{}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %v\n%s", err, b)
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+		}
+	}))
+	defer srv.Close()
+
+	target := pushTarget{
+		URL:      "telegram://-100123",
+		Provider: pushProviderTelegram,
+		Token:    "123:ABC",
+		Options:  map[string]any{"api_url": srv.URL, "silent": true},
+	}
+	n := notification{
+		Title:    "Build (api)",
+		Body:     "failed (exit 2) in 3m",
+		Subtitle: "make test && echo `done`",
+		Failed:   true,
+		Duration: 3 * time.Minute,
+		ExitCode: 2,
+		Labels:   map[string]string{"env": "prod-1"},
+	}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if gotPath != "/bot123:ABC/sendMessage" {
+		t.Errorf("path = %q, want /bot123:ABC/sendMessage", gotPath)
+	}
+	want := "❌ *Build \\(api\\)*\nfailed \\(exit 2\\) in 3m\n```\nmake test && echo \\`done\\`\n```\n*Duration:* 3m0s  *Exit:* 2\n_#env prod\\-1_"
+	want = strings.Replace(want, "3m0s", escapeMarkdownV2(formatDuration(3*time.Minute)), 1)
+	if got.ChatID != "-100123" || got.ParseMode != "MarkdownV2" || got.Text != want {
+		t.Errorf("message = %+v, want chat -100123 with text\n%s", got, want)
+	}
+	if got.DisableNotification {
+		t.Error("silent option muted a failure")
+	}
+
+	n.Quiet = true
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if !got.DisableNotification {
+		t.Error("failure during quiet hours was not muted")
+	}
+	n.Quiet = false
+
+	n.Failed = false
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if !got.DisableNotification {
+		t.Error("silent option did not mute a success")
+	}
+
+	status = http.StatusBadRequest
+	err := pushToPhone(target, n)
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("pushToPhone() error = %v, want Telegram's description", err)
+	}
+
+	srv.Close()
+	err = pushToPhone(target, n)
+	if err == nil || strings.Contains(err.Error(), "ABC") {
+		t.Errorf("pushToPhone() error = %v, want an error that does not reveal the bot token", err)
+	}
+}
+
+func TestNewTelegramPush(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  pushTarget
+		chatID  string
+		wantErr bool
+	}{
+		{name: "url", target: pushTarget{URL: "telegram://12345", Token: "t"}, chatID: "12345"},
+		{name: "channel", target: pushTarget{URL: "telegram://@builds/", Token: "t"}, chatID: "@builds"},
+		{name: "option", target: pushTarget{URL: "telegram://", Token: "t", Options: map[string]any{"chat_id": "777"}}, chatID: "777"},
+		{name: "no chat", target: pushTarget{URL: "telegram://", Token: "t"}, wantErr: true},
+		{name: "no token", target: pushTarget{URL: "telegram://12345"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newTelegramPush(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.(telegramPush).chatID != tt.chatID {
+				t.Errorf("chat ID = %q, want %q", p.(telegramPush).chatID, tt.chatID)
+			}
+		})
+	}
+}
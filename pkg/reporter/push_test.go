@@ -0,0 +1,355 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// noPushBackoff makes retried pushes in the test try again at once.
+func noPushBackoff(t *testing.T) {
+	old := pushBackoff
+	pushBackoff = 0
+	t.Cleanup(func() { pushBackoff = old })
+}
+
+func TestPushToPhone(t *testing.T) {
+	noPushBackoff(t)
+	var gotBody string
+	var gotHeader http.Header
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotHeader = string(b), r.Header
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	if err := pushToPhone(pushTarget{}, notification{}); err != nil {
+		t.Errorf("pushToPhone with empty URL returned error: %v", err)
+	}
+
+	plain := pushTarget{URL: srv.URL, Provider: pushProviderPlain}
+	n := notification{Title: "Task finished", Body: "succeeded in 12s", Subtitle: "make test"}
+	if err := pushToPhone(plain, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if want := "Task finished — succeeded in 12s\nmake test"; gotBody != want {
+		t.Errorf("push body = %q, want %q", gotBody, want)
+	}
+	if got := gotHeader.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q, want text/plain", got)
+	}
+	for _, h := range []string{"Title", "Priority", "Tags", "Authorization", "X-Reporter-Dedup-Key"} {
+		if got := gotHeader.Get(h); got != "" {
+			t.Errorf("plain push sent %s: %q, want none", h, got)
+		}
+	}
+
+	n.DedupKey = "deploy-prod"
+	n.Labels = map[string]string{"project": "atlas", "env": "prod"}
+	n.Failed = true
+	n.Output = "main.go:3: undefined: x\nFAIL"
+	if err := pushToPhone(plain, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if want := "Task finished — succeeded in 12s\nmake test\n\nmain.go:3: undefined: x\nFAIL"; gotBody != want {
+		t.Errorf("push body with output = %q, want %q", gotBody, want)
+	}
+	if got := gotHeader.Get("X-Reporter-Dedup-Key"); got != "deploy-prod" {
+		t.Errorf("X-Reporter-Dedup-Key = %q, want deploy-prod", got)
+	}
+	if got := gotHeader.Get("X-Reporter-Labels"); got != "env=prod,project=atlas" {
+		t.Errorf("X-Reporter-Labels = %q, want env=prod,project=atlas", got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := pushToPhone(plain, n); err == nil {
+		t.Error("pushToPhone() succeeded against a 500 response, want error")
+	}
+}
+
+func TestPushRetries(t *testing.T) {
+	noPushBackoff(t)
+	var requests int
+	var statuses []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[min(requests, len(statuses)-1)])
+		requests++
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderPlain}
+	tests := []struct {
+		statuses []int
+		requests int
+		ok       bool
+	}{
+		{statuses: []int{http.StatusOK}, requests: 1, ok: true},
+		{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, requests: 3, ok: true},
+		{statuses: []int{http.StatusServiceUnavailable}, requests: 1 + pushRetries},
+		{statuses: []int{http.StatusUnauthorized}, requests: 1},
+	}
+	for _, tt := range tests {
+		requests, statuses = 0, tt.statuses
+		err := pushToPhone(target, notification{Title: "Build"})
+		if requests != tt.requests || (err == nil) != tt.ok {
+			t.Errorf("statuses %v: %d requests, error %v; want %d requests, ok %v", tt.statuses, requests, err, tt.requests, tt.ok)
+		}
+	}
+
+	srv.Close()
+	if err := pushToPhone(target, notification{}); !pushRetryable(err) {
+		t.Errorf("connection error %v is not retryable", err)
+	}
+}
+
+func TestPushToNtfy(t *testing.T) {
+	var gotBody string
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotHeader = string(b), r.Header
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		target pushTarget
+		n      notification
+		body   string
+		header map[string]string
+	}{
+		{
+			name:   "success",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy},
+			n:      notification{Title: "Task finished", Body: "succeeded in 12s", Subtitle: "make test"},
+			body:   "succeeded in 12s\nmake test",
+			header: map[string]string{"Title": "Task finished", "Priority": "default", "Tags": "white_check_mark", "Click": "", "Authorization": "", "X-Sequence-ID": ""},
+		},
+		{
+			name:   "failure with click and token",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy, Token: "tk_abc123", Click: "https://ci.example/jobs/7"},
+			n:      notification{Title: "[api] ✓ Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true},
+			body:   "failed (exit 2) in 3m\nmake",
+			header: map[string]string{
+				"Title":         "=?utf-8?q?[api]_=E2=9C=93_Build?=",
+				"Priority":      "high",
+				"Tags":          "warning",
+				"Click":         "https://ci.example/jobs/7",
+				"Authorization": "Bearer tk_abc123",
+			},
+		},
+		{
+			name: "options",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy, Options: map[string]any{
+				"failure_priority": "urgent",
+				"failure_tags":     []any{"rotating_light", "skull"},
+				"icon":             "https://example.com/icon.png",
+			}},
+			n:      notification{Title: "Deploy", Body: "failed (exit 1) in 40s", Subtitle: "deploy", Failed: true},
+			body:   "failed (exit 1) in 40s\ndeploy",
+			header: map[string]string{"Priority": "urgent", "Tags": "rotating_light,skull", "Icon": "https://example.com/icon.png"},
+		},
+		{
+			name:   "recovered",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy},
+			n:      notification{Title: "Build", Body: "recovered: succeeded in 3m after 2 failed runs", Subtitle: "make", Recovered: 2},
+			body:   "recovered: succeeded in 3m after 2 failed runs\nmake",
+			header: map[string]string{"Priority": "high", "Tags": "tada"},
+		},
+		{
+			name:   "quiet hours",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy, Options: map[string]any{"failure_priority": "min"}},
+			n:      notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true, Quiet: true},
+			body:   "failed (exit 2) in 3m\nmake",
+			header: map[string]string{"Priority": "min", "Tags": "warning"},
+		},
+		{
+			name:   "quiet hours lower the priority",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy},
+			n:      notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true, Quiet: true},
+			body:   "failed (exit 2) in 3m\nmake",
+			header: map[string]string{"Priority": "low", "Tags": "warning"},
+		},
+		{
+			name:   "running",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy},
+			n:      progressNotification("make test", "ci-7", 12*time.Minute, nil),
+			body:   "12m00s elapsed\nmake test",
+			header: map[string]string{"Title": "Running", "Priority": "low", "Tags": "hourglass_flowing_sand", "X-Sequence-ID": ntfySequenceID("ci-7")},
+		},
+		{
+			name:   "basic auth",
+			target: pushTarget{URL: srv.URL, Provider: pushProviderNtfy, Token: "phil:secret"},
+			n:      notification{Title: "Task finished"},
+			body:   "\n",
+			header: map[string]string{"Authorization": "Basic cGhpbDpzZWNyZXQ="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pushToPhone(tt.target, tt.n); err != nil {
+				t.Fatalf("pushToPhone() returned error: %v", err)
+			}
+			if gotBody != tt.body {
+				t.Errorf("body = %q, want %q", gotBody, tt.body)
+			}
+			for h, want := range tt.header {
+				if got := gotHeader.Get(h); got != want {
+					t.Errorf("%s = %q, want %q", h, got, want)
+				}
+			}
+		})
+	}
+}
+
+// listen accepts connections on a loopback port, counting them and passing
+// each to handle.
+func listen(t *testing.T, handle func(net.Conn)) (addr string, accepted *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	accepted = &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go handle(conn)
+		}
+	}()
+	return ln.Addr().String(), accepted
+}
+
+func TestWarmDialerReusesConnection(t *testing.T) {
+	addr, accepted := listen(t, func(c net.Conn) { io.Copy(io.Discard, c) })
+	host, port, _ := net.SplitHostPort(addr)
+
+	d := &warmDialer{}
+	d.warm(host, port)
+	conn, err := d.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("DialContext() returned error: %v", err)
+	}
+	defer conn.Close()
+
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("server accepted %d connections, want the pre-dialed one only", n)
+	}
+	if len(d.conns) != 0 {
+		t.Error("pre-dialed connection was not handed out")
+	}
+}
+
+func TestWarmDialerDiscardsClosedConnection(t *testing.T) {
+	addr, accepted := listen(t, func(c net.Conn) { c.Close() })
+	host, port, _ := net.SplitHostPort(addr)
+
+	d := &warmDialer{}
+	d.warm(host, port)
+	time.Sleep(50 * time.Millisecond) // let the server close it
+
+	conn, err := d.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("DialContext() returned error: %v", err)
+	}
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); accepted.Load() < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("server accepted %d connections, want a fresh dial after the warm one closed", n)
+	}
+}
+
+func TestWarmDialerForgetsStaleAddresses(t *testing.T) {
+	addr, accepted := listen(t, func(c net.Conn) { io.Copy(io.Discard, c) })
+	host, _, _ := net.SplitHostPort(addr)
+
+	// The server moved off the address the dialer resolved the host to.
+	d := &warmDialer{addrs: map[string][]string{host: {"::1"}}}
+	conn, err := d.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("DialContext() returned error: %v", err)
+	}
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); accepted.Load() < 1 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("server accepted %d connections, want the fallback dial", n)
+	}
+	if _, ok := d.addrs[host]; ok {
+		t.Error("DialContext() kept the addresses that failed")
+	}
+}
+
+func TestPushAll(t *testing.T) {
+	noPushBackoff(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	// Each server waits until both first requests have arrived, so the
+	// pushes only complete if they are sent concurrently.
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	handler := func(status int) http.HandlerFunc {
+		var first sync.Once
+		return func(w http.ResponseWriter, r *http.Request) {
+			first.Do(func() {
+				arrived.Done()
+				arrived.Wait()
+			})
+			w.WriteHeader(status)
+		}
+	}
+	ok := httptest.NewServer(handler(http.StatusOK))
+	defer ok.Close()
+	down := httptest.NewServer(handler(http.StatusServiceUnavailable))
+	defer down.Close()
+
+	targets := []pushTarget{
+		{URL: ok.URL + "/topic", Provider: pushProviderPlain},
+		{URL: down.URL + "/topic", Provider: pushProviderPlain},
+	}
+	done := make(chan []delivery)
+	go func() {
+		ds, _ := pushAll(targets, notification{Title: "Build"}, spoolDir())
+		done <- ds
+	}()
+	var ds []delivery
+	select {
+	case ds = <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("pushAll() did not send to both destinations at once")
+	}
+
+	if len(ds) != 2 || !ds[0].OK || ds[1].OK {
+		t.Fatalf("deliveries = %+v, want the first to succeed and the second to fail", ds)
+	}
+	for i, srv := range []*httptest.Server{ok, down} {
+		if want := strings.TrimPrefix(srv.URL, "http://"); ds[i].Destination != want {
+			t.Errorf("delivery %d destination = %q, want %q", i, ds[i].Destination, want)
+		}
+	}
+	if !strings.Contains(ds[1].Error, "503") {
+		t.Errorf("error = %q, want the failing destination's status", ds[1].Error)
+	}
+	if ds[0].Queued || !ds[1].Queued || countSpool(spoolDir()) != 1 {
+		t.Errorf("deliveries = %+v with %d queued, want the failed push queued", ds, countSpool(spoolDir()))
+	}
+}
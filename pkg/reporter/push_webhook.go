@@ -0,0 +1,52 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+func init() {
+	registerPushProvider(pushProviderWebhook, pushProviderSpec{
+		new: func(target pushTarget) (pushProvider, error) {
+			if err := checkPayloadVersion(target.PayloadVersion); err != nil {
+				return nil, err
+			}
+			return webhookPush{target: target}, nil
+		},
+	})
+}
+
+// webhookPush POSTs reporter's versioned JSON payload, for receivers written
+// against it rather than against a chat service.
+type webhookPush struct{ target pushTarget }
+
+func (p webhookPush) Push(ctx context.Context, n notification) error {
+	body, err := encodePayload(p.target.PayloadVersion, n)
+	if err != nil {
+		return err
+	}
+	req, err := newPushRequest(ctx, p.target.URL, p.target, n, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Reporter-Schema", payloadSchema(p.target.PayloadVersion))
+	if p.target.Secret != "" {
+		req.Header.Set("X-Reporter-Signature", signPayload(p.target.Secret, body))
+	}
+	return sendPush(req)
+}
+
+// signPayload returns the X-Reporter-Signature value for body: "sha256="
+// followed by the hex HMAC-SHA256 of the exact bytes sent, keyed with secret.
+// Receivers recompute it over the raw request body and compare in constant
+// time.
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,95 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToWebhook(t *testing.T) {
+	var got Payload
+	var gotHeader http.Header
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotHeader, gotBody = r.Header, b
+		got = Payload{}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %v\n%s", err, b)
+		}
+	}))
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderWebhook, Token: "tk_abc", PayloadVersion: 1}
+	n := notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true,
+		Duration: 3 * time.Minute, ExitCode: 2, DedupKey: "ci-7"}
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	if got.Schema != "reporter/v1" || got.Command != "make" || got.ExitCode != 2 || got.DurationMS != 180000 || !got.Failed {
+		t.Errorf("payload = %+v", got)
+	}
+	for h, want := range map[string]string{
+		"Content-Type":         "application/json",
+		"X-Reporter-Schema":    "reporter/v1",
+		"X-Reporter-Dedup-Key": "ci-7",
+		"Authorization":        "Bearer tk_abc",
+	} {
+		if got := gotHeader.Get(h); got != want {
+			t.Errorf("%s = %q, want %q", h, got, want)
+		}
+	}
+
+	if got := gotHeader.Get("X-Reporter-Signature"); got != "" {
+		t.Errorf("unsigned push sent X-Reporter-Signature: %q", got)
+	}
+
+	target.Secret = "s3cret"
+	if err := pushToPhone(target, n); err != nil {
+		t.Fatalf("pushToPhone() returned error: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(gotBody)
+	if got, want := gotHeader.Get("X-Reporter-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("X-Reporter-Signature = %q, want %q", got, want)
+	}
+
+	target.PayloadVersion = 99
+	if err := pushToPhone(target, n); err == nil {
+		t.Error("pushToPhone() with an unsupported payload version succeeded")
+	}
+}
+
+func TestNotifyAgentHTTP(t *testing.T) {
+	var got Payload
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		path = r.URL.Path
+		json.Unmarshal(b, &got)
+		if got.Failed {
+			http.Error(w, "no project open", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	addr := srv.URL + "/api/reporter"
+	if err := notifyAgent(addr, notification{Title: "Build", Subtitle: "gradle build"}); err != nil {
+		t.Fatalf("notifyAgent() returned error: %v", err)
+	}
+	if path != "/api/reporter" || got.Schema != "reporter/v1" || got.Command != "gradle build" {
+		t.Errorf("agent got %+v at %s", got, path)
+	}
+	if err := notifyAgent(addr, notification{Title: "Build", Failed: true}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("notifyAgent() error = %v, want the endpoint's status", err)
+	}
+}
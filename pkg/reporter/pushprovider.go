@@ -0,0 +1,109 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Push backends are providers registered by name. Each lives in its own file
+// and registers itself from init, so adding one does not touch the flag
+// handling or the notification path.
+
+// pushProvider delivers a notification to one push service.
+type pushProvider interface {
+	Push(ctx context.Context, n notification) error
+}
+
+// pushProviderSpec describes a registered provider.
+type pushProviderSpec struct {
+	// schemes are URL schemes that select the provider, such as ntfy for
+	// ntfy://ntfy.sh/topic.
+	schemes []string
+	// match reports whether an http or https URL belongs to the provider
+	// when -push-provider is auto. It may be nil.
+	match func(u *url.URL) bool
+	// options lists the keys accepted in the provider's [push.<name>]
+	// config table.
+	options map[string]configKind
+	// new returns a provider delivering to target.
+	new func(target pushTarget) (pushProvider, error)
+}
+
+// pushTable prefixes keys from [push.<provider>] config tables.
+const pushTable = "push."
+
+var pushProviders = map[string]pushProviderSpec{}
+
+// registerPushProvider makes a provider available under name. It panics on a
+// duplicate name, which is a programming error.
+func registerPushProvider(name string, spec pushProviderSpec) {
+	if _, dup := pushProviders[name]; dup {
+		panic("push provider " + name + " registered twice")
+	}
+	pushProviders[name] = spec
+}
+
+// pushProviderNames returns the registered provider names, sorted.
+func pushProviderNames() []string {
+	names := make([]string, 0, len(pushProviders))
+	for name := range pushProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// detectPushProvider picks the provider for rawURL: one claiming its scheme,
+// then one recognising the URL, then plain.
+func detectPushProvider(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return pushProviderPlain
+	}
+	names := pushProviderNames()
+	for _, name := range names {
+		for _, scheme := range pushProviders[name].schemes {
+			if strings.EqualFold(u.Scheme, scheme) {
+				return name
+			}
+		}
+	}
+	for _, name := range names {
+		if match := pushProviders[name].match; match != nil && match(u) {
+			return name
+		}
+	}
+	return pushProviderPlain
+}
+
+// checkPushOption validates a key from a [push.<provider>] table. Without
+// push support compiled in no providers are registered, so the tables are
+// accepted as-is rather than making shared config files fail to load.
+func checkPushOption(key string, val any) error {
+	rest := strings.TrimPrefix(key, pushTable)
+	name, option, ok := strings.Cut(rest, ".")
+	if !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+	if len(pushProviders) == 0 {
+		return nil
+	}
+	spec, ok := pushProviders[name]
+	if !ok {
+		return fmt.Errorf("[push.%s]: unknown push provider %q (want %s)", name, name, strings.Join(pushProviderNames(), ", "))
+	}
+	kind, ok := spec.options[option]
+	if option == "token" {
+		kind, ok = kindString, true
+	}
+	if !ok {
+		return fmt.Errorf("[push.%s]: unknown option %q", name, option)
+	}
+	if err := checkConfigKind(kind, val); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
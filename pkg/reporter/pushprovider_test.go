@@ -0,0 +1,94 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectPushProvider(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://ntfy.sh/builds", want: pushProviderNtfy},
+		{url: "https://ntfy.example.com/builds", want: pushProviderNtfy},
+		{url: "http://push.ntfy.internal:8080/t", want: pushProviderNtfy},
+		{url: "ntfy://example.com/builds", want: pushProviderNtfy},
+		{url: "pushover://ukey", want: pushProviderPushover},
+		{url: "gotify://push.example.com", want: pushProviderGotify},
+		{url: "telegram://-100123", want: pushProviderTelegram},
+		{url: "NTFY://example.com/builds", want: pushProviderNtfy},
+		{url: "https://hooks.slack.com/services/x", want: pushProviderSlack},
+		{url: "https://example.com/hooks.slack.com", want: pushProviderPlain},
+		{url: "https://discord.com/api/webhooks/1/abc", want: pushProviderDiscord},
+		{url: "https://canary.discordapp.com/api/webhooks/1/abc", want: pushProviderDiscord},
+		{url: "https://discord.com/channels/1", want: pushProviderPlain},
+		{url: "https://notntfy.sh/t", want: pushProviderPlain},
+		{url: "::", want: pushProviderPlain},
+	}
+	for _, tt := range tests {
+		if got := detectPushProvider(tt.url); got != tt.want {
+			t.Errorf("detectPushProvider(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPushProviderOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		toml    string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "ntfy table",
+			toml: "[push.ntfy]\npriority = \"low\"\nfailure_tags = [\"rotating_light\"]\n",
+			want: map[string]any{"priority": "low", "failure_tags": []any{"rotating_light"}},
+		},
+		{name: "unknown provider", toml: "[push.pigeon]\nloft = \"roof\"\n", wantErr: `unknown push provider "pigeon"`},
+		{name: "unknown option", toml: "[push.ntfy]\nvolume = \"11\"\n", wantErr: `unknown option "volume"`},
+		{name: "wrong kind", toml: "[push.ntfy]\ntags = \"warning\"\n", wantErr: "push.ntfy.tags: expected list of strings"},
+		{name: "bare push key", toml: "[push]\nurl = \"https://ntfy.sh/x\"\n", wantErr: `unknown key "push.url"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateConfig(t)
+			dir := t.TempDir()
+			writeFile(t, userConfigPath(), "push_url = \"https://ntfy.sh/x\"\n"+tt.toml)
+			cfg, err := loadConfig(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfig() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			targets, err := registerPushFlags(flag.NewFlagSet("test", flag.ContinueOnError), cfg, "")()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(targets) != 1 || !reflect.DeepEqual(targets[0].Options, tt.want) {
+				t.Errorf("targets = %+v, want one with Options %v", targets, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewNtfyPush(t *testing.T) {
+	p, err := newNtfyPush(pushTarget{URL: "ntfy://ntfy.example.com/builds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.(ntfyPush).url; got != "https://ntfy.example.com/builds" {
+		t.Errorf("url = %q, want the https form", got)
+	}
+	if _, err := newNtfyPush(pushTarget{Options: map[string]any{"failure_priority": "loud"}}); err == nil {
+		t.Error("newNtfyPush() accepted an invalid priority")
+	}
+}
@@ -0,0 +1,199 @@
+package reporter
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Values for -push-provider besides the registered provider names.
+const (
+	pushProviderAuto        = "auto"        // chosen from the URL (see detectPushProvider)
+	pushProviderNtfy        = "ntfy"        // ntfy headers: Title, Priority, Tags, Click
+	pushProviderPlain       = "plain"       // just the text body
+	pushProviderWebhook     = "webhook"     // reporter's JSON payload; never detected from a URL
+	pushProviderCloudEvents = "cloudevents" // the JSON payload as a CloudEvent; never detected either
+)
+
+// Values for -push-format.
+const (
+	pushFormatText        = "text"        // whatever the provider sends
+	pushFormatJSON        = "json"        // reporter's JSON payload (the webhook provider)
+	pushFormatCloudEvents = "cloudevents" // the JSON payload as a CloudEvent (the cloudevents provider)
+)
+
+// pushTarget describes where and how to push a notification.
+type pushTarget struct {
+	URL      string
+	Provider string
+	// Token authenticates the push: "user:password" is sent as basic
+	// auth, anything else as a bearer token (e.g. an ntfy access token).
+	Token string
+	// Secret, if set, signs JSON payloads with an HMAC so receivers can
+	// check they came from reporter.
+	Secret string
+	// Click is a URL the notification opens when tapped, for providers
+	// that support it.
+	Click string
+	// PayloadVersion selects the JSON payload schema for providers that
+	// send reporter's own format (see payload.go).
+	PayloadVersion int
+	// Options holds the provider's [push.<provider>] config table.
+	Options map[string]any
+}
+
+// registerPushFlags defines the push flags on fset with defaults from the
+// environment and cfg, and returns a function that resolves them after
+// parsing into one target per push URL. The token and signing secret are only
+// read from the environment (REPORTER_PUSH_TOKEN, REPORTER_PUSH_SECRET) or
+// config so they never show up in process listings.
+func registerPushFlags(fset *flag.FlagSet, cfg *config, urlUsage string) func() ([]pushTarget, error) {
+	pushURLs := &urlListFlag{urls: defaultPushURLs(cfg)}
+	fset.Var(pushURLs, "push-url", urlUsage+" (repeatable)")
+	provider := fset.String("push-provider", cfg.string("push_provider", pushProviderAuto),
+		"push provider: auto (detected from each URL) or one of "+strings.Join(pushProviderNames(), ", "))
+	click := fset.String("push-click", getenvDefault("REPORTER_PUSH_CLICK", cfg.string("push_click", "")), "URL to open when the push notification is tapped (ntfy)")
+	format := fset.String("push-format", cfg.string("push_format", pushFormatText), "push body format: text, json for reporter's JSON payload to any URL (same as -push-provider webhook), or cloudevents for the payload as a CloudEvent (same as -push-provider cloudevents)")
+	payloadVersion := fset.Int("payload-version", cfg.int("payload_version", defaultPayloadVersion), "`version` of the JSON payload sent by the webhook provider (reporter/v1, ...)")
+	return func() ([]pushTarget, error) {
+		if err := checkPayloadVersion(*payloadVersion); err != nil {
+			return nil, fmt.Errorf("invalid -payload-version: %w", err)
+		}
+		name := *provider
+		switch *format {
+		case pushFormatText:
+		case pushFormatJSON:
+			if name != pushProviderAuto && name != pushProviderWebhook {
+				return nil, fmt.Errorf("-push-format json sends reporter's JSON payload and cannot be combined with -push-provider %s", name)
+			}
+			name = pushProviderWebhook
+		case pushFormatCloudEvents:
+			if name != pushProviderAuto && name != pushProviderCloudEvents {
+				return nil, fmt.Errorf("-push-format cloudevents sends reporter's JSON payload as a CloudEvent and cannot be combined with -push-provider %s", name)
+			}
+			name = pushProviderCloudEvents
+		default:
+			return nil, fmt.Errorf("invalid -push-format %q: want %s, %s or %s", *format, pushFormatText, pushFormatJSON, pushFormatCloudEvents)
+		}
+		if _, ok := pushProviders[name]; !ok && name != pushProviderAuto && len(pushProviders) > 0 {
+			return nil, fmt.Errorf("invalid -push-provider %q: want auto or one of %s", name, strings.Join(pushProviderNames(), ", "))
+		}
+
+		var targets []pushTarget
+		for _, u := range pushURLs.urls {
+			if u == "" {
+				continue
+			}
+			t := pushTarget{
+				URL:            u,
+				Provider:       name,
+				Token:          getenvDefault("REPORTER_PUSH_TOKEN", cfg.string("push_token", "")),
+				Secret:         getenvDefault("REPORTER_PUSH_SECRET", cfg.string("push_secret", "")),
+				Click:          *click,
+				PayloadVersion: *payloadVersion,
+			}
+			if t.Provider == pushProviderAuto {
+				t.Provider = detectPushProvider(t.URL)
+			}
+			t.Options = cfg.table(pushTable + t.Provider + ".")
+			// A provider's own token wins, so e.g. an ntfy access token and
+			// a Telegram bot token can be used side by side.
+			t.Token = t.option("token", t.Token)
+			targets = append(targets, t)
+		}
+		return targets, nil
+	}
+}
+
+// defaultPushURLs returns the push URLs used when -push-url is not given:
+// REPORTER_PUSH_URL, which may list several separated by spaces, or else
+// push_url followed by the [push] table's urls list.
+func defaultPushURLs(cfg *config) []string {
+	if env := os.Getenv("REPORTER_PUSH_URL"); env != "" {
+		return strings.Fields(env)
+	}
+	var urls []string
+	if u := cfg.string("push_url", ""); u != "" {
+		urls = append(urls, u)
+	}
+	return append(urls, cfg.strings(pushURLsKey)...)
+}
+
+// pushURLsKey is the urls list in the [push] table.
+const pushURLsKey = pushTable + "urls"
+
+// urlListFlag collects repeated -push-url values. The first one given
+// replaces the defaults, so the command line fully decides where pushes go;
+// -push-url "" disables them.
+type urlListFlag struct {
+	urls []string
+	set  bool
+}
+
+func (f *urlListFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.urls, " ")
+}
+
+func (f *urlListFlag) Set(s string) error {
+	if !f.set {
+		f.urls, f.set = nil, true
+	}
+	f.urls = append(f.urls, s)
+	return nil
+}
+
+// reset forgets the values set so far, for config.enforceLocks.
+func (f *urlListFlag) reset() { f.urls, f.set = nil, false }
+
+// option returns the string option key from the provider's config table, or
+// def if it is unset.
+func (t pushTarget) option(key, def string) string {
+	if s, ok := t.Options[key].(string); ok {
+		return s
+	}
+	return def
+}
+
+// optionList returns the string list option key, or def if it is unset.
+func (t pushTarget) optionList(key string, def []string) []string {
+	list, ok := t.Options[key].([]any)
+	if !ok {
+		return def
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// optionInt returns the integer option key, or def if it is unset. Options
+// of spooled pushes come back from JSON as float64.
+func (t pushTarget) optionInt(key string, def int) int {
+	switch n := t.Options[key].(type) {
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return def
+}
+
+// authorization returns the Authorization header value for token.
+func (t pushTarget) authorization() string {
+	switch {
+	case t.Token == "":
+		return ""
+	case strings.Contains(t.Token, ":"):
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(t.Token))
+	default:
+		return "Bearer " + t.Token
+	}
+}
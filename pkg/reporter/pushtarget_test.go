@@ -0,0 +1,116 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestRegisterPushFlags(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("REPORTER_PUSH_URL", "")
+	t.Setenv("REPORTER_PUSH_CLICK", "")
+	t.Setenv("REPORTER_PUSH_TOKEN", "tk_env")
+	cfg, err := loadConfig(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args      []string
+		providers []string
+		wantErr   bool
+	}{
+		{args: []string{"-push-url", "https://ntfy.sh/x"}, providers: []string{pushProviderNtfy}},
+		{args: []string{"-push-url", "https://example.com/hook"}, providers: []string{pushProviderPlain}},
+		{args: []string{"-push-url", "https://example.com/hook", "-push-provider", "ntfy"}, providers: []string{pushProviderNtfy}},
+		{args: []string{"-push-url", "https://ntfy.sh/x", "-push-provider", "plain"}, providers: []string{pushProviderPlain}},
+		{args: []string{"-push-url", "ntfy://ntfy.example.com/x"}, providers: []string{pushProviderNtfy}},
+		{args: []string{"-push-provider", "pigeon"}, wantErr: true},
+		{args: []string{"-push-url", "https://example.com/hook", "-push-provider", "webhook"}, providers: []string{pushProviderWebhook}},
+		{args: []string{"-payload-version", "2"}, wantErr: true},
+		{args: []string{"-push-url", "https://ntfy.sh/x", "-push-format", "json"}, providers: []string{pushProviderWebhook}},
+		{args: []string{"-push-url", "https://example.com/hook", "-push-format", "text"}, providers: []string{pushProviderPlain}},
+		{args: []string{"-push-format", "json", "-push-provider", "slack"}, wantErr: true},
+		{args: []string{"-push-url", "https://example.com/hook", "-push-format", "cloudevents"}, providers: []string{pushProviderCloudEvents}},
+		{args: []string{"-push-format", "cloudevents", "-push-provider", "webhook"}, wantErr: true},
+		{args: []string{"-push-format", "xml"}, wantErr: true},
+		{
+			args:      []string{"-push-url", "https://ntfy.sh/x", "-push-url", "https://hooks.slack.com/services/T/B/X"},
+			providers: []string{pushProviderNtfy, pushProviderSlack},
+		},
+		{args: []string{"-push-url", ""}},
+	}
+	for _, tt := range tests {
+		fset := flag.NewFlagSet("test", flag.ContinueOnError)
+		resolve := registerPushFlags(fset, cfg, "")
+		if err := fset.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		got, err := resolve()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v: error = %v, wantErr %v", tt.args, err, tt.wantErr)
+		}
+		var providers []string
+		for _, target := range got {
+			providers = append(providers, target.Provider)
+			if target.Token != "tk_env" {
+				t.Errorf("%v: token = %q, want the one from the environment", tt.args, target.Token)
+			}
+		}
+		if !reflect.DeepEqual(providers, tt.providers) {
+			t.Errorf("%v: providers = %q, want %q", tt.args, providers, tt.providers)
+		}
+	}
+}
+
+func TestPushURLDefaults(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("REPORTER_PUSH_TOKEN", "")
+	dir := t.TempDir()
+	writeFile(t, userConfigPath(), `push_url = "https://ntfy.sh/x"
+push_token = "shared"
+
+[push]
+urls = ["telegram://42", "https://hooks.slack.com/services/T/B/X"]
+
+[push.telegram]
+token = "123:bot"
+`)
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type dest struct{ URL, Token string }
+	tests := []struct {
+		env  string
+		args []string
+		want []dest
+	}{
+		{want: []dest{{"https://ntfy.sh/x", "shared"}, {"telegram://42", "123:bot"}, {"https://hooks.slack.com/services/T/B/X", "shared"}}},
+		{env: "https://a.example/1  https://b.example/2", want: []dest{{"https://a.example/1", "shared"}, {"https://b.example/2", "shared"}}},
+		{env: "https://a.example/1", args: []string{"-push-url", "https://c.example/3"}, want: []dest{{"https://c.example/3", "shared"}}},
+	}
+	for _, tt := range tests {
+		t.Setenv("REPORTER_PUSH_URL", tt.env)
+		fset := flag.NewFlagSet("test", flag.ContinueOnError)
+		resolve := registerPushFlags(fset, cfg, "")
+		if err := fset.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		targets, err := resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []dest
+		for _, target := range targets {
+			got = append(got, dest{target.URL, target.Token})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("env %q, args %q: destinations = %v, want %v", tt.env, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quiet hours keep pushes from waking anyone when an overnight job finishes.
+// Each quiet_hours entry is a time range, days, or days followed by a time
+// range, in local time:
+//
+//	quiet_hours = ["22:00-08:00", "sat-sun"]
+//	quiet_hours_push = "downgrade"
+//
+// A range that passes midnight belongs to the day it starts on, so
+// "fri 22:00-08:00" runs into Saturday morning. During quiet hours pushes are
+// sent without sound ("downgrade", the default) or not at all ("suppress");
+// desktop notifications are unaffected. -force-push ignores quiet hours.
+
+// Values for quiet_hours_push.
+const (
+	quietHoursDowngrade = "downgrade"
+	quietHoursSuppress  = "suppress"
+)
+
+const minutesPerDay = 24 * 60
+
+// weekdays is a set of days, bit n standing for time.Weekday(n).
+type weekdays uint8
+
+const everyDay weekdays = 1<<7 - 1
+
+func (w weekdays) has(d time.Weekday) bool { return w&(1<<d) != 0 }
+
+var weekdayNames = map[string]weekdays{
+	"sun":      1 << time.Sunday,
+	"mon":      1 << time.Monday,
+	"tue":      1 << time.Tuesday,
+	"wed":      1 << time.Wednesday,
+	"thu":      1 << time.Thursday,
+	"fri":      1 << time.Friday,
+	"sat":      1 << time.Saturday,
+	"weekdays": 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday,
+	"weekends": 1<<time.Saturday | 1<<time.Sunday,
+	"daily":    everyDay,
+}
+
+// quietPeriod is one quiet_hours entry: from start to end minutes past
+// midnight on each of days, where an end before the start is on the next day.
+type quietPeriod struct {
+	days       weekdays
+	start, end int
+}
+
+func (p quietPeriod) contains(t time.Time) bool {
+	day, minute := t.Weekday(), t.Hour()*60+t.Minute()
+	if p.start < p.end {
+		return p.days.has(day) && minute >= p.start && minute < p.end
+	}
+	yesterday := (day + 6) % 7
+	return p.days.has(day) && minute >= p.start || p.days.has(yesterday) && minute < p.end
+}
+
+type quietHours struct {
+	periods  []quietPeriod
+	suppress bool // drop pushes rather than send them without sound
+}
+
+// active reports whether t falls in quiet hours. A nil q never does.
+func (q *quietHours) active(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	for _, p := range q.periods {
+		if p.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQuietHours(entries []string) ([]quietPeriod, error) {
+	periods := make([]quietPeriod, 0, len(entries))
+	for _, entry := range entries {
+		p, err := parseQuietPeriod(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours %q: %w", entry, err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
+
+func parseQuietPeriod(entry string) (quietPeriod, error) {
+	fields := strings.Fields(strings.ToLower(entry))
+	p := quietPeriod{days: everyDay, end: minutesPerDay}
+	if len(fields) == 0 || len(fields) > 2 {
+		return p, errors.New("want a time range such as 22:00-08:00, days such as sat-sun, or both")
+	}
+	if !strings.Contains(fields[0], ":") {
+		days, err := parseWeekdays(fields[0])
+		if err != nil {
+			return p, err
+		}
+		p.days, fields = days, fields[1:]
+	}
+	if len(fields) == 0 {
+		return p, nil
+	}
+	from, to, ok := strings.Cut(fields[0], "-")
+	if !ok || len(fields) > 1 {
+		return p, errors.New("want a time range such as 22:00-08:00, days such as sat-sun, or both")
+	}
+	var err error
+	if p.start, err = parseClock(from); err != nil {
+		return p, err
+	}
+	if p.end, err = parseClock(to); err != nil {
+		return p, err
+	}
+	if p.start == p.end {
+		return p, errors.New("range is empty")
+	}
+	return p, nil
+}
+
+// parseWeekdays parses a comma-separated list of days and day ranges, such as
+// "mon-fri" or "sat,sun". Ranges may wrap around the week, as in "fri-mon".
+func parseWeekdays(s string) (weekdays, error) {
+	var days weekdays
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdayNames[from]
+		if !ok {
+			return 0, fmt.Errorf("unknown day %q", from)
+		}
+		if !isRange {
+			days |= first
+			continue
+		}
+		last, ok := weekdayNames[to]
+		if !ok || !single(first) || !single(last) {
+			return 0, fmt.Errorf("invalid day range %q", part)
+		}
+		end := bits.TrailingZeros8(uint8(last))
+		for d := bits.TrailingZeros8(uint8(first)); ; d = (d + 1) % 7 {
+			days |= 1 << d
+			if d == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// single reports whether w holds exactly one day.
+func single(w weekdays) bool { return w != 0 && w&(w-1) == 0 }
+
+// parseClock parses a 24-hour time such as 08:00 into minutes past midnight.
+// 24:00 is accepted as the end of the day.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hour, herr := strconv.Atoi(h)
+	minute, merr := strconv.Atoi(m)
+	if !ok || herr != nil || merr != nil || len(m) != 2 || hour < 0 || minute < 0 || minute > 59 ||
+		hour*60+minute > minutesPerDay {
+		return 0, fmt.Errorf("invalid time %q: want HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// configQuietHours returns the configured quiet hours, or nil if there are
+// none. Config validation has already checked the entries.
+func configQuietHours(cfg *config) *quietHours {
+	periods, _ := parseQuietHours(cfg.strings("quiet_hours"))
+	if len(periods) == 0 {
+		return nil
+	}
+	return &quietHours{
+		periods:  periods,
+		suppress: cfg.string("quiet_hours_push", quietHoursDowngrade) == quietHoursSuppress,
+	}
+}
+
+// quietPush applies quiet hours at now to the pushes in opts.
+func quietPush(opts options, now time.Time) options {
+	if !opts.quietHours.active(now) {
+		return opts
+	}
+	if opts.quietHours.suppress {
+		opts.push = nil
+	} else {
+		opts.pushQuietly = true
+	}
+	return opts
+}
@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	// 2026-03-06 is a Friday.
+	at := func(day int, clock string) time.Time {
+		c, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2026, 3, day, c.Hour(), c.Minute(), 0, 0, time.Local)
+	}
+	tests := []struct {
+		entries []string
+		t       time.Time
+		quiet   bool
+	}{
+		{entries: []string{"22:00-08:00"}, t: at(6, "23:30"), quiet: true},
+		{entries: []string{"22:00-08:00"}, t: at(6, "07:59"), quiet: true},
+		{entries: []string{"22:00-08:00"}, t: at(6, "08:00"), quiet: false},
+		{entries: []string{"22:00-08:00"}, t: at(6, "21:59"), quiet: false},
+		{entries: []string{"12:00-13:00"}, t: at(6, "12:30"), quiet: true},
+		{entries: []string{"12:00-13:00"}, t: at(6, "13:00"), quiet: false},
+		{entries: []string{"sat-sun"}, t: at(7, "12:00"), quiet: true},
+		{entries: []string{"weekends"}, t: at(8, "23:59"), quiet: true},
+		{entries: []string{"sat-sun"}, t: at(6, "23:00"), quiet: false},
+		// An overnight range belongs to the day it starts on.
+		{entries: []string{"fri 22:00-08:00"}, t: at(7, "07:00"), quiet: true},
+		{entries: []string{"fri 22:00-08:00"}, t: at(6, "07:00"), quiet: false},
+		{entries: []string{"mon-fri 18:00-24:00"}, t: at(6, "23:59"), quiet: true},
+		{entries: []string{"fri-mon"}, t: at(9, "10:00"), quiet: true},
+		{entries: []string{"fri-mon"}, t: at(10, "10:00"), quiet: false},
+		{entries: []string{"Sat,Sun 00:00-10:00"}, t: at(8, "09:00"), quiet: true},
+		{entries: []string{"22:00-08:00", "weekends"}, t: at(7, "15:00"), quiet: true},
+		{entries: nil, t: at(6, "03:00"), quiet: false},
+	}
+	for _, tt := range tests {
+		periods, err := parseQuietHours(tt.entries)
+		if err != nil {
+			t.Fatalf("parseQuietHours(%q) returned error: %v", tt.entries, err)
+		}
+		q := &quietHours{periods: periods}
+		if got := q.active(tt.t); got != tt.quiet {
+			t.Errorf("quiet hours %q active at %s = %v, want %v", tt.entries, tt.t.Format("Mon 15:04"), got, tt.quiet)
+		}
+	}
+	var none *quietHours
+	if none.active(at(6, "03:00")) {
+		t.Error("nil quiet hours are active")
+	}
+}
+
+func TestParseQuietHoursErrors(t *testing.T) {
+	for _, entry := range []string{"", "22:00", "22-08", "25:00-08:00", "22:00-08:60", "8:0-9:00", "09:00-09:00", "someday", "mon-weekends", "sat 22:00-23:00 extra"} {
+		if _, err := parseQuietHours([]string{entry}); err == nil {
+			t.Errorf("parseQuietHours(%q) accepted an invalid entry", entry)
+		}
+	}
+}
+
+func TestQuietHoursConfig(t *testing.T) {
+	base := isolateConfig(t)
+	writeFile(t, systemConfigPath, "quiet_hours = [\"22:00-08:00\"]\nquiet_hours_push = \"suppress\"\n")
+	cfg, err := loadConfig(base)
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+	opts := options{
+		quietHours: configQuietHours(cfg),
+		push:       []pushTarget{{URL: "https://ntfy.sh/builds", Provider: pushProviderNtfy}},
+	}
+	night := time.Date(2026, 3, 6, 23, 0, 0, 0, time.Local)
+	if got := quietPush(opts, night); got.push != nil {
+		t.Errorf("suppress kept pushes during quiet hours: %v", got.push)
+	}
+	if got := quietPush(opts, night.Add(10*time.Hour)); len(got.push) != 1 || got.pushQuietly {
+		t.Errorf("quiet hours applied outside their range: %+v", got)
+	}
+
+	opts.quietHours.suppress = false
+	if got := quietPush(opts, night); len(got.push) != 1 || !got.pushQuietly {
+		t.Errorf("downgrade = %+v, want the push sent quietly", got)
+	}
+
+	for _, bad := range []string{"quiet_hours = [\"late\"]\n", "quiet_hours_push = \"mute\"\n"} {
+		writeFile(t, systemConfigPath, bad)
+		if _, err := loadConfig(base); err == nil {
+			t.Errorf("loadConfig() accepted %q, want error", bad)
+		}
+	}
+}
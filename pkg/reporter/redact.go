@@ -0,0 +1,286 @@
+package reporter
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Command lines end up in notifications, pushes, and the history, so
+// obvious secrets are scrubbed from them first: the values of flags and
+// environment variables named like secrets (--password=..., GITHUB_TOKEN=...),
+// the password flags of a few clients (mysql -p...), credentials in URLs
+// and Authorization headers, and tokens with well-known prefixes. The
+// command itself still runs with them, of course. The redact config key
+// adds names to scrub, and -no-redact turns it off.
+
+// redacted replaces a scrubbed secret.
+const redacted = "***"
+
+// secretFlagName matches flag and variable names, normalized by
+// normalizeSecretName, whose values are secrets.
+var secretFlagName = regexp.MustCompile(`(password|passwd|passphrase|secret|token|api-?key|access-?key|secret-?key|private-?key|credentials?)$|(^|-)(pass|pwd|auth)$`)
+
+// shortSecretFlags are the password flags of clients that take the
+// password as -pSECRET or -p SECRET, by program name.
+var shortSecretFlags = map[string]string{
+	"mysql":        "-p",
+	"mysqldump":    "-p",
+	"mysqladmin":   "-p",
+	"mariadb":      "-p",
+	"mariadb-dump": "-p",
+	"sshpass":      "-p",
+	"redis-cli":    "-a",
+}
+
+// commandWrappers run the command that follows them, whose name is then
+// the program's.
+var commandWrappers = map[string]bool{
+	"sudo": true, "env": true, "exec": true, "time": true, "nice": true, "nohup": true, "command": true,
+}
+
+// secretPatterns match secrets anywhere in a command line; the secret is
+// what lies between the first group and the second, if any.
+var secretPatterns = []*regexp.Regexp{
+	// user:password@ in URLs
+	regexp.MustCompile(`(://[^/\s:@'"]+:)[^/\s@'"]+(@)`),
+	// Authorization: Bearer ..., and the like
+	regexp.MustCompile(`(?i)(\b(?:bearer|basic|token)\s+)[A-Za-z0-9._~+/=][A-Za-z0-9._~+/=-]{7,}`),
+	regexp.MustCompile(`(?i)(\b(?:x-api-key|x-auth-token|private-token|api-key)\s*:\s*)[^\s'"]+`),
+	// ?access_token=... in URLs
+	regexp.MustCompile(`(?i)([?&](?:access_token|token|api_key|apikey|key|secret|password|sig|signature)=)[^&\s'"]+`),
+	// OpenAI, Stripe, GitHub, GitLab, Slack, AWS, and npm tokens, and JWTs
+	regexp.MustCompile(`()\b(?:sk-[A-Za-z0-9_-]{16,}|sk_(?:live|test)_[A-Za-z0-9]{16,}|gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|glpat-[A-Za-z0-9_-]{20,}|xox[abprs]-[A-Za-z0-9-]{10,}|AKIA[0-9A-Z]{16}|npm_[A-Za-z0-9]{36}|eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,})`),
+}
+
+// redactor scrubs secrets from command lines. A nil redactor leaves them
+// as they are.
+type redactor struct {
+	// names are further flag or variable names whose values are secrets,
+	// normalized.
+	names map[string]bool
+}
+
+// newRedactor returns a redactor that also scrubs the values of the flags
+// or environment variables in names, such as "DATABASE_URL" or "--dsn".
+func newRedactor(names []string) *redactor {
+	r := &redactor{names: map[string]bool{}}
+	for _, name := range names {
+		r.names[normalizeSecretName(name)] = true
+	}
+	return r
+}
+
+// normalizeSecretName lowercases name and drops its leading dashes, with
+// underscores as dashes, so --api_key, API_KEY, and -api-key are alike.
+func normalizeSecretName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimLeft(name, "-")), "_", "-")
+}
+
+func (r *redactor) secretName(name string) bool {
+	name = normalizeSecretName(name)
+	if r.names[name] {
+		return true
+	}
+	return !strings.HasPrefix(name, "no-") && secretFlagName.MatchString(name)
+}
+
+// args returns argv with secrets scrubbed. The script of sh -c and the
+// like is scrubbed as a command line.
+func (r *redactor) args(argv []string) []string {
+	if r == nil || argv == nil {
+		return argv
+	}
+	out := make([]string, len(argv))
+	keep := r.plan(argv)
+	for i, arg := range argv {
+		if i > 0 && argv[i-1] == "-c" {
+			out[i] = r.command(arg)
+			continue
+		}
+		out[i] = scrubPatterns(applyKeep(arg, keep[i]))
+	}
+	return out
+}
+
+// command returns the shell command line s with secrets scrubbed. Words are
+// split as a shell would, roughly; a scrubbed word loses its quotes.
+func (r *redactor) command(s string) string {
+	if r == nil {
+		return s
+	}
+	words := shellWords(s)
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.text
+	}
+	keep := r.plan(texts)
+	var b strings.Builder
+	last := 0
+	for i, w := range words {
+		if keep[i] < 0 {
+			continue
+		}
+		b.WriteString(s[last:w.start])
+		b.WriteString(applyKeep(w.text, keep[i]))
+		last = w.end
+	}
+	b.WriteString(s[last:])
+	return scrubPatterns(b.String())
+}
+
+// plan decides which of a command's words hold secrets. For each word it
+// returns -1 to leave it, or how many of its leading bytes to keep before
+// the rest is scrubbed.
+func (r *redactor) plan(words []string) []int {
+	keep := make([]int, len(words))
+	for i := range keep {
+		keep[i] = -1
+	}
+	program, atStart := "", true
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if isShellSeparator(w) {
+			program, atStart = "", true
+			continue
+		}
+		if name, _, ok := strings.Cut(w, "="); ok && isVariableName(name) {
+			// FOO=bar before a command, or env FOO=bar, docker run -e FOO=bar
+			if r.secretName(name) {
+				keep[i] = len(name) + 1
+			}
+			continue
+		}
+		if atStart {
+			if commandWrappers[program] && strings.HasPrefix(w, "-") {
+				continue // sudo -E ..., nice -n 10 ...
+			}
+			program = filepath.Base(w)
+			atStart = commandWrappers[program]
+			continue
+		}
+		if !strings.HasPrefix(w, "-") || w == "-" || w == "--" {
+			continue
+		}
+		if flag := shortSecretFlags[program]; flag != "" && !strings.HasPrefix(w, "--") && strings.HasPrefix(w, flag) {
+			if w != flag {
+				keep[i] = len(flag)
+			} else if i+1 < len(words) && !isShellSeparator(words[i+1]) {
+				i++
+				keep[i] = 0
+			}
+			continue
+		}
+		name, _, hasValue := strings.Cut(w, "=")
+		if !r.secretName(name) {
+			continue
+		}
+		if hasValue {
+			keep[i] = len(name) + 1
+		} else if i+1 < len(words) && !isShellSeparator(words[i+1]) && !strings.HasPrefix(words[i+1], "-") {
+			i++
+			keep[i] = 0
+		}
+	}
+	return keep
+}
+
+// applyKeep scrubs word after its first keep bytes, or leaves it if keep is
+// negative.
+func applyKeep(word string, keep int) string {
+	if keep < 0 {
+		return word
+	}
+	return word[:keep] + redacted
+}
+
+func scrubPatterns(s string) string {
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	}
+	return s
+}
+
+func isVariableName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// shellWord is a word of a shell command line: its text with quotes
+// removed, and where it is in the line.
+type shellWord struct {
+	text       string
+	start, end int
+}
+
+// shellWords splits a command line into words as a shell would, roughly:
+// quotes and backslashes are honoured, and ;, &, |, and parentheses are
+// words of their own. Expansions are left as they are.
+func shellWords(s string) []shellWord {
+	var words []shellWord
+	var text strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			words = append(words, shellWord{text.String(), start, end})
+			text.Reset()
+			start = -1
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush(i)
+			continue
+		case strings.IndexByte(";&|()", c) >= 0:
+			flush(i)
+			j := i + 1
+			for j < len(s) && strings.IndexByte(";&|", s[j]) >= 0 && c != '(' && c != ')' {
+				j++
+			}
+			words = append(words, shellWord{s[i:j], i, j})
+			i = j - 1
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		switch c {
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			text.WriteString(s[i+1 : i+1+end])
+			i = min(i+1+end, len(s)-1)
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				text.WriteByte(s[i])
+			}
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				text.WriteByte(s[i])
+			}
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flush(len(s))
+	return words
+}
+
+func isShellSeparator(w string) bool {
+	return w != "" && strings.Trim(w, ";&|()") == ""
+}
@@ -0,0 +1,53 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+)
+
+// resourceUsage is what a command used while it ran, as the kernel
+// accounts it when it exits: CPU time in user and system mode, and the peak
+// resident memory of the command or of the largest of its descendants that
+// it waited for. Zero fields were not measured.
+type resourceUsage struct {
+	UserCPU   time.Duration
+	SystemCPU time.Duration
+	MaxRSS    int64 // bytes
+}
+
+// add combines the usage of two runs of a command, as with -retries: CPU
+// time adds up, and the peak is the higher one.
+func (u resourceUsage) add(v resourceUsage) resourceUsage {
+	u.UserCPU += v.UserCPU
+	u.SystemCPU += v.SystemCPU
+	u.MaxRSS = max(u.MaxRSS, v.MaxRSS)
+	return u
+}
+
+// resourceNote describes u for a notification body, such as "used 31m00s
+// CPU, 12.0 GB peak RSS", or returns "" if nothing was measured.
+func resourceNote(u resourceUsage) string {
+	cpu := u.UserCPU + u.SystemCPU
+	switch {
+	case cpu > 0 && u.MaxRSS > 0:
+		return fmt.Sprintf("used %s CPU, %s peak RSS", formatDuration(cpu), formatBytes(u.MaxRSS))
+	case cpu > 0:
+		return fmt.Sprintf("used %s CPU", formatDuration(cpu))
+	case u.MaxRSS > 0:
+		return fmt.Sprintf("%s peak RSS", formatBytes(u.MaxRSS))
+	}
+	return ""
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1f GB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1f MB", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1f kB", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,49 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResourceNote(t *testing.T) {
+	tests := []struct {
+		name string
+		u    resourceUsage
+		want string
+	}{
+		{name: "compile", u: resourceUsage{UserCPU: 29 * time.Minute, SystemCPU: 2 * time.Minute, MaxRSS: 12e9}, want: "used 31m00s CPU, 12.0 GB peak RSS"},
+		{name: "small", u: resourceUsage{UserCPU: 300 * time.Millisecond, MaxRSS: 4_500_000}, want: "used 300ms CPU, 4.5 MB peak RSS"},
+		{name: "cpu only", u: resourceUsage{UserCPU: time.Hour, SystemCPU: 5 * time.Minute}, want: "used 1h05m00s CPU"},
+		{name: "unmeasured", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resourceNote(tt.u); got != tt.want {
+				t.Errorf("resourceNote(%+v) = %q, want %q", tt.u, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceUsageAdd(t *testing.T) {
+	a := resourceUsage{UserCPU: time.Second, SystemCPU: time.Second, MaxRSS: 2e9}
+	b := resourceUsage{UserCPU: 2 * time.Second, MaxRSS: 1e9}
+	want := resourceUsage{UserCPU: 3 * time.Second, SystemCPU: time.Second, MaxRSS: 2e9}
+	if got := a.add(b); got != want {
+		t.Errorf("add() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		512:           "512 B",
+		2048:          "2.0 kB",
+		734_003_200:   "734.0 MB",
+		1_500_000_000: "1.5 GB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
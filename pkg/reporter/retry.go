@@ -0,0 +1,51 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// With -retries N, a command that fails is run again, up to N more times,
+// for jobs that fail now and then for reasons outside them, such as a
+// download or terraform apply over a flaky network. Each failed attempt
+// that will be retried is notified as it happens, and the last attempt is
+// reported like any run, noting how many there were. Only that last one is
+// recorded in the history and counts toward recoveries.
+
+// defaultRetryDelay is how long to wait before the first retry.
+const defaultRetryDelay = 30 * time.Second
+
+// sleepUnlessSignaled waits d, and returns false if reporter is sent
+// SIGINT, SIGTERM, or SIGHUP first, so Ctrl-C between attempts stops the
+// retries instead of waiting for the next one.
+func sleepUnlessSignaled(d time.Duration) bool {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-sigs:
+		return false
+	}
+}
+
+// attemptNote describes the attempts behind res for its notification, such
+// as "; retrying in 1m00s (attempt 2 of 4)" or " after 4 attempts", or ""
+// without -retries.
+func attemptNote(res runResult, retries int) string {
+	switch {
+	case res.RetryIn > 0:
+		return fmt.Sprintf("; retrying in %s (attempt %d of %d)", formatDuration(res.RetryIn), res.Attempts+1, retries+1)
+	case res.Attempts > 1 && res.ExitCode == 0:
+		return fmt.Sprintf(" on attempt %d of %d", res.Attempts, retries+1)
+	case res.Attempts > 1:
+		return fmt.Sprintf(" after %d attempts", res.Attempts)
+	}
+	return ""
+}
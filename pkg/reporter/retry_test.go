@@ -0,0 +1,64 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttemptNote(t *testing.T) {
+	for _, tt := range []struct {
+		res     runResult
+		retries int
+		want    string
+	}{
+		{res: runResult{ExitCode: 1}, want: ""},
+		{res: runResult{ExitCode: 1, Attempts: 1}, retries: 3, want: ""},
+		{res: runResult{ExitCode: 1, Attempts: 1, RetryIn: 30 * time.Second}, retries: 3, want: "; retrying in 30s (attempt 2 of 4)"},
+		{res: runResult{ExitCode: 0, Attempts: 3}, retries: 3, want: " on attempt 3 of 4"},
+		{res: runResult{ExitCode: 2, Attempts: 4}, retries: 3, want: " after 4 attempts"},
+	} {
+		if got := attemptNote(tt.res, tt.retries); got != tt.want {
+			t.Errorf("attemptNote(%+v, %d) = %q, want %q", tt.res, tt.retries, got, tt.want)
+		}
+	}
+}
+
+func TestRunWithRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs sh")
+	}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	history := jsonlHistory{path: filepath.Join(dir, "history.jsonl")}
+	opts := options{threshold: time.Hour, quiet: true, history: history, retries: 2, retryDelay: time.Millisecond}
+	// count runs the script and fails until it has run succeedOn times.
+	count := filepath.Join(dir, "count")
+	script := func(succeedOn int) []string {
+		return []string{"sh", "-c", `n=$(cat "$0" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$0"; [ $n -ge ` + strconv.Itoa(succeedOn) + ` ]`, count}
+	}
+	runs := func() string {
+		data, _ := os.ReadFile(count)
+		os.Remove(count)
+		return strings.TrimSpace(string(data))
+	}
+
+	if code := runWithNotification(script(2), "flaky", opts); code != 0 || runs() != "2" {
+		t.Errorf("a command that succeeds on its second attempt exited %d", code)
+	}
+	if code := runWithNotification(script(9), "broken", opts); code != 1 || runs() != "3" {
+		t.Errorf("a command that always fails exited %d", code)
+	}
+	// Only each run's last attempt is recorded.
+	entries, err := history.Load()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("history has %d entries (%v), want 2", len(entries), err)
+	}
+	if entries[0].ExitCode != 0 || entries[1].ExitCode != 1 {
+		t.Errorf("history = %+v", entries)
+	}
+}
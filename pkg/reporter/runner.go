@@ -0,0 +1,116 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Runner runs commands and notifies about those that take at least
+// Threshold, the way the reporter command does. The zero Runner notifies
+// about every run, but has no one to tell.
+type Runner struct {
+	// Title of the notifications; "Task finished" if empty.
+	Title     string
+	Threshold time.Duration
+	// OnlyFailures skips notifications about successful runs, except, with
+	// KeepState, for a success after failures, which reports the recovery.
+	OnlyFailures bool
+	Notifiers    []Notifier
+	// PushURLs are pushed to as by the command's -push-url, each through
+	// the provider its URL selects, such as ntfy for ntfy://ntfy.sh/builds.
+	PushURLs []string
+	// Desktop also shows the notifications on this machine's desktop,
+	// unless the terminal is focused.
+	Desktop bool
+	// Labels are attached to every notification.
+	Labels map[string]string
+	// KeepState keeps what the command keeps in reporter's state directory:
+	// each command's count of failures, which recoveries are reported
+	// against, and pushes that failed for a transient reason, which are
+	// resent before later notifications. Otherwise a Runner writes nothing
+	// there.
+	KeepState bool
+}
+
+// Result is how a run went.
+type Result struct {
+	Command  string
+	ExitCode int    // 128 plus the signal number when killed by one
+	Signal   string // the signal that killed the command, such as SIGKILL
+	Duration time.Duration
+	// Notified reports whether the run passed the threshold and filters,
+	// whether or not every notifier took the notification.
+	Notified bool
+}
+
+// Run runs cmd, waits for it, and notifies about it as configured. Like the
+// command's, cmd runs in a process group of its own, which gets the SIGINT,
+// SIGTERM, and SIGHUP sent to this process while it runs.
+//
+// A command that fails is not an error: its exit status is in the Result.
+// The error is from starting cmd, in which case nobody is notified, or from
+// the notifiers and pushes, joined. ctx bounds the Notifiers; to bound the
+// command too, create it with exec.CommandContext.
+func (r *Runner) Run(ctx context.Context, cmd *exec.Cmd) (Result, error) {
+	display := strings.Join(cmd.Args, " ")
+	opts := r.options(ctx)
+	run, _, err := execute(cmd, display, nil, opts)
+	if err != nil {
+		return Result{Command: display}, err
+	}
+	run.Args = cmd.Args
+
+	// report decides whether to notify, as for the command, and hands the
+	// run to hold to send it, so that how the deliveries went is kept.
+	var deliveries []delivery
+	opts.hold = func(run runResult, opts options) {
+		n := newNotification(run, opts)
+		n.Dir = cmd.Dir
+		deliveries = deliver(n, opts)
+	}
+	res := Result{
+		Command:  display,
+		ExitCode: run.ExitCode,
+		Duration: run.Duration,
+		Notified: report(run, opts),
+	}
+	if run.Signal != 0 {
+		res.Signal = signalName(run.Signal)
+	}
+	var errs []error
+	for _, d := range deliveries {
+		if d.err != nil {
+			errs = append(errs, d.err)
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// options returns the settings reporting r's runs, quietly, since the
+// program embedding the Runner decides what it prints and keeps.
+func (r *Runner) options(ctx context.Context) options {
+	opts := options{
+		threshold:     r.Threshold,
+		notifyOn:      notifyOnAlways,
+		title:         r.Title,
+		desktop:       r.Desktop,
+		quiet:         true,
+		labels:        r.Labels,
+		notifiers:     r.Notifiers,
+		notifyContext: ctx,
+		noState:       !r.KeepState,
+	}
+	if opts.title == "" {
+		opts.title = "Task finished"
+	}
+	if r.OnlyFailures {
+		opts.notifyOn = notifyOnFailure
+	}
+	for _, u := range r.PushURLs {
+		opts.push = append(opts.push, pushTarget{URL: u, Provider: detectPushProvider(u), PayloadVersion: defaultPayloadVersion})
+	}
+	return opts
+}
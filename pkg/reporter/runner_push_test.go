@@ -0,0 +1,54 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRunnerKeepsNothing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	noPushBackoff(t)
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	old := os.Stderr
+	os.Stderr = stderr
+	defer func() { os.Stderr = old }()
+
+	r := Runner{PushURLs: []string{srv.URL}, Notifiers: []Notifier{NotifierFunc(func(context.Context, Notification) error {
+		return errors.New("offline")
+	})}}
+	for _, script := range []string{"exit 1", "exit 0"} {
+		if _, err := r.Run(context.Background(), exec.Command("sh", "-c", script)); err == nil {
+			t.Errorf("Run(%q) returned no error, want the failed deliveries'", script)
+		}
+	}
+	os.Stderr = old
+
+	if out, _ := os.ReadFile(stderr.Name()); len(out) > 0 {
+		t.Errorf("Run() wrote to stderr:\n%s", out)
+	}
+	if ents, _ := os.ReadDir(state); len(ents) > 0 {
+		t.Errorf("Run() wrote %s to the state directory", ents[0].Name())
+	}
+}
@@ -0,0 +1,140 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	for _, tt := range []struct {
+		name         string
+		script       string
+		threshold    time.Duration
+		onlyFailures bool
+		want         Result
+		body         string
+	}{
+		{name: "success", script: "exit 0", want: Result{Command: "sh -c exit 0", Notified: true}, body: "succeeded in "},
+		{name: "failure", script: "exit 3", want: Result{Command: "sh -c exit 3", ExitCode: 3, Notified: true}, body: "failed (exit 3) in "},
+		{name: "killed", script: "kill -TERM $$", want: Result{Command: "sh -c kill -TERM $$", ExitCode: 143, Signal: "SIGTERM", Notified: true}, body: "killed by SIGTERM in "},
+		{name: "under threshold", script: "exit 3", threshold: time.Hour, want: Result{Command: "sh -c exit 3", ExitCode: 3}},
+		{name: "only failures", script: "exit 0", onlyFailures: true, want: Result{Command: "sh -c exit 0"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			var got []Notification
+			r := Runner{
+				Threshold:    tt.threshold,
+				OnlyFailures: tt.onlyFailures,
+				Labels:       map[string]string{"env": "ci"},
+				Notifiers: []Notifier{NotifierFunc(func(ctx context.Context, n Notification) error {
+					got = append(got, n)
+					return nil
+				})},
+			}
+			res, err := r.Run(context.Background(), exec.Command("sh", "-c", tt.script))
+			if err != nil {
+				t.Fatalf("Run() returned error: %v", err)
+			}
+			res.Duration = 0
+			if res != tt.want {
+				t.Errorf("Run() = %+v, want %+v", res, tt.want)
+			}
+			if !tt.want.Notified {
+				if len(got) != 0 {
+					t.Errorf("notified about %+v", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("notified %d times, want once", len(got))
+			}
+			n := got[0]
+			if n.Title != "Task finished" || !strings.HasPrefix(n.Body, tt.body) || n.Command != tt.want.Command ||
+				n.ExitCode != tt.want.ExitCode || n.Failed != (tt.want.ExitCode != 0) || n.Labels["env"] != "ci" || n.Dir == "" {
+				t.Errorf("notification = %+v", n)
+			}
+		})
+	}
+}
+
+func TestRunnerRecovery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	var got []Notification
+	r := Runner{OnlyFailures: true, KeepState: true, Notifiers: []Notifier{NotifierFunc(func(ctx context.Context, n Notification) error {
+		got = append(got, n)
+		return nil
+	})}}
+	flag := filepath.Join(t.TempDir(), "fixed")
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			writeFile(t, flag, "")
+		}
+		if _, err := r.Run(context.Background(), exec.Command("sh", "-c", "test -e "+flag)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 3 || !strings.HasPrefix(got[2].Body, "recovered: succeeded in ") || !strings.HasSuffix(got[2].Body, " after 2 failed runs") {
+		t.Errorf("notified %+v, want two failures and the recovery", got)
+	}
+}
+
+func TestRunnerPush(t *testing.T) {
+	if !pushCompiled {
+		t.Skip("built without push support")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+	}))
+	defer srv.Close()
+	r := Runner{PushURLs: []string{srv.URL}}
+	if _, err := r.Run(context.Background(), exec.Command("sh", "-c", "exit 2")); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-bodies; !strings.Contains(body, "failed (exit 2) in ") {
+		t.Errorf("pushed %q", body)
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	r := Runner{}
+	if _, err := r.Run(context.Background(), exec.Command("reporter-test-no-such-command")); err == nil {
+		t.Error("Run() of a missing command returned no error")
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	failing := NotifierFunc(func(context.Context, Notification) error { return errors.New("offline") })
+	var delivered bool
+	r.Notifiers = []Notifier{failing, NotifierFunc(func(context.Context, Notification) error {
+		delivered = true
+		return nil
+	})}
+	res, err := r.Run(context.Background(), exec.Command("sh", "-c", "exit 0"))
+	if err == nil || err.Error() != "offline" || !res.Notified || !delivered {
+		t.Errorf("Run() = %+v, %v; want the failed notifier's error after notifying the rest", res, err)
+	}
+}
@@ -0,0 +1,108 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// -sentry-monitor reports a wrapped command to a Sentry Cron Monitor, so a
+// scheduled job Sentry already watches needs no second wrapper: reporter
+// checks in as in progress when the command starts and as ok or error when it
+// exits, whatever the threshold and even while notifications are muted.
+
+// Check-in statuses.
+const (
+	sentryInProgress = "in_progress"
+	sentryOK         = "ok"
+	sentryError      = "error"
+)
+
+// sentryMonitor is a cron monitor to check in to.
+type sentryMonitor struct {
+	// endpoint is the monitor's check-in URL, without a status.
+	endpoint    string
+	environment string
+}
+
+// newSentryMonitor returns the monitor slug in the project of dsn, or nil if
+// slug is "".
+func newSentryMonitor(dsn, slug, environment string) (*sentryMonitor, error) {
+	if slug == "" {
+		return nil, nil
+	}
+	if dsn == "" {
+		return nil, errors.New("no DSN: set SENTRY_DSN or sentry_dsn")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DSN %q: want https://<key>@<host>/<project>", redactDSN(dsn))
+	}
+	// Self-hosted Sentry may live under a path, which comes before the
+	// project ID.
+	path := strings.Trim(u.Path, "/")
+	prefix, project := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		prefix, project = path[:i], path[i+1:]
+	}
+	if project == "" {
+		return nil, fmt.Errorf("invalid DSN %q: no project ID", redactDSN(dsn))
+	}
+	base := u.Scheme + "://" + u.Host
+	if prefix != "" {
+		base += "/" + prefix
+	}
+	return &sentryMonitor{
+		endpoint:    fmt.Sprintf("%s/api/%s/cron/%s/%s/", base, project, url.PathEscape(slug), url.PathEscape(u.User.Username())),
+		environment: environment,
+	}, nil
+}
+
+// redactDSN hides the key in dsn, for error messages.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		u.User = url.User("***")
+		return u.String()
+	}
+	return "***"
+}
+
+// checkInURL returns the URL that checks in with status.
+func (m *sentryMonitor) checkInURL(status string) string {
+	q := url.Values{"status": {status}}
+	if m.environment != "" {
+		q.Set("environment", m.environment)
+	}
+	return m.endpoint + "?" + q.Encode()
+}
+
+// start checks in as in progress, without holding up the command, and
+// returns the function that checks in how it exited. Failures are reported on
+// stderr unless quiet. A nil monitor does nothing.
+func (m *sentryMonitor) start(quiet bool) (finish func(exitCode int)) {
+	if m == nil {
+		return func(int) {}
+	}
+	warn := func(err error) {
+		if err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "[sentry] %v\n", err)
+		}
+	}
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		warn(m.checkIn(sentryInProgress))
+	}()
+	return func(exitCode int) {
+		// Sentry pairs the result with the open check-in, so it must
+		// arrive second.
+		<-started
+		status := sentryOK
+		if exitCode != 0 {
+			status = sentryError
+		}
+		warn(m.checkIn(status))
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// checkIn sends one check-in with status.
+func (m *sentryMonitor) checkIn(status string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.checkInURL(status), nil)
+	if err != nil {
+		return fmt.Errorf("creating check-in request: %w", err)
+	}
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		// A *url.Error names the URL, which holds the DSN's key.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("checking in %s to %s: %w", status, req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("checking in %s: sentry returned %s", status, resp.Status)
+	}
+	return nil
+}
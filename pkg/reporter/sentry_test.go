@@ -0,0 +1,66 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewSentryMonitor(t *testing.T) {
+	tests := []struct {
+		dsn, slug string
+		want      string // check-in URL for ok; "" for an error
+	}{
+		{"https://abc123@o42.ingest.sentry.io/7", "nightly-etl", "https://o42.ingest.sentry.io/api/7/cron/nightly-etl/abc123/?status=ok"},
+		{"https://abc123@sentry.example.com/sentry/7", "etl", "https://sentry.example.com/sentry/api/7/cron/etl/abc123/?status=ok"},
+		{"https://o42.ingest.sentry.io/7", "etl", ""},
+		{"https://abc123@o42.ingest.sentry.io/", "etl", ""},
+		{"", "etl", ""},
+	}
+	for _, tt := range tests {
+		m, err := newSentryMonitor(tt.dsn, tt.slug, "")
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("newSentryMonitor(%q) accepted an invalid DSN", tt.dsn)
+		case tt.want != "" && err != nil:
+			t.Errorf("newSentryMonitor(%q) returned error: %v", tt.dsn, err)
+		case tt.want != "" && m.checkInURL(sentryOK) != tt.want:
+			t.Errorf("newSentryMonitor(%q) checks in at %q, want %q", tt.dsn, m.checkInURL(sentryOK), tt.want)
+		}
+		if err != nil && strings.Contains(err.Error(), "abc123") {
+			t.Errorf("error %q reveals the DSN's key", err)
+		}
+	}
+	if m, err := newSentryMonitor("", "", ""); m != nil || err != nil {
+		t.Errorf("newSentryMonitor() without a slug = %v, %v; want nil", m, err)
+	}
+}
+
+func TestSentryCheckIns(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, r.URL.RawQuery)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	m, err := newSentryMonitor(strings.Replace(srv.URL, "://", "://abc123@", 1)+"/7", "etl", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.start(false)(2)
+	want := []string{"environment=production&status=in_progress", "environment=production&status=error"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("check-ins %q, want %q", got, want)
+	}
+
+	var none *sentryMonitor
+	none.start(false)(0) // does nothing
+}
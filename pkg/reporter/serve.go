@@ -0,0 +1,153 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// `reporter serve` is a push destination of your own: it accepts the
+// reporter/v1 JSON payload over HTTP, exactly as the webhook provider POSTs
+// it, and raises each one as a desktop notification. Reporters on remote
+// servers point -push-url at a laptop, or its tailnet address, instead of at
+// a third-party push service.
+//
+// Anyone who can reach the port can raise notifications, so beyond loopback
+// a token is required, sent as the push token is (see
+// pushTarget.authorization); with REPORTER_SERVE_SECRET, the payload's
+// X-Reporter-Signature is checked too.
+
+// serveDefaultListen is the address `reporter serve` listens on by default.
+const serveDefaultListen = ":8787"
+
+// serveHandler accepts payloads and raises them with show.
+type serveHandler struct {
+	token  string
+	secret string
+	show   func(notification) error
+}
+
+func (h serveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a reporter/v1 payload", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.token != "" {
+		want := pushTarget{Token: h.token}.authorization()
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, agentMaxPayload))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("payload larger than %d bytes", agentMaxPayload), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if h.secret != "" && !hmac.Equal([]byte(r.Header.Get("X-Reporter-Signature")), []byte(signPayload(h.secret, body))) {
+		http.Error(w, "missing or wrong signature", http.StatusUnauthorized)
+		return
+	}
+	n, err := decodePayload(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var sender struct {
+		Host string `json:"host"`
+	}
+	_ = json.Unmarshal(body, &sender)
+	n.Title = remoteTitle(sender.Host, n.Title)
+	if err := h.show(n); err != nil {
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		http.Error(w, oneLine(err.Error()), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// remoteTitle prefixes title with the host it came from, so notifications
+// from several servers can be told apart, unless the title names it already.
+func remoteTitle(host, title string) string {
+	if host == "" || strings.Contains(title, host) {
+		return title
+	}
+	return host + ": " + title
+}
+
+// isLoopbackListen reports whether addr, a host:port, only accepts
+// connections from this machine.
+func isLoopbackListen(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || ip != nil && ip.IsLoopback()
+}
+
+// runServe implements `reporter serve`.
+func runServe(args []string) int {
+	fset := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter serve [-listen host:port] [-token token]")
+		fset.PrintDefaults()
+	}
+	listen := fset.String("listen", serveDefaultListen, "`host:port` to accept notifications on")
+	token := fset.String("token", os.Getenv("REPORTER_SERVE_TOKEN"), "`token` senders must give as their push token (or REPORTER_SERVE_TOKEN, which stays out of process listings); required beyond loopback")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 || *listen == "" {
+		fset.Usage()
+		return 2
+	}
+	if *token == "" && !isLoopbackListen(*listen) {
+		fmt.Fprintf(os.Stderr, "serve: refusing to listen on %s without -token: anyone who can connect could raise notifications\n", *listen)
+		return 2
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		return 1
+	}
+	srv := &http.Server{
+		Handler:           serveHandler{token: *token, secret: os.Getenv("REPORTER_SERVE_SECRET"), show: notifyDesktop},
+		ReadHeaderTimeout: agentTimeout,
+		ReadTimeout:       agentTimeout,
+	}
+	// On SIGINT or SIGTERM, finish the notifications being raised and
+	// exit.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), agentTimeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+	fmt.Fprintf(os.Stderr, "serve: listening on %s\n", ln.Addr())
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		return 1
+	}
+	<-done
+	return 0
+}
@@ -0,0 +1,102 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeRoundTrip(t *testing.T) {
+	shown := make(chan notification, 1)
+	showErr := error(nil)
+	srv := httptest.NewServer(serveHandler{token: "tk_abc", secret: "s3cret", show: func(n notification) error {
+		err := showErr
+		shown <- n
+		return err
+	}})
+	defer srv.Close()
+
+	target := pushTarget{URL: srv.URL, Provider: pushProviderWebhook, Token: "tk_abc", Secret: "s3cret", PayloadVersion: 1}
+	n := notification{Title: "Build", Body: "failed (exit 2) in 3m", Subtitle: "make", Failed: true,
+		Duration: 3 * time.Minute, ExitCode: 2, Finished: time.Date(2026, 3, 4, 15, 4, 5, 0, time.UTC)}
+	if err := pushOnce(target, n); err != nil {
+		t.Fatalf("pushOnce() returned error: %v", err)
+	}
+	got := <-shown
+	host, _ := os.Hostname()
+	if got.Title != host+": Build" || got.Body != n.Body || got.Subtitle != "make" || !got.Failed || got.ExitCode != 2 || got.Duration != n.Duration {
+		t.Errorf("shown %+v", got)
+	}
+
+	showErr = errors.New("notify-send: not found")
+	if err := pushOnce(target, n); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("pushOnce() with a failing notifier = %v, want a 502", err)
+	}
+	<-shown
+
+	for name, bad := range map[string]pushTarget{
+		"no token":     {URL: srv.URL, Provider: pushProviderWebhook, Secret: "s3cret", PayloadVersion: 1},
+		"wrong token":  {URL: srv.URL, Provider: pushProviderWebhook, Token: "nope", Secret: "s3cret", PayloadVersion: 1},
+		"wrong secret": {URL: srv.URL, Provider: pushProviderWebhook, Token: "tk_abc", Secret: "nope", PayloadVersion: 1},
+	} {
+		if err := pushOnce(bad, n); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("%s: pushOnce() = %v, want a 401", name, err)
+		}
+	}
+	select {
+	case n := <-shown:
+		t.Errorf("rejected payload was shown: %+v", n)
+	default:
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", resp.StatusCode)
+	}
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"schema":"reporter/v9"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST without a token: status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestRemoteTitle(t *testing.T) {
+	for _, tt := range []struct{ host, title, want string }{
+		{host: "build-01", title: "Task finished", want: "build-01: Task finished"},
+		{host: "build-01", title: "[build-01] Deploy", want: "[build-01] Deploy"},
+		{host: "", title: "Task finished", want: "Task finished"},
+	} {
+		if got := remoteTitle(tt.host, tt.title); got != tt.want {
+			t.Errorf("remoteTitle(%q, %q) = %q, want %q", tt.host, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestIsLoopbackListen(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8787": true,
+		"localhost:8787": true,
+		"[::1]:8787":     true,
+		":8787":          false,
+		"0.0.0.0:8787":   false,
+		"100.64.0.7:80":  false,
+		"8787":           false,
+	} {
+		if got := isLoopbackListen(addr); got != want {
+			t.Errorf("isLoopbackListen(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
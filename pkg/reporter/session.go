@@ -0,0 +1,64 @@
+package reporter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Values for -desktop.
+const (
+	desktopAuto   = "auto" // with a host agent, or at a terminal outside containers and CI
+	desktopAlways = "always"
+	desktopNever  = "never"
+)
+
+// interactive reports whether a person is likely watching: stderr is a
+// terminal, or the process still has a controlling terminal. The shell hooks
+// discard stderr but keep the terminal, while cron jobs, CI runners, and
+// containers started without -t have neither, and looking for a desktop
+// notifier there only wastes time.
+var interactive = sync.OnceValue(func() bool {
+	if isTerminal(os.Stderr) {
+		return true
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return false
+	}
+	tty.Close()
+	return true
+})
+
+// registerDesktopFlag defines -desktop on fset and returns a function that
+// reports, after parsing, whether to attempt desktop notifications.
+func registerDesktopFlag(fset *flag.FlagSet, cfg *config) func() (bool, error) {
+	mode := fset.String("desktop", cfg.string("desktop", desktopAuto), "when to show desktop notifications: auto (through a host agent if there is one, else only with a terminal attached and outside containers and CI), always, or never")
+	return func() (bool, error) {
+		switch *mode {
+		case desktopAuto:
+			return hostAgent(cfg) != "" || (interactive() && !currentEnvironment().headless()), nil
+		case desktopAlways:
+			return true, nil
+		case desktopNever:
+			return false, nil
+		default:
+			return false, fmt.Errorf("invalid -desktop %q: want auto, always, or never", *mode)
+		}
+	}
+}
+
+// ringBell sounds the terminal bell on the controlling terminal, so it is
+// heard even when stderr is redirected, as it is in the shell hooks. Without
+// a terminal there is nobody to hear it.
+func ringBell() {
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		tty.WriteString("\a")
+		tty.Close()
+		return
+	}
+	if isTerminal(os.Stderr) {
+		fmt.Fprint(os.Stderr, "\a")
+	}
+}
@@ -0,0 +1,38 @@
+package reporter
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestRegisterDesktopFlag(t *testing.T) {
+	isolateConfig(t)
+	cfg, err := loadConfig(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args    []string
+		want    bool
+		wantErr bool
+	}{
+		{args: []string{"-desktop", "always"}, want: true},
+		{args: []string{"-desktop", "never"}, want: false},
+		{args: nil, want: interactive()},
+		{args: []string{"-desktop", "sometimes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		fset := flag.NewFlagSet("test", flag.ContinueOnError)
+		resolve := registerDesktopFlag(fset, cfg)
+		if err := fset.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		got, err := resolve()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v: error = %v, wantErr %v", tt.args, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("%v: desktop = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
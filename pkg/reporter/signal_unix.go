@@ -0,0 +1,54 @@
+//go:build !windows
+
+package reporter
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// signalCommand sends sig to the process group p leads, as every command
+// reporter runs does, so its children get it too. A process that does not
+// lead a group, such as one reporter attached to, is signalled alone.
+func signalCommand(p *os.Process, sig syscall.Signal) error {
+	if pgid, err := syscall.Getpgid(p.Pid); err == nil && pgid == p.Pid {
+		return syscall.Kill(-p.Pid, sig)
+	}
+	return p.Signal(sig)
+}
+
+// signalNames are the names signals are reported by.
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP: "SIGHUP", syscall.SIGINT: "SIGINT", syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGILL: "SIGILL", syscall.SIGTRAP: "SIGTRAP", syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS: "SIGBUS", syscall.SIGFPE: "SIGFPE", syscall.SIGKILL: "SIGKILL",
+	syscall.SIGUSR1: "SIGUSR1", syscall.SIGSEGV: "SIGSEGV", syscall.SIGUSR2: "SIGUSR2",
+	syscall.SIGPIPE: "SIGPIPE", syscall.SIGALRM: "SIGALRM", syscall.SIGTERM: "SIGTERM",
+	syscall.SIGXCPU: "SIGXCPU", syscall.SIGXFSZ: "SIGXFSZ", syscall.SIGSYS: "SIGSYS",
+}
+
+// processExists reports whether a process with the given pid is running,
+// whoever owns it.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// pollProcessExit waits for pid to exit by checking for it every interval,
+// for systems with no way to be told.
+func pollProcessExit(pid int, interval time.Duration) {
+	for processExists(pid) {
+		time.Sleep(interval)
+	}
+}
+
+// waitExitCode returns the exit code a shell would show for ws: the status
+// the process exited with, or 128 plus the signal that killed it.
+func waitExitCode(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
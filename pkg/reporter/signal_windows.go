@@ -0,0 +1,22 @@
+package reporter
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalNames are the names signals are reported by. Windows processes
+// do not die from signals, but a shell such as Git Bash reports them.
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP: "SIGHUP", syscall.SIGINT: "SIGINT", syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGILL: "SIGILL", syscall.SIGTRAP: "SIGTRAP", syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS: "SIGBUS", syscall.SIGFPE: "SIGFPE", syscall.SIGKILL: "SIGKILL",
+	syscall.SIGSEGV: "SIGSEGV", syscall.SIGPIPE: "SIGPIPE", syscall.SIGALRM: "SIGALRM",
+	syscall.SIGTERM: "SIGTERM",
+}
+
+// signalCommand stops p: Windows has no signals to send, so every sig
+// terminates it.
+func signalCommand(p *os.Process, sig syscall.Signal) error {
+	return p.Kill()
+}
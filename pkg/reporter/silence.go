@@ -0,0 +1,84 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// -success-every keeps recurring jobs, such as cron entries, from notifying
+// on every successful run: a success only notifies if the same job (see
+// fingerprint) has not had a success notification within the period.
+// Failures and recoveries always notify.
+
+const (
+	silenceFileName = "successes.json"
+	// silenceTTL is how long a job is remembered. Jobs may use different
+	// periods, so records are only dropped once older than any of them is
+	// likely to be.
+	silenceTTL = 31 * 24 * time.Hour
+)
+
+func silencePath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, silenceFileName)
+}
+
+func loadSilence(path string) (map[string]time.Time, error) {
+	records := map[string]time.Time{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		// A corrupt file only costs one extra notification.
+		return map[string]time.Time{}, nil
+	}
+	return records, nil
+}
+
+// successSilenced reports whether a success notification for the job fp
+// should be dropped because one was sent less than every ago. When it is not
+// dropped, now is recorded as the job's latest success notification. A
+// recovery is never dropped, but is recorded, so the successes that follow it
+// are silenced.
+func successSilenced(path, fp string, every time.Duration, recovery bool, now time.Time) (bool, error) {
+	if path == "" || every <= 0 {
+		return false, nil
+	}
+	records, err := loadSilence(path)
+	if err != nil {
+		return false, err
+	}
+	if last, ok := records[fp]; ok && !recovery && now.Sub(last) < every {
+		return true, nil
+	}
+	for k, last := range records {
+		if now.Sub(last) >= max(every, silenceTTL) {
+			delete(records, k)
+		}
+	}
+	records[fp] = now
+	data, err := json.Marshal(records)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return false, err
+	}
+	return false, os.Rename(tmp, path)
+}
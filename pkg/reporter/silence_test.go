@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSuccessSilenced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), silenceFileName)
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	if got, err := successSilenced(path, "cmd:a", 0, false, now); err != nil || got {
+		t.Fatalf("without a period = %v, %v; want false", got, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("without a period the state file was written (stat: %v)", err)
+	}
+
+	steps := []struct {
+		fp       string
+		recovery bool
+		after    time.Duration
+		want     bool
+	}{
+		{"cmd:a", false, 0, false},
+		{"cmd:b", false, time.Hour, false},
+		{"cmd:a", false, 2 * time.Hour, true},
+		{"cmd:a", true, 3 * time.Hour, false},
+		{"cmd:a", false, 25 * time.Hour, true}, // within a day of the recovery
+		{"cmd:a", false, 27 * time.Hour, false},
+		{"cmd:b", false, 27 * time.Hour, false},
+	}
+	for i, s := range steps {
+		got, err := successSilenced(path, s.fp, 24*time.Hour, s.recovery, now.Add(s.after))
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got != s.want {
+			t.Errorf("step %d: %s silenced = %v, want %v", i, s.fp, got, s.want)
+		}
+	}
+}
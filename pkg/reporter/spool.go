@@ -0,0 +1,235 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The spool keeps pushes that failed for a transient reason, such as a
+// laptop that is offline, after their retries ran out. Each is a file in
+// $XDG_STATE_HOME/reporter/spool holding the target and the notification; the
+// next reporter invocation, or `reporter flush`, sends them again in the
+// order they were queued.
+
+const (
+	spoolDirName = "spool"
+	// spoolTTL is how long a push is kept; news of a run finished days ago
+	// is not worth a late alert.
+	spoolTTL = 24 * time.Hour
+	// spoolClaimTTL is how long a push being resent stays claimed; after
+	// that, the process sending it is assumed to have died.
+	spoolClaimTTL = time.Minute
+)
+
+// Spool files are named "<queued unix nanoseconds>-<random>" plus one of
+// these suffixes, so sorting their names sorts them by age.
+const (
+	spoolSuffix   = ".json"
+	claimedSuffix = ".sending"
+)
+
+type spoolEntry struct {
+	Target       pushTarget   `json:"target"`
+	Notification notification `json:"notification"`
+	Queued       time.Time    `json:"queued"`
+}
+
+func spoolDir() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, spoolDirName)
+}
+
+// spoolPush queues n for target. The file holds the push token, like the
+// config it came from, so it is only readable by the user.
+func spoolPush(dir string, target pushTarget, n notification, now time.Time) error {
+	if dir == "" {
+		return errors.New("no state directory to queue it in")
+	}
+	data, err := json.Marshal(spoolEntry{Target: target, Notification: n, Queued: now})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	return writeSpoolFile(dir, now, data, 0o600)
+}
+
+// writeSpoolFile writes data to a new file in dir named for now, with perm.
+// It is written under a .tmp name and renamed into place, so anything
+// reading the directory never sees half a file.
+func writeSpoolFile(dir string, now time.Time, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%019d-*.tmp", now.UnixNano()))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), strings.TrimSuffix(f.Name(), ".tmp")+spoolSuffix)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// flushResult summarizes one pass over the spool.
+type flushResult struct {
+	Sent    int
+	Queued  int     // still waiting, for a later pass
+	Dropped []error // pushes given up on, and why
+}
+
+// flushSpool tries once to send every queued push, oldest first. It stops at
+// the first transient failure, which most likely means the network is still
+// down, leaving the rest for next time.
+func flushSpool(dir string, now time.Time) flushResult {
+	var res flushResult
+	if !pushCompiled || dir == "" {
+		return res
+	}
+	names := spoolNames(dir, now)
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		claimed := path + claimedSuffix
+		if os.Rename(path, claimed) != nil {
+			continue // another reporter is sending it
+		}
+		_ = os.Chtimes(claimed, now, now)
+
+		var e spoolEntry
+		data, err := os.ReadFile(claimed)
+		if err == nil {
+			err = json.Unmarshal(data, &e)
+		}
+		switch {
+		case err != nil:
+			res.Dropped = append(res.Dropped, fmt.Errorf("unreadable queued push %s: %w", name, err))
+		case now.Sub(e.Queued) >= spoolTTL:
+			res.Dropped = append(res.Dropped, fmt.Errorf("%s push queued at %s: expired", e.Target.Provider, e.Queued.Format(time.DateTime)))
+		default:
+			err = pushOnce(e.Target, delayed(e.Notification, now))
+			if err != nil && pushRetryable(err) {
+				os.Rename(claimed, path)
+				res.Queued = len(names) - i
+				return res
+			}
+			if err != nil {
+				res.Dropped = append(res.Dropped, fmt.Errorf("%s: %w", e.Target.Provider, err))
+			} else {
+				res.Sent++
+			}
+		}
+		os.Remove(claimed)
+	}
+	return res
+}
+
+// spoolNames lists the queued pushes in dir, oldest first, first releasing
+// claims left behind by a reporter that died while sending.
+func spoolNames(dir string, now time.Time) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, ent := range entries {
+		name := ent.Name()
+		if base, ok := strings.CutSuffix(name, claimedSuffix); ok {
+			if info, err := ent.Info(); err == nil && now.Sub(info.ModTime()) >= spoolClaimTTL &&
+				os.Rename(filepath.Join(dir, name), filepath.Join(dir, base)) == nil {
+				names = append(names, base)
+			}
+			continue
+		}
+		if strings.HasSuffix(name, spoolSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// countSpool returns how many pushes are queued in dir.
+func countSpool(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, ent := range entries {
+		if strings.HasSuffix(ent.Name(), spoolSuffix) || strings.HasSuffix(ent.Name(), claimedSuffix) {
+			n++
+		}
+	}
+	return n
+}
+
+// delayed notes in n's body when the run finished, if that was a while ago.
+func delayed(n notification, now time.Time) notification {
+	if n.Finished.IsZero() || now.Sub(n.Finished) < time.Minute {
+		return n
+	}
+	n.Body += fmt.Sprintf(" (delayed; finished %s)", clockTime(n.Finished, now))
+	return n
+}
+
+// flushQueued resends spooled pushes ahead of a new notification, reporting
+// on stderr unless quiet.
+func flushQueued(quiet bool) {
+	res := flushSpool(spoolDir(), time.Now())
+	if quiet {
+		return
+	}
+	for _, err := range res.Dropped {
+		fmt.Fprintf(os.Stderr, "[push] dropped queued push: %v\n", err)
+	}
+	if res.Sent > 0 {
+		fmt.Fprintf(os.Stderr, "[push] resent %s\n", plural(res.Sent, "queued notification"))
+	}
+}
+
+// runFlush implements `reporter flush`.
+func runFlush(args []string) int {
+	fset := flag.NewFlagSet("flush", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter flush")
+		fmt.Fprintln(fset.Output(), "Resend push notifications queued while their destination was unreachable.")
+	}
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	if !pushCompiled {
+		fmt.Fprintln(os.Stderr, "flush: push support is not compiled into this build (built with -tags nopush)")
+		return 1
+	}
+	res := flushSpool(spoolDir(), time.Now())
+	for _, err := range res.Dropped {
+		fmt.Fprintf(os.Stderr, "flush: dropped %v\n", err)
+	}
+	fmt.Printf("sent %d, %d still queued\n", res.Sent, res.Queued)
+	if res.Queued > 0 {
+		return 1
+	}
+	return 0
+}
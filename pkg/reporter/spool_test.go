@@ -0,0 +1,122 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFlushSpool(t *testing.T) {
+	var bodies []string
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if status == http.StatusOK {
+			bodies = append(bodies, string(b))
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), spoolDirName)
+	now := time.Date(2026, 3, 4, 13, 0, 0, 0, time.Local)
+	target := pushTarget{URL: srv.URL, Provider: pushProviderPlain}
+	for i, title := range []string{"first", "second", "third"} {
+		n := notification{Title: title, Body: "succeeded in 2h", Finished: now}
+		if err := spoolPush(dir, target, n, now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("spool directory mode = %v, %v; want private to the user", info, err)
+	}
+
+	res := flushSpool(dir, now)
+	if res.Sent != 0 || res.Queued != 3 || len(res.Dropped) != 0 {
+		t.Errorf("flush while the server is down = %+v, want all 3 still queued", res)
+	}
+
+	status = http.StatusOK
+	res = flushSpool(dir, now.Add(time.Hour))
+	if res.Sent != 3 || res.Queued != 0 {
+		t.Errorf("flush once the server is up = %+v, want all 3 sent", res)
+	}
+	delay := " (delayed; finished 13:00)"
+	want := []string{
+		"first — succeeded in 2h" + delay + "\n",
+		"second — succeeded in 2h" + delay + "\n",
+		"third — succeeded in 2h" + delay + "\n",
+	}
+	if !reflect.DeepEqual(bodies, want) {
+		t.Errorf("resent bodies = %q, want %q in the order queued", bodies, want)
+	}
+	if n := countSpool(dir); n != 0 {
+		t.Errorf("%d pushes left in the spool, want none", n)
+	}
+
+	// Expired pushes are dropped unsent.
+	if err := spoolPush(dir, target, notification{Title: "old"}, now); err != nil {
+		t.Fatal(err)
+	}
+	bodies = nil
+	res = flushSpool(dir, now.Add(spoolTTL))
+	if res.Sent != 0 || len(res.Dropped) != 1 || len(bodies) != 0 {
+		t.Errorf("flush of an expired push = %+v, sent %q; want it dropped", res, bodies)
+	}
+}
+
+func TestFlushSpoolClaims(t *testing.T) {
+	var sent int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { sent++ }))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), spoolDirName)
+	now := time.Now()
+	if err := spoolPush(dir, pushTarget{URL: srv.URL, Provider: pushProviderPlain}, notification{Title: "x"}, now); err != nil {
+		t.Fatal(err)
+	}
+	// Another reporter is sending it.
+	names, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSuffix))
+	claimed := names[0] + claimedSuffix
+	if err := os.Rename(names[0], claimed); err != nil {
+		t.Fatal(err)
+	}
+	if res := flushSpool(dir, now); res.Sent != 0 || sent != 0 {
+		t.Errorf("flush sent a push claimed by another reporter: %+v", res)
+	}
+	// That reporter died.
+	old := now.Add(-2 * spoolClaimTTL)
+	if err := os.Chtimes(claimed, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if res := flushSpool(dir, now); res.Sent != 1 || sent != 1 {
+		t.Errorf("flush of an abandoned claim = %+v, want it sent", res)
+	}
+}
+
+func TestDelayed(t *testing.T) {
+	finished := time.Date(2026, 3, 4, 15, 4, 0, 0, time.Local)
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{finished.Add(30 * time.Second), "succeeded in 2h"},
+		{finished.Add(time.Hour), "succeeded in 2h (delayed; finished 15:04)"},
+		{finished.Add(24 * time.Hour), "succeeded in 2h (delayed; finished Wed 15:04)"},
+	}
+	for _, tt := range tests {
+		if got := delayed(notification{Body: "succeeded in 2h", Finished: finished}, tt.now).Body; got != tt.want {
+			t.Errorf("delayed at %s = %q, want %q", tt.now, got, tt.want)
+		}
+	}
+	if got := delayed(notification{Body: "digest"}, finished).Body; got != "digest" {
+		t.Errorf("delayed without a finish time = %q, want it unchanged", got)
+	}
+}
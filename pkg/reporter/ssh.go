@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// `reporter ssh <host>` runs ssh with a relay back to this desktop: a host
+// agent listening on a private socket here, reverse-forwarded to a socket on
+// the remote machine with ssh -R. The remote socket's path travels in
+// LC_REPORTER_AGENT, which sshd accepts along with the locale variables on
+// most systems, and reporters in the session send their desktop
+// notifications there (see hostAgent), over the agent's socket protocol.
+// Remote builds then notify this desktop without any push service; the
+// relay lasts as long as the ssh connection.
+
+// sshRelayEnv carries the remote socket to reporters on the other side.
+const sshRelayEnv = "LC_REPORTER_AGENT"
+
+// sshRelayArgs returns the arguments to ssh that forward remote to the local
+// socket and send its path along, followed by the user's own.
+func sshRelayArgs(remote, local string, args []string) []string {
+	return append([]string{"-R", remote + ":" + local, "-o", "SendEnv=" + sshRelayEnv}, args...)
+}
+
+// sshRelaySocket returns a fresh path for the remote end of the relay. /tmp
+// is shared, so the name is random; sshd creates the socket readable only
+// by the user.
+func sshRelaySocket() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "/tmp/reporter-" + hex.EncodeToString(b) + ".sock", nil
+}
+
+// runSSH implements `reporter ssh`, passing every argument on to ssh.
+func runSSH(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprintln(os.Stderr, "Usage: reporter ssh [ssh options] <host> [command]")
+		return 2
+	}
+	dir, err := os.MkdirTemp("", "reporter-ssh")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ssh: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, "agent.sock")
+	ln, err := listenAgent(local)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ssh: %v\n", err)
+		return 1
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					fmt.Fprintf(os.Stderr, "[relay] %v\n", err)
+				}
+				return
+			}
+			go serveAgentConn(conn, notifyDesktop)
+		}
+	}()
+
+	remote, err := sshRelaySocket()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ssh: %v\n", err)
+		return 1
+	}
+	cmd := exec.Command("ssh", sshRelayArgs(remote, local, args)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), sshRelayEnv+"="+remote)
+	// ssh, in reporter's process group, gets Ctrl-C and Ctrl-\ from the
+	// terminal too and handles them itself; reporter only has to outlive
+	// it. They are caught, not ignored, so ssh does not inherit that.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGQUIT)
+	defer signal.Stop(sigs)
+	exitCode, _, err := exitStatus(cmd.Run())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ssh: %v\n", err)
+		return 255
+	}
+	return exitCode
+}
@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSSHRelayArgs(t *testing.T) {
+	got := sshRelayArgs("/tmp/reporter-ab.sock", "/tmp/reporter-ssh1/agent.sock", []string{"-p", "2222", "build-01", "make"})
+	want := []string{"-R", "/tmp/reporter-ab.sock:/tmp/reporter-ssh1/agent.sock", "-o", "SendEnv=LC_REPORTER_AGENT", "-p", "2222", "build-01", "make"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sshRelayArgs() = %q, want %q", got, want)
+	}
+	a, err := sshRelaySocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := sshRelaySocket()
+	if a == b || !strings.HasPrefix(a, "/tmp/reporter-") || !strings.HasSuffix(a, ".sock") {
+		t.Errorf("sshRelaySocket() = %q, then %q", a, b)
+	}
+}
+
+func TestHostAgentSSHRelay(t *testing.T) {
+	cfg := &config{values: map[string]any{"agent": "127.0.0.1:8765"}}
+	t.Setenv("REPORTER_AGENT", "")
+	t.Setenv(sshRelayEnv, "")
+	if got := hostAgent(cfg); got != "127.0.0.1:8765" {
+		t.Errorf("hostAgent() = %q, want the config's", got)
+	}
+	t.Setenv(sshRelayEnv, "/tmp/reporter-ab.sock")
+	if got := hostAgent(cfg); got != "/tmp/reporter-ab.sock" {
+		t.Errorf("hostAgent() in a reporter ssh session = %q, want the relay", got)
+	}
+	t.Setenv("REPORTER_AGENT", "/run/user/1000/reporter/agent.sock")
+	if got := hostAgent(cfg); got != "/run/user/1000/reporter/agent.sock" {
+		t.Errorf("hostAgent() with REPORTER_AGENT = %q, want it", got)
+	}
+	cfg.locked = map[string]bool{"agent": true}
+	if got := hostAgent(cfg); got != "127.0.0.1:8765" {
+		t.Errorf("hostAgent() with agent locked = %q, want the config's", got)
+	}
+}
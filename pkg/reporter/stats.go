@@ -0,0 +1,182 @@
+package reporter
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyStats aggregates history entries for `reporter stats`.
+type historyStats struct {
+	Since     time.Time      `json:"since"`
+	Runs      int            `json:"runs"`
+	Failures  int            `json:"failures"`
+	TotalMS   int64          `json:"total_ms"`
+	Slowest   []historyEntry `json:"slowest"`
+	ByCommand []commandStats `json:"by_command"`
+}
+
+type commandStats struct {
+	Command  string `json:"command"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+	AvgMS    int64  `json:"avg_ms"`
+	MaxMS    int64  `json:"max_ms"`
+	TotalMS  int64  `json:"total_ms"`
+}
+
+func (c commandStats) failureRate() float64 { return float64(c.Failures) / float64(c.Runs) }
+
+// startOfWeek returns midnight on the Monday of t's week, in t's location.
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.Date()
+	return time.Date(y, m, d-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// computeStats summarizes entries, keeping the top slowest runs and the top
+// commands by total time spent.
+func computeStats(entries []historyEntry, since time.Time, top int) historyStats {
+	s := historyStats{Since: since}
+	byCommand := map[string]*commandStats{}
+	for _, e := range entries {
+		s.Runs++
+		s.TotalMS += e.DurationMS
+		cmd := strings.Join(strings.Fields(e.Command), " ")
+		c := byCommand[cmd]
+		if c == nil {
+			c = &commandStats{Command: cmd}
+			byCommand[cmd] = c
+		}
+		c.Runs++
+		c.TotalMS += e.DurationMS
+		c.MaxMS = max(c.MaxMS, e.DurationMS)
+		if e.ExitCode != 0 {
+			s.Failures++
+			c.Failures++
+		}
+	}
+
+	s.Slowest = append([]historyEntry(nil), entries...)
+	sort.SliceStable(s.Slowest, func(i, j int) bool { return s.Slowest[i].DurationMS > s.Slowest[j].DurationMS })
+	if len(s.Slowest) > top {
+		s.Slowest = s.Slowest[:top]
+	}
+
+	s.ByCommand = make([]commandStats, 0, len(byCommand))
+	for _, c := range byCommand {
+		c.AvgMS = c.TotalMS / int64(c.Runs)
+		s.ByCommand = append(s.ByCommand, *c)
+	}
+	sort.Slice(s.ByCommand, func(i, j int) bool {
+		if s.ByCommand[i].TotalMS != s.ByCommand[j].TotalMS {
+			return s.ByCommand[i].TotalMS > s.ByCommand[j].TotalMS
+		}
+		return s.ByCommand[i].Command < s.ByCommand[j].Command
+	})
+	if len(s.ByCommand) > top {
+		s.ByCommand = s.ByCommand[:top]
+	}
+	return s
+}
+
+// runStats implements `reporter stats`.
+func runStats(args []string) int {
+	fset := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter stats [flags]")
+		fset.PrintDefaults()
+	}
+	since := fset.String("since", "", "only count runs started at or after this time (e.g. 24h, 2006-01-02, or RFC 3339; default: start of this week)")
+	command := fset.String("command", "", "only count runs whose command contains this text (case-insensitive)")
+	labels := labelFlag{}
+	fset.Var(labels, "label", "only count runs with this `key=value` label (repeatable)")
+	where := fset.String("where", "", "only count runs matching this expression, e.g. 'label.project == \"atlas\"'")
+	top := fset.Int("n", 10, "how many slowest runs and commands to list")
+	asJSON := fset.Bool("json", false, "print the statistics as JSON")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 || *top < 1 {
+		fset.Usage()
+		return 2
+	}
+
+	now := time.Now()
+	filter := historyFilter{command: *command, labels: labels, since: startOfWeek(now)}
+	if *since != "" {
+		t, err := parseTimeBound(*since, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-since: %v\n", err)
+			return 2
+		}
+		filter.since = t
+	}
+	if *where != "" {
+		expr, err := compileWhere(*where, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-where: %v\n", err)
+			return 2
+		}
+		filter.where = expr
+	}
+
+	entries, ok := readHistory()
+	if !ok {
+		return 1
+	}
+	var matched []historyEntry
+	for _, e := range entries {
+		if filter.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	stats := computeStats(matched, filter.since, *top)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			return 1
+		}
+		return 0
+	}
+	printStats(os.Stdout, stats)
+	return 0
+}
+
+func printStats(w io.Writer, s historyStats) {
+	fmt.Fprintf(w, "Since %s\n", s.Since.Local().Format("Mon 2006-01-02 15:04"))
+	if s.Runs == 0 {
+		fmt.Fprintln(w, "  no runs recorded")
+		return
+	}
+	fmt.Fprintf(w, "  %d runs, %d failed (%.0f%%), %s spent waiting\n",
+		s.Runs, s.Failures, 100*float64(s.Failures)/float64(s.Runs), formatDuration(ms(s.TotalMS)))
+
+	fmt.Fprintln(w, "\nSlowest runs")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  DURATION\tEXIT\tSTARTED\tCOMMAND")
+	for _, e := range s.Slowest {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\n", formatDuration(e.duration()), e.ExitCode,
+			e.Time.Local().Format("Mon 15:04"), oneLine(e.Command))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nTime by command")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  TOTAL\tRUNS\tAVG\tMAX\tFAILED\tCOMMAND")
+	for _, c := range s.ByCommand {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\t%.0f%%\t%s\n", formatDuration(ms(c.TotalMS)), c.Runs,
+			formatDuration(ms(c.AvgMS)), formatDuration(ms(c.MaxMS)), 100*c.failureRate(), oneLine(c.Command))
+	}
+	tw.Flush()
+}
+
+func ms(n int64) time.Duration { return time.Duration(n) * time.Millisecond }
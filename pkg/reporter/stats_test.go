@@ -0,0 +1,55 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		{t: time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC), want: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},  // Wednesday
+		{t: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), want: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},    // Monday
+		{t: time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC), want: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},  // Sunday
+		{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), want: time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC)}, // across a year
+	}
+	for _, tt := range tests {
+		if got := startOfWeek(tt.t); !got.Equal(tt.want) {
+			t.Errorf("startOfWeek(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	entries := []historyEntry{
+		{Command: "make test", DurationMS: 30000, ExitCode: 0},
+		{Command: "make  test", DurationMS: 50000, ExitCode: 2},
+		{Command: "cargo build", DurationMS: 200000, ExitCode: 0},
+		{Command: "ls", DurationMS: 10, ExitCode: 0},
+	}
+	s := computeStats(entries, time.Time{}, 2)
+
+	if s.Runs != 4 || s.Failures != 1 || s.TotalMS != 280010 {
+		t.Errorf("totals = %d runs, %d failures, %d ms; want 4, 1, 280010", s.Runs, s.Failures, s.TotalMS)
+	}
+	if len(s.Slowest) != 2 || s.Slowest[0].Command != "cargo build" || s.Slowest[1].Command != "make  test" {
+		t.Errorf("Slowest = %+v, want cargo build then the 50s make test", s.Slowest)
+	}
+	want := []commandStats{
+		{Command: "cargo build", Runs: 1, AvgMS: 200000, MaxMS: 200000, TotalMS: 200000},
+		{Command: "make test", Runs: 2, Failures: 1, AvgMS: 40000, MaxMS: 50000, TotalMS: 80000},
+	}
+	if len(s.ByCommand) != len(want) {
+		t.Fatalf("ByCommand = %+v, want %+v", s.ByCommand, want)
+	}
+	for i := range want {
+		if s.ByCommand[i] != want[i] {
+			t.Errorf("ByCommand[%d] = %+v, want %+v", i, s.ByCommand[i], want[i])
+		}
+	}
+	if got := s.ByCommand[1].failureRate(); got != 0.5 {
+		t.Errorf("failureRate() = %v, want 0.5", got)
+	}
+}
@@ -0,0 +1,112 @@
+package reporter
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The statsd config key sends two metrics for every run reporter records to a
+// statsd or DogStatsD server, so runs can be graphed without other plumbing:
+//
+//	reporter.run.duration:184000|ms|#status:failed,exit_code:2,command:make,env:prod
+//	reporter.run.completed:1|c|#status:failed,exit_code:2,command:make,env:prod
+//
+// The tags are the outcome, the command's program name, and the run's
+// labels. Plain statsd has no tags, so with statsd_tags = false the status
+// goes into the metric names instead, as in reporter.run.failed.duration.
+
+// statsdTimeout bounds resolving and connecting to the server.
+const statsdTimeout = time.Second
+
+// statsdSink sends run metrics to one server.
+type statsdSink struct {
+	network, addr string
+	prefix        string
+	tags          bool
+}
+
+// newStatsdSink returns the sink for addr, a host:port for UDP or
+// unix:///path for a Unix datagram socket, or nil if addr is "".
+func newStatsdSink(addr, prefix string, tags bool) (*statsdSink, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	s := &statsdSink{network: "udp", addr: addr, prefix: prefix, tags: tags}
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		s.network, s.addr = "unixgram", path
+	} else if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid statsd address %q: want host:port or unix:///path", addr)
+	}
+	if s.prefix != "" && !strings.HasSuffix(s.prefix, ".") {
+		s.prefix += "."
+	}
+	return s, nil
+}
+
+// lines renders the metrics for res.
+func (s *statsdSink) lines(res runResult) []string {
+	status := "succeeded"
+	if res.ExitCode != 0 {
+		status = "failed"
+	}
+	ms := res.Duration.Milliseconds()
+	if !s.tags {
+		name := s.prefix + "run." + status
+		return []string{fmt.Sprintf("%s.duration:%d|ms", name, ms), name + ".completed:1|c"}
+	}
+	tags := []string{"status:" + status, fmt.Sprintf("exit_code:%d", res.ExitCode)}
+	if program := programName(res); program != "" {
+		tags = append(tags, "command:"+statsdTag(program))
+	}
+	keys := make([]string, 0, len(res.Labels))
+	for k := range res.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags = append(tags, statsdTag(k)+":"+statsdTag(res.Labels[k]))
+	}
+	suffix := "|#" + strings.Join(tags, ",")
+	return []string{
+		fmt.Sprintf("%srun.duration:%d|ms%s", s.prefix, ms, suffix),
+		s.prefix + "run.completed:1|c" + suffix,
+	}
+}
+
+// record sends the metrics for res in one datagram.
+func (s *statsdSink) record(res runResult) error {
+	conn, err := net.DialTimeout(s.network, s.addr, statsdTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(strings.Join(s.lines(res), "\n")))
+	return err
+}
+
+// programName returns the base name of the program res ran, which keeps the
+// command tag to a handful of values, unlike the full command line.
+func programName(res runResult) string {
+	if len(res.Args) > 0 {
+		return filepath.Base(res.Args[0])
+	}
+	if fields := strings.Fields(res.Command); len(fields) > 0 {
+		return filepath.Base(fields[0])
+	}
+	return ""
+}
+
+// statsdTag replaces the characters that delimit DogStatsD tags.
+func statsdTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ',' || r == '|' || r == '#' || r == ':' || isControl(r):
+			return '_'
+		}
+		return r
+	}, s)
+}
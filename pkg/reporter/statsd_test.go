@@ -0,0 +1,68 @@
+package reporter
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatsdLines(t *testing.T) {
+	res := runResult{
+		Command: "make test", Args: []string{"/usr/bin/make", "test"}, Duration: 184 * time.Second, ExitCode: 2,
+		Labels: map[string]string{"env": "prod", "team": "data,infra"},
+	}
+	tagged, _ := newStatsdSink("127.0.0.1:8125", "ci", true)
+	want := []string{
+		"ci.run.duration:184000|ms|#status:failed,exit_code:2,command:make,env:prod,team:data_infra",
+		"ci.run.completed:1|c|#status:failed,exit_code:2,command:make,env:prod,team:data_infra",
+	}
+	if got := tagged.lines(res); !reflect.DeepEqual(got, want) {
+		t.Errorf("lines() =\n%q\nwant\n%q", got, want)
+	}
+
+	plain, _ := newStatsdSink("127.0.0.1:8125", "reporter.", false)
+	res.ExitCode = 0
+	want = []string{"reporter.run.succeeded.duration:184000|ms", "reporter.run.succeeded.completed:1|c"}
+	if got := plain.lines(res); !reflect.DeepEqual(got, want) {
+		t.Errorf("lines() without tags = %q, want %q", got, want)
+	}
+}
+
+func TestNewStatsdSink(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8125":                     true,
+		"statsd.internal:8125":               true,
+		"unix:///var/run/datadog/dsd.socket": true,
+		"localhost":                          false,
+	} {
+		if _, err := newStatsdSink(addr, "", true); (err == nil) != ok {
+			t.Errorf("newStatsdSink(%q) error = %v, want ok %v", addr, err, ok)
+		}
+	}
+	if s, err := newStatsdSink("", "reporter", true); s != nil || err != nil {
+		t.Errorf("newStatsdSink(\"\") = %v, %v; want nil", s, err)
+	}
+}
+
+func TestStatsdRecord(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP: %v", err)
+	}
+	defer conn.Close()
+	s, _ := newStatsdSink(conn.LocalAddr().String(), "reporter", true)
+	if err := s.record(runResult{Command: "sleep 1", Duration: time.Second}); err != nil {
+		t.Fatalf("record() returned error: %v", err)
+	}
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "reporter.run.duration:1000|ms|#status:succeeded,exit_code:0,command:sleep\nreporter.run.completed:1|c|#status:succeeded,exit_code:0,command:sleep"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("datagram = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status describes how a command ended, given its exit code and the name of
+// the signal that killed it, if any: "succeeded", "failed (exit 2)", or
+// "killed by SIGKILL (OOM?)". A command the kernel kills for running out of
+// memory gets SIGKILL, which little else sends.
+func Status(exitCode int, signal string) string {
+	switch {
+	case signal == "SIGINT":
+		return "interrupted (SIGINT)"
+	case signal == "SIGKILL":
+		return "killed by SIGKILL (OOM?)"
+	case signal != "":
+		return "killed by " + signal
+	case exitCode != 0:
+		return fmt.Sprintf("failed (exit %d)", exitCode)
+	}
+	return "succeeded"
+}
+
+// FormatDuration formats d the way notifications show it: 850ms, 42s,
+// 12m03s, or 2h05m00s.
+func FormatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	// Keep it human-friendly while avoiding allocations from String().
+	seconds := int(d.Round(time.Second).Seconds())
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
+	secs := seconds % 60
+
+	switch {
+	case hours > 0:
+		return fmt.Sprintf("%dh%02dm%02ds", hours, minutes, secs)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%02ds", minutes, secs)
+	default:
+		return fmt.Sprintf("%ds", secs)
+	}
+}
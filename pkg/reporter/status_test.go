@@ -0,0 +1,21 @@
+package reporter
+
+import "testing"
+
+func TestStatus(t *testing.T) {
+	for _, tt := range []struct {
+		exitCode int
+		signal   string
+		want     string
+	}{
+		{exitCode: 0, want: "succeeded"},
+		{exitCode: 2, want: "failed (exit 2)"},
+		{exitCode: 130, signal: "SIGINT", want: "interrupted (SIGINT)"},
+		{exitCode: 137, signal: "SIGKILL", want: "killed by SIGKILL (OOM?)"},
+		{exitCode: 143, signal: "SIGTERM", want: "killed by SIGTERM"},
+	} {
+		if got := Status(tt.exitCode, tt.signal); got != tt.want {
+			t.Errorf("Status(%d, %q) = %q, want %q", tt.exitCode, tt.signal, got, tt.want)
+		}
+	}
+}
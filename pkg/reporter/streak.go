@@ -0,0 +1,118 @@
+package reporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A failure streak counts the consecutive failed runs of one job, so the
+// first success after them can be reported as a recovery. A job is identified
+// by its fingerprint: the dedup key when there is one, else the command and
+// the directory it ran in. Only jobs that are currently failing are stored,
+// so the file stays small however many commands succeed.
+
+const (
+	streakFileName = "streaks.json"
+	// streakTTL forgets a job that has not failed for a week; a success
+	// after that is not news.
+	streakTTL = 7 * 24 * time.Hour
+)
+
+type streakRecord struct {
+	Failures int       `json:"failures"`
+	Last     time.Time `json:"last"` // when the latest failure was recorded
+}
+
+func streakPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, streakFileName)
+}
+
+// fingerprint identifies the job res belongs to. The command is hashed so the
+// state file does not collect command lines, which can contain secrets.
+func fingerprint(res runResult, dedupKey, dir string) string {
+	if dedupKey != "" {
+		return "key:" + dedupKey
+	}
+	sum := sha256.Sum256([]byte(res.Command + "\x00" + dir))
+	return "cmd:" + hex.EncodeToString(sum[:12])
+}
+
+func loadStreaks(path string) (map[string]streakRecord, error) {
+	records := map[string]streakRecord{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		// A corrupt file only costs one missed recovery.
+		return map[string]streakRecord{}, nil
+	}
+	return records, nil
+}
+
+// updateStreak records the outcome of a run of the job fp and returns how many
+// failed runs in a row a successful one recovers from, or 0.
+func updateStreak(path, fp string, exitCode int, now time.Time) (recovered int, err error) {
+	if path == "" {
+		return 0, nil
+	}
+	records, err := loadStreaks(path)
+	if err != nil {
+		return 0, err
+	}
+	r, found := records[fp]
+	if exitCode == 0 && !found {
+		// The common case: nothing to record, so no write.
+		return 0, nil
+	}
+	failing := found && now.Sub(r.Last) < streakTTL
+
+	for k, old := range records {
+		if now.Sub(old.Last) >= streakTTL {
+			delete(records, k)
+		}
+	}
+	if exitCode == 0 {
+		if failing {
+			recovered = r.Failures
+		}
+		delete(records, fp)
+	} else {
+		if !failing {
+			r = streakRecord{}
+		}
+		records[fp] = streakRecord{Failures: r.Failures + 1, Last: now}
+	}
+	return recovered, writeStreaks(path, records)
+}
+
+func writeStreaks(path string, records map[string]streakRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Concurrent shells each write their own temporary file; the last
+	// rename wins, which at worst loses one run's update.
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,67 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateStreak(t *testing.T) {
+	path := filepath.Join(t.TempDir(), streakFileName)
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	if got, err := updateStreak(path, "cmd:a", 0, now); err != nil || got != 0 {
+		t.Fatalf("success without a streak = %d, %v; want 0", got, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a success without a streak wrote the state file (stat: %v)", err)
+	}
+
+	steps := []struct {
+		fp       string
+		exitCode int
+		after    time.Duration
+		want     int
+	}{
+		{"cmd:a", 1, 0, 0},
+		{"cmd:b", 2, time.Minute, 0},
+		{"cmd:a", 1, 2 * time.Minute, 0},
+		{"cmd:a", 0, 3 * time.Minute, 2},
+		{"cmd:a", 0, 4 * time.Minute, 0},
+		{"cmd:b", 0, 5 * time.Minute, 1},
+		// A streak older than streakTTL is forgotten.
+		{"cmd:c", 1, 0, 0},
+		{"cmd:c", 0, streakTTL, 0},
+	}
+	for i, s := range steps {
+		got, err := updateStreak(path, s.fp, s.exitCode, now.Add(s.after))
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got != s.want {
+			t.Errorf("step %d: %s exit %d recovered = %d, want %d", i, s.fp, s.exitCode, got, s.want)
+		}
+	}
+	records, err := loadStreaks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("records left after every job recovered or expired: %v", records)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	res := runResult{Command: "make test"}
+	if got := fingerprint(res, "nightly", "/src"); got != "key:nightly" {
+		t.Errorf("fingerprint with a dedup key = %q, want key:nightly", got)
+	}
+	a, b := fingerprint(res, "", "/src/a"), fingerprint(res, "", "/src/b")
+	if a == b {
+		t.Errorf("the same command in different directories shares fingerprint %q", a)
+	}
+	if a != fingerprint(runResult{Command: "make test", ExitCode: 2}, "", "/src/a") {
+		t.Error("fingerprint depends on the outcome")
+	}
+}
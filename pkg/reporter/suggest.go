@@ -0,0 +1,346 @@
+package reporter
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// `reporter suggest-thresholds` looks through the history for commands that
+// notify all the time and proposes config to quiet them: interactive programs,
+// whose run times are all over the place, go on the notify_deny list, and
+// short commands that just clear the threshold get a [[command_threshold]]
+// table a little above their usual run time. Long jobs are left alone, since
+// those are what notifications are for.
+
+const (
+	// suggestSpread is how many times longer the slower runs of a command
+	// (90th percentile) must take than the quicker ones (10th percentile)
+	// for it to look interactive, as an editor left open does.
+	suggestSpread = 10
+	// suggestShortRun is the run time under which a command is not worth
+	// walking away from, so notifying about it every time is noise.
+	suggestShortRun = 2 * time.Minute
+)
+
+// thresholdSuggestion is a proposed change for one command shape (see
+// commandShape).
+type thresholdSuggestion struct {
+	command   string // the command shape, such as "git pull"
+	pattern   string // a pattern matching it, for notify_deny or match
+	notified  int    // how many runs notified
+	low, high time.Duration
+	// ignore proposes adding pattern to notify_deny; otherwise threshold
+	// is proposed as a [[command_threshold]].
+	ignore    bool
+	threshold time.Duration
+}
+
+func (s thresholdSuggestion) String() string {
+	if s.ignore {
+		return fmt.Sprintf("%s triggered %s, taking %s to %s; suggest ignoring it",
+			s.command, plural(s.notified, "notification"), formatDuration(s.low), formatDuration(s.high))
+	}
+	return fmt.Sprintf("%s triggered %s, usually taking %s to %s; suggest a %s threshold",
+		s.command, plural(s.notified, "notification"), formatDuration(s.low), formatDuration(s.high), formatDuration(s.threshold))
+}
+
+// suggestThresholds groups entries by command shape and proposes a change
+// for each shape with at least minNotified runs that notified, most
+// notifications first. notifies says whether a run notified under the current
+// settings, and tuned whether a [[command_threshold]] already covers it;
+// shapes with tuned runs are left alone.
+func suggestThresholds(entries []historyEntry, notifies, tuned func(historyEntry) bool, minNotified int) []thresholdSuggestion {
+	type shapeRuns struct {
+		durations []time.Duration
+		failures  int
+		path      bool // a run named the program by its path
+		tuned     bool
+	}
+	byShape := map[string]*shapeRuns{}
+	var order []string
+	for _, e := range entries {
+		shape := commandShape(e.Command)
+		if shape == "" {
+			continue
+		}
+		s := byShape[shape]
+		if s == nil {
+			s = &shapeRuns{}
+			byShape[shape] = s
+			order = append(order, shape)
+		}
+		s.tuned = s.tuned || tuned(e)
+		if !notifies(e) {
+			continue
+		}
+		s.durations = append(s.durations, e.duration())
+		if e.ExitCode != 0 {
+			s.failures++
+		}
+		program := strings.Fields(e.Command)
+		for len(program) > 1 && strings.Contains(program[0], "=") {
+			program = program[1:]
+		}
+		s.path = s.path || strings.Contains(program[0], "/")
+	}
+
+	var suggestions []thresholdSuggestion
+	for _, shape := range order {
+		s := byShape[shape]
+		n := len(s.durations)
+		if n < minNotified {
+			continue
+		}
+		low, high := percentile(s.durations, 10), percentile(s.durations, 90)
+		sug := thresholdSuggestion{command: shape, pattern: shapePattern(shape, s.path), notified: n, low: low, high: high}
+		switch {
+		case high >= suggestSpread*max(low, time.Second) && s.failures*10 < n:
+			sug.ignore = true
+		case high < suggestShortRun && !s.tuned:
+			sug.threshold = roundUpThreshold(high + high/4)
+		default:
+			continue
+		}
+		suggestions = append(suggestions, sug)
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].notified > suggestions[j].notified })
+	return suggestions
+}
+
+// wordEnd matches text ending in a word character, after which \b marks the
+// end of the program or subcommand.
+var wordEnd = regexp.MustCompile(`\w$`)
+
+// shapePattern returns a pattern matching the command lines of a command
+// shape, allowing a path before the program if path is set.
+func shapePattern(shape string, path bool) string {
+	pattern := "^"
+	if path {
+		pattern += `(\S*/)?`
+	}
+	pattern += regexp.QuoteMeta(shape)
+	if wordEnd.MatchString(shape) {
+		return pattern + `\b`
+	}
+	return pattern + `(\s|$)`
+}
+
+// roundUpThreshold rounds d up to a threshold that reads well: to 5 seconds
+// under a minute, 30 seconds under ten minutes, and whole minutes above.
+func roundUpThreshold(d time.Duration) time.Duration {
+	step := time.Minute
+	switch {
+	case d <= time.Minute:
+		step = 5 * time.Second
+	case d <= 10*time.Minute:
+		step = 30 * time.Second
+	}
+	return (d + step - 1).Truncate(step)
+}
+
+// suggestionConfig renders suggestions as config: deny is the notify_deny
+// list in effect, to which the ignored commands are added.
+func suggestionConfig(suggestions []thresholdSuggestion, deny []string) (keys, tables string) {
+	patterns := make([]any, 0, len(deny)+len(suggestions))
+	for _, p := range deny {
+		patterns = append(patterns, p)
+	}
+	var b strings.Builder
+	for _, s := range suggestions {
+		if s.ignore {
+			patterns = append(patterns, s.pattern)
+			continue
+		}
+		fmt.Fprintf(&b, "\n[[%s]]\nmatch = %s\nthreshold = %s\n", commandThresholdTable,
+			formatConfigValue(s.pattern), formatConfigValue(s.threshold.String()))
+	}
+	if len(patterns) > len(deny) {
+		keys = "notify_deny = " + formatConfigValue(patterns) + "\n"
+	}
+	return keys, b.String()
+}
+
+// writeSuggestions adds suggestions to the config file at path. notify_deny
+// goes at the top, where keys outside any table belong, and the tables at
+// the end. A file that already sets notify_deny is left for the user to edit,
+// since its list cannot be rewritten in place.
+func writeSuggestions(path string, suggestions []thresholdSuggestion, deny []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if _, ok := values["notify_deny"]; ok {
+		for _, s := range suggestions {
+			if s.ignore {
+				return fmt.Errorf("%s already sets notify_deny; add %s to it there", shortenHome(path), formatConfigValue(s.pattern))
+			}
+		}
+	}
+	keys, tables := suggestionConfig(suggestions, deny)
+	content := keys + string(data)
+	switch {
+	case content == "":
+		tables = strings.TrimPrefix(tables, "\n")
+	case !strings.HasSuffix(content, "\n"):
+		content += "\n"
+	}
+	content += tables
+
+	// Check the result the way loadConfig will read it.
+	values, err = parseTOML(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for key, val := range values {
+		if err := checkConfigValue(key, val); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	mode := fs.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// confirm asks question on w and reports whether the answer read from r
+// was yes.
+func confirm(w io.Writer, r *bufio.Reader, question string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := r.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// runSuggestThresholds implements `reporter suggest-thresholds`.
+func runSuggestThresholds(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+
+	fset := flag.NewFlagSet("suggest-thresholds", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter suggest-thresholds [flags]")
+		fset.PrintDefaults()
+	}
+	since := fset.String("since", "720h", "only look at runs started at or after this time (e.g. 24h, 2006-01-02, or RFC 3339)")
+	thresholdStr := fset.String("threshold", getenvDefault("REPORTER_THRESHOLD", cfg.string("threshold", "10s")), "the threshold runs were notified at, if not the shell hook's or config's")
+	minNotified := fset.Int("min", 10, "only suggest changes for commands that notified at least this many times")
+	write := fset.Bool("write", false, "add the suggestions to the user config file, asking about each one")
+	yes := fset.Bool("yes", false, "with -write, add every suggestion without asking")
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	if fset.NArg() != 0 || *minNotified < 1 {
+		fset.Usage()
+		return 2
+	}
+	threshold := autoThresholdFloor
+	if *thresholdStr != thresholdAuto {
+		if threshold, err = time.ParseDuration(*thresholdStr); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid threshold: %v\n", err)
+			return 2
+		}
+	}
+	from, err := parseTimeBound(*since, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-since: %v\n", err)
+		return 2
+	}
+
+	entries, ok := readHistory()
+	if !ok {
+		return 1
+	}
+	filter := historyFilter{since: from}
+	var matched []historyEntry
+	for _, e := range entries {
+		if filter.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	allow, deny, rules := cfg.strings("notify_allow"), notifyDenyPatterns(cfg), configCommandThresholds(cfg)
+	notifies := func(e historyEntry) bool {
+		if muted, _ := hookMuted(e.Command, allow, deny); muted {
+			return false
+		}
+		t := threshold
+		if r, ok := matchCommandThreshold(rules, e.Command); ok {
+			t = r
+		}
+		return e.duration() >= t
+	}
+	tuned := func(e historyEntry) bool {
+		_, ok := matchCommandThreshold(rules, e.Command)
+		return ok
+	}
+	suggestions := suggestThresholds(matched, notifies, tuned, *minNotified)
+	if len(suggestions) == 0 {
+		fmt.Printf("No suggestions: no command notified %d or more times in %s since %s.\n",
+			*minNotified, plural(len(matched), "run"), from.Local().Format("2006-01-02"))
+		return 0
+	}
+	for _, s := range suggestions {
+		fmt.Println(s)
+	}
+
+	path := userConfigPath()
+	if !*write {
+		keys, tables := suggestionConfig(suggestions, deny)
+		fmt.Printf("\nTo apply them, add this to %s, or run with -write:\n\n%s%s", shortenHome(path), keys, strings.TrimPrefix(tables, "\n"))
+		return 0
+	}
+	if cfg.locked["notify_deny"] || cfg.locked[commandThresholdTable] {
+		fmt.Fprintf(os.Stderr, "notify_deny or %s is locked by %s\n", commandThresholdTable, systemConfigPath)
+		return 1
+	}
+	accepted := suggestions
+	if !*yes {
+		accepted = nil
+		fmt.Println()
+		in := bufio.NewReader(os.Stdin)
+		for _, s := range suggestions {
+			question := fmt.Sprintf("Stop notifying about %s?", s.command)
+			if !s.ignore {
+				question = fmt.Sprintf("Only notify about %s after %s?", s.command, formatDuration(s.threshold))
+			}
+			if confirm(os.Stdout, in, question) {
+				accepted = append(accepted, s)
+			}
+		}
+	}
+	if len(accepted) == 0 {
+		return 0
+	}
+	if err := writeSuggestions(path, accepted, deny); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Updated %s\n", shortenHome(path))
+	return 0
+}
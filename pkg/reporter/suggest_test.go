@@ -0,0 +1,159 @@
+package reporter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuggestThresholds(t *testing.T) {
+	var entries []historyEntry
+	add := func(command string, exitCode int, durations ...time.Duration) {
+		for _, d := range durations {
+			entries = append(entries, historyEntry{Command: command, ExitCode: exitCode, DurationMS: d.Milliseconds()})
+		}
+	}
+	// An editor left open for anything from a few seconds to hours.
+	for i := range 12 {
+		add("vi notes.md", 0, time.Duration(i*i+1)*time.Minute/4)
+	}
+	// A pull that always takes a little over the threshold.
+	for i := range 12 {
+		add("git pull --rebase", 0, 11*time.Second+time.Duration(i)*200*time.Millisecond)
+	}
+	// Builds worth hearing about, and a script run by path.
+	for i := range 12 {
+		add("cargo build --release", 0, 3*time.Minute+time.Duration(i)*time.Second)
+		add("./scripts/sync.sh prod", 0, 20*time.Second)
+	}
+	// Too few notifications to go by, and runs under the threshold.
+	add("make lint", 0, 30*time.Second, 31*time.Second)
+	add("ls", 0, time.Duration(20)*time.Millisecond)
+
+	notifies := func(e historyEntry) bool { return e.duration() >= 10*time.Second }
+	untuned := func(historyEntry) bool { return false }
+	got := suggestThresholds(entries, notifies, untuned, 10)
+
+	want := []thresholdSuggestion{
+		{command: "vi", pattern: `^vi\b`, ignore: true},
+		{command: "git pull", pattern: `^git pull\b`, threshold: 20 * time.Second},
+		{command: "sync.sh prod", pattern: `^(\S*/)?sync\.sh prod\b`, threshold: 25 * time.Second},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("suggestThresholds() = %+v, want %d suggestions", got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.command != w.command || g.pattern != w.pattern || g.ignore != w.ignore || g.threshold != w.threshold {
+			t.Errorf("suggestion %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if got[0].notified != 12 || got[0].low != 15*time.Second || got[0].high != 25*time.Minute+15*time.Second {
+		t.Errorf("vi notified %d times, taking %v to %v", got[0].notified, got[0].low, got[0].high)
+	}
+
+	tuned := func(e historyEntry) bool { return strings.HasPrefix(e.Command, "git") }
+	for _, s := range suggestThresholds(entries, notifies, tuned, 10) {
+		if s.command == "git pull" {
+			t.Errorf("suggested %+v for a command a [[command_threshold]] already covers", s)
+		}
+	}
+}
+
+func TestSuggestionString(t *testing.T) {
+	s := thresholdSuggestion{command: "vim", notified: 212, low: 15 * time.Second, high: 3 * time.Hour, ignore: true}
+	if got, want := s.String(), "vim triggered 212 notifications, taking 15s to 3h00m00s; suggest ignoring it"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestShapePattern(t *testing.T) {
+	for _, tt := range []struct {
+		shape string
+		path  bool
+		want  string
+	}{
+		{shape: "vim", want: `^vim\b`},
+		{shape: "git pull", want: `^git pull\b`},
+		{shape: "g++", want: `^g\+\+(\s|$)`},
+		{shape: "deploy.sh", path: true, want: `^(\S*/)?deploy\.sh\b`},
+	} {
+		if got := shapePattern(tt.shape, tt.path); got != tt.want {
+			t.Errorf("shapePattern(%q, %v) = %q, want %q", tt.shape, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRoundUpThreshold(t *testing.T) {
+	for _, tt := range []struct{ d, want time.Duration }{
+		{d: 17500 * time.Millisecond, want: 20 * time.Second},
+		{d: 20 * time.Second, want: 20 * time.Second},
+		{d: 95 * time.Second, want: 2 * time.Minute},
+		{d: 12*time.Minute + time.Second, want: 13 * time.Minute},
+	} {
+		if got := roundUpThreshold(tt.d); got != tt.want {
+			t.Errorf("roundUpThreshold(%v) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSuggestions(t *testing.T) {
+	isolateConfig(t)
+	path := userConfigPath()
+	writeFile(t, path, "threshold = \"15s\"\n\n[labels]\nteam = \"infra\"")
+	suggestions := []thresholdSuggestion{
+		{command: "vim", pattern: `^vim\b`, ignore: true},
+		{command: "git pull", pattern: `^git pull\b`, threshold: 20 * time.Second},
+	}
+	if err := writeSuggestions(path, suggestions, []string{`^less\b`}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("loadConfig() after writing suggestions: %v", err)
+	}
+	if got := cfg.strings("notify_deny"); strings.Join(got, " ") != `^less\b ^vim\b` {
+		t.Errorf("notify_deny = %q", got)
+	}
+	if got := cfg.labels()["team"]; got != "infra" {
+		t.Errorf("labels.team = %q, want the existing table kept", got)
+	}
+	if d, ok := matchCommandThreshold(configCommandThresholds(cfg), "git pull"); !ok || d != 20*time.Second {
+		t.Errorf("git pull threshold = %v, %v, want 20s", d, ok)
+	}
+
+	// The list is in the file now, so a second ignore cannot be merged in.
+	err = writeSuggestions(path, suggestions[:1], nil)
+	if err == nil || !strings.Contains(err.Error(), "already sets notify_deny") {
+		t.Errorf("writeSuggestions() over an existing notify_deny = %v", err)
+	}
+	if err := writeSuggestions(path, suggestions[1:], nil); err != nil {
+		t.Errorf("writeSuggestions() of a threshold alone = %v", err)
+	}
+
+	// Nothing is written when the file does not parse.
+	bad := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, bad, "threshold = \n")
+	if err := writeSuggestions(bad, suggestions, nil); err == nil {
+		t.Error("writeSuggestions() into an invalid file succeeded")
+	}
+	if data, _ := os.ReadFile(bad); string(data) != "threshold = \n" {
+		t.Errorf("invalid file was rewritten to %q", data)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("y\nno\n YES \n"))
+	var out strings.Builder
+	for _, want := range []bool{true, false, true, false} {
+		if got := confirm(&out, in, "Go?"); got != want {
+			t.Errorf("confirm() = %v, want %v", got, want)
+		}
+	}
+	if !strings.HasPrefix(out.String(), "Go? [y/N] ") {
+		t.Errorf("confirm() asked %q", out.String())
+	}
+}
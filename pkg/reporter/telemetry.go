@@ -0,0 +1,187 @@
+package reporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Latency telemetry is opt-in and never leaves the machine: each delivery
+// attempt is appended to a JSONL file in the state directory so `reporter
+// doctor` can point at the backend that slows down every prompt.
+
+const (
+	latencyFileName = "latency.jsonl"
+	// latencyKeep bounds the telemetry file; older samples are dropped once
+	// it grows past latencyMaxBytes.
+	latencyKeep     = 1000
+	latencyMaxBytes = 256 << 10
+)
+
+type latencySample struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend"`
+	Millis  float64   `json:"ms"`
+	OK      bool      `json:"ok"`
+}
+
+type latencySummary struct {
+	Backend  string
+	Count    int
+	Failures int
+	P50      time.Duration
+	P95      time.Duration
+	Max      time.Duration
+}
+
+func latencyPath() string {
+	dir := stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, latencyFileName)
+}
+
+// timeBackend runs deliver and returns its error along with a sample
+// describing how long it took.
+func timeBackend(backend string, deliver func() error) (latencySample, error) {
+	start := time.Now()
+	err := deliver()
+	return latencySample{
+		Time:    start,
+		Backend: backend,
+		Millis:  float64(time.Since(start).Microseconds()) / 1000,
+		OK:      err == nil,
+	}, err
+}
+
+func recordLatency(path string, samples []latencySample) error {
+	if path == "" || len(samples) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if fi, err := os.Stat(path); err == nil && fi.Size() > latencyMaxBytes {
+		return trimLatency(path)
+	}
+	return nil
+}
+
+// trimLatency rewrites the telemetry file keeping only the newest samples.
+func trimLatency(path string) error {
+	samples, err := loadLatency(path)
+	if err != nil {
+		return err
+	}
+	if len(samples) > latencyKeep {
+		samples = samples[len(samples)-latencyKeep:]
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadLatency reads recorded samples, skipping malformed lines. A missing file
+// yields no samples.
+func loadLatency(path string) ([]latencySample, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []latencySample
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s latencySample
+		if json.Unmarshal(scanner.Bytes(), &s) == nil {
+			samples = append(samples, s)
+		}
+	}
+	return samples, scanner.Err()
+}
+
+// summarizeLatency groups samples by backend, slowest median first.
+func summarizeLatency(samples []latencySample) []latencySummary {
+	byBackend := map[string][]time.Duration{}
+	failures := map[string]int{}
+	for _, s := range samples {
+		byBackend[s.Backend] = append(byBackend[s.Backend], time.Duration(s.Millis*float64(time.Millisecond)))
+		if !s.OK {
+			failures[s.Backend]++
+		}
+	}
+
+	out := make([]latencySummary, 0, len(byBackend))
+	for backend, durations := range byBackend {
+		out = append(out, latencySummary{
+			Backend:  backend,
+			Count:    len(durations),
+			Failures: failures[backend],
+			P50:      percentile(durations, 50),
+			P95:      percentile(durations, 95),
+			Max:      percentile(durations, 100),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].P50 != out[j].P50 {
+			return out[i].P50 > out[j].P50
+		}
+		return out[i].Backend < out[j].Backend
+	})
+	return out
+}
+
+// percentile returns the nearest-rank p-th percentile of values.
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
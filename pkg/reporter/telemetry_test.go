@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []time.Duration{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1},
+		{p: 50, want: 5},
+		{p: 75, want: 8},
+		{p: 95, want: 10},
+		{p: 100, want: 10},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil) = %v, want 0", got)
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	samples := []latencySample{
+		{Backend: "desktop", Millis: 20, OK: true},
+		{Backend: "desktop", Millis: 40, OK: true},
+		{Backend: "push", Millis: 4000, OK: false},
+		{Backend: "push", Millis: 300, OK: true},
+		{Backend: "push", Millis: 250, OK: true},
+	}
+
+	got := summarizeLatency(samples)
+	if len(got) != 2 {
+		t.Fatalf("summarizeLatency() returned %d backends, want 2", len(got))
+	}
+	if got[0].Backend != "push" {
+		t.Errorf("slowest backend = %q, want push first", got[0].Backend)
+	}
+	if got[0].Count != 3 || got[0].Failures != 1 {
+		t.Errorf("push count/failures = %d/%d, want 3/1", got[0].Count, got[0].Failures)
+	}
+	if got[0].P50 != 300*time.Millisecond || got[0].Max != 4*time.Second {
+		t.Errorf("push p50/max = %v/%v, want 300ms/4s", got[0].P50, got[0].Max)
+	}
+}
+
+func TestRecordLatencyTrims(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", latencyFileName)
+	batch := make([]latencySample, 500)
+	for i := range batch {
+		batch[i] = latencySample{Backend: "desktop", Millis: float64(i), OK: true}
+	}
+	for i := 0; i < 10; i++ {
+		if err := recordLatency(path, batch); err != nil {
+			t.Fatalf("recordLatency() returned error: %v", err)
+		}
+	}
+
+	samples, err := loadLatency(path)
+	if err != nil {
+		t.Fatalf("loadLatency() returned error: %v", err)
+	}
+	if len(samples) >= 10*len(batch) {
+		t.Errorf("telemetry file holds all %d samples, want it trimmed", len(samples))
+	}
+	if last := samples[len(samples)-1]; last.Millis != 499 {
+		t.Errorf("newest sample = %v, want the last one recorded", last.Millis)
+	}
+}
@@ -0,0 +1,107 @@
+package reporter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// -title-template and -body-template replace the fixed title and body of
+// notifications with Go templates over a messageFields, for team
+// conventions or another language:
+//
+//	title_template = "[{{.Host}}] {{.Command}}"
+//	body_template = "{{if .Failed}}Fehler{{else}}Fertig{{end}} nach {{.Duration}} ({{.Branch}})"
+
+// messageFields is what title and body templates see about a run.
+type messageFields struct {
+	Title    string // the title otherwise shown
+	Body     string // the body otherwise shown, such as "succeeded in 5m00s"
+	Command  string // on one line
+	Duration string // as notifications show it, such as 12m03s
+	ExitCode int
+	Failed   bool
+	Status   string // "succeeded", "failed (exit 2)", "killed by SIGKILL", ...
+	Signal   string // the signal that killed the command, such as SIGKILL
+	Host     string
+	Cwd      string // with the home directory shortened to ~
+	Branch   string // the git branch checked out in Cwd, or ""
+	Repo     string // the git repository holding Cwd, such as acme/app, or ""
+	Labels   map[string]string
+}
+
+// messageTemplates holds the parsed -title-template and -body-template;
+// either may be nil, to keep the usual text.
+type messageTemplates struct {
+	title, body *template.Template
+}
+
+// newMessageTemplates parses the title and body templates. It returns nil
+// if both are "".
+func newMessageTemplates(title, body string) (*messageTemplates, error) {
+	if title == "" && body == "" {
+		return nil, nil
+	}
+	var m messageTemplates
+	var err error
+	if title != "" {
+		if m.title, err = template.New("title").Option("missingkey=error").Parse(title); err != nil {
+			return nil, fmt.Errorf("title template: %w", err)
+		}
+	}
+	if body != "" {
+		if m.body, err = template.New("body").Option("missingkey=error").Parse(body); err != nil {
+			return nil, fmt.Errorf("body template: %w", err)
+		}
+	}
+	return &m, nil
+}
+
+// apply renders the templates for res into n's title and body. On an
+// error, n keeps the usual text.
+func (m *messageTemplates) apply(res runResult, n *notification) error {
+	if m == nil {
+		return nil
+	}
+	dir, _ := os.Getwd()
+	host, _ := os.Hostname()
+	f := messageFields{
+		Title:    n.Title,
+		Body:     n.Body,
+		Command:  oneLine(res.Command),
+		Duration: formatDuration(res.Duration),
+		ExitCode: res.ExitCode,
+		Failed:   res.ExitCode != 0,
+		Status:   resultStatus(res),
+		Signal:   n.Signal,
+		Host:     host,
+		Cwd:      shortenHome(dir),
+		Branch:   gitBranch(dir),
+		Repo:     gitRepo(dir),
+		Labels:   res.Labels,
+	}
+	title, body := n.Title, n.Body
+	var err error
+	if m.title != nil {
+		if title, err = renderTemplate(m.title, f); err != nil {
+			return err
+		}
+	}
+	if m.body != nil {
+		if body, err = renderTemplate(m.body, f); err != nil {
+			return err
+		}
+	}
+	n.Title, n.Body = title, body
+	return nil
+}
+
+func renderTemplate(t *template.Template, data any) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,46 @@
+package reporter
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMessageTemplates(t *testing.T) {
+	host, _ := os.Hostname()
+	res := runResult{Command: "make\nall", Duration: 5 * time.Minute, ExitCode: 137, Signal: syscall.Signal(9), Labels: map[string]string{"team": "infra"}}
+	tests := []struct {
+		name        string
+		title, body string
+		wantTitle   string
+		wantBody    string
+		wantErr     bool
+	}{
+		{name: "unset", wantTitle: "Task finished", wantBody: "killed by SIGKILL (OOM?) in 5m00s"},
+		{name: "title", title: "[{{.Host}}] {{.Command}}", wantTitle: "[" + host + "] make ⏎ all", wantBody: "killed by SIGKILL (OOM?) in 5m00s"},
+		{name: "body", body: "{{if .Failed}}Fehler ({{.ExitCode}}, {{.Signal}}){{else}}Fertig{{end}} nach {{.Duration}} für {{.Labels.team}}\n",
+			wantTitle: "Task finished", wantBody: "Fehler (137, SIGKILL) nach 5m00s für infra"},
+		{name: "extends the body", body: "{{.Body}} · {{.Status}}", wantTitle: "Task finished", wantBody: "killed by SIGKILL (OOM?) in 5m00s · killed by SIGKILL (OOM?)"},
+		{name: "unknown field", body: "{{.Elapsed}}", wantTitle: "Task finished", wantBody: "killed by SIGKILL (OOM?) in 5m00s", wantErr: true},
+		{name: "missing label", title: "{{.Labels.project}}", wantTitle: "Task finished", wantBody: "killed by SIGKILL (OOM?) in 5m00s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newMessageTemplates(tt.title, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := notification{Title: "Task finished", Body: "killed by SIGKILL (OOM?) in 5m00s", Signal: "SIGKILL"}
+			err = m.apply(res, &n)
+			if (err != nil) != tt.wantErr || n.Title != tt.wantTitle || n.Body != tt.wantBody {
+				t.Errorf("apply() = %q, %q, %v; want %q, %q, error %t", n.Title, n.Body, err, tt.wantTitle, tt.wantBody, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := newMessageTemplates("{{.Command", ""); err == nil {
+		t.Error("newMessageTemplates() accepted an unclosed action")
+	}
+}
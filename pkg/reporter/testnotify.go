@@ -0,0 +1,113 @@
+package reporter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// sampleCommand is the command line sample notifications report on.
+const sampleCommand = "make release VERSION=1.4.2"
+
+// sampleResult is the made-up run `reporter test` notifies about: a success,
+// or with failed a failure with the output -capture-output would attach.
+func sampleResult(failed bool, labels map[string]string) runResult {
+	res := runResult{
+		Command:  sampleCommand,
+		Args:     []string{"make", "release", "VERSION=1.4.2"},
+		Duration: 4*time.Minute + 12*time.Second,
+		Labels:   labels,
+	}
+	if failed {
+		res.ExitCode = 2
+		res.Output = []string{
+			"go test ./...",
+			"--- FAIL: TestRelease (0.02s)",
+			"make: *** [Makefile:12: release] Error 2",
+		}
+	}
+	return res
+}
+
+// runTest implements `reporter test`, which sends a sample notification
+// rendered the way a real one would be, so a newly configured ntfy topic,
+// Slack webhook or desktop setup can be checked straight away.
+func runTest(args []string) int {
+	cwd, _ := os.Getwd()
+	cfg, err := loadConfig(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "Usage: reporter test [flags]")
+		fset.PrintDefaults()
+	}
+	push := fset.Bool("push", false, "also send it to every push destination, not just the desktop and terminal multiplexer")
+	failed := fset.Bool("fail", false, "report a failed run, with captured output, instead of a successful one")
+	title := fset.String("title", cfg.string("title", "Task finished"), "title to display in notifications")
+	resolvePush := registerPushFlags(fset, cfg, "HTTP endpoint to send to instead of the configured ones; implies -push")
+	resolveDesktop := registerDesktopFlag(fset, cfg)
+	if err := fset.Parse(args); err != nil {
+		return 2
+	}
+	cfg.enforceLocks(fset)
+	if fset.NArg() != 0 {
+		fset.Usage()
+		return 2
+	}
+	fset.Visit(func(f *flag.Flag) {
+		if f.Name == "push-url" {
+			*push = true
+		}
+	})
+	targets, err := resolvePush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	desktop, err := resolveDesktop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	templates, err := newMessageTemplates(cfg.string("title_template", ""), cfg.string("body_template", ""))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+	if prefix := cfg.string("title_prefix", ""); prefix != "" {
+		*title = prefix + " " + *title
+	}
+
+	opts := options{title: *title, templates: templates, context: cfg.bool("context", false)}
+	n := newNotification(sampleResult(*failed, cfg.labels()), opts)
+	agent := hostAgent(cfg)
+	terminal := terminalNotifier(cfg, currentEnvironment(), agent)
+	mux, popup := "", false
+	if mode := cfg.string("multiplexer", multiplexerAuto); mode != multiplexerNever {
+		mux, popup = detectMultiplexer(os.Getenv), mode == multiplexerPopup
+	}
+	pushTargets := targets
+	if !*push {
+		pushTargets = nil
+	}
+
+	deliveries := sendTestNotification(n, desktop, agent, terminal, mux, popup, pushTargets)
+	if len(deliveries) == 0 && len(pushTargets) < len(targets) {
+		fmt.Println("Test notification")
+		fmt.Printf("  not sent: desktop notifications are off; add -push to send it to %s\n", plural(len(targets), "push destination"))
+	} else {
+		printTestDeliveries(os.Stdout, deliveries)
+		if len(pushTargets) < len(targets) {
+			fmt.Printf("\nAdd -push to also send it to %s.\n", plural(len(targets), "push destination"))
+		}
+	}
+	if anyFailed(deliveries) {
+		return 1
+	}
+	return 0
+}
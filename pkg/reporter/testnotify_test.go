@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSampleNotification(t *testing.T) {
+	labels := map[string]string{"team": "infra"}
+	templates, err := newMessageTemplates("{{.Status}}: {{.Command}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		failed     bool
+		opts       options
+		wantTitle  string
+		wantBody   string
+		wantOutput string
+	}{
+		{
+			name:      "success",
+			opts:      options{title: "Task finished"},
+			wantTitle: "Task finished",
+			wantBody:  "succeeded in 4m12s",
+		},
+		{
+			name:       "failure",
+			failed:     true,
+			opts:       options{title: "Task finished"},
+			wantTitle:  "Task finished",
+			wantBody:   "failed (exit 2) in 4m12s",
+			wantOutput: "make: *** [Makefile:12: release] Error 2",
+		},
+		{
+			name:      "templates",
+			opts:      options{title: "Task finished", templates: templates},
+			wantTitle: "succeeded: " + sampleCommand,
+			wantBody:  "succeeded in 4m12s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newNotification(sampleResult(tt.failed, labels), tt.opts)
+			if n.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", n.Title, tt.wantTitle)
+			}
+			if n.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", n.Body, tt.wantBody)
+			}
+			if n.Failed != tt.failed || n.Subtitle != sampleCommand || n.Labels["team"] != "infra" {
+				t.Errorf("notification = %+v", n)
+			}
+			if tt.wantOutput == "" && n.Output != "" || !strings.Contains(n.Output, tt.wantOutput) {
+				t.Errorf("Output = %q, want it to contain %q", n.Output, tt.wantOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// -threshold auto derives the threshold for each run from the history of the
+// same command: a run notifies only when it took longer than most earlier
+// runs did, so commands that are routinely slow stop notifying every time.
+
+const thresholdAuto = "auto"
+
+const (
+	// autoThresholdPercentile is the share of earlier runs a run must be
+	// slower than to notify.
+	autoThresholdPercentile = 75
+	// autoThresholdMinRuns is how many earlier runs a command needs before
+	// its own history is trusted; until then the floor applies alone.
+	autoThresholdMinRuns = 5
+	// autoThresholdWindow is how many of the latest runs count, so a
+	// command that got faster or slower is judged by its recent runs.
+	autoThresholdWindow = 50
+	// autoThresholdFloor is the least an automatic threshold can be:
+	// runs shorter than the default threshold never notify.
+	autoThresholdFloor = 10 * time.Second
+)
+
+// autoThreshold returns the threshold for a run of command in dir, given the
+// history entries recorded before it, oldest first.
+func autoThreshold(entries []historyEntry, command, dir string) time.Duration {
+	command = strings.Join(strings.Fields(command), " ")
+	var durations []time.Duration
+	for i := len(entries) - 1; i >= 0 && len(durations) < autoThresholdWindow; i-- {
+		e := entries[i]
+		if e.Dir == dir && strings.Join(strings.Fields(e.Command), " ") == command {
+			durations = append(durations, e.duration())
+		}
+	}
+	if len(durations) < autoThresholdMinRuns {
+		return autoThresholdFloor
+	}
+	// A run must be slower than the percentile, not merely as slow; the
+	// history keeps milliseconds.
+	return max(percentile(durations, autoThresholdPercentile)+time.Millisecond, autoThresholdFloor)
+}
+
+// [[command_threshold]] tables set the threshold for the commands matching a
+// pattern, checked in order, so a routinely slow command stops notifying
+// without raising the threshold for everything else:
+//
+//	[[command_threshold]]
+//	match = '^git (pull|fetch)\b'
+//	threshold = "30s"
+//
+// A matching table takes precedence over -threshold, which the shell hooks
+// always pass, and over -threshold auto. `reporter suggest-thresholds`
+// proposes tables from the history.
+
+const commandThresholdTable = "command_threshold"
+
+type commandThreshold struct {
+	match     *regexp.Regexp
+	threshold time.Duration
+}
+
+// checkCommandThresholds validates the [[command_threshold]] tables of a
+// config file.
+func checkCommandThresholds(tables []map[string]any) error {
+	for i, t := range tables {
+		for key := range t {
+			if key != "match" && key != "threshold" {
+				return fmt.Errorf("table %d: unknown key %q", i+1, key)
+			}
+		}
+		match, ok := t["match"].(string)
+		if !ok {
+			return fmt.Errorf("table %d: match must be a pattern", i+1)
+		}
+		if _, err := regexp.Compile(match); err != nil {
+			return fmt.Errorf("table %d: invalid pattern %q: %v", i+1, match, err)
+		}
+		threshold, ok := t["threshold"]
+		if !ok {
+			return fmt.Errorf("table %d: threshold is required", i+1)
+		}
+		if err := checkConfigKind(kindDuration, threshold); err != nil {
+			return fmt.Errorf("table %d: threshold: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// configCommandThresholds returns the configured [[command_threshold]]
+// tables, in order.
+func configCommandThresholds(cfg *config) []commandThreshold {
+	tables, _ := cfg.values[commandThresholdTable].([]map[string]any)
+	rules := make([]commandThreshold, 0, len(tables))
+	for _, t := range tables {
+		threshold, _ := time.ParseDuration(t["threshold"].(string))
+		rules = append(rules, commandThreshold{match: regexp.MustCompile(t["match"].(string)), threshold: threshold})
+	}
+	return rules
+}
+
+// matchCommandThreshold returns the threshold of the first rule whose pattern
+// matches command, with runs of whitespace collapsed as for notify_deny.
+func matchCommandThreshold(rules []commandThreshold, command string) (time.Duration, bool) {
+	normalized := strings.Join(strings.Fields(command), " ")
+	for _, r := range rules {
+		if r.match.MatchString(normalized) {
+			return r.threshold, true
+		}
+	}
+	return 0, false
+}
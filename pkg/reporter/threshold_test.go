@@ -0,0 +1,108 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThresholdAutoConfig(t *testing.T) {
+	isolateConfig(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, projectConfigName), []byte("threshold = \"auto\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v, want threshold = \"auto\" accepted", err)
+	}
+	if got := cfg.string("threshold", "10s"); got != thresholdAuto {
+		t.Errorf("threshold = %q, want %q", got, thresholdAuto)
+	}
+}
+
+func TestAutoThreshold(t *testing.T) {
+	runs := func(command, dir string, seconds ...int) []historyEntry {
+		var entries []historyEntry
+		for _, s := range seconds {
+			entries = append(entries, historyEntry{Command: command, Dir: dir, DurationMS: int64(s) * 1000})
+		}
+		return entries
+	}
+	var history []historyEntry
+	history = append(history, runs("cargo build", "/src/a", 40, 60, 50, 45, 55, 300)...)
+	history = append(history, runs("git pull", "/src/a", 12, 14, 11, 13, 12)...)
+	history = append(history, runs("make", "/src/a", 1, 2, 1, 1, 2)...)
+	history = append(history, runs("go test ./...", "/src/b", 30, 30, 30)...)
+
+	tests := []struct {
+		command, dir string
+		want         time.Duration
+	}{
+		{"cargo build", "/src/a", 60*time.Second + time.Millisecond},
+		{"cargo  build", "/src/a", 60*time.Second + time.Millisecond}, // spacing does not matter
+		{"git pull", "/src/a", 13*time.Second + time.Millisecond},
+		{"make", "/src/a", autoThresholdFloor},          // fast commands keep the floor
+		{"go test ./...", "/src/b", autoThresholdFloor}, // too few runs
+		{"cargo build", "/src/c", autoThresholdFloor},   // another project
+		{"ls", "/src/a", autoThresholdFloor},
+	}
+	for _, tt := range tests {
+		if got := autoThreshold(history, tt.command, tt.dir); got != tt.want {
+			t.Errorf("autoThreshold(%q in %s) = %v, want %v", tt.command, tt.dir, got, tt.want)
+		}
+	}
+
+	// Only the latest runs count.
+	history = append(history, runs("git pull", "/src/a", make([]int, autoThresholdWindow)...)...)
+	if got := autoThreshold(history, "git pull", "/src/a"); got != autoThresholdFloor {
+		t.Errorf("autoThreshold after %d fast runs = %v, want the floor", autoThresholdWindow, got)
+	}
+}
+
+func TestCommandThresholds(t *testing.T) {
+	isolateConfig(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, projectConfigName), `
+[[command_threshold]]
+match = '^git (pull|fetch)\b'
+threshold = "30s"
+
+[[command_threshold]]
+match = '^git\b'
+threshold = "5s"
+`)
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := configCommandThresholds(cfg)
+	for _, tt := range []struct {
+		command string
+		want    time.Duration
+		ok      bool
+	}{
+		{command: "git   pull --rebase", want: 30 * time.Second, ok: true},
+		{command: "git push", want: 5 * time.Second, ok: true},
+		{command: "make test", ok: false},
+	} {
+		got, ok := matchCommandThreshold(rules, tt.command)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("matchCommandThreshold(%q) = %v, %v, want %v, %v", tt.command, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	for _, bad := range []string{
+		"[[command_threshold]]\nthreshold = \"30s\"\n",
+		"[[command_threshold]]\nmatch = \"(\"\nthreshold = \"30s\"\n",
+		"[[command_threshold]]\nmatch = \"^make\"\n",
+		"[[command_threshold]]\nmatch = \"^make\"\nthreshold = \"auto\"\n",
+		"[[command_threshold]]\nmatch = \"^make\"\nthreshold = \"30s\"\nalways = true\n",
+	} {
+		writeFile(t, filepath.Join(dir, projectConfigName), bad)
+		if _, err := loadConfig(dir); err == nil {
+			t.Errorf("loadConfig() accepted %q, want error", bad)
+		}
+	}
+}
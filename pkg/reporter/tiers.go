@@ -0,0 +1,155 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tiers route notifications by how long the run took, so a long run can
+// reach more, or louder, destinations than a short one:
+//
+//	[[tier]]
+//	after = "10s"
+//	to = ["desktop"]
+//
+//	[[tier]]
+//	after = "1h"
+//	to = ["desktop", "slack", "pushover"]
+//
+// A run goes to the destinations of the highest tier it reached. Each entry
+// in to is "desktop", "push" for every push destination, a push provider
+// name such as "ntfy", or one destination's exact URL. Tiers only route:
+// whether a run notifies at all is still up to -threshold and the filters.
+
+const tierTable = "tier"
+
+// Values in a tier's to list besides provider names and URLs.
+const (
+	tierDesktop = "desktop"
+	tierPush    = "push"
+)
+
+type tier struct {
+	after time.Duration
+	to    []string
+}
+
+// checkTiers validates the [[tier]] tables of a config file.
+func checkTiers(tables []map[string]any) error {
+	for i, t := range tables {
+		for key := range t {
+			if key != "after" && key != "to" {
+				return fmt.Errorf("tier %d: unknown key %q", i+1, key)
+			}
+		}
+		after, ok := t["after"]
+		if !ok {
+			return fmt.Errorf("tier %d: after is required", i+1)
+		}
+		if err := checkConfigKind(kindDuration, after); err != nil {
+			return fmt.Errorf("tier %d: after: %w", i+1, err)
+		}
+		to, ok := t["to"]
+		if !ok {
+			return fmt.Errorf("tier %d: to is required", i+1)
+		}
+		if err := checkConfigKind(kindStringList, to); err != nil {
+			return fmt.Errorf("tier %d: to: %w", i+1, err)
+		}
+		for _, item := range to.([]any) {
+			if err := checkTierDestination(item.(string)); err != nil {
+				return fmt.Errorf("tier %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkTierDestination(dest string) error {
+	switch {
+	case dest == tierDesktop || dest == tierPush || strings.Contains(dest, "://"):
+		return nil
+	case dest == "":
+		return errors.New("empty destination")
+	}
+	if _, ok := pushProviders[dest]; ok || len(pushProviders) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unknown destination %q: want %s, %s, a push URL, or one of %s",
+		dest, tierDesktop, tierPush, strings.Join(pushProviderNames(), ", "))
+}
+
+// configTiers returns the configured tiers, shortest first.
+func configTiers(cfg *config) []tier {
+	tables, _ := cfg.values[tierTable].([]map[string]any)
+	tiers := make([]tier, 0, len(tables))
+	for _, t := range tables {
+		after, _ := time.ParseDuration(t["after"].(string))
+		var to []string
+		for _, item := range t["to"].([]any) {
+			to = append(to, item.(string))
+		}
+		tiers = append(tiers, tier{after: after, to: to})
+	}
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].after < tiers[j].after })
+	return tiers
+}
+
+// selectTier returns the highest tier a run of duration d reached, or the
+// first tier for runs shorter than all of them, such as ones notified because
+// of -always. ok is false when there are no tiers.
+func selectTier(tiers []tier, d time.Duration) (t tier, ok bool) {
+	if len(tiers) == 0 {
+		return tier{}, false
+	}
+	t = tiers[0]
+	for _, next := range tiers[1:] {
+		if d >= next.after {
+			t = next
+		}
+	}
+	return t, true
+}
+
+// desktop reports whether the tier shows desktop notifications.
+func (t tier) desktop() bool {
+	for _, to := range t.to {
+		if to == tierDesktop {
+			return true
+		}
+	}
+	return false
+}
+
+// sendsTo reports whether the tier pushes to target.
+func (t tier) sendsTo(target pushTarget) bool {
+	for _, to := range t.to {
+		if to == tierPush || to == target.Provider || to == target.URL {
+			return true
+		}
+	}
+	return false
+}
+
+// routeTier narrows the destinations in opts to those of the tier a run of
+// duration d reached.
+func routeTier(opts options, d time.Duration) options {
+	t, ok := selectTier(opts.tiers, d)
+	if !ok {
+		return opts
+	}
+	if !t.desktop() {
+		opts.desktop, opts.bell = false, false
+	}
+	var push []pushTarget
+	for _, target := range opts.push {
+		if t.sendsTo(target) {
+			push = append(push, target)
+		}
+	}
+	opts.push = push
+	return opts
+}
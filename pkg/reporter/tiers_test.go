@@ -0,0 +1,89 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigTiers(t *testing.T) {
+	isolateConfig(t)
+	dir := t.TempDir()
+	writeFile(t, userConfigPath(), `
+[[tier]]
+after = "1h"
+to = ["desktop", "push"]
+
+[[tier]]
+after = "10s"
+to = ["desktop"]
+
+[[tier]]
+after = "5m"
+to = ["https://ntfy.sh/me"]
+`)
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tier{
+		{after: 10 * time.Second, to: []string{"desktop"}},
+		{after: 5 * time.Minute, to: []string{"https://ntfy.sh/me"}},
+		{after: time.Hour, to: []string{"desktop", "push"}},
+	}
+	if got := configTiers(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("configTiers() = %+v, want %+v", got, want)
+	}
+
+	bad := []string{
+		"[[tier]]\nto = [\"desktop\"]\n",
+		"[[tier]]\nafter = \"soon\"\nto = [\"desktop\"]\n",
+		"[[tier]]\nafter = \"1m\"\n",
+		"[[tier]]\nafter = \"1m\"\nto = [\"desktop\"]\nsound = \"loud\"\n",
+		"tier = \"1m\"\n",
+	}
+	if len(pushProviders) > 0 {
+		bad = append(bad, "[[tier]]\nafter = \"1m\"\nto = [\"slak\"]\n")
+	}
+	for _, bad := range bad {
+		writeFile(t, userConfigPath(), bad)
+		if _, err := loadConfig(dir); err == nil {
+			t.Errorf("loadConfig() accepted %q, want error", bad)
+		}
+	}
+}
+
+func TestRouteTier(t *testing.T) {
+	ntfy := pushTarget{URL: "https://ntfy.sh/me", Provider: pushProviderNtfy}
+	work := pushTarget{URL: "https://ntfy.example.com/builds", Provider: pushProviderNtfy}
+	hook := pushTarget{URL: "https://example.com/hook", Provider: pushProviderPlain}
+	base := options{desktop: true, bell: true, push: []pushTarget{ntfy, work, hook}}
+	base.tiers = []tier{
+		{after: 10 * time.Second, to: []string{"desktop"}},
+		{after: 5 * time.Minute, to: []string{"desktop", "https://ntfy.sh/me"}},
+		{after: time.Hour, to: []string{"ntfy", "plain"}},
+		{after: 3 * time.Hour, to: []string{"desktop", "push"}},
+	}
+
+	tests := []struct {
+		d       time.Duration
+		desktop bool
+		push    []pushTarget
+	}{
+		{d: time.Second, desktop: true}, // shorter than every tier, e.g. with -always
+		{d: 30 * time.Second, desktop: true},
+		{d: 5 * time.Minute, desktop: true, push: []pushTarget{ntfy}},
+		{d: 2 * time.Hour, push: []pushTarget{ntfy, work, hook}},
+		{d: 4 * time.Hour, desktop: true, push: []pushTarget{ntfy, work, hook}},
+	}
+	for _, tt := range tests {
+		got := routeTier(base, tt.d)
+		if got.desktop != tt.desktop || got.bell != tt.desktop || !reflect.DeepEqual(got.push, tt.push) {
+			t.Errorf("routeTier(%v) = desktop %v, bell %v, push %v; want desktop %v, push %v", tt.d, got.desktop, got.bell, got.push, tt.desktop, tt.push)
+		}
+	}
+
+	if got := routeTier(options{desktop: true, push: []pushTarget{hook}}, time.Hour); !got.desktop || len(got.push) != 1 {
+		t.Errorf("routeTier() without tiers changed the destinations: %+v", got)
+	}
+}
@@ -0,0 +1,123 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{
+			name:  "empty document",
+			input: "",
+			want:  map[string]any{},
+		},
+		{
+			name:  "scalars and comments",
+			input: "# defaults\nthreshold = \"30s\" # slow builds\nalways = true\ncount = 1_000\nratio = 0.5\n",
+			want: map[string]any{
+				"threshold": "30s",
+				"always":    true,
+				"count":     int64(1000),
+				"ratio":     0.5,
+			},
+		},
+		{
+			name:  "string escapes and literal strings",
+			input: "a = \"say \\\"hi\\\"\\n\"\nb = 'C:\\path'\nc = \"\\u00e9\"\n",
+			want: map[string]any{
+				"a": "say \"hi\"\n",
+				"b": `C:\path`,
+				"c": "é",
+			},
+		},
+		{
+			name:  "tables flatten keys",
+			input: "[push]\nurl = \"https://ntfy.sh/t\"\n[push.ntfy]\ntoken = \"x\"\n",
+			want: map[string]any{
+				"push.url":        "https://ntfy.sh/t",
+				"push.ntfy.token": "x",
+			},
+		},
+		{
+			name:  "multi-line array with trailing comma",
+			input: "exclude = [\n  \"ls\", # listing\n  \"cd\",\n]\n",
+			want: map[string]any{
+				"exclude": []any{"ls", "cd"},
+			},
+		},
+		{
+			name:  "CRLF without a final newline",
+			input: "always = true\r\nthreshold = \"5s\"\r",
+			want: map[string]any{
+				"always":    true,
+				"threshold": "5s",
+			},
+		},
+		{
+			name:  "table header ending the file with CR",
+			input: "[0]\r",
+			want:  map[string]any{},
+		},
+		{
+			name:  "array of tables",
+			input: "[[tier]]\nafter = \"10s\"\n[[tier]]\nafter = \"5m\"\n",
+			want: map[string]any{
+				"tier": []map[string]any{
+					{"after": "10s"},
+					{"after": "5m"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.input)
+			if err != nil {
+				t.Fatalf("parseTOML(%q) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing equals", input: "threshold \"10s\"\n"},
+		{name: "unterminated string", input: "title = \"oops\n"},
+		{name: "duplicate key", input: "a = 1\na = 2\n"},
+		{name: "trailing garbage", input: "a = 1 2\n"},
+		{name: "unclosed table header", input: "[push\n"},
+		{name: "unterminated array", input: "a = [1, 2\n"},
+		{name: "bare CR between values", input: "a = 1\rb = 2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseTOML(tt.input); err == nil {
+				t.Errorf("parseTOML(%q) succeeded, want error", tt.input)
+			}
+		})
+	}
+}
+
+// FuzzParseTOML checks that no config file, such as a project's
+// .reporter.toml, can crash the parser.
+func FuzzParseTOML(f *testing.F) {
+	for _, s := range []string{"threshold = \"5s\"\r", "[0]\r", "a = [1, 2]\r\n[[t]]\r\nb = 'x'", "a = 1\rb = 2"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		parseTOML(s)
+	})
+}
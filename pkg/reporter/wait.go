@@ -0,0 +1,172 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// reporter wait polls until a condition holds, such as a port accepting
+// connections, and then reports it as if a command had run for that long.
+// It suits waits that are not one command, like a database restore started
+// elsewhere. It takes the same flags as wrapping a command, plus those in
+// registerWaitFlags.
+
+// defaultWaitInterval is how often conditions are checked.
+const defaultWaitInterval = 5 * time.Second
+
+// waitCondition is something reporter wait checks for.
+type waitCondition struct {
+	desc string // e.g. "-tcp localhost:5432"
+	// check returns nil once the condition holds, and otherwise why not.
+	check func(ctx context.Context) error
+}
+
+// registerWaitFlags adds reporter wait's flags to fset, and returns a
+// function that resolves them once fset is parsed.
+func registerWaitFlags(fset *flag.FlagSet) func() ([]waitCondition, time.Duration, time.Duration, error) {
+	tcp := fset.String("tcp", "", "wait until `host:port` accepts TCP connections")
+	file := fset.String("file", "", "wait until `path` exists and has stopped growing")
+	httpURL := fset.String("http", "", "wait until `url` answers a GET with a 2xx status")
+	timeout := fset.Duration("timeout", 0, "give up after this `long` and report the wait as timed out (default no limit)")
+	interval := fset.Duration("interval", defaultWaitInterval, "check the conditions every `interval`")
+	return func() ([]waitCondition, time.Duration, time.Duration, error) {
+		var conds []waitCondition
+		if *tcp != "" {
+			if _, _, err := net.SplitHostPort(*tcp); err != nil {
+				return nil, 0, 0, fmt.Errorf("invalid -tcp %q: want host:port", *tcp)
+			}
+			conds = append(conds, tcpCondition(*tcp))
+		}
+		if *file != "" {
+			conds = append(conds, fileCondition(*file))
+		}
+		if *httpURL != "" {
+			if !strings.HasPrefix(*httpURL, "http://") && !strings.HasPrefix(*httpURL, "https://") {
+				return nil, 0, 0, fmt.Errorf("invalid -http %q: want an http:// or https:// URL", *httpURL)
+			}
+			cond, err := httpCondition(*httpURL)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			conds = append(conds, cond)
+		}
+		if len(conds) == 0 {
+			return nil, 0, 0, errors.New("usage: reporter wait [flags] -tcp host:port | -file path | -http url")
+		}
+		if *interval <= 0 || *timeout < 0 {
+			return nil, 0, 0, errors.New("-interval must be positive and -timeout not negative")
+		}
+		return conds, *timeout, *interval, nil
+	}
+}
+
+func tcpCondition(addr string) waitCondition {
+	return waitCondition{
+		desc: "-tcp " + addr,
+		check: func(ctx context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+	}
+}
+
+// fileCondition holds once path exists and its size and modification time
+// are unchanged since the last check, so a file still being written does
+// not count.
+func fileCondition(path string) waitCondition {
+	var last os.FileInfo
+	return waitCondition{
+		desc: "-file " + path,
+		check: func(context.Context) error {
+			info, err := os.Stat(path)
+			if err != nil {
+				last = nil
+				return err
+			}
+			prev := last
+			last = info
+			if prev == nil || prev.Size() != info.Size() || !prev.ModTime().Equal(info.ModTime()) {
+				return fmt.Errorf("%s is %d bytes and may still be growing", path, info.Size())
+			}
+			return nil
+		},
+	}
+}
+
+// checkConditions returns nil if every condition holds, and otherwise why
+// the first that does not. Each check gets at most interval.
+func checkConditions(conds []waitCondition, interval time.Duration) error {
+	for _, c := range conds {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := c.check(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitMode checks conds every interval until they all hold, or until
+// timeout if it is positive, and reports the wait.
+func waitMode(conds []waitCondition, timeout, interval time.Duration, opts options) int {
+	descs := make([]string, len(conds))
+	for i, c := range conds {
+		descs[i] = c.desc
+	}
+	display := "wait " + strings.Join(descs, " ")
+	if !opts.quiet {
+		fmt.Fprintf(os.Stderr, "[wait] checking %s every %s\n", strings.Join(descs, ", "), formatDuration(interval))
+	}
+
+	start := time.Now()
+	key := opts.dedupKey
+	if key == "" {
+		dir, _ := os.Getwd()
+		key = fingerprint(runResult{Command: display}, "", dir)
+	}
+	stopProgress := startProgress(display, key, start, opts)
+	stopHeartbeat := startHeartbeat(display, start, opts)
+	timedOut := false
+	for {
+		err := checkConditions(conds, interval)
+		if err == nil {
+			break
+		}
+		next := interval
+		if timeout > 0 {
+			remaining := timeout - time.Since(start)
+			if remaining <= 0 {
+				timedOut = true
+				if !opts.quiet {
+					fmt.Fprintf(os.Stderr, "[wait] timed out after %s: %v\n", formatDuration(timeout), err)
+				}
+				break
+			}
+			next = min(next, remaining)
+		}
+		time.Sleep(next)
+	}
+	stopProgress()
+	stopHeartbeat()
+	if opts.progress > 0 {
+		opts.progressKey = key
+	}
+
+	res := runResult{Command: display, Duration: time.Since(start), Labels: opts.labels}
+	if timedOut {
+		res.ExitCode, res.TimedOut = exitTimedOut, true
+	}
+	report(res, opts)
+	return res.ExitCode
+}
@@ -0,0 +1,31 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// httpCondition holds once rawURL answers a GET with a 2xx status.
+func httpCondition(rawURL string) (waitCondition, error) {
+	return waitCondition{
+		desc: "-http " + rawURL,
+		check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return fmt.Errorf("%s returned %s", rawURL, resp.Status)
+			}
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,51 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegisterWaitFlagsHTTP(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{args: []string{"-tcp", "[::1]:80", "-file", "out.tar.gz", "-http", "https://example.com/health"}, want: 3},
+		{args: []string{"-http", "example.com/health"}, wantErr: true},
+	} {
+		fset := flag.NewFlagSet("wait", flag.ContinueOnError)
+		resolve := registerWaitFlags(fset)
+		if err := fset.Parse(tt.args); err != nil {
+			t.Fatalf("parsing %q: %v", tt.args, err)
+		}
+		conds, _, _, err := resolve()
+		if (err != nil) != tt.wantErr || len(conds) != tt.want {
+			t.Errorf("%q: got %d conditions, %v; want %d, error %v", tt.args, len(conds), err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHTTPCondition(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	cond, err := httpCondition(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkConditions([]waitCondition{cond}, time.Second) == nil {
+		t.Error("503 counted as ready")
+	}
+	status = http.StatusNoContent
+	if err := checkConditions([]waitCondition{cond}, time.Second); err != nil {
+		t.Errorf("204: %v", err)
+	}
+}
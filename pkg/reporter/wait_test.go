@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegisterWaitFlags(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{args: []string{"-tcp", "localhost:5432"}, want: 1},
+		{args: []string{"-tcp", "[::1]:80", "-file", "out.tar.gz"}, want: 2},
+		{args: nil, wantErr: true},
+		{args: []string{"-tcp", "localhost"}, wantErr: true},
+		{args: []string{"-http", "example.com/health"}, wantErr: true},
+		{args: []string{"-file", "x", "-interval", "0s"}, wantErr: true},
+		{args: []string{"-file", "x", "-timeout", "-1s"}, wantErr: true},
+	} {
+		fset := flag.NewFlagSet("wait", flag.ContinueOnError)
+		resolve := registerWaitFlags(fset)
+		if err := fset.Parse(tt.args); err != nil {
+			t.Fatalf("parsing %q: %v", tt.args, err)
+		}
+		conds, _, interval, err := resolve()
+		if (err != nil) != tt.wantErr || len(conds) != tt.want {
+			t.Errorf("%q: got %d conditions, %v; want %d, error %v", tt.args, len(conds), err, tt.want, tt.wantErr)
+		}
+		if err == nil && interval != defaultWaitInterval {
+			t.Errorf("%q: interval %v, want %v", tt.args, interval, defaultWaitInterval)
+		}
+	}
+}
+
+func TestTCPCondition(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	if err := checkConditions([]waitCondition{tcpCondition(addr)}, time.Second); err != nil {
+		t.Errorf("listening: %v", err)
+	}
+	ln.Close()
+	if err := checkConditions([]waitCondition{tcpCondition(addr)}, time.Second); err == nil {
+		t.Error("closed port counted as ready")
+	}
+}
+
+func TestFileCondition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.tar.gz")
+	cond := fileCondition(path)
+	check := func() error { return checkConditions([]waitCondition{cond}, time.Second) }
+	if check() == nil {
+		t.Error("missing file counted as ready")
+	}
+	writeFile(t, path, "part")
+	if check() == nil {
+		t.Error("new file counted as ready on first sight")
+	}
+	writeFile(t, path, "partial content")
+	if check() == nil {
+		t.Error("growing file counted as ready")
+	}
+	if err := check(); err != nil {
+		t.Errorf("unchanged file: %v", err)
+	}
+	os.Remove(path)
+	if check() == nil {
+		t.Error("removed file counted as ready")
+	}
+}
@@ -0,0 +1,88 @@
+package reporter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// -max-duration sets a time limit on the wrapped command. By itself it only
+// sends a notification when the command runs past the limit; with
+// -kill-after, the command is sent SIGTERM at the limit and SIGKILL if it is
+// still running that much later, and the run is reported as timed out, as
+// with timeout(1).
+
+// exitTimedOut is reporter's exit status for a command stopped at its time
+// limit, the same as timeout(1)'s.
+const exitTimedOut = 124
+
+// watchdog enforces -max-duration on a running command.
+type watchdog struct {
+	mu       sync.Mutex
+	timers   []*time.Timer
+	stopped  bool
+	timedOut bool
+}
+
+// startWatchdog starts enforcing opts.maxDuration on p, the process running
+// command since start. The returned watchdog must be stopped once p exits.
+func startWatchdog(p *os.Process, command string, start time.Time, opts options) *watchdog {
+	w := &watchdog{}
+	if opts.maxDuration <= 0 {
+		return w
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.after(opts.maxDuration-time.Since(start), func() {
+		if opts.killAfter <= 0 {
+			w.overLimit(command, start, opts)
+			return
+		}
+		w.timedOut = true
+		if !opts.quiet {
+			fmt.Fprintf(os.Stderr, "[max-duration] %s limit reached; stopping the command\n", formatDuration(opts.maxDuration))
+		}
+		_ = signalCommand(p, syscall.SIGTERM)
+		w.after(opts.killAfter, func() { _ = signalCommand(p, syscall.SIGKILL) })
+	})
+	return w
+}
+
+// after calls fn after d, under w's lock, unless w is stopped first. The
+// caller holds the lock.
+func (w *watchdog) after(d time.Duration, fn func()) {
+	w.timers = append(w.timers, time.AfterFunc(d, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.stopped {
+			fn()
+		}
+	}))
+}
+
+// overLimit sends the notification that command is still running past the
+// limit to every backend.
+func (w *watchdog) overLimit(command string, start time.Time, opts options) {
+	if opts.snoozed {
+		return
+	}
+	now := time.Now()
+	n := progressNotification(command, "", now.Sub(start), opts.labels)
+	n.Title = heartbeatTitle
+	n.Body = fmt.Sprintf("past the %s limit", formatDuration(opts.maxDuration))
+	sendRunning(n, opts, now, warnOnce("max-duration", opts.quiet))
+}
+
+// stop cancels the pending timers and reports whether the command was
+// stopped for running past its limit.
+func (w *watchdog) stop() (timedOut bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for _, t := range w.timers {
+		t.Stop()
+	}
+	return w.timedOut
+}
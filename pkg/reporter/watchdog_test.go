@@ -0,0 +1,65 @@
+//go:build !windows
+
+package reporter
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchdogKillsProcessGroup(t *testing.T) {
+	// The shell ignores SIGTERM, so only the SIGKILL that follows stops it;
+	// its sleep must go with it.
+	cmd := exec.Command("sh", "-c", `trap "" TERM; sleep 30 & wait`)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	w := startWatchdog(cmd.Process, "sleep", start, options{maxDuration: 50 * time.Millisecond, killAfter: 50 * time.Millisecond, quiet: true})
+	err := cmd.Wait()
+	if !w.stop() {
+		t.Error("stop() = false after the limit, want timed out")
+	}
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || ws.Signal() != syscall.SIGKILL {
+		t.Errorf("Wait() = %v, want killed by SIGKILL", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("command ran for %s past its limit", elapsed)
+	}
+	// The orphaned sleep is reaped by init, so give it a moment.
+	for deadline := time.Now().Add(5 * time.Second); syscall.Kill(-cmd.Process.Pid, 0) == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Error("process group still has members after SIGKILL")
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			break
+		}
+	}
+}
+
+func TestWatchdogStoppedInTime(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w := startWatchdog(cmd.Process, "true", time.Now(), options{maxDuration: time.Hour, killAfter: time.Second, quiet: true})
+	cmd.Wait()
+	if w.stop() {
+		t.Error("stop() = true for a command that finished in time")
+	}
+
+	// Without -kill-after the limit only notifies.
+	cmd = exec.Command("sleep", "0.2")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w = startWatchdog(cmd.Process, "sleep 0.2", time.Now(), options{maxDuration: time.Millisecond, quiet: true})
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want the command left running to finish", err)
+	}
+	if w.stop() {
+		t.Error("stop() = true without -kill-after")
+	}
+}
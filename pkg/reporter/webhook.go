@@ -0,0 +1,75 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Webhook is a Notifier POSTing each notification as the reporter/v1 JSON
+// payload, with the headers the reporter command's webhook provider sends.
+type Webhook struct {
+	URL string
+	// Token is sent as a bearer token, or as basic authorization when it
+	// is user:password.
+	Token string
+	// Client sends the requests; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (w Webhook) Notify(ctx context.Context, n Notification) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(n.Payload()); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, &body)
+	if err != nil {
+		return fmt.Errorf("creating request for %s: %w", w.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case strings.Contains(w.Token, ":"):
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(w.Token)))
+	case w.Token != "":
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+	if n.DedupKey != "" {
+		req.Header.Set("X-Reporter-Dedup-Key", n.DedupKey)
+	}
+	if len(n.Labels) > 0 {
+		keys := make([]string, 0, len(n.Labels))
+		for k := range n.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			keys[i] = k + "=" + n.Labels[k]
+		}
+		req.Header.Set("X-Reporter-Labels", strings.Join(keys, ","))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", w.URL, resp.Status)
+	}
+	return nil
+}
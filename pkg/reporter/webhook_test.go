@@ -0,0 +1,68 @@
+//go:build !nopush
+
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var got Payload
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	finished := time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC)
+	n := Notification{Title: "Task finished", Body: "failed (exit 2) in 3m00s", Command: "make release", Args: []string{"make", "release"},
+		Host: "build-1", Failed: true, ExitCode: 2, Duration: 3 * time.Minute, Finished: finished,
+		Labels: map[string]string{"project": "atlas", "env": "ci"}, DedupKey: "release"}
+	if err := (Webhook{URL: srv.URL, Token: "s3cret"}).Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify() returned error: %v", err)
+	}
+	if got.Schema != PayloadSchema || got.Command != "make release" || got.ExitCode != 2 || !got.Failed || got.DurationMS != 180000 ||
+		got.StartedAt != "2026-05-01T09:27:00Z" || got.FinishedAt != "2026-05-01T09:30:00Z" || got.Host != "build-1" {
+		t.Errorf("payload = %+v", got)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":         "application/json",
+		"Authorization":        "Bearer s3cret",
+		"X-Reporter-Dedup-Key": "release",
+		"X-Reporter-Labels":    "env=ci,project=atlas",
+	} {
+		if header.Get(name) != want {
+			t.Errorf("%s = %q, want %q", name, header.Get(name), want)
+		}
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	err := Webhook{URL: srv.URL}.Notify(context.Background(), Notification{})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Notify() = %v, want the 403 status", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var b strings.Builder
+	if err := (Writer{W: &b}).Notify(context.Background(), Notification{Command: "make", Body: "succeeded in 42s"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "[notify] make — succeeded in 42s\n"; got != want {
+		t.Errorf("Writer wrote %q, want %q", got, want)
+	}
+}
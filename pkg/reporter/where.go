@@ -0,0 +1,361 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file implements the small expression language behind -where:
+//
+//	exit != 0 && duration > 10m && label.project == "atlas"
+//	command =~ "^(make|cargo) " || !(host == "ci-1")
+//	failed && time >= "2026-03-01"
+//
+// Fields are typed, and a comparison is checked against its field's type when
+// the expression is compiled, so typos fail before any history is read.
+
+type whereType int
+
+const (
+	whereInt whereType = iota
+	whereDuration
+	whereString
+	whereTime
+	whereBool
+)
+
+func (t whereType) String() string {
+	return [...]string{"integer", "duration", "string", "time", "boolean"}[t]
+}
+
+// whereFields lists the fields expressions can refer to, besides label.<key>.
+var whereFields = map[string]whereType{
+	"exit":     whereInt,
+	"duration": whereDuration,
+	"command":  whereString,
+	"cwd":      whereString,
+	"host":     whereString,
+	"time":     whereTime,
+	"failed":   whereBool,
+}
+
+type whereExpr func(historyEntry) bool
+
+// compileWhere parses expr into a predicate. Relative times such as
+// time >= "24h" are resolved against now.
+func compileWhere(expr string, now time.Time) (whereExpr, error) {
+	toks, err := lexWhere(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{toks: toks, now: now}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+	}
+	return e, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber // numbers and durations: 0, 2, 10m, 1h30m
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type whereToken struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t whereToken) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func lexWhere(s string) ([]whereToken, error) {
+	var toks []whereToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			kind := tokLParen
+			if c == ')' {
+				kind = tokRParen
+			}
+			toks = append(toks, whereToken{kind, string(c), i})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %v", i, err)
+			}
+			toks = append(toks, whereToken{tokString, text, i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] >= 'a' && s[j] <= 'z') {
+				j++
+			}
+			toks = append(toks, whereToken{tokNumber, s[i:j], i})
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(s) && isIdentByte(s[j]) {
+				j++
+			}
+			toks = append(toks, whereToken{tokIdent, s[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"} {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, whereToken{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, whereToken{kind: tokEOF, pos: len(s)}), nil
+}
+
+type whereParser struct {
+	toks []whereToken
+	i    int
+	now  time.Time
+}
+
+func (p *whereParser) peek() whereToken { return p.toks[p.i] }
+
+func (p *whereParser) next() whereToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *whereParser) or() (whereExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e historyEntry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *whereParser) and() (whereExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e historyEntry) bool { return l(e) && right(e) }
+	}
+	return left, nil
+}
+
+func (p *whereParser) unary() (whereExpr, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokOp && t.text == "!":
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e historyEntry) bool { return !inner(e) }, nil
+	case t.kind == tokLParen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at offset %d, got %s", r.pos, r)
+		}
+		return inner, nil
+	case t.kind == tokIdent:
+		return p.comparison(t)
+	default:
+		return nil, fmt.Errorf("expected a field, \"!\", or \"(\" at offset %d, got %s", t.pos, t)
+	}
+}
+
+func (p *whereParser) comparison(field whereToken) (whereExpr, error) {
+	typ, get, err := whereField(field.text)
+	if err != nil {
+		return nil, fmt.Errorf("offset %d: %v", field.pos, err)
+	}
+
+	op := p.peek()
+	if op.kind != tokOp || !comparisonOps[op.text] {
+		if typ == whereBool {
+			return func(e historyEntry) bool { return get(e).(bool) }, nil
+		}
+		return nil, fmt.Errorf("offset %d: %s is a %s; compare it with an operator", field.pos, field.text, typ)
+	}
+	p.next()
+	lit := p.next()
+	if lit.kind != tokNumber && lit.kind != tokString && lit.kind != tokIdent {
+		return nil, fmt.Errorf("expected a value after %s at offset %d, got %s", op.text, lit.pos, lit)
+	}
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("offset %d: %s", lit.pos, fmt.Sprintf(format, args...))
+	}
+
+	switch typ {
+	case whereString:
+		if lit.kind != tokString {
+			return nil, fail("%s is a string; quote the value", field.text)
+		}
+		switch op.text {
+		case "==":
+			return func(e historyEntry) bool { return get(e).(string) == lit.text }, nil
+		case "!=":
+			return func(e historyEntry) bool { return get(e).(string) != lit.text }, nil
+		case "=~", "!~":
+			re, err := regexp.Compile(lit.text)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			want := op.text == "=~"
+			return func(e historyEntry) bool { return re.MatchString(get(e).(string)) == want }, nil
+		}
+		return nil, fail("%s cannot be used with strings", op.text)
+
+	case whereBool:
+		if op.text != "==" && op.text != "!=" || lit.text != "true" && lit.text != "false" {
+			return nil, fail("%s is a boolean; use it alone or compare with == true or == false", field.text)
+		}
+		want := (lit.text == "true") == (op.text == "==")
+		return func(e historyEntry) bool { return get(e).(bool) == want }, nil
+
+	case whereInt:
+		n, err := strconv.ParseInt(lit.text, 10, 64)
+		if lit.kind != tokNumber || err != nil {
+			return nil, fail("%s is an integer, got %s", field.text, lit)
+		}
+		return ordered(op.text, func(e historyEntry) int64 { return int64(get(e).(int)) }, n)
+
+	case whereDuration:
+		d, err := time.ParseDuration(lit.text)
+		if lit.kind != tokNumber || err != nil {
+			return nil, fail("%s is a duration such as 90s or 10m, got %s", field.text, lit)
+		}
+		return ordered(op.text, func(e historyEntry) int64 { return int64(get(e).(time.Duration)) }, int64(d))
+
+	default: // whereTime
+		if lit.kind != tokString {
+			return nil, fail("%s is a time; quote the value, e.g. \"2026-03-01\" or \"24h\"", field.text)
+		}
+		t, err := parseTimeBound(lit.text, p.now)
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+		return ordered(op.text, func(e historyEntry) int64 { return get(e).(time.Time).UnixNano() }, t.UnixNano())
+	}
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "=~": true, "!~": true}
+
+// ordered builds a comparison of get(e) against want.
+func ordered(op string, get func(historyEntry) int64, want int64) (whereExpr, error) {
+	var cmp func(a, b int64) bool
+	switch op {
+	case "==":
+		cmp = func(a, b int64) bool { return a == b }
+	case "!=":
+		cmp = func(a, b int64) bool { return a != b }
+	case "<":
+		cmp = func(a, b int64) bool { return a < b }
+	case "<=":
+		cmp = func(a, b int64) bool { return a <= b }
+	case ">":
+		cmp = func(a, b int64) bool { return a > b }
+	case ">=":
+		cmp = func(a, b int64) bool { return a >= b }
+	default:
+		return nil, fmt.Errorf("%s only applies to strings", op)
+	}
+	return func(e historyEntry) bool { return cmp(get(e), want) }, nil
+}
+
+// whereField resolves a field name to its type and accessor. Missing labels
+// read as "".
+func whereField(name string) (whereType, func(historyEntry) any, error) {
+	if key, ok := strings.CutPrefix(name, "label."); ok {
+		if err := checkLabelKey(key); err != nil {
+			return 0, nil, err
+		}
+		return whereString, func(e historyEntry) any { return e.Labels[key] }, nil
+	}
+	typ, ok := whereFields[name]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown field %q (want exit, duration, command, cwd, host, time, failed, or label.<key>)", name)
+	}
+	var get func(historyEntry) any
+	switch name {
+	case "exit":
+		get = func(e historyEntry) any { return e.ExitCode }
+	case "duration":
+		get = func(e historyEntry) any { return e.duration() }
+	case "command":
+		get = func(e historyEntry) any { return e.Command }
+	case "cwd":
+		get = func(e historyEntry) any { return e.Dir }
+	case "host":
+		get = func(e historyEntry) any { return e.Host }
+	case "time":
+		get = func(e historyEntry) any { return e.Time }
+	case "failed":
+		get = func(e historyEntry) any { return e.ExitCode != 0 }
+	}
+	return typ, get, nil
+}
@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompileWhere(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	entry := historyEntry{
+		Time:       now.Add(-2 * time.Hour),
+		Command:    "cargo build --release",
+		Dir:        "/home/dev/atlas",
+		Host:       "ci-1",
+		DurationMS: (12 * time.Minute).Milliseconds(),
+		ExitCode:   101,
+		Labels:     map[string]string{"project": "atlas"},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `exit != 0 && duration > 10m && label.project == "atlas"`, want: true},
+		{expr: `exit == 0 || duration < 1m`, want: false},
+		{expr: `exit >= 100 && exit <= 101`, want: true},
+		{expr: `failed`, want: true},
+		{expr: `!failed`, want: false},
+		{expr: `failed == false`, want: false},
+		{expr: `command =~ "^(make|cargo) "`, want: true},
+		{expr: `command !~ "release"`, want: false},
+		{expr: `host == "ci-1" && cwd == "/home/dev/atlas"`, want: true},
+		{expr: `label.owner == ""`, want: true},
+		{expr: `label.project != "atlas"`, want: false},
+		{expr: `!(exit == 0 || host == "ci-2") && duration >= 12m`, want: true},
+		{expr: `time >= "3h" && time < "1h"`, want: true},
+		{expr: `time > "2026-03-10T11:00:00Z"`, want: false},
+		{expr: `exit == 0 || exit == 101 && host == "ci-2"`, want: false}, // && binds tighter
+	}
+
+	for _, tt := range tests {
+		match, err := compileWhere(tt.expr, now)
+		if err != nil {
+			t.Errorf("compileWhere(%q) returned error: %v", tt.expr, err)
+			continue
+		}
+		if got := match(entry); got != tt.want {
+			t.Errorf("compileWhere(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileWhereErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`exit`,
+		`exit != `,
+		`exit == "1"`,
+		`duration > 10`,
+		`duration > 10 minutes`,
+		`command == make`,
+		`command > "a"`,
+		`failed == yes`,
+		`time > 24h`,
+		`stauts == 1`,
+		`label.bad key == "x"`,
+		`command =~ "("`,
+		`(exit == 1`,
+		`exit == 1)`,
+		`exit == 1 & exit == 2`,
+		`command == "unterminated`,
+	}
+	for _, expr := range tests {
+		if _, err := compileWhere(expr, time.Now()); err == nil {
+			t.Errorf("compileWhere(%q) succeeded, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// powershellFallback is where Windows keeps PowerShell when the Windows PATH is
+// not appended to the WSL PATH (appendWindowsPath=false in wsl.conf).
+const powershellFallback = "/mnt/c/Windows/System32/WindowsPowerShell/v1.0/powershell.exe"
+
+// powershellAppID is PowerShell's registered AppUserModelID; Windows only shows
+// toasts from registered applications.
+const powershellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// isWSL reports whether reporter is running inside Windows Subsystem for Linux.
+func isWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" || os.Getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	data, err := os.ReadFile("/proc/version")
+	return err == nil && isWSLKernel(string(data))
+}
+
+// toastTag returns PowerShell that tags the toast with a hash of key, so a
+// later toast with the same key replaces it in the Action Center. Tags are
+// limited to 16 characters on older Windows 10 builds.
+func toastTag(key string) string {
+	if key == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return fmt.Sprintf("$toast.Tag = '%016x'\n$toast.Group = 'reporter'\n", h.Sum64())
+}
+
+func isWSLKernel(version string) bool {
+	v := strings.ToLower(version)
+	return strings.Contains(v, "microsoft") || strings.Contains(v, "wsl")
+}
+
+// notifyWSL raises a toast on the Windows host, preferring wsl-notify-send and
+// falling back to PowerShell's WinRT toast API.
+func notifyWSL(n notification) error {
+	title := n.Title
+	switch {
+	case n.Failed:
+		title = "✗ " + title
+	case n.Recovered > 0:
+		title = "✓ " + title
+	}
+	message := notificationText(n)
+	// wsl-notify-send cannot replace an earlier toast, so keyed
+	// notifications go through PowerShell.
+	if n.DedupKey == "" {
+		for _, name := range []string{"wsl-notify-send.exe", "wsl-notify-send"} {
+			if path, err := exec.LookPath(name); err == nil {
+				return exec.Command(path, "--category", title, message).Run()
+			}
+		}
+	}
+
+	ps, err := exec.LookPath("powershell.exe")
+	if err != nil {
+		if _, statErr := os.Stat(powershellFallback); statErr != nil {
+			return errors.New("neither wsl-notify-send nor powershell.exe found")
+		}
+		ps = powershellFallback
+	}
+	script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode(%s)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode(%s)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+%s[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(%s).Show($toast)`,
+		powershellQuote(title), powershellQuote(message), toastTag(n.DedupKey), powershellQuote(powershellAppID))
+	return exec.Command(ps, "-NoProfile", "-NonInteractive", "-Command", script).Run()
+}
@@ -0,0 +1,54 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsWSLKernel(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    bool
+	}{
+		{
+			name:    "WSL2 kernel",
+			version: "Linux version 5.15.153.1-microsoft-standard-WSL2 (root@1c602f52c2e4) (gcc (GCC) 11.2.0) #1 SMP",
+			want:    true,
+		},
+		{
+			name:    "WSL1 kernel",
+			version: "Linux version 4.4.0-19041-Microsoft (Microsoft@Microsoft.com) (gcc version 5.4.0 (GCC) ) #1237-Microsoft",
+			want:    true,
+		},
+		{
+			name:    "regular kernel",
+			version: "Linux version 6.8.0-45-generic (buildd@lcy02-amd64-115) (x86_64-linux-gnu-gcc-13 (Ubuntu 13.2.0-23ubuntu4) 13.2.0)",
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWSLKernel(tt.version); got != tt.want {
+				t.Errorf("isWSLKernel(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToastTag(t *testing.T) {
+	if got := toastTag(""); got != "" {
+		t.Errorf("toastTag(\"\") = %q, want empty", got)
+	}
+	a, b := toastTag("deploy-prod"), toastTag("deploy-staging")
+	if a == b {
+		t.Errorf("toastTag() gives different keys the same tag %q", a)
+	}
+	if a != toastTag("deploy-prod") {
+		t.Error("toastTag() is not stable for a key")
+	}
+	if !strings.HasPrefix(a, "$toast.Tag = '") || len(strings.SplitN(a, "'", 3)[1]) != 16 {
+		t.Errorf("toastTag() = %q, want a 16-character tag", a)
+	}
+}
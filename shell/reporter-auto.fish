@@ -0,0 +1,38 @@
+# Automatic terminal notifications via reporter (fish).
+# Load with `reporter init fish | source` or source this file from config.fish.
+# The last command triggers a notification when it runs longer than the threshold.
+
+# Guard against multiple sourcing.
+if not set -q _reporter_loaded
+    set -g _reporter_loaded 1
+
+    set -q REPORTER_BIN; or set -g REPORTER_BIN (command -v reporter)
+    set -q REPORTER_THRESHOLD; or set -g REPORTER_THRESHOLD 10s
+    # Comma-separated list of command prefixes to exclude from notifications.
+    # Example: set -gx REPORTER_EXCLUDE "ls,cd,pwd,echo,cat"
+    set -q REPORTER_EXCLUDE; or set -g REPORTER_EXCLUDE ""
+
+    # fish reports the duration of the last command in $CMD_DURATION (ms),
+    # so no preexec bookkeeping is needed.
+    function _reporter_postexec --on-event fish_postexec
+        set -l last_status $status
+        set -l cmd $argv[1]
+        test -n "$REPORTER_BIN"; or return
+        test -n (string trim -- $cmd); or return
+
+        set -l first_word (string split -m 1 ' ' -- (string trim -- $cmd))[1]
+        for pattern in (string split ',' -- $REPORTER_EXCLUDE)
+            test "$first_word" = (string trim -- $pattern); and return
+        end
+
+        set -l args notify -duration-from fish:$CMD_DURATION -cmd $cmd -exit $last_status -threshold $REPORTER_THRESHOLD
+        test -n "$REPORTER_ALWAYS"; and set -a args -always
+        test -n "$REPORTER_PUSH_URL"; and set -a args -push-url $REPORTER_PUSH_URL
+        # Tag the run with atuin's session so the histories can be joined.
+        test -n "$ATUIN_SESSION"; and set -a args -label atuin_session=$ATUIN_SESSION
+
+        command $REPORTER_BIN $args >/dev/null 2>&1 &
+        # Keep fish from printing job-control messages.
+        disown 2>/dev/null
+    end
+end
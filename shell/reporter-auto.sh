@@ -13,6 +13,13 @@ _REPORTER_LOADED=1
 # Comma-separated list of command prefixes to exclude from notifications.
 # Example: REPORTER_EXCLUDE="ls,cd,pwd,echo,cat"
 : "${REPORTER_EXCLUDE:=}"
+# Socket of `reporter daemon`; while it is listening, zsh hands reports to it
+# instead of starting reporter.
+if [[ -n "$XDG_RUNTIME_DIR" ]]; then
+  : "${REPORTER_DAEMON_SOCKET:=$XDG_RUNTIME_DIR/reporter/daemon.sock}"
+else
+  : "${REPORTER_DAEMON_SOCKET:=${XDG_STATE_HOME:-$HOME/.local/state}/reporter/daemon.sock}"
+fi
 
 if [[ -z "$REPORTER_BIN" ]]; then
   return 0 2>/dev/null || exit 0
@@ -67,6 +74,34 @@ _reporter_now_ms() {
   fi
 }
 
+# Set _reporter_ms to the current time in whole milliseconds. zsh reads its
+# own clock, so a prompt starts no process. No typeset: `reporter init` runs
+# this file inside a function, where typeset would make it local.
+_reporter_ms=0
+_reporter_set_now_ms() {
+  if [[ -n "$ZSH_VERSION" && -n "$EPOCHREALTIME" ]]; then
+    (( _reporter_ms = EPOCHREALTIME * 1000 ))
+    _reporter_ms="${_reporter_ms%%.*}"
+  else
+    _reporter_ms="$(_reporter_now_ms)"
+  fi
+}
+
+# Hand the finished command to reporter daemon over its socket (zsh only:
+# bash and fish cannot open a Unix socket). Fails if the daemon is not
+# listening, so the caller can run reporter instead.
+_reporter_send_daemon() {
+  local dur_ms="$1" last_exit="$2" labels="" fd
+  [[ -n "$ZSH_VERSION" && -S "$REPORTER_DAEMON_SOCKET" ]] || return 1
+  zmodload zsh/net/socket 2>/dev/null || return 1
+  zsocket "$REPORTER_DAEMON_SOCKET" 2>/dev/null || return 1
+  fd="$REPLY"
+  [[ -n "$ATUIN_SESSION" ]] && labels+="atuin_session=$ATUIN_SESSION "
+  [[ -n "$HISTDB_SESSION" ]] && labels+="histdb_session=$HISTDB_SESSION"
+  print -rn -u "$fd" -- "reporter-hook/1"$'\n'"$dur_ms"$'\n'"$last_exit"$'\n'"$REPORTER_THRESHOLD"$'\n'"${REPORTER_ALWAYS:+1}"$'\n'"$PWD"$'\n'"$labels"$'\n'"$_reporter_cmd"
+  exec {fd}>&-
+}
+
 _reporter_start() {
   # Avoid recursive triggers from our own functions.
   [[ $_reporter_guard -eq 1 ]] && return
@@ -86,7 +121,8 @@ _reporter_start() {
     return
   fi
 
-  _reporter_started_ms="$(_reporter_now_ms)"
+  _reporter_set_now_ms
+  _reporter_started_ms="$_reporter_ms"
   _reporter_started="1"
 }
 
@@ -94,28 +130,25 @@ _reporter_finish() {
   local last_exit=$?
   [[ -z "$_reporter_started" ]] && return
 
-  local now_ms
-  now_ms="$(_reporter_now_ms)"
-  local dur_ms=$((now_ms - _reporter_started_ms))
+  _reporter_set_now_ms
+  local dur_ms=$((_reporter_ms - _reporter_started_ms))
   ((dur_ms < 0)) && dur_ms=0
 
-  # Convert to duration string with millisecond precision.
-  local dur_str
-  if ((dur_ms < 1000)); then
-    dur_str="${dur_ms}ms"
-  else
-    local dur_s=$((dur_ms / 1000))
-    local dur_ms_rem=$((dur_ms % 1000))
-    if ((dur_ms_rem == 0)); then
-      dur_str="${dur_s}s"
-    else
-      dur_str="${dur_s}s${dur_ms_rem}ms"
-    fi
+  _reporter_guard=1
+  if _reporter_send_daemon "$dur_ms" "$last_exit"; then
+    _reporter_guard=0
+    _reporter_started=""
+    return
   fi
+  _reporter_guard=0
 
-  local args=(-notify-only -duration "$dur_str" -cmd "$_reporter_cmd" -exit "$last_exit" -threshold "$REPORTER_THRESHOLD")
+  local args=(notify -duration-from "ms:$dur_ms" -cmd "$_reporter_cmd" -exit "$last_exit" -threshold "$REPORTER_THRESHOLD")
   [[ -n "$REPORTER_ALWAYS" ]] && args+=(-always)
   [[ -n "$REPORTER_PUSH_URL" ]] && args+=(-push-url "$REPORTER_PUSH_URL")
+  # Tag the run with the shell-history session so reporter's history can be
+  # joined with atuin's or zsh-histdb's later.
+  [[ -n "$ATUIN_SESSION" ]] && args+=(-label "atuin_session=$ATUIN_SESSION")
+  [[ -n "$HISTDB_SESSION" ]] && args+=(-label "histdb_session=$HISTDB_SESSION")
 
   _reporter_guard=1
   # Subshell prevents job control messages from appearing in the terminal.
@@ -126,6 +159,7 @@ _reporter_finish() {
 }
 
 if [[ -n "$ZSH_VERSION" ]]; then
+  zmodload zsh/datetime 2>/dev/null
   autoload -Uz add-zsh-hook
   add-zsh-hook preexec _reporter_start
   add-zsh-hook precmd _reporter_finish
@@ -0,0 +1,15 @@
+// Package shell embeds the shell integration scripts so the reporter binary
+// can print them with `reporter init`.
+package shell
+
+import _ "embed"
+
+// Posix is the bash/zsh hook, also installed as reporter-auto.sh.
+//
+//go:embed reporter-auto.sh
+var Posix string
+
+// Fish is the fish hook, also installed as reporter-auto.fish.
+//
+//go:embed reporter-auto.fish
+var Fish string